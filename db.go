@@ -2,6 +2,7 @@ package hermes
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -9,16 +10,21 @@ import (
 // DB wraps the *pgxpool.Pool and provides the missing hermes function wrappers.
 type DB struct {
 	*pgxpool.Pool
+	defaultTimeout time.Duration
 }
 
 // Begin a new transaction.
 func (db *DB) Begin(ctx context.Context) (Conn, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Tx{tx}, nil
+	return &Tx{tx, db.defaultTimeout}, nil
 }
 
 // Commit does nothing.
@@ -27,11 +33,19 @@ func (db *DB) Commit(context.Context) error {
 }
 
 // Rollback does nothing
-func (db *DB) Rollback(_ context.Context) error {
+func (db *DB) Rollback(context.Context) error {
 	return nil
 }
 
-// Close does nothing.
+// Close does nothing.  Since this Close method is meant to be used interchangably with
+// transactions, it doesn't actually close anything, because we don't want to close the underlying
+// database pool at the end of every non-transactional request.  Instead, see DB.Shutdown.
 func (db *DB) Close(context.Context) error {
 	return nil
 }
+
+// Shutdown the underlying pgx Pool.  You should call this when your application is closing to
+// release all the database pool connections.
+func (db *DB) Shutdown() {
+	db.Pool.Close()
+}
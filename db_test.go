@@ -0,0 +1,68 @@
+package hermes_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/sbowman/hermes-pgx"
+)
+
+func TestDBTransactionMethods(t *testing.T) {
+	db := &hermes.DB{}
+
+	// The DB stands in for a transaction, so these do nothing
+	if err := db.Commit(nil); err != nil {
+		t.Errorf("Expected Commit to do nothing; was %s", err)
+	}
+
+	if err := db.Rollback(nil); err != nil {
+		t.Errorf("Expected Rollback to do nothing; was %s", err)
+	}
+
+	if err := db.Close(nil); err != nil {
+		t.Errorf("Expected Close to do nothing; was %s", err)
+	}
+}
+
+func TestBeginWithTimeout(t *testing.T) {
+	db := connect(t)
+
+	tx, err := db.BeginWithTimeout(nil)
+	if err != nil {
+		t.Fatalf("Unable to start a transaction: %s", err)
+	}
+	defer tx.Close()
+
+	savepoint, err := db.Begin(nil)
+	if err != nil {
+		t.Fatalf("Unable to start a transaction: %s", err)
+	}
+	defer savepoint.Close(nil)
+
+	nested, err := savepoint.(*hermes.Tx).BeginWithTimeout(nil)
+	if err != nil {
+		t.Fatalf("Unable to start a savepoint: %s", err)
+	}
+
+	var n int
+	if err := nested.QueryRow("SELECT 1").Scan(&n); err != nil || n != 1 {
+		t.Errorf("Expected 1; was %d, %v", n, err)
+	}
+
+	if err := nested.Commit(); err != nil {
+		t.Errorf("Unable to release the savepoint: %s", err)
+	}
+
+	if err := nested.Close(); !errors.Is(err, pgx.ErrTxClosed) {
+		t.Errorf("Expected ErrTxClosed closing a committed savepoint; was %v", err)
+	}
+
+	if _, err := tx.Exec("SELECT 1"); err != nil {
+		t.Errorf("Unable to query in the transaction: %s", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Errorf("Unable to commit the transaction: %s", err)
+	}
+}
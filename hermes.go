@@ -41,7 +41,7 @@ func ConnectConfig(config *pgxpool.Config) (*DB, error) {
 		return nil, err
 	}
 
-	return &DB{pool}, nil
+	return &DB{Pool: pool}, nil
 }
 
 // Register a new datatype to be associated with connections, such as a custom UUID or time data
@@ -2,6 +2,7 @@ package hermes
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
@@ -47,4 +48,27 @@ type Conn interface {
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (commandTag pgconn.CommandTag, err error)
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+
+	// Lock creates a session-wide advisory lock on a connection, and a transactional advisory
+	// lock on a transaction.  Will block until the lock is available.  Returns an AdvisoryLock,
+	// which must be released when you're done with the lock.
+	Lock(ctx context.Context, id uint64) (AdvisoryLock, error)
+
+	// TryLock tries to create a session-wide or transactional advisory lock, based on the
+	// connection type.  If successful, returns an AdvisoryLock which must be released when
+	// you're done with it.  If unsuccessful (lock is in use), returns an ErrLocked error.
+	TryLock(ctx context.Context, id uint64) (AdvisoryLock, error)
+
+	// WithTimeout returns a timeout context configured with the timeout setting configured on
+	// the database connection pool.  If ctx already has an expiration, simply returns the
+	// existing context.
+	WithTimeout(ctx context.Context) (context.Context, context.CancelFunc)
+
+	// SetTimeout sets the default timeout used for WithTimeout calls.
+	SetTimeout(dur time.Duration)
+
+	// BeginWithTimeout starts a custom transaction that manages the timeout context for you.
+	// If Conn already represents a transaction, pgx will create a savepoint instead.  This is
+	// experimental; use at your own risk!
+	BeginWithTimeout(ctx context.Context) (*ContextualTx, error)
 }
@@ -0,0 +1,289 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ErrLocked returned if you try to acquire an advisory lock and it's already in use.
+var ErrLocked = errors.New("advisory lock already acquired")
+
+// AdvisoryLock is an advisory lock acquired with Conn.Lock or Conn.TryLock.
+type AdvisoryLock interface {
+	Release() error
+}
+
+// SessionAdvisoryLock creates a session-wide advisory lock.  The lock holds its connection out of
+// the pool until it's released.
+type SessionAdvisoryLock struct {
+	mutex sync.Mutex
+
+	ID   uint64
+	conn *pgxpool.Conn
+}
+
+// Release the session-wide advisory lock and return its connection to the pool.  If the unlock
+// fails, the connection is closed instead, which releases the lock, and the error is returned.
+func (lock *SessionAdvisoryLock) Release() error {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+
+	// The lock was already released
+	if lock.conn == nil {
+		return nil
+	}
+
+	_, err := lock.conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lock.ID)
+	releaseConn(lock.conn, err)
+	lock.conn = nil
+
+	return err
+}
+
+// SessionAdvisoryLocks are a set of session-wide advisory locks held on the same connection,
+// acquired and released together.
+type SessionAdvisoryLocks struct {
+	mutex sync.Mutex
+
+	IDs  []uint64
+	conn *pgxpool.Conn
+}
+
+// Release the session-wide advisory locks in a single round trip and return their connection to
+// the pool.  If the unlock fails, the connection is closed instead, which releases the locks, and
+// the error is returned.
+func (locks *SessionAdvisoryLocks) Release() error {
+	locks.mutex.Lock()
+	defer locks.mutex.Unlock()
+
+	// The locks were already released
+	if locks.conn == nil {
+		return nil
+	}
+
+	err := sendLocks(context.Background(), locks.conn, "pg_advisory_unlock", locks.IDs)
+	releaseConn(locks.conn, err)
+	locks.conn = nil
+
+	return err
+}
+
+// releaseConn returns a session lock's connection to the pool.  If unlocking failed, the
+// connection is closed first, as the session may still hold the locks; the pool then discards it.
+func releaseConn(conn *pgxpool.Conn, err error) {
+	if err != nil {
+		_ = conn.Conn().Close(context.Background())
+	}
+
+	conn.Release()
+}
+
+// Lock creates a session-wide advisory lock in the database.  Call Release() to release the
+// advisory lock.
+func (db *DB) Lock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", id); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return &SessionAdvisoryLock{
+		ID:   id,
+		conn: conn,
+	}, nil
+}
+
+// TryLock tries to create a session-wide advisory lock in the database.  If successful, returns the
+// advisory lock.  If not, returns ErrLocked.  If you acquire the lock, be sure to release it!
+func (db *DB) TryLock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var available bool
+	row := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", id)
+	if err := row.Scan(&available); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	if !available {
+		conn.Release()
+		return nil, ErrLocked
+	}
+
+	return &SessionAdvisoryLock{
+		ID:   id,
+		conn: conn,
+	}, nil
+}
+
+// LockAll creates session-wide advisory locks on all the IDs, on the same connection, sending
+// the pg_advisory_lock calls in a single round trip rather than one per ID.  The locks are taken
+// in ascending order, so concurrent calls with overlapping IDs can't deadlock each other, and
+// duplicate IDs are locked once.  If any lock fails, those already acquired are released.  Call
+// Release() to release all the locks.
+func (db *DB) LockAll(ctx context.Context, ids ...uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids = lockOrder(ids)
+
+	if err := lockAll(ctx, conn, "pg_advisory_lock", ids); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return &SessionAdvisoryLocks{
+		IDs:  ids,
+		conn: conn,
+	}, nil
+}
+
+// lockOrder returns a sorted copy of the IDs, without duplicates.
+func lockOrder(ids []uint64) []uint64 {
+	sorted := append([]uint64{}, ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	unique := sorted[:0]
+	for i, id := range sorted {
+		if i == 0 || id != sorted[i-1] {
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+// batchSender is a connection that can send a batch of queries.
+type batchSender interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// lockAll calls the advisory lock function on each of the IDs in a single batch.  If a session
+// lock fails, the locks already acquired are released, as they outlive the batch's implicit
+// transaction.
+func lockAll(ctx context.Context, conn batchSender, fn string, ids []uint64) error {
+	err := sendLocks(ctx, conn, fn, ids)
+	if err != nil && fn == "pg_advisory_lock" {
+		_ = sendLocks(context.Background(), conn, "pg_advisory_unlock", ids)
+	}
+
+	return err
+}
+
+// sendLocks calls the advisory lock function on each of the IDs in a single batch.
+func sendLocks(ctx context.Context, conn batchSender, fn string, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var batch pgx.Batch
+	for _, id := range ids {
+		batch.Queue("SELECT "+fn+"($1)", id)
+	}
+
+	return conn.SendBatch(ctx, &batch).Close()
+}
+
+// TxAdvisoryLock is a placeholder so the Lock/Release functionality is the same for the
+// hermes.Conn interface.
+type TxAdvisoryLock struct {
+	ID uint64
+}
+
+// Release does nothing on a transactional advisory lock.
+func (lock *TxAdvisoryLock) Release() error {
+	return nil
+}
+
+// TxAdvisoryLocks is the placeholder for a set of transactional advisory locks acquired together.
+type TxAdvisoryLocks struct {
+	IDs []uint64
+}
+
+// Release does nothing on transactional advisory locks.
+func (locks *TxAdvisoryLocks) Release() error {
+	return nil
+}
+
+// Lock creates an transactional advisory lock in the database.  This lock will be released at the
+// end of the transaction, on either commit or rollback.  You may call AdvisoryLock.Release(), but
+// it does nothing on this type of advisory lock.
+func (tx *Tx) Lock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", id); err != nil {
+		return nil, err
+	}
+
+	return &TxAdvisoryLock{
+		ID: id,
+	}, nil
+}
+
+// LockAll creates transactional advisory locks on all the IDs, sending the pg_advisory_xact_lock
+// calls in a single round trip rather than one per ID.  The locks are taken in ascending order,
+// so concurrent calls with overlapping IDs can't deadlock each other.  They're released at the
+// end of the transaction.
+func (tx *Tx) LockAll(ctx context.Context, ids ...uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ids = lockOrder(ids)
+
+	if err := lockAll(ctx, tx.Tx, "pg_advisory_xact_lock", ids); err != nil {
+		return nil, err
+	}
+
+	return &TxAdvisoryLocks{IDs: ids}, nil
+}
+
+// TryLock creates an transactional advisory lock in the database.  You may manually call Release() on
+// the AdvisoryLock, or the lock will release automatically on commit or rollback.
+func (tx *Tx) TryLock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var available bool
+	row := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", id)
+	if err := row.Scan(&available); err != nil {
+		return nil, err
+	}
+
+	if !available {
+		return nil, ErrLocked
+	}
+
+	return &TxAdvisoryLock{
+		ID: id,
+	}, nil
+}
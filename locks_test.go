@@ -0,0 +1,144 @@
+package hermes_test
+
+import (
+	"testing"
+
+	"github.com/sbowman/hermes-pgx"
+)
+
+func TestSessionLock(t *testing.T) {
+	db := connect(t)
+
+	const id uint64 = 12
+
+	lock, err := db.Lock(nil, id)
+	if err != nil {
+		t.Fatalf("Failed to acquire a lock: %s", err)
+	}
+
+	if _, err := db.TryLock(nil, id); err != hermes.ErrLocked {
+		t.Errorf("Expected the lock to be taken; was %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Failed to release the lock: %s", err)
+	}
+
+	// Releasing again does nothing
+	if err := lock.Release(); err != nil {
+		t.Errorf("Expected a second release to do nothing; was %s", err)
+	}
+
+	other, err := db.TryLock(nil, id)
+	if err != nil {
+		t.Fatalf("Expected the lock to be available: %s", err)
+	}
+
+	if err := other.Release(); err != nil {
+		t.Errorf("Problem releasing the lock: %s", err)
+	}
+}
+
+func TestTransactionalLock(t *testing.T) {
+	db := connect(t)
+
+	const id uint64 = 13
+
+	tx, err := db.Begin(nil)
+	if err != nil {
+		t.Fatalf("Unable to start a transaction: %s", err)
+	}
+	defer tx.Close(nil)
+
+	if _, err := tx.Lock(nil, id); err != nil {
+		t.Fatalf("Failed to acquire a lock: %s", err)
+	}
+
+	other, err := db.Begin(nil)
+	if err != nil {
+		t.Fatalf("Unable to start a transaction: %s", err)
+	}
+	defer other.Close(nil)
+
+	if _, err := other.TryLock(nil, id); err != hermes.ErrLocked {
+		t.Errorf("Expected the lock to be taken; was %v", err)
+	}
+
+	// The lock is released with the transaction
+	if err := tx.Close(nil); err != nil {
+		t.Fatalf("Failed to close the transaction: %s", err)
+	}
+
+	if _, err := other.TryLock(nil, id); err != nil {
+		t.Errorf("Expected the lock to be available: %s", err)
+	}
+}
+
+func TestLockAll(t *testing.T) {
+	db := connect(t)
+
+	lock, err := db.LockAll(nil, 23, 21, 22, 21)
+	if err != nil {
+		t.Fatalf("Failed to acquire the locks: %s", err)
+	}
+
+	if ids := lock.(*hermes.SessionAdvisoryLocks).IDs; len(ids) != 3 || ids[0] != 21 || ids[2] != 23 {
+		t.Errorf("Expected the locks taken in order without duplicates; was %v", ids)
+	}
+
+	if acquired := db.Stat().AcquiredConns(); acquired != 1 {
+		t.Errorf("Expected the locks to hold 1 connection; was %d", acquired)
+	}
+
+	if _, err := db.TryLock(nil, 22); err != hermes.ErrLocked {
+		t.Errorf("Expected lock 22 to be taken; was %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Failed to release the locks: %s", err)
+	}
+
+	if acquired := db.Stat().AcquiredConns(); acquired != 0 {
+		t.Errorf("Expected the connection returned to the pool; was %d acquired", acquired)
+	}
+
+	other, err := db.TryLock(nil, 22)
+	if err != nil {
+		t.Fatalf("Expected lock 22 to be available: %s", err)
+	}
+
+	if err := other.Release(); err != nil {
+		t.Errorf("Problem releasing lock 22: %s", err)
+	}
+}
+
+func TestTxLockAll(t *testing.T) {
+	db := connect(t)
+
+	tx, err := db.Begin(nil)
+	if err != nil {
+		t.Fatalf("Unable to start a transaction: %s", err)
+	}
+	defer tx.Close(nil)
+
+	if _, err := tx.(*hermes.Tx).LockAll(nil, 25, 24); err != nil {
+		t.Fatalf("Failed to acquire the locks: %s", err)
+	}
+
+	if _, err := db.TryLock(nil, 24); err != hermes.ErrLocked {
+		t.Errorf("Expected lock 24 to be taken; was %v", err)
+	}
+
+	if err := tx.Close(nil); err != nil {
+		t.Fatalf("Failed to close the transaction: %s", err)
+	}
+
+	lock, err := db.TryLock(nil, 24)
+	if err != nil {
+		t.Fatalf("Expected the locks to be released with the transaction: %s", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Errorf("Problem releasing lock 24: %s", err)
+	}
+}
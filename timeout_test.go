@@ -0,0 +1,81 @@
+package hermes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+func TestWithTimeout(t *testing.T) {
+	db := &DB{}
+
+	ctx, cancel := db.WithTimeout(nil)
+	defer cancel()
+
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Second {
+		t.Errorf("Expected the default 1 second timeout; was %v", deadline)
+	}
+
+	db.SetTimeout(time.Minute)
+
+	ctx, cancel = db.WithTimeout(context.Background())
+	defer cancel()
+
+	if deadline, _ := ctx.Deadline(); time.Until(deadline) <= time.Second {
+		t.Errorf("Expected the DB's timeout; was %v", deadline)
+	}
+
+	// An existing deadline is kept
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelParent()
+
+	if ctx, _ := db.WithTimeout(parent); ctx != parent {
+		t.Error("Expected a context with a deadline to be returned as is")
+	}
+}
+
+func TestTxWithTimeout(t *testing.T) {
+	tx := &Tx{}
+	tx.SetTimeout(time.Minute)
+
+	ctx, cancel := tx.WithTimeout(nil)
+	defer cancel()
+
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) <= time.Second {
+		t.Errorf("Expected the transaction's timeout; was %v", deadline)
+	}
+}
+
+func TestBeginWithTimeoutUnavailable(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://localhost:1/hermes_test?connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Unable to parse the configuration: %s", err)
+	}
+	config.LazyConnect = true
+
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Unable to create the pool: %s", err)
+	}
+	defer pool.Close()
+
+	db := &DB{Pool: pool}
+
+	if tx, err := db.BeginWithTimeout(nil); err == nil || tx != nil {
+		t.Errorf("Expected an error without a database; was %v", err)
+	}
+}
+
+func TestLockOrder(t *testing.T) {
+	ids := lockOrder([]uint64{23, 21, 22, 21})
+
+	if len(ids) != 3 || ids[0] != 21 || ids[1] != 22 || ids[2] != 23 {
+		t.Errorf("Expected 21, 22, 23; was %v", ids)
+	}
+
+	if ids := lockOrder(nil); len(ids) != 0 {
+		t.Errorf("Expected no IDs; was %v", ids)
+	}
+}
@@ -2,6 +2,7 @@ package hermes
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 )
@@ -9,16 +10,21 @@ import (
 // Tx wraps the pgx.Tx interface and provides the missing hermes function wrappers.
 type Tx struct {
 	pgx.Tx
+	defaultTimeout time.Duration
 }
 
 // Begin starts a pseudo nested transaction.
 func (tx *Tx) Begin(ctx context.Context) (Conn, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	newTx, err := tx.Tx.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Tx{newTx}, nil
+	return &Tx{newTx, tx.defaultTimeout}, nil
 }
 
 // Close rolls back the transaction if this is a real transaction or rolls back to the
@@ -30,5 +36,9 @@ func (tx *Tx) Begin(ctx context.Context) (Conn, error) {
 //
 // Any other failure of a real transaction will result in the connection being closed.
 func (tx *Tx) Close(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	return tx.Tx.Rollback(ctx)
 }
@@ -0,0 +1,204 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrGroupClosed is returned when pinning a connection from a closed StatementGroup.
+var ErrGroupClosed = errors.New("statement group closed")
+
+// StatementGroup keeps a logical group of queries, such as the hot queries of a particular
+// service, on a small set of the pool's connections.  Each connection prepares and caches the
+// statements it runs, so running the group's queries on the same few connections keeps their
+// prepared statements warm, rather than re-preparing them across the whole pool as connections
+// come and go.
+//
+// The group holds onto up to Size connections from the pool, lending them out through Pin and Run.
+// Connections are returned to the pool when the group is closed, when they're broken or left in
+// a transaction, and once they've been held for the pool's maximum connection lifetime, so they
+// still rotate.
+type StatementGroup struct {
+	// Name identifies the group.
+	Name string
+
+	// Size is the maximum number of connections the group holds.
+	Size int
+
+	db       *DB
+	lifetime time.Duration
+	idle     chan *pgxpool.Conn
+
+	mutex    sync.Mutex
+	acquired map[*pgxpool.Conn]time.Time
+	pending  int
+	closed   bool
+}
+
+// NewStatementGroup creates a statement group holding up to size of the database's connections.
+// Connections are acquired from the pool the first time they're needed.  Call Close when the
+// group is no longer needed to return its connections to the pool.
+func NewStatementGroup(db *DB, name string, size int) *StatementGroup {
+	if size <= 0 {
+		size = 1
+	}
+
+	return &StatementGroup{
+		Name:     name,
+		Size:     size,
+		db:       db,
+		lifetime: db.Config().MaxConnLifetime,
+		idle:     make(chan *pgxpool.Conn, size),
+		acquired: make(map[*pgxpool.Conn]time.Time),
+	}
+}
+
+// Pin returns one of the group's connections for your exclusive use, waiting for one to become
+// available if they're all in use.  Close the connection to return it to the group.
+func (g *StatementGroup) Pin(ctx context.Context) (*PinnedConn, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		conn, err := g.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if g.db.stmtCache != nil && !g.db.stmtCache.refresh(ctx, conn.Conn()) {
+			g.discard(conn)
+			continue
+		}
+
+		return &PinnedConn{Conn: conn, defaultTimeout: g.db.defaultTimeout, db: g.db, group: g}, nil
+	}
+}
+
+// Run pins one of the group's connections, calls fn with it, then returns the connection to the
+// group.
+func (g *StatementGroup) Run(ctx context.Context, fn func(conn Conn) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := g.Pin(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	return fn(conn)
+}
+
+// Held returns the number of connections the group is holding, whether idle or in use.
+func (g *StatementGroup) Held() int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return len(g.acquired)
+}
+
+// Close returns the group's idle connections to the pool.  Connections in use are returned to the
+// pool when they're closed.  Safe to call multiple times.
+func (g *StatementGroup) Close() {
+	g.mutex.Lock()
+	g.closed = true
+	g.mutex.Unlock()
+
+	for {
+		select {
+		case conn := <-g.idle:
+			g.discard(conn)
+		default:
+			return
+		}
+	}
+}
+
+// acquire returns an idle connection from the group, a new connection from the pool if the group
+// isn't full, or waits for a connection to be returned to the group.
+func (g *StatementGroup) acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	for {
+		select {
+		case conn := <-g.idle:
+			if conn.Conn().IsClosed() {
+				g.discard(conn)
+				continue
+			}
+			return conn, nil
+		default:
+		}
+
+		g.mutex.Lock()
+
+		if g.closed {
+			g.mutex.Unlock()
+			return nil, ErrGroupClosed
+		}
+
+		if len(g.acquired)+g.pending < g.Size {
+			// Reserve the slot while acquiring
+			g.pending++
+			g.mutex.Unlock()
+
+			conn, err := g.db.Acquire(ctx)
+
+			g.mutex.Lock()
+			g.pending--
+			if err == nil {
+				g.acquired[conn] = time.Now()
+			}
+			g.mutex.Unlock()
+
+			return conn, err
+		}
+
+		g.mutex.Unlock()
+
+		select {
+		case conn := <-g.idle:
+			if conn.Conn().IsClosed() {
+				g.discard(conn)
+				continue
+			}
+			return conn, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release returns the connection to the group, or to the pool if the group is closed, the
+// connection is no longer usable, or the group has held it for the pool's maximum connection
+// lifetime.
+func (g *StatementGroup) release(conn *pgxpool.Conn) {
+	pc := conn.Conn().PgConn()
+	usable := !pc.IsClosed() && !pc.IsBusy() && pc.TxStatus() == 'I'
+
+	g.mutex.Lock()
+	expired := g.lifetime > 0 && time.Since(g.acquired[conn]) >= g.lifetime
+
+	// Queued under the lock so Close can't miss it; the channel has room for every connection
+	if usable && !expired && !g.closed {
+		g.idle <- conn
+		g.mutex.Unlock()
+		return
+	}
+
+	g.mutex.Unlock()
+	g.discard(conn)
+}
+
+// discard returns the connection to the pool and stops holding it.
+func (g *StatementGroup) discard(conn *pgxpool.Conn) {
+	g.mutex.Lock()
+	delete(g.acquired, conn)
+	g.mutex.Unlock()
+
+	conn.Release()
+}
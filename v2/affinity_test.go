@@ -0,0 +1,39 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStatementGroupClosed(t *testing.T) {
+	db, err := Connect("postgres://localhost/hermes_test?sslmode=disable")
+	if err != nil {
+		t.Fatalf("Unable to create the pool: %s", err)
+	}
+	defer db.Shutdown()
+
+	group := NewStatementGroup(db, "reports", 0)
+	if group.Size != 1 {
+		t.Errorf("Expected the group to hold at least 1 connection; was %d", group.Size)
+	}
+
+	group.Close()
+	group.Close()
+
+	if _, err := group.Pin(context.Background()); !errors.Is(err, ErrGroupClosed) {
+		t.Errorf("Expected ErrGroupClosed; was %v", err)
+	}
+
+	err = group.Run(context.Background(), func(Conn) error {
+		t.Error("Expected Run not to call fn on a closed group")
+		return nil
+	})
+	if !errors.Is(err, ErrGroupClosed) {
+		t.Errorf("Expected ErrGroupClosed; was %v", err)
+	}
+
+	if held := group.Held(); held != 0 {
+		t.Errorf("Expected the group to hold no connections; was %d", held)
+	}
+}
@@ -0,0 +1,184 @@
+package hermes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrArrayDimensions is returned when an Array doesn't have the dimensions required, e.g. calling
+// Matrix on a one-dimensional array.
+var ErrArrayDimensions = errors.New("array has the wrong number of dimensions")
+
+// Array is a PostgreSQL array of any number of dimensions, holding elements of type T.  Arrays
+// scan from and encode to any PostgreSQL array type whose elements T can represent.  For arrays
+// with NULL elements, use a nullable element type, such as a pointer or Null[T].
+//
+// The elements are stored flat, in row-major order; Dims holds the length of each dimension.
+// Use NewArray or NewMatrix to build an array, and At, Elements, or Matrix to read one.
+type Array[T any] struct {
+	// Elements of the array, in row-major order.
+	Elements []T
+
+	// Dims holds the length of each dimension.  Empty for an empty array.
+	Dims []int
+
+	// Valid is false if the array is NULL.
+	Valid bool
+}
+
+// NewArray returns a one-dimensional array of the elements.  A nil slice is a NULL array.
+func NewArray[T any](elements []T) Array[T] {
+	if elements == nil {
+		return Array[T]{}
+	}
+
+	if len(elements) == 0 {
+		return Array[T]{Elements: elements, Valid: true}
+	}
+
+	return Array[T]{Elements: elements, Dims: []int{len(elements)}, Valid: true}
+}
+
+// NewMatrix returns a two-dimensional array of the rows.  Every row must be the same length.
+func NewMatrix[T any](rows [][]T) (Array[T], error) {
+	if rows == nil {
+		return Array[T]{}, nil
+	}
+
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return Array[T]{Elements: []T{}, Valid: true}, nil
+	}
+
+	cols := len(rows[0])
+	elements := make([]T, 0, len(rows)*cols)
+
+	for i, row := range rows {
+		if len(row) != cols {
+			return Array[T]{}, fmt.Errorf("%w: row %d has %d elements; expected %d", ErrArrayDimensions,
+				i, len(row), cols)
+		}
+		elements = append(elements, row...)
+	}
+
+	return Array[T]{Elements: elements, Dims: []int{len(rows), cols}, Valid: true}, nil
+}
+
+// Len returns the total number of elements in the array.
+func (a Array[T]) Len() int {
+	return len(a.Elements)
+}
+
+// At returns the element at the given zero-based index in each dimension.  Panics if the number
+// of indexes doesn't match the array's dimensions or an index is out of range.
+func (a Array[T]) At(indexes ...int) T {
+	if len(indexes) != len(a.Dims) {
+		panic(fmt.Sprintf("hermes: %d indexes for a %d-dimensional array", len(indexes), len(a.Dims)))
+	}
+
+	offset := 0
+	for i, idx := range indexes {
+		if idx < 0 || idx >= a.Dims[i] {
+			panic(fmt.Sprintf("hermes: array index %d out of range [0:%d]", idx, a.Dims[i]))
+		}
+		offset = offset*a.Dims[i] + idx
+	}
+
+	return a.Elements[offset]
+}
+
+// Slice returns the elements of a one-dimensional array, or nil if the array is NULL.  Returns
+// ErrArrayDimensions for a multidimensional array.
+func (a Array[T]) Slice() ([]T, error) {
+	if !a.Valid {
+		return nil, nil
+	}
+
+	if len(a.Dims) > 1 {
+		return nil, fmt.Errorf("%w: expected 1; was %d", ErrArrayDimensions, len(a.Dims))
+	}
+
+	return a.Elements, nil
+}
+
+// Matrix returns the rows of a two-dimensional array, or nil if the array is NULL.  Returns
+// ErrArrayDimensions if the array doesn't have two dimensions; an empty array returns no rows.
+func (a Array[T]) Matrix() ([][]T, error) {
+	if !a.Valid {
+		return nil, nil
+	}
+
+	if len(a.Dims) == 0 {
+		return [][]T{}, nil
+	}
+
+	if len(a.Dims) != 2 {
+		return nil, fmt.Errorf("%w: expected 2; was %d", ErrArrayDimensions, len(a.Dims))
+	}
+
+	rows := make([][]T, a.Dims[0])
+	for i := range rows {
+		rows[i] = a.Elements[i*a.Dims[1] : (i+1)*a.Dims[1]]
+	}
+
+	return rows, nil
+}
+
+// Dimensions implements pgtype.ArrayGetter.
+func (a Array[T]) Dimensions() []pgtype.ArrayDimension {
+	if !a.Valid {
+		return nil
+	}
+
+	dims := make([]pgtype.ArrayDimension, len(a.Dims))
+	for i, length := range a.Dims {
+		dims[i] = pgtype.ArrayDimension{Length: int32(length), LowerBound: 1}
+	}
+
+	return dims
+}
+
+// Index implements pgtype.ArrayGetter.
+func (a Array[T]) Index(i int) interface{} {
+	return a.Elements[i]
+}
+
+// IndexType implements pgtype.ArrayGetter.
+func (a Array[T]) IndexType() interface{} {
+	var el T
+	return el
+}
+
+// SetDimensions implements pgtype.ArraySetter.
+func (a *Array[T]) SetDimensions(dimensions []pgtype.ArrayDimension) error {
+	if dimensions == nil {
+		*a = Array[T]{}
+		return nil
+	}
+
+	count := 1
+	dims := make([]int, len(dimensions))
+	for i, dim := range dimensions {
+		dims[i] = int(dim.Length)
+		count *= dims[i]
+	}
+
+	if len(dimensions) == 0 {
+		count = 0
+		dims = nil
+	}
+
+	*a = Array[T]{Elements: make([]T, count), Dims: dims, Valid: true}
+	return nil
+}
+
+// ScanIndex implements pgtype.ArraySetter.
+func (a *Array[T]) ScanIndex(i int) interface{} {
+	return &a.Elements[i]
+}
+
+// ScanIndexType implements pgtype.ArraySetter.
+func (a *Array[T]) ScanIndexType() interface{} {
+	return new(T)
+}
@@ -0,0 +1,131 @@
+package hermes_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+func TestArrayRoundTrip(t *testing.T) {
+	m := pgtype.NewMap()
+
+	matrix, err := hermes.NewMatrix([][]int32{{1, 2, 3}, {4, 5, 6}})
+	if err != nil {
+		t.Fatalf("Unable to create matrix: %s", err)
+	}
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(pgtype.Int4ArrayOID, format, matrix, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode matrix in format %d: %s", format, err)
+		}
+
+		var check hermes.Array[int32]
+		if err := m.Scan(pgtype.Int4ArrayOID, format, buf, &check); err != nil {
+			t.Fatalf("Unable to scan matrix in format %d: %s", format, err)
+		}
+
+		if !check.Valid || len(check.Dims) != 2 || check.Dims[0] != 2 || check.Dims[1] != 3 {
+			t.Fatalf("Expected a valid 2x3 array; was %+v", check)
+		}
+
+		if check.At(1, 2) != 6 {
+			t.Errorf("Expected element [1][2] to be 6; was %d", check.At(1, 2))
+		}
+
+		rows, err := check.Matrix()
+		if err != nil {
+			t.Fatalf("Unable to get matrix rows: %s", err)
+		}
+
+		if rows[0][1] != 2 || rows[1][0] != 4 {
+			t.Errorf("Expected rows [[1 2 3] [4 5 6]]; was %v", rows)
+		}
+
+		if _, err := check.Slice(); !errors.Is(err, hermes.ErrArrayDimensions) {
+			t.Errorf("Expected ErrArrayDimensions getting a slice of a matrix; was %v", err)
+		}
+	}
+}
+
+func TestArrayNullElements(t *testing.T) {
+	m := pgtype.NewMap()
+
+	two := int32(2)
+	arr := hermes.NewArray([]*int32{nil, &two})
+
+	buf, err := m.Encode(pgtype.Int4ArrayOID, pgtype.BinaryFormatCode, arr, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode array: %s", err)
+	}
+
+	var check hermes.Array[*int32]
+	if err := m.Scan(pgtype.Int4ArrayOID, pgtype.BinaryFormatCode, buf, &check); err != nil {
+		t.Fatalf("Unable to scan array: %s", err)
+	}
+
+	elements, err := check.Slice()
+	if err != nil {
+		t.Fatalf("Unable to get slice: %s", err)
+	}
+
+	if len(elements) != 2 || elements[0] != nil || elements[1] == nil || *elements[1] != 2 {
+		t.Errorf("Expected [nil 2]; was %v", elements)
+	}
+}
+
+func TestArrayNull(t *testing.T) {
+	m := pgtype.NewMap()
+
+	buf, err := m.Encode(pgtype.TextArrayOID, pgtype.BinaryFormatCode, hermes.NewArray[string](nil), nil)
+	if err != nil {
+		t.Fatalf("Unable to encode NULL array: %s", err)
+	}
+
+	if buf != nil {
+		t.Error("Expected nil slice to encode as NULL")
+	}
+
+	check := hermes.NewArray([]string{"a"})
+	if err := m.Scan(pgtype.TextArrayOID, pgtype.BinaryFormatCode, nil, &check); err != nil {
+		t.Fatalf("Unable to scan NULL array: %s", err)
+	}
+
+	if check.Valid {
+		t.Error("Expected NULL array to be invalid")
+	}
+
+	if elements, _ := check.Slice(); elements != nil {
+		t.Errorf("Expected nil slice for NULL array; was %v", elements)
+	}
+}
+
+func TestArrayEmpty(t *testing.T) {
+	m := pgtype.NewMap()
+
+	buf, err := m.Encode(pgtype.TextArrayOID, pgtype.TextFormatCode, hermes.NewArray([]string{}), nil)
+	if err != nil {
+		t.Fatalf("Unable to encode empty array: %s", err)
+	}
+
+	if string(buf) != "{}" {
+		t.Errorf("Expected {}; was %s", buf)
+	}
+
+	var check hermes.Array[string]
+	if err := m.Scan(pgtype.TextArrayOID, pgtype.TextFormatCode, buf, &check); err != nil {
+		t.Fatalf("Unable to scan empty array: %s", err)
+	}
+
+	if !check.Valid || check.Len() != 0 {
+		t.Errorf("Expected valid empty array; was %+v", check)
+	}
+}
+
+func TestNewMatrixRagged(t *testing.T) {
+	if _, err := hermes.NewMatrix([][]int{{1, 2}, {3}}); !errors.Is(err, hermes.ErrArrayDimensions) {
+		t.Errorf("Expected ErrArrayDimensions for ragged rows; was %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+// Package arrow exports query results as Apache Arrow record batches, in the Arrow IPC streaming
+// format, for analytics tools such as pandas, Polars, DuckDB, and Spark to read directly.  The
+// format is written by hand, so the package has no dependencies beyond pgx.
+//
+// PostgreSQL types map to Arrow types as follows:
+//
+//	boolean                         Bool
+//	smallint, integer, bigint       Int16, Int32, Int64
+//	oid                             Uint32
+//	real, double precision          Float32, Float64
+//	bytea                           Binary
+//	uuid                            FixedSizeBinary(16)
+//	date                            Date32
+//	time                            Time64(microsecond)
+//	timestamp                       Timestamp(microsecond)
+//	timestamptz                     Timestamp(microsecond, "UTC")
+//	everything else                 Utf8, in PostgreSQL's text format or JSON
+//
+// Numeric values are exported as text to keep their precision; cast them in the query to export
+// them as floating point, e.g. "price::float8".
+package arrow
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// DefaultBatchRows is the number of rows in each record batch by default.
+const DefaultBatchRows = 64 * 1024
+
+// ErrUnsupportedValue is returned when a value can't be converted to its column's Arrow type,
+// e.g. an infinite timestamp.
+var ErrUnsupportedValue = errors.New("value unsupported by the Arrow type")
+
+// Options configure an export.
+type Options struct {
+	// BatchRows is the number of rows in each record batch.  Defaults to DefaultBatchRows.
+	BatchRows int
+}
+
+// Export runs the query and streams the results to w in the Arrow IPC streaming format, one
+// record batch per opts.BatchRows rows.  Returns the number of rows exported.
+func Export(ctx context.Context, conn hermes.Conn, w io.Writer, opts Options, sql string, args ...interface{}) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	return WriteRows(w, rows, opts)
+}
+
+// WriteRows streams the rows to w in the Arrow IPC streaming format, and closes them.  Returns
+// the number of rows written.
+func WriteRows(w io.Writer, rows pgx.Rows, opts Options) (int64, error) {
+	defer rows.Close()
+
+	batchRows := opts.BatchRows
+	if batchRows <= 0 {
+		batchRows = DefaultBatchRows
+	}
+
+	fds := rows.FieldDescriptions()
+	columns := make([]*column, len(fds))
+	for i, fd := range fds {
+		columns[i] = newColumn(fd.Name, fd.DataTypeOID)
+	}
+
+	if err := writeMessage(w, schemaMessage(columns), nil); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	var batched int
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return count, err
+		}
+
+		for i, value := range values {
+			if err := columns[i].append(value); err != nil {
+				return count, fmt.Errorf("column %s: %w", columns[i].name, err)
+			}
+		}
+
+		count++
+		batched++
+
+		if batched == batchRows {
+			if err := writeBatch(w, columns, batched); err != nil {
+				return count, err
+			}
+			batched = 0
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	if batched > 0 {
+		if err := writeBatch(w, columns, batched); err != nil {
+			return count, err
+		}
+	}
+
+	// End of stream
+	_, err := w.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+	return count, err
+}
+
+// Arrow IPC identifiers, from the Arrow format's Schema.fbs and Message.fbs.
+const (
+	metadataV5 = 4
+
+	headerSchema      = 1
+	headerRecordBatch = 3
+)
+
+// writeBatch writes the rows collected in the columns as a record batch, and resets the columns.
+func writeBatch(w io.Writer, columns []*column, length int) error {
+	var nodes [][2]int64
+	var buffers [][2]int64
+	var body []byte
+
+	for _, col := range columns {
+		nodes = append(nodes, [2]int64{int64(length), int64(col.nulls)})
+
+		for _, buf := range col.buffers() {
+			buffers = append(buffers, [2]int64{int64(len(body)), int64(len(buf))})
+			body = append(body, buf...)
+			body = append(body, make([]byte, padding(len(body)))...)
+		}
+
+		col.reset()
+	}
+
+	return writeMessage(w, recordBatchMessage(length, nodes, buffers, len(body)), body)
+}
+
+// writeMessage writes an encapsulated message:  a continuation marker, the length of the
+// metadata, the metadata padded to 8 bytes, and the body.
+func writeMessage(w io.Writer, metadata, body []byte) error {
+	size := len(metadata) + padding(len(metadata))
+
+	prefix := make([]byte, 8, 8+size)
+	binary.LittleEndian.PutUint32(prefix, 0xffffffff)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(size))
+
+	msg := append(append(prefix, metadata...), make([]byte, size-len(metadata))...)
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+
+	if len(body) > 0 {
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// schemaMessage returns the metadata of the Schema message describing the columns.
+func schemaMessage(columns []*column) []byte {
+	var b builder
+
+	fields := make([]int, len(columns))
+	for i, col := range columns {
+		name := b.createString(col.name)
+		typ := col.typ.write(&b)
+		children := b.createOffsets(nil)
+
+		// Field
+		b.startTable(7)
+		b.addOffset(0, name)
+		b.addBool(1, true)
+		b.addUint8(2, col.typ.id)
+		b.addOffset(3, typ)
+		b.addOffset(5, children)
+		fields[i] = b.endTable()
+	}
+
+	fieldsVector := b.createOffsets(fields)
+
+	// Schema, little endian
+	b.startTable(4)
+	b.addInt16(0, 0)
+	b.addOffset(1, fieldsVector)
+	schema := b.endTable()
+
+	return b.finish(message(&b, headerSchema, schema, 0))
+}
+
+// recordBatchMessage returns the metadata of the RecordBatch message describing a batch's field
+// nodes and buffers.
+func recordBatchMessage(length int, nodes, buffers [][2]int64, bodyLength int) []byte {
+	var b builder
+
+	nodesVector := b.createPairs(nodes)
+	buffersVector := b.createPairs(buffers)
+
+	// RecordBatch
+	b.startTable(5)
+	b.addInt64(0, int64(length))
+	b.addOffset(1, nodesVector)
+	b.addOffset(2, buffersVector)
+	batch := b.endTable()
+
+	return b.finish(message(&b, headerRecordBatch, batch, bodyLength))
+}
+
+// message writes the Message table wrapping the header and returns its offset.
+func message(b *builder, headerType uint8, header int, bodyLength int) int {
+	b.startTable(5)
+	b.addInt64(3, int64(bodyLength))
+	b.addOffset(2, header)
+	b.addInt16(0, metadataV5)
+	b.addUint8(1, headerType)
+
+	return b.endTable()
+}
+
+// padding returns the bytes needed to pad n bytes to a multiple of 8.
+func padding(n int) int {
+	return (8 - n%8) % 8
+}
@@ -0,0 +1,354 @@
+package arrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeRows returns the values as query results.
+type fakeRows struct {
+	fields []pgconn.FieldDescription
+	values [][]interface{}
+	row    int
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return r.fields }
+func (r *fakeRows) Scan(dest ...interface{}) error               { return nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	r.row++
+	return r.row <= len(r.values)
+}
+
+func (r *fakeRows) Values() ([]interface{}, error) {
+	return r.values[r.row-1], nil
+}
+
+// table reads a FlatBuffer table, failing the test on misaligned values.
+type table struct {
+	t   *testing.T
+	buf []byte
+	pos int
+}
+
+func rootTable(t *testing.T, buf []byte) table {
+	return table{t: t, buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+// field returns the position of the field, or 0 if it isn't set.
+func (tb table) field(i int) int {
+	vtable := tb.pos - int(int32(binary.LittleEndian.Uint32(tb.buf[tb.pos:])))
+	size := int(binary.LittleEndian.Uint16(tb.buf[vtable:]))
+
+	if 4+2*i >= size {
+		return 0
+	}
+
+	rel := int(binary.LittleEndian.Uint16(tb.buf[vtable+4+2*i:]))
+	if rel == 0 {
+		return 0
+	}
+
+	return tb.pos + rel
+}
+
+func (tb table) aligned(pos, size int) int {
+	if pos%size != 0 {
+		tb.t.Fatalf("Expected a value aligned to %d bytes at %d", size, pos)
+	}
+	return pos
+}
+
+func (tb table) uint8(i int) uint8 {
+	return tb.buf[tb.field(i)]
+}
+
+func (tb table) int16(i int) int16 {
+	return int16(binary.LittleEndian.Uint16(tb.buf[tb.aligned(tb.field(i), 2):]))
+}
+
+func (tb table) int32(i int) int32 {
+	return int32(binary.LittleEndian.Uint32(tb.buf[tb.aligned(tb.field(i), 4):]))
+}
+
+func (tb table) int64(i int) int64 {
+	return int64(binary.LittleEndian.Uint64(tb.buf[tb.aligned(tb.field(i), 8):]))
+}
+
+// follow returns the position of the object referred to by the field.
+func (tb table) follow(i int) int {
+	pos := tb.aligned(tb.field(i), 4)
+	if pos == 0 {
+		tb.t.Fatalf("Expected field %d to be set", i)
+	}
+	return pos + int(binary.LittleEndian.Uint32(tb.buf[pos:]))
+}
+
+func (tb table) table(i int) table {
+	return table{t: tb.t, buf: tb.buf, pos: tb.follow(i)}
+}
+
+func (tb table) string(i int) string {
+	pos := tb.follow(i)
+	n := int(binary.LittleEndian.Uint32(tb.buf[pos:]))
+	return string(tb.buf[pos+4 : pos+4+n])
+}
+
+// tables returns the tables in the vector field.
+func (tb table) tables(i int) []table {
+	pos := tb.follow(i)
+	n := int(binary.LittleEndian.Uint32(tb.buf[pos:]))
+
+	tables := make([]table, n)
+	for j := range tables {
+		elem := pos + 4 + 4*j
+		tables[j] = table{t: tb.t, buf: tb.buf, pos: elem + int(binary.LittleEndian.Uint32(tb.buf[elem:]))}
+	}
+	return tables
+}
+
+// pairs returns the structs of two longs in the vector field.
+func (tb table) pairs(i int) [][2]int64 {
+	pos := tb.follow(i)
+	n := int(binary.LittleEndian.Uint32(tb.buf[pos:]))
+
+	pairs := make([][2]int64, n)
+	for j := range pairs {
+		elem := tb.aligned(pos+4+16*j, 8)
+		pairs[j][0] = int64(binary.LittleEndian.Uint64(tb.buf[elem:]))
+		pairs[j][1] = int64(binary.LittleEndian.Uint64(tb.buf[elem+8:]))
+	}
+	return pairs
+}
+
+// ipcMessage is a message read from an Arrow IPC stream.
+type ipcMessage struct {
+	header table
+	kind   uint8
+	body   []byte
+}
+
+// readStream splits the Arrow IPC stream into its messages.
+func readStream(t *testing.T, stream []byte) []ipcMessage {
+	var messages []ipcMessage
+
+	for {
+		if len(stream) < 8 || binary.LittleEndian.Uint32(stream) != 0xffffffff {
+			t.Fatalf("Expected a continuation marker; was %v", stream)
+		}
+
+		size := int(binary.LittleEndian.Uint32(stream[4:]))
+		if size == 0 {
+			if len(stream) != 8 {
+				t.Errorf("Expected the stream to end; %d bytes remain", len(stream)-8)
+			}
+			return messages
+		}
+
+		if size%8 != 0 {
+			t.Errorf("Expected the metadata padded to 8 bytes; was %d", size)
+		}
+
+		// Copy, so alignment is relative to the start of the metadata
+		metadata := append([]byte(nil), stream[8:8+size]...)
+		msg := rootTable(t, metadata)
+
+		if version := msg.int16(0); version != metadataV5 {
+			t.Errorf("Expected metadata version V5; was %d", version)
+		}
+
+		bodyLength := int(msg.int64(3))
+		stream = stream[8+size:]
+
+		messages = append(messages, ipcMessage{header: msg.table(2), kind: msg.uint8(1), body: stream[:bodyLength]})
+		stream = stream[bodyLength:]
+	}
+}
+
+func TestWriteRows(t *testing.T) {
+	created := time.Date(2022, 11, 30, 12, 0, 0, 0, time.UTC)
+	uid := [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	var price pgtype.Numeric
+	if err := price.Scan("12.50"); err != nil {
+		t.Fatalf("Unable to scan the numeric: %s", err)
+	}
+
+	rows := &fakeRows{
+		fields: []pgconn.FieldDescription{
+			{Name: "id", DataTypeOID: pgtype.Int4OID},
+			{Name: "name", DataTypeOID: pgtype.TextOID},
+			{Name: "active", DataTypeOID: pgtype.BoolOID},
+			{Name: "score", DataTypeOID: pgtype.Float8OID},
+			{Name: "created_at", DataTypeOID: pgtype.TimestamptzOID},
+			{Name: "day", DataTypeOID: pgtype.DateOID},
+			{Name: "uid", DataTypeOID: pgtype.UUIDOID},
+			{Name: "price", DataTypeOID: pgtype.NumericOID},
+		},
+		values: [][]interface{}{
+			{int32(1), "alice", true, 1.5, created, time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC), uid, price},
+			{int32(2), nil, false, nil, nil, nil, nil, nil},
+			{int32(3), "carol", nil, 3.0, created, created, uid, map[string]interface{}{"a": 1}},
+		},
+	}
+
+	var out bytes.Buffer
+	count, err := WriteRows(&out, rows, Options{BatchRows: 2})
+	if err != nil {
+		t.Fatalf("Unable to write the rows: %s", err)
+	}
+
+	if count != 3 {
+		t.Errorf("Expected 3 rows; was %d", count)
+	}
+
+	messages := readStream(t, out.Bytes())
+	if len(messages) != 3 {
+		t.Fatalf("Expected a schema and two record batches; was %d messages", len(messages))
+	}
+
+	schema := messages[0]
+	if schema.kind != headerSchema {
+		t.Fatalf("Expected a schema first; was %d", schema.kind)
+	}
+
+	types := []uint8{typeInt, typeUtf8, typeBool, typeFloatingPoint, typeTimestamp, typeDate, typeFixedSizeBinary, typeUtf8}
+	fields := schema.header.tables(1)
+	if len(fields) != len(types) {
+		t.Fatalf("Expected %d fields; was %d", len(types), len(fields))
+	}
+
+	for i, field := range fields {
+		if name := field.string(0); name != rows.fields[i].Name {
+			t.Errorf("Expected field %s; was %s", rows.fields[i].Name, name)
+		}
+
+		if kind := field.uint8(2); kind != types[i] {
+			t.Errorf("Expected %s to be type %d; was %d", rows.fields[i].Name, types[i], kind)
+		}
+
+		if children := field.tables(5); len(children) != 0 {
+			t.Errorf("Expected no children; was %d", len(children))
+		}
+	}
+
+	if bits, signed := fields[0].table(3).int32(0), fields[0].table(3).uint8(1); bits != 32 || signed != 1 {
+		t.Errorf("Expected a signed 32-bit integer; was %d bits, signed %d", bits, signed)
+	}
+
+	if tz := fields[4].table(3).string(1); tz != "UTC" {
+		t.Errorf("Expected a UTC timestamp; was %q", tz)
+	}
+
+	first, second := messages[1], messages[2]
+	if first.kind != headerRecordBatch || second.kind != headerRecordBatch {
+		t.Fatalf("Expected record batches; were %d and %d", first.kind, second.kind)
+	}
+
+	if length := first.header.int64(0); length != 2 {
+		t.Errorf("Expected 2 rows in the first batch; was %d", length)
+	}
+
+	if length := second.header.int64(0); length != 1 {
+		t.Errorf("Expected 1 row in the second batch; was %d", length)
+	}
+
+	nodes := first.header.pairs(1)
+	if nodes[0] != [2]int64{2, 0} || nodes[1] != [2]int64{2, 1} || nodes[6] != [2]int64{2, 1} {
+		t.Errorf("Expected the lengths and null counts of the columns; was %v", nodes)
+	}
+
+	buffers := first.header.pairs(2)
+	buffer := func(i int) []byte {
+		return first.body[buffers[i][0] : buffers[i][0]+buffers[i][1]]
+	}
+
+	for _, b := range buffers {
+		if b[0]%8 != 0 {
+			t.Errorf("Expected buffers aligned to 8 bytes; was %d", b[0])
+		}
+	}
+
+	// id: validity, data
+	if len(buffer(0)) != 0 || !bytes.Equal(buffer(1), []byte{1, 0, 0, 0, 2, 0, 0, 0}) {
+		t.Errorf("Expected no validity bitmap and ids 1 and 2; was %v and %v", buffer(0), buffer(1))
+	}
+
+	// name: validity, offsets, data
+	if !bytes.Equal(buffer(2), []byte{1}) || !bytes.Equal(buffer(3), []byte{0, 0, 0, 0, 5, 0, 0, 0, 5, 0, 0, 0}) || string(buffer(4)) != "alice" {
+		t.Errorf("Expected alice and a null; was %v, %v, %q", buffer(2), buffer(3), buffer(4))
+	}
+
+	// active: validity, bits
+	if len(buffer(5)) != 0 || !bytes.Equal(buffer(6), []byte{1}) {
+		t.Errorf("Expected true and false; was %v", buffer(6))
+	}
+
+	// score: validity, data
+	if score := math.Float64frombits(binary.LittleEndian.Uint64(buffer(8))); score != 1.5 {
+		t.Errorf("Expected a score of 1.5; was %f", score)
+	}
+
+	// created_at: validity, microseconds
+	if micros := int64(binary.LittleEndian.Uint64(buffer(10))); micros != created.UnixMicro() {
+		t.Errorf("Expected %d microseconds; was %d", created.UnixMicro(), micros)
+	}
+
+	// day: validity, days since the epoch
+	if days := int32(binary.LittleEndian.Uint32(buffer(12))); days != -1 {
+		t.Errorf("Expected the day before the epoch; was %d", days)
+	}
+
+	// uid: validity, 16 byte values
+	if data := buffer(14); len(data) != 32 || !bytes.Equal(data[:16], uid[:]) {
+		t.Errorf("Expected the UUID and a null; was %v", data)
+	}
+
+	// price: validity, offsets, data
+	if text := string(buffer(17)); text != "12.50" {
+		t.Errorf("Expected the numeric as text; was %q", text)
+	}
+
+	last := second.header.pairs(2)
+	if price := second.body[last[17][0] : last[17][0]+last[17][1]]; string(price) != `{"a":1}` {
+		t.Errorf("Expected JSON text; was %q", price)
+	}
+}
+
+func TestWriteRowsEmpty(t *testing.T) {
+	rows := &fakeRows{fields: []pgconn.FieldDescription{{Name: "id", DataTypeOID: pgtype.Int8OID}}}
+
+	var out bytes.Buffer
+	if _, err := WriteRows(&out, rows, Options{}); err != nil {
+		t.Fatalf("Unable to write the rows: %s", err)
+	}
+
+	if messages := readStream(t, out.Bytes()); len(messages) != 1 || messages[0].kind != headerSchema {
+		t.Errorf("Expected only a schema; was %d messages", len(messages))
+	}
+}
+
+func TestWriteRowsUnsupported(t *testing.T) {
+	rows := &fakeRows{
+		fields: []pgconn.FieldDescription{{Name: "created_at", DataTypeOID: pgtype.TimestampOID}},
+		values: [][]interface{}{{pgtype.Infinity}},
+	}
+
+	var out bytes.Buffer
+	if _, err := WriteRows(&out, rows, Options{}); !errors.Is(err, ErrUnsupportedValue) {
+		t.Errorf("Expected ErrUnsupportedValue for an infinite timestamp; was %v", err)
+	}
+}
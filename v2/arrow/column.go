@@ -0,0 +1,407 @@
+package arrow
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Arrow type identifiers, from the Type union in the Arrow format's Schema.fbs.
+const (
+	typeInt             = 2
+	typeFloatingPoint   = 3
+	typeBinary          = 4
+	typeUtf8            = 5
+	typeBool            = 6
+	typeDate            = 8
+	typeTime            = 9
+	typeTimestamp       = 10
+	typeFixedSizeBinary = 15
+)
+
+// Arrow enum values for the types' units and precisions.
+const (
+	precisionSingle = 1
+	precisionDouble = 2
+
+	dateDay = 0
+
+	unitMicrosecond = 2
+)
+
+// arrowType is a column's Arrow type.
+type arrowType struct {
+	id uint8
+
+	// write the type's table to the builder, returning its offset
+	write func(b *builder) int
+}
+
+// emptyType is a type without parameters, such as Utf8.
+func emptyType(id uint8) arrowType {
+	return arrowType{id: id, write: func(b *builder) int {
+		b.startTable(0)
+		return b.endTable()
+	}}
+}
+
+func intType(bits int32, signed bool) arrowType {
+	return arrowType{id: typeInt, write: func(b *builder) int {
+		b.startTable(2)
+		b.addInt32(0, bits)
+		b.addBool(1, signed)
+		return b.endTable()
+	}}
+}
+
+func floatType(precision int16) arrowType {
+	return arrowType{id: typeFloatingPoint, write: func(b *builder) int {
+		b.startTable(1)
+		b.addInt16(0, precision)
+		return b.endTable()
+	}}
+}
+
+func dateType() arrowType {
+	return arrowType{id: typeDate, write: func(b *builder) int {
+		b.startTable(1)
+		b.addInt16(0, dateDay)
+		return b.endTable()
+	}}
+}
+
+func timeType() arrowType {
+	return arrowType{id: typeTime, write: func(b *builder) int {
+		b.startTable(2)
+		b.addInt16(0, unitMicrosecond)
+		b.addInt32(1, 64)
+		return b.endTable()
+	}}
+}
+
+func timestampType(timezone string) arrowType {
+	return arrowType{id: typeTimestamp, write: func(b *builder) int {
+		var tz int
+		if timezone != "" {
+			tz = b.createString(timezone)
+		}
+
+		b.startTable(2)
+		b.addInt16(0, unitMicrosecond)
+		if timezone != "" {
+			b.addOffset(1, tz)
+		}
+		return b.endTable()
+	}}
+}
+
+func fixedSizeBinaryType(width int32) arrowType {
+	return arrowType{id: typeFixedSizeBinary, write: func(b *builder) int {
+		b.startTable(1)
+		b.addInt32(0, width)
+		return b.endTable()
+	}}
+}
+
+// column collects a column's values for the next record batch in Arrow's memory layout.
+type column struct {
+	name string
+	typ  arrowType
+
+	// width is the size of each value of a fixed-width type; zero for variable-width types
+	width int
+
+	// encode appends the value to the column's data
+	encode func(data []byte, value interface{}) ([]byte, error)
+
+	length   int
+	nulls    int
+	validity []byte
+	offsets  []byte
+	data     []byte
+}
+
+// newColumn returns a column for the PostgreSQL type.
+func newColumn(name string, oid uint32) *column {
+	col := &column{name: name}
+
+	switch oid {
+	case pgtype.BoolOID:
+		col.typ = emptyType(typeBool)
+	case pgtype.Int2OID:
+		col.typ, col.width, col.encode = intType(16, true), 2, encodeInt16
+	case pgtype.Int4OID:
+		col.typ, col.width, col.encode = intType(32, true), 4, encodeInt32
+	case pgtype.Int8OID:
+		col.typ, col.width, col.encode = intType(64, true), 8, encodeInt64
+	case pgtype.OIDOID:
+		col.typ, col.width, col.encode = intType(32, false), 4, encodeUint32
+	case pgtype.Float4OID:
+		col.typ, col.width, col.encode = floatType(precisionSingle), 4, encodeFloat32
+	case pgtype.Float8OID:
+		col.typ, col.width, col.encode = floatType(precisionDouble), 8, encodeFloat64
+	case pgtype.UUIDOID:
+		col.typ, col.width, col.encode = fixedSizeBinaryType(16), 16, encodeUUID
+	case pgtype.DateOID:
+		col.typ, col.width, col.encode = dateType(), 4, encodeDate
+	case pgtype.TimeOID:
+		col.typ, col.width, col.encode = timeType(), 8, encodeTime
+	case pgtype.TimestampOID:
+		col.typ, col.width, col.encode = timestampType(""), 8, encodeTimestamp
+	case pgtype.TimestamptzOID:
+		col.typ, col.width, col.encode = timestampType("UTC"), 8, encodeTimestamp
+	case pgtype.ByteaOID:
+		col.typ, col.encode = emptyType(typeBinary), encodeBytes
+	default:
+		col.typ, col.encode = emptyType(typeUtf8), encodeText
+	}
+
+	col.reset()
+	return col
+}
+
+// append the value to the column.
+func (col *column) append(value interface{}) error {
+	index := col.length
+	valid := value != nil
+
+	if valid {
+		var err error
+		if col.data, err = col.encodeValue(index, value); err != nil {
+			return err
+		}
+	} else {
+		col.nulls++
+		col.data = col.encodeNull(index)
+	}
+
+	col.validity = setBit(col.validity, index, valid)
+
+	if col.typ.id == typeUtf8 || col.typ.id == typeBinary {
+		col.offsets = appendUint32(col.offsets, uint32(len(col.data)))
+	}
+
+	col.length++
+	return nil
+}
+
+// encodeValue returns the column's data with the value appended.
+func (col *column) encodeValue(index int, value interface{}) ([]byte, error) {
+	if col.typ.id == typeBool {
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+		}
+		return setBit(col.data, index, b), nil
+	}
+
+	data, err := col.encode(col.data, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if col.width > 0 && len(data) != len(col.data)+col.width {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+
+	return data, nil
+}
+
+// encodeNull returns the column's data with the space for a null appended.
+func (col *column) encodeNull(index int) []byte {
+	switch {
+	case col.typ.id == typeBool:
+		return setBit(col.data, index, false)
+	case col.width > 0:
+		return append(col.data, make([]byte, col.width)...)
+	}
+
+	return col.data
+}
+
+// buffers returns the column's buffers in Arrow's order:  the validity bitmap, then the offsets
+// for variable-width types, then the data.  The validity bitmap is left empty if there are no
+// nulls.
+func (col *column) buffers() [][]byte {
+	validity := col.validity
+	if col.nulls == 0 {
+		validity = nil
+	}
+
+	if col.typ.id == typeUtf8 || col.typ.id == typeBinary {
+		return [][]byte{validity, col.offsets, col.data}
+	}
+
+	return [][]byte{validity, col.data}
+}
+
+// reset the column for the next record batch.
+func (col *column) reset() {
+	col.length, col.nulls = 0, 0
+	col.validity = col.validity[:0]
+	col.data = col.data[:0]
+
+	// Variable-width values start at offset zero
+	col.offsets = append(col.offsets[:0], 0, 0, 0, 0)
+}
+
+// setBit sets or clears the bit at index in the bitmap, growing it as needed.
+func setBit(bitmap []byte, index int, value bool) []byte {
+	if index%8 == 0 {
+		bitmap = append(bitmap, 0)
+	}
+
+	if value {
+		bitmap[index/8] |= 1 << (index % 8)
+	}
+
+	return bitmap
+}
+
+func encodeInt16(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.(int16)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return appendUint16(data, uint16(v)), nil
+}
+
+func encodeInt32(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.(int32)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return appendUint32(data, uint32(v)), nil
+}
+
+func encodeInt64(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.(int64)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return appendUint64(data, uint64(v)), nil
+}
+
+func encodeUint32(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.(uint32)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return appendUint32(data, v), nil
+}
+
+func encodeFloat32(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.(float32)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return appendUint32(data, math.Float32bits(v)), nil
+}
+
+func encodeFloat64(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return appendUint64(data, math.Float64bits(v)), nil
+}
+
+func encodeUUID(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.([16]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return append(data, v[:]...), nil
+}
+
+// encodeDate appends the number of days since the Unix epoch.
+func encodeDate(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedValue, value)
+	}
+
+	secs := v.Unix()
+	days := secs / 86400
+	if secs%86400 < 0 {
+		days--
+	}
+
+	return appendUint32(data, uint32(int32(days))), nil
+}
+
+// encodeTime appends the number of microseconds since midnight.
+func encodeTime(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.(pgtype.Time)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return appendUint64(data, uint64(v.Microseconds)), nil
+}
+
+// encodeTimestamp appends the number of microseconds since the Unix epoch.
+func encodeTimestamp(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedValue, value)
+	}
+	return appendUint64(data, uint64(v.UnixMicro())), nil
+}
+
+func encodeBytes(data []byte, value interface{}) ([]byte, error) {
+	v, ok := value.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return append(data, v...), nil
+}
+
+// encodeText appends the value as text:  strings as they are, values such as numerics and
+// intervals in PostgreSQL's text format, and JSON documents and arrays as JSON.
+func encodeText(data []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return append(data, v...), nil
+	case []byte:
+		return append(data, v...), nil
+	case driver.Valuer:
+		text, err := v.Value()
+		if err != nil {
+			return nil, err
+		}
+
+		if s, ok := text.(string); ok {
+			return append(data, s...), nil
+		}
+	case fmt.Stringer:
+		return append(data, v.String()...), nil
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		doc, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return append(data, doc...), nil
+	}
+
+	return append(data, fmt.Sprint(value)...), nil
+}
+
+func appendUint16(data []byte, v uint16) []byte {
+	return append(data, byte(v), byte(v>>8))
+}
+
+func appendUint32(data []byte, v uint32) []byte {
+	return append(data, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(data []byte, v uint64) []byte {
+	return appendUint32(appendUint32(data, uint32(v)), uint32(v>>32))
+}
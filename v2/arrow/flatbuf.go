@@ -0,0 +1,181 @@
+package arrow
+
+import "encoding/binary"
+
+// builder writes a FlatBuffer back to front, as the flatc generated code does, so each object's
+// children are written before the object that refers to them.  Only the pieces the Arrow
+// messages need are supported.  Offsets are measured from the end of the buffer.
+type builder struct {
+	buf      []byte
+	minAlign int
+
+	// Positions of the fields of the table being written, and where it started
+	fields []int
+	start  int
+}
+
+// offset returns the position of the start of the buffer, measured from the end.
+func (b *builder) offset() int {
+	return len(b.buf)
+}
+
+// prepend the bytes to the buffer.
+func (b *builder) prepend(p []byte) {
+	b.buf = append(append(make([]byte, 0, len(p)+len(b.buf)), p...), b.buf...)
+}
+
+// prep pads the buffer so a value of size bytes is aligned once additional bytes are written.
+func (b *builder) prep(size, additional int) {
+	if size > b.minAlign {
+		b.minAlign = size
+	}
+
+	if pad := (size - (len(b.buf)+additional)%size) % size; pad > 0 {
+		b.prepend(make([]byte, pad))
+	}
+}
+
+func (b *builder) prependUint8(v uint8) {
+	b.prepend([]byte{v})
+}
+
+func (b *builder) prependUint16(v uint16) {
+	b.prep(2, 0)
+
+	var p [2]byte
+	binary.LittleEndian.PutUint16(p[:], v)
+	b.prepend(p[:])
+}
+
+func (b *builder) prependUint32(v uint32) {
+	b.prep(4, 0)
+
+	var p [4]byte
+	binary.LittleEndian.PutUint32(p[:], v)
+	b.prepend(p[:])
+}
+
+func (b *builder) prependInt64(v int64) {
+	b.prep(8, 0)
+
+	var p [8]byte
+	binary.LittleEndian.PutUint64(p[:], uint64(v))
+	b.prepend(p[:])
+}
+
+// prependOffset writes a reference to the object at off, relative to the reference itself.
+func (b *builder) prependOffset(off int) {
+	b.prep(4, 0)
+	b.prependUint32(uint32(b.offset() - off + 4))
+}
+
+// createString writes a null-terminated string and returns its offset.
+func (b *builder) createString(s string) int {
+	b.prep(4, len(s)+1)
+	b.prepend(append([]byte(s), 0))
+	b.prependUint32(uint32(len(s)))
+
+	return b.offset()
+}
+
+// createOffsets writes a vector of references to objects and returns its offset.
+func (b *builder) createOffsets(offs []int) int {
+	b.prep(4, 4*len(offs))
+	for i := len(offs) - 1; i >= 0; i-- {
+		b.prependOffset(offs[i])
+	}
+	b.prependUint32(uint32(len(offs)))
+
+	return b.offset()
+}
+
+// createPairs writes a vector of structs of two longs, e.g. Arrow's FieldNode and Buffer, and
+// returns its offset.
+func (b *builder) createPairs(pairs [][2]int64) int {
+	b.prep(4, 16*len(pairs))
+	b.prep(8, 16*len(pairs))
+
+	for i := len(pairs) - 1; i >= 0; i-- {
+		b.prependInt64(pairs[i][1])
+		b.prependInt64(pairs[i][0])
+	}
+	b.prependUint32(uint32(len(pairs)))
+
+	return b.offset()
+}
+
+// startTable begins a table with room for n fields.  Write the table's children first.
+func (b *builder) startTable(n int) {
+	b.fields = make([]int, n)
+	b.start = b.offset()
+}
+
+// slot records the field just written.
+func (b *builder) slot(field int) {
+	b.fields[field] = b.offset()
+}
+
+func (b *builder) addUint8(field int, v uint8) {
+	b.prependUint8(v)
+	b.slot(field)
+}
+
+func (b *builder) addBool(field int, v bool) {
+	var u uint8
+	if v {
+		u = 1
+	}
+	b.addUint8(field, u)
+}
+
+func (b *builder) addInt16(field int, v int16) {
+	b.prependUint16(uint16(v))
+	b.slot(field)
+}
+
+func (b *builder) addInt32(field int, v int32) {
+	b.prependUint32(uint32(v))
+	b.slot(field)
+}
+
+func (b *builder) addInt64(field int, v int64) {
+	b.prependInt64(v)
+	b.slot(field)
+}
+
+func (b *builder) addOffset(field, off int) {
+	b.prependOffset(off)
+	b.slot(field)
+}
+
+// endTable writes the table's vtable and returns the table's offset.
+func (b *builder) endTable() int {
+	b.prependUint32(0)
+	table := b.offset()
+
+	for i := len(b.fields) - 1; i >= 0; i-- {
+		var rel uint16
+		if b.fields[i] != 0 {
+			rel = uint16(table - b.fields[i])
+		}
+		b.prependUint16(rel)
+	}
+
+	b.prependUint16(uint16(table - b.start))
+	b.prependUint16(uint16((len(b.fields) + 2) * 2))
+
+	// The table starts with the distance back to its vtable
+	vtable := b.offset()
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-table:], uint32(int32(vtable-table)))
+
+	b.fields = nil
+	return table
+}
+
+// finish writes the reference to the root table and returns the buffer.
+func (b *builder) finish(root int) []byte {
+	b.prep(b.minAlign, 4)
+	b.prependOffset(root)
+
+	return b.buf
+}
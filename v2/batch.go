@@ -0,0 +1,189 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Batch queues statements to send to the database in a single round trip, as a pgx.Batch does,
+// but registers where each statement's results go as it's queued.  When the batch is sent, the
+// results are read in order and scanned into their destinations, so there's no reading
+// pgx.BatchResults by hand:
+//
+//	var b hermes.Batch
+//	b.Queue("insert into audit (user_id, action) values ($1, $2)", userID, "login")
+//	b.Queue("select name from users where id = $1", userID).Scan(&name)
+//	b.Queue("select count(*) from sessions where user_id = $1", userID).Scan(&sessions)
+//
+//	if err := b.Send(ctx, db); err != nil {
+//		return err
+//	}
+//
+// The statements sent together run in an implicit transaction:  if one fails, the statements
+// after it fail too.  Send returns a *BatchError naming the statement that failed first, and
+// each BatchQuery has its own error.
+type Batch struct {
+	queries []*BatchQuery
+}
+
+// BatchQuery is a statement queued in a Batch.  Register where its results go with Scan or
+// ScanEach before the batch is sent; without either, the statement is run for its command tag.
+type BatchQuery struct {
+	// SQL is the statement.
+	SQL string
+
+	// Args are the statement's arguments.
+	Args []interface{}
+
+	dest []interface{}
+	each func(rows pgx.Rows) error
+
+	tag pgconn.CommandTag
+	err error
+}
+
+// BatchError is returned by Batch.Send when a statement in the batch fails.
+type BatchError struct {
+	// Index is the position of the failed statement in the batch, starting at 0.
+	Index int
+
+	// SQL is the failed statement.
+	SQL string
+
+	// Err is the statement's error.
+	Err error
+}
+
+// Error describes the failed statement.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch statement %d failed: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the statement's error.
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Queue adds a statement to the batch.  Call Scan or ScanEach on the returned BatchQuery to
+// collect its rows.
+func (b *Batch) Queue(sql string, args ...interface{}) *BatchQuery {
+	query := &BatchQuery{SQL: sql, Args: args}
+	b.queries = append(b.queries, query)
+
+	return query
+}
+
+// Len returns the number of statements queued.
+func (b *Batch) Len() int {
+	return len(b.queries)
+}
+
+// Send sends the queued statements to the database in a single round trip and reads their
+// results in order, scanning each into the destinations registered with it.  Returns a
+// *BatchError for the first statement that failed, or the error closing the batch.  The batch
+// may be sent again, e.g. on another connection.
+func (b *Batch) Send(ctx context.Context, conn Conn) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(b.queries) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, query := range b.queries {
+		batch.Queue(query.SQL, query.Args...)
+	}
+
+	br := conn.SendBatch(ctx, batch)
+
+	var first error
+	for i, query := range b.queries {
+		if err := query.receive(br); err != nil && first == nil {
+			first = &BatchError{Index: i, SQL: query.SQL, Err: err}
+		}
+	}
+
+	if err := br.Close(); err != nil && first == nil {
+		first = err
+	}
+
+	return first
+}
+
+// Scan registers dest to scan the statement's first row into once the batch is sent.  Any rows
+// after the first are ignored.  If the statement returns no rows, its error is pgx.ErrNoRows.
+func (q *BatchQuery) Scan(dest ...interface{}) *BatchQuery {
+	q.dest, q.each = dest, nil
+	return q
+}
+
+// ScanEach registers fn to call on each of the statement's rows once the batch is sent; call
+// rows.Scan in fn to read the row.  If fn returns an error, the rest of the rows are skipped and
+// the error is the statement's.
+func (q *BatchQuery) ScanEach(fn func(rows pgx.Rows) error) *BatchQuery {
+	q.dest, q.each = nil, fn
+	return q
+}
+
+// CommandTag returns the statement's command tag once the batch is sent.
+func (q *BatchQuery) CommandTag() pgconn.CommandTag {
+	return q.tag
+}
+
+// Err returns the statement's error once the batch is sent, or nil if it succeeded.
+func (q *BatchQuery) Err() error {
+	return q.err
+}
+
+// receive reads the statement's result from the batch.
+func (q *BatchQuery) receive(br pgx.BatchResults) error {
+	q.tag, q.err = pgconn.CommandTag{}, nil
+
+	if q.dest == nil && q.each == nil {
+		q.tag, q.err = br.Exec()
+		return q.err
+	}
+
+	rows, err := br.Query()
+	if err != nil {
+		q.err = err
+		return err
+	}
+
+	q.err = q.scan(rows)
+	rows.Close()
+
+	if err := rows.Err(); err != nil && q.err == nil {
+		q.err = err
+	}
+	q.tag = rows.CommandTag()
+
+	return q.err
+}
+
+// scan the rows into the registered destinations.
+func (q *BatchQuery) scan(rows pgx.Rows) error {
+	if q.each != nil {
+		for rows.Next() {
+			if err := q.each(rows); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+
+	return rows.Scan(q.dest...)
+}
@@ -0,0 +1,90 @@
+package hermes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestBatch(t *testing.T) {
+	conn := &batchConn{}
+
+	var first, second string
+	var each []string
+
+	var b Batch
+	insert := b.Queue("insert into audit (action) values ($1)", "login")
+	b.Queue("select name from users where id = $1", 1).Scan(&first)
+	b.Queue("select name from users where id = $1", 2).Scan(&second)
+	b.Queue("select name from users").ScanEach(func(rows pgx.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		each = append(each, name)
+		return nil
+	})
+
+	if b.Len() != 4 {
+		t.Errorf("Expected 4 statements queued; was %d", b.Len())
+	}
+
+	if err := b.Send(nil, conn); err != nil {
+		t.Fatalf("Unable to send the batch: %s", err)
+	}
+
+	if !reflect.DeepEqual(conn.sent, []int{4}) {
+		t.Errorf("Expected a single batch of 4; was %v", conn.sent)
+	}
+
+	if tag := insert.CommandTag().String(); tag != "INSERT 0 1" {
+		t.Errorf("Expected the insert's command tag; was %q", tag)
+	}
+
+	if first != "row 2" {
+		t.Errorf("Expected \"row 2\"; was %q", first)
+	}
+
+	if second != "row 3" {
+		t.Errorf("Expected \"row 3\"; was %q", second)
+	}
+
+	if !reflect.DeepEqual(each, []string{"row 4"}) {
+		t.Errorf("Expected [row 4]; was %v", each)
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	conn := &batchConn{failAt: 2}
+
+	var name string
+
+	var b Batch
+	first := b.Queue("insert into audit (action) values ($1)", "login")
+	failed := b.Queue("select name from users where id = $1", 1).Scan(&name)
+
+	err := b.Send(nil, conn)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a BatchError; was %v", err)
+	}
+
+	if batchErr.Index != 1 {
+		t.Errorf("Expected the second statement to fail; was %d", batchErr.Index)
+	}
+
+	if !errors.Is(err, errBatch) {
+		t.Errorf("Expected the statement's error; was %v", err)
+	}
+
+	if first.Err() != nil {
+		t.Errorf("Expected the first statement to succeed; was %s", first.Err())
+	}
+
+	if !errors.Is(failed.Err(), errBatch) {
+		t.Errorf("Expected the statement's error; was %v", failed.Err())
+	}
+}
@@ -0,0 +1,328 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BeginExec starts a transaction and runs the first statement in it, sending BEGIN and the
+// statement together in a single round trip, rather than waiting on BEGIN before sending the
+// statement.  Returns the transaction and the statement's command tag.  If the statement fails,
+// the transaction is rolled back and the error returned.
+//
+// The transaction works like any other from Begin, except that LargeObjects isn't supported.
+func (db *DB) BeginExec(ctx context.Context, sql string, args ...interface{}) (Conn, pgconn.CommandTag, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tx, br, err := db.beginBatch(ctx, sql, args)
+	if err != nil {
+		return nil, pgconn.CommandTag{}, err
+	}
+
+	tag, err := br.Exec()
+	if closeErr := br.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, pgconn.CommandTag{}, err
+	}
+
+	return tx, tag, nil
+}
+
+// BeginQuery starts a transaction and runs the first query in it, sending BEGIN and the query
+// together in a single round trip.  Returns the transaction and the query's rows.  Read or close
+// the rows before using the transaction.  If the query fails, the transaction is rolled back and
+// the error returned.  See BeginExec.
+func (db *DB) BeginQuery(ctx context.Context, sql string, args ...interface{}) (Conn, pgx.Rows, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tx, br, err := db.beginBatch(ctx, sql, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := br.Query()
+	if err != nil {
+		_ = br.Close()
+		_ = tx.Rollback(ctx)
+		return nil, nil, err
+	}
+
+	return tx, &beginRows{Rows: rows, br: br}, nil
+}
+
+// beginBatch acquires a connection and sends BEGIN along with the statement, returning the
+// transaction and the statement's pending result.
+func (db *DB) beginBatch(ctx context.Context, sql string, args []interface{}) (*Tx, pgx.BatchResults, error) {
+	if err := db.acquireSlot(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := db.Acquire(db.timeAcquire(ctx))
+	if err != nil {
+		db.releaseSlot()
+		return nil, nil, err
+	}
+
+	var batch pgx.Batch
+	batch.Queue("begin")
+	batch.Queue(sql, args...)
+
+	br := conn.SendBatch(ctx, &batch)
+	if _, err := br.Exec(); err != nil {
+		_ = br.Close()
+		conn.Release()
+		db.releaseSlot()
+		return nil, nil, err
+	}
+
+	tx := &Tx{Tx: &openedTx{conn: conn}, defaultTimeout: db.defaultTimeout, db: db, limited: true}
+	db.txBegan(&tx.ended, conn.Conn())
+
+	return tx, br, nil
+}
+
+// beginRows close the batch they were sent in once they're closed or read to the end, so the
+// transaction's connection is free for the next statement.
+type beginRows struct {
+	pgx.Rows
+	br     pgx.BatchResults
+	closed bool
+}
+
+// Next prepares the next row for reading, closing the batch after the last row.
+func (rows *beginRows) Next() bool {
+	if rows.Rows.Next() {
+		return true
+	}
+
+	rows.Close()
+	return false
+}
+
+// Close the rows and the batch.
+func (rows *beginRows) Close() {
+	rows.Rows.Close()
+
+	if !rows.closed {
+		rows.closed = true
+		_ = rows.br.Close()
+	}
+}
+
+// openedTx is a pgx.Tx for a transaction begun by sending BEGIN in a batch, which pgx can't wrap
+// in one of its own.  The connection is released back to the pool when the transaction ends.
+type openedTx struct {
+	conn       *pgxpool.Conn
+	savepoints int64
+	closed     bool
+}
+
+// Begin starts a pseudo nested transaction with a savepoint.
+func (tx *openedTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	if tx.closed {
+		return nil, pgx.ErrTxClosed
+	}
+
+	tx.savepoints++
+	savepoint := tx.savepoints
+
+	if _, err := tx.conn.Exec(ctx, fmt.Sprintf("savepoint sp_%d", savepoint)); err != nil {
+		return nil, err
+	}
+
+	return &savepointTx{tx: tx, savepoint: savepoint}, nil
+}
+
+// Commit the transaction and release the connection.  Returns pgx.ErrTxCommitRollback if the
+// transaction had failed, and so was rolled back.
+func (tx *openedTx) Commit(ctx context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+
+	tag, err := tx.conn.Exec(ctx, "commit")
+	tx.end()
+
+	if err != nil {
+		return err
+	}
+
+	if tag.String() == "ROLLBACK" {
+		return pgx.ErrTxCommitRollback
+	}
+
+	return nil
+}
+
+// Rollback the transaction and release the connection.
+func (tx *openedTx) Rollback(ctx context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+
+	_, err := tx.conn.Exec(ctx, "rollback")
+	tx.end()
+
+	return err
+}
+
+// end releases the connection.  The pool closes the connection instead if it's still in the
+// transaction, e.g. because the commit failed.
+func (tx *openedTx) end() {
+	tx.closed = true
+	tx.conn.Release()
+}
+
+func (tx *openedTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if tx.closed {
+		return 0, pgx.ErrTxClosed
+	}
+	return tx.conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (tx *openedTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if tx.closed {
+		return &limitedBatchResults{err: pgx.ErrTxClosed}
+	}
+	return tx.conn.SendBatch(ctx, b)
+}
+
+// LargeObjects isn't supported, as pgx doesn't allow them outside its own transactions.  The
+// returned value panics if used.
+func (tx *openedTx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+func (tx *openedTx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	if tx.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return tx.conn.Conn().Prepare(ctx, name, sql)
+}
+
+func (tx *openedTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if tx.closed {
+		return pgconn.CommandTag{}, pgx.ErrTxClosed
+	}
+	return tx.conn.Exec(ctx, sql, args...)
+}
+
+func (tx *openedTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if tx.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return tx.conn.Query(ctx, sql, args...)
+}
+
+func (tx *openedTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if tx.closed {
+		return &limitedRow{err: pgx.ErrTxClosed}
+	}
+	return tx.conn.QueryRow(ctx, sql, args...)
+}
+
+func (tx *openedTx) Conn() *pgx.Conn {
+	return tx.conn.Conn()
+}
+
+// savepointTx is a pseudo nested transaction within an openedTx.
+type savepointTx struct {
+	tx        *openedTx
+	savepoint int64
+	closed    bool
+}
+
+// Begin starts a pseudo nested transaction with another savepoint.
+func (sp *savepointTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	if sp.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return sp.tx.Begin(ctx)
+}
+
+// Commit releases the savepoint.
+func (sp *savepointTx) Commit(ctx context.Context) error {
+	if sp.closed {
+		return pgx.ErrTxClosed
+	}
+
+	_, err := sp.Exec(ctx, fmt.Sprintf("release savepoint sp_%d", sp.savepoint))
+	sp.closed = true
+
+	return err
+}
+
+// Rollback rolls back to the savepoint.
+func (sp *savepointTx) Rollback(ctx context.Context) error {
+	if sp.closed {
+		return pgx.ErrTxClosed
+	}
+
+	_, err := sp.Exec(ctx, fmt.Sprintf("rollback to savepoint sp_%d", sp.savepoint))
+	sp.closed = true
+
+	return err
+}
+
+func (sp *savepointTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if sp.closed {
+		return 0, pgx.ErrTxClosed
+	}
+	return sp.tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (sp *savepointTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if sp.closed {
+		return &limitedBatchResults{err: pgx.ErrTxClosed}
+	}
+	return sp.tx.SendBatch(ctx, b)
+}
+
+// LargeObjects isn't supported.  See openedTx.LargeObjects.
+func (sp *savepointTx) LargeObjects() pgx.LargeObjects {
+	return pgx.LargeObjects{}
+}
+
+func (sp *savepointTx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	if sp.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return sp.tx.Prepare(ctx, name, sql)
+}
+
+func (sp *savepointTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if sp.closed {
+		return pgconn.CommandTag{}, pgx.ErrTxClosed
+	}
+	return sp.tx.Exec(ctx, sql, args...)
+}
+
+func (sp *savepointTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if sp.closed {
+		return nil, pgx.ErrTxClosed
+	}
+	return sp.tx.Query(ctx, sql, args...)
+}
+
+func (sp *savepointTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if sp.closed {
+		return &limitedRow{err: pgx.ErrTxClosed}
+	}
+	return sp.tx.QueryRow(ctx, sql, args...)
+}
+
+func (sp *savepointTx) Conn() *pgx.Conn {
+	return sp.tx.Conn()
+}
@@ -0,0 +1,76 @@
+package hermes
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// closeCounter counts the times the batch results are closed.
+type closeCounter struct {
+	pgx.BatchResults
+	closed int
+}
+
+func (br *closeCounter) Close() error {
+	br.closed++
+	return nil
+}
+
+func TestBeginRows(t *testing.T) {
+	br := &closeCounter{}
+	fake := &fakeRows{columns: []string{"id"}, values: [][]interface{}{{1}, {2}}}
+	rows := &beginRows{Rows: fake, br: br}
+
+	count := 0
+	for rows.Next() {
+		if br.closed != 0 {
+			t.Error("Expected the batch to stay open while reading rows")
+		}
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 rows; was %d", count)
+	}
+
+	if br.closed != 1 || !fake.closed {
+		t.Errorf("Expected the rows and batch to be closed after the last row; batch closed %d times", br.closed)
+	}
+
+	rows.Close()
+
+	if br.closed != 1 {
+		t.Errorf("Expected the batch to be closed once; was %d", br.closed)
+	}
+}
+
+func TestOpenedTxClosed(t *testing.T) {
+	tx := &openedTx{closed: true}
+
+	if err := tx.Commit(nil); err != pgx.ErrTxClosed {
+		t.Errorf("Expected ErrTxClosed on commit; was %v", err)
+	}
+
+	if err := tx.Rollback(nil); err != pgx.ErrTxClosed {
+		t.Errorf("Expected ErrTxClosed on rollback; was %v", err)
+	}
+
+	if _, err := tx.Exec(nil, "select 1"); err != pgx.ErrTxClosed {
+		t.Errorf("Expected ErrTxClosed on exec; was %v", err)
+	}
+
+	var n int
+	if err := tx.QueryRow(nil, "select 1").Scan(&n); err != pgx.ErrTxClosed {
+		t.Errorf("Expected ErrTxClosed on query row; was %v", err)
+	}
+
+	if err := tx.SendBatch(nil, &pgx.Batch{}).Close(); err != pgx.ErrTxClosed {
+		t.Errorf("Expected ErrTxClosed on batch; was %v", err)
+	}
+
+	sp := &savepointTx{tx: tx, savepoint: 1, closed: true}
+	if _, err := sp.Begin(nil); err != pgx.ErrTxClosed {
+		t.Errorf("Expected ErrTxClosed on nested begin; was %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package benchmarks_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbowman/hermes-pgx/v2"
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+// DefaultURI is the database the benchmarks run against if HERMES_BENCH_URI isn't set.
+const DefaultURI = "postgres://localhost/hermes_test?sslmode=disable"
+
+// Number of rows in the table the query benchmarks read from.
+const seedRows = 1000
+
+type account struct {
+	ID      int64
+	Name    string
+	Balance float64
+}
+
+// connect opens the benchmark database, skipping the benchmark if it isn't available, and
+// creates the accounts table with seedRows rows.
+func connect(b *testing.B) *hermes.DB {
+	b.Helper()
+
+	uri := os.Getenv("HERMES_BENCH_URI")
+	if uri == "" {
+		uri = DefaultURI
+	}
+
+	db, err := hermes.Connect(uri)
+	if err != nil {
+		b.Skipf("Unable to connect to %s: %s", uri, err)
+	}
+
+	if err := db.Ping(context.Background()); err != nil {
+		db.Shutdown()
+		b.Skipf("Unable to connect to %s: %s", uri, err)
+	}
+
+	b.Cleanup(db.Shutdown)
+
+	hermestest.Setup(b, db, `
+		create table hermes_bench_accounts (
+			id      bigint primary key,
+			name    text not null,
+			balance float8 not null
+		)`,
+		"drop table hermes_bench_accounts")
+
+	if _, err := db.CopyFrom(context.Background(), pgx.Identifier{"hermes_bench_accounts"},
+		[]string{"id", "name", "balance"}, accountRows(0, seedRows)); err != nil {
+		b.Fatalf("Unable to seed accounts: %s", err)
+	}
+
+	return db
+}
+
+// accountRows generates n accounts, with IDs starting at first.
+func accountRows(first, n int) pgx.CopyFromSource {
+	return hermestest.GenerateRows(n, func(i int) []interface{} {
+		id := int64(first + i)
+		return []interface{}{id, "account", float64(id) / 100}
+	})
+}
+
+func BenchmarkExec(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := db.Exec(ctx, "update hermes_bench_accounts set balance = balance + 1 where id = $1", int64(i%seedRows)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkExecPgx is the baseline for BenchmarkExec, calling the pool directly, to isolate the
+// cost of the hermes wrappers.
+func BenchmarkExecPgx(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := db.Pool.Exec(ctx, "update hermes_bench_accounts set balance = balance + 1 where id = $1", int64(i%seedRows)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecParallel(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	var latency hermestest.Latency
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		var i int64
+		for pb.Next() {
+			i++
+			err := latency.Time(func() error {
+				_, err := db.Exec(ctx, "update hermes_bench_accounts set balance = balance + 1 where id = $1", i%seedRows)
+				return err
+			})
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+
+	latency.Report(b)
+}
+
+func BenchmarkBeginFunc(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := db.BeginFunc(ctx, func(tx hermes.Conn) error {
+			_, err := tx.Exec(ctx, "update hermes_bench_accounts set balance = balance + 1 where id = $1", int64(i%seedRows))
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQueryRow(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+
+	var acct account
+	for i := 0; i < b.N; i++ {
+		err := db.QueryRow(ctx, "select id, name, balance from hermes_bench_accounts where id = $1", int64(i%seedRows)).
+			Scan(&acct.ID, &acct.Name, &acct.Balance)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQueryScan(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Query(ctx, "select id, name, balance from hermes_bench_accounts")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		accounts, err := hermes.CollectStructs[account](rows, seedRows)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(accounts) != seedRows {
+			b.Fatalf("Expected %d accounts; was %d", seedRows, len(accounts))
+		}
+	}
+}
+
+// BenchmarkQueryScanPgx is the baseline for BenchmarkQueryScan, using pgx's own struct scanning.
+func BenchmarkQueryScanPgx(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rows, err := db.Pool.Query(ctx, "select id, name, balance from hermes_bench_accounts")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		accounts, err := pgx.CollectRows(rows, pgx.RowToStructByPos[account])
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(accounts) != seedRows {
+			b.Fatalf("Expected %d accounts; was %d", seedRows, len(accounts))
+		}
+	}
+}
+
+func BenchmarkCopyFrom(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	const rows = 10000
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := db.CopyFrom(ctx, pgx.Identifier{"hermes_bench_accounts"}, []string{"id", "name", "balance"},
+			accountRows(seedRows+i*rows, rows)); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(rows*b.N)/b.Elapsed().Seconds(), "rows/s")
+}
+
+func BenchmarkCopyFromWorkers(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	const rows = 10000
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := hermes.CopyFromWithOptions(ctx, db, pgx.Identifier{"hermes_bench_accounts"},
+			[]string{"id", "name", "balance"}, accountRows(seedRows+i*rows, rows), hermes.CopyOptions{Workers: 4}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(rows*b.N)/b.Elapsed().Seconds(), "rows/s")
+}
+
+func BenchmarkSendBatch(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	const queries = 100
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var batch pgx.Batch
+		for j := 0; j < queries; j++ {
+			batch.Queue("update hermes_bench_accounts set balance = balance + 1 where id = $1", int64(j))
+		}
+
+		if err := db.SendBatch(ctx, &batch).Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLock(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		lock, err := db.Lock(ctx, uint64(i%seedRows))
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if err := lock.Release(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLockAll(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	ids := make([]uint64, 16)
+	for i := range ids {
+		ids[i] = uint64(i)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		lock, err := db.LockAll(ctx, ids...)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if err := lock.Release(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTxLock(b *testing.B) {
+	db := connect(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := db.BeginFunc(ctx, func(tx hermes.Conn) error {
+			_, err := tx.(*hermes.Tx).Lock(ctx, uint64(i%seedRows))
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Package benchmarks measures the throughput of the hermes wrappers against a local PostgreSQL
+// database, to catch performance regressions before release.  The package has no code of its
+// own; run the benchmarks with:
+//
+//	go test -run XXX -bench . -benchmem -count 10 ./benchmarks | tee new.txt
+//	benchstat baseline.txt new.txt
+//
+// The database defaults to postgres://localhost/hermes_test?sslmode=disable; set
+// HERMES_BENCH_URI to use another.  The benchmarks are skipped if the database isn't available.
+// See README.md for recording and comparing baselines.
+package benchmarks
@@ -0,0 +1,88 @@
+package hermes
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Borrowed is a text, varchar, or bytea value scanned without copying it:  the slice refers to
+// the driver's read buffer, so it's only valid until the next call to Rows.Next or Rows.Close.
+// Write it out or copy it before moving on to the next row.  NULL scans as nil.
+//
+// Borrowed saves copying large values in streaming or proxy code that writes the bytes straight
+// out, e.g. with ForEach.  Never scan a Borrowed from QueryRow, or from a Select or Collect
+// function, as the rows are gone by the time you read it.  Bytea values sent in the text format,
+// such as with the simple protocol, are decoded from hex and so are copied anyway.
+type Borrowed []byte
+
+// ScanBytes keeps a reference to the driver's bytes.  Implements pgtype.BytesScanner.
+func (b *Borrowed) ScanBytes(v []byte) error {
+	*b = v
+	return nil
+}
+
+// String returns a copy of the value as a string.
+func (b Borrowed) String() string {
+	return string(b)
+}
+
+// Clone returns a copy of the value that's safe to keep after the row.
+func (b Borrowed) Clone() []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append([]byte{}, b...)
+}
+
+// ForEach scans every row into the same struct of type T and calls fn with it, then closes the
+// rows.  Stops at the first error from fn and returns it.  See ScanStruct for how columns are
+// matched to fields.
+//
+// The struct is reused from row to row, so fn must not keep it.  Combined with Borrowed fields,
+// large values are read without copying or allocating a struct per row:
+//
+//	type File struct {
+//		Name string
+//		Data hermes.Borrowed
+//	}
+//
+//	rows, err := db.Query(ctx, "select name, data from files where bucket = $1", bucket)
+//	if err != nil {
+//		return err
+//	}
+//
+//	err = hermes.ForEach(rows, func(file *File) error {
+//		_, err := w.Write(file.Data)
+//		return err
+//	})
+func ForEach[T any](rows pgx.Rows, fn func(row *T) error) error {
+	defer rows.Close()
+
+	var dest T
+	v := reflect.ValueOf(&dest).Elem()
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot scan into %T; expected a struct", dest)
+	}
+
+	plan, err := planScan(v.Type(), fieldNames(rows))
+	if err != nil {
+		return err
+	}
+
+	targets := plan.targets(v, nil)
+
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+
+		if err := fn(&dest); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
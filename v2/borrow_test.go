@@ -0,0 +1,113 @@
+package hermes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestBorrowed(t *testing.T) {
+	m := pgtype.NewMap()
+
+	tests := []struct {
+		oid    uint32
+		format int16
+		src    []byte
+	}{
+		{pgtype.TextOID, pgtype.TextFormatCode, []byte("Hello, World")},
+		{pgtype.TextOID, pgtype.BinaryFormatCode, []byte("Hello, World")},
+		{pgtype.VarcharOID, pgtype.BinaryFormatCode, []byte("Hello, World")},
+		{pgtype.ByteaOID, pgtype.BinaryFormatCode, []byte("Hello, World")},
+	}
+
+	for _, test := range tests {
+		var b Borrowed
+		if err := m.Scan(test.oid, test.format, test.src, &b); err != nil {
+			t.Fatalf("Unable to scan OID %d in format %d: %s", test.oid, test.format, err)
+		}
+
+		if b.String() != "Hello, World" {
+			t.Errorf("Expected Hello, World; was %s", b)
+		}
+
+		if &b[0] != &test.src[0] {
+			t.Errorf("Expected OID %d in format %d to be borrowed, not copied", test.oid, test.format)
+		}
+
+		if clone := b.Clone(); &clone[0] == &test.src[0] {
+			t.Error("Expected Clone to copy the value")
+		}
+	}
+
+	var b Borrowed
+	if err := m.Scan(pgtype.ByteaOID, pgtype.TextFormatCode, []byte(`\x4869`), &b); err != nil {
+		t.Fatalf("Unable to scan text format bytea: %s", err)
+	}
+
+	if b.String() != "Hi" {
+		t.Errorf("Expected Hi; was %s", b)
+	}
+
+	b = Borrowed("stale")
+	if err := m.Scan(pgtype.TextOID, pgtype.TextFormatCode, nil, &b); err != nil {
+		t.Fatalf("Unable to scan NULL: %s", err)
+	}
+
+	if b != nil || b.Clone() != nil {
+		t.Errorf("Expected NULL to scan as nil; was %q", b)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	type file struct {
+		Name string
+		Data Borrowed
+	}
+
+	rows := &fakeRows{
+		columns: []string{"name", "data"},
+		values: [][]interface{}{
+			{"a.txt", Borrowed("first")},
+			{"b.txt", Borrowed("second")},
+			{"c.txt", Borrowed("third")},
+		},
+	}
+
+	var seen []*file
+	var names []string
+
+	err := ForEach(rows, func(f *file) error {
+		seen = append(seen, f)
+		names = append(names, f.Name+"="+f.Data.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unable to iterate over rows: %s", err)
+	}
+
+	if len(names) != 3 || names[0] != "a.txt=first" || names[2] != "c.txt=third" {
+		t.Errorf("Expected every row in order; was %v", names)
+	}
+
+	if seen[0] != seen[2] {
+		t.Error("Expected the struct to be reused from row to row")
+	}
+
+	if !rows.closed {
+		t.Error("Expected rows to be closed")
+	}
+
+	stop := errors.New("stop")
+	rows = &fakeRows{columns: []string{"name", "data"}, values: [][]interface{}{{"a.txt", Borrowed(nil)}, {"b.txt", Borrowed(nil)}}}
+
+	count := 0
+	err = ForEach(rows, func(f *file) error {
+		count++
+		return stop
+	})
+
+	if !errors.Is(err, stop) || count != 1 {
+		t.Errorf("Expected to stop at the first error; was %v after %d rows", err, count)
+	}
+}
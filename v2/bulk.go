@@ -0,0 +1,370 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BulkUpdate updates many rows of a table in a single statement.  Rather than running an UPDATE
+// per row, the new values are sent as one array per column and joined to the table server-side
+// with unnest(), which is dramatically faster for thousands of rows:
+//
+//	UPDATE accounts AS t SET balance = v.balance
+//	FROM unnest($1::bigint[], $2::numeric[]) AS v(id, balance)
+//	WHERE t.id = v.id
+//
+// Each row is a struct whose fields map to the table's columns, as with ScanStruct.  The row
+// with the matching keyColumn is updated with the values of every other mapped column, or only
+// the given columns.  Returns the number of rows updated.
+func BulkUpdate[T any](ctx context.Context, conn Conn, table, keyColumn string, rows []T, columns ...string) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	var zero T
+	structType := reflect.TypeOf(zero)
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("cannot update from %T; expected a struct", zero)
+	}
+
+	fields := make(map[string][]int)
+	structFields(structType, nil, fields)
+
+	if len(columns) == 0 {
+		columns = orderedColumns(fields)
+	}
+
+	// The key column always comes first
+	all := []string{keyColumn}
+	for _, column := range columns {
+		if column != keyColumn {
+			all = append(all, column)
+		}
+	}
+
+	if len(all) < 2 {
+		return 0, fmt.Errorf("no columns to update in %s", structType)
+	}
+
+	indexes, err := fieldIndexes(structType, fields, all)
+	if err != nil {
+		return 0, err
+	}
+
+	types, err := columnTypes(ctx, conn, table, all)
+	if err != nil {
+		return 0, err
+	}
+
+	arrays := make([]interface{}, len(all))
+	for i, index := range indexes {
+		values := make([]interface{}, len(rows))
+		for j := range rows {
+			values[j] = reflect.ValueOf(&rows[j]).Elem().FieldByIndex(index).Interface()
+		}
+		arrays[i] = values
+	}
+
+	tag, err := conn.Exec(ctx, bulkUpdateSQL(table, all, types), arrays...)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ErrNoConflictColumns is returned by CopyUpsert and Upsert if they aren't given the columns to
+// match existing rows on.
+var ErrNoConflictColumns = errors.New("no conflict columns")
+
+// upsertTables counts the temporary tables created by CopyUpsert and Upsert, to name them
+// uniquely.
+var upsertTables int64
+
+// CopyUpsert inserts or updates many rows of a table at once.  The rows are copied into a
+// temporary table with COPY, then merged into the table with a single statement:
+//
+//	INSERT INTO accounts (id, balance) SELECT ... FROM hermes_upsert_1
+//	ON CONFLICT (id) DO UPDATE SET balance = EXCLUDED.balance
+//
+// That's far faster than an upsert per row for thousands of rows.  Each row is a struct whose
+// fields map to the table's columns, as with ScanStruct.  Every mapped column is copied, or only
+// the given columns, along with the conflictColumns, which must match a unique index on the
+// table.  Rows that conflict with an existing row update the rest of the columns; if the
+// conflict columns are all there is, those rows are skipped.  If rows repeat a key, the last one
+// wins.
+//
+// The copy and merge run in a transaction, or a savepoint if conn is a transaction.  Returns the
+// number of rows inserted or updated.
+func CopyUpsert[T any](ctx context.Context, conn Conn, table string, conflictColumns []string, rows []T, columns ...string) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	if len(conflictColumns) == 0 {
+		return 0, fmt.Errorf("%w: unable to upsert into %s", ErrNoConflictColumns, table)
+	}
+
+	var zero T
+	structType := reflect.TypeOf(zero)
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("cannot upsert from %T; expected a struct", zero)
+	}
+
+	fields := make(map[string][]int)
+	structFields(structType, nil, fields)
+
+	if len(columns) == 0 {
+		columns = orderedColumns(fields)
+	}
+
+	all := append([]string{}, columns...)
+	for _, column := range conflictColumns {
+		if !contains(all, column) {
+			all = append(all, column)
+		}
+	}
+
+	indexes, err := fieldIndexes(structType, fields, all)
+	if err != nil {
+		return 0, err
+	}
+
+	// pgx encodes each row before asking for the next, so the values can be reused
+	values := make([]interface{}, len(all))
+	src := pgx.CopyFromSlice(len(rows), func(i int) ([]interface{}, error) {
+		row := reflect.ValueOf(&rows[i]).Elem()
+		for j, index := range indexes {
+			values[j] = row.FieldByIndex(index).Interface()
+		}
+		return values, nil
+	})
+
+	return upsert(ctx, conn, table, all, conflictColumns, src)
+}
+
+// Upsert inserts or updates many rows of a table at once, like CopyUpsert, but with the rows'
+// values supplied by a pgx.CopyFromSource, such as pgx.CopyFromRows, in the order of the columns:
+//
+//	rows := [][]interface{}{
+//		{1, "Alice", 12.5},
+//		{2, "Bob", 7.0},
+//	}
+//
+//	count, err := hermes.Upsert(ctx, conn, "accounts", []string{"id", "name", "balance"},
+//		[]string{"id"}, pgx.CopyFromRows(rows))
+//
+// The conflictColumns must be among the columns, and match a unique index on the table.  Rows
+// that conflict with an existing row update the rest of the columns.  The copy and merge run in a
+// transaction, or a savepoint if conn is a transaction.  Returns the number of rows inserted or
+// updated.
+func Upsert(ctx context.Context, conn Conn, table string, columns, conflictColumns []string, rows pgx.CopyFromSource) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(conflictColumns) == 0 {
+		return 0, fmt.Errorf("%w: unable to upsert into %s", ErrNoConflictColumns, table)
+	}
+
+	for _, column := range conflictColumns {
+		if !contains(columns, column) {
+			return 0, fmt.Errorf("%w: %s isn't one of the columns upserted into %s", ErrNoConflictColumns, column, table)
+		}
+	}
+
+	return upsert(ctx, conn, table, columns, conflictColumns, rows)
+}
+
+// upsert copies the rows into a temporary table and merges them into the table, in a transaction.
+func upsert(ctx context.Context, conn Conn, table string, columns, conflictColumns []string, src pgx.CopyFromSource) (int64, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Close(ctx)
+
+	temp := fmt.Sprintf("hermes_upsert_%d", atomic.AddInt64(&upsertTables, 1))
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA",
+		temp, quoteColumns(columns), pgx.Identifier(strings.Split(table, ".")).Sanitize())); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{temp}, columns, src); err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, copyUpsertSQL(table, temp, columns, conflictColumns))
+	if err != nil {
+		return 0, err
+	}
+
+	// Dropped on commit anyway, but a savepoint's commit isn't the end of the transaction
+	if _, err := tx.Exec(ctx, "DROP TABLE "+temp); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// copyUpsertSQL returns the statement merging the temporary table into the table, keeping the
+// last row copied for each key.
+func copyUpsertSQL(table, temp string, columns, conflictColumns []string) string {
+	var sets []string
+	for _, column := range columns {
+		if !contains(conflictColumns, column) {
+			quoted := pgx.Identifier{column}.Sanitize()
+			sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", quoted, quoted))
+		}
+	}
+
+	action := "DO NOTHING"
+	if len(sets) > 0 {
+		action = "DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+
+	keys := quoteColumns(conflictColumns)
+
+	return fmt.Sprintf("INSERT INTO %s (%s) SELECT DISTINCT ON (%s) %s FROM %s ORDER BY %s, ctid DESC ON CONFLICT (%s) %s",
+		pgx.Identifier(strings.Split(table, ".")).Sanitize(),
+		quoteColumns(columns), keys, quoteColumns(columns), temp, keys, keys, action)
+}
+
+// fieldIndexes returns the index of the struct field for each column.  Returns
+// ErrUnmappedColumn if a column doesn't match a field.
+func fieldIndexes(structType reflect.Type, fields map[string][]int, columns []string) ([][]int, error) {
+	indexes := make([][]int, len(columns))
+	for i, column := range columns {
+		index, ok := fields[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s in %s", ErrUnmappedColumn, column, structType)
+		}
+		indexes[i] = index
+	}
+
+	return indexes, nil
+}
+
+// quoteColumns returns the quoted column names, separated by commas.
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// contains returns true if the column is in the list.
+func contains(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bulkUpdateSQL returns the UPDATE statement joining the table to the unnested arrays on the
+// first column.
+func bulkUpdateSQL(table string, columns, types []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+
+	sets := make([]string, 0, len(columns)-1)
+	for _, column := range quoted[1:] {
+		sets = append(sets, fmt.Sprintf("%s = v.%s", column, column))
+	}
+
+	params := make([]string, len(columns))
+	for i, t := range types {
+		params[i] = fmt.Sprintf("$%d::%s[]", i+1, t)
+	}
+
+	return fmt.Sprintf("UPDATE %s AS t SET %s FROM unnest(%s) AS v(%s) WHERE t.%s = v.%s",
+		pgx.Identifier(strings.Split(table, ".")).Sanitize(),
+		strings.Join(sets, ", "),
+		strings.Join(params, ", "),
+		strings.Join(quoted, ", "),
+		quoted[0], quoted[0])
+}
+
+// columnTypes looks up the types of the table's columns, in order, to cast the arrays to.
+func columnTypes(ctx context.Context, conn Conn, table string, columns []string) ([]string, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT attname::text, format_type(atttypid, atttypmod)
+		FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attname = ANY($2) AND attnum > 0 AND NOT attisdropped`,
+		pgx.Identifier(strings.Split(table, ".")).Sanitize(), columns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]string, len(columns))
+	for rows.Next() {
+		var name, t string
+		if err := rows.Scan(&name, &t); err != nil {
+			return nil, err
+		}
+		found[name] = t
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	types := make([]string, len(columns))
+	for i, column := range columns {
+		t, ok := found[column]
+		if !ok {
+			return nil, fmt.Errorf("column %s doesn't exist in %s", column, table)
+		}
+		types[i] = t
+	}
+
+	return types, nil
+}
+
+// orderedColumns returns the mapped columns in the order their fields are declared.
+func orderedColumns(fields map[string][]int) []string {
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		columns = append(columns, column)
+	}
+
+	sort.Slice(columns, func(i, j int) bool {
+		a, b := fields[columns[i]], fields[columns[j]]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	return columns
+}
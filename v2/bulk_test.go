@@ -0,0 +1,251 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// bulkConn returns the column types of the accounts table and records the update.
+type bulkConn struct {
+	Conn
+
+	sql  string
+	args []interface{}
+}
+
+func (c *bulkConn) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return &fakeRows{
+		columns: []string{"attname", "format_type"},
+		values: [][]interface{}{
+			{"display_name", "text"},
+			{"balance", "numeric(12,2)"},
+			{"id", "bigint"},
+		},
+	}, nil
+}
+
+func (c *bulkConn) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	c.sql, c.args = sql, args
+	return pgconn.NewCommandTag("UPDATE 2"), nil
+}
+
+func TestBulkUpdate(t *testing.T) {
+	conn := &bulkConn{}
+	rows := []account{
+		{ID: 1, Name: "Alice", Balance: 12.5},
+		{ID: 2, Name: "Bob", Balance: 7},
+	}
+
+	count, err := BulkUpdate(context.Background(), conn, "app.accounts", "id", rows, "display_name", "balance")
+	if err != nil {
+		t.Fatalf("Unable to update: %s", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 rows updated; was %d", count)
+	}
+
+	expected := `UPDATE "app"."accounts" AS t SET "display_name" = v."display_name", "balance" = v."balance" ` +
+		`FROM unnest($1::bigint[], $2::text[], $3::numeric(12,2)[]) AS v("id", "display_name", "balance") ` +
+		`WHERE t."id" = v."id"`
+	if conn.sql != expected {
+		t.Errorf("Expected %s; was %s", expected, conn.sql)
+	}
+
+	if len(conn.args) != 3 {
+		t.Fatalf("Expected an array per column; was %v", conn.args)
+	}
+
+	names := conn.args[1].([]interface{})
+	if len(names) != 2 || names[0] != "Alice" || names[1] != "Bob" {
+		t.Errorf("Expected [Alice Bob]; was %v", names)
+	}
+}
+
+func TestBulkUpdateColumns(t *testing.T) {
+	if _, err := BulkUpdate(context.Background(), &bulkConn{}, "accounts", "id", []account{{ID: 1}}); err == nil {
+		t.Error("Expected an error for columns missing from the table")
+	}
+
+	_, err := BulkUpdate(context.Background(), &bulkConn{}, "accounts", "id", []account{{ID: 1}}, "missing")
+	if !errors.Is(err, ErrUnmappedColumn) {
+		t.Errorf("Expected ErrUnmappedColumn; was %v", err)
+	}
+
+	if count, err := BulkUpdate[account](context.Background(), &bulkConn{}, "accounts", "id", nil); err != nil || count != 0 {
+		t.Errorf("Expected nothing to update; was %d, %v", count, err)
+	}
+}
+
+func TestOrderedColumns(t *testing.T) {
+	fields := make(map[string][]int)
+	structFields(reflect.TypeOf(account{}), nil, fields)
+
+	columns := orderedColumns(fields)
+	expected := []string{"created_at", "updated_at", "id", "display_name", "balance"}
+
+	if len(columns) != len(expected) {
+		t.Fatalf("Expected %v; was %v", expected, columns)
+	}
+
+	for i := range expected {
+		if columns[i] != expected[i] {
+			t.Errorf("Expected %v; was %v", expected, columns)
+			break
+		}
+	}
+}
+
+// upsertConn records the statements and rows of a CopyUpsert.
+type upsertConn struct {
+	Conn
+
+	execs     []string
+	table     pgx.Identifier
+	columns   []string
+	copied    [][]interface{}
+	committed bool
+}
+
+func (c *upsertConn) Begin(context.Context) (Conn, error) { return c, nil }
+func (c *upsertConn) Close(context.Context) error         { return nil }
+
+func (c *upsertConn) Commit(context.Context) error {
+	c.committed = true
+	return nil
+}
+
+func (c *upsertConn) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	c.execs = append(c.execs, sql)
+	return pgconn.NewCommandTag("INSERT 0 2"), nil
+}
+
+func (c *upsertConn) CopyFrom(_ context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error) {
+	c.table, c.columns = table, columns
+
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			return 0, err
+		}
+		c.copied = append(c.copied, append([]interface{}{}, values...))
+	}
+
+	return int64(len(c.copied)), nil
+}
+
+func TestCopyUpsert(t *testing.T) {
+	conn := &upsertConn{}
+	rows := []account{
+		{ID: 1, Name: "Alice", Balance: 12.5},
+		{ID: 2, Name: "Bob", Balance: 7},
+	}
+
+	count, err := CopyUpsert(context.Background(), conn, "app.accounts", []string{"id"}, rows, "display_name", "balance")
+	if err != nil {
+		t.Fatalf("Unable to upsert: %s", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 rows upserted; was %d", count)
+	}
+
+	if !conn.committed {
+		t.Error("Expected the upsert to be committed")
+	}
+
+	if len(conn.execs) != 3 {
+		t.Fatalf("Expected create, insert, and drop statements; was %v", conn.execs)
+	}
+
+	temp := conn.table[0]
+
+	create := `CREATE TEMP TABLE ` + temp + ` ON COMMIT DROP AS SELECT "display_name", "balance", "id" FROM "app"."accounts" WITH NO DATA`
+	if conn.execs[0] != create {
+		t.Errorf("Expected %s; was %s", create, conn.execs[0])
+	}
+
+	insert := `INSERT INTO "app"."accounts" ("display_name", "balance", "id") ` +
+		`SELECT DISTINCT ON ("id") "display_name", "balance", "id" FROM ` + temp + ` ORDER BY "id", ctid DESC ` +
+		`ON CONFLICT ("id") DO UPDATE SET "display_name" = EXCLUDED."display_name", "balance" = EXCLUDED."balance"`
+	if conn.execs[1] != insert {
+		t.Errorf("Expected %s; was %s", insert, conn.execs[1])
+	}
+
+	if conn.execs[2] != "DROP TABLE "+temp {
+		t.Errorf("Expected the temporary table to be dropped; was %s", conn.execs[2])
+	}
+
+	expected := [][]interface{}{{"Alice", 12.5, int64(1)}, {"Bob", 7.0, int64(2)}}
+	if !reflect.DeepEqual(conn.copied, expected) {
+		t.Errorf("Expected %v copied; was %v", expected, conn.copied)
+	}
+}
+
+func TestCopyUpsertNothingToUpdate(t *testing.T) {
+	sql := copyUpsertSQL("tags", "hermes_upsert_1", []string{"name"}, []string{"name"})
+
+	expected := `INSERT INTO "tags" ("name") SELECT DISTINCT ON ("name") "name" FROM hermes_upsert_1 ` +
+		`ORDER BY "name", ctid DESC ON CONFLICT ("name") DO NOTHING`
+	if sql != expected {
+		t.Errorf("Expected %s; was %s", expected, sql)
+	}
+
+	_, err := CopyUpsert(context.Background(), &upsertConn{}, "tags", nil, []account{{ID: 1}})
+	if !errors.Is(err, ErrNoConflictColumns) {
+		t.Errorf("Expected ErrNoConflictColumns; was %v", err)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	conn := &upsertConn{}
+	rows := [][]interface{}{
+		{1, "Alice", 12.5},
+		{2, "Bob", 7.0},
+	}
+
+	count, err := Upsert(context.Background(), conn, "accounts", []string{"id", "name", "balance"}, []string{"id"}, pgx.CopyFromRows(rows))
+	if err != nil {
+		t.Fatalf("Unable to upsert: %s", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 rows upserted; was %d", count)
+	}
+
+	if !conn.committed {
+		t.Error("Expected the upsert to be committed")
+	}
+
+	if !reflect.DeepEqual(conn.columns, []string{"id", "name", "balance"}) {
+		t.Errorf("Expected the columns copied in order; was %v", conn.columns)
+	}
+
+	if !reflect.DeepEqual(conn.copied, rows) {
+		t.Errorf("Expected %v copied; was %v", rows, conn.copied)
+	}
+
+	insert := `INSERT INTO "accounts" ("id", "name", "balance") ` +
+		`SELECT DISTINCT ON ("id") "id", "name", "balance" FROM ` + conn.table[0] + ` ORDER BY "id", ctid DESC ` +
+		`ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "balance" = EXCLUDED."balance"`
+	if len(conn.execs) != 3 || conn.execs[1] != insert {
+		t.Errorf("Expected %s; was %v", insert, conn.execs)
+	}
+}
+
+func TestUpsertConflictColumns(t *testing.T) {
+	_, err := Upsert(context.Background(), &upsertConn{}, "accounts", []string{"name"}, []string{"id"}, pgx.CopyFromRows(nil))
+	if !errors.Is(err, ErrNoConflictColumns) {
+		t.Errorf("Expected ErrNoConflictColumns; was %v", err)
+	}
+
+	_, err = Upsert(context.Background(), &upsertConn{}, "accounts", []string{"name"}, nil, pgx.CopyFromRows(nil))
+	if !errors.Is(err, ErrNoConflictColumns) {
+		t.Errorf("Expected ErrNoConflictColumns; was %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultByteaChunk is the default number of bytes a Bytea reads or writes per query.
+const DefaultByteaChunk = 1024 * 1024
+
+// Bytea streams a large bytea value in a single row to or from the database in chunks, so the
+// whole value never has to fit in memory.  Values are written by appending each chunk to the
+// column, and read with substring.
+//
+// Reading a chunk of a compressed value decompresses it from the start, and appending a chunk
+// rewrites the value so far.  For values over a few megabytes, store the column uncompressed so
+// PostgreSQL can read just the chunks it needs:
+//
+//	ALTER TABLE files ALTER COLUMN data SET STORAGE EXTERNAL
+type Bytea struct {
+	// Table holding the value, optionally qualified with its schema, e.g. "app.files".
+	Table string
+
+	// Column holding the value.
+	Column string
+
+	// KeyColumn identifies the row.  Defaults to "id".
+	KeyColumn string
+
+	// Key is the value of KeyColumn in the row holding the value.
+	Key interface{}
+
+	// ChunkSize is the number of bytes read or written per query.  Defaults to
+	// DefaultByteaChunk.
+	ChunkSize int
+}
+
+// NewBytea returns a Bytea for the value in the table's column, in the row whose id is key.
+func NewBytea(table, column string, key interface{}) *Bytea {
+	return &Bytea{Table: table, Column: column, KeyColumn: "id", Key: key, ChunkSize: DefaultByteaChunk}
+}
+
+// Write replaces the value with the contents of r, appending a chunk at a time in a transaction
+// (or a savepoint, if conn is a transaction), so a failed write leaves the value unchanged.
+// Returns the number of bytes written.  The row must already exist; if it doesn't, returns
+// pgx.ErrNoRows.
+func (b *Bytea) Write(ctx context.Context, conn Conn, r io.Reader) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Close(ctx)
+
+	table, column, key := b.identifiers()
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`UPDATE %s SET %s = ''::bytea WHERE %s = $1`, table, column, key),
+		b.Key)
+	if err != nil {
+		return 0, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return 0, pgx.ErrNoRows
+	}
+
+	appendSQL := fmt.Sprintf(`UPDATE %s SET %s = %s || $2 WHERE %s = $1`, table, column, column, key)
+
+	buf := make([]byte, b.chunkSize())
+
+	var written int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if _, err := tx.Exec(ctx, appendSQL, b.Key, buf[:n]); err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		} else if err != nil {
+			return written, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
+
+// Reader returns a reader that fetches the value a chunk at a time as it's read.  A NULL value
+// reads as empty.  If the row doesn't exist, the first Read returns pgx.ErrNoRows.  The value may
+// change between chunks unless conn is a transaction with repeatable read isolation.
+func (b *Bytea) Reader(ctx context.Context, conn Conn) io.Reader {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	table, column, key := b.identifiers()
+
+	return &byteaReader{
+		ctx:   ctx,
+		conn:  conn,
+		sql:   fmt.Sprintf(`SELECT substring(%s FROM $2 FOR $3) FROM %s WHERE %s = $1`, column, table, key),
+		key:   b.Key,
+		chunk: b.chunkSize(),
+	}
+}
+
+// Size returns the length of the value in bytes, or -1 if it's NULL.
+func (b *Bytea) Size(ctx context.Context, conn Conn) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	table, column, key := b.identifiers()
+
+	var size *int64
+	if err := conn.QueryRow(ctx, fmt.Sprintf(`SELECT octet_length(%s) FROM %s WHERE %s = $1`,
+		column, table, key), b.Key).Scan(&size); err != nil {
+		return 0, err
+	}
+
+	if size == nil {
+		return -1, nil
+	}
+
+	return *size, nil
+}
+
+// identifiers returns the sanitized table, column, and key column names.
+func (b *Bytea) identifiers() (string, string, string) {
+	keyColumn := b.KeyColumn
+	if keyColumn == "" {
+		keyColumn = "id"
+	}
+
+	return pgx.Identifier(strings.Split(b.Table, ".")).Sanitize(),
+		pgx.Identifier{b.Column}.Sanitize(),
+		pgx.Identifier{keyColumn}.Sanitize()
+}
+
+// chunkSize returns the configured chunk size, or the default.
+func (b *Bytea) chunkSize() int {
+	if b.ChunkSize <= 0 {
+		return DefaultByteaChunk
+	}
+	return b.ChunkSize
+}
+
+// byteaReader reads a bytea value a chunk at a time.
+type byteaReader struct {
+	ctx   context.Context
+	conn  Conn
+	sql   string
+	key   interface{}
+	chunk int
+
+	// offset is the position of the next chunk in the value, starting at 1 as in SQL
+	offset int64
+	buf    []byte
+	done   bool
+	err    error
+}
+
+func (r *byteaReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 && !r.done && r.err == nil {
+		r.fetch()
+	}
+
+	if len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		return 0, io.EOF
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
+
+// fetch reads the next chunk of the value into the buffer.
+func (r *byteaReader) fetch() {
+	if r.offset == 0 {
+		r.offset = 1
+	}
+
+	var chunk []byte
+	if err := r.conn.QueryRow(r.ctx, r.sql, r.key, r.offset, r.chunk).Scan(&chunk); err != nil {
+		r.err = err
+		return
+	}
+
+	r.buf = chunk
+	r.offset += int64(len(chunk))
+	r.done = len(chunk) < r.chunk
+}
@@ -0,0 +1,142 @@
+package hermes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// byteaConn fakes a table with a single bytea value, updated and queried the way Bytea does.
+type byteaConn struct {
+	Conn
+
+	value     []byte
+	exists    bool
+	committed bool
+	queries   int
+}
+
+func (c *byteaConn) Begin(context.Context) (Conn, error) { return c, nil }
+func (c *byteaConn) Close(context.Context) error         { return nil }
+
+func (c *byteaConn) Commit(context.Context) error {
+	c.committed = true
+	return nil
+}
+
+func (c *byteaConn) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if !c.exists {
+		return pgconn.NewCommandTag("UPDATE 0"), nil
+	}
+
+	if strings.Contains(sql, "||") {
+		c.value = append(c.value, args[1].([]byte)...)
+	} else {
+		c.value = []byte{}
+	}
+
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (c *byteaConn) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	c.queries++
+	return byteaRow{conn: c, args: args}
+}
+
+type byteaRow struct {
+	conn *byteaConn
+	args []interface{}
+}
+
+func (r byteaRow) Scan(dest ...interface{}) error {
+	if !r.conn.exists {
+		return pgx.ErrNoRows
+	}
+
+	start := int(r.args[1].(int64)) - 1
+	end := start + r.args[2].(int)
+
+	if start > len(r.conn.value) {
+		start = len(r.conn.value)
+	}
+	if end > len(r.conn.value) {
+		end = len(r.conn.value)
+	}
+
+	*dest[0].(*[]byte) = r.conn.value[start:end]
+	return nil
+}
+
+func TestByteaWrite(t *testing.T) {
+	conn := &byteaConn{value: []byte("old"), exists: true}
+
+	data := bytes.Repeat([]byte("0123456789"), 25)
+
+	b := NewBytea("app.files", "data", 1)
+	b.ChunkSize = 100
+
+	n, err := b.Write(context.Background(), conn, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unable to write bytea: %s", err)
+	}
+
+	if n != int64(len(data)) {
+		t.Errorf("Expected %d bytes written; was %d", len(data), n)
+	}
+
+	if !bytes.Equal(conn.value, data) {
+		t.Errorf("Expected value to be replaced; was %q", conn.value)
+	}
+
+	if !conn.committed {
+		t.Error("Expected write to be committed")
+	}
+}
+
+func TestByteaWriteMissing(t *testing.T) {
+	conn := &byteaConn{}
+
+	if _, err := NewBytea("files", "data", 1).Write(context.Background(), conn, strings.NewReader("x")); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Expected pgx.ErrNoRows; was %v", err)
+	}
+
+	if _, err := io.ReadAll(NewBytea("files", "data", 1).Reader(context.Background(), conn)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Expected pgx.ErrNoRows; was %v", err)
+	}
+}
+
+func TestByteaReader(t *testing.T) {
+	for _, size := range []int{0, 99, 100, 250} {
+		conn := &byteaConn{value: bytes.Repeat([]byte("x"), size), exists: true}
+
+		b := NewBytea("files", "data", 1)
+		b.ChunkSize = 100
+
+		data, err := io.ReadAll(b.Reader(context.Background(), conn))
+		if err != nil {
+			t.Fatalf("Unable to read %d bytes: %s", size, err)
+		}
+
+		if len(data) != size {
+			t.Errorf("Expected %d bytes; was %d", size, len(data))
+		}
+
+		if expected := size/100 + 1; conn.queries != expected {
+			t.Errorf("Expected %d queries for %d bytes; was %d", expected, size, conn.queries)
+		}
+	}
+}
+
+func TestByteaIdentifiers(t *testing.T) {
+	table, column, key := (&Bytea{Table: "app.files", Column: "Data"}).identifiers()
+
+	if table != `"app"."files"` || column != `"Data"` || key != `"id"` {
+		t.Errorf(`Expected "app"."files", "Data", "id"; was %s, %s, %s`, table, column, key)
+	}
+}
@@ -0,0 +1,423 @@
+// Package cdc captures row changes from PostgreSQL using logical replication.  A Consumer
+// creates (or reuses) a logical replication slot, streams the write-ahead log through the
+// built-in pgoutput plugin, and hands each committed transaction's inserts, updates, and deletes
+// to a handler.  Once the handler returns, the transaction's position in the log is acknowledged
+// to PostgreSQL, so a restarted Consumer picks up where the last one left off.
+//
+// The database must be configured with wal_level = logical, and the tables to capture must be
+// added to a publication:
+//
+//	CREATE PUBLICATION app_changes FOR TABLE users, accounts;
+//
+// The replication protocol and pgoutput messages are decoded by this package itself, on top of
+// pgconn, rather than with github.com/jackc/pglogrepl, to avoid the extra dependency.  Only
+// pgoutput protocol version 1 is supported:  no streamed in-progress transactions, two-phase
+// commits, or binary column values.
+package cdc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// Op is the type of a row change.
+type Op string
+
+// Row change types.
+const (
+	Insert   Op = "INSERT"
+	Update   Op = "UPDATE"
+	Delete   Op = "DELETE"
+	Truncate Op = "TRUNCATE"
+)
+
+const (
+	// How often the Consumer reports its progress to PostgreSQL by default.
+	defaultStatusInterval = 10 * time.Second
+
+	// Reconnect delays back off between these values.
+	minReconnectDelay = 100 * time.Millisecond
+	maxReconnectDelay = 30 * time.Second
+
+	// SQLSTATE returned when creating a replication slot that already exists.
+	duplicateObject = "42710"
+)
+
+// Change is a single row change.
+type Change struct {
+	Op     Op
+	Schema string
+	Table  string
+
+	// New holds the row's column values after an insert or update.
+	New map[string]interface{}
+
+	// Old holds the row's previous values for an update or delete.  Unless the table's
+	// REPLICA IDENTITY is FULL, only the primary key columns are included, and for updates only
+	// if the key changed.
+	Old map[string]interface{}
+}
+
+// Scan copies the change's column values into the fields of the struct dest points to.  Fields
+// are matched to columns by their `db` tag, or by their lowercased name.  For deletes, the old
+// values are used.  Values are converted only within the same kind, e.g. an int32 column into an
+// int field or a string into a named string type, and numbers only if they fit; anything else,
+// such as an integer into a string field or a float into an int, is an error.
+func (change *Change) Scan(dest interface{}) error {
+	values := change.New
+	if change.Op == Delete {
+		values = change.Old
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("cdc: scan destination must be a pointer to a struct")
+	}
+
+	v = v.Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Tag.Get("db")
+		if name == "-" {
+			continue
+		} else if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		value, ok := values[name]
+		if !ok || value == nil {
+			continue
+		}
+
+		src := reflect.ValueOf(value)
+		dst := v.Field(i)
+
+		switch {
+		case src.Type().AssignableTo(dst.Type()):
+			dst.Set(src)
+		case convertible(src, dst.Type()):
+			dst.Set(src.Convert(dst.Type()))
+		default:
+			return fmt.Errorf("cdc: cannot assign %s column %s to field %s of type %s", src.Type(), name,
+				field.Name, dst.Type())
+		}
+	}
+
+	return nil
+}
+
+// convertible returns true if src may be converted to typ without changing its meaning:  both are
+// signed integers, unsigned integers, or floats, and the value fits, or both are the same kind
+// otherwise.  reflect's own conversions turn integers into strings as runes and truncate floats.
+func convertible(src reflect.Value, typ reflect.Type) bool {
+	if !src.Type().ConvertibleTo(typ) {
+		return false
+	}
+
+	switch kind(src.Kind()) {
+	case reflect.Int:
+		return kind(typ.Kind()) == reflect.Int && !reflect.Zero(typ).OverflowInt(src.Int())
+	case reflect.Uint:
+		return kind(typ.Kind()) == reflect.Uint && !reflect.Zero(typ).OverflowUint(src.Uint())
+	case reflect.Float64:
+		return kind(typ.Kind()) == reflect.Float64 && !reflect.Zero(typ).OverflowFloat(src.Float())
+	default:
+		return src.Kind() == typ.Kind()
+	}
+}
+
+// kind groups the sized numeric kinds, e.g. reflect.Int32 is reflect.Int.
+func kind(k reflect.Kind) reflect.Kind {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.Uint
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	default:
+		return k
+	}
+}
+
+// Transaction is the set of changes made by a committed transaction.
+type Transaction struct {
+	XID        uint32
+	LSN        LSN
+	EndLSN     LSN
+	CommitTime time.Time
+	Changes    []Change
+}
+
+// Handler processes a committed transaction's changes.  If the handler returns an error, the
+// Consumer stops and the transaction will be delivered again when the Consumer restarts, so
+// handlers should be idempotent.
+type Handler func(ctx context.Context, tx *Transaction) error
+
+// Consumer streams committed changes from a logical replication slot.  Configure its fields
+// before calling Run.
+type Consumer struct {
+	// Slot is the name of the logical replication slot.  Created if it doesn't exist.
+	Slot string
+
+	// Publication is the name of the publication whose tables are captured.
+	Publication string
+
+	// StatusInterval is how often the Consumer reports its progress to PostgreSQL.  Defaults to
+	// 10 seconds.
+	StatusInterval time.Duration
+
+	// OnError is called with connection errors the Consumer recovers from by reconnecting, for
+	// logging.
+	OnError func(err error)
+
+	config *pgconn.Config
+
+	// acknowledged is the end of the last transaction the handler processed
+	acknowledged LSN
+}
+
+// NewConsumer creates a Consumer using the connection settings of the database pool.  The
+// database user must have the REPLICATION attribute.
+func NewConsumer(db *hermes.DB, slot, publication string) *Consumer {
+	config := db.Config().ConnConfig.Config.Copy()
+
+	if config.RuntimeParams == nil {
+		config.RuntimeParams = make(map[string]string)
+	}
+	config.RuntimeParams["replication"] = "database"
+
+	return &Consumer{
+		Slot:        slot,
+		Publication: publication,
+		config:      config,
+	}
+}
+
+// Run streams changes to the handler until ctx is canceled or the handler returns an error.  If
+// the replication connection is lost, Run reconnects and resumes from the last acknowledged
+// transaction.  Returns nil if ctx was canceled, or the handler's error.
+func (c *Consumer) Run(ctx context.Context, handler Handler) error {
+	delay := minReconnectDelay
+
+	for {
+		started, err := c.stream(ctx, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var handlerErr *handlerError
+		if errors.As(err, &handlerErr) {
+			return handlerErr.err
+		}
+
+		if started {
+			delay = minReconnectDelay
+		}
+
+		if c.OnError != nil {
+			c.OnError(err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		if delay *= 2; delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// DropSlot removes the Consumer's replication slot.  PostgreSQL retains write-ahead log for a
+// slot until it's consumed, so drop slots that are no longer needed.
+func (c *Consumer) DropSlot(ctx context.Context) error {
+	conn, err := pgconn.ConnectConfig(ctx, c.config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "DROP_REPLICATION_SLOT "+pgx.Identifier{c.Slot}.Sanitize()).ReadAll()
+	return err
+}
+
+// handlerError distinguishes handler failures from connection failures.
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string {
+	return e.err.Error()
+}
+
+// stream connects, starts replication, and delivers transactions until an error occurs.  Returns
+// whether replication started, to reset the reconnect delay.
+func (c *Consumer) stream(ctx context.Context, handler Handler) (bool, error) {
+	conn, err := pgconn.ConnectConfig(ctx, c.config)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.Background())
+
+	if err := c.createSlot(ctx, conn); err != nil {
+		return false, err
+	}
+
+	if err := c.startReplication(ctx, conn); err != nil {
+		return false, err
+	}
+
+	interval := c.StatusInterval
+	if interval <= 0 {
+		interval = defaultStatusInterval
+	}
+
+	dec := newDecoder()
+	nextStatus := time.Now().Add(interval)
+
+	for {
+		if time.Now().After(nextStatus) {
+			if err := c.sendStatus(conn); err != nil {
+				return true, err
+			}
+			nextStatus = time.Now().Add(interval)
+		}
+
+		recvCtx, cancel := context.WithDeadline(ctx, nextStatus)
+		msg, err := conn.ReceiveMessage(recvCtx)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return true, ctx.Err()
+			}
+
+			if pgconn.Timeout(err) {
+				continue
+			}
+
+			return true, err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.CopyData:
+			if len(msg.Data) == 0 {
+				continue
+			}
+
+			switch msg.Data[0] {
+			case 'k':
+				// Primary keepalive: wal end, server time, reply requested
+				if len(msg.Data) >= 18 && msg.Data[17] != 0 {
+					nextStatus = time.Now()
+				}
+
+			case 'w':
+				// XLogData: wal start, wal end, server time, then the pgoutput message
+				if len(msg.Data) < 25 {
+					return true, errShortMessage
+				}
+
+				tx, err := dec.decode(msg.Data[25:])
+				if err != nil {
+					return true, err
+				}
+
+				if tx != nil {
+					if err := handler(ctx, tx); err != nil {
+						return true, &handlerError{err}
+					}
+
+					c.acknowledged = tx.EndLSN
+				}
+			}
+
+		case *pgproto3.ErrorResponse:
+			return true, pgconn.ErrorResponseToPgError(msg)
+
+		case *pgproto3.CopyDone:
+			return true, errors.New("replication stream ended by the server")
+		}
+	}
+}
+
+// createSlot creates the logical replication slot if it doesn't already exist.
+func (c *Consumer) createSlot(ctx context.Context, conn *pgconn.PgConn) error {
+	sql := fmt.Sprintf("CREATE_REPLICATION_SLOT %s LOGICAL pgoutput", pgx.Identifier{c.Slot}.Sanitize())
+
+	_, err := conn.Exec(ctx, sql).ReadAll()
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == duplicateObject {
+		return nil
+	}
+
+	return err
+}
+
+// startReplication switches the connection into streaming replication mode.
+func (c *Consumer) startReplication(ctx context.Context, conn *pgconn.PgConn) error {
+	sql := fmt.Sprintf("START_REPLICATION SLOT %s LOGICAL %s (proto_version '1', publication_names %s)",
+		pgx.Identifier{c.Slot}.Sanitize(), c.acknowledged, quoteLiteral(c.Publication))
+
+	conn.Frontend().Send(&pgproto3.Query{String: sql})
+	if err := conn.Frontend().Flush(); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := conn.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.CopyBothResponse:
+			return nil
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(msg)
+		}
+	}
+}
+
+// sendStatus reports the acknowledged position to PostgreSQL, so it can discard the write-ahead
+// log the Consumer no longer needs.
+func (c *Consumer) sendStatus(conn *pgconn.PgConn) error {
+	data := make([]byte, 34)
+	data[0] = 'r'
+
+	lsn := uint64(c.acknowledged)
+	binary.BigEndian.PutUint64(data[1:], lsn)  // written
+	binary.BigEndian.PutUint64(data[9:], lsn)  // flushed
+	binary.BigEndian.PutUint64(data[17:], lsn) // applied
+	binary.BigEndian.PutUint64(data[25:], uint64(time.Since(postgresEpoch).Microseconds()))
+
+	conn.Frontend().Send(&pgproto3.CopyData{Data: data})
+	return conn.Frontend().Flush()
+}
+
+// quoteLiteral quotes a string as an SQL literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
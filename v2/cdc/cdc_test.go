@@ -0,0 +1,87 @@
+package cdc
+
+import "testing"
+
+func TestChangeScan(t *testing.T) {
+	type user struct {
+		ID      int64
+		Name    string `db:"name"`
+		Age     int
+		private string
+	}
+
+	change := Change{Op: Insert, New: map[string]interface{}{"id": int64(5), "name": "Carol", "age": int32(30)}}
+
+	var u user
+	if err := change.Scan(&u); err != nil {
+		t.Fatalf("Unable to scan change: %s", err)
+	}
+
+	if u.ID != 5 || u.Name != "Carol" || u.Age != 30 {
+		t.Errorf("Expected {5 Carol 30}; was %+v", u)
+	}
+
+	del := Change{Op: Delete, Old: map[string]interface{}{"id": int64(9)}}
+
+	u = user{}
+	if err := del.Scan(&u); err != nil {
+		t.Fatalf("Unable to scan delete: %s", err)
+	}
+
+	if u.ID != 9 {
+		t.Errorf("Expected old id 9 on delete; was %d", u.ID)
+	}
+
+	if err := change.Scan(u); err == nil {
+		t.Error("Expected an error scanning into a non-pointer")
+	}
+
+	bad := Change{Op: Insert, New: map[string]interface{}{"name": true}}
+	if err := bad.Scan(&u); err == nil {
+		t.Error("Expected an error assigning an incompatible type")
+	}
+}
+
+func TestChangeScanConversions(t *testing.T) {
+	type status string
+
+	type row struct {
+		Small  int16
+		Big    int64
+		Count  uint32
+		Ratio  float64
+		Status status
+		Name   string
+	}
+
+	ok := Change{Op: Insert, New: map[string]interface{}{
+		"small":  int64(12),
+		"big":    int32(7),
+		"count":  uint8(3),
+		"ratio":  float32(0.5),
+		"status": "active",
+	}}
+
+	var r row
+	if err := ok.Scan(&r); err != nil {
+		t.Fatalf("Unable to scan change: %s", err)
+	}
+
+	if r.Small != 12 || r.Big != 7 || r.Count != 3 || r.Ratio != 0.5 || r.Status != "active" {
+		t.Errorf("Expected {12 7 3 0.5 active}; was %+v", r)
+	}
+
+	bad := []map[string]interface{}{
+		{"name": int64(65)},
+		{"big": 1.9},
+		{"ratio": int64(1)},
+		{"count": int64(-1)},
+		{"small": int64(1 << 20)},
+	}
+
+	for _, values := range bad {
+		if err := (&Change{Op: Insert, New: values}).Scan(&row{}); err == nil {
+			t.Errorf("Expected an error scanning %v", values)
+		}
+	}
+}
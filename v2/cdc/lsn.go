@@ -0,0 +1,23 @@
+package cdc
+
+import (
+	"fmt"
+)
+
+// LSN is a PostgreSQL write-ahead log sequence number.
+type LSN uint64
+
+// ParseLSN parses an LSN in PostgreSQL's "XXX/XXX" text format.
+func ParseLSN(s string) (LSN, error) {
+	var hi, lo uint32
+	if _, err := fmt.Sscanf(s, "%X/%X", &hi, &lo); err != nil {
+		return 0, fmt.Errorf("invalid LSN %q: %w", s, err)
+	}
+
+	return LSN(uint64(hi)<<32 | uint64(lo)), nil
+}
+
+// String formats the LSN in PostgreSQL's "XXX/XXX" text format.
+func (lsn LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(lsn>>32), uint32(lsn))
+}
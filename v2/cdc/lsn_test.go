@@ -0,0 +1,26 @@
+package cdc
+
+import "testing"
+
+func TestLSN(t *testing.T) {
+	lsn, err := ParseLSN("16/B374D848")
+	if err != nil {
+		t.Fatalf("Unable to parse LSN: %s", err)
+	}
+
+	if lsn != LSN(0x16B374D848) {
+		t.Errorf("Expected LSN 0x16B374D848; was %#x", uint64(lsn))
+	}
+
+	if lsn.String() != "16/B374D848" {
+		t.Errorf("Expected 16/B374D848; was %s", lsn)
+	}
+
+	if LSN(0).String() != "0/0" {
+		t.Errorf("Expected 0/0; was %s", LSN(0))
+	}
+
+	if _, err := ParseLSN("bogus"); err == nil {
+		t.Error("Expected an error parsing an invalid LSN")
+	}
+}
@@ -0,0 +1,325 @@
+package cdc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// errShortMessage is returned when a pgoutput message is truncated.
+var errShortMessage = errors.New("pgoutput message too short")
+
+// PostgreSQL timestamps count microseconds from 2000-01-01.
+var postgresEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// relation describes a table, as sent by pgoutput ahead of the first change to the table.
+type relation struct {
+	id        uint32
+	namespace string
+	name      string
+	columns   []relationColumn
+}
+
+type relationColumn struct {
+	key  bool
+	name string
+	oid  uint32
+}
+
+// decoder decodes pgoutput (protocol version 1) messages into Transactions.
+type decoder struct {
+	relations map[uint32]*relation
+	types     *pgtype.Map
+
+	// tx is the transaction being assembled, between Begin and Commit messages
+	tx *Transaction
+}
+
+func newDecoder() *decoder {
+	return &decoder{
+		relations: make(map[uint32]*relation),
+		types:     pgtype.NewMap(),
+	}
+}
+
+// decode a single pgoutput message.  Returns the completed transaction when the message is a
+// Commit; otherwise returns nil.
+func (d *decoder) decode(data []byte) (*Transaction, error) {
+	if len(data) == 0 {
+		return nil, errShortMessage
+	}
+
+	r := &reader{data: data[1:]}
+
+	switch data[0] {
+	case 'B':
+		finalLSN := r.uint64()
+		commitTime := r.timestamp()
+		xid := r.uint32()
+
+		d.tx = &Transaction{XID: xid, LSN: LSN(finalLSN), CommitTime: commitTime}
+
+	case 'C':
+		r.uint8() // flags
+		commitLSN := r.uint64()
+		endLSN := r.uint64()
+		commitTime := r.timestamp()
+
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		tx := d.tx
+		if tx == nil {
+			tx = &Transaction{}
+		}
+		d.tx = nil
+
+		tx.LSN = LSN(commitLSN)
+		tx.EndLSN = LSN(endLSN)
+		tx.CommitTime = commitTime
+
+		return tx, nil
+
+	case 'R':
+		rel := &relation{id: r.uint32(), namespace: r.string(), name: r.string()}
+		r.uint8() // replica identity
+
+		count := int(r.uint16())
+		for i := 0; i < count && r.err == nil; i++ {
+			flags := r.uint8()
+			name := r.string()
+			oid := r.uint32()
+			r.uint32() // type modifier
+
+			rel.columns = append(rel.columns, relationColumn{key: flags&1 != 0, name: name, oid: oid})
+		}
+
+		if r.err == nil {
+			d.relations[rel.id] = rel
+		}
+
+	case 'I':
+		rel, err := d.relation(r.uint32())
+		if err != nil {
+			return nil, err
+		}
+
+		r.uint8() // 'N'
+
+		change := Change{Op: Insert, Schema: rel.namespace, Table: rel.name}
+		if change.New, err = d.tuple(r, rel); err != nil {
+			return nil, err
+		}
+
+		d.append(change)
+
+	case 'U':
+		rel, err := d.relation(r.uint32())
+		if err != nil {
+			return nil, err
+		}
+
+		change := Change{Op: Update, Schema: rel.namespace, Table: rel.name}
+
+		kind := r.uint8()
+		if kind == 'K' || kind == 'O' {
+			if change.Old, err = d.tuple(r, rel); err != nil {
+				return nil, err
+			}
+			kind = r.uint8()
+		}
+
+		if kind != 'N' {
+			return nil, fmt.Errorf("unexpected pgoutput update tuple type %q", kind)
+		}
+
+		if change.New, err = d.tuple(r, rel); err != nil {
+			return nil, err
+		}
+
+		d.append(change)
+
+	case 'D':
+		rel, err := d.relation(r.uint32())
+		if err != nil {
+			return nil, err
+		}
+
+		r.uint8() // 'K' or 'O'
+
+		change := Change{Op: Delete, Schema: rel.namespace, Table: rel.name}
+		if change.Old, err = d.tuple(r, rel); err != nil {
+			return nil, err
+		}
+
+		d.append(change)
+
+	case 'T':
+		count := int(r.uint32())
+		r.uint8() // options
+
+		for i := 0; i < count && r.err == nil; i++ {
+			rel, err := d.relation(r.uint32())
+			if err != nil {
+				return nil, err
+			}
+
+			d.append(Change{Op: Truncate, Schema: rel.namespace, Table: rel.name})
+		}
+
+	default:
+		// Origin, Type, and Message messages aren't needed
+	}
+
+	return nil, r.err
+}
+
+// relation looks up a previously received relation.
+func (d *decoder) relation(id uint32) (*relation, error) {
+	rel, ok := d.relations[id]
+	if !ok {
+		return nil, fmt.Errorf("pgoutput change for unknown relation %d", id)
+	}
+
+	return rel, nil
+}
+
+// append adds the change to the current transaction.
+func (d *decoder) append(change Change) {
+	if d.tx == nil {
+		d.tx = &Transaction{}
+	}
+
+	d.tx.Changes = append(d.tx.Changes, change)
+}
+
+// tuple decodes the column values of a row.  Values are decoded into Go types based on the
+// column's type, falling back to a string for unknown types.  Unchanged TOASTed values are left
+// out.
+func (d *decoder) tuple(r *reader, rel *relation) (map[string]interface{}, error) {
+	count := int(r.uint16())
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if count > len(rel.columns) {
+		return nil, fmt.Errorf("pgoutput tuple has %d columns; relation %s.%s has %d", count,
+			rel.namespace, rel.name, len(rel.columns))
+	}
+
+	values := make(map[string]interface{}, count)
+
+	for i := 0; i < count; i++ {
+		col := rel.columns[i]
+
+		switch kind := r.uint8(); kind {
+		case 'n':
+			values[col.name] = nil
+		case 'u':
+			// Unchanged TOAST value; not sent
+		case 't':
+			data := r.bytes(int(r.uint32()))
+			if r.err != nil {
+				return nil, r.err
+			}
+
+			value, err := d.value(col.oid, data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to decode %s.%s.%s: %w", rel.namespace, rel.name, col.name, err)
+			}
+			values[col.name] = value
+		default:
+			if r.err != nil {
+				return nil, r.err
+			}
+			return nil, fmt.Errorf("unexpected pgoutput column type %q", kind)
+		}
+	}
+
+	return values, r.err
+}
+
+// value decodes a text-format column value.
+func (d *decoder) value(oid uint32, data []byte) (interface{}, error) {
+	if typ, ok := d.types.TypeForOID(oid); ok {
+		return typ.Codec.DecodeValue(d.types, oid, pgtype.TextFormatCode, data)
+	}
+
+	return string(data), nil
+}
+
+// reader reads big-endian values from a pgoutput message, recording the first error.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if n < 0 || len(r.data) < n {
+		r.err = errShortMessage
+		return nil
+	}
+
+	b := r.data[:n]
+	r.data = r.data[n:]
+
+	return b
+}
+
+func (r *reader) uint8() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) uint64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *reader) timestamp() time.Time {
+	return postgresEpoch.Add(time.Duration(int64(r.uint64())) * time.Microsecond)
+}
+
+// string reads a null-terminated string.
+func (r *reader) string() string {
+	if r.err != nil {
+		return ""
+	}
+
+	for i, b := range r.data {
+		if b == 0 {
+			s := string(r.data[:i])
+			r.data = r.data[i+1:]
+			return s
+		}
+	}
+
+	r.err = errShortMessage
+	return ""
+}
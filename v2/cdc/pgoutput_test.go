@@ -0,0 +1,138 @@
+package cdc
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// message builds a pgoutput message for testing.
+type message []byte
+
+func (m message) byte(b byte) message {
+	return append(m, b)
+}
+
+func (m message) uint16(v uint16) message {
+	return append(m, byte(v>>8), byte(v))
+}
+
+func (m message) uint32(v uint32) message {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return append(m, b[:]...)
+}
+
+func (m message) uint64(v uint64) message {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(m, b[:]...)
+}
+
+func (m message) string(s string) message {
+	return append(append(m, s...), 0)
+}
+
+func (m message) text(s string) message {
+	return append(m.byte('t').uint32(uint32(len(s))), s...)
+}
+
+func TestDecodeTransaction(t *testing.T) {
+	dec := newDecoder()
+
+	commitTime := time.Date(2026, time.May, 1, 12, 0, 0, 0, time.UTC)
+	micros := uint64(commitTime.Sub(postgresEpoch) / time.Microsecond)
+
+	messages := []message{
+		message{'B'}.uint64(0x100).uint64(micros).uint32(42),
+		message{'R'}.uint32(7).string("public").string("users").byte('d').uint16(3).
+			byte(1).string("id").uint32(20).uint32(0xffffffff).
+			byte(0).string("name").uint32(25).uint32(0xffffffff).
+			byte(0).string("shape").uint32(999999).uint32(0xffffffff),
+		message{'I'}.uint32(7).byte('N').uint16(3).text("1").text("Alice").text("circle"),
+		message{'U'}.uint32(7).byte('N').uint16(3).text("1").text("Bob").byte('u'),
+		message{'D'}.uint32(7).byte('K').uint16(3).text("1").byte('n').byte('n'),
+	}
+
+	for i, msg := range messages {
+		tx, err := dec.decode(msg)
+		if err != nil {
+			t.Fatalf("Unable to decode message %d: %s", i, err)
+		}
+		if tx != nil {
+			t.Fatalf("Expected no transaction before commit; message %d returned one", i)
+		}
+	}
+
+	tx, err := dec.decode(message{'C'}.byte(0).uint64(0x100).uint64(0x180).uint64(micros))
+	if err != nil {
+		t.Fatalf("Unable to decode commit: %s", err)
+	}
+
+	if tx == nil {
+		t.Fatal("Expected a transaction on commit")
+	}
+
+	if tx.XID != 42 {
+		t.Errorf("Expected XID 42; was %d", tx.XID)
+	}
+
+	if tx.EndLSN != 0x180 {
+		t.Errorf("Expected end LSN 0/180; was %s", tx.EndLSN)
+	}
+
+	if !tx.CommitTime.Equal(commitTime) {
+		t.Errorf("Expected commit time %s; was %s", commitTime, tx.CommitTime)
+	}
+
+	if len(tx.Changes) != 3 {
+		t.Fatalf("Expected 3 changes; was %d", len(tx.Changes))
+	}
+
+	insert := tx.Changes[0]
+	if insert.Op != Insert || insert.Schema != "public" || insert.Table != "users" {
+		t.Errorf("Expected insert into public.users; was %s into %s.%s", insert.Op, insert.Schema, insert.Table)
+	}
+
+	if insert.New["id"] != int64(1) {
+		t.Errorf("Expected id to decode to int64 1; was %#v", insert.New["id"])
+	}
+
+	if insert.New["shape"] != "circle" {
+		t.Errorf("Expected unknown type to decode as a string; was %#v", insert.New["shape"])
+	}
+
+	update := tx.Changes[1]
+	if update.Op != Update || update.New["name"] != "Bob" {
+		t.Errorf("Expected update setting name to Bob; was %s %v", update.Op, update.New)
+	}
+
+	if _, ok := update.New["shape"]; ok {
+		t.Error("Expected unchanged TOAST column to be left out")
+	}
+
+	del := tx.Changes[2]
+	if del.Op != Delete || del.Old["id"] != int64(1) {
+		t.Errorf("Expected delete of id 1; was %s %v", del.Op, del.Old)
+	}
+
+	if v, ok := del.Old["name"]; !ok || v != nil {
+		t.Errorf("Expected null name; was %#v", v)
+	}
+}
+
+func TestDecodeUnknownRelation(t *testing.T) {
+	dec := newDecoder()
+
+	if _, err := dec.decode(message{'I'}.uint32(7).byte('N').uint16(0)); err == nil {
+		t.Error("Expected an error decoding a change to an unknown relation")
+	}
+}
+
+func TestDecodeShortMessage(t *testing.T) {
+	dec := newDecoder()
+
+	if _, err := dec.decode(message{'B'}.uint32(1)); err != errShortMessage {
+		t.Errorf("Expected errShortMessage; was %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package hermes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Balance is how a Cluster spreads reads across its replicas.
+type Balance int
+
+// Cluster load balancing strategies.
+const (
+	// LatencyWeighted picks replicas at random, weighted towards the fastest, as with
+	// ReplicaSet.Pick.
+	LatencyWeighted Balance = iota
+
+	// RoundRobin takes turns across the healthy replicas.
+	RoundRobin
+
+	// LeastConnections picks the replica with the fewest of the Cluster's reads in flight.
+	LeastConnections
+)
+
+// Cluster is a Conn that splits reads from writes:  Query and QueryRow run on a healthy read
+// replica, while everything else, including transactions, runs on the primary.  Use the Cluster
+// wherever a Conn is expected, and pass the transactions it begins to code that must read its own
+// writes.
+//
+// Reads fall back to the primary when no replica is healthy, or when a replica's connection fails
+// before the query returns rows; the replica is then ejected until its next probe.  Writes made
+// with a context tracking writes (see TrackWrites) are recorded on it, and its reads only go to
+// replicas that have replayed past them.
+//
+// The Cluster embeds its ReplicaSet; configure the probes and call Run in a goroutine to keep the
+// replicas' health up to date.
+type Cluster struct {
+	// next is the round robin counter, first in the struct for 64-bit atomic alignment on 32-bit
+	// platforms
+	next uint64
+
+	*ReplicaSet
+
+	// Balance is how reads are spread across the replicas.  Defaults to LatencyWeighted.
+	Balance Balance
+}
+
+// NewCluster creates a Cluster that writes to the primary and reads from the replicas.
+func NewCluster(primary *DB, replicas ...*DB) *Cluster {
+	rs := NewReplicaSet(replicas...)
+	rs.Primary = primary
+
+	return &Cluster{ReplicaSet: rs}
+}
+
+// reader picks a replica for a read in ctx, or returns nil if the read should go to the primary.
+func (c *Cluster) reader(ctx context.Context) *Replica {
+	after := readAfter(ctx)
+
+	if c.Balance == LatencyWeighted {
+		return c.pick(after)
+	}
+
+	var candidates []*Replica
+	for _, replica := range c.replicas {
+		if status := replica.Status(); status.Healthy && status.ReplayLSN >= after {
+			candidates = append(candidates, replica)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// Start from the next replica in turn, so ties are shared out
+	start := int(atomic.AddUint64(&c.next, 1) % uint64(len(candidates)))
+	if c.Balance == RoundRobin {
+		return candidates[start]
+	}
+
+	least := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		replica := candidates[(start+i)%len(candidates)]
+		if atomic.LoadInt64(&replica.reads) < atomic.LoadInt64(&least.reads) {
+			least = replica
+		}
+	}
+
+	return least
+}
+
+// failover returns true if the read should be retried on the primary:  the replica's connection
+// failed, rather than the query, and ctx is still live.  The replica is ejected until its next
+// probe.
+func (c *Cluster) failover(ctx context.Context, replica *Replica, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	if !isReconnectable(err) {
+		return false
+	}
+
+	c.observe(replica, 0, 0, 0, 0, err)
+	return true
+}
+
+// Query runs the query on a replica, or the primary if none is available.  See Cluster.
+func (c *Cluster) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	replica := c.reader(ctx)
+	if replica == nil {
+		return c.Primary.Query(ctx, sql, args...)
+	}
+
+	atomic.AddInt64(&replica.reads, 1)
+
+	rows, err := replica.DB.Query(ctx, sql, args...)
+	if err != nil {
+		atomic.AddInt64(&replica.reads, -1)
+
+		if c.failover(ctx, replica, err) {
+			return c.Primary.Query(ctx, sql, args...)
+		}
+		return nil, err
+	}
+
+	return &clusterRows{Rows: rows, replica: replica}, nil
+}
+
+// QueryRow runs the query on a replica, or the primary if none is available.  See Cluster.
+func (c *Cluster) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return &clusterRow{cluster: c, ctx: ctx, sql: sql, args: args}
+}
+
+// Exec runs the statement on the primary.
+func (c *Cluster) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return c.Primary.Exec(ctx, sql, args...)
+}
+
+// Begin starts a transaction on the primary.
+func (c *Cluster) Begin(ctx context.Context) (Conn, error) {
+	return c.Primary.Begin(ctx)
+}
+
+// BeginFunc runs fn in a transaction on the primary.  See DB.BeginFunc.
+func (c *Cluster) BeginFunc(ctx context.Context, fn func(tx Conn) error) error {
+	return c.Primary.BeginFunc(ctx, fn)
+}
+
+// Commit does nothing.
+func (c *Cluster) Commit(context.Context) error {
+	return nil
+}
+
+// Rollback does nothing.
+func (c *Cluster) Rollback(context.Context) error {
+	return nil
+}
+
+// Close does nothing.  See DB.Close.
+func (c *Cluster) Close(context.Context) error {
+	return nil
+}
+
+// Shutdown the primary's and the replicas' connection pools.  See DB.Shutdown.
+func (c *Cluster) Shutdown() {
+	_ = c.ShutdownContext(context.Background())
+}
+
+// ShutdownContext shuts down the primary's and the replicas' connection pools at the same time,
+// waiting for their open transactions up to ctx's deadline.  Returns the primary's error, or the
+// first of the replicas'.  See DB.ShutdownContext.
+func (c *Cluster) ShutdownContext(ctx context.Context) error {
+	dbs := []*DB{c.Primary}
+	for _, replica := range c.replicas {
+		dbs = append(dbs, replica.DB)
+	}
+
+	errs := make([]error, len(dbs))
+
+	var wg sync.WaitGroup
+	for i, db := range dbs {
+		wg.Add(1)
+		go func(i int, db *DB) {
+			defer wg.Done()
+			errs[i] = db.ShutdownContext(ctx)
+		}(i, db)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CopyFrom copies the rows into the table on the primary.
+func (c *Cluster) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return c.Primary.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// SendBatch sends the batch to the primary, as it may contain writes.
+func (c *Cluster) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return c.Primary.SendBatch(ctx, b)
+}
+
+// Lock takes a session-wide advisory lock on the primary.
+func (c *Cluster) Lock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	return c.Primary.Lock(ctx, id)
+}
+
+// TryLock tries to take a session-wide advisory lock on the primary.
+func (c *Cluster) TryLock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	return c.Primary.TryLock(ctx, id)
+}
+
+// WithTimeout returns a context with the primary's default timeout.  See DB.WithTimeout.
+func (c *Cluster) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return c.Primary.WithTimeout(ctx)
+}
+
+// SetTimeout sets the default timeout on the primary and the replicas.
+func (c *Cluster) SetTimeout(dur time.Duration) {
+	c.Primary.SetTimeout(dur)
+
+	for _, replica := range c.replicas {
+		replica.DB.SetTimeout(dur)
+	}
+}
+
+// BeginWithTimeout starts a transaction on the primary.  See DB.BeginWithTimeout.
+func (c *Cluster) BeginWithTimeout(ctx context.Context) (*ContextualTx, error) {
+	return c.Primary.BeginWithTimeout(ctx)
+}
+
+// Notify sends the notification through the primary.
+func (c *Cluster) Notify(ctx context.Context, channel string, payload interface{}) error {
+	return c.Primary.Notify(ctx, channel, payload)
+}
+
+// ExecNamed runs the statement on the primary.  See Named.
+func (c *Cluster) ExecNamed(ctx context.Context, sql string, args map[string]interface{}) (pgconn.CommandTag, error) {
+	return execNamed(ctx, c, sql, args)
+}
+
+// QueryNamed runs the query on a replica, or the primary if none is available.  See Named.
+func (c *Cluster) QueryNamed(ctx context.Context, sql string, args map[string]interface{}) (pgx.Rows, error) {
+	return queryNamed(ctx, c, sql, args)
+}
+
+// Prepare creates a named prepared statement on the primary and every replica, so the statement
+// may be run wherever the query is sent.  Returns the primary's description of the statement.
+// See DB.Prepare.
+func (c *Cluster) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	sd, err := c.Primary.Prepare(ctx, name, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, replica := range c.replicas {
+		if _, err := replica.DB.Prepare(ctx, name, sql); err != nil {
+			return nil, err
+		}
+	}
+
+	return sd, nil
+}
+
+// Deallocate drops the named prepared statement from the primary and every replica.
+func (c *Cluster) Deallocate(ctx context.Context, name string) error {
+	if err := c.Primary.Deallocate(ctx, name); err != nil {
+		return err
+	}
+
+	for _, replica := range c.replicas {
+		if err := replica.DB.Deallocate(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueryCursor runs the query through a server-side cursor on a replica, or the primary if none is
+// available.  See DB.QueryCursor.
+func (c *Cluster) QueryCursor(ctx context.Context, sql string, batchSize int, args ...interface{}) (pgx.Rows, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	replica := c.reader(ctx)
+	if replica == nil {
+		return c.Primary.QueryCursor(ctx, sql, batchSize, args...)
+	}
+
+	atomic.AddInt64(&replica.reads, 1)
+
+	rows, err := replica.DB.QueryCursor(ctx, sql, batchSize, args...)
+	if err != nil {
+		atomic.AddInt64(&replica.reads, -1)
+
+		if c.failover(ctx, replica, err) {
+			return c.Primary.QueryCursor(ctx, sql, batchSize, args...)
+		}
+		return nil, err
+	}
+
+	return &clusterRows{Rows: rows, replica: replica}, nil
+}
+
+// clusterRows count the read against the replica until they're closed or read to the end.
+type clusterRows struct {
+	pgx.Rows
+	replica *Replica
+	done    int32
+}
+
+// Next prepares the next row for reading, ending the read after the last row.
+func (rows *clusterRows) Next() bool {
+	if rows.Rows.Next() {
+		return true
+	}
+
+	rows.end()
+	return false
+}
+
+// Close the rows and end the read.
+func (rows *clusterRows) Close() {
+	rows.Rows.Close()
+	rows.end()
+}
+
+func (rows *clusterRows) end() {
+	if atomic.CompareAndSwapInt32(&rows.done, 0, 1) {
+		atomic.AddInt64(&rows.replica.reads, -1)
+	}
+}
+
+// clusterRow runs its query when scanned, so it can fall back to the primary if the replica's
+// connection fails.
+type clusterRow struct {
+	cluster *Cluster
+	ctx     context.Context
+	sql     string
+	args    []interface{}
+}
+
+// Scan runs the query on a replica, or the primary, and scans the row.
+func (row *clusterRow) Scan(dest ...interface{}) error {
+	c := row.cluster
+
+	replica := c.reader(row.ctx)
+	if replica == nil {
+		return c.Primary.QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+	}
+
+	atomic.AddInt64(&replica.reads, 1)
+	err := replica.DB.QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+	atomic.AddInt64(&replica.reads, -1)
+
+	if c.failover(row.ctx, replica, err) {
+		return c.Primary.QueryRow(row.ctx, row.sql, row.args...).Scan(dest...)
+	}
+
+	return err
+}
+
+// A Cluster can be used wherever a Conn is expected.
+var _ Conn = (*Cluster)(nil)
@@ -0,0 +1,108 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestClusterRoundRobin(t *testing.T) {
+	c := NewCluster(&DB{}, &DB{}, &DB{}, &DB{})
+	c.Balance = RoundRobin
+
+	replicas := c.Replicas()
+	c.observe(replicas[2], 0, 0, 0, 0, errors.New("connection refused"))
+
+	picks := make(map[*Replica]int)
+	for i := 0; i < 100; i++ {
+		picks[c.reader(context.Background())]++
+	}
+
+	if picks[replicas[0]] != 50 || picks[replicas[1]] != 50 || picks[replicas[2]] != 0 {
+		t.Errorf("Expected reads shared evenly across the healthy replicas; was %d, %d, and %d",
+			picks[replicas[0]], picks[replicas[1]], picks[replicas[2]])
+	}
+}
+
+func TestClusterLeastConnections(t *testing.T) {
+	c := NewCluster(&DB{}, &DB{}, &DB{})
+	c.Balance = LeastConnections
+
+	busy, idle := c.Replicas()[0], c.Replicas()[1]
+	busy.reads = 3
+
+	for i := 0; i < 10; i++ {
+		if c.reader(context.Background()) != idle {
+			t.Fatal("Expected reads to go to the replica with the fewest in flight")
+		}
+	}
+
+	rows := &clusterRows{Rows: &fakeRows{}, replica: idle}
+	idle.reads = 4
+
+	for rows.Next() {
+	}
+	rows.Close()
+
+	if idle.reads != 3 {
+		t.Errorf("Expected the read to end once; was %d in flight", idle.reads)
+	}
+
+	if c.reader(context.Background()) != idle {
+		t.Error("Expected reads to go to the other replica once it has fewer in flight")
+	}
+}
+
+func TestClusterReadYourWrites(t *testing.T) {
+	c := NewCluster(&DB{}, &DB{}, &DB{})
+	c.Balance = RoundRobin
+
+	behind, ahead := c.Replicas()[0], c.Replicas()[1]
+	c.observe(behind, time.Millisecond, 0, 100, 0, nil)
+	c.observe(ahead, time.Millisecond, 0, 200, 0, nil)
+
+	ctx := TrackWrites(context.Background(), 150)
+	for i := 0; i < 10; i++ {
+		if c.reader(ctx) != ahead {
+			t.Fatal("Expected reads to go to the replica that has replayed the writes")
+		}
+	}
+
+	if c.reader(TrackWrites(context.Background(), 300)) != nil {
+		t.Error("Expected reads to go to the primary when no replica has caught up")
+	}
+}
+
+func TestClusterFailover(t *testing.T) {
+	c := NewCluster(&DB{}, &DB{})
+	replica := c.Replicas()[0]
+	ctx := context.Background()
+
+	if c.failover(ctx, replica, &pgconn.PgError{Code: "42P01"}) {
+		t.Error("Expected query errors to stay on the replica")
+	}
+
+	if !replica.Status().Healthy {
+		t.Error("Expected the replica to stay healthy after a query error")
+	}
+
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	if !c.failover(ctx, replica, dial) {
+		t.Error("Expected a connection error to fall back to the primary")
+	}
+
+	if replica.Status().Healthy {
+		t.Error("Expected the replica to be ejected")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if c.failover(canceled, replica, &pgconn.PgError{Code: AdminShutdown}) {
+		t.Error("Expected no fallback once the context is done")
+	}
+}
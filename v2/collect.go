@@ -0,0 +1,225 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrColumnCount is returned when collecting values from rows that don't have exactly one column.
+var ErrColumnCount = errors.New("expected a single column")
+
+// ExpectRows is passed in a Select or SelectValues call's arguments to hint at the number of rows
+// the query returns, so the results are sized up front.  It's removed from the arguments before
+// the query is run.
+//
+//	users, err := hermes.Select[User](ctx, db, "select * from users where org_id = $1",
+//	    hermes.ExpectRows(500), orgID)
+type ExpectRows int
+
+// CollectValues scans the single column of every row into a slice of T, then closes the rows.  If
+// given, hint is the number of rows expected, used to size the results up front.
+func CollectValues[T any](rows pgx.Rows, hint ...int) ([]T, error) {
+	return AppendValues(preallocate[T](hint), rows)
+}
+
+// AppendValues scans the single column of every row into a T, appending them to dest, then closes
+// the rows.  Pass dest[:0] to reuse a slice from an earlier call.  Rows are scanned directly into
+// the slice, so the only allocations are from growing it and from the values themselves, e.g.
+// strings.
+func AppendValues[T any](dest []T, rows pgx.Rows) ([]T, error) {
+	defer rows.Close()
+
+	if columns := len(rows.FieldDescriptions()); columns != 1 {
+		return nil, fmt.Errorf("%w; query returned %d", ErrColumnCount, columns)
+	}
+
+	var zero T
+	targets := make([]interface{}, 1)
+
+	for rows.Next() {
+		dest = append(dest, zero)
+		targets[0] = &dest[len(dest)-1]
+
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+// Select runs the query and scans every row into a struct of type T.  See ScanStruct for how
+// columns are matched to fields, and ExpectRows to size the results up front.
+func Select[T any](ctx context.Context, conn Conn, sql string, args ...interface{}) ([]T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	args, hint := expectRows(args)
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return CollectStructs[T](rows, hint)
+}
+
+// SelectValues runs a query returning a single column and scans every row into a T.  See
+// ExpectRows to size the results up front.
+func SelectValues[T any](ctx context.Context, conn Conn, sql string, args ...interface{}) ([]T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	args, hint := expectRows(args)
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return CollectValues[T](rows, hint)
+}
+
+// expectRows removes any ExpectRows hint from the query arguments, returning the remaining
+// arguments and the hint.
+func expectRows(args []interface{}) ([]interface{}, int) {
+	for i, arg := range args {
+		if hint, ok := arg.(ExpectRows); ok {
+			remaining := make([]interface{}, 0, len(args)-1)
+			remaining = append(remaining, args[:i]...)
+			remaining = append(remaining, args[i+1:]...)
+
+			return remaining, int(hint)
+		}
+	}
+
+	return args, 0
+}
+
+// preallocate returns an empty slice sized for the hinted number of rows, or nil without a hint.
+func preallocate[T any](hint []int) []T {
+	if len(hint) == 0 || hint[0] <= 0 {
+		return nil
+	}
+
+	return make([]T, 0, hint[0])
+}
+
+// ScanOne runs the query and scans the first row into dest, which points to a struct or a single
+// value, e.g. a *User or an *int64.  Structs are scanned as with ScanStruct.  Returns
+// pgx.ErrNoRows if the query returns no rows; any rows after the first are ignored.
+func ScanOne(ctx context.Context, conn Conn, dest interface{}, sql string, args ...interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("cannot scan into %T; expected a pointer", dest)
+	}
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+
+	if scansStruct(v.Elem().Type()) {
+		err = ScanStruct(rows, dest)
+	} else {
+		err = rows.Scan(dest)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	rows.Close()
+	return rows.Err()
+}
+
+// ScanAll runs the query and scans every row into the slice dest points to, replacing its
+// contents.  The slice may hold structs, pointers to structs, or single values, e.g. a *[]User,
+// *[]*User, or *[]int64.  Structs are scanned as with ScanStruct.  For the generic equivalents,
+// see Select and SelectValues.
+func ScanAll(ctx context.Context, conn Conn, dest interface{}, sql string, args ...interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("cannot scan into %T; expected a pointer to a slice", dest)
+	}
+
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+
+	pointers := elemType.Kind() == reflect.Ptr && scansStruct(elemType.Elem())
+	structType := elemType
+	if pointers {
+		structType = elemType.Elem()
+	}
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var plan *scanPlan
+	if scansStruct(structType) {
+		if plan, err = planScan(structType, fieldNames(rows)); err != nil {
+			return err
+		}
+	}
+
+	result := slice.Slice(0, 0)
+	var targets []interface{}
+
+	for rows.Next() {
+		var target reflect.Value
+		if pointers {
+			target = reflect.New(structType)
+			result = reflect.Append(result, target)
+		} else {
+			result = reflect.Append(result, reflect.Zero(elemType))
+			target = result.Index(result.Len() - 1).Addr()
+		}
+
+		if plan != nil {
+			targets = plan.targets(target.Elem(), targets)
+			err = rows.Scan(targets...)
+		} else {
+			err = rows.Scan(target.Interface())
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	slice.Set(result)
+	return nil
+}
@@ -0,0 +1,206 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestCollectValues(t *testing.T) {
+	rows := &fakeRows{columns: []string{"name"}, values: [][]interface{}{{"Alice"}, {"Bob"}}}
+
+	names, err := CollectValues[string](rows, 10)
+	if err != nil {
+		t.Fatalf("Unable to collect values: %s", err)
+	}
+
+	if len(names) != 2 || names[0] != "Alice" || names[1] != "Bob" {
+		t.Errorf("Expected [Alice Bob]; was %v", names)
+	}
+
+	if cap(names) != 10 {
+		t.Errorf("Expected the results to be sized from the hint; capacity was %d", cap(names))
+	}
+
+	if !rows.closed {
+		t.Error("Expected the rows to be closed")
+	}
+}
+
+func TestCollectValuesColumns(t *testing.T) {
+	rows := &fakeRows{columns: []string{"id", "name"}, values: [][]interface{}{{int64(1), "Alice"}}}
+
+	if _, err := CollectValues[string](rows); !errors.Is(err, ErrColumnCount) {
+		t.Errorf("Expected ErrColumnCount; was %v", err)
+	}
+
+	if !rows.closed {
+		t.Error("Expected the rows to be closed")
+	}
+}
+
+func TestAppendStructs(t *testing.T) {
+	columns := []string{"id", "display_name"}
+
+	accounts, err := AppendStructs([]account{}, &fakeRows{columns: columns,
+		values: [][]interface{}{{int64(1), "Alice"}, {int64(2), "Bob"}}})
+	if err != nil {
+		t.Fatalf("Unable to collect accounts: %s", err)
+	}
+
+	accounts[0].Balance = 12.5
+	first := &accounts[0]
+
+	accounts, err = AppendStructs(accounts[:0], &fakeRows{columns: columns,
+		values: [][]interface{}{{int64(3), "Carol"}}})
+	if err != nil {
+		t.Fatalf("Unable to collect accounts: %s", err)
+	}
+
+	if len(accounts) != 1 || accounts[0].Name != "Carol" {
+		t.Fatalf("Expected [Carol]; was %v", accounts)
+	}
+
+	if &accounts[0] != first {
+		t.Error("Expected the slice to be reused")
+	}
+
+	if accounts[0].Balance != 0 {
+		t.Errorf("Expected reused structs to be reset; balance was %f", accounts[0].Balance)
+	}
+}
+
+func TestExpectRows(t *testing.T) {
+	args, hint := expectRows([]interface{}{12, ExpectRows(100), "active"})
+	if hint != 100 {
+		t.Errorf("Expected a hint of 100; was %d", hint)
+	}
+
+	if len(args) != 2 || args[0] != 12 || args[1] != "active" {
+		t.Errorf("Expected [12 active]; was %v", args)
+	}
+
+	args, hint = expectRows([]interface{}{12})
+	if hint != 0 || len(args) != 1 {
+		t.Errorf("Expected no hint and the arguments unchanged; was %d, %v", hint, args)
+	}
+}
+
+func benchmarkRows(n int) [][]interface{} {
+	values := make([][]interface{}, n)
+	for i := range values {
+		values[i] = []interface{}{int64(i), "name", 1.5}
+	}
+	return values
+}
+
+func BenchmarkCollectStructsHint(b *testing.B) {
+	values := benchmarkRows(1000)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		rows := &fakeRows{columns: []string{"id", "display_name", "balance"}, values: values}
+		if _, err := CollectStructs[account](rows, len(values)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAppendStructs(b *testing.B) {
+	values := benchmarkRows(1000)
+	b.ReportAllocs()
+
+	var accounts []account
+	for i := 0; i < b.N; i++ {
+		var err error
+		rows := &fakeRows{columns: []string{"id", "display_name", "balance"}, values: values}
+		if accounts, err = AppendStructs(accounts[:0], rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCollectValues(b *testing.B) {
+	values := make([][]interface{}, 1000)
+	for i := range values {
+		values[i] = []interface{}{int64(i)}
+	}
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		rows := &fakeRows{columns: []string{"id"}, values: values}
+		if _, err := CollectValues[int64](rows, len(values)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestScanOne(t *testing.T) {
+	conn := &queryConn{columns: []string{"id", "display_name"}, values: [][]interface{}{{int64(1), "Alice"}, {int64(2), "Bob"}}}
+	ctx := context.Background()
+
+	var acct account
+	if err := ScanOne(ctx, conn, &acct, "select id, display_name from accounts"); err != nil {
+		t.Fatalf("Unable to scan the account: %s", err)
+	}
+
+	if acct.ID != 1 || acct.Name != "Alice" {
+		t.Errorf("Expected Alice; was %#v", acct)
+	}
+
+	ids := &queryConn{columns: []string{"id"}, values: [][]interface{}{{int64(7)}}}
+
+	var id int64
+	if err := ScanOne(ctx, ids, &id, "select id from accounts"); err != nil || id != 7 {
+		t.Errorf("Expected id 7; was %d (%v)", id, err)
+	}
+
+	empty := &queryConn{columns: []string{"id"}}
+	if err := ScanOne(ctx, empty, &id, "select id from accounts"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Expected pgx.ErrNoRows; was %v", err)
+	}
+
+	if err := ScanOne(ctx, conn, acct, "select id, display_name from accounts"); err == nil {
+		t.Error("Expected an error scanning into a non-pointer")
+	}
+}
+
+func TestScanAll(t *testing.T) {
+	conn := &queryConn{columns: []string{"id", "display_name"}, values: [][]interface{}{{int64(1), "Alice"}, {int64(2), "Bob"}}}
+	ctx := context.Background()
+
+	accounts := []account{{ID: 99}, {ID: 98}, {ID: 97}}
+	if err := ScanAll(ctx, conn, &accounts, "select id, display_name from accounts"); err != nil {
+		t.Fatalf("Unable to scan the accounts: %s", err)
+	}
+
+	if len(accounts) != 2 || accounts[0].Name != "Alice" || accounts[1].ID != 2 {
+		t.Errorf("Expected Alice and Bob to replace the contents; was %#v", accounts)
+	}
+
+	var pointers []*account
+	if err := ScanAll(ctx, conn, &pointers, "select id, display_name from accounts"); err != nil {
+		t.Fatalf("Unable to scan the accounts: %s", err)
+	}
+
+	if len(pointers) != 2 || pointers[0].Name != "Alice" || pointers[1].Name != "Bob" {
+		t.Errorf("Expected pointers to Alice and Bob; was %v", pointers)
+	}
+
+	ids := &queryConn{columns: []string{"id"}, values: [][]interface{}{{int64(1)}, {int64(2)}}}
+
+	var values []int64
+	if err := ScanAll(ctx, ids, &values, "select id from accounts"); err != nil {
+		t.Fatalf("Unable to scan the ids: %s", err)
+	}
+
+	if len(values) != 2 || values[1] != 2 {
+		t.Errorf("Expected ids 1 and 2; was %v", values)
+	}
+
+	if err := ScanAll(ctx, ids, values, "select id from accounts"); err == nil {
+		t.Error("Expected an error scanning into a slice rather than a pointer to one")
+	}
+}
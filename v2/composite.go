@@ -0,0 +1,183 @@
+package hermes
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RegisterComposite registers a PostgreSQL composite type on every connection, mapped to a Go
+// struct by field name, e.g.
+//
+//	type Address struct {
+//		Street string `db:"street"`
+//		City   string `db:"city"`
+//		Zip    string `db:"postal_code"`
+//	}
+//
+//	hermes.RegisterComposite("address", Address{})
+//
+// Struct fields are matched to the composite's fields by their `db` tag, or by their lowercased
+// name, in any order.  Composite fields without a matching struct field are ignored when scanning
+// and sent as NULL.  Values of the struct type, and slices of them, are encoded as the composite
+// and its array type.  Panics if value isn't a struct.  Best to call this before calling Connect.
+//
+// To map a composite to a struct by field order instead, simply Register the composite with a nil
+// codec; pgx scans composites into structs with the same number of exported fields, in order.
+func RegisterComposite(name string, value interface{}) {
+	t := reflect.TypeOf(value)
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("hermes: composite %s must be registered with a struct, not %T", name, value))
+	}
+
+	register(dataType{
+		name:   name,
+		values: []interface{}{value},
+		wrap: func(codec pgtype.Codec) (pgtype.Codec, error) {
+			cc, ok := codec.(*pgtype.CompositeCodec)
+			if !ok {
+				return nil, errors.New("not a composite type")
+			}
+			return newCompositeCodec(cc, t), nil
+		},
+	})
+}
+
+// compositeCodec maps a composite type to a Go struct by field name.  Other values are handled by
+// the composite codec it wraps.
+type compositeCodec struct {
+	*pgtype.CompositeCodec
+
+	structType reflect.Type
+
+	// index maps each composite field to the index of the struct field, or -1 if the struct has
+	// no matching field
+	index []int
+}
+
+func newCompositeCodec(cc *pgtype.CompositeCodec, structType reflect.Type) *compositeCodec {
+	fields := make(map[string]int)
+	for i := 0; i < structType.NumField(); i++ {
+		if name := columnName(structType.Field(i)); name != "" {
+			fields[name] = i
+		}
+	}
+
+	index := make([]int, len(cc.Fields))
+	for i, field := range cc.Fields {
+		if idx, ok := fields[field.Name]; ok {
+			index[i] = idx
+		} else {
+			index[i] = -1
+		}
+	}
+
+	return &compositeCodec{CompositeCodec: cc, structType: structType, index: index}
+}
+
+// columnName returns the database column a struct field maps to: its `db` tag, or its lowercased
+// name.  Returns a blank name for unexported fields and fields tagged `db:"-"`.
+func columnName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+
+	name := field.Tag.Get("db")
+	if name == "-" {
+		return ""
+	} else if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	return name
+}
+
+// PlanEncode returns an encode plan for values of the struct type, or defers to the composite
+// codec.
+func (c *compositeCodec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value interface{}) pgtype.EncodePlan {
+	switch reflect.TypeOf(value) {
+	case c.structType, reflect.PtrTo(c.structType):
+		next := c.CompositeCodec.PlanEncode(m, oid, format, compositeGetter{})
+		if next == nil {
+			return nil
+		}
+		return &compositeEncodePlan{codec: c, next: next}
+	}
+
+	return c.CompositeCodec.PlanEncode(m, oid, format, value)
+}
+
+// PlanScan returns a scan plan for a pointer to the struct type, or defers to the composite codec.
+func (c *compositeCodec) PlanScan(m *pgtype.Map, oid uint32, format int16, target interface{}) pgtype.ScanPlan {
+	if reflect.TypeOf(target) == reflect.PtrTo(c.structType) {
+		next := c.CompositeCodec.PlanScan(m, oid, format, compositeScanner{})
+		if next == nil {
+			return nil
+		}
+		return &compositeScanPlan{codec: c, next: next}
+	}
+
+	return c.CompositeCodec.PlanScan(m, oid, format, target)
+}
+
+type compositeEncodePlan struct {
+	codec *compositeCodec
+	next  pgtype.EncodePlan
+}
+
+func (plan *compositeEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+
+	return plan.next.Encode(compositeGetter{value: reflect.Indirect(v), index: plan.codec.index}, buf)
+}
+
+type compositeScanPlan struct {
+	codec *compositeCodec
+	next  pgtype.ScanPlan
+}
+
+func (plan *compositeScanPlan) Scan(src []byte, target interface{}) error {
+	return plan.next.Scan(src, compositeScanner{value: reflect.ValueOf(target).Elem(), index: plan.codec.index})
+}
+
+// compositeGetter presents a struct's fields in the order of the composite's fields.
+type compositeGetter struct {
+	value reflect.Value
+	index []int
+}
+
+func (g compositeGetter) IsNull() bool {
+	return false
+}
+
+func (g compositeGetter) Index(i int) interface{} {
+	if g.index[i] < 0 {
+		return nil
+	}
+	return g.value.Field(g.index[i]).Interface()
+}
+
+// compositeScanner scans the composite's fields into the matching struct fields.
+type compositeScanner struct {
+	value reflect.Value
+	index []int
+}
+
+func (s compositeScanner) ScanNull() error {
+	s.value.Set(reflect.Zero(s.value.Type()))
+	return nil
+}
+
+func (s compositeScanner) ScanIndex(i int) interface{} {
+	if s.index[i] < 0 {
+		var discard interface{}
+		return &discard
+	}
+	return s.value.Field(s.index[i]).Addr().Interface()
+}
@@ -0,0 +1,76 @@
+package hermes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type testAddress struct {
+	City    string `db:"city"`
+	Street  string
+	Ignored int `db:"-"`
+}
+
+// newTestComposite registers an "address" composite of (street text, zip int4, city text) on a
+// new type map, mapped to testAddress by name.
+func newTestComposite() (*pgtype.Map, uint32) {
+	m := pgtype.NewMap()
+
+	text, _ := m.TypeForName("text")
+	int4, _ := m.TypeForName("int4")
+
+	cc := &pgtype.CompositeCodec{Fields: []pgtype.CompositeCodecField{
+		{Name: "street", Type: text},
+		{Name: "zip", Type: int4},
+		{Name: "city", Type: text},
+	}}
+
+	const oid = 100000
+	m.RegisterType(&pgtype.Type{Name: "address", OID: oid, Codec: newCompositeCodec(cc, reflect.TypeOf(testAddress{}))})
+
+	return m, oid
+}
+
+func TestCompositeByName(t *testing.T) {
+	m, oid := newTestComposite()
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(oid, format, testAddress{City: "Boston", Street: "1 Main St", Ignored: 5}, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode address in format %d: %s", format, err)
+		}
+
+		var addr testAddress
+		if err := m.Scan(oid, format, buf, &addr); err != nil {
+			t.Fatalf("Unable to scan address in format %d: %s", format, err)
+		}
+
+		if addr.City != "Boston" || addr.Street != "1 Main St" || addr.Ignored != 0 {
+			t.Errorf("Expected Boston address; was %+v", addr)
+		}
+	}
+}
+
+func TestCompositeNull(t *testing.T) {
+	m, oid := newTestComposite()
+
+	addr := testAddress{City: "Boston"}
+	if err := m.Scan(oid, pgtype.BinaryFormatCode, nil, &addr); err != nil {
+		t.Fatalf("Unable to scan NULL address: %s", err)
+	}
+
+	if addr.City != "" {
+		t.Errorf("Expected NULL to zero the address; was %+v", addr)
+	}
+
+	buf, err := m.Encode(oid, pgtype.BinaryFormatCode, (*testAddress)(nil), nil)
+	if err != nil {
+		t.Fatalf("Unable to encode nil address: %s", err)
+	}
+
+	if buf != nil {
+		t.Error("Expected nil address to encode as NULL")
+	}
+}
@@ -0,0 +1,193 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNoPrimary is returned when recording a write on a ReplicaSet without a Primary.
+var ErrNoPrimary = errors.New("replica set has no primary")
+
+// writesKey is the context key for the writeTracker attached by TrackWrites.
+type writesKey struct{}
+
+// writeTracker remembers the furthest WAL position written during a session.
+type writeTracker struct {
+	mutex sync.Mutex
+	lsn   LSN
+
+	// lost is set when a write's position couldn't be read, so the session reads from the
+	// primary from then on
+	lost bool
+}
+
+// TrackWrites returns a copy of ctx that tracks the WAL position of the session's writes, so
+// reads made with it through ReplicaSet.Reader or a Cluster see those writes.  Call it once per
+// session, e.g. in middleware at the start of each request.  Writes made with the context through
+// a DB are recorded automatically:  after Exec, CopyFrom, or SendBatch succeeds, or a transaction
+// commits with the context, the DB asks for its current WAL position, at the cost of an extra
+// round trip.  Writes made any other way, e.g. INSERT ... RETURNING run with Query, need
+// ReplicaSet.RecordWrite.  Pass the position the session last wrote, e.g.
+// from a cookie set on a previous request, to carry read-your-writes across requests; otherwise
+// pass zero.
+//
+// If ctx is already tracking writes, the existing tracker is kept, moved forward to after.
+func TrackWrites(ctx context.Context, after LSN) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if tracker, ok := ctx.Value(writesKey{}).(*writeTracker); ok {
+		tracker.advance(after)
+		return ctx
+	}
+
+	return context.WithValue(ctx, writesKey{}, &writeTracker{lsn: after})
+}
+
+// WrittenLSN returns the furthest WAL position written during the session tracked by ctx, or zero
+// if ctx isn't tracking writes or nothing has been written.
+func WrittenLSN(ctx context.Context) LSN {
+	if ctx == nil {
+		return 0
+	}
+
+	tracker, ok := ctx.Value(writesKey{}).(*writeTracker)
+	if !ok {
+		return 0
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	return tracker.lsn
+}
+
+// readAfter returns the WAL position a replica must have replayed to serve the session's reads.
+// If a write's position was lost, no replica can serve them.
+func readAfter(ctx context.Context) LSN {
+	if ctx == nil {
+		return 0
+	}
+
+	tracker, ok := ctx.Value(writesKey{}).(*writeTracker)
+	if !ok {
+		return 0
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if tracker.lost {
+		return ^LSN(0)
+	}
+
+	return tracker.lsn
+}
+
+// advance moves the tracker forward to lsn, if it's further along.
+func (tracker *writeTracker) advance(lsn LSN) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if lsn > tracker.lsn {
+		tracker.lsn = lsn
+	}
+}
+
+// RecordWrite asks the primary for its current WAL insert position and records it in the session
+// tracked by ctx (see TrackWrites).  Writes through the DB are recorded automatically; call it
+// after writes made some other way, once the transaction commits.  Subsequent reads through
+// Reader go to the primary until a replica has replayed past it.  Returns the position, e.g. to
+// store in a cookie for the session's next request.
+func (rs *ReplicaSet) RecordWrite(ctx context.Context) (LSN, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if rs.Primary == nil {
+		return 0, ErrNoPrimary
+	}
+
+	lsn, err := rs.Primary.currentLSN(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if tracker, ok := ctx.Value(writesKey{}).(*writeTracker); ok {
+		tracker.advance(lsn)
+	}
+
+	return lsn, nil
+}
+
+// recordWrite records the DB's current WAL insert position in the session tracked by ctx, if
+// any, after a write through the DB.  If the position can't be read, the session's reads go to
+// the primary from then on, so they still see the write.
+func (db *DB) recordWrite(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+
+	tracker, ok := ctx.Value(writesKey{}).(*writeTracker)
+	if !ok {
+		return
+	}
+
+	lsn, err := db.currentLSN(ctx)
+	if err != nil {
+		tracker.mutex.Lock()
+		tracker.lost = true
+		tracker.mutex.Unlock()
+		return
+	}
+
+	tracker.advance(lsn)
+}
+
+// currentLSN returns the DB's current WAL insert position.  It queries the pool directly, as the
+// write being recorded may still hold the DB's last concurrency slot.
+func (db *DB) currentLSN(ctx context.Context) (LSN, error) {
+	var current string
+	if err := db.Pool.QueryRow(ctx, "SELECT pg_current_wal_insert_lsn()::text").Scan(&current); err != nil {
+		return 0, err
+	}
+
+	return ParseLSN(current)
+}
+
+// Reader returns the connection pool to read from in the session tracked by ctx: a healthy
+// replica that has replayed past the session's last write, or the Primary if none has.  Replay
+// positions are updated by each probe, so reads fall back to the primary for up to a
+// ProbeInterval after a write.  Returns nil if no replica is available and there's no Primary.
+func (rs *ReplicaSet) Reader(ctx context.Context) *DB {
+	if replica := rs.pick(readAfter(ctx)); replica != nil {
+		return replica.DB
+	}
+
+	return rs.Primary
+}
+
+// trackedBatchResults record the batch as a write in the session tracked by ctx once the results
+// are closed.
+type trackedBatchResults struct {
+	pgx.BatchResults
+	db       *DB
+	ctx      context.Context
+	recorded bool
+}
+
+// Close the results and record the write, if the batch succeeded.
+func (br *trackedBatchResults) Close() error {
+	err := br.BatchResults.Close()
+
+	if err == nil && !br.recorded {
+		br.recorded = true
+		br.db.recordWrite(br.ctx)
+	}
+
+	return err
+}
@@ -0,0 +1,63 @@
+package hermes
+
+import "context"
+
+// connKey is the context key for the Conn stored by NewContext.
+type connKey struct{}
+
+// NewContext returns a copy of ctx carrying the Conn, e.g. a transaction begun by middleware for
+// the request, so handlers deeper in the stack can retrieve it with FromContext rather than
+// passing it through every function.
+func NewContext(ctx context.Context, conn Conn) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// FromContext returns the Conn stored in ctx by NewContext, if any.
+func FromContext(ctx context.Context) (Conn, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	conn, ok := ctx.Value(connKey{}).(Conn)
+	return conn, ok && conn != nil
+}
+
+// FromContextOr returns the Conn stored in ctx by NewContext, or fallback if there isn't one,
+// typically the DB.  Functions that may run in a request's transaction or on their own can use
+// it to pick up the transaction when there is one.
+func FromContextOr(ctx context.Context, fallback Conn) Conn {
+	if conn, ok := FromContext(ctx); ok {
+		return conn
+	}
+
+	return fallback
+}
+
+// RunInTxContext runs fn in a transaction, passing it a context carrying the transaction, so the
+// functions fn calls can retrieve it with FromContext or FromContextOr.  If ctx already carries a
+// Conn, the transaction is begun on it, becoming a savepoint when the Conn is a transaction;
+// otherwise it's begun on the DB.  Service layers can then group their work without knowing
+// whether they're called in a transaction:
+//
+//	err := db.RunInTxContext(ctx, func(ctx context.Context) error {
+//		if err := users.Save(ctx, u); err != nil {
+//			return err
+//		}
+//		return audit.Record(ctx, "user saved")
+//	})
+//
+// If fn returns nil, the transaction is committed; if it returns an error or panics, it's rolled
+// back, as with WithTransaction.  Don't hold onto the context once fn returns.
+func (db *DB) RunInTxContext(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return WithTransaction(ctx, FromContextOr(ctx, db), func(tx Conn) error {
+		return fn(NewContext(ctx, tx))
+	})
+}
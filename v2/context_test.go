@@ -0,0 +1,73 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestConnContext(t *testing.T) {
+	db := &DB{}
+	tx := &Tx{}
+
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("Expected no Conn in an empty context")
+	}
+
+	if conn := FromContextOr(context.Background(), db); conn != db {
+		t.Errorf("Expected the fallback; was %v", conn)
+	}
+
+	ctx := NewContext(context.Background(), tx)
+
+	if conn, ok := FromContext(ctx); !ok || conn != tx {
+		t.Errorf("Expected the transaction; was %v", conn)
+	}
+
+	if conn := FromContextOr(ctx, db); conn != tx {
+		t.Errorf("Expected the transaction over the fallback; was %v", conn)
+	}
+
+	if _, ok := FromContext(NewContext(context.Background(), nil)); ok {
+		t.Error("Expected a nil Conn not to be found")
+	}
+}
+
+func TestRunInTxContext(t *testing.T) {
+	parent := &Tx{Tx: &fakeTx{}}
+	ctx := NewContext(context.Background(), parent)
+
+	var savepoint *fakeTx
+	err := (&DB{}).RunInTxContext(ctx, func(ctx context.Context) error {
+		conn, ok := FromContext(ctx)
+		if !ok || conn == parent {
+			t.Fatalf("Expected a savepoint in the context; was %v", conn)
+		}
+
+		savepoint = conn.(*Tx).Tx.(*fakeTx)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Unable to run the transaction: %s", err)
+	}
+
+	if savepoint.committed != 1 || savepoint.rolledBack != 0 {
+		t.Errorf("Expected the savepoint to commit; was %d commits and %d rollbacks", savepoint.committed, savepoint.rolledBack)
+	}
+
+	failed := errors.New("failed")
+
+	err = (&DB{}).RunInTxContext(ctx, func(ctx context.Context) error {
+		savepoint = FromContextOr(ctx, nil).(*Tx).Tx.(*fakeTx)
+		return failed
+	})
+
+	if err != failed {
+		t.Errorf("Expected the function's error; was %v", err)
+	}
+
+	if savepoint.committed != 0 || savepoint.rolledBack != 1 {
+		t.Errorf("Expected the savepoint to roll back; was %d commits and %d rollbacks", savepoint.committed, savepoint.rolledBack)
+	}
+}
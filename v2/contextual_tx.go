@@ -2,6 +2,7 @@ package hermes
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -18,7 +19,7 @@ func (tx *Tx) BeginWithTimeout(ctx context.Context) (*ContextualTx, error) {
 		return nil, err
 	}
 
-	return &ContextualTx{newTx, ctx, cancel}, nil
+	return &ContextualTx{Tx: newTx, ctx: ctx, cancel: cancel}, nil
 }
 
 // ContextualTx is a prototype for starting a transaction using the default timeout and using the
@@ -31,17 +32,40 @@ type ContextualTx struct {
 
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// db is set for root transactions so the DB can track open transactions.
+	db    *DB
+	ended int32
+
+	// limited transactions hold one of the DB's concurrency slots until they end
+	limited bool
 }
 
 // Commit the transaction.  Does nothing if Conn is a *pgxpool.Pool.  If the transaction is
 // a psuedo-transaction, i.e. a savepoint, releases the savepoint.  Otherwise commits the
 // transaction.
-func (tx *ContextualTx) Commit() error {
-	return tx.Tx.Commit(tx.ctx)
+func (tx *ContextualTx) Commit() (err error) {
+	defer func() {
+		tx.db.txEnded(&tx.ended, tx.limited, err == nil)
+	}()
+
+	started := time.Now()
+	err = tx.Tx.Commit(tx.ctx)
+
+	if tx.db != nil {
+		tx.db.metrics().ObserveTxCommit(time.Since(started), err)
+
+		if err == nil {
+			tx.db.recordWrite(tx.ctx)
+		}
+	}
+
+	return err
 }
 
 // Rollback the transaction. Does nothing if Conn is a *pgxpool.Pool.
 func (tx *ContextualTx) Rollback() error {
+	defer tx.db.txEnded(&tx.ended, tx.limited, false)
 	return tx.Tx.Rollback(tx.ctx)
 }
 
@@ -0,0 +1,483 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// DefaultCopyBuffer is the number of bytes of encoded rows collected before they're sent to
+	// the database, the same as pgx's CopyFrom.
+	DefaultCopyBuffer = 64 * 1024
+
+	// DefaultCopyBatch is the number of rows each worker encodes at a time.
+	DefaultCopyBatch = 1000
+
+	// copyQueue is the number of encoded buffers waiting to be sent while encoding continues.
+	copyQueue = 4
+)
+
+// copyHeader starts a binary COPY stream:  the signature, flags, and header extension length.
+var copyHeader = []byte("PGCOPY\n\377\r\n\000\000\000\000\000\000\000\000\000")
+
+// CopyOptions tune CopyFromWithOptions.
+type CopyOptions struct {
+	// BufferSize is the number of bytes of encoded rows collected before they're sent to the
+	// database.  Defaults to DefaultCopyBuffer.
+	BufferSize int
+
+	// Workers is the number of goroutines encoding rows.  Defaults to 1, which encodes rows one
+	// at a time as pgx does, but still encodes the next rows while the last are being sent.  With
+	// more than one worker, rows are read from the source in batches and encoded concurrently,
+	// then sent in order.
+	Workers int
+
+	// BatchRows is the number of rows each worker encodes at a time.  Defaults to
+	// DefaultCopyBatch.
+	BatchRows int
+}
+
+// CopyFromWithOptions copies rows into the table using the PostgreSQL binary COPY protocol, like
+// pgx's CopyFrom, but with a configurable buffer and optional concurrent encoding for large bulk
+// loads.  Returns the number of rows copied.
+//
+// With more than one worker, the values returned by rowSrc must not be modified once they've been
+// returned, as they may still be waiting to be encoded.  The slice itself may be reused.
+//
+// If conn isn't one of the hermes connection types, falls back to conn.CopyFrom.
+func CopyFromWithOptions(ctx context.Context, conn Conn, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource, opts CopyOptions) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var pc *pgx.Conn
+
+	switch c := conn.(type) {
+	case *DB:
+		if err := c.acquireSlot(ctx); err != nil {
+			return 0, err
+		}
+		defer c.releaseSlot()
+
+		acquired, err := c.Acquire(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer acquired.Release()
+
+		pc = acquired.Conn()
+	case *PinnedConn:
+		pc = c.Conn.Conn()
+	case *Tx:
+		pc = c.Tx.Conn()
+	default:
+		return conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	}
+
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultCopyBuffer
+	}
+
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+
+	if opts.BatchRows <= 0 {
+		opts.BatchRows = DefaultCopyBatch
+	}
+
+	c := &copier{conn: pc, m: pc.TypeMap(), columns: columnNames, src: rowSrc, opts: opts}
+	return c.run(ctx, tableName)
+}
+
+// copier runs a single CopyFromWithOptions.
+type copier struct {
+	conn    *pgx.Conn
+	m       *pgtype.Map
+	columns []string
+	oids    []uint32
+	src     pgx.CopyFromSource
+	opts    CopyOptions
+
+	// fallback serializes the type map's Scan calls, which aren't safe for concurrent use
+	fallback sync.Mutex
+
+	// batchBufs recycles the workers' buffers
+	batchBufs sync.Pool
+}
+
+// run the COPY, encoding the rows in the background as they're sent.
+func (c *copier) run(ctx context.Context, tableName pgx.Identifier) (int64, error) {
+	tracer, _ := c.conn.Config().Tracer.(pgx.CopyFromTracer)
+	if tracer != nil {
+		ctx = tracer.TraceCopyFromStart(ctx, c.conn, pgx.TraceCopyFromStartData{
+			TableName:   tableName,
+			ColumnNames: c.columns,
+		})
+	}
+
+	quoted := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+	columns := strings.Join(quoted, ", ")
+	table := tableName.Sanitize()
+
+	var count int64
+	var err error
+
+	sd, err := c.conn.Prepare(ctx, "", fmt.Sprintf("select %s from %s", columns, table))
+	if err == nil {
+		c.oids = make([]uint32, len(sd.Fields))
+		for i, field := range sd.Fields {
+			c.oids[i] = field.DataTypeOID
+		}
+
+		// Builds the type map's lazy lookup table now, so encoding only reads from the map
+		c.m.TypeForValue("")
+
+		stream := newCopyStream(c.opts.BufferSize)
+		encodeCtx, cancel := context.WithCancel(ctx)
+		encoded := make(chan struct{})
+
+		go func() {
+			defer close(encoded)
+			stream.finish(c.encode(encodeCtx, stream))
+		}()
+
+		tag, copyErr := c.conn.PgConn().CopyFrom(ctx, stream, fmt.Sprintf("copy %s ( %s ) from stdin binary;", table, columns))
+		cancel()
+		<-encoded
+
+		count, err = tag.RowsAffected(), copyErr
+		if encodeErr := stream.err; encodeErr != nil && !errors.Is(encodeErr, context.Canceled) {
+			err = encodeErr
+		}
+	}
+
+	if tracer != nil {
+		tracer.TraceCopyFromEnd(ctx, c.conn, pgx.TraceCopyFromEndData{Err: err})
+	}
+
+	return count, err
+}
+
+// encode the rows into the stream.
+func (c *copier) encode(ctx context.Context, stream *copyStream) error {
+	buf := append(stream.buffer(), copyHeader...)
+
+	var err error
+	if c.opts.Workers > 1 {
+		buf, err = c.encodeConcurrently(ctx, stream, buf)
+	} else {
+		buf, err = c.encodeRows(ctx, stream, buf)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// File trailer
+	buf = append(buf, 0xff, 0xff)
+	return stream.send(ctx, buf)
+}
+
+// encodeRows encodes each row as it's read from the source, sending the buffer to the stream as
+// it fills.
+func (c *copier) encodeRows(ctx context.Context, stream *copyStream, buf []byte) ([]byte, error) {
+	for c.src.Next() {
+		values, err := c.src.Values()
+		if err != nil {
+			return nil, err
+		}
+
+		if buf, err = c.encodeRow(buf, values); err != nil {
+			return nil, err
+		}
+
+		if len(buf) >= c.opts.BufferSize {
+			if err := stream.send(ctx, buf); err != nil {
+				return nil, err
+			}
+			buf = stream.buffer()
+		}
+	}
+
+	return buf, c.src.Err()
+}
+
+// copyBatch is a batch of rows to be encoded by a worker.
+type copyBatch struct {
+	rows [][]interface{}
+	out  chan copyEncoded
+}
+
+// copyEncoded is a worker's encoded batch of rows.
+type copyEncoded struct {
+	buf []byte
+	err error
+}
+
+// encodeConcurrently reads batches of rows from the source and encodes them on the workers,
+// sending the encoded batches to the stream in the order they were read.
+func (c *copier) encodeConcurrently(ctx context.Context, stream *copyStream, buf []byte) ([]byte, error) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Cancelled ahead of the wait, so the reader stops if encoding fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan copyBatch)
+	order := make(chan chan copyEncoded, c.opts.Workers*2)
+
+	var readErr error
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(order)
+		defer close(batches)
+
+		readErr = c.readBatches(ctx, batches, order)
+	}()
+
+	for i := 0; i < c.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for batch := range batches {
+				var result copyEncoded
+				if buf, ok := c.batchBufs.Get().([]byte); ok {
+					result.buf = buf[:0]
+				}
+
+				for _, values := range batch.rows {
+					if result.buf, result.err = c.encodeRow(result.buf, values); result.err != nil {
+						break
+					}
+				}
+				batch.out <- result
+			}
+		}()
+	}
+
+	for out := range order {
+		result := <-out
+		if result.err != nil {
+			return nil, result.err
+		}
+
+		if len(buf) == 0 && len(result.buf) >= c.opts.BufferSize {
+			buf = result.buf
+		} else {
+			buf = append(buf, result.buf...)
+			c.batchBufs.Put(result.buf)
+		}
+
+		if len(buf) >= c.opts.BufferSize {
+			if err := stream.send(ctx, buf); err != nil {
+				return nil, err
+			}
+			buf = stream.buffer()
+		}
+	}
+
+	// Wait for the reader to finish before checking its error
+	cancel()
+	wg.Wait()
+
+	return buf, readErr
+}
+
+// readBatches reads the rows from the source into batches, queuing them for the workers.
+func (c *copier) readBatches(ctx context.Context, batches chan<- copyBatch, order chan<- chan copyEncoded) error {
+	for {
+		rows := make([][]interface{}, 0, c.opts.BatchRows)
+		for len(rows) < c.opts.BatchRows && c.src.Next() {
+			values, err := c.src.Values()
+			if err != nil {
+				return err
+			}
+
+			// Sources may reuse the slice for the next row
+			rows = append(rows, append([]interface{}(nil), values...))
+		}
+
+		if len(rows) == 0 {
+			return c.src.Err()
+		}
+
+		out := make(chan copyEncoded, 1)
+
+		select {
+		case order <- out:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		select {
+		case batches <- copyBatch{rows: rows, out: out}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// encodeRow appends the row in the binary COPY format.
+func (c *copier) encodeRow(buf []byte, values []interface{}) ([]byte, error) {
+	if len(values) != len(c.columns) {
+		return nil, fmt.Errorf("expected %d values, got %d values", len(c.columns), len(values))
+	}
+
+	buf = append(buf, byte(len(values)>>8), byte(len(values)))
+
+	for i, value := range values {
+		if isNil(value) {
+			buf = append(buf, 0xff, 0xff, 0xff, 0xff)
+			continue
+		}
+
+		start := len(buf)
+		buf = append(buf, 0, 0, 0, 0)
+
+		encoded, err := c.m.Encode(c.oids[i], pgtype.BinaryFormatCode, value, buf)
+		if err != nil {
+			if encoded, err = c.encodeString(c.oids[i], value, buf); err != nil {
+				return nil, err
+			}
+		}
+
+		if encoded == nil {
+			buf = append(buf[:start], 0xff, 0xff, 0xff, 0xff)
+			continue
+		}
+
+		buf = encoded
+		size := len(buf) - start - 4
+		buf[start], buf[start+1], buf[start+2], buf[start+3] = byte(size>>24), byte(size>>16), byte(size>>8), byte(size)
+	}
+
+	return buf, nil
+}
+
+// encodeString parses a string value in the text format and encodes the result in the binary
+// format, as pgx does for values it can't encode directly.
+func (c *copier) encodeString(oid uint32, value interface{}, buf []byte) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		_, err := c.m.Encode(oid, pgtype.BinaryFormatCode, value, buf)
+		return nil, err
+	}
+
+	c.fallback.Lock()
+	defer c.fallback.Unlock()
+
+	var v interface{}
+	if err := c.m.Scan(oid, pgtype.TextFormatCode, []byte(s), &v); err != nil {
+		return nil, err
+	}
+
+	return c.m.Encode(oid, pgtype.BinaryFormatCode, v, buf)
+}
+
+// isNil returns true if the value is nil or a nil pointer, slice, map, or the like.
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Map, reflect.Ptr, reflect.UnsafePointer, reflect.Interface, reflect.Slice:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// copyStream is the io.Reader the connection reads the encoded rows from.  Encoded buffers are
+// queued so encoding can continue while the last buffer is sent, and recycled once they've been
+// read.
+type copyStream struct {
+	size   int
+	chunks chan []byte
+	free   chan []byte
+
+	// err is set before chunks is closed
+	err error
+
+	current []byte
+	read    []byte
+}
+
+func newCopyStream(size int) *copyStream {
+	return &copyStream{
+		size:   size,
+		chunks: make(chan []byte, copyQueue),
+		free:   make(chan []byte, copyQueue+1),
+	}
+}
+
+// buffer returns an empty buffer, reusing one that's been read if available.
+func (s *copyStream) buffer() []byte {
+	select {
+	case buf := <-s.free:
+		return buf[:0]
+	default:
+		return make([]byte, 0, s.size+s.size/4)
+	}
+}
+
+// send queues the buffer to be read by the connection.
+func (s *copyStream) send(ctx context.Context, buf []byte) error {
+	select {
+	case s.chunks <- buf:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish ends the stream.  The connection's next read returns err, or io.EOF if err is nil.
+func (s *copyStream) finish(err error) {
+	s.err = err
+	close(s.chunks)
+}
+
+// Read implements io.Reader.
+func (s *copyStream) Read(p []byte) (int, error) {
+	for len(s.current) == 0 {
+		if s.read != nil {
+			select {
+			case s.free <- s.read:
+			default:
+			}
+			s.read = nil
+		}
+
+		chunk, ok := <-s.chunks
+		if !ok {
+			if s.err != nil {
+				return 0, s.err
+			}
+			return 0, io.EOF
+		}
+
+		s.current, s.read = chunk, chunk
+	}
+
+	n := copy(p, s.current)
+	s.current = s.current[n:]
+
+	return n, nil
+}
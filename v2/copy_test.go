@@ -0,0 +1,131 @@
+package hermes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// encodeCopy runs the copier's encoding against the rows and returns the COPY stream.
+func encodeCopy(rows [][]interface{}, opts CopyOptions) ([]byte, error) {
+	if opts.BufferSize == 0 {
+		opts.BufferSize = DefaultCopyBuffer
+	}
+
+	if opts.Workers == 0 {
+		opts.Workers = 1
+	}
+
+	if opts.BatchRows == 0 {
+		opts.BatchRows = DefaultCopyBatch
+	}
+
+	c := &copier{
+		m:       pgtype.NewMap(),
+		columns: []string{"id", "name", "created_at"},
+		oids:    []uint32{pgtype.Int4OID, pgtype.TextOID, pgtype.TimestamptzOID},
+		src:     pgx.CopyFromRows(rows),
+		opts:    opts,
+	}
+
+	stream := newCopyStream(opts.BufferSize)
+	go func() {
+		stream.finish(c.encode(context.Background(), stream))
+	}()
+
+	return io.ReadAll(stream)
+}
+
+func copyRows(n int) [][]interface{} {
+	created := time.Date(2022, 11, 30, 12, 0, 0, 0, time.UTC)
+
+	rows := make([][]interface{}, n)
+	for i := range rows {
+		rows[i] = []interface{}{i, fmt.Sprintf("user %d", i), created.Add(time.Duration(i) * time.Second)}
+	}
+
+	return rows
+}
+
+func TestCopyEncode(t *testing.T) {
+	var name *string
+	buf, err := encodeCopy([][]interface{}{{1, "a", nil}, {"2", name, nil}}, CopyOptions{})
+	if err != nil {
+		t.Fatalf("Unable to encode rows: %s", err)
+	}
+
+	expected := append([]byte{}, copyHeader...)
+	expected = append(expected,
+		0, 3, 0, 0, 0, 4, 0, 0, 0, 1, 0, 0, 0, 1, 'a', 0xff, 0xff, 0xff, 0xff,
+		0, 3, 0, 0, 0, 4, 0, 0, 0, 2, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff)
+
+	if !bytes.Equal(buf, expected) {
+		t.Errorf("Expected %v; was %v", expected, buf)
+	}
+}
+
+func TestCopyEncodeConcurrently(t *testing.T) {
+	rows := copyRows(2500)
+
+	expected, err := encodeCopy(rows, CopyOptions{})
+	if err != nil {
+		t.Fatalf("Unable to encode rows: %s", err)
+	}
+
+	buf, err := encodeCopy(rows, CopyOptions{Workers: 4, BatchRows: 100, BufferSize: 1024})
+	if err != nil {
+		t.Fatalf("Unable to encode rows concurrently: %s", err)
+	}
+
+	if !bytes.Equal(buf, expected) {
+		t.Errorf("Expected concurrent encoding to match; was %d bytes, expected %d", len(buf), len(expected))
+	}
+}
+
+func TestCopyEncodeError(t *testing.T) {
+	rows := copyRows(500)
+	rows[321] = []interface{}{1, "short"}
+
+	for _, workers := range []int{1, 4} {
+		_, err := encodeCopy(rows, CopyOptions{Workers: workers, BatchRows: 10, BufferSize: 256})
+		if err == nil {
+			t.Errorf("Expected an error encoding a short row with %d workers", workers)
+		}
+	}
+
+	rows[321] = []interface{}{"x", "bad id", time.Now()}
+
+	if _, err := encodeCopy(rows, CopyOptions{Workers: 4, BatchRows: 10}); err == nil {
+		t.Error("Expected an error encoding an invalid integer")
+	}
+}
+
+func benchmarkCopyEncode(b *testing.B, opts CopyOptions) {
+	rows := copyRows(100000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := encodeCopy(rows, opts); err != nil {
+			b.Fatalf("Unable to encode rows: %s", err)
+		}
+	}
+}
+
+func BenchmarkCopyEncode(b *testing.B) {
+	benchmarkCopyEncode(b, CopyOptions{})
+}
+
+func BenchmarkCopyEncodeLargeBuffer(b *testing.B) {
+	benchmarkCopyEncode(b, CopyOptions{BufferSize: 1024 * 1024})
+}
+
+func BenchmarkCopyEncodeWorkers(b *testing.B) {
+	benchmarkCopyEncode(b, CopyOptions{Workers: 4})
+}
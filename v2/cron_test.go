@@ -0,0 +1,59 @@
+package hermes_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+func TestCronNext(t *testing.T) {
+	// Wednesday, March 15th 2023, 10:07
+	now := time.Date(2023, time.March, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2023, time.March, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, time.March, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2023, time.March, 16, 9, 0, 0, 0, time.UTC)},
+		{"30 8-10 * * *", time.Date(2023, time.March, 15, 10, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 1,5", time.Date(2023, time.March, 17, 12, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2023, time.March, 15, 11, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		sched, err := hermes.ParseCron(test.spec)
+		if err != nil {
+			t.Errorf("Unable to parse %q: %s", test.spec, err)
+			continue
+		}
+
+		if next := sched.Next(now); !next.Equal(test.expected) {
+			t.Errorf("Expected %q to run next at %s; was %s", test.spec, test.expected, next)
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := hermes.ParseCron(spec); err == nil {
+			t.Errorf("Expected %q to be invalid", spec)
+		}
+	}
+}
+
+func TestCronNever(t *testing.T) {
+	sched, err := hermes.ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if next := sched.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Expected February 30th never to match; was %s", next)
+	}
+}
@@ -0,0 +1,173 @@
+package hermes
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrCopyStream is returned when a Conn can't stream COPY data, e.g. a Pipeline.
+var ErrCopyStream = errors.New("connection doesn't support streaming COPY")
+
+// CSVOptions configure how CopyCSV reads the CSV.  The zero value reads comma-separated values
+// without a header, with unquoted empty values as NULL, as PostgreSQL does.
+type CSVOptions struct {
+	// Header is true if the first line names the columns.  The header is skipped, or if no
+	// columns are given to CopyCSV, used as the columns.
+	Header bool
+
+	// MatchHeader has PostgreSQL check the header names the columns given to CopyCSV, in order,
+	// failing the copy if it doesn't.  Requires PostgreSQL 15 or later.
+	MatchHeader bool
+
+	// Delimiter separates the values on each line.  Defaults to a comma.
+	Delimiter rune
+
+	// Null is the unquoted value read as NULL.  Defaults to an empty value.
+	Null string
+
+	// ForceNull lists the columns where the Null value is read as NULL even when it's quoted.
+	ForceNull []string
+
+	// Quote surrounds values containing the delimiter, quotes, or line breaks.  Defaults to a
+	// double quote.
+	Quote rune
+
+	// Escape precedes a quote within a quoted value.  Defaults to the Quote, i.e. quotes are
+	// doubled.
+	Escape rune
+
+	// Encoding is the character set of the CSV, e.g. "LATIN1".  Defaults to the client encoding.
+	Encoding string
+}
+
+// CopyCSV streams CSV from r into the table's columns using the COPY protocol, so files can be
+// loaded without parsing them into rows first.  PostgreSQL parses the CSV, so values take the
+// columns' text formats, e.g. "2022-11-30" for a date.  Returns the number of rows copied.
+//
+// If columns is empty and opts.Header is set, the header's names are used as the columns.  Only
+// the header is parsed locally, so it must use a double quote to quote names.
+func CopyCSV(ctx context.Context, conn Conn, tableName pgx.Identifier, columns []string, r io.Reader, opts CSVOptions) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(columns) == 0 && opts.Header {
+		var err error
+		if columns, r, err = csvHeader(r, opts.Delimiter); err != nil {
+			return 0, err
+		}
+
+		// The header's been read; don't skip the first row too
+		opts.Header, opts.MatchHeader = false, false
+	}
+
+	sql := copyCSVSQL(tableName, columns, opts)
+
+	switch c := conn.(type) {
+	case *DB:
+		if err := c.acquireSlot(ctx); err != nil {
+			return 0, err
+		}
+		defer c.releaseSlot()
+
+		acquired, err := c.Acquire(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer acquired.Release()
+
+		tag, err := acquired.Conn().PgConn().CopyFrom(ctx, r, sql)
+		return tag.RowsAffected(), err
+	case *PinnedConn:
+		tag, err := c.Conn.Conn().PgConn().CopyFrom(ctx, r, sql)
+		return tag.RowsAffected(), err
+	case *Tx:
+		tag, err := c.Tx.Conn().PgConn().CopyFrom(ctx, r, sql)
+		return tag.RowsAffected(), err
+	}
+
+	return 0, fmt.Errorf("%w: %T", ErrCopyStream, conn)
+}
+
+// csvHeader reads the column names from the first line of the CSV, returning them and a reader
+// for the rest.
+func csvHeader(r io.Reader, delimiter rune) ([]string, io.Reader, error) {
+	// The csv.Reader reads through the bufio.Reader a line at a time, so the rest is left for
+	// the copy
+	buffered := bufio.NewReader(r)
+
+	header := csv.NewReader(buffered)
+	if delimiter != 0 {
+		header.Comma = delimiter
+	}
+
+	columns, err := header.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read the CSV header: %w", err)
+	}
+
+	return columns, buffered, nil
+}
+
+// copyCSVSQL returns the COPY statement for the table's columns and the options.
+func copyCSVSQL(tableName pgx.Identifier, columns []string, opts CSVOptions) string {
+	var sql strings.Builder
+
+	sql.WriteString("COPY ")
+	sql.WriteString(tableName.Sanitize())
+
+	if len(columns) > 0 {
+		sql.WriteString(" (")
+		sql.WriteString(quoteColumns(columns))
+		sql.WriteString(")")
+	}
+
+	sql.WriteString(" FROM STDIN WITH (FORMAT csv")
+
+	switch {
+	case opts.MatchHeader:
+		sql.WriteString(", HEADER MATCH")
+	case opts.Header:
+		sql.WriteString(", HEADER true")
+	}
+
+	if opts.Delimiter != 0 {
+		sql.WriteString(", DELIMITER " + quoteLiteral(string(opts.Delimiter)))
+	}
+
+	if opts.Null != "" {
+		sql.WriteString(", NULL " + quoteLiteral(opts.Null))
+	}
+
+	if len(opts.ForceNull) > 0 {
+		sql.WriteString(", FORCE_NULL (" + quoteColumns(opts.ForceNull) + ")")
+	}
+
+	if opts.Quote != 0 {
+		sql.WriteString(", QUOTE " + quoteLiteral(string(opts.Quote)))
+	}
+
+	if opts.Escape != 0 {
+		sql.WriteString(", ESCAPE " + quoteLiteral(string(opts.Escape)))
+	}
+
+	if opts.Encoding != "" {
+		sql.WriteString(", ENCODING " + quoteLiteral(opts.Encoding))
+	}
+
+	sql.WriteString(")")
+
+	return sql.String()
+}
+
+// quoteLiteral returns s as a string literal, assuming standard_conforming_strings.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
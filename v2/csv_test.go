@@ -0,0 +1,59 @@
+package hermes
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestCopyCSVSQL(t *testing.T) {
+	table := pgx.Identifier{"app", "events"}
+
+	tests := []struct {
+		columns  []string
+		opts     CSVOptions
+		expected string
+	}{
+		{nil, CSVOptions{}, `COPY "app"."events" FROM STDIN WITH (FORMAT csv)`},
+		{[]string{"id", "name"}, CSVOptions{Header: true}, `COPY "app"."events" ("id", "name") FROM STDIN WITH (FORMAT csv, HEADER true)`},
+		{[]string{"id"}, CSVOptions{Header: true, MatchHeader: true}, `COPY "app"."events" ("id") FROM STDIN WITH (FORMAT csv, HEADER MATCH)`},
+		{
+			[]string{"id", "name"},
+			CSVOptions{Delimiter: '\t', Null: `\N`, ForceNull: []string{"name"}, Quote: '\'', Escape: '\\', Encoding: "LATIN1"},
+			"COPY \"app\".\"events\" (\"id\", \"name\") FROM STDIN WITH (FORMAT csv, DELIMITER '\t', NULL '\\N', " +
+				`FORCE_NULL ("name"), QUOTE '''', ESCAPE '\', ENCODING 'LATIN1')`,
+		},
+	}
+
+	for _, test := range tests {
+		if sql := copyCSVSQL(table, test.columns, test.opts); sql != test.expected {
+			t.Errorf("Expected %s; was %s", test.expected, sql)
+		}
+	}
+}
+
+func TestCSVHeader(t *testing.T) {
+	columns, rest, err := csvHeader(strings.NewReader("id;\"full name\"\n1;\"Smith; John\"\n2;\n"), ';')
+	if err != nil {
+		t.Fatalf("Unable to read the header: %s", err)
+	}
+
+	if len(columns) != 2 || columns[0] != "id" || columns[1] != "full name" {
+		t.Errorf("Expected columns id and full name; was %q", columns)
+	}
+
+	body, err := io.ReadAll(rest)
+	if err != nil {
+		t.Fatalf("Unable to read the rest of the CSV: %s", err)
+	}
+
+	if expected := "1;\"Smith; John\"\n2;\n"; string(body) != expected {
+		t.Errorf("Expected %q; was %q", expected, body)
+	}
+
+	if _, _, err := csvHeader(strings.NewReader(""), 0); err == nil {
+		t.Error("Expected an error reading the header of an empty CSV")
+	}
+}
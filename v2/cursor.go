@@ -0,0 +1,261 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DefaultFetchSize is the number of rows fetched from a cursor at a time if CursorQuery.FetchSize
+// or the batch size given to QueryCursor isn't set.
+const DefaultFetchSize = 1000
+
+// cursors counts the cursors declared by Reduce and QueryCursor, to name them uniquely.
+var cursors int64
+
+// CursorQuery is a query read in batches through a server-side cursor by Reduce.
+type CursorQuery struct {
+	// SQL selects the rows to process.
+	SQL string
+
+	// Args are the arguments to the SQL.
+	Args []interface{}
+
+	// FetchSize is the number of rows fetched at a time.  Defaults to DefaultFetchSize.
+	FetchSize int
+}
+
+// Reduce runs the query through a server-side cursor, fetching FetchSize rows at a time, scanning
+// them into structs of type T, and calling fn with each batch.  Only one batch is held in memory
+// at a time, however large the result set, so jobs that aggregate millions of rows don't run out
+// of memory:
+//
+//	var total decimal.Decimal
+//	err := hermes.Reduce(ctx, db, hermes.CursorQuery{
+//	    SQL:  "select amount from payments where paid_at >= $1",
+//	    Args: []interface{}{since},
+//	}, func(batch []Payment) error {
+//	    for _, p := range batch {
+//	        total = total.Add(p.Amount)
+//	    }
+//	    return nil
+//	})
+//
+// The batch's slice is reused from one call to the next, so fn must not keep it; copy out what
+// you need.  If fn returns an error, Reduce stops and returns it.  The cursor is read in a
+// transaction, or a savepoint if conn is a transaction.  See ScanStruct for how columns map to
+// fields.
+func Reduce[T any](ctx context.Context, conn Conn, q CursorQuery, fn func(batch []T) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	size := q.FetchSize
+	if size <= 0 {
+		size = DefaultFetchSize
+	}
+
+	tx, cursor, err := declareCursor(ctx, conn, q.SQL, q.Args)
+	if err != nil {
+		return err
+	}
+	defer tx.Close(ctx)
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", size, cursor)
+	batch := make([]T, 0, size)
+
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return err
+		}
+
+		if batch, err = AppendStructs(batch[:0], rows); err != nil {
+			return err
+		}
+
+		if len(batch) == 0 {
+			break
+		}
+
+		if err := fn(batch); err != nil {
+			return err
+		}
+
+		if len(batch) < size {
+			break
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "CLOSE "+cursor); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// declareCursor begins a transaction on conn, or a savepoint if conn is a transaction, and
+// declares a cursor for the query in it.  Returns the transaction and the cursor's name.  Close
+// the transaction when you're done with the cursor.
+func declareCursor(ctx context.Context, conn Conn, sql string, args []interface{}) (Conn, string, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cursor := fmt.Sprintf("hermes_cursor_%d", atomic.AddInt64(&cursors, 1))
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR %s", cursor, sql), args...); err != nil {
+		_ = tx.Close(ctx)
+		return nil, "", err
+	}
+
+	return tx, cursor, nil
+}
+
+// queryCursor declares a cursor for the query on conn and fetches the first batch of rows.
+func queryCursor(ctx context.Context, conn Conn, sql string, batchSize int, args []interface{}) (pgx.Rows, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if batchSize <= 0 {
+		batchSize = DefaultFetchSize
+	}
+
+	tx, cursor, err := declareCursor(ctx, conn, sql, args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &cursorRows{
+		ctx:    ctx,
+		tx:     tx,
+		cursor: cursor,
+		fetch:  fmt.Sprintf("FETCH FORWARD %d FROM %s", batchSize, cursor),
+		size:   batchSize,
+	}
+
+	if rows.Rows, err = tx.Query(ctx, rows.fetch); err != nil {
+		_ = tx.Close(ctx)
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// QueryCursor runs the query through a server-side cursor, returning rows that fetch batchSize
+// rows at a time as they're read, or DefaultFetchSize if batchSize isn't positive.  Only a batch
+// of rows is in flight at a time, however large the result set.  See Reduce.
+//
+// The cursor is read in a transaction, which holds a connection until the rows are closed or
+// read to the end, so always close the rows.
+func (db *DB) QueryCursor(ctx context.Context, sql string, batchSize int, args ...interface{}) (pgx.Rows, error) {
+	return queryCursor(ctx, db, sql, batchSize, args)
+}
+
+// QueryCursor runs the query through a server-side cursor declared in a savepoint, fetching
+// batchSize rows at a time.  See DB.QueryCursor.
+func (tx *Tx) QueryCursor(ctx context.Context, sql string, batchSize int, args ...interface{}) (pgx.Rows, error) {
+	return queryCursor(ctx, tx, sql, batchSize, args)
+}
+
+// cursorRows read a cursor's rows, fetching the next batch when the last is read.  Once the rows
+// are read to the end, the cursor is closed and its transaction committed; closing the rows early
+// rolls the transaction back.
+type cursorRows struct {
+	pgx.Rows
+
+	ctx    context.Context
+	tx     Conn
+	cursor string
+	fetch  string
+	size   int
+
+	// fetched counts the rows read from the current batch, and total from every batch
+	fetched int
+	total   int64
+
+	done bool
+	err  error
+}
+
+// Next prepares the next row for reading, fetching the next batch if necessary.
+func (rows *cursorRows) Next() bool {
+	if rows.done {
+		return false
+	}
+
+	for {
+		if rows.Rows.Next() {
+			rows.fetched++
+			rows.total++
+			return true
+		}
+
+		rows.Rows.Close()
+		if err := rows.Rows.Err(); err != nil {
+			rows.end(err)
+			return false
+		}
+
+		// A short batch is the last
+		if rows.fetched < rows.size {
+			rows.finish()
+			return false
+		}
+
+		next, err := rows.tx.Query(rows.ctx, rows.fetch)
+		if err != nil {
+			rows.end(err)
+			return false
+		}
+
+		rows.Rows, rows.fetched = next, 0
+	}
+}
+
+// finish closes the cursor and commits its transaction.
+func (rows *cursorRows) finish() {
+	if _, err := rows.tx.Exec(rows.ctx, "CLOSE "+rows.cursor); err != nil {
+		rows.end(err)
+		return
+	}
+
+	rows.end(rows.tx.Commit(rows.ctx))
+}
+
+// end the rows with err, rolling the transaction back if it hasn't been committed.
+func (rows *cursorRows) end(err error) {
+	rows.done = true
+	rows.err = err
+
+	_ = rows.tx.Close(rows.ctx)
+}
+
+// Close the rows, rolling back the cursor's transaction if they weren't read to the end.
+func (rows *cursorRows) Close() {
+	if rows.done {
+		return
+	}
+
+	rows.Rows.Close()
+	rows.end(rows.Rows.Err())
+}
+
+// Err returns the error reading the rows, if any.
+func (rows *cursorRows) Err() error {
+	if rows.err != nil {
+		return rows.err
+	}
+
+	return rows.Rows.Err()
+}
+
+// CommandTag returns the number of rows read from every batch.
+func (rows *cursorRows) CommandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag(fmt.Sprintf("SELECT %d", rows.total))
+}
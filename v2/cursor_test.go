@@ -0,0 +1,184 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// cursorConn serves the rows of a cursor in the fetched batch sizes.
+type cursorConn struct {
+	Conn
+
+	remaining int
+	fetches   int
+	execs     []string
+	committed bool
+}
+
+func (c *cursorConn) Begin(context.Context) (Conn, error) { return c, nil }
+func (c *cursorConn) Close(context.Context) error         { return nil }
+
+func (c *cursorConn) Commit(context.Context) error {
+	c.committed = true
+	return nil
+}
+
+func (c *cursorConn) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	c.execs = append(c.execs, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *cursorConn) Query(_ context.Context, sql string, _ ...interface{}) (pgx.Rows, error) {
+	c.fetches++
+
+	var size int
+	if _, err := fmt.Sscanf(sql, "FETCH FORWARD %d", &size); err != nil {
+		return nil, err
+	}
+
+	if size > c.remaining {
+		size = c.remaining
+	}
+	c.remaining -= size
+
+	values := make([][]interface{}, size)
+	for i := range values {
+		values[i] = []interface{}{int64(i)}
+	}
+
+	return &fakeRows{columns: []string{"id"}, values: values}, nil
+}
+
+func TestReduce(t *testing.T) {
+	conn := &cursorConn{remaining: 25}
+
+	var total, batches int
+	err := Reduce(context.Background(), conn, CursorQuery{SQL: "select id from accounts", FetchSize: 10},
+		func(batch []account) error {
+			if len(batch) > 10 {
+				t.Errorf("Expected at most 10 rows per batch; was %d", len(batch))
+			}
+			total += len(batch)
+			batches++
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Unable to reduce: %s", err)
+	}
+
+	if total != 25 || batches != 3 {
+		t.Errorf("Expected 25 rows in 3 batches; was %d in %d", total, batches)
+	}
+
+	if conn.fetches != 3 {
+		t.Errorf("Expected to stop fetching after a short batch; fetched %d times", conn.fetches)
+	}
+
+	if len(conn.execs) != 2 || !strings.HasPrefix(conn.execs[0], "DECLARE hermes_cursor_") ||
+		!strings.HasSuffix(conn.execs[0], " NO SCROLL CURSOR FOR select id from accounts") ||
+		!strings.HasPrefix(conn.execs[1], "CLOSE hermes_cursor_") {
+		t.Errorf("Expected the cursor to be declared and closed; was %v", conn.execs)
+	}
+
+	if !conn.committed {
+		t.Error("Expected the transaction to be committed")
+	}
+}
+
+func TestReduceStops(t *testing.T) {
+	conn := &cursorConn{remaining: 100}
+	stop := errors.New("stop")
+
+	err := Reduce(context.Background(), conn, CursorQuery{SQL: "select id from accounts", FetchSize: 10},
+		func(batch []account) error {
+			return stop
+		})
+
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the reducer's error; was %v", err)
+	}
+
+	if conn.fetches != 1 || conn.committed {
+		t.Errorf("Expected to stop after the first batch without committing; fetched %d times", conn.fetches)
+	}
+
+	conn = &cursorConn{remaining: 20}
+	batches := 0
+	if err := Reduce(nil, conn, CursorQuery{SQL: "select id from accounts", FetchSize: 10}, func([]account) error {
+		batches++
+		return nil
+	}); err != nil {
+		t.Fatalf("Unable to reduce: %s", err)
+	}
+
+	if batches != 2 || conn.fetches != 3 {
+		t.Errorf("Expected 2 batches and a final empty fetch; was %d batches in %d fetches", batches, conn.fetches)
+	}
+}
+
+func TestQueryCursor(t *testing.T) {
+	conn := &cursorConn{remaining: 25}
+
+	rows, err := queryCursor(context.Background(), conn, "select id from accounts", 10, nil)
+	if err != nil {
+		t.Fatalf("Unable to query the cursor: %s", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Unable to read the rows: %s", err)
+	}
+
+	if count != 25 || conn.fetches != 3 {
+		t.Errorf("Expected 25 rows in 3 fetches; was %d rows in %d fetches", count, conn.fetches)
+	}
+
+	if tag := rows.CommandTag().String(); tag != "SELECT 25" {
+		t.Errorf("Expected SELECT 25; was %s", tag)
+	}
+
+	if !conn.committed {
+		t.Error("Expected the cursor's transaction to commit")
+	}
+
+	if len(conn.execs) != 2 || !strings.HasPrefix(conn.execs[0], "DECLARE hermes_cursor_") || !strings.HasPrefix(conn.execs[1], "CLOSE hermes_cursor_") {
+		t.Errorf("Expected the cursor to be declared and closed; was %v", conn.execs)
+	}
+
+	if rows.Next() {
+		t.Error("Expected no more rows")
+	}
+}
+
+func TestQueryCursorClosed(t *testing.T) {
+	conn := &cursorConn{remaining: 100}
+
+	rows, err := queryCursor(context.Background(), conn, "select id from accounts", 10, nil)
+	if err != nil {
+		t.Fatalf("Unable to query the cursor: %s", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		rows.Next()
+	}
+	rows.Close()
+
+	if rows.Next() {
+		t.Error("Expected no rows after closing")
+	}
+
+	if conn.fetches != 1 || conn.committed {
+		t.Errorf("Expected to stop after the first batch without committing; fetched %d times", conn.fetches)
+	}
+}
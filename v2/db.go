@@ -2,29 +2,136 @@ package hermes
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // DB wraps the *pgxpool.Pool and provides the missing hermes function wrappers.
 type DB struct {
+	// Counters are kept first in the struct for 64-bit atomic alignment on 32-bit platforms.
+	txOpen       int64
+	txBegun      int64
+	txCommitted  int64
+	txRolledBack int64
+	locksHeld    int64
+	retries      int64
+	closing      int32
+
 	*pgxpool.Pool
 	defaultTimeout time.Duration
+	stmtCache      *statementCache
+	limiter        *limiter
+	tenantSetting  string
+	reloader       *reloader
+	observer       *metricsHook
+	reconnect      *ReconnectPolicy
+	health         *HealthCheck
+	maxLockHold    time.Duration
+
+	// openTxs maps the ended flag of each open transaction to its connection, so Shutdown can
+	// abort them
+	openTxs sync.Map
+}
+
+// TxOptions set the isolation level and access mode of a transaction started with BeginTx.  The
+// zero value starts a transaction with the server's defaults, as Begin does.
+type TxOptions struct {
+	// Isolation is the transaction's isolation level, such as pgx.Serializable.  Defaults to the
+	// server's default_transaction_isolation, usually READ COMMITTED.
+	Isolation pgx.TxIsoLevel
+
+	// ReadOnly starts a READ ONLY transaction, which fails any statement that writes.
+	ReadOnly bool
+
+	// Deferrable starts a DEFERRABLE transaction.  Only meaningful for SERIALIZABLE, READ ONLY
+	// transactions, which then wait for a snapshot that can't fail with a serialization failure.
+	Deferrable bool
+}
+
+// pgxOptions returns the pgx options for the transaction.
+func (opts TxOptions) pgxOptions() pgx.TxOptions {
+	txOptions := pgx.TxOptions{IsoLevel: opts.Isolation}
+	if opts.ReadOnly {
+		txOptions.AccessMode = pgx.ReadOnly
+	}
+
+	if opts.Deferrable {
+		txOptions.DeferrableMode = pgx.Deferrable
+	}
+
+	return txOptions
 }
 
 // Begin a new transaction.
 func (db *DB) Begin(ctx context.Context) (Conn, error) {
+	return db.BeginTx(ctx, TxOptions{})
+}
+
+// BeginTx begins a new transaction with the isolation level and access mode in opts, e.g. a
+// serializable or read-only transaction:
+//
+//	tx, err := db.BeginTx(ctx, hermes.TxOptions{Isolation: pgx.Serializable, ReadOnly: true})
+//
+// Savepoints started from the transaction share its options.
+func (db *DB) BeginTx(ctx context.Context, opts TxOptions) (Conn, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	tx, err := db.Pool.Begin(ctx)
+	if db.shuttingDown() {
+		return nil, ErrShuttingDown
+	}
+
+	if err := db.acquireSlot(ctx); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Pool.BeginTx(db.timeAcquire(ctx), opts.pgxOptions())
 	if err != nil {
+		db.releaseSlot()
 		return nil, err
 	}
 
-	return &Tx{tx, db.defaultTimeout}, nil
+	wrapped := &Tx{Tx: tx, defaultTimeout: db.defaultTimeout, db: db, limited: true}
+	db.txBegan(&wrapped.ended, tx.Conn())
+
+	return wrapped, nil
+}
+
+// BeginFunc starts a transaction and calls fn with it.  If fn returns nil, the transaction is
+// committed; otherwise, or if fn panics, it's rolled back.  Returns fn's error, or the error
+// committing.
+//
+// The transaction's wrapper is recycled once fn returns, saving an allocation per transaction on
+// hot paths, so don't hold onto tx after fn returns.
+func (db *DB) BeginFunc(ctx context.Context, fn func(tx Conn) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if db.shuttingDown() {
+		return ErrShuttingDown
+	}
+
+	if err := db.acquireSlot(ctx); err != nil {
+		return err
+	}
+
+	pgxTx, err := db.Pool.Begin(db.timeAcquire(ctx))
+	if err != nil {
+		db.releaseSlot()
+		return err
+	}
+
+	tx := txPool.Get().(*Tx)
+	tx.Tx, tx.defaultTimeout, tx.db, tx.limited = pgxTx, db.defaultTimeout, db, true
+	db.txBegan(&tx.ended, pgxTx.Conn())
+
+	return tx.run(ctx, fn)
 }
 
 // Commit does nothing.
@@ -44,8 +151,47 @@ func (db *DB) Close(context.Context) error {
 	return nil
 }
 
-// Shutdown the underlying pgx Pool.  You should call this when your application is closing to
-// release all the database pool connections.
-func (db *DB) Shutdown() {
-	db.Pool.Close()
+// OpenTransactions returns the number of transactions started through this DB that have not yet
+// been committed, rolled back, or closed.  Pseudo nested transactions (savepoints) aren't counted.
+func (db *DB) OpenTransactions() int64 {
+	return atomic.LoadInt64(&db.txOpen)
+}
+
+// HeldLocks returns the number of session-wide advisory locks acquired through this DB that
+// haven't been released.
+func (db *DB) HeldLocks() int64 {
+	return atomic.LoadInt64(&db.locksHeld)
+}
+
+// txBegan counts a transaction started through the DB, tracking its connection by its ended
+// flag until txEnded.
+func (db *DB) txBegan(ended *int32, conn *pgx.Conn) {
+	db.openTxs.Store(ended, conn)
+	atomic.AddInt64(&db.txOpen, 1)
+	atomic.AddInt64(&db.txBegun, 1)
+}
+
+// txEnded decrements the open transaction count the first time it's called for a given
+// transaction, tracked by the ended flag, counting the transaction as committed or rolled back.
+// If the transaction holds one of the DB's concurrency slots, i.e. it wasn't started on a pinned
+// connection, the slot is released.
+func (db *DB) txEnded(ended *int32, limited, committed bool) {
+	if db == nil {
+		return
+	}
+
+	if atomic.CompareAndSwapInt32(ended, 0, 1) {
+		db.openTxs.Delete(ended)
+		atomic.AddInt64(&db.txOpen, -1)
+
+		if committed {
+			atomic.AddInt64(&db.txCommitted, 1)
+		} else {
+			atomic.AddInt64(&db.txRolledBack, 1)
+		}
+
+		if limited {
+			db.releaseSlot()
+		}
+	}
 }
@@ -0,0 +1,28 @@
+package hermes
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestTxOptions(t *testing.T) {
+	tests := []struct {
+		opts     TxOptions
+		expected pgx.TxOptions
+	}{
+		{TxOptions{}, pgx.TxOptions{}},
+		{TxOptions{Isolation: pgx.Serializable}, pgx.TxOptions{IsoLevel: pgx.Serializable}},
+		{TxOptions{ReadOnly: true}, pgx.TxOptions{AccessMode: pgx.ReadOnly}},
+		{
+			TxOptions{Isolation: pgx.Serializable, ReadOnly: true, Deferrable: true},
+			pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly, DeferrableMode: pgx.Deferrable},
+		},
+	}
+
+	for _, test := range tests {
+		if opts := test.opts.pgxOptions(); opts != test.expected {
+			t.Errorf("Expected %+v for %+v; was %+v", test.expected, test.opts, opts)
+		}
+	}
+}
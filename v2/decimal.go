@@ -0,0 +1,128 @@
+package hermes
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+)
+
+// UseDecimal registers a codec on every connection that encodes and scans
+// github.com/shopspring/decimal Decimal and NullDecimal values as PostgreSQL numerics, including
+// arrays of them, and returns decimal.Decimal values for numeric columns scanned into
+// interface{}.  Best to call this before calling Connect.
+func UseDecimal() {
+	register(dataType{
+		name:   "numeric",
+		codec:  decimalCodec{},
+		values: []interface{}{decimal.Decimal{}, decimal.NullDecimal{}},
+	})
+}
+
+// decimalCodec adapts pgx's numeric codec to github.com/shopspring/decimal types.
+type decimalCodec struct {
+	pgtype.NumericCodec
+}
+
+func (c decimalCodec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value interface{}) pgtype.EncodePlan {
+	switch value.(type) {
+	case decimal.Decimal, decimal.NullDecimal:
+		// pgx mangles the text of small negative numerics, e.g. -0.00001, so format them here
+		if format == pgtype.TextFormatCode {
+			return &decimalEncodePlan{}
+		}
+
+		next := c.NumericCodec.PlanEncode(m, oid, format, pgtype.Numeric{})
+		if next == nil {
+			return nil
+		}
+		return &decimalEncodePlan{next: next}
+	}
+
+	return c.NumericCodec.PlanEncode(m, oid, format, value)
+}
+
+func (c decimalCodec) PlanScan(m *pgtype.Map, oid uint32, format int16, target interface{}) pgtype.ScanPlan {
+	switch target.(type) {
+	case *decimal.Decimal, *decimal.NullDecimal:
+		next := c.NumericCodec.PlanScan(m, oid, format, &pgtype.Numeric{})
+		if next == nil {
+			return nil
+		}
+		return &decimalScanPlan{next: next}
+	}
+
+	return c.NumericCodec.PlanScan(m, oid, format, target)
+}
+
+func (c decimalCodec) DecodeValue(m *pgtype.Map, oid uint32, format int16, src []byte) (interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	var d decimal.Decimal
+	if err := m.PlanScan(oid, format, &d).Scan(src, &d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// decimalEncodePlan encodes decimals in the binary format with the next plan, or as text if
+// there isn't one.
+type decimalEncodePlan struct {
+	next pgtype.EncodePlan
+}
+
+func (plan *decimalEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	var d decimal.Decimal
+
+	switch v := value.(type) {
+	case decimal.Decimal:
+		d = v
+	case decimal.NullDecimal:
+		if !v.Valid {
+			return nil, nil
+		}
+		d = v.Decimal
+	default:
+		return nil, fmt.Errorf("cannot encode %T as numeric", value)
+	}
+
+	if plan.next == nil {
+		return append(buf, d.String()...), nil
+	}
+
+	return plan.next.Encode(pgtype.Numeric{Int: d.Coefficient(), Exp: d.Exponent(), Valid: true}, buf)
+}
+
+type decimalScanPlan struct {
+	next pgtype.ScanPlan
+}
+
+func (plan *decimalScanPlan) Scan(src []byte, target interface{}) error {
+	var num pgtype.Numeric
+	if err := plan.next.Scan(src, &num); err != nil {
+		return err
+	}
+
+	if num.NaN || num.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("cannot scan non-finite numeric into %T", target)
+	}
+
+	switch t := target.(type) {
+	case *decimal.Decimal:
+		if !num.Valid {
+			return fmt.Errorf("cannot scan NULL into %T", target)
+		}
+		*t = decimal.NewFromBigInt(num.Int, num.Exp)
+	case *decimal.NullDecimal:
+		if !num.Valid {
+			*t = decimal.NullDecimal{}
+			return nil
+		}
+		*t = decimal.NullDecimal{Decimal: decimal.NewFromBigInt(num.Int, num.Exp), Valid: true}
+	}
+
+	return nil
+}
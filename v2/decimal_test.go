@@ -0,0 +1,106 @@
+package hermes
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+)
+
+func TestDecimal(t *testing.T) {
+	resetTypes(t)
+	UseDecimal()
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], decimalCodec{}, pgtype.NumericOID, pgtype.NumericArrayOID)
+
+	for _, value := range []string{"0", "123.456", "-0.00001", "98765432109876543210.0123456789"} {
+		d := decimal.RequireFromString(value)
+
+		for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+			buf, err := m.Encode(pgtype.NumericOID, format, d, nil)
+			if err != nil {
+				t.Fatalf("Unable to encode %s in format %d: %s", value, format, err)
+			}
+
+			var check decimal.Decimal
+			if err := m.Scan(pgtype.NumericOID, format, buf, &check); err != nil {
+				t.Fatalf("Unable to scan %s in format %d: %s", value, format, err)
+			}
+
+			if !check.Equal(d) {
+				t.Errorf("Expected %s; was %s", d, check)
+			}
+
+			dt, _ := m.TypeForOID(pgtype.NumericOID)
+			decoded, _ := dt.Codec.DecodeValue(m, pgtype.NumericOID, format, buf)
+			if dec, ok := decoded.(decimal.Decimal); !ok || !dec.Equal(d) {
+				t.Errorf("Expected decoded value to be decimal.Decimal %s; was %#v", d, decoded)
+			}
+		}
+	}
+}
+
+func TestNullDecimal(t *testing.T) {
+	resetTypes(t)
+	UseDecimal()
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], decimalCodec{}, pgtype.NumericOID, pgtype.NumericArrayOID)
+
+	buf, err := m.Encode(pgtype.NumericOID, pgtype.BinaryFormatCode, decimal.NullDecimal{}, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode null decimal: %s", err)
+	}
+
+	if buf != nil {
+		t.Error("Expected invalid NullDecimal to encode as NULL")
+	}
+
+	check := decimal.NewNullDecimal(decimal.New(1, 0))
+	if err := m.Scan(pgtype.NumericOID, pgtype.BinaryFormatCode, nil, &check); err != nil {
+		t.Fatalf("Unable to scan NULL into NullDecimal: %s", err)
+	}
+
+	if check.Valid {
+		t.Error("Expected NULL to scan as invalid NullDecimal")
+	}
+
+	var d decimal.Decimal
+	if err := m.Scan(pgtype.NumericOID, pgtype.BinaryFormatCode, nil, &d); err == nil {
+		t.Error("Expected error scanning NULL into decimal.Decimal")
+	}
+
+	buf, err = m.Encode(pgtype.NumericOID, pgtype.TextFormatCode, pgtype.Numeric{NaN: true, Valid: true}, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode NaN: %s", err)
+	}
+
+	if err := m.Scan(pgtype.NumericOID, pgtype.TextFormatCode, buf, &d); err == nil {
+		t.Error("Expected error scanning NaN into decimal.Decimal")
+	}
+}
+
+func TestDecimalArray(t *testing.T) {
+	resetTypes(t)
+	UseDecimal()
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], decimalCodec{}, pgtype.NumericOID, pgtype.NumericArrayOID)
+
+	values := []decimal.Decimal{decimal.RequireFromString("1.5"), decimal.RequireFromString("-2.25")}
+
+	buf, err := m.Encode(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, values, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode decimal array: %s", err)
+	}
+
+	var check []decimal.Decimal
+	if err := m.Scan(pgtype.NumericArrayOID, pgtype.BinaryFormatCode, buf, &check); err != nil {
+		t.Fatalf("Unable to scan decimal array: %s", err)
+	}
+
+	if len(check) != 2 || !check[0].Equal(values[0]) || !check[1].Equal(values[1]) {
+		t.Errorf("Expected %v; was %v", values, check)
+	}
+}
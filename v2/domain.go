@@ -0,0 +1,108 @@
+package hermes
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var autoDomains bool
+
+// UseDomains registers every domain in the database on each connection, so columns and
+// parameters of domain types are encoded and scanned like their base types.  Domains over types
+// the connection doesn't know, such as unregistered enums, are skipped; domains registered with
+// Register are left alone.  The domains are looked up the first time the pool connects; domains
+// created after that aren't registered until the pool is recreated.  Best to call this before
+// calling Connect.
+func UseDomains() {
+	dtMutex.Lock()
+	defer dtMutex.Unlock()
+
+	autoDomains = true
+}
+
+// useDomains returns true if domains are registered automatically.
+func useDomains() bool {
+	dtMutex.RLock()
+	defer dtMutex.RUnlock()
+
+	return autoDomains
+}
+
+// domainInfo describes a domain defined in the database.
+type domainInfo struct {
+	name     string
+	oid      uint32
+	arrayOID uint32
+	baseOID  uint32
+}
+
+// registerDomains registers the database's domains in the connection's type map, loading them
+// the first time.
+func (r *typeRegistry) registerDomains(ctx context.Context, conn *pgx.Conn) error {
+	r.mutex.Lock()
+	domains, loaded := r.domains, r.domainsLoaded
+	r.mutex.Unlock()
+
+	if !loaded {
+		var err error
+		if domains, err = loadDomains(ctx, conn); err != nil {
+			return err
+		}
+
+		r.mutex.Lock()
+		r.domains, r.domainsLoaded = domains, true
+		r.mutex.Unlock()
+	}
+
+	registerDomains(conn.TypeMap(), domains)
+	return nil
+}
+
+// loadDomains queries the database for the domains outside the system schemas, in the order
+// they were created, so domains over other domains follow their base types.
+func loadDomains(ctx context.Context, conn *pgx.Conn) ([]domainInfo, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT CASE WHEN n.nspname = 'public' THEN t.typname::text
+			ELSE n.nspname || '.' || t.typname END,
+			t.oid, t.typarray, t.typbasetype
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.typtype = 'd'
+		AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		AND n.nspname NOT LIKE 'pg\_%'
+		ORDER BY t.oid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var domains []domainInfo
+	for rows.Next() {
+		var domain domainInfo
+		if err := rows.Scan(&domain.name, &domain.oid, &domain.arrayOID, &domain.baseOID); err != nil {
+			return nil, err
+		}
+		domains = append(domains, domain)
+	}
+
+	return domains, rows.Err()
+}
+
+// registerDomains adds the domains to the type map using their base types' codecs.  Domains
+// already in the map, or whose base types aren't, are skipped.
+func registerDomains(m *pgtype.Map, domains []domainInfo) {
+	for _, domain := range domains {
+		if _, ok := m.TypeForOID(domain.oid); ok {
+			continue
+		}
+
+		base, ok := m.TypeForOID(domain.baseOID)
+		if !ok {
+			continue
+		}
+
+		registerType(m, dataType{name: domain.name}, base.Codec, domain.oid, domain.arrayOID)
+	}
+}
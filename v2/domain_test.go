@@ -0,0 +1,55 @@
+package hermes
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRegisterDomains(t *testing.T) {
+	m := pgtype.NewMap()
+
+	registerDomains(m, []domainInfo{
+		{name: "quantity", oid: 100001, arrayOID: 100002, baseOID: pgtype.Int4OID},
+		{name: "app.positive_quantity", oid: 100003, arrayOID: 100004, baseOID: 100001},
+		{name: "status_code", oid: 100005, arrayOID: 100006, baseOID: 99999},
+		{name: "text", oid: pgtype.TextOID, baseOID: pgtype.Int4OID},
+	})
+
+	for _, oid := range []uint32{100001, 100003} {
+		buf, err := m.Encode(oid, pgtype.BinaryFormatCode, 12, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode domain %d: %s", oid, err)
+		}
+
+		var n int32
+		if err := m.Scan(oid, pgtype.BinaryFormatCode, buf, &n); err != nil {
+			t.Fatalf("Unable to scan domain %d: %s", oid, err)
+		}
+
+		if n != 12 {
+			t.Errorf("Expected 12; was %d", n)
+		}
+	}
+
+	var values []int32
+	if err := m.Scan(100004, pgtype.TextFormatCode, []byte("{1,2}"), &values); err != nil {
+		t.Fatalf("Unable to scan domain array: %s", err)
+	}
+
+	if len(values) != 2 || values[1] != 2 {
+		t.Errorf("Expected [1 2]; was %v", values)
+	}
+
+	if dt, ok := m.TypeForName("app.positive_quantity"); !ok || dt.OID != 100003 {
+		t.Error("Expected domain to be registered by its qualified name")
+	}
+
+	if _, ok := m.TypeForOID(100005); ok {
+		t.Error("Expected domain over an unknown type to be skipped")
+	}
+
+	if dt, _ := m.TypeForOID(pgtype.TextOID); dt.Codec != (pgtype.TextCodec{}) {
+		t.Errorf("Expected existing type to be left alone; was %#v", dt.Codec)
+	}
+}
@@ -0,0 +1,139 @@
+package hermes
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrInexactInterval is returned when scanning an interval into a time.Duration if the interval
+// has days or months the DurationPolicy doesn't allow.
+var ErrInexactInterval = errors.New("interval has no exact duration")
+
+// DurationPolicy determines how the day and month components of an interval, whose lengths vary
+// with the calendar, are converted to a time.Duration.
+type DurationPolicy int
+
+const (
+	// DurationExact only converts intervals made up entirely of hours, minutes, and seconds.
+	// Intervals with days or months return ErrInexactInterval.
+	DurationExact DurationPolicy = iota
+
+	// DurationDays counts each day as 24 hours, ignoring daylight saving time.  Intervals with
+	// months return ErrInexactInterval.
+	DurationDays
+
+	// DurationApproximate counts each day as 24 hours and each month as 30 days, as
+	// PostgreSQL's justify_days function does.
+	DurationApproximate
+)
+
+const (
+	dayDuration   = 24 * time.Hour
+	monthDuration = 30 * dayDuration
+)
+
+// UseDuration registers a codec on every connection that scans interval columns into
+// time.Duration values, and encodes time.Duration arguments as intervals, converting days and
+// months according to the policy.  Intervals scanned into interface{} are returned as
+// time.Duration as well.  Best to call this before calling Connect.
+func UseDuration(policy DurationPolicy) {
+	register(dataType{
+		name:   "interval",
+		codec:  durationCodec{policy: policy},
+		values: []interface{}{time.Duration(0)},
+	})
+}
+
+// durationCodec adapts pgx's interval codec to time.Duration.
+type durationCodec struct {
+	pgtype.IntervalCodec
+	policy DurationPolicy
+}
+
+func (c durationCodec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value interface{}) pgtype.EncodePlan {
+	if _, ok := value.(time.Duration); ok {
+		next := c.IntervalCodec.PlanEncode(m, oid, format, pgtype.Interval{})
+		if next == nil {
+			return nil
+		}
+		return &durationEncodePlan{next: next}
+	}
+
+	return c.IntervalCodec.PlanEncode(m, oid, format, value)
+}
+
+func (c durationCodec) PlanScan(m *pgtype.Map, oid uint32, format int16, target interface{}) pgtype.ScanPlan {
+	if _, ok := target.(*time.Duration); ok {
+		next := c.IntervalCodec.PlanScan(m, oid, format, &pgtype.Interval{})
+		if next == nil {
+			return nil
+		}
+		return &durationScanPlan{next: next, policy: c.policy}
+	}
+
+	return c.IntervalCodec.PlanScan(m, oid, format, target)
+}
+
+func (c durationCodec) DecodeValue(m *pgtype.Map, oid uint32, format int16, src []byte) (interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	var d time.Duration
+	if err := m.PlanScan(oid, format, &d).Scan(src, &d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+type durationEncodePlan struct {
+	next pgtype.EncodePlan
+}
+
+func (plan *durationEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	d := value.(time.Duration)
+	return plan.next.Encode(pgtype.Interval{Microseconds: d.Microseconds(), Valid: true}, buf)
+}
+
+type durationScanPlan struct {
+	next   pgtype.ScanPlan
+	policy DurationPolicy
+}
+
+func (plan *durationScanPlan) Scan(src []byte, target interface{}) error {
+	var interval pgtype.Interval
+	if err := plan.next.Scan(src, &interval); err != nil {
+		return err
+	}
+
+	if !interval.Valid {
+		return fmt.Errorf("cannot scan NULL into %T", target)
+	}
+
+	d, err := intervalDuration(interval, plan.policy)
+	if err != nil {
+		return err
+	}
+
+	*target.(*time.Duration) = d
+	return nil
+}
+
+// intervalDuration converts the interval to a time.Duration according to the policy.
+func intervalDuration(interval pgtype.Interval, policy DurationPolicy) (time.Duration, error) {
+	if interval.Months != 0 && policy < DurationApproximate {
+		return 0, fmt.Errorf("%w: %d months", ErrInexactInterval, interval.Months)
+	}
+
+	if interval.Days != 0 && policy < DurationDays {
+		return 0, fmt.Errorf("%w: %d days", ErrInexactInterval, interval.Days)
+	}
+
+	return time.Duration(interval.Months)*monthDuration +
+		time.Duration(interval.Days)*dayDuration +
+		time.Duration(interval.Microseconds)*time.Microsecond, nil
+}
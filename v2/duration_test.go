@@ -0,0 +1,104 @@
+package hermes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestDuration(t *testing.T) {
+	resetTypes(t)
+	UseDuration(DurationExact)
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], durationCodec{}, pgtype.IntervalOID, pgtype.IntervalArrayOID)
+
+	d := 36*time.Hour + 15*time.Minute + 1500*time.Microsecond
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(pgtype.IntervalOID, format, d, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode duration in format %d: %s", format, err)
+		}
+
+		var check time.Duration
+		if err := m.Scan(pgtype.IntervalOID, format, buf, &check); err != nil {
+			t.Fatalf("Unable to scan duration in format %d: %s", format, err)
+		}
+
+		if check != d {
+			t.Errorf("Expected %s; was %s", d, check)
+		}
+
+		dt, _ := m.TypeForOID(pgtype.IntervalOID)
+		decoded, _ := dt.Codec.DecodeValue(m, pgtype.IntervalOID, format, buf)
+		if decoded != d {
+			t.Errorf("Expected decoded value to be time.Duration %s; was %#v", d, decoded)
+		}
+	}
+
+	var check time.Duration
+	if err := m.Scan(pgtype.IntervalOID, pgtype.TextFormatCode, nil, &check); err == nil {
+		t.Error("Expected error scanning NULL into time.Duration")
+	}
+}
+
+func TestDurationPolicy(t *testing.T) {
+	tests := []struct {
+		policy   DurationPolicy
+		interval string
+		expected time.Duration
+		err      error
+	}{
+		{DurationExact, "01:30:00", 90 * time.Minute, nil},
+		{DurationExact, "1 day", 0, ErrInexactInterval},
+		{DurationExact, "1 mon", 0, ErrInexactInterval},
+		{DurationDays, "2 days 01:00:00", 49 * time.Hour, nil},
+		{DurationDays, "1 mon", 0, ErrInexactInterval},
+		{DurationApproximate, "1 mon 1 day", 31 * 24 * time.Hour, nil},
+		{DurationApproximate, "-1 year", -360 * 24 * time.Hour, nil},
+	}
+
+	for _, test := range tests {
+		m := pgtype.NewMap()
+		m.RegisterType(&pgtype.Type{Name: "interval", OID: pgtype.IntervalOID, Codec: durationCodec{policy: test.policy}})
+
+		var check time.Duration
+		err := m.Scan(pgtype.IntervalOID, pgtype.TextFormatCode, []byte(test.interval), &check)
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("Expected error %v for %q under policy %d; was %v", test.err, test.interval, test.policy, err)
+			continue
+		}
+
+		if check != test.expected {
+			t.Errorf("Expected %s for %q under policy %d; was %s", test.expected, test.interval, test.policy, check)
+		}
+	}
+}
+
+func TestDurationArray(t *testing.T) {
+	resetTypes(t)
+	UseDuration(DurationDays)
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], durationCodec{policy: DurationDays}, pgtype.IntervalOID, pgtype.IntervalArrayOID)
+
+	values := []time.Duration{time.Second, 48 * time.Hour}
+
+	buf, err := m.Encode(pgtype.IntervalArrayOID, pgtype.BinaryFormatCode, values, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode duration array: %s", err)
+	}
+
+	var check []time.Duration
+	if err := m.Scan(pgtype.IntervalArrayOID, pgtype.BinaryFormatCode, buf, &check); err != nil {
+		t.Fatalf("Unable to scan duration array: %s", err)
+	}
+
+	if len(check) != 2 || check[0] != values[0] || check[1] != values[1] {
+		t.Errorf("Expected %v; was %v", values, check)
+	}
+}
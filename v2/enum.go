@@ -0,0 +1,30 @@
+package hermes
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RegisterEnum registers a PostgreSQL enum type on every connection, mapped to a Go string type,
+// e.g.
+//
+//	type OrderStatus string
+//
+//	hermes.RegisterEnum("order_status", OrderStatus(""))
+//
+// Values of the Go type, and slices of them, are encoded as the enum and its array type, and the
+// enum scans into the Go type.  Panics if value isn't a string type.  Best to call this before
+// calling Connect.
+func RegisterEnum(name string, value interface{}) {
+	if t := reflect.TypeOf(value); t == nil || t.Kind() != reflect.String {
+		panic(fmt.Sprintf("hermes: enum %s must be registered with a string type, not %T", name, value))
+	}
+
+	register(dataType{
+		name:   name,
+		codec:  &pgtype.EnumCodec{},
+		values: []interface{}{value},
+	})
+}
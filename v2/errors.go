@@ -1,6 +1,10 @@
 package hermes
 
-import "github.com/jackc/pgx/v5/pgconn"
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
 
 // PostgreSQL disconnect errors - https://www.postgresql.org/docs/current/errcodes-appendix.html
 const (
@@ -45,3 +49,27 @@ func IsDisconnected(err error) bool {
 
 	return false
 }
+
+// UniqueViolation is the SQLSTATE for a unique constraint violation.
+const UniqueViolation = "23505"
+
+// IsUniqueViolation returns true if the error, or any error it wraps, is a PostgreSQL unique
+// constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == UniqueViolation
+}
+
+// PostgreSQL errors that roll back a transaction, which may succeed if retried.
+const (
+	SerializationFailure = "40001"
+	DeadlockDetected     = "40P01"
+)
+
+// IsSerializationFailure returns true if the error, or any error it wraps, is a PostgreSQL
+// serialization failure or deadlock, i.e. the transaction was rolled back because of concurrent
+// transactions and may succeed if retried.
+func IsSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == SerializationFailure || pgErr.Code == DeadlockDetected)
+}
@@ -0,0 +1,29 @@
+package hermes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// unreachableURI is a database that refuses connections, for testing how failures are handled
+// without a database.
+const unreachableURI = "postgres://localhost:1/hermes_test?connect_timeout=1"
+
+// newUnreachableDB returns a DB whose pool can't connect.  The pool is closed when the test
+// completes.
+func newUnreachableDB(t testing.TB) *DB {
+	t.Helper()
+
+	pool, err := pgxpool.New(context.Background(), unreachableURI)
+	if err != nil {
+		t.Fatalf("Unable to create the pool: %s", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return &DB{Pool: pool}
+}
+
+// NewUnreachableDB exports newUnreachableDB to the hermes_test package.
+var NewUnreachableDB = newUnreachableDB
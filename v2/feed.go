@@ -0,0 +1,161 @@
+package hermes
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// How often a ChangeFeed runs its catch-up query when no notifications arrive, by default.
+const defaultFeedPollInterval = 30 * time.Second
+
+// FeedFetch loads and processes the changes after the cursor, in cursor order, and returns the
+// cursor of the last change processed.  Return the same cursor if there are no new changes.  The
+// fetch may process a limited batch; the ChangeFeed calls it again until the cursor stops
+// advancing.
+type FeedFetch func(ctx context.Context, conn Conn, cursor int64) (int64, error)
+
+// ChangeFeed delivers an ordered feed of changes, such as rows in an events table, combining a
+// catch-up query with LISTEN so new changes are picked up as soon as they're committed.  Writers
+// NOTIFY on the feed's channel after inserting a change; the payload is ignored.
+//
+// The ChangeFeed LISTENs before running the catch-up query, and runs the query again after every
+// notification, reconnect, and PollInterval, so a change committed between a query and the next
+// notification is never missed.  Changes may be delivered more than once, e.g. if the application
+// restarts before saving its cursor, so processing should be idempotent.
+//
+// Cursors assigned by a sequence may commit out of order: a transaction holding cursor 10 can
+// commit after one holding cursor 11 has been delivered, and be skipped.  Assign cursors at commit
+// time, or have the fetch hold back rows until older transactions have finished.
+type ChangeFeed struct {
+	// Channel the feed LISTENs on for new changes.
+	Channel string
+
+	// PollInterval is how often the catch-up query runs when no notifications arrive, as a
+	// safety net.  Defaults to 30 seconds.
+	PollInterval time.Duration
+
+	// OnError is called with errors the feed recovers from, such as a dropped LISTEN connection,
+	// for logging.
+	OnError func(err error)
+
+	db     *DB
+	fetch  FeedFetch
+	cursor int64
+}
+
+// NewChangeFeed creates a change feed that LISTENs on the channel and calls fetch to load the
+// changes.
+func NewChangeFeed(db *DB, channel string, fetch FeedFetch) *ChangeFeed {
+	return &ChangeFeed{
+		Channel: channel,
+		db:      db,
+		fetch:   fetch,
+	}
+}
+
+// Cursor returns the cursor of the last change the feed processed.
+func (f *ChangeFeed) Cursor() int64 {
+	return atomic.LoadInt64(&f.cursor)
+}
+
+// Run delivers changes after the cursor until ctx is canceled, the fetch returns an error, or the
+// feed's Listener closes itself.  Returns nil if ctx was canceled, the fetch's error, or the
+// reason the Listener closed, such as ErrSlowConsumer.
+func (f *ChangeFeed) Run(ctx context.Context, cursor int64) error {
+	atomic.StoreInt64(&f.cursor, cursor)
+
+	// Catch up after reconnecting, since notifications sent while disconnected are lost
+	reconnected := make(chan struct{}, 1)
+
+	listener := NewListener(f.db)
+	listener.OnError = f.OnError
+	listener.OnReconnect = func(ctx context.Context) error {
+		select {
+		case reconnected <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	defer listener.Close()
+
+	if err := listener.Listen(ctx, f.Channel); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	interval := f.PollInterval
+	if interval <= 0 {
+		interval = defaultFeedPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	return f.follow(ctx, listener, reconnected, ticker.C)
+}
+
+// follow runs the catch-up query, then again after every notification, reconnect, and tick, until
+// ctx is canceled, the fetch fails, or the listener closes.
+func (f *ChangeFeed) follow(ctx context.Context, listener *Listener, reconnected <-chan struct{}, tick <-chan time.Time) error {
+	notifications := listener.Notifications()
+
+	for {
+		if err := f.catchUp(ctx, f.db); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-notifications:
+			if !ok {
+				if err := listener.Err(); err != nil {
+					return err
+				}
+				return ErrListenerClosed
+			}
+			drain(notifications)
+		case <-reconnected:
+		case <-tick:
+		}
+	}
+}
+
+// catchUp calls fetch until the cursor stops advancing.
+func (f *ChangeFeed) catchUp(ctx context.Context, conn Conn) error {
+	for {
+		cursor := atomic.LoadInt64(&f.cursor)
+
+		next, err := f.fetch(ctx, conn, cursor)
+		if err != nil {
+			return err
+		}
+
+		if next <= cursor {
+			return nil
+		}
+
+		atomic.StoreInt64(&f.cursor, next)
+	}
+}
+
+// drain discards the notifications already buffered, since a single catch-up query covers all of
+// them.
+func drain(notifications <-chan *Notification) {
+	for {
+		select {
+		case _, ok := <-notifications:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChangeFeedCatchUp(t *testing.T) {
+	var calls []int64
+
+	// Deliver changes in batches of 10, up to 25
+	feed := NewChangeFeed(nil, "events", func(ctx context.Context, conn Conn, cursor int64) (int64, error) {
+		calls = append(calls, cursor)
+
+		next := cursor + 10
+		if next > 25 {
+			next = 25
+		}
+		return next, nil
+	})
+	feed.cursor = 3
+
+	if err := feed.catchUp(context.Background(), nil); err != nil {
+		t.Fatalf("Unable to catch up: %s", err)
+	}
+
+	if feed.Cursor() != 25 {
+		t.Errorf("Expected cursor 25; was %d", feed.Cursor())
+	}
+
+	expected := []int64{3, 13, 23, 25}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected fetches after %v; was %v", expected, calls)
+	}
+
+	for i, cursor := range expected {
+		if calls[i] != cursor {
+			t.Errorf("Expected fetch %d after %d; was %d", i, cursor, calls[i])
+		}
+	}
+}
+
+func TestChangeFeedCatchUpError(t *testing.T) {
+	failed := errors.New("failed")
+
+	feed := NewChangeFeed(nil, "events", func(ctx context.Context, conn Conn, cursor int64) (int64, error) {
+		if cursor > 0 {
+			return cursor, failed
+		}
+		return 5, nil
+	})
+
+	if err := feed.catchUp(context.Background(), nil); err != failed {
+		t.Errorf("Expected fetch error; was %v", err)
+	}
+
+	if feed.Cursor() != 5 {
+		t.Errorf("Expected cursor to stay at the last successful fetch, 5; was %d", feed.Cursor())
+	}
+}
+
+func TestChangeFeedListenerClosed(t *testing.T) {
+	var fetches int
+	feed := NewChangeFeed(nil, "events", func(ctx context.Context, conn Conn, cursor int64) (int64, error) {
+		fetches++
+		return cursor, nil
+	})
+
+	closed := NewListener(nil)
+	_ = closed.Close()
+
+	slow := NewListener(nil)
+	slow.err = ErrSlowConsumer
+	_ = slow.Close()
+
+	tests := []struct {
+		listener *Listener
+		expected error
+	}{
+		{closed, ErrListenerClosed},
+		{slow, ErrSlowConsumer},
+	}
+
+	for _, test := range tests {
+		result := make(chan error, 1)
+		go func() {
+			result <- feed.follow(context.Background(), test.listener, nil, nil)
+		}()
+
+		// A closed notifications channel is always ready, so the feed mustn't keep selecting it
+		select {
+		case err := <-result:
+			if !errors.Is(err, test.expected) {
+				t.Errorf("Expected %v; was %v", test.expected, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the feed to stop when the listener closed")
+		}
+	}
+
+	if fetches != 2 {
+		t.Errorf("Expected one catch-up per run; was %d", fetches)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	notifications := make(chan *Notification, 4)
+	notifications <- &Notification{}
+	notifications <- &Notification{}
+
+	drain(notifications)
+
+	if len(notifications) != 0 {
+		t.Errorf("Expected notifications drained; %d remain", len(notifications))
+	}
+}
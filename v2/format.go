@@ -0,0 +1,34 @@
+package hermes
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ResultFormat returns a query option controlling the format of the query's results:
+// pgtype.TextFormatCode or pgtype.BinaryFormatCode.  Pass a single format for every column, or one
+// format per column.  Pass the option before the query's arguments:
+//
+//	rows, err := conn.Query(ctx, "select id, shape from shapes", hermes.ResultFormat(pgtype.TextFormatCode))
+//
+// Useful for types pgx can't decode in the binary format, or to skip converting values you'll
+// only scan into strings.  Results are always text in the simple protocol.
+func ResultFormat(formats ...int16) pgx.QueryResultFormats {
+	return pgx.QueryResultFormats(formats)
+}
+
+// TextResults returns a query option that requests every result column in the text format.
+func TextResults() pgx.QueryResultFormats {
+	return ResultFormat(pgtype.TextFormatCode)
+}
+
+// BinaryResults returns a query option that requests every result column in the binary format.
+func BinaryResults() pgx.QueryResultFormats {
+	return ResultFormat(pgtype.BinaryFormatCode)
+}
+
+// ResultFormatByOID returns a query option controlling the format of the query's results by the
+// columns' type OIDs.  Columns of types that aren't listed use the text format.
+func ResultFormatByOID(formats map[uint32]int16) pgx.QueryResultFormatsByOID {
+	return pgx.QueryResultFormatsByOID(formats)
+}
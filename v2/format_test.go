@@ -0,0 +1,68 @@
+package hermes_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sbowman/hermes-pgx/v2"
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+func TestResultFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   pgx.QueryResultFormats
+		expected pgx.QueryResultFormats
+	}{
+		{"text", hermes.TextResults(), pgx.QueryResultFormats{pgtype.TextFormatCode}},
+		{"binary", hermes.BinaryResults(), pgx.QueryResultFormats{pgtype.BinaryFormatCode}},
+		{
+			"per column",
+			hermes.ResultFormat(pgtype.BinaryFormatCode, pgtype.TextFormatCode),
+			pgx.QueryResultFormats{pgtype.BinaryFormatCode, pgtype.TextFormatCode},
+		},
+	}
+
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.option, test.expected) {
+			t.Errorf("Expected %v for %s; was %v", test.expected, test.name, test.option)
+		}
+	}
+
+	byOID := hermes.ResultFormatByOID(map[uint32]int16{pgtype.JSONBOID: pgtype.TextFormatCode})
+	if byOID[pgtype.JSONBOID] != pgtype.TextFormatCode {
+		t.Errorf("Expected jsonb in the text format; was %v", byOID)
+	}
+}
+
+func TestResultFormatPassedThrough(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectQuery(`select id, shape from shapes`).
+		WithArgs(pgx.QueryResultFormats{pgtype.TextFormatCode}, 12).
+		WillReturnRows(hermestest.NewRows("id", "shape").AddRow(12, "((0,0),1)"))
+
+	// The option goes before the query's arguments, which pgx reads first
+	rows, err := mock.Query(ctx, "select id, shape from shapes where id = $1", hermes.TextResults(), 12)
+	if err != nil {
+		t.Fatalf("Unable to query: %s", err)
+	}
+	rows.Close()
+
+	calls := mock.Calls()
+	if len(calls) != 1 || len(calls[0].Args) != 2 {
+		t.Fatalf("Expected one query with the option and an argument; was %v", calls)
+	}
+
+	if _, ok := calls[0].Args[0].(pgx.QueryResultFormats); !ok {
+		t.Errorf("Expected the result formats first; was %T", calls[0].Args[0])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
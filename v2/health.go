@@ -0,0 +1,237 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDatabaseDown is returned by DB.Healthy when the database can't be reached.
+	ErrDatabaseDown = errors.New("database down")
+
+	// ErrDatabaseDegraded is returned by DB.Healthy when the database is reachable, but the
+	// connection pool is saturated or the replication lag is too high.
+	ErrDatabaseDegraded = errors.New("database degraded")
+)
+
+const (
+	// Default time to wait for the database to answer a health check.
+	defaultHealthTimeout = time.Second
+
+	// Default fraction of the pool's connections in use at which the database is degraded.
+	defaultMaxSaturation = 0.9
+)
+
+// ReplicaLagQuery is a LagQuery for a streaming replica, returning how many seconds behind the
+// primary it's replaying transactions.  It returns 0 on a primary, or on a replica that has
+// replayed everything it's received.
+const ReplicaLagQuery = `
+	SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE coalesce(extract(epoch FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+
+// HealthStatus is the overall health of the database in a health Report.
+type HealthStatus int
+
+const (
+	// HealthUp means the database is reachable and within its limits.
+	HealthUp HealthStatus = iota
+
+	// HealthDegraded means the database is reachable, but the connection pool is saturated or
+	// the replication lag is too high.  Queries still work, but may be slow or stale.
+	HealthDegraded
+
+	// HealthDown means the database can't be reached.
+	HealthDown
+)
+
+// String returns "up", "degraded", or "down".
+func (status HealthStatus) String() string {
+	switch status {
+	case HealthUp:
+		return "up"
+	case HealthDegraded:
+		return "degraded"
+	case HealthDown:
+		return "down"
+	}
+
+	return fmt.Sprintf("HealthStatus(%d)", int(status))
+}
+
+// HealthCheck configures DB.SetHealthCheck.
+type HealthCheck struct {
+	// Timeout is how long to wait for the database to answer, including waiting on a connection
+	// from the pool.  Defaults to 1 second.
+	Timeout time.Duration
+
+	// MaxSaturation is the fraction of the pool's connections in use, from 0 to 1, at which the
+	// database is degraded.  Defaults to 0.9.
+	MaxSaturation float64
+
+	// LagQuery, if set, is run to measure the replication lag, returning a single number of
+	// seconds, e.g. ReplicaLagQuery.  If the query fails, the database is degraded.
+	LagQuery string
+
+	// MaxLag is the replication lag at which the database is degraded.  Defaults to 30 seconds.
+	MaxLag time.Duration
+}
+
+// Report describes the health of the database, returned by DB.HealthReport.
+type Report struct {
+	// Status is the database's overall health.
+	Status HealthStatus
+
+	// Latency is the round trip to ping the database, including acquiring a connection.
+	Latency time.Duration
+
+	// Lag is the replication lag returned by the LagQuery, if there is one.
+	Lag time.Duration
+
+	// Saturation is the fraction of the pool's connections in use, from 0 to 1.
+	Saturation float64
+
+	// AcquiredConns is the number of connections in use.
+	AcquiredConns int32
+
+	// TotalConns is the number of connections open, in use or idle.
+	TotalConns int32
+
+	// MaxConns is the most connections the pool will open.
+	MaxConns int32
+
+	// Problems explains why the database isn't up.
+	Problems []string
+
+	// Err is the error pinging the database, if it's down.
+	Err error
+
+	// Checked is when the report was made.
+	Checked time.Time
+}
+
+// Healthy returns an error if the status isn't HealthUp:  ErrDatabaseDown if the database is
+// down, or ErrDatabaseDegraded if it's degraded, along with the problems.
+func (r Report) Healthy() error {
+	switch r.Status {
+	case HealthUp:
+		return nil
+	case HealthDown:
+		if r.Err != nil {
+			return fmt.Errorf("%w: %s", ErrDatabaseDown, r.Err)
+		}
+		return ErrDatabaseDown
+	}
+
+	return fmt.Errorf("%w: %s", ErrDatabaseDegraded, strings.Join(r.Problems, "; "))
+}
+
+// SetHealthCheck configures the checks run by Healthy and HealthReport.  Without it, the
+// defaults in HealthCheck apply and replication lag isn't checked.
+//
+// Call SetHealthCheck before using the DB.
+func (db *DB) SetHealthCheck(check HealthCheck) {
+	db.health = &check
+}
+
+// Healthy pings the database and checks the pool's saturation and the replication lag, returning
+// nil if everything is within its limits.  Otherwise returns an error wrapping ErrDatabaseDown or
+// ErrDatabaseDegraded.  A liveness check might only fail on ErrDatabaseDown, while a readiness
+// check fails on either:
+//
+//	if err := db.Healthy(r.Context()); errors.Is(err, hermes.ErrDatabaseDown) {
+//		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+//		return
+//	}
+//
+// See HealthReport for the details.
+func (db *DB) Healthy(ctx context.Context) error {
+	return db.HealthReport(ctx).Healthy()
+}
+
+// HealthReport pings the database, measures the replication lag with the HealthCheck's LagQuery,
+// and reports on the pool's saturation.  The database is down if the ping fails, and degraded if
+// the pool is saturated, the lag is too high, or the lag can't be measured.
+func (db *DB) HealthReport(ctx context.Context) Report {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	check := HealthCheck{}
+	if db.health != nil {
+		check = *db.health
+	}
+
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	report := Report{Checked: time.Now()}
+
+	stat := db.Pool.Stat()
+	report.AcquiredConns, report.TotalConns, report.MaxConns = stat.AcquiredConns(), stat.TotalConns(), stat.MaxConns()
+
+	start := time.Now()
+	err := db.Pool.Ping(ctx)
+	report.Latency = time.Since(start)
+
+	if err != nil {
+		report.Err = err
+		return check.assess(report)
+	}
+
+	if check.LagQuery != "" {
+		var lag float64
+		if err := db.Pool.QueryRow(ctx, check.LagQuery).Scan(&lag); err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("unable to measure the replication lag: %s", err))
+		}
+		report.Lag = time.Duration(lag * float64(time.Second))
+	}
+
+	return check.assess(report)
+}
+
+// assess sets the report's status and problems from the measurements against the limits.
+func (check HealthCheck) assess(report Report) Report {
+	if report.Err != nil {
+		report.Status = HealthDown
+		report.Problems = append(report.Problems, fmt.Sprintf("unable to reach the database: %s", report.Err))
+		return report
+	}
+
+	maxSaturation := check.MaxSaturation
+	if maxSaturation <= 0 {
+		maxSaturation = defaultMaxSaturation
+	}
+
+	maxLag := check.MaxLag
+	if maxLag <= 0 {
+		maxLag = defaultMaxLag
+	}
+
+	if report.MaxConns > 0 {
+		report.Saturation = float64(report.AcquiredConns) / float64(report.MaxConns)
+	}
+
+	if report.Saturation >= maxSaturation {
+		report.Problems = append(report.Problems, fmt.Sprintf("%d of %d connections in use", report.AcquiredConns, report.MaxConns))
+	}
+
+	if report.Lag > maxLag {
+		report.Problems = append(report.Problems, fmt.Sprintf("replication lag of %s", report.Lag))
+	}
+
+	report.Status = HealthUp
+	if len(report.Problems) > 0 {
+		report.Status = HealthDegraded
+	}
+
+	return report
+}
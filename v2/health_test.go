@@ -0,0 +1,46 @@
+package hermes
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthAssess(t *testing.T) {
+	check := HealthCheck{MaxLag: 10 * time.Second}
+
+	report := check.assess(Report{AcquiredConns: 2, MaxConns: 10, Lag: time.Second})
+	if report.Status != HealthUp {
+		t.Errorf("Expected up; was %s: %v", report.Status, report.Problems)
+	}
+
+	if report.Saturation != 0.2 {
+		t.Errorf("Expected a saturation of 0.2; was %f", report.Saturation)
+	}
+
+	if err := report.Healthy(); err != nil {
+		t.Errorf("Expected no error; was %s", err)
+	}
+
+	report = check.assess(Report{AcquiredConns: 10, MaxConns: 10, Lag: time.Minute})
+	if report.Status != HealthDegraded {
+		t.Errorf("Expected degraded; was %s", report.Status)
+	}
+
+	if len(report.Problems) != 2 {
+		t.Errorf("Expected saturation and lag problems; was %v", report.Problems)
+	}
+
+	if err := report.Healthy(); !errors.Is(err, ErrDatabaseDegraded) {
+		t.Errorf("Expected ErrDatabaseDegraded; was %v", err)
+	}
+
+	report = check.assess(Report{Err: errors.New("connection refused")})
+	if report.Status != HealthDown {
+		t.Errorf("Expected down; was %s", report.Status)
+	}
+
+	if err := report.Healthy(); !errors.Is(err, ErrDatabaseDown) {
+		t.Errorf("Expected ErrDatabaseDown; was %v", err)
+	}
+}
@@ -17,12 +17,27 @@ func Connect(uri string) (*DB, error) {
 }
 
 // ConnectConfig creates a pgx database connection pool based on a pool configuration and returns
-// it.
+// it.  Data types added with Register are registered on each connection before the configuration's
+// AfterConnect function, if any, is called.  The connections' prepared statement caches are
+// tracked through the configuration's tracer; see DB.StatementCacheStats.  The connection hooks
+// are also wrapped so DB.Reload can retire connections, and so DB.SetMetrics and DB.SetQueryLogger
+// can time statements and connection waits.
 func ConnectConfig(config *pgxpool.Config) (*DB, error) {
+	stmtCache := newStatementCache(config)
+	stmtCache.install(config)
+
+	reloader := newReloader(config)
+	reloader.install(config)
+
+	observer := newMetricsHook(config)
+	observer.install(config)
+
+	config.AfterConnect = newTypeRegistry().afterConnect(config.AfterConnect)
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, err
 	}
 
-	return &DB{pool, 0}, nil
+	return &DB{Pool: pool, stmtCache: stmtCache, reloader: reloader, observer: observer}, nil
 }
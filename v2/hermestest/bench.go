@@ -0,0 +1,126 @@
+package hermestest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// Setup executes the setup SQL against conn and registers the teardown SQL to run when the test
+// or benchmark completes.  Either may be blank.  Typically used to create and drop the tables a
+// benchmark runs against:
+//
+//	hermestest.Setup(b, db,
+//	    "create table bench_users (id serial primary key, name text)",
+//	    "drop table bench_users")
+func Setup(tb testing.TB, conn hermes.Conn, setup, teardown string) {
+	tb.Helper()
+
+	if setup != "" {
+		if _, err := conn.Exec(context.Background(), setup); err != nil {
+			tb.Fatalf("Setup failed: %s", err)
+		}
+	}
+
+	if teardown != "" {
+		tb.Cleanup(func() {
+			if _, err := conn.Exec(context.Background(), teardown); err != nil {
+				tb.Errorf("Teardown failed: %s", err)
+			}
+		})
+	}
+}
+
+// GenerateRows returns a pgx.CopyFromSource that produces n rows, building each row on demand
+// by calling fn with the row index.  Rows aren't materialized up front, so large data sets can be
+// fed to CopyFrom without skewing the benchmark's memory profile.
+func GenerateRows(n int, fn func(i int) []interface{}) pgx.CopyFromSource {
+	return &generatedRows{n: n, fn: fn, i: -1}
+}
+
+type generatedRows struct {
+	n  int
+	i  int
+	fn func(i int) []interface{}
+}
+
+func (g *generatedRows) Next() bool {
+	g.i++
+	return g.i < g.n
+}
+
+func (g *generatedRows) Values() ([]interface{}, error) {
+	return g.fn(g.i), nil
+}
+
+func (g *generatedRows) Err() error {
+	return nil
+}
+
+// Latency collects per-operation latency samples so a benchmark can report percentiles in
+// addition to the mean ns/op reported by the testing package.  Safe for use with b.RunParallel.
+type Latency struct {
+	mutex   sync.Mutex
+	samples []time.Duration
+}
+
+// Observe records a single latency sample.
+func (l *Latency) Observe(dur time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.samples = append(l.samples, dur)
+}
+
+// Time runs fn and records how long it took.  Returns fn's error.
+func (l *Latency) Time(fn func() error) error {
+	start := time.Now()
+	err := fn()
+	l.Observe(time.Since(start))
+
+	return err
+}
+
+// Percentile returns the latency at percentile p, between 0 and 100.  Returns 0 if no samples
+// were recorded.
+func (l *Latency) Percentile(p float64) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(l.samples) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(l.samples))
+	copy(sorted, l.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(p / 100 * float64(len(sorted)-1))
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+
+	return sorted[idx]
+}
+
+// Report adds the p50, p90, and p99 latencies to the benchmark results as custom metrics.
+func (l *Latency) Report(b *testing.B) {
+	b.ReportMetric(float64(l.Percentile(50).Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(l.Percentile(90).Nanoseconds()), "p90-ns")
+	b.ReportMetric(float64(l.Percentile(99).Nanoseconds()), "p99-ns")
+}
+
+// Reset discards the recorded samples, e.g. after b.ResetTimer.
+func (l *Latency) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.samples = l.samples[:0]
+}
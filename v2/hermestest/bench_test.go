@@ -0,0 +1,55 @@
+package hermestest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+func TestLatencyPercentile(t *testing.T) {
+	var latency hermestest.Latency
+
+	if p := latency.Percentile(50); p != 0 {
+		t.Errorf("Expected no samples to report 0; was %s", p)
+	}
+
+	for i := 100; i > 0; i-- {
+		latency.Observe(time.Duration(i) * time.Millisecond)
+	}
+
+	if p := latency.Percentile(0); p != time.Millisecond {
+		t.Errorf("Expected p0 to be 1ms; was %s", p)
+	}
+
+	if p := latency.Percentile(50); p != 50*time.Millisecond {
+		t.Errorf("Expected p50 to be 50ms; was %s", p)
+	}
+
+	if p := latency.Percentile(100); p != 100*time.Millisecond {
+		t.Errorf("Expected p100 to be 100ms; was %s", p)
+	}
+}
+
+func TestGenerateRows(t *testing.T) {
+	src := hermestest.GenerateRows(3, func(i int) []interface{} {
+		return []interface{}{i, "name"}
+	})
+
+	count := 0
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if values[0] != count {
+			t.Errorf("Expected row %d; was %v", count, values[0])
+		}
+		count++
+	}
+
+	if count != 3 {
+		t.Errorf("Expected 3 rows; generated %d", count)
+	}
+}
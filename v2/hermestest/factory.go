@@ -0,0 +1,157 @@
+package hermestest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// Attrs are the column values for a row, keyed by column name.
+type Attrs map[string]interface{}
+
+// Builder builds the default column values for a new row created by a factory, returning the
+// table to insert into along with the values.  The seq value is unique for each row the factory
+// builds, for generating unique emails, names, etc.
+//
+// Builders may call Create on conn to construct related rows, such as a user's account:
+//
+//	hermestest.Define("user", func(ctx context.Context, conn hermes.Conn, seq int) (string, hermestest.Attrs, error) {
+//	    account, err := hermestest.Create(ctx, conn, "account", nil)
+//	    if err != nil {
+//	        return "", nil, err
+//	    }
+//
+//	    return "users", hermestest.Attrs{
+//	        "account_id": account["id"],
+//	        "email":      fmt.Sprintf("user%d@example.com", seq),
+//	    }, nil
+//	})
+type Builder func(ctx context.Context, conn hermes.Conn, seq int) (table string, attrs Attrs, err error)
+
+type factory struct {
+	builder Builder
+	seq     int
+}
+
+var (
+	factories    = make(map[string]*factory)
+	factoryMutex sync.Mutex
+)
+
+// Define registers a factory under name.  Redefining a factory replaces it.  Typically called
+// from TestMain or an init function.
+func Define(name string, builder Builder) {
+	factoryMutex.Lock()
+	defer factoryMutex.Unlock()
+
+	factories[name] = &factory{builder: builder}
+}
+
+// Create builds a row using the named factory, replaces any default values with the overrides,
+// and inserts the row through conn.  Returns every column of the inserted row, including those
+// generated by the database, such as serial IDs.
+func Create(ctx context.Context, conn hermes.Conn, name string, overrides Attrs) (Attrs, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	factoryMutex.Lock()
+	f, ok := factories[name]
+	var seq int
+	if ok {
+		f.seq++
+		seq = f.seq
+	}
+	factoryMutex.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no factory defined for %q", name)
+	}
+
+	table, attrs, err := f.builder(ctx, conn, seq)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(Attrs, len(attrs)+len(overrides))
+	for col, val := range attrs {
+		values[col] = val
+	}
+	for col, val := range overrides {
+		values[col] = val
+	}
+
+	return insert(ctx, conn, table, values)
+}
+
+// MustCreate is like Create, but fails the test if the row can't be created.
+func MustCreate(t testing.TB, conn hermes.Conn, name string, overrides Attrs) Attrs {
+	t.Helper()
+
+	attrs, err := Create(context.Background(), conn, name, overrides)
+	if err != nil {
+		t.Fatalf("Unable to create %s: %s", name, err)
+	}
+
+	return attrs
+}
+
+// insert the values into table, returning every column of the new row.
+func insert(ctx context.Context, conn hermes.Conn, table string, values Attrs) (Attrs, error) {
+	cols := make([]string, 0, len(values))
+	for col := range values {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	names := make([]string, len(cols))
+	params := make([]string, len(cols))
+	args := make([]interface{}, len(cols))
+
+	for i, col := range cols {
+		names[i] = pgx.Identifier{col}.Sanitize()
+		params[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = values[col]
+	}
+
+	sql := fmt.Sprintf("INSERT INTO %s DEFAULT VALUES RETURNING *", pgx.Identifier(strings.Split(table, ".")).Sanitize())
+	if len(cols) > 0 {
+		sql = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING *",
+			pgx.Identifier(strings.Split(table, ".")).Sanitize(),
+			strings.Join(names, ", "),
+			strings.Join(params, ", "))
+	}
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, pgx.ErrNoRows
+	}
+
+	row, err := rows.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(Attrs, len(row))
+	for i, fd := range rows.FieldDescriptions() {
+		result[fd.Name] = row[i]
+	}
+
+	rows.Close()
+
+	return result, rows.Err()
+}
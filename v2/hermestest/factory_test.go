@@ -0,0 +1,104 @@
+package hermestest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbowman/hermes-pgx/v2"
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+// defineFactories defines a user factory that creates an account for each user.  Redefining
+// the factories restarts their sequence numbers.
+func defineFactories() {
+	hermestest.Define("factory account", func(ctx context.Context, conn hermes.Conn, seq int) (string, hermestest.Attrs, error) {
+		return "accounts", nil, nil
+	})
+
+	hermestest.Define("factory user", func(ctx context.Context, conn hermes.Conn, seq int) (string, hermestest.Attrs, error) {
+		account, err := hermestest.Create(ctx, conn, "factory account", nil)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return "public.users", hermestest.Attrs{
+			"account_id": account["id"],
+			"email":      fmt.Sprintf("user%d@example.com", seq),
+			"name":       "Ann",
+		}, nil
+	})
+}
+
+func TestCreate(t *testing.T) {
+	defineFactories()
+
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	account := regexp.QuoteMeta(`INSERT INTO "accounts" DEFAULT VALUES RETURNING *`)
+	user := regexp.QuoteMeta(`INSERT INTO "public"."users" ("account_id", "email", "name") VALUES ($1, $2, $3) RETURNING *`)
+
+	mock.ExpectQuery(account).WillReturnRows(hermestest.NewRows("id").AddRow(int64(7)))
+	mock.ExpectQuery(user).WithArgs(int64(7), "user1@example.com", "Bob").
+		WillReturnRows(hermestest.NewRows("id", "account_id", "email", "name").
+			AddRow(int64(1), int64(7), "user1@example.com", "Bob"))
+	mock.ExpectQuery(account).WillReturnRows(hermestest.NewRows("id").AddRow(int64(8)))
+	mock.ExpectQuery(user).WithArgs(int64(8), "user2@example.com", "Ann").
+		WillReturnRows(hermestest.NewRows("id", "account_id", "email", "name").
+			AddRow(int64(2), int64(8), "user2@example.com", "Ann"))
+
+	// The overrides replace the builder's values
+	bob, err := hermestest.Create(ctx, mock, "factory user", hermestest.Attrs{"name": "Bob"})
+	if err != nil {
+		t.Fatalf("Unable to create the user: %s", err)
+	}
+
+	if bob["id"] != int64(1) || bob["account_id"] != int64(7) || bob["name"] != "Bob" {
+		t.Errorf("Expected the inserted row; was %v", bob)
+	}
+
+	// Each row gets the next sequence number
+	ann := hermestest.MustCreate(t, mock, "factory user", nil)
+
+	if ann["email"] != "user2@example.com" {
+		t.Errorf("Expected the second user's email; was %v", ann["email"])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateFails(t *testing.T) {
+	defineFactories()
+
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	if _, err := hermestest.Create(ctx, mock, "factory missing", nil); err == nil {
+		t.Error("Expected an error for an undefined factory")
+	}
+
+	failed := errors.New("failed")
+	hermestest.Define("factory failing", func(ctx context.Context, conn hermes.Conn, seq int) (string, hermestest.Attrs, error) {
+		return "", nil, failed
+	})
+
+	if _, err := hermestest.Create(ctx, mock, "factory failing", nil); !errors.Is(err, failed) {
+		t.Errorf("Expected the builder's error; was %v", err)
+	}
+
+	mock.ExpectQuery(`INSERT INTO "accounts"`).WillReturnRows(hermestest.NewRows("id"))
+
+	if _, err := hermestest.Create(ctx, mock, "factory account", nil); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Expected pgx.ErrNoRows when nothing is returned; was %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,2 @@
+// Package hermestest provides helpers for testing code built on hermes.Conn.
+package hermestest
@@ -0,0 +1,29 @@
+package hermestest
+
+import (
+	"testing"
+
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// AssertClean fails the test if db still has connections checked out of the pool, transactions
+// that were never committed or rolled back, or session-wide advisory locks that were never
+// released.  Call it at the end of a test, or register it with t.Cleanup, to catch resource leaks
+// before they starve the pool in production:
+//
+//	t.Cleanup(func() { hermestest.AssertClean(t, db) })
+func AssertClean(t testing.TB, db *hermes.DB) {
+	t.Helper()
+
+	if n := db.Stat().AcquiredConns(); n > 0 {
+		t.Errorf("hermes: %d database connection(s) still checked out of the pool", n)
+	}
+
+	if n := db.OpenTransactions(); n > 0 {
+		t.Errorf("hermes: %d transaction(s) still open", n)
+	}
+
+	if n := db.HeldLocks(); n > 0 {
+		t.Errorf("hermes: %d session advisory lock(s) still held", n)
+	}
+}
@@ -0,0 +1,69 @@
+package hermestest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbowman/hermes-pgx/v2"
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+func TestAssertCleanUnused(t *testing.T) {
+	db, err := hermes.Connect("postgres://localhost:1/hermes_test?connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Unable to create the pool: %s", err)
+	}
+	defer db.Shutdown()
+
+	rt := &recordingT{TB: t}
+	hermestest.AssertClean(rt, db)
+
+	if len(rt.failures) != 0 {
+		t.Errorf("Expected an unused database to be clean; was %v", rt.failures)
+	}
+}
+
+func TestAssertCleanLeaks(t *testing.T) {
+	db, err := hermes.Connect("postgres://localhost/hermes_test?sslmode=disable")
+	if err != nil {
+		t.Skipf("Unable to connect to database: %s", err)
+	}
+	defer db.Shutdown()
+
+	if err := db.Ping(context.Background()); err != nil {
+		t.Skipf("Unable to connect to database: %s", err)
+	}
+
+	tx, err := db.Begin(nil)
+	if err != nil {
+		t.Fatalf("Unable to begin a transaction: %s", err)
+	}
+
+	lock, err := db.Lock(nil, 0x6c65616b) // "leak"
+	if err != nil {
+		t.Fatalf("Unable to acquire the lock: %s", err)
+	}
+
+	rt := &recordingT{TB: t}
+	hermestest.AssertClean(rt, db)
+
+	// The transaction and the lock each hold a connection
+	if len(rt.failures) != 3 {
+		t.Errorf("Expected the connections, transaction, and lock to be reported; was %v", rt.failures)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Errorf("Unable to release the lock: %s", err)
+	}
+
+	if err := tx.Close(nil); err != nil {
+		t.Errorf("Unable to close the transaction: %s", err)
+	}
+
+	rt = &recordingT{TB: t}
+	hermestest.AssertClean(rt, db)
+
+	if len(rt.failures) != 0 {
+		t.Errorf("Expected the database to be clean once released; was %v", rt.failures)
+	}
+}
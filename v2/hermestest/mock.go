@@ -0,0 +1,809 @@
+package hermestest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+var (
+	// ErrUnexpectedCall is returned when a MockConn is called without a matching expectation.
+	ErrUnexpectedCall = errors.New("unexpected call")
+
+	// ErrUnsupported is returned by the MockConn methods that can't be mocked.
+	ErrUnsupported = errors.New("not supported by the mock")
+)
+
+// AnyArg matches any argument in Expectation.WithArgs.
+var AnyArg = anyArg{}
+
+type anyArg struct{}
+
+// MockConn is an in-memory hermes.Conn for unit testing code without a database.  Stub the
+// results of the statements the code should run, in order, with ExpectExec, ExpectQuery,
+// ExpectBatch, and ExpectCopyFrom, and the transaction calls with ExpectBegin, ExpectCommit, and
+// ExpectRollback:
+//
+//	mock := hermestest.NewMock(t)
+//	mock.ExpectBegin()
+//	mock.ExpectQuery(`select balance from accounts`).WithArgs(1).
+//	    WillReturnRows(hermestest.NewRows("balance").AddRow(100))
+//	mock.ExpectExec(`update accounts`).WithArgs(1, 50).WillReturnResult("UPDATE 1")
+//	mock.ExpectCommit()
+//
+//	err := Withdraw(ctx, mock, 1, 50)
+//
+// Each call must match the next expectation:  the SQL must match the expectation's regular
+// expression, and the arguments, if given with WithArgs, must be equal.  A call that doesn't
+// match fails the test and returns ErrUnexpectedCall.  When the test finishes, it fails if any
+// expectations weren't met.
+//
+// Like a DB, the MockConn itself ignores Commit, Rollback, and Close; the transactions from Begin
+// expect them.  Lock, TryLock, Notify, Prepare, and Deallocate always succeed, and are only
+// recorded.
+// BeginWithTimeout isn't supported.
+type MockConn struct {
+	state   *mockState
+	tx      bool
+	ended   bool
+	timeout time.Duration
+}
+
+// mockState is shared by a MockConn and its transactions.
+type mockState struct {
+	t            testing.TB
+	mutex        sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+}
+
+var _ hermes.Conn = (*MockConn)(nil)
+
+// NewMock creates a MockConn, failing the test if its expectations aren't met when the test
+// finishes.
+func NewMock(t testing.TB) *MockConn {
+	mock := &MockConn{state: &mockState{t: t}}
+
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return mock
+}
+
+// ExpectBegin expects a transaction or savepoint to be started.
+func (mock *MockConn) ExpectBegin() *Expectation {
+	return mock.expect(&Expectation{method: "Begin"})
+}
+
+// ExpectCommit expects a transaction to be committed, or a savepoint released.
+func (mock *MockConn) ExpectCommit() *Expectation {
+	return mock.expect(&Expectation{method: "Commit"})
+}
+
+// ExpectRollback expects a transaction or savepoint to be rolled back, with Rollback or Close.
+func (mock *MockConn) ExpectRollback() *Expectation {
+	return mock.expect(&Expectation{method: "Rollback"})
+}
+
+// ExpectExec expects Exec to be called with SQL matching the regular expression.  Returns an
+// empty command tag unless WillReturnResult is called.
+func (mock *MockConn) ExpectExec(pattern string) *Expectation {
+	return mock.expect(&Expectation{method: "Exec", pattern: regexp.MustCompile(pattern)})
+}
+
+// ExpectQuery expects Query, QueryRow, or QueryCursor to be called with SQL matching the regular
+// expression.  Returns no rows unless WillReturnRows is called.
+func (mock *MockConn) ExpectQuery(pattern string) *Expectation {
+	return mock.expect(&Expectation{method: "Query", pattern: regexp.MustCompile(pattern)})
+}
+
+// ExpectBatch expects SendBatch to be called with a batch of n statements.  pgx doesn't expose
+// the SQL of a queued statement, so the statements aren't matched; give their results with
+// WillReturnBatch.  Callbacks registered on the queued statements aren't called.
+func (mock *MockConn) ExpectBatch(n int) *Expectation {
+	return mock.expect(&Expectation{method: "SendBatch", batchLen: n})
+}
+
+// ExpectCopyFrom expects CopyFrom to be called on the table.  The rows are read, and their count
+// returned.
+func (mock *MockConn) ExpectCopyFrom(tableName string) *Expectation {
+	return mock.expect(&Expectation{method: "CopyFrom", table: tableName})
+}
+
+// ExpectationsWereMet returns an error listing the expectations that weren't met.
+func (mock *MockConn) ExpectationsWereMet() error {
+	mock.state.mutex.Lock()
+	defer mock.state.mutex.Unlock()
+
+	var unmet []string
+	for _, e := range mock.state.expectations {
+		if !e.met {
+			unmet = append(unmet, e.String())
+		}
+	}
+
+	if len(unmet) > 0 {
+		return fmt.Errorf("hermes: expected %s", strings.Join(unmet, ", "))
+	}
+
+	return nil
+}
+
+// Calls returns a copy of the calls made on the MockConn and its transactions, in order.
+func (mock *MockConn) Calls() []Call {
+	mock.state.mutex.Lock()
+	defer mock.state.mutex.Unlock()
+
+	calls := make([]Call, len(mock.state.calls))
+	copy(calls, mock.state.calls)
+
+	return calls
+}
+
+func (mock *MockConn) expect(e *Expectation) *Expectation {
+	mock.state.mutex.Lock()
+	defer mock.state.mutex.Unlock()
+
+	mock.state.expectations = append(mock.state.expectations, e)
+	return e
+}
+
+// match records the call and checks it against the next expectation, returning the expectation
+// if it matches.  Otherwise fails the test and returns ErrUnexpectedCall.
+func (mock *MockConn) match(call Call) (*Expectation, error) {
+	state := mock.state
+
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	var next *Expectation
+	for _, e := range state.expectations {
+		if !e.met {
+			next = e
+			break
+		}
+	}
+
+	var err error
+	switch {
+	case next == nil:
+		err = fmt.Errorf("%w: %s; expected nothing more", ErrUnexpectedCall, describe(call))
+	case !next.matches(call):
+		err = fmt.Errorf("%w: %s; expected %s", ErrUnexpectedCall, describe(call), next)
+	default:
+		next.met = true
+		err = next.err
+	}
+
+	if next == nil || !next.met {
+		state.t.Helper()
+		state.t.Errorf("hermes: %s", err)
+		next = nil
+	}
+
+	call.Err = err
+	if next != nil && next.method == "Exec" {
+		call.CommandTag = next.tag
+	}
+	state.calls = append(state.calls, call)
+
+	return next, err
+}
+
+// record the call without checking it against the expectations.
+func (mock *MockConn) record(call Call) {
+	mock.state.mutex.Lock()
+	defer mock.state.mutex.Unlock()
+
+	mock.state.calls = append(mock.state.calls, call)
+}
+
+// Begin starts a mock transaction, or savepoint if the MockConn is a transaction.
+func (mock *MockConn) Begin(context.Context) (hermes.Conn, error) {
+	if mock.ended {
+		return nil, pgx.ErrTxClosed
+	}
+
+	if _, err := mock.match(Call{Method: "Begin"}); err != nil {
+		return nil, err
+	}
+
+	return &MockConn{state: mock.state, tx: true, timeout: mock.timeout}, nil
+}
+
+// Commit the mock transaction.  Does nothing if the MockConn isn't a transaction.
+func (mock *MockConn) Commit(context.Context) error {
+	return mock.end("Commit")
+}
+
+// Rollback the mock transaction.  Does nothing if the MockConn isn't a transaction.
+func (mock *MockConn) Rollback(context.Context) error {
+	return mock.end("Rollback")
+}
+
+// Close rolls back the mock transaction.  Returns pgx.ErrTxClosed if the transaction has ended.
+func (mock *MockConn) Close(context.Context) error {
+	return mock.end("Rollback")
+}
+
+// end the transaction with the commit or rollback.
+func (mock *MockConn) end(method string) error {
+	if !mock.tx {
+		return nil
+	}
+
+	if mock.ended {
+		return pgx.ErrTxClosed
+	}
+
+	mock.ended = true
+
+	_, err := mock.match(Call{Method: method})
+	return err
+}
+
+// CopyFrom reads the rows from rowSrc and returns their count.
+func (mock *MockConn) CopyFrom(_ context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if mock.ended {
+		return 0, pgx.ErrTxClosed
+	}
+
+	table := strings.Join(tableName, ".")
+
+	if _, err := mock.match(Call{Method: "CopyFrom", SQL: table, Args: stringArgs(columnNames)}); err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for rowSrc.Next() {
+		if _, err := rowSrc.Values(); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, rowSrc.Err()
+}
+
+// SendBatch returns the results given to the expectation with WillReturnBatch.
+func (mock *MockConn) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	if mock.ended {
+		return &mockBatchResults{err: pgx.ErrTxClosed}
+	}
+
+	e, err := mock.match(Call{Method: "SendBatch", Args: []interface{}{b.Len()}})
+	if err != nil {
+		return &mockBatchResults{err: err}
+	}
+
+	return &mockBatchResults{results: e.batch}
+}
+
+// Exec returns the command tag given to the expectation with WillReturnResult.
+func (mock *MockConn) Exec(_ context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	if mock.ended {
+		return pgconn.CommandTag{}, pgx.ErrTxClosed
+	}
+
+	e, err := mock.match(Call{Method: "Exec", SQL: sql, Args: arguments})
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return e.tag, nil
+}
+
+// Query returns the rows given to the expectation with WillReturnRows.
+func (mock *MockConn) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if mock.ended {
+		return nil, pgx.ErrTxClosed
+	}
+
+	e, err := mock.match(Call{Method: "Query", SQL: sql, Args: args})
+	if err != nil {
+		return nil, err
+	}
+
+	return e.rows.rows(), nil
+}
+
+// QueryRow returns the first row given to the expectation with WillReturnRows.  Scan returns
+// pgx.ErrNoRows if there are no rows.
+func (mock *MockConn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if mock.ended {
+		return &mockRow{err: pgx.ErrTxClosed}
+	}
+
+	e, err := mock.match(Call{Method: "QueryRow", SQL: sql, Args: args})
+	if err != nil {
+		return &mockRow{err: err}
+	}
+
+	return &mockRow{rows: e.rows.rows()}
+}
+
+// ExecNamed translates the named parameters with hermes.Named, then calls Exec.
+func (mock *MockConn) ExecNamed(ctx context.Context, sql string, args map[string]interface{}) (pgconn.CommandTag, error) {
+	query, values, err := hermes.Named(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return mock.Exec(ctx, query, values...)
+}
+
+// QueryNamed translates the named parameters with hermes.Named, then calls Query.
+func (mock *MockConn) QueryNamed(ctx context.Context, sql string, args map[string]interface{}) (pgx.Rows, error) {
+	query, values, err := hermes.Named(sql, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return mock.Query(ctx, query, values...)
+}
+
+// QueryCursor returns the rows given to the expectation with WillReturnRows, as Query does.  The
+// batch size is ignored.
+func (mock *MockConn) QueryCursor(ctx context.Context, sql string, _ int, args ...interface{}) (pgx.Rows, error) {
+	return mock.Query(ctx, sql, args...)
+}
+
+// Lock records the lock and returns an AdvisoryLock that does nothing.
+func (mock *MockConn) Lock(_ context.Context, id uint64) (hermes.AdvisoryLock, error) {
+	mock.record(Call{Method: "Lock", Args: []interface{}{id}})
+	return mockLock{}, nil
+}
+
+// TryLock records the lock and returns an AdvisoryLock that does nothing.
+func (mock *MockConn) TryLock(_ context.Context, id uint64) (hermes.AdvisoryLock, error) {
+	mock.record(Call{Method: "TryLock", Args: []interface{}{id}})
+	return mockLock{}, nil
+}
+
+// WithTimeout returns a context with the MockConn's timeout, if set.
+func (mock *MockConn) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, ok := ctx.Deadline(); ok || mock.timeout == 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, mock.timeout)
+}
+
+// SetTimeout sets the timeout used by WithTimeout.
+func (mock *MockConn) SetTimeout(dur time.Duration) {
+	mock.timeout = dur
+}
+
+// BeginWithTimeout isn't supported, and returns ErrUnsupported.
+func (mock *MockConn) BeginWithTimeout(context.Context) (*hermes.ContextualTx, error) {
+	return nil, ErrUnsupported
+}
+
+// Notify records the notification.
+func (mock *MockConn) Notify(_ context.Context, channel string, payload interface{}) error {
+	mock.record(Call{Method: "Notify", SQL: channel, Args: []interface{}{payload}})
+	return nil
+}
+
+// Prepare records the statement and returns a description with its name and SQL.
+func (mock *MockConn) Prepare(_ context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	mock.record(Call{Method: "Prepare", SQL: sql, Args: []interface{}{name}})
+	return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+}
+
+// Deallocate records the statement's name.
+func (mock *MockConn) Deallocate(_ context.Context, name string) error {
+	mock.record(Call{Method: "Deallocate", Args: []interface{}{name}})
+	return nil
+}
+
+// Expectation is a call a MockConn expects, and its stubbed result.
+type Expectation struct {
+	method    string
+	pattern   *regexp.Regexp
+	args      []interface{}
+	checkArgs bool
+	table     string
+	batchLen  int
+
+	tag   pgconn.CommandTag
+	rows  *Rows
+	batch []BatchResult
+	err   error
+
+	met bool
+}
+
+// WithArgs expects the statement's arguments to equal args.  Use AnyArg to match any value.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = args
+	e.checkArgs = true
+	return e
+}
+
+// WillReturnResult returns the command tag from Exec, e.g. "UPDATE 1".
+func (e *Expectation) WillReturnResult(tag string) *Expectation {
+	e.tag = pgconn.NewCommandTag(tag)
+	return e
+}
+
+// WillReturnRows returns the rows from Query or QueryRow.
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnBatch returns the results of the statements in a batch, in order.
+func (e *Expectation) WillReturnBatch(results ...BatchResult) *Expectation {
+	e.batch = results
+	return e
+}
+
+// WillReturnError fails the call with err.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// String describes the expectation for error messages.
+func (e *Expectation) String() string {
+	switch {
+	case e.pattern != nil && e.checkArgs:
+		return fmt.Sprintf("%s matching %q with %v", e.method, e.pattern, e.args)
+	case e.pattern != nil:
+		return fmt.Sprintf("%s matching %q", e.method, e.pattern)
+	case e.method == "SendBatch":
+		return fmt.Sprintf("SendBatch of %d statement(s)", e.batchLen)
+	case e.method == "CopyFrom":
+		return fmt.Sprintf("CopyFrom into %s", e.table)
+	default:
+		return e.method
+	}
+}
+
+// matches returns true if the call meets the expectation.
+func (e *Expectation) matches(call Call) bool {
+	method := call.Method
+	if method == "QueryRow" {
+		method = "Query"
+	}
+
+	if method != e.method {
+		return false
+	}
+
+	switch e.method {
+	case "SendBatch":
+		return call.Args[0] == e.batchLen
+	case "CopyFrom":
+		return call.SQL == e.table
+	}
+
+	if e.pattern != nil && !e.pattern.MatchString(call.SQL) {
+		return false
+	}
+
+	if !e.checkArgs {
+		return true
+	}
+
+	if len(call.Args) != len(e.args) {
+		return false
+	}
+
+	for i, arg := range e.args {
+		if arg == AnyArg {
+			continue
+		}
+
+		if !reflect.DeepEqual(arg, call.Args[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// describe the call for error messages.
+func describe(call Call) string {
+	switch {
+	case call.SQL != "" && len(call.Args) > 0:
+		return fmt.Sprintf("%s %q with %v", call.Method, call.SQL, call.Args)
+	case call.SQL != "":
+		return fmt.Sprintf("%s %q", call.Method, call.SQL)
+	default:
+		return call.Method
+	}
+}
+
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
+
+// BatchResult is the stubbed result of a statement in a batch:  Tag for Exec, Rows for Query and
+// QueryRow, or Err if the statement fails.
+type BatchResult struct {
+	Tag  string
+	Rows *Rows
+	Err  error
+}
+
+// Rows are stubbed query results.  Build them with NewRows and AddRow.
+type Rows struct {
+	columns []string
+	values  [][]interface{}
+	err     error
+}
+
+// NewRows creates stubbed query results with the columns.
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// AddRow adds a row of values, one per column.
+func (r *Rows) AddRow(values ...interface{}) *Rows {
+	r.values = append(r.values, values)
+	return r
+}
+
+// RowError fails reading the rows with err after the rows added so far.
+func (r *Rows) RowError(err error) *Rows {
+	r.err = err
+	return r
+}
+
+// rows returns a pgx.Rows reading the stubbed results.
+func (r *Rows) rows() *mockRows {
+	if r == nil {
+		return &mockRows{Rows: &Rows{}, i: -1}
+	}
+
+	return &mockRows{Rows: r, i: -1}
+}
+
+// mockRows read the stubbed rows.
+type mockRows struct {
+	*Rows
+
+	i      int
+	closed bool
+	err    error
+}
+
+func (rows *mockRows) Close() {
+	rows.closed = true
+}
+
+func (rows *mockRows) Err() error {
+	return rows.err
+}
+
+func (rows *mockRows) CommandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag(fmt.Sprintf("SELECT %d", len(rows.values)))
+}
+
+func (rows *mockRows) FieldDescriptions() []pgconn.FieldDescription {
+	fields := make([]pgconn.FieldDescription, len(rows.columns))
+	for i, column := range rows.columns {
+		fields[i] = pgconn.FieldDescription{Name: column}
+	}
+	return fields
+}
+
+func (rows *mockRows) Next() bool {
+	if rows.closed {
+		return false
+	}
+
+	rows.i++
+	if rows.i >= len(rows.values) {
+		rows.err = rows.Rows.err
+		rows.closed = true
+		return false
+	}
+
+	return true
+}
+
+func (rows *mockRows) Scan(dest ...interface{}) error {
+	values, err := rows.Values()
+	if err != nil {
+		return err
+	}
+
+	if len(dest) != len(values) {
+		return fmt.Errorf("hermes: %d destination(s) for %d column(s)", len(dest), len(values))
+	}
+
+	for i, value := range values {
+		if err := assign(dest[i], value); err != nil {
+			return fmt.Errorf("hermes: can't scan column %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (rows *mockRows) Values() ([]interface{}, error) {
+	if rows.i < 0 || rows.i >= len(rows.values) {
+		return nil, errors.New("hermes: no row to read")
+	}
+
+	return rows.values[rows.i], nil
+}
+
+func (rows *mockRows) RawValues() [][]byte {
+	return nil
+}
+
+func (rows *mockRows) Conn() *pgx.Conn {
+	return nil
+}
+
+// mockRow scans the first row of the stubbed results.
+type mockRow struct {
+	rows *mockRows
+	err  error
+}
+
+func (row *mockRow) Scan(dest ...interface{}) error {
+	if row.err != nil {
+		return row.err
+	}
+
+	defer row.rows.Close()
+
+	if !row.rows.Next() {
+		if err := row.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+
+	return row.rows.Scan(dest...)
+}
+
+// mockBatchResults return the stubbed results of a batch in order.
+type mockBatchResults struct {
+	results []BatchResult
+	next    int
+	err     error
+}
+
+// result returns the next statement's result.
+func (br *mockBatchResults) result() (BatchResult, error) {
+	if br.err != nil {
+		return BatchResult{}, br.err
+	}
+
+	if br.next >= len(br.results) {
+		return BatchResult{}, errors.New("hermes: no more results in the batch")
+	}
+
+	result := br.results[br.next]
+	br.next++
+
+	return result, result.Err
+}
+
+func (br *mockBatchResults) Exec() (pgconn.CommandTag, error) {
+	result, err := br.result()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return pgconn.NewCommandTag(result.Tag), nil
+}
+
+func (br *mockBatchResults) Query() (pgx.Rows, error) {
+	result, err := br.result()
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Rows.rows(), nil
+}
+
+func (br *mockBatchResults) QueryRow() pgx.Row {
+	result, err := br.result()
+	if err != nil {
+		return &mockRow{err: err}
+	}
+
+	return &mockRow{rows: result.Rows.rows()}
+}
+
+func (br *mockBatchResults) Close() error {
+	return br.err
+}
+
+// mockLock is an AdvisoryLock that does nothing.
+type mockLock struct{}
+
+func (mockLock) Release() error {
+	return nil
+}
+
+// assign the value to the scan destination, converting between compatible types.
+func assign(dest, value interface{}) error {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("destination %T isn't a pointer", dest)
+	}
+	target = target.Elem()
+
+	if value == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	source := reflect.ValueOf(value)
+
+	// Allocate pointer destinations, e.g. a *string for a nullable column
+	if target.Kind() == reflect.Ptr && !source.Type().AssignableTo(target.Type()) {
+		elem := reflect.New(target.Type().Elem())
+		if err := assign(elem.Interface(), value); err != nil {
+			return err
+		}
+		target.Set(elem)
+		return nil
+	}
+
+	switch {
+	case source.Type().AssignableTo(target.Type()):
+		target.Set(source)
+	case convertible(source, target):
+		target.Set(source.Convert(target.Type()))
+	default:
+		return fmt.Errorf("can't assign %T to %s", value, target.Type())
+	}
+
+	return nil
+}
+
+// convertible returns true if the value converts to the target's type without reinterpreting it,
+// i.e. between numbers or between strings, not from a number to a string.
+func convertible(source, target reflect.Value) bool {
+	if !source.Type().ConvertibleTo(target.Type()) {
+		return false
+	}
+
+	return numeric(source.Kind()) == numeric(target.Kind())
+}
+
+func numeric(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,239 @@
+package hermestest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbowman/hermes-pgx/v2"
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+// recordingT records test failures instead of failing, to test the mock's own failures.
+type recordingT struct {
+	testing.TB
+
+	failures []string
+	cleanups []func()
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Error(args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprint(args...))
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Cleanup(fn func()) {
+	t.cleanups = append(t.cleanups, fn)
+}
+
+// finish runs the cleanup functions, as the test would when it finishes.
+func (t *recordingT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+// withdraw is an example repository function to test with the mock.
+func withdraw(ctx context.Context, conn hermes.Conn, id, amount int) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Close(ctx)
+
+	var balance int
+	if err := tx.QueryRow(ctx, "select balance from accounts where id = $1", id).Scan(&balance); err != nil {
+		return err
+	}
+
+	if balance < amount {
+		return errors.New("insufficient funds")
+	}
+
+	if _, err := tx.Exec(ctx, "update accounts set balance = balance - $2 where id = $1", id, amount); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func TestMockTransaction(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`select balance from accounts`).WithArgs(1).
+		WillReturnRows(hermestest.NewRows("balance").AddRow(int64(100)))
+	mock.ExpectExec(`update accounts`).WithArgs(1, hermestest.AnyArg).WillReturnResult("UPDATE 1")
+	mock.ExpectCommit()
+
+	if err := withdraw(ctx, mock, 1, 50); err != nil {
+		t.Fatalf("Unable to withdraw: %s", err)
+	}
+
+	calls := mock.Calls()
+	methods := []string{"Begin", "QueryRow", "Exec", "Commit"}
+
+	if len(calls) != len(methods) {
+		t.Fatalf("Expected %d calls; was %d", len(methods), len(calls))
+	}
+
+	for i, method := range methods {
+		if calls[i].Method != method {
+			t.Errorf("Expected call %d to be %s; was %s", i, method, calls[i].Method)
+		}
+	}
+
+	if calls[2].CommandTag.String() != "UPDATE 1" {
+		t.Errorf("Expected the command tag to be recorded; was %q", calls[2].CommandTag)
+	}
+}
+
+func TestMockRollback(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`select balance`).WillReturnRows(hermestest.NewRows("balance").AddRow(10))
+	mock.ExpectRollback()
+
+	if err := withdraw(ctx, mock, 1, 50); err == nil || err.Error() != "insufficient funds" {
+		t.Errorf("Expected insufficient funds; was %v", err)
+	}
+}
+
+func TestMockError(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`select balance`).WillReturnRows(hermestest.NewRows("balance"))
+	mock.ExpectRollback()
+
+	if err := withdraw(ctx, mock, 1, 50); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Expected pgx.ErrNoRows; was %v", err)
+	}
+
+	failed := errors.New("failed")
+
+	mock.ExpectExec(`delete`).WillReturnError(failed)
+
+	if _, err := mock.Exec(ctx, "delete from accounts"); !errors.Is(err, failed) {
+		t.Errorf("Expected the stubbed error; was %v", err)
+	}
+}
+
+func TestMockUnexpected(t *testing.T) {
+	ctx := context.Background()
+	rt := &recordingT{}
+	mock := hermestest.NewMock(rt)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	mock.ExpectExec(`insert`)
+
+	if _, err := mock.Exec(ctx, "insert into accounts"); !errors.Is(err, hermestest.ErrUnexpectedCall) {
+		t.Errorf("Expected ErrUnexpectedCall out of order; was %v", err)
+	}
+
+	if len(rt.failures) != 1 {
+		t.Errorf("Expected the unexpected call to fail the test; was %v", rt.failures)
+	}
+
+	rt.finish()
+
+	if len(rt.failures) != 2 {
+		t.Errorf("Expected the unmet expectations to fail the test; was %v", rt.failures)
+	}
+}
+
+func TestMockQuery(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectQuery(`select id, name`).WillReturnRows(
+		hermestest.NewRows("id", "name", "nickname").
+			AddRow(1, "Alice", nil).
+			AddRow(2, "Bob", "Bobby"))
+
+	rows, err := mock.Query(ctx, "select id, name, nickname from users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	type user struct {
+		id       int64
+		name     string
+		nickname *string
+	}
+
+	var users []user
+	for rows.Next() {
+		var u user
+		if err := rows.Scan(&u.id, &u.name, &u.nickname); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 users; was %d", len(users))
+	}
+
+	if users[0].nickname != nil || users[1].nickname == nil || *users[1].nickname != "Bobby" {
+		t.Errorf("Expected a nil and a set nickname; was %v and %v", users[0].nickname, users[1].nickname)
+	}
+}
+
+func TestMockBatch(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectBatch(2).WillReturnBatch(
+		hermestest.BatchResult{Tag: "INSERT 0 1"},
+		hermestest.BatchResult{Rows: hermestest.NewRows("count").AddRow(3)})
+
+	var batch pgx.Batch
+	batch.Queue("insert into users (name) values ($1)", "Carol")
+	batch.Queue("select count(*) from users")
+
+	br := mock.SendBatch(ctx, &batch)
+
+	if tag, err := br.Exec(); err != nil || tag.RowsAffected() != 1 {
+		t.Errorf("Expected 1 row inserted; was %d (%v)", tag.RowsAffected(), err)
+	}
+
+	var count int
+	if err := br.QueryRow().Scan(&count); err != nil || count != 3 {
+		t.Errorf("Expected a count of 3; was %d (%v)", count, err)
+	}
+
+	if err := br.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMockCopyFrom(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectCopyFrom("users")
+
+	n, err := mock.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"name"},
+		pgx.CopyFromRows([][]interface{}{{"Dave"}, {"Erin"}}))
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 rows copied; was %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,110 @@
+package hermestest
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// RunInRollback begins a transaction on db, calls fn with it, and always rolls the transaction
+// back, even if fn fails the test or panics.  Tests may insert, update, and delete freely without
+// cleaning up after themselves, and without seeing each other's data:
+//
+//	func TestRenameUser(t *testing.T) {
+//	    hermestest.RunInRollback(t, db, func(conn hermes.Conn) {
+//	        hermestest.LoadFixtures(t, conn, "testdata/users.sql")
+//
+//	        if err := RenameUser(ctx, conn, 1, "Alice"); err != nil {
+//	            t.Fatal(err)
+//	        }
+//	    })
+//	}
+//
+// Code under test that commits the transaction is committing a savepoint, which the rollback
+// still undoes.  See Run to share fixtures between subtests.
+func RunInRollback(t *testing.T, db *hermes.DB, fn func(conn hermes.Conn)) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Unable to begin the transaction: %s", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil {
+			t.Errorf("Unable to roll back the transaction: %s", err)
+		}
+	}()
+
+	// Commits by the code under test release a savepoint, leaving the transaction to roll back
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Unable to create a savepoint: %s", err)
+	}
+	defer savepoint.Close(ctx)
+
+	fn(savepoint)
+}
+
+// LoadFixtures runs the SQL fixture files on conn, in order, failing the test if any can't be
+// read or fail to run.  Each path may be a glob pattern, such as "testdata/fixtures/*.sql", whose
+// matches are loaded in name order.  A file may hold any number of statements; they're sent as a
+// script with hermes.ExecScript, so statements can't take arguments.
+func LoadFixtures(t testing.TB, conn hermes.Conn, paths ...string) {
+	t.Helper()
+
+	loadFixtures(t, conn, paths, filepath.Glob, os.ReadFile)
+}
+
+// LoadFixturesFS is like LoadFixtures, but reads the files from fsys, e.g. an embed.FS.
+func LoadFixturesFS(t testing.TB, conn hermes.Conn, fsys fs.FS, paths ...string) {
+	t.Helper()
+
+	glob := func(pattern string) ([]string, error) {
+		return fs.Glob(fsys, pattern)
+	}
+
+	read := func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}
+
+	loadFixtures(t, conn, paths, glob, read)
+}
+
+// loadFixtures finds the files matching each path with glob, and runs them after reading them
+// with read.  A path without a match fails the test, so a misspelled path doesn't go unnoticed.
+func loadFixtures(t testing.TB, conn hermes.Conn, paths []string, glob func(string) ([]string, error), read func(string) ([]byte, error)) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	for _, pattern := range paths {
+		files, err := glob(pattern)
+		if err != nil {
+			t.Fatalf("Unable to find the fixtures %s: %s", pattern, err)
+		}
+
+		if len(files) == 0 {
+			t.Fatalf("No fixtures found matching %s", pattern)
+		}
+
+		sort.Strings(files)
+
+		for _, file := range files {
+			script, err := read(file)
+			if err != nil {
+				t.Fatalf("Unable to read the fixtures %s: %s", file, err)
+			}
+
+			if err := hermes.ExecScript(ctx, conn, string(script)); err != nil {
+				t.Fatalf("Unable to load the fixtures %s: %s", file, err)
+			}
+		}
+	}
+}
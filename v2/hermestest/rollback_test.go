@@ -0,0 +1,42 @@
+package hermestest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"02_accounts.sql": "insert into accounts (user_id) values (1);",
+		"01_users.sql":    "insert into users (name) values ('Alice');\ninsert into users (name) values ('Bob');",
+	}
+
+	for name, script := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mock := hermestest.NewMock(t)
+	mock.ExpectExec(`(?s)^insert into users .*'Alice'.*'Bob'`)
+	mock.ExpectExec(`^insert into accounts`)
+
+	hermestest.LoadFixtures(t, mock, filepath.Join(dir, "*.sql"))
+}
+
+func TestLoadFixturesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/users.sql": {Data: []byte("insert into users (name) values ('Carol')")},
+	}
+
+	mock := hermestest.NewMock(t)
+	mock.ExpectExec(`'Carol'`)
+
+	hermestest.LoadFixturesFS(t, mock, fsys, "fixtures/users.sql")
+}
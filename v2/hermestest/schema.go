@@ -0,0 +1,237 @@
+package hermestest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// Column describes a table column as loaded from the PostgreSQL catalog.
+type Column struct {
+	Table string
+	Name  string
+
+	// Type is the formatted type of the column, e.g. "character varying(255)" or "citext".
+	Type string
+
+	// UDTName is the underlying type name, e.g. "varchar" or "int4".
+	UDTName string
+
+	NotNull    bool
+	HasDefault bool
+	Default    string
+}
+
+// ColumnProperty checks an expected property of a column, returning an error describing the
+// mismatch if the column doesn't have the property.
+type ColumnProperty func(col Column) error
+
+var (
+	// NotNull expects the column to have a NOT NULL constraint.
+	NotNull ColumnProperty = func(col Column) error {
+		if !col.NotNull {
+			return fmt.Errorf("expected %s.%s to be NOT NULL", col.Table, col.Name)
+		}
+		return nil
+	}
+
+	// Nullable expects the column to allow NULL values.
+	Nullable ColumnProperty = func(col Column) error {
+		if col.NotNull {
+			return fmt.Errorf("expected %s.%s to be nullable", col.Table, col.Name)
+		}
+		return nil
+	}
+)
+
+// Default expects the column to have a default value matching expr, as reported by PostgreSQL
+// (e.g. "now()" or "'pending'::text").  If expr is blank, simply expects some default.
+func Default(expr string) ColumnProperty {
+	return func(col Column) error {
+		if !col.HasDefault {
+			return fmt.Errorf("expected %s.%s to have a default value", col.Table, col.Name)
+		}
+
+		if expr != "" && col.Default != expr {
+			return fmt.Errorf("expected %s.%s to default to %s; was %s", col.Table, col.Name, expr, col.Default)
+		}
+
+		return nil
+	}
+}
+
+// ConstraintKind identifies the type of a table constraint, matching pg_constraint.contype.
+type ConstraintKind string
+
+// Table constraint types.
+const (
+	PrimaryKey ConstraintKind = "p"
+	ForeignKey ConstraintKind = "f"
+	Unique     ConstraintKind = "u"
+	Check      ConstraintKind = "c"
+	Exclusion  ConstraintKind = "x"
+)
+
+// String returns a readable name for the constraint kind.
+func (kind ConstraintKind) String() string {
+	switch kind {
+	case PrimaryKey:
+		return "primary key"
+	case ForeignKey:
+		return "foreign key"
+	case Unique:
+		return "unique"
+	case Check:
+		return "check"
+	case Exclusion:
+		return "exclusion"
+	}
+
+	return string(kind)
+}
+
+// AssertTable fails the test if the table doesn't exist.  The table name may be schema
+// qualified; otherwise it's resolved using the connection's search_path.
+func AssertTable(t testing.TB, conn hermes.Conn, table string) {
+	t.Helper()
+
+	var exists bool
+	row := conn.QueryRow(context.Background(),
+		"SELECT EXISTS (SELECT 1 FROM pg_class WHERE oid = to_regclass($1) AND relkind IN ('r', 'p'))",
+		table)
+	if err := row.Scan(&exists); err != nil {
+		t.Errorf("Unable to look up table %s: %s", table, err)
+		return
+	}
+
+	if !exists {
+		t.Errorf("Expected table %s to exist", table)
+	}
+}
+
+// LoadColumn looks up a column in the PostgreSQL catalog.  Returns pgx.ErrNoRows if the table or
+// column doesn't exist.
+func LoadColumn(ctx context.Context, conn hermes.Conn, table, column string) (Column, error) {
+	col := Column{Table: table, Name: column}
+
+	var def *string
+	row := conn.QueryRow(ctx, `
+		SELECT format_type(a.atttypid, a.atttypmod), t.typname, a.attnotnull, pg_get_expr(d.adbin, d.adrelid)
+		FROM pg_attribute a
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = to_regclass($1) AND a.attname = $2 AND a.attnum > 0 AND NOT a.attisdropped`,
+		table, column)
+	if err := row.Scan(&col.Type, &col.UDTName, &col.NotNull, &def); err != nil {
+		return col, err
+	}
+
+	if def != nil {
+		col.HasDefault = true
+		col.Default = *def
+	}
+
+	return col, nil
+}
+
+// AssertColumn fails the test if the column doesn't exist on the table, isn't of the given data
+// type, or doesn't have all the expected properties.  The data type may be either the formatted
+// type, e.g. "character varying(255)", or the underlying type name, e.g. "varchar"; leave it
+// blank to skip the type check.
+//
+//	hermestest.AssertColumn(t, conn, "users", "email", "citext", hermestest.NotNull)
+func AssertColumn(t testing.TB, conn hermes.Conn, table, column, dataType string, props ...ColumnProperty) {
+	t.Helper()
+
+	col, err := LoadColumn(context.Background(), conn, table, column)
+	if hermes.NoRows(err) {
+		t.Errorf("Expected column %s.%s to exist", table, column)
+		return
+	} else if err != nil {
+		t.Errorf("Unable to look up column %s.%s: %s", table, column, err)
+		return
+	}
+
+	if dataType != "" && !strings.EqualFold(col.Type, dataType) && !strings.EqualFold(col.UDTName, dataType) {
+		t.Errorf("Expected column %s.%s to be of type %s; was %s", table, column, dataType, col.Type)
+	}
+
+	for _, prop := range props {
+		if err := prop(col); err != nil {
+			t.Errorf("Column mismatch: %s", err)
+		}
+	}
+}
+
+// AssertIndex fails the test if the named index doesn't exist on the table.  If columns are
+// supplied, the index must cover exactly those columns, in order.
+func AssertIndex(t testing.TB, conn hermes.Conn, table, index string, columns ...string) {
+	t.Helper()
+	assertIndex(t, conn, table, index, false, columns)
+}
+
+// AssertUniqueIndex is like AssertIndex, but also expects the index to be unique.
+func AssertUniqueIndex(t testing.TB, conn hermes.Conn, table, index string, columns ...string) {
+	t.Helper()
+	assertIndex(t, conn, table, index, true, columns)
+}
+
+func assertIndex(t testing.TB, conn hermes.Conn, table, index string, unique bool, columns []string) {
+	t.Helper()
+
+	var isUnique bool
+	var indexed []string
+
+	row := conn.QueryRow(context.Background(), `
+		SELECT i.indisunique,
+			ARRAY(SELECT a.attname::text
+				FROM unnest(i.indkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+				ORDER BY k.ord)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		WHERE i.indrelid = to_regclass($1) AND c.relname = $2`,
+		table, index)
+	if err := row.Scan(&isUnique, &indexed); hermes.NoRows(err) {
+		t.Errorf("Expected index %s to exist on %s", index, table)
+		return
+	} else if err != nil {
+		t.Errorf("Unable to look up index %s on %s: %s", index, table, err)
+		return
+	}
+
+	if unique && !isUnique {
+		t.Errorf("Expected index %s on %s to be unique", index, table)
+	}
+
+	if len(columns) > 0 && strings.Join(columns, ",") != strings.Join(indexed, ",") {
+		t.Errorf("Expected index %s on %s to cover (%s); covers (%s)", index, table,
+			strings.Join(columns, ", "), strings.Join(indexed, ", "))
+	}
+}
+
+// AssertConstraint fails the test if the named constraint doesn't exist on the table, or isn't
+// of the expected kind.
+func AssertConstraint(t testing.TB, conn hermes.Conn, table, constraint string, kind ConstraintKind) {
+	t.Helper()
+
+	var contype string
+	row := conn.QueryRow(context.Background(),
+		"SELECT contype::text FROM pg_constraint WHERE conrelid = to_regclass($1) AND conname = $2",
+		table, constraint)
+	if err := row.Scan(&contype); hermes.NoRows(err) {
+		t.Errorf("Expected constraint %s to exist on %s", constraint, table)
+		return
+	} else if err != nil {
+		t.Errorf("Unable to look up constraint %s on %s: %s", constraint, table, err)
+		return
+	}
+
+	if ConstraintKind(contype) != kind {
+		t.Errorf("Expected constraint %s on %s to be a %s constraint; was %s", constraint, table, kind,
+			ConstraintKind(contype))
+	}
+}
@@ -0,0 +1,212 @@
+package hermestest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbowman/hermes-pgx/v2"
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+func TestColumnProperties(t *testing.T) {
+	col := hermestest.Column{Table: "users", Name: "email", NotNull: true, HasDefault: true, Default: "''::text"}
+
+	if err := hermestest.NotNull(col); err != nil {
+		t.Errorf("Expected the column to be NOT NULL: %s", err)
+	}
+
+	if err := hermestest.Nullable(col); err == nil {
+		t.Error("Expected the column not to be nullable")
+	}
+
+	if err := hermestest.Default("")(col); err != nil {
+		t.Errorf("Expected the column to have a default: %s", err)
+	}
+
+	if err := hermestest.Default("''::text")(col); err != nil {
+		t.Errorf("Expected the column's default to match: %s", err)
+	}
+
+	if err := hermestest.Default("now()")(col); err == nil {
+		t.Error("Expected the wrong default to fail")
+	}
+
+	if err := hermestest.Default("")(hermestest.Column{}); err == nil {
+		t.Error("Expected a column without a default to fail")
+	}
+}
+
+func TestConstraintKindString(t *testing.T) {
+	if hermestest.PrimaryKey.String() != "primary key" {
+		t.Errorf("Expected primary key; was %s", hermestest.PrimaryKey)
+	}
+
+	if kind := hermestest.ConstraintKind("t"); kind.String() != "t" {
+		t.Errorf("Expected an unknown kind to print as is; was %s", kind)
+	}
+}
+
+func TestAssertTable(t *testing.T) {
+	mock := hermestest.NewMock(t)
+	rt := &recordingT{}
+
+	mock.ExpectQuery(`pg_class`).WithArgs("users").
+		WillReturnRows(hermestest.NewRows("exists").AddRow(true))
+	mock.ExpectQuery(`pg_class`).WithArgs("missing").
+		WillReturnRows(hermestest.NewRows("exists").AddRow(false))
+
+	hermestest.AssertTable(rt, mock, "users")
+
+	if len(rt.failures) != 0 {
+		t.Errorf("Expected the table to exist; was %v", rt.failures)
+	}
+
+	hermestest.AssertTable(rt, mock, "missing")
+
+	if len(rt.failures) != 1 {
+		t.Errorf("Expected a missing table to fail; was %v", rt.failures)
+	}
+}
+
+func TestLoadColumn(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectQuery(`pg_attribute`).WithArgs("users", "status").
+		WillReturnRows(hermestest.NewRows("format_type", "typname", "attnotnull", "default").
+			AddRow("text", "text", true, "'pending'::text"))
+	mock.ExpectQuery(`pg_attribute`).WithArgs("users", "nickname").
+		WillReturnRows(hermestest.NewRows("format_type", "typname", "attnotnull", "default").
+			AddRow("character varying(32)", "varchar", false, nil))
+	mock.ExpectQuery(`pg_attribute`).WithArgs("users", "missing").
+		WillReturnRows(hermestest.NewRows("format_type", "typname", "attnotnull", "default"))
+
+	col, err := hermestest.LoadColumn(ctx, mock, "users", "status")
+	if err != nil {
+		t.Fatalf("Unable to load the column: %s", err)
+	}
+
+	if col.Type != "text" || !col.NotNull || !col.HasDefault || col.Default != "'pending'::text" {
+		t.Errorf("Expected a NOT NULL text column defaulting to 'pending'; was %+v", col)
+	}
+
+	col, err = hermestest.LoadColumn(ctx, mock, "users", "nickname")
+	if err != nil {
+		t.Fatalf("Unable to load the column: %s", err)
+	}
+
+	if col.UDTName != "varchar" || col.NotNull || col.HasDefault {
+		t.Errorf("Expected a nullable varchar column without a default; was %+v", col)
+	}
+
+	if _, err := hermestest.LoadColumn(ctx, mock, "users", "missing"); !hermes.NoRows(err) {
+		t.Errorf("Expected no rows for a missing column; was %v", err)
+	}
+}
+
+func TestAssertColumn(t *testing.T) {
+	mock := hermestest.NewMock(t)
+	rt := &recordingT{}
+
+	column := func() *hermestest.Rows {
+		return hermestest.NewRows("format_type", "typname", "attnotnull", "default").
+			AddRow("character varying(255)", "varchar", true, nil)
+	}
+
+	mock.ExpectQuery(`pg_attribute`).WillReturnRows(column())
+	mock.ExpectQuery(`pg_attribute`).WillReturnRows(column())
+	mock.ExpectQuery(`pg_attribute`).WillReturnRows(column())
+	mock.ExpectQuery(`pg_attribute`).WillReturnRows(hermestest.NewRows("format_type", "typname", "attnotnull", "default"))
+
+	// Either the formatted type or the underlying type name matches
+	hermestest.AssertColumn(rt, mock, "users", "email", "character varying(255)", hermestest.NotNull)
+	hermestest.AssertColumn(rt, mock, "users", "email", "VARCHAR")
+
+	if len(rt.failures) != 0 {
+		t.Fatalf("Expected the column to match; was %v", rt.failures)
+	}
+
+	hermestest.AssertColumn(rt, mock, "users", "email", "citext", hermestest.Nullable, hermestest.Default(""))
+
+	if len(rt.failures) != 3 {
+		t.Errorf("Expected the type and both properties to fail; was %v", rt.failures)
+	}
+
+	hermestest.AssertColumn(rt, mock, "users", "missing", "")
+
+	if len(rt.failures) != 4 {
+		t.Errorf("Expected a missing column to fail; was %v", rt.failures)
+	}
+}
+
+func TestAssertIndex(t *testing.T) {
+	mock := hermestest.NewMock(t)
+	rt := &recordingT{}
+
+	index := func(unique bool) *hermestest.Rows {
+		return hermestest.NewRows("indisunique", "columns").AddRow(unique, []string{"account_id", "email"})
+	}
+
+	mock.ExpectQuery(`pg_index`).WithArgs("users", "users_email_idx").WillReturnRows(index(true))
+	mock.ExpectQuery(`pg_index`).WithArgs("users", "users_email_idx").WillReturnRows(index(true))
+	mock.ExpectQuery(`pg_index`).WithArgs("users", "users_email_idx").WillReturnRows(index(false))
+	mock.ExpectQuery(`pg_index`).WithArgs("users", "users_email_idx").WillReturnRows(index(true))
+	mock.ExpectQuery(`pg_index`).WithArgs("users", "missing_idx").
+		WillReturnRows(hermestest.NewRows("indisunique", "columns"))
+
+	hermestest.AssertIndex(rt, mock, "users", "users_email_idx")
+	hermestest.AssertUniqueIndex(rt, mock, "users", "users_email_idx", "account_id", "email")
+
+	if len(rt.failures) != 0 {
+		t.Fatalf("Expected the index to match; was %v", rt.failures)
+	}
+
+	hermestest.AssertUniqueIndex(rt, mock, "users", "users_email_idx")
+
+	if len(rt.failures) != 1 {
+		t.Errorf("Expected a non-unique index to fail; was %v", rt.failures)
+	}
+
+	// The columns must be in order
+	hermestest.AssertIndex(rt, mock, "users", "users_email_idx", "email", "account_id")
+
+	if len(rt.failures) != 2 {
+		t.Errorf("Expected the wrong columns to fail; was %v", rt.failures)
+	}
+
+	hermestest.AssertIndex(rt, mock, "users", "missing_idx")
+
+	if len(rt.failures) != 3 {
+		t.Errorf("Expected a missing index to fail; was %v", rt.failures)
+	}
+}
+
+func TestAssertConstraint(t *testing.T) {
+	mock := hermestest.NewMock(t)
+	rt := &recordingT{}
+
+	mock.ExpectQuery(`pg_constraint`).WithArgs("users", "users_email_key").
+		WillReturnRows(hermestest.NewRows("contype").AddRow("u"))
+	mock.ExpectQuery(`pg_constraint`).WithArgs("users", "users_email_key").
+		WillReturnRows(hermestest.NewRows("contype").AddRow("u"))
+	mock.ExpectQuery(`pg_constraint`).WithArgs("users", "missing").
+		WillReturnRows(hermestest.NewRows("contype"))
+
+	hermestest.AssertConstraint(rt, mock, "users", "users_email_key", hermestest.Unique)
+
+	if len(rt.failures) != 0 {
+		t.Fatalf("Expected the constraint to match; was %v", rt.failures)
+	}
+
+	hermestest.AssertConstraint(rt, mock, "users", "users_email_key", hermestest.PrimaryKey)
+
+	if len(rt.failures) != 1 {
+		t.Errorf("Expected the wrong kind of constraint to fail; was %v", rt.failures)
+	}
+
+	hermestest.AssertConstraint(rt, mock, "users", "missing", hermestest.ForeignKey)
+
+	if len(rt.failures) != 2 {
+		t.Errorf("Expected a missing constraint to fail; was %v", rt.failures)
+	}
+}
@@ -0,0 +1,91 @@
+package hermestest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// SmokeLockID is the advisory lock ID Smoke acquires to verify advisory locking works.
+const SmokeLockID uint64 = 0x6865726d6573 // "hermes"
+
+// SmokeTimeout is how long Smoke waits for a deliberately slow query before expecting it to be
+// canceled.
+const SmokeTimeout = 100 * time.Millisecond
+
+// Smoke runs a quick canary check against the database, intended for deployment smoke tests and
+// readiness gates.  It verifies, in order:
+//
+//   - connectivity, by pinging the database
+//   - write permission, by inserting into a temporary table in a transaction that's rolled back
+//   - advisory locking, by acquiring a transactional advisory lock
+//   - timeout behavior, by confirming a slow query is canceled when its context expires
+//
+// Returns nil if every check passes, or an error identifying the first check that failed.
+func Smoke(ctx context.Context, db *hermes.DB) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := db.Ping(ctx); err != nil {
+		return fmt.Errorf("smoke test failed connecting to the database: %w", err)
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("smoke test failed starting a transaction: %w", err)
+	}
+	defer tx.Close(ctx)
+
+	if _, err := tx.Exec(ctx, "CREATE TEMPORARY TABLE hermes_smoke (id integer) ON COMMIT DROP"); err != nil {
+		return fmt.Errorf("smoke test failed creating a temporary table: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, "INSERT INTO hermes_smoke (id) VALUES (1)"); err != nil {
+		return fmt.Errorf("smoke test failed writing to a temporary table: %w", err)
+	}
+
+	// Another instance running its own smoke test may hold the lock, which still demonstrates
+	// advisory locks are working
+	lock, err := tx.TryLock(ctx, SmokeLockID)
+	if err != nil && !errors.Is(err, hermes.ErrLocked) {
+		return fmt.Errorf("smoke test failed acquiring an advisory lock: %w", err)
+	} else if err == nil {
+		_ = lock.Release()
+	}
+
+	if err := tx.Close(ctx); err != nil {
+		return fmt.Errorf("smoke test failed rolling back the transaction: %w", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, SmokeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := db.Exec(timeoutCtx, "SELECT pg_sleep(5)"); err == nil {
+		return errors.New("smoke test expected a slow query to time out, but it completed")
+	} else if !timedOut(err) {
+		return fmt.Errorf("smoke test expected a slow query to time out: %w", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*SmokeTimeout {
+		return fmt.Errorf("smoke test expected a slow query to time out after %s; took %s", SmokeTimeout, elapsed)
+	}
+
+	return nil
+}
+
+// timedOut returns true if the error is from a query canceled by its context's deadline:  the
+// deadline itself, pgconn's timeout error, or the server's query_canceled error (SQLSTATE 57014).
+func timedOut(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == hermes.QueryCanceled
+}
@@ -0,0 +1,31 @@
+package hermestest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+func TestTimedOut(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"deadline", fmt.Errorf("exec: %w", context.DeadlineExceeded), true},
+		{"query canceled", fmt.Errorf("exec: %w", &pgconn.PgError{Code: hermes.QueryCanceled}), true},
+		{"canceled", context.Canceled, false},
+		{"syntax error", &pgconn.PgError{Code: "42601"}, false},
+		{"connection refused", errors.New("dial tcp: connection refused"), false},
+	}
+
+	for _, test := range tests {
+		if timedOut(test.err) != test.expected {
+			t.Errorf("Expected %s to be a timeout: %v; was %v", test.name, test.expected, !test.expected)
+		}
+	}
+}
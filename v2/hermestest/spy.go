@@ -0,0 +1,280 @@
+package hermestest
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// Call records a single database operation made through a SpyConn.
+type Call struct {
+	// Method is the name of the hermes.Conn method called, e.g. "Exec" or "QueryRow".
+	Method string
+
+	// SQL is the statement executed.  Blank for SendBatch and CopyFrom.
+	SQL string
+
+	// Args are the arguments passed with the statement.
+	Args []interface{}
+
+	// Duration is how long the call took.  For QueryRow, this includes the call to Scan.
+	Duration time.Duration
+
+	// CommandTag is the result of an Exec call.
+	CommandTag pgconn.CommandTag
+
+	// Err is the error returned by the call, if any.
+	Err error
+}
+
+// SpyConn passes every operation through to a real hermes.Conn, recording the SQL, arguments,
+// timings, and results of each call so tests can assert what actually reached the database.
+// Transactions started from a SpyConn are spied on as well, and record to the same log.
+//
+// Calls made on a ContextualTx from BeginWithTimeout are not recorded.
+type SpyConn struct {
+	hermes.Conn
+
+	log *callLog
+}
+
+type callLog struct {
+	mutex sync.Mutex
+	calls []Call
+}
+
+// Spy wraps conn in a SpyConn.
+func Spy(conn hermes.Conn) *SpyConn {
+	return &SpyConn{Conn: conn, log: &callLog{}}
+}
+
+// Calls returns a copy of the calls recorded so far, in the order they were made.
+func (spy *SpyConn) Calls() []Call {
+	spy.log.mutex.Lock()
+	defer spy.log.mutex.Unlock()
+
+	calls := make([]Call, len(spy.log.calls))
+	copy(calls, spy.log.calls)
+
+	return calls
+}
+
+// Reset discards the recorded calls.
+func (spy *SpyConn) Reset() {
+	spy.log.mutex.Lock()
+	defer spy.log.mutex.Unlock()
+
+	spy.log.calls = nil
+}
+
+// Executed returns the recorded calls whose SQL matches the regular expression pattern.
+func (spy *SpyConn) Executed(pattern string) []Call {
+	re := regexp.MustCompile(pattern)
+
+	var matches []Call
+	for _, call := range spy.Calls() {
+		if re.MatchString(call.SQL) {
+			matches = append(matches, call)
+		}
+	}
+
+	return matches
+}
+
+// AssertExecuted fails the test if no recorded SQL matches the regular expression pattern.
+func (spy *SpyConn) AssertExecuted(t testing.TB, pattern string) {
+	t.Helper()
+
+	if len(spy.Executed(pattern)) == 0 {
+		t.Errorf("Expected SQL matching %q to be executed", pattern)
+	}
+}
+
+// AssertNotExecuted fails the test if any recorded SQL matches the regular expression pattern.
+// Useful to verify a caching layer actually skipped the database.
+func (spy *SpyConn) AssertNotExecuted(t testing.TB, pattern string) {
+	t.Helper()
+
+	if calls := spy.Executed(pattern); len(calls) > 0 {
+		t.Errorf("Expected SQL matching %q not to be executed; was executed %d time(s)", pattern, len(calls))
+	}
+}
+
+// AssertExecutedTimes fails the test unless exactly n recorded SQL statements match the regular
+// expression pattern.
+func (spy *SpyConn) AssertExecutedTimes(t testing.TB, pattern string, n int) {
+	t.Helper()
+
+	if calls := spy.Executed(pattern); len(calls) != n {
+		t.Errorf("Expected SQL matching %q to be executed %d time(s); was executed %d time(s)", pattern, n, len(calls))
+	}
+}
+
+func (spy *SpyConn) record(call Call) {
+	spy.log.mutex.Lock()
+	defer spy.log.mutex.Unlock()
+
+	spy.log.calls = append(spy.log.calls, call)
+}
+
+// Begin starts a transaction on the underlying Conn and returns a SpyConn wrapping it.
+func (spy *SpyConn) Begin(ctx context.Context) (hermes.Conn, error) {
+	start := time.Now()
+	tx, err := spy.Conn.Begin(ctx)
+	spy.record(Call{Method: "Begin", Duration: time.Since(start), Err: err})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpyConn{Conn: tx, log: spy.log}, nil
+}
+
+// Commit records the commit and passes it through to the underlying Conn.
+func (spy *SpyConn) Commit(ctx context.Context) error {
+	start := time.Now()
+	err := spy.Conn.Commit(ctx)
+	spy.record(Call{Method: "Commit", Duration: time.Since(start), Err: err})
+
+	return err
+}
+
+// Rollback records the rollback and passes it through to the underlying Conn.
+func (spy *SpyConn) Rollback(ctx context.Context) error {
+	start := time.Now()
+	err := spy.Conn.Rollback(ctx)
+	spy.record(Call{Method: "Rollback", Duration: time.Since(start), Err: err})
+
+	return err
+}
+
+// Close records the close and passes it through to the underlying Conn.
+func (spy *SpyConn) Close(ctx context.Context) error {
+	start := time.Now()
+	err := spy.Conn.Close(ctx)
+	spy.record(Call{Method: "Close", Duration: time.Since(start), Err: err})
+
+	return err
+}
+
+// CopyFrom records the copy and passes it through to the underlying Conn.
+func (spy *SpyConn) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	start := time.Now()
+	n, err := spy.Conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	spy.record(Call{
+		Method:     "CopyFrom",
+		Duration:   time.Since(start),
+		CommandTag: pgconn.NewCommandTag("COPY"),
+		Err:        err,
+	})
+
+	return n, err
+}
+
+// SendBatch records the batch and passes it through to the underlying Conn.  The duration is
+// only the time to send the batch, not to read the results.
+func (spy *SpyConn) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	start := time.Now()
+	results := spy.Conn.SendBatch(ctx, b)
+	spy.record(Call{Method: "SendBatch", Duration: time.Since(start)})
+
+	return results
+}
+
+// Exec records the statement and passes it through to the underlying Conn.
+func (spy *SpyConn) Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error) {
+	start := time.Now()
+	tag, err := spy.Conn.Exec(ctx, sql, arguments...)
+	spy.record(Call{
+		Method:     "Exec",
+		SQL:        sql,
+		Args:       arguments,
+		Duration:   time.Since(start),
+		CommandTag: tag,
+		Err:        err,
+	})
+
+	return tag, err
+}
+
+// Query records the query and passes it through to the underlying Conn.  The duration is only
+// the time for the query to return, not to read all the rows.
+func (spy *SpyConn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	start := time.Now()
+	rows, err := spy.Conn.Query(ctx, sql, args...)
+	spy.record(Call{Method: "Query", SQL: sql, Args: args, Duration: time.Since(start), Err: err})
+
+	return rows, err
+}
+
+// ExecNamed translates the named parameters with hermes.Named, then records the statement and
+// passes it through to the underlying Conn as Exec does.
+func (spy *SpyConn) ExecNamed(ctx context.Context, sql string, args map[string]interface{}) (pgconn.CommandTag, error) {
+	query, values, err := hermes.Named(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return spy.Exec(ctx, query, values...)
+}
+
+// QueryNamed translates the named parameters with hermes.Named, then records the query and passes
+// it through to the underlying Conn as Query does.
+func (spy *SpyConn) QueryNamed(ctx context.Context, sql string, args map[string]interface{}) (pgx.Rows, error) {
+	query, values, err := hermes.Named(sql, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return spy.Query(ctx, query, values...)
+}
+
+// QueryCursor records the query and passes it through to the underlying Conn.  As with Query, the
+// duration is only the time to fetch the first batch of rows.
+func (spy *SpyConn) QueryCursor(ctx context.Context, sql string, batchSize int, args ...interface{}) (pgx.Rows, error) {
+	start := time.Now()
+	rows, err := spy.Conn.QueryCursor(ctx, sql, batchSize, args...)
+	spy.record(Call{Method: "QueryCursor", SQL: sql, Args: args, Duration: time.Since(start), Err: err})
+
+	return rows, err
+}
+
+// QueryRow passes the query through to the underlying Conn.  The query is recorded when Scan is
+// called on the returned row, so the recorded error reflects the scan.
+func (spy *SpyConn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return &spyRow{
+		Row:   spy.Conn.QueryRow(ctx, sql, args...),
+		spy:   spy,
+		sql:   sql,
+		args:  args,
+		start: time.Now(),
+	}
+}
+
+type spyRow struct {
+	pgx.Row
+
+	spy   *SpyConn
+	sql   string
+	args  []interface{}
+	start time.Time
+}
+
+func (row *spyRow) Scan(dest ...interface{}) error {
+	err := row.Row.Scan(dest...)
+	row.spy.record(Call{
+		Method:   "QueryRow",
+		SQL:      row.sql,
+		Args:     row.args,
+		Duration: time.Since(row.start),
+		Err:      err,
+	})
+
+	return err
+}
@@ -0,0 +1,100 @@
+package hermestest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+func TestSpyRecords(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`select balance from accounts`).WithArgs(1).
+		WillReturnRows(hermestest.NewRows("balance").AddRow(int64(100)))
+	mock.ExpectExec(`update accounts`).WithArgs(1, 50).WillReturnResult("UPDATE 1")
+	mock.ExpectCommit()
+
+	spy := hermestest.Spy(mock)
+
+	if err := withdraw(ctx, spy, 1, 50); err != nil {
+		t.Fatalf("Unable to withdraw: %s", err)
+	}
+
+	calls := spy.Calls()
+	// The transaction is recorded by the same spy, including its deferred Close
+	methods := []string{"Begin", "QueryRow", "Exec", "Commit", "Close"}
+
+	if len(calls) != len(methods) {
+		t.Fatalf("Expected %d calls recorded; was %d", len(methods), len(calls))
+	}
+
+	for i, method := range methods {
+		if calls[i].Method != method {
+			t.Errorf("Expected call %d to be %s; was %s", i, method, calls[i].Method)
+		}
+	}
+
+	if len(calls[1].Args) != 1 || calls[1].Args[0] != 1 {
+		t.Errorf("Expected the query's arguments to be recorded; was %v", calls[1].Args)
+	}
+
+	if calls[2].CommandTag.String() != "UPDATE 1" {
+		t.Errorf("Expected the command tag to be recorded; was %q", calls[2].CommandTag)
+	}
+
+	spy.AssertExecuted(t, `^update accounts`)
+	spy.AssertExecutedTimes(t, `accounts`, 2)
+	spy.AssertNotExecuted(t, `delete`)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSpyRecordsErrors(t *testing.T) {
+	ctx := context.Background()
+	mock := hermestest.NewMock(t)
+
+	failed := errors.New("failed")
+	mock.ExpectExec(`delete`).WillReturnError(failed)
+
+	spy := hermestest.Spy(mock)
+
+	if _, err := spy.Exec(ctx, "delete from accounts"); !errors.Is(err, failed) {
+		t.Errorf("Expected the error to be passed through; was %v", err)
+	}
+
+	if calls := spy.Executed(`delete`); len(calls) != 1 || !errors.Is(calls[0].Err, failed) {
+		t.Errorf("Expected the error to be recorded; was %v", calls)
+	}
+
+	spy.Reset()
+
+	if calls := spy.Calls(); len(calls) != 0 {
+		t.Errorf("Expected the calls to be discarded; was %v", calls)
+	}
+}
+
+func TestSpyAssertions(t *testing.T) {
+	mock := hermestest.NewMock(t)
+	mock.ExpectExec(`insert`)
+
+	spy := hermestest.Spy(mock)
+	if _, err := spy.Exec(context.Background(), "insert into users (name) values ($1)", "Alice"); err != nil {
+		t.Fatalf("Unable to insert: %s", err)
+	}
+
+	rt := &recordingT{TB: t}
+
+	spy.AssertExecuted(rt, `delete`)
+	spy.AssertNotExecuted(rt, `insert`)
+	spy.AssertExecutedTimes(rt, `insert`, 2)
+
+	if len(rt.failures) != 3 {
+		t.Errorf("Expected 3 failed assertions; was %v", rt.failures)
+	}
+}
@@ -0,0 +1,48 @@
+package hermestest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// Run runs fn as a subtest of t, like t.Run, but inside its own savepoint on conn.  The savepoint
+// is always rolled back when the subtest finishes, so each subtest sees the data conn had when
+// Run was called, without paying for a fresh transaction and fixture setup per subtest:
+//
+//	tx, _ := db.Begin(ctx)
+//	defer tx.Close(ctx)
+//
+//	loadFixtures(tx) // expensive, done once
+//
+//	hermestest.Run(t, tx, "rename user", func(t *testing.T, conn hermes.Conn) { ... })
+//	hermestest.Run(t, tx, "delete user", func(t *testing.T, conn hermes.Conn) { ... })
+//
+// If conn is a DB rather than a transaction, each subtest simply runs in its own transaction.  If
+// fn commits its conn, e.g. to test code that commits, the savepoint is released instead.
+//
+// Subtests share a single database connection, so they must not call t.Parallel.  Returns
+// whether the subtest succeeded, like t.Run.
+func Run(t *testing.T, conn hermes.Conn, name string, fn func(t *testing.T, conn hermes.Conn)) bool {
+	t.Helper()
+
+	return t.Run(name, func(t *testing.T) {
+		ctx := context.Background()
+
+		savepoint, err := conn.Begin(ctx)
+		if err != nil {
+			t.Fatalf("Unable to create a savepoint: %s", err)
+		}
+		defer func() {
+			// Already committed by fn
+			if err := savepoint.Close(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+				t.Errorf("Unable to roll back the savepoint: %s", err)
+			}
+		}()
+
+		fn(t, savepoint)
+	})
+}
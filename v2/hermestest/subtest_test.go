@@ -0,0 +1,56 @@
+package hermestest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbowman/hermes-pgx/v2"
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+func TestRunRollsBack(t *testing.T) {
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`delete from users`).WillReturnResult("DELETE 1")
+	mock.ExpectRollback()
+
+	var ran bool
+	ok := hermestest.Run(t, mock, "delete user", func(t *testing.T, conn hermes.Conn) {
+		ran = true
+
+		if _, err := conn.Exec(context.Background(), "delete from users where id = $1", 1); err != nil {
+			t.Errorf("Unable to delete the user: %s", err)
+		}
+	})
+
+	if !ok || !ran {
+		t.Errorf("Expected the subtest to run and pass; ran %v, passed %v", ran, ok)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunCommitted(t *testing.T) {
+	mock := hermestest.NewMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	// Closing the committed savepoint isn't a failure
+	ok := hermestest.Run(t, mock, "commit", func(t *testing.T, conn hermes.Conn) {
+		if err := conn.Commit(context.Background()); err != nil {
+			t.Errorf("Unable to commit: %s", err)
+		}
+	})
+
+	if !ok {
+		t.Error("Expected committing the savepoint to pass")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
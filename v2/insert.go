@@ -0,0 +1,66 @@
+package hermes
+
+import "context"
+
+// Statement is a SQL statement and its arguments.
+type Statement struct {
+	SQL  string
+	Args []interface{}
+}
+
+// InsertOrSelect runs the insert, returning the row it inserts, or if the row already exists, runs
+// the query and returns the existing row.  Returns true if the row was inserted.  The insert must
+// return the row, with a RETURNING clause; the query must return the same columns.
+//
+//	user, created, err := hermes.InsertOrSelect[User](ctx, tx,
+//	    hermes.Statement{SQL: "insert into users (email, name) values ($1, $2) returning *", Args: []interface{}{email, name}},
+//	    hermes.Statement{SQL: "select * from users where email = $1", Args: []interface{}{email}})
+//
+// The insert runs in a savepoint.  If it fails with a unique violation, or returns no rows, e.g.
+// because of ON CONFLICT DO NOTHING, the savepoint is rolled back, so the expected conflict doesn't
+// abort the enclosing transaction, and the query runs instead.  Other errors are returned.  On a
+// *DB, the insert runs in a transaction of its own.
+//
+// If T is a struct, the row is scanned into it as with ScanStruct; otherwise the row's single
+// column is scanned into it.  In a REPEATABLE READ or SERIALIZABLE transaction, the query can't
+// see a row committed by another transaction after this one began, so use the default READ
+// COMMITTED isolation.
+func InsertOrSelect[T any](ctx context.Context, conn Conn, insert, query Statement) (T, bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var value T
+
+	savepoint, err := conn.Begin(ctx)
+	if err != nil {
+		return value, false, err
+	}
+
+	value, err = getOne[T](ctx, savepoint, insert)
+	if err == nil {
+		if err := savepoint.Commit(ctx); err != nil {
+			return value, false, err
+		}
+		return value, true, nil
+	}
+
+	if rbErr := savepoint.Rollback(ctx); rbErr != nil {
+		return value, false, rbErr
+	}
+
+	if !IsUniqueViolation(err) && !NoRows(err) {
+		return value, false, err
+	}
+
+	value, err = getOne[T](ctx, conn, query)
+	return value, false, err
+}
+
+// getOne runs the statement and scans the first row it returns into a T.  Returns pgx.ErrNoRows
+// if the statement doesn't return any rows.
+func getOne[T any](ctx context.Context, conn Conn, stmt Statement) (T, error) {
+	var value T
+	err := ScanOne(ctx, conn, &value, stmt.SQL, stmt.Args...)
+	return value, err
+}
@@ -0,0 +1,92 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// insertConn fails inserts with insertErr, and returns the existing account from queries.  It
+// acts as its own savepoint.
+type insertConn struct {
+	Conn
+
+	insertErr  error
+	committed  int
+	rolledBack int
+	selected   bool
+}
+
+func (c *insertConn) Begin(context.Context) (Conn, error) { return c, nil }
+func (c *insertConn) Commit(context.Context) error        { c.committed++; return nil }
+func (c *insertConn) Rollback(context.Context) error      { c.rolledBack++; return nil }
+
+func (c *insertConn) Query(_ context.Context, sql string, _ ...interface{}) (pgx.Rows, error) {
+	if strings.HasPrefix(sql, "insert") {
+		if c.insertErr != nil {
+			return nil, c.insertErr
+		}
+		return &fakeRows{columns: []string{"id", "display_name"}, values: [][]interface{}{{int64(2), "Bob"}}}, nil
+	}
+
+	c.selected = true
+	return &fakeRows{columns: []string{"id", "display_name"}, values: [][]interface{}{{int64(1), "Alice"}}}, nil
+}
+
+var (
+	insertAccount = Statement{SQL: "insert into accounts (display_name) values ($1) returning id, display_name", Args: []interface{}{"Alice"}}
+	selectAccount = Statement{SQL: "select id, display_name from accounts where display_name = $1", Args: []interface{}{"Alice"}}
+)
+
+func TestInsertOrSelectInserts(t *testing.T) {
+	conn := &insertConn{}
+
+	acct, inserted, err := InsertOrSelect[account](context.Background(), conn, insertAccount, selectAccount)
+	if err != nil {
+		t.Fatalf("Unable to insert account: %s", err)
+	}
+
+	if !inserted || acct.ID != 2 {
+		t.Errorf("Expected the inserted account; was %#v, %t", acct, inserted)
+	}
+
+	if conn.committed != 1 || conn.rolledBack != 0 || conn.selected {
+		t.Errorf("Expected the savepoint released without a query; was %d, %d, %t", conn.committed,
+			conn.rolledBack, conn.selected)
+	}
+}
+
+func TestInsertOrSelectConflict(t *testing.T) {
+	conn := &insertConn{insertErr: fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: UniqueViolation})}
+
+	acct, inserted, err := InsertOrSelect[account](context.Background(), conn, insertAccount, selectAccount)
+	if err != nil {
+		t.Fatalf("Unable to select account: %s", err)
+	}
+
+	if inserted || acct.ID != 1 || acct.Name != "Alice" {
+		t.Errorf("Expected the existing account; was %#v, %t", acct, inserted)
+	}
+
+	if conn.rolledBack != 1 || !conn.selected {
+		t.Errorf("Expected the savepoint rolled back before the query; was %d, %t", conn.rolledBack, conn.selected)
+	}
+}
+
+func TestInsertOrSelectError(t *testing.T) {
+	failed := &pgconn.PgError{Code: "23502"}
+	conn := &insertConn{insertErr: failed}
+
+	if _, _, err := InsertOrSelect[account](context.Background(), conn, insertAccount, selectAccount); !errors.Is(err, failed) {
+		t.Errorf("Expected the not null violation; was %v", err)
+	}
+
+	if conn.rolledBack != 1 || conn.selected {
+		t.Errorf("Expected the savepoint rolled back without a query; was %d, %t", conn.rolledBack, conn.selected)
+	}
+}
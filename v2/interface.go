@@ -42,13 +42,29 @@ type Conn interface {
 	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
 	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
 
-	// TODO: Implement Prepare on *DB?
-	// Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error)
+	// Prepare creates a named prepared statement.  On a DB, the statement is prepared on every
+	// connection in the pool; on a transaction, only on the transaction's connection.  Run the
+	// statement by passing its name in place of the SQL.
+	Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error)
+
+	// Deallocate drops the named prepared statement.
+	Deallocate(ctx context.Context, name string) error
 
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (commandTag pgconn.CommandTag, err error)
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 
+	// ExecNamed runs the SQL command with :name placeholders for the arguments.  See Named.
+	ExecNamed(ctx context.Context, sql string, args map[string]interface{}) (pgconn.CommandTag, error)
+
+	// QueryNamed runs the SQL query with :name placeholders for the arguments.  See Named.
+	QueryNamed(ctx context.Context, sql string, args map[string]interface{}) (pgx.Rows, error)
+
+	// QueryCursor runs the query through a server-side cursor in a transaction, or a savepoint if
+	// Conn is already a transaction, fetching batchSize rows at a time as the rows are read.
+	// Close the rows to end the transaction.
+	QueryCursor(ctx context.Context, sql string, batchSize int, args ...interface{}) (pgx.Rows, error)
+
 	// Lock creates a session-wide advisory lock on a connection, and a transactional advisory
 	// lock on a transaction.  Will block until the lock is available.  Returns an AdvsioryLock,
 	// which must be released when you're done with the lock.
@@ -71,4 +87,8 @@ type Conn interface {
 	// If Conn already represents a transaction, pgx will create a savepoint instead.  This is
 	// experimental; use at your own risk!
 	BeginWithTimeout(ctx context.Context) (*ContextualTx, error)
+
+	// Notify sends a notification with a JSON-encoded payload on the channel.  In a
+	// transaction, the notification is only delivered if the transaction commits.
+	Notify(ctx context.Context, channel string, payload interface{}) error
 }
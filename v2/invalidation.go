@@ -0,0 +1,140 @@
+package hermes
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// DefaultInvalidationChannel is the notification channel an InvalidationBus uses if none is
+// given.
+const DefaultInvalidationChannel = "hermes_invalidate"
+
+// InvalidationBus keeps read caches coherent across a fleet of processes using only PostgreSQL.
+// Writers call Invalidate with the keys their change affects; every process's bus receives the
+// keys over LISTEN/NOTIFY and calls the registered callbacks, which purge the stale entries.
+//
+// Keys are plain strings.  Callbacks are registered against a key prefix, so a callback for
+// "user:" receives invalidations for "user:12", "user:13", and so on.
+type InvalidationBus struct {
+	channel  string
+	listener *Listener
+
+	mutex     sync.RWMutex
+	callbacks map[string][]func(key string)
+
+	done chan struct{}
+}
+
+// NewInvalidationBus creates a bus listening for invalidations on the channel, or on
+// DefaultInvalidationChannel if channel is blank.  Every process sharing caches must use the same
+// channel.  Call Close to shut the bus down.
+//
+// Invalidations sent while the bus is disconnected from the database are lost, so after a
+// reconnect the bus invalidates everything: every callback is called with an empty key.
+func NewInvalidationBus(ctx context.Context, db *DB, channel string) (*InvalidationBus, error) {
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+
+	bus := &InvalidationBus{
+		channel:   channel,
+		listener:  NewListener(db),
+		callbacks: make(map[string][]func(key string)),
+		done:      make(chan struct{}),
+	}
+
+	bus.listener.OnReconnect = func(context.Context) error {
+		bus.invalidateAll()
+		return nil
+	}
+
+	if err := bus.listener.Listen(ctx, channel); err != nil {
+		_ = bus.listener.Close()
+		return nil, err
+	}
+
+	go bus.run()
+
+	return bus, nil
+}
+
+// OnInvalidate registers a callback for keys beginning with prefix.  Use a blank prefix to
+// receive every key.  The callback is also called with an empty key when the bus has to assume
+// everything is stale, after reconnecting to the database.
+//
+// Callbacks are called from the bus's goroutine, one at a time, so they should be quick.
+func (bus *InvalidationBus) OnInvalidate(prefix string, fn func(key string)) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	bus.callbacks[prefix] = append(bus.callbacks[prefix], fn)
+}
+
+// Invalidate publishes the keys to every process listening on the bus, including this one.  If
+// conn is a transaction, the invalidation is only sent if the transaction commits, so other
+// processes never purge their caches before the change is visible to them.
+func (bus *InvalidationBus) Invalidate(ctx context.Context, conn Conn, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if tx, ok := conn.(*Tx); ok {
+		return tx.NotifyOnCommit(bus.channel, keys)
+	}
+
+	return conn.Notify(ctx, bus.channel, keys)
+}
+
+// Close shuts the bus down and releases its database connection.
+func (bus *InvalidationBus) Close() error {
+	err := bus.listener.Close()
+	<-bus.done
+
+	return err
+}
+
+// run dispatches received invalidations to the callbacks.
+func (bus *InvalidationBus) run() {
+	defer close(bus.done)
+
+	for n := range bus.listener.Notifications() {
+		var keys []string
+		if err := n.Decode(&keys); err != nil {
+			// Not one of ours; ignore it
+			continue
+		}
+
+		for _, key := range keys {
+			bus.invalidate(key)
+		}
+	}
+}
+
+// invalidate calls the callbacks registered for the key.
+func (bus *InvalidationBus) invalidate(key string) {
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+
+	for prefix, callbacks := range bus.callbacks {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		for _, fn := range callbacks {
+			fn(key)
+		}
+	}
+}
+
+// invalidateAll calls every callback with an empty key.
+func (bus *InvalidationBus) invalidateAll() {
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+
+	for _, callbacks := range bus.callbacks {
+		for _, fn := range callbacks {
+			fn("")
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package hermes
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestInvalidationPrefixes(t *testing.T) {
+	bus := &InvalidationBus{callbacks: make(map[string][]func(key string))}
+
+	var users, all []string
+	bus.OnInvalidate("user:", func(key string) { users = append(users, key) })
+	bus.OnInvalidate("", func(key string) { all = append(all, key) })
+
+	bus.invalidate("user:12")
+	bus.invalidate("account:3")
+
+	if len(users) != 1 || users[0] != "user:12" {
+		t.Errorf("Expected user callback to receive only user:12; received %v", users)
+	}
+
+	sort.Strings(all)
+	if len(all) != 2 || all[0] != "account:3" || all[1] != "user:12" {
+		t.Errorf("Expected catch-all callback to receive every key; received %v", all)
+	}
+
+	bus.invalidateAll()
+
+	if len(users) != 2 || users[1] != "" {
+		t.Errorf("Expected invalidate all to call back with an empty key; received %v", users)
+	}
+}
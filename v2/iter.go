@@ -0,0 +1,74 @@
+//go:build go1.23
+
+package hermes
+
+import (
+	"context"
+	"iter"
+	"reflect"
+)
+
+// Rows runs the query and returns an iterator over its rows, each scanned into a T, for use with
+// range:
+//
+//	for user, err := range hermes.Rows[User](ctx, db, "select * from users") {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // ...
+//	}
+//
+// If T is a struct, each row is scanned into it as with ScanStruct.  Any other T, including
+// time.Time and types implementing sql.Scanner, is scanned from the query's single column.
+//
+// The query runs when the loop starts.  If the query or a scan fails, the iterator yields the
+// error once with a zero T, then stops.  The rows are closed when the loop ends, including on
+// break or return, so there's nothing to clean up.  Requires Go 1.23.
+func Rows[T any](ctx context.Context, conn Conn, sql string, args ...interface{}) iter.Seq2[T, error] {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		rows, err := conn.Query(ctx, sql, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		scan := func(dest *T) error { return rows.Scan(dest) }
+
+		if t := reflect.TypeOf(zero); scansStruct(t) {
+			plan, err := planScan(t, fieldNames(rows))
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			var targets []interface{}
+			scan = func(dest *T) error {
+				targets = plan.targets(reflect.ValueOf(dest).Elem(), targets)
+				return rows.Scan(targets...)
+			}
+		}
+
+		for rows.Next() {
+			var value T
+			if err := scan(&value); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			if !yield(value, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
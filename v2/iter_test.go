@@ -0,0 +1,79 @@
+//go:build go1.23
+
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestRowsStructs(t *testing.T) {
+	conn := &queryConn{columns: []string{"id", "display_name"}, values: [][]interface{}{{int64(1), "Alice"}, {int64(2), "Bob"}}}
+
+	var names []string
+	for acct, err := range Rows[account](context.Background(), conn, "select id, display_name from accounts") {
+		if err != nil {
+			t.Fatalf("Unable to read accounts: %s", err)
+		}
+		names = append(names, acct.Name)
+	}
+
+	if len(names) != 2 || names[0] != "Alice" || names[1] != "Bob" {
+		t.Errorf("Expected Alice and Bob; was %v", names)
+	}
+}
+
+func TestRowsBreak(t *testing.T) {
+	rows := &fakeRows{columns: []string{"id"}, values: [][]interface{}{{int64(1)}, {int64(2)}, {int64(3)}}}
+	conn := &rowsConn{rows: rows}
+
+	var ids []int64
+	for id, err := range Rows[int64](context.Background(), conn, "select id from accounts") {
+		if err != nil {
+			t.Fatalf("Unable to read IDs: %s", err)
+		}
+
+		ids = append(ids, id)
+		if id == 2 {
+			break
+		}
+	}
+
+	if len(ids) != 2 {
+		t.Errorf("Expected to stop after 2 IDs; was %v", ids)
+	}
+
+	if !rows.closed {
+		t.Error("Expected the rows to be closed after break")
+	}
+}
+
+func TestRowsError(t *testing.T) {
+	conn := &queryConn{columns: []string{"email"}, values: [][]interface{}{{"alice@example.com"}}}
+
+	var errs int
+	for _, err := range Rows[account](context.Background(), conn, "select email from accounts") {
+		if !errors.Is(err, ErrUnmappedColumn) {
+			t.Errorf("Expected ErrUnmappedColumn; was %v", err)
+		}
+		errs++
+	}
+
+	if errs != 1 {
+		t.Errorf("Expected the error once; was %d", errs)
+	}
+}
+
+// rowsConn returns the given rows, so tests can check they're closed.
+type rowsConn struct {
+	Conn
+
+	rows *fakeRows
+}
+
+func (c *rowsConn) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return c.rows, nil
+}
@@ -0,0 +1,77 @@
+package hermes
+
+import (
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// JSON holds a value of type T stored in a json or jsonb column.  The value is marshaled to JSON
+// when written and unmarshaled into T when scanned, so structs may be stored as jsonb without
+// custom Scanner and Valuer implementations:
+//
+//	var prefs hermes.JSON[Preferences]
+//	err := conn.QueryRow(ctx, "select preferences from users where id = $1", id).Scan(&prefs)
+//
+// An SQL NULL scans as an invalid JSON with the zero value of T, and an invalid JSON is written as
+// NULL.  When copying rows with CopyFrom, which uses the binary format, an invalid JSON is written
+// as the JSON null value instead.
+type JSON[T any] struct {
+	Value T
+	Valid bool
+}
+
+// NewJSON returns a valid JSON holding the value.
+func NewJSON[T any](value T) JSON[T] {
+	return JSON[T]{Value: value, Valid: true}
+}
+
+// ScanBytes implements pgtype.BytesScanner, unmarshaling the JSON into the value.
+func (j *JSON[T]) ScanBytes(src []byte) error {
+	var value T
+	if src == nil {
+		*j = JSON[T]{Value: value}
+		return nil
+	}
+
+	if err := json.Unmarshal(src, &value); err != nil {
+		return err
+	}
+
+	*j = JSON[T]{Value: value, Valid: true}
+	return nil
+}
+
+// TextValue implements pgtype.TextValuer, marshaling the value to JSON.
+func (j JSON[T]) TextValue() (pgtype.Text, error) {
+	if !j.Valid {
+		return pgtype.Text{}, nil
+	}
+
+	data, err := json.Marshal(j.Value)
+	if err != nil {
+		return pgtype.Text{}, err
+	}
+
+	return pgtype.Text{String: string(data), Valid: true}, nil
+}
+
+// MarshalJSON implements json.Marshaler.  An invalid JSON marshals as null.
+func (j JSON[T]) MarshalJSON() ([]byte, error) {
+	if !j.Valid {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(j.Value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  A JSON null is invalid.
+func (j *JSON[T]) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		var value T
+		*j = JSON[T]{Value: value}
+		return nil
+	}
+
+	return j.ScanBytes(data)
+}
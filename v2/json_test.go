@@ -0,0 +1,74 @@
+package hermes_test
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+type testPrefs struct {
+	Theme string `json:"theme"`
+	Size  int    `json:"size"`
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	m := pgtype.NewMap()
+	prefs := hermes.NewJSON(testPrefs{Theme: "dark", Size: 12})
+
+	for _, oid := range []uint32{pgtype.JSONOID, pgtype.JSONBOID} {
+		for _, format := range []int16{pgtype.TextFormatCode, pgtype.BinaryFormatCode} {
+			buf, err := m.Encode(oid, format, prefs, nil)
+			if err != nil {
+				t.Fatalf("Unable to encode JSON (OID %d, format %d): %s", oid, format, err)
+			}
+
+			var check hermes.JSON[testPrefs]
+			if err := m.Scan(oid, format, buf, &check); err != nil {
+				t.Fatalf("Unable to scan JSON (OID %d, format %d): %s", oid, format, err)
+			}
+
+			if !check.Valid || check.Value != prefs.Value {
+				t.Errorf("Expected %+v; was %+v", prefs, check)
+			}
+		}
+	}
+}
+
+func TestJSONNull(t *testing.T) {
+	m := pgtype.NewMap()
+
+	buf, err := m.Encode(pgtype.JSONBOID, pgtype.TextFormatCode, hermes.JSON[testPrefs]{}, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode invalid JSON: %s", err)
+	}
+
+	if buf != nil {
+		t.Errorf("Expected invalid JSON to encode as NULL; was %s", buf)
+	}
+
+	check := hermes.NewJSON(testPrefs{Theme: "dark"})
+	if err := m.Scan(pgtype.JSONBOID, pgtype.BinaryFormatCode, nil, &check); err != nil {
+		t.Fatalf("Unable to scan NULL: %s", err)
+	}
+
+	if check.Valid || check.Value.Theme != "" {
+		t.Errorf("Expected NULL to scan as an invalid, zero value; was %+v", check)
+	}
+}
+
+func TestJSONMarshal(t *testing.T) {
+	data, err := hermes.JSON[testPrefs]{}.MarshalJSON()
+	if err != nil || string(data) != "null" {
+		t.Errorf("Expected invalid JSON to marshal as null; was %s, %v", data, err)
+	}
+
+	var check hermes.JSON[testPrefs]
+	if err := check.UnmarshalJSON([]byte(`{"theme":"light"}`)); err != nil {
+		t.Fatalf("Unable to unmarshal JSON: %s", err)
+	}
+
+	if !check.Valid || check.Value.Theme != "light" {
+		t.Errorf("Expected light theme; was %+v", check)
+	}
+}
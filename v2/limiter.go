@@ -0,0 +1,503 @@
+package hermes
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrOverloaded is returned by a DB with a concurrency limit when a query can't get a slot,
+// because too many queries are already waiting or the query waited longer than MaxWait.
+var ErrOverloaded = errors.New("database overloaded")
+
+// ConcurrencyLimit configures how many queries a DB runs at once.  See DB.LimitConcurrency.
+type ConcurrencyLimit struct {
+	// MaxInFlight is the number of queries, transactions, and pinned connections allowed at
+	// once.  Zero or less removes the limit.
+	MaxInFlight int
+
+	// MaxWaiting is the number of queries allowed to wait for a slot.  Queries beyond that fail
+	// immediately with ErrOverloaded.  Zero or less lets any number of queries wait.
+	MaxWaiting int
+
+	// MaxWait is how long a query waits for a slot before failing with ErrOverloaded.  Zero
+	// waits until the query's context is done.
+	MaxWait time.Duration
+}
+
+// ConcurrencyStats describe the queries passing through a DB's concurrency limiter.
+type ConcurrencyStats struct {
+	// Limit is the current maximum number of queries in flight.
+	Limit int
+
+	// InFlight is the number of queries, transactions, and pinned connections holding a slot.
+	InFlight int
+
+	// Waiting is the number of queries waiting for a slot.
+	Waiting int
+
+	// Acquired is the total number of slots handed out.
+	Acquired int64
+
+	// Waited is the number of those that had to wait for a slot.
+	Waited int64
+
+	// WaitDuration is the total time queries spent waiting for a slot.
+	WaitDuration time.Duration
+
+	// Rejected is the number of queries that failed with ErrOverloaded.
+	Rejected int64
+
+	// Canceled is the number of queries whose context was done before they got a slot.
+	Canceled int64
+}
+
+// limiter is a FIFO semaphore with a limit that may be changed while queries are waiting.
+type limiter struct {
+	// Counters are kept first in the struct for 64-bit atomic alignment on 32-bit platforms.
+	acquired  int64
+	waited    int64
+	waitNanos int64
+	rejected  int64
+	canceled  int64
+
+	mutex      sync.Mutex
+	limit      int
+	inFlight   int
+	maxWaiting int
+	maxWait    time.Duration
+	waiters    list.List
+}
+
+// LimitConcurrency caps the number of queries, transactions, and pinned connections the DB runs
+// at once, separately from the pool's MaxConns.  Queries beyond the limit wait their turn in FIFO
+// order, so a burst of requests queues here, where it's measured and bounded, instead of piling
+// onto the pool.  See ConcurrencyStats for the queueing metrics.
+//
+// A slot is held until Exec or CopyFrom return, until the rows from Query are closed or read to
+// the end, until the row from QueryRow is scanned, until the results from SendBatch are closed,
+// until a transaction is committed or rolled back, or until a pinned connection is closed.  Calls
+// made on the pool directly, such as Acquire, aren't limited.
+//
+// Call LimitConcurrency before using the DB.  Later calls adjust the limit in place, and are safe
+// while queries are running.
+func (db *DB) LimitConcurrency(cl ConcurrencyLimit) {
+	if db.limiter == nil {
+		db.limiter = &limiter{}
+	}
+
+	db.limiter.configure(cl)
+}
+
+// ConcurrencyStats returns the queueing metrics of the DB's concurrency limiter.  Returns the
+// zero value if the DB isn't limited.
+func (db *DB) ConcurrencyStats() ConcurrencyStats {
+	if db.limiter == nil {
+		return ConcurrencyStats{}
+	}
+
+	return db.limiter.stats()
+}
+
+// Exec runs the SQL command, waiting for a slot if the DB's concurrency is limited.  If ctx is
+// marked Idempotent, the command is retried after a disconnect; see SetReconnectPolicy.  If ctx
+// is tracking writes, the write is recorded; see TrackWrites.
+func (db *DB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var tag pgconn.CommandTag
+	var err error
+
+	if db.reconnect != nil && isIdempotent(ctx) {
+		tag, err = db.reconnectExec(ctx, sql, args)
+	} else {
+		tag, err = db.exec(ctx, sql, args)
+	}
+
+	if err == nil {
+		db.recordWrite(ctx)
+	}
+
+	return tag, err
+}
+
+// exec runs the SQL command, waiting for a slot if the DB's concurrency is limited.
+func (db *DB) exec(ctx context.Context, sql string, args []interface{}) (pgconn.CommandTag, error) {
+	if err := db.acquireSlot(ctx); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer db.releaseSlot()
+
+	return db.Pool.Exec(db.timeAcquire(ctx), sql, args...)
+}
+
+// Query runs the SQL query, waiting for a slot if the DB's concurrency is limited.  The slot is
+// released when the rows are closed.  The query is retried after a disconnect if the DB has a
+// reconnect policy; see SetReconnectPolicy.
+func (db *DB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if db.reconnect != nil {
+		return db.reconnectQuery(ctx, sql, args)
+	}
+
+	return db.query(ctx, sql, args)
+}
+
+// query runs the SQL query, waiting for a slot if the DB's concurrency is limited.
+func (db *DB) query(ctx context.Context, sql string, args []interface{}) (pgx.Rows, error) {
+	if db.limiter == nil {
+		return db.Pool.Query(db.timeAcquire(ctx), sql, args...)
+	}
+
+	if err := db.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Pool.Query(db.timeAcquire(ctx), sql, args...)
+	if err != nil {
+		db.limiter.release()
+		return nil, err
+	}
+
+	return &limitedRows{Rows: rows, limiter: db.limiter}, nil
+}
+
+// QueryRow runs the SQL query, waiting for a slot if the DB's concurrency is limited.  The slot is
+// released when the row is scanned.  The query is retried after a disconnect if the DB has a
+// reconnect policy; see SetReconnectPolicy.
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if db.reconnect != nil {
+		return &reconnectRow{db: db, ctx: ctx, sql: sql, args: args}
+	}
+
+	return db.queryRow(ctx, sql, args)
+}
+
+// queryRow runs the SQL query, waiting for a slot if the DB's concurrency is limited.
+func (db *DB) queryRow(ctx context.Context, sql string, args []interface{}) pgx.Row {
+	if db.limiter == nil {
+		return db.Pool.QueryRow(db.timeAcquire(ctx), sql, args...)
+	}
+
+	if err := db.limiter.acquire(ctx); err != nil {
+		return &limitedRow{err: err}
+	}
+
+	return &limitedRow{Row: db.Pool.QueryRow(db.timeAcquire(ctx), sql, args...), limiter: db.limiter}
+}
+
+// SendBatch sends the queued queries, waiting for a slot if the DB's concurrency is limited.  The
+// slot is released when the results are closed.  If ctx is tracking writes, the batch is recorded
+// as a write once the results are closed; see TrackWrites.
+func (db *DB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var results pgx.BatchResults
+
+	if db.limiter == nil {
+		results = db.Pool.SendBatch(db.timeAcquire(ctx), b)
+	} else if err := db.limiter.acquire(ctx); err != nil {
+		return &limitedBatchResults{err: err}
+	} else {
+		results = &limitedBatchResults{BatchResults: db.Pool.SendBatch(db.timeAcquire(ctx), b), limiter: db.limiter}
+	}
+
+	if _, ok := ctx.Value(writesKey{}).(*writeTracker); ok {
+		return &trackedBatchResults{BatchResults: results, db: db, ctx: ctx}
+	}
+
+	return results
+}
+
+// CopyFrom bulk loads rows into the table, waiting for a slot if the DB's concurrency is limited.
+// If ctx is tracking writes, the write is recorded; see TrackWrites.
+func (db *DB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := db.acquireSlot(ctx); err != nil {
+		return 0, err
+	}
+
+	n, err := db.Pool.CopyFrom(db.timeAcquire(ctx), tableName, columnNames, rowSrc)
+	db.releaseSlot()
+
+	if err == nil {
+		db.recordWrite(ctx)
+	}
+
+	return n, err
+}
+
+// acquireSlot waits for a slot if the DB's concurrency is limited.
+func (db *DB) acquireSlot(ctx context.Context) error {
+	if db.limiter == nil {
+		return nil
+	}
+
+	return db.limiter.acquire(ctx)
+}
+
+// releaseSlot returns a slot acquired with acquireSlot.
+func (db *DB) releaseSlot() {
+	if db.limiter != nil {
+		db.limiter.release()
+	}
+}
+
+// configure applies the limits, waking any waiters the new limit makes room for.
+func (l *limiter) configure(cl ConcurrencyLimit) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.maxWaiting = cl.MaxWaiting
+	l.maxWait = cl.MaxWait
+	l.setLimit(cl.MaxInFlight)
+}
+
+// resize changes the number of slots, leaving the queue limits alone.
+func (l *limiter) resize(limit int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.setLimit(limit)
+}
+
+// setLimit changes the number of slots.  Must be called with the mutex locked.
+func (l *limiter) setLimit(limit int) {
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+
+	l.limit = limit
+	l.grant()
+}
+
+// grant hands free slots to the waiters, in order.  Must be called with the mutex locked.
+func (l *limiter) grant() {
+	for l.inFlight < l.limit && l.waiters.Len() > 0 {
+		ready := l.waiters.Remove(l.waiters.Front()).(chan struct{})
+		l.inFlight++
+		close(ready)
+	}
+}
+
+// acquire a slot, waiting behind any earlier queries.  Returns ErrOverloaded if the queue is full
+// or the wait exceeds MaxWait, or ctx's error if ctx is done first.
+func (l *limiter) acquire(ctx context.Context) error {
+	l.mutex.Lock()
+
+	if l.inFlight < l.limit && l.waiters.Len() == 0 {
+		l.inFlight++
+		l.mutex.Unlock()
+
+		atomic.AddInt64(&l.acquired, 1)
+		return nil
+	}
+
+	if l.maxWaiting > 0 && l.waiters.Len() >= l.maxWaiting {
+		l.mutex.Unlock()
+
+		atomic.AddInt64(&l.rejected, 1)
+		return fmt.Errorf("%w: %d queries waiting", ErrOverloaded, l.maxWaiting)
+	}
+
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(ready)
+	maxWait := l.maxWait
+	l.mutex.Unlock()
+
+	start := time.Now()
+
+	var timeout <-chan time.Time
+	if maxWait > 0 {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+
+	select {
+	case <-ready:
+		l.waitedFor(start)
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = fmt.Errorf("%w: waited %s", ErrOverloaded, maxWait)
+	}
+
+	l.mutex.Lock()
+	select {
+	case <-ready:
+		// Granted a slot while giving up, so pass it on
+		l.inFlight--
+		l.grant()
+	default:
+		l.waiters.Remove(elem)
+	}
+	l.mutex.Unlock()
+
+	if errors.Is(err, ErrOverloaded) {
+		atomic.AddInt64(&l.rejected, 1)
+	} else {
+		atomic.AddInt64(&l.canceled, 1)
+	}
+
+	return err
+}
+
+// waitedFor records a slot acquired after waiting since start.
+func (l *limiter) waitedFor(start time.Time) {
+	atomic.AddInt64(&l.acquired, 1)
+	atomic.AddInt64(&l.waited, 1)
+	atomic.AddInt64(&l.waitNanos, int64(time.Since(start)))
+}
+
+// release a slot, handing it to the next waiter, if any.
+func (l *limiter) release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.inFlight--
+	l.grant()
+}
+
+// stats returns a snapshot of the limiter's metrics.
+func (l *limiter) stats() ConcurrencyStats {
+	l.mutex.Lock()
+	limit, inFlight, waiting := l.limit, l.inFlight, l.waiters.Len()
+	l.mutex.Unlock()
+
+	if limit == math.MaxInt32 {
+		limit = 0
+	}
+
+	return ConcurrencyStats{
+		Limit:        limit,
+		InFlight:     inFlight,
+		Waiting:      waiting,
+		Acquired:     atomic.LoadInt64(&l.acquired),
+		Waited:       atomic.LoadInt64(&l.waited),
+		WaitDuration: time.Duration(atomic.LoadInt64(&l.waitNanos)),
+		Rejected:     atomic.LoadInt64(&l.rejected),
+		Canceled:     atomic.LoadInt64(&l.canceled),
+	}
+}
+
+// limitedRows release their slot when closed, including when Next reaches the end of the rows.
+type limitedRows struct {
+	pgx.Rows
+	limiter  *limiter
+	released int32
+}
+
+// Next prepares the next row for reading, releasing the slot after the last row.
+func (rows *limitedRows) Next() bool {
+	if rows.Rows.Next() {
+		return true
+	}
+
+	rows.release()
+	return false
+}
+
+// Close the rows and release the slot.
+func (rows *limitedRows) Close() {
+	rows.Rows.Close()
+	rows.release()
+}
+
+func (rows *limitedRows) release() {
+	if atomic.CompareAndSwapInt32(&rows.released, 0, 1) {
+		rows.limiter.release()
+	}
+}
+
+// limitedRow releases its slot once scanned.  If err is set, the row failed before it was
+// queried, and Scan returns err.
+type limitedRow struct {
+	pgx.Row
+	limiter  *limiter
+	err      error
+	released int32
+}
+
+// Scan the row and release the slot.  The slot is only released once, if the row is scanned
+// again.
+func (row *limitedRow) Scan(dest ...interface{}) error {
+	if row.err != nil {
+		return row.err
+	}
+
+	if atomic.CompareAndSwapInt32(&row.released, 0, 1) {
+		defer row.limiter.release()
+	}
+
+	return row.Row.Scan(dest...)
+}
+
+// limitedBatchResults release their slot when closed.  If err is set, the batch failed before it
+// was sent, and every result returns err.
+type limitedBatchResults struct {
+	pgx.BatchResults
+	limiter  *limiter
+	err      error
+	released int32
+}
+
+func (br *limitedBatchResults) Exec() (pgconn.CommandTag, error) {
+	if br.err != nil {
+		return pgconn.CommandTag{}, br.err
+	}
+	return br.BatchResults.Exec()
+}
+
+func (br *limitedBatchResults) Query() (pgx.Rows, error) {
+	if br.err != nil {
+		return nil, br.err
+	}
+	return br.BatchResults.Query()
+}
+
+func (br *limitedBatchResults) QueryRow() pgx.Row {
+	if br.err != nil {
+		return &limitedRow{err: br.err}
+	}
+	return br.BatchResults.QueryRow()
+}
+
+// Close the results and release the slot.
+func (br *limitedBatchResults) Close() error {
+	if br.err != nil {
+		return br.err
+	}
+
+	if !atomic.CompareAndSwapInt32(&br.released, 0, 1) {
+		return br.BatchResults.Close()
+	}
+
+	defer br.limiter.release()
+	return br.BatchResults.Close()
+}
@@ -0,0 +1,218 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newLimiter returns a limiter configured with cl.
+func newLimiter(cl ConcurrencyLimit) *limiter {
+	l := &limiter{}
+	l.configure(cl)
+	return l
+}
+
+// waitFor polls until check returns true, failing the test after a second.
+func waitFor(t *testing.T, check func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the limiter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimiterQueues(t *testing.T) {
+	l := newLimiter(ConcurrencyLimit{MaxInFlight: 2})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := l.acquire(ctx); err != nil {
+			t.Fatalf("Unable to acquire slot %d: %s", i, err)
+		}
+	}
+
+	order := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		i := i
+		waitFor(t, func() bool { return l.stats().Waiting == i })
+
+		go func() {
+			if err := l.acquire(ctx); err != nil {
+				t.Errorf("Unable to acquire slot: %s", err)
+			}
+			order <- i
+		}()
+	}
+
+	waitFor(t, func() bool { return l.stats().Waiting == 2 })
+
+	l.release()
+	if first := <-order; first != 0 {
+		t.Errorf("Expected the first waiter to get the slot; was waiter %d", first)
+	}
+
+	l.release()
+	if second := <-order; second != 1 {
+		t.Errorf("Expected the second waiter to get the slot; was waiter %d", second)
+	}
+
+	stats := l.stats()
+	if stats.InFlight != 2 {
+		t.Errorf("Expected 2 in flight; was %d", stats.InFlight)
+	}
+
+	if stats.Acquired != 4 {
+		t.Errorf("Expected 4 acquired; was %d", stats.Acquired)
+	}
+
+	if stats.Waited != 2 {
+		t.Errorf("Expected 2 waited; was %d", stats.Waited)
+	}
+
+	if stats.WaitDuration <= 0 {
+		t.Errorf("Expected a wait duration; was %s", stats.WaitDuration)
+	}
+}
+
+func TestLimiterRejects(t *testing.T) {
+	l := newLimiter(ConcurrencyLimit{MaxInFlight: 1, MaxWaiting: 1, MaxWait: 20 * time.Millisecond})
+	ctx := context.Background()
+
+	if err := l.acquire(ctx); err != nil {
+		t.Fatalf("Unable to acquire slot: %s", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- l.acquire(ctx)
+	}()
+
+	waitFor(t, func() bool { return l.stats().Waiting == 1 })
+
+	if err := l.acquire(ctx); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Expected ErrOverloaded with a full queue; was %v", err)
+	}
+
+	if err := <-done; !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Expected ErrOverloaded after MaxWait; was %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if err := l.acquire(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled; was %v", err)
+	}
+
+	stats := l.stats()
+	if stats.Rejected != 2 {
+		t.Errorf("Expected 2 rejected; was %d", stats.Rejected)
+	}
+
+	if stats.Canceled != 1 {
+		t.Errorf("Expected 1 canceled; was %d", stats.Canceled)
+	}
+
+	if stats.Waiting != 0 || stats.InFlight != 1 {
+		t.Errorf("Expected 1 in flight and none waiting; was %d and %d", stats.InFlight, stats.Waiting)
+	}
+}
+
+func TestLimiterRaiseLimit(t *testing.T) {
+	l := newLimiter(ConcurrencyLimit{MaxInFlight: 1})
+	ctx := context.Background()
+
+	if err := l.acquire(ctx); err != nil {
+		t.Fatalf("Unable to acquire slot: %s", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- l.acquire(ctx)
+	}()
+
+	waitFor(t, func() bool { return l.stats().Waiting == 1 })
+
+	l.configure(ConcurrencyLimit{MaxInFlight: 2})
+
+	if err := <-done; err != nil {
+		t.Fatalf("Expected raising the limit to admit the waiter; was %s", err)
+	}
+
+	l.configure(ConcurrencyLimit{})
+
+	if stats := l.stats(); stats.Limit != 0 || stats.InFlight != 2 {
+		t.Errorf("Expected no limit with 2 in flight; was %d with %d", stats.Limit, stats.InFlight)
+	}
+}
+
+func TestLimitedRows(t *testing.T) {
+	l := newLimiter(ConcurrencyLimit{MaxInFlight: 1})
+
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("Unable to acquire slot: %s", err)
+	}
+
+	rows := &limitedRows{Rows: &fakeRows{columns: []string{"id"}, values: [][]interface{}{{1}}}, limiter: l}
+
+	for rows.Next() {
+		if l.stats().InFlight != 1 {
+			t.Error("Expected the slot to be held while reading rows")
+		}
+	}
+
+	rows.Close()
+
+	if inFlight := l.stats().InFlight; inFlight != 0 {
+		t.Errorf("Expected the slot to be released once; was %d in flight", inFlight)
+	}
+}
+
+func TestLimitedRow(t *testing.T) {
+	l := newLimiter(ConcurrencyLimit{MaxInFlight: 2})
+
+	for i := 0; i < 2; i++ {
+		if err := l.acquire(context.Background()); err != nil {
+			t.Fatalf("Unable to acquire slot: %s", err)
+		}
+	}
+
+	row := &limitedRow{Row: scannedRow{}, limiter: l}
+
+	for i := 0; i < 3; i++ {
+		if err := row.Scan(); err != nil {
+			t.Fatalf("Unable to scan the row: %s", err)
+		}
+	}
+
+	if inFlight := l.stats().InFlight; inFlight != 1 {
+		t.Errorf("Expected the slot to be released once; was %d in flight", inFlight)
+	}
+}
+
+// scannedRow scans nothing.
+type scannedRow struct{}
+
+func (scannedRow) Scan(...interface{}) error {
+	return nil
+}
+
+func TestUnlimitedDB(t *testing.T) {
+	db := &DB{}
+
+	if stats := db.ConcurrencyStats(); stats != (ConcurrencyStats{}) {
+		t.Errorf("Expected empty stats; was %#v", stats)
+	}
+
+	if err := db.acquireSlot(context.Background()); err != nil {
+		t.Errorf("Expected no limit; was %s", err)
+	}
+
+	db.releaseSlot()
+}
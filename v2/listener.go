@@ -0,0 +1,713 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrListenerClosed is returned when calling Listen or Unlisten on a Listener that's been closed.
+var ErrListenerClosed = errors.New("listener closed")
+
+// ErrNotCancelable is returned by DB.Listen when its context can never be canceled, so the
+// Listener would never be closed.
+var ErrNotCancelable = errors.New("context can't be canceled")
+
+const (
+	// Reconnect delays back off exponentially between these values when a Listener loses its
+	// connection and can't immediately reconnect.
+	minReconnectDelay = 100 * time.Millisecond
+	maxReconnectDelay = 30 * time.Second
+
+	// Default number of notifications buffered by the Listener.
+	notificationBuffer = 64
+)
+
+// ListenerOptions configure how a Listener buffers notifications.
+type ListenerOptions struct {
+	// Buffer is the number of notifications buffered for the application.  Defaults to 64.
+	Buffer int
+
+	// Overflow is what to do when the buffer is full.  Defaults to OverflowBlock, which stops
+	// reading from the database connection until there's room; PostgreSQL queues notifications
+	// for the connection in the meantime, up to its own limit.  Under OverflowDisconnect, the
+	// Listener is closed and Err returns ErrSlowConsumer.
+	Overflow OverflowPolicy
+}
+
+// ListenerStats describe the notifications a Listener has handled.
+type ListenerStats struct {
+	// Received is the number of notifications received from the database.
+	Received int64
+
+	// Dropped is the number of notifications discarded because the buffer was full.
+	Dropped int64
+
+	// Buffered is the number of notifications currently waiting in the buffer.
+	Buffered int
+
+	// HighWater is the largest number of notifications that have been waiting in the buffer at
+	// once.
+	HighWater int64
+}
+
+// Notification is a message delivered to a Listener on a LISTEN channel.
+type Notification struct {
+	// PID is the process ID of the database backend that sent the notification.
+	PID uint32
+
+	// Channel the notification was sent on.
+	Channel string
+
+	// Payload is the message sent with the notification, if any.
+	Payload string
+}
+
+// Listener holds a dedicated database connection that LISTENs on one or more channels, and
+// delivers notifications over a Go channel.  If the connection is lost, the Listener reconnects
+// in the background and re-LISTENs on every channel, then calls OnReconnect so the application can
+// resync anything it may have missed while disconnected.
+//
+// The Listener's connection is separate from the database pool, so it doesn't tie up one of the
+// pool's connections.
+type Listener struct {
+	received  int64
+	dropped   int64
+	highWater int64
+
+	// OnReconnect is called after the Listener has reconnected to the database and LISTENed on
+	// its channels again.  Notifications sent while the Listener was disconnected are lost, so
+	// use this to reload any state kept in sync by notifications.  Set before calling Listen.
+	OnReconnect func(ctx context.Context) error
+
+	// OnError is called with connection errors the Listener recovers from on its own, such as
+	// a dropped connection or failed reconnect attempt, for logging.  Set before calling
+	// Listen.
+	OnError func(err error)
+
+	db       *DB
+	overflow OverflowPolicy
+
+	mutex    sync.Mutex
+	channels map[string]struct{}
+	pending  []listenCommand
+	started  bool
+	stopping bool
+	closed   bool
+	err      error
+
+	// wait cancels the connection's current WaitForNotification so pending commands can be
+	// run; wake interrupts the reconnect delay
+	wait context.CancelFunc
+	wake chan struct{}
+
+	notifications chan *Notification
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// listenCommand is a LISTEN or UNLISTEN statement queued for the Listener's connection.
+type listenCommand struct {
+	sql    string
+	result chan error
+}
+
+// NewListener creates a Listener on the database.  The Listener doesn't connect to the database
+// until the first call to Listen.  Call Close when you're done with the Listener to release its
+// connection.
+func NewListener(db *DB) *Listener {
+	return NewListenerWithOptions(db, ListenerOptions{})
+}
+
+// NewListenerWithOptions creates a Listener on the database with the given buffer size and
+// overflow policy.
+func NewListenerWithOptions(db *DB, opts ListenerOptions) *Listener {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = notificationBuffer
+	}
+
+	return &Listener{
+		db:            db,
+		overflow:      opts.Overflow,
+		channels:      make(map[string]struct{}),
+		wake:          make(chan struct{}, 1),
+		notifications: make(chan *Notification, buffer),
+		ctx:           ctx,
+		cancel:        cancel,
+		done:          make(chan struct{}),
+	}
+}
+
+// Listen starts a Listener on the channel, returning the Go channel its notifications are
+// delivered on.  The Listener reconnects and re-LISTENs if its connection drops, and is closed,
+// closing the returned channel, once ctx is done.  Returns ErrNotCancelable if ctx can't be
+// canceled, e.g. context.Background(), as the Listener and its connection would never be closed.
+// For more control, e.g. to listen on several channels or resync after a reconnect, use
+// NewListener.
+func (db *DB) Listen(ctx context.Context, channel string) (<-chan *Notification, error) {
+	if ctx == nil || ctx.Done() == nil {
+		return nil, ErrNotCancelable
+	}
+
+	l := NewListener(db)
+	if err := l.Listen(ctx, channel); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	return l.Notifications(), nil
+}
+
+// Notifications returns the Go channel notifications are delivered on.  The channel is closed
+// when the Listener is closed.
+func (l *Listener) Notifications() <-chan *Notification {
+	return l.notifications
+}
+
+// Listen starts listening for notifications on the given channels.  The first call to Listen
+// connects the Listener to the database; if the connection fails, returns the error and you may
+// try again.  Channels that are already being listened to are ignored.
+func (l *Listener) Listen(ctx context.Context, channels ...string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	l.mutex.Lock()
+
+	if l.closed || l.stopping {
+		l.mutex.Unlock()
+		return ErrListenerClosed
+	}
+
+	for _, channel := range channels {
+		l.channels[channel] = struct{}{}
+	}
+
+	if !l.started {
+		defer l.mutex.Unlock()
+
+		conn, err := l.connect(ctx, l.listening())
+		if err != nil {
+			return err
+		}
+
+		l.started = true
+		go l.run(conn)
+
+		return nil
+	}
+
+	l.mutex.Unlock()
+
+	if len(channels) == 0 {
+		return nil
+	}
+
+	return l.send(ctx, listenSQL("LISTEN", channels))
+}
+
+// Unlisten stops listening for notifications on the given channels.
+func (l *Listener) Unlisten(ctx context.Context, channels ...string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	l.mutex.Lock()
+
+	if l.closed || l.stopping {
+		l.mutex.Unlock()
+		return ErrListenerClosed
+	}
+
+	for _, channel := range channels {
+		delete(l.channels, channel)
+	}
+
+	started := l.started
+	l.mutex.Unlock()
+
+	if !started || len(channels) == 0 {
+		return nil
+	}
+
+	return l.send(ctx, listenSQL("UNLISTEN", channels))
+}
+
+// Stats returns the Listener's notification counters.
+func (l *Listener) Stats() ListenerStats {
+	return ListenerStats{
+		Received:  atomic.LoadInt64(&l.received),
+		Dropped:   atomic.LoadInt64(&l.dropped),
+		Buffered:  len(l.notifications),
+		HighWater: atomic.LoadInt64(&l.highWater),
+	}
+}
+
+// Err returns the reason the Listener closed itself, such as ErrSlowConsumer, or nil if the
+// Listener is open or was closed by the application.
+func (l *Listener) Err() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.err
+}
+
+// Channels returns the channels the Listener is listening on.
+func (l *Listener) Channels() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.listening()
+}
+
+// Close stops listening on every channel, closes the Listener's database connection, and closes
+// the notifications channel.  Any notifications not yet received are discarded.  Safe to call
+// multiple times.
+func (l *Listener) Close() error {
+	l.mutex.Lock()
+
+	if l.closed {
+		l.mutex.Unlock()
+		return nil
+	}
+
+	l.closed = true
+	started := l.started
+	l.mutex.Unlock()
+
+	l.cancel()
+
+	if started {
+		<-l.done
+	} else {
+		close(l.notifications)
+	}
+
+	return nil
+}
+
+// Shutdown stops the Listener gracefully.  Listen and Unlisten return ErrListenerClosed from
+// the moment Shutdown is called.  The Listener UNLISTENs on every channel, delivers the
+// notifications it has already received, closes its database connection, and closes the
+// notifications channel.  Shutdown then waits for the application to receive the buffered
+// notifications, up to ctx's deadline.  If ctx expires first, any remaining notifications are
+// discarded and ctx's error is returned.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	cmd := listenCommand{sql: "UNLISTEN *", result: make(chan error, 1)}
+
+	l.mutex.Lock()
+
+	if l.closed || l.stopping {
+		l.mutex.Unlock()
+		return nil
+	}
+
+	if !l.started {
+		l.mutex.Unlock()
+		return l.Close()
+	}
+
+	// Queued alongside the stopping flag, so the run loop can't stop without running it
+	l.stopping = true
+	l.pending = append(l.pending, cmd)
+	wait := l.wait
+	l.mutex.Unlock()
+
+	if wait != nil {
+		wait()
+	}
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		_ = l.Close()
+		return ctx.Err()
+	}
+
+	l.mutex.Lock()
+	l.closed = true
+	l.mutex.Unlock()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for len(l.notifications) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			drain(l.notifications)
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// listening returns the channels in the listen set.  Call with the mutex locked.
+func (l *Listener) listening() []string {
+	channels := make([]string, 0, len(l.channels))
+	for channel := range l.channels {
+		channels = append(channels, channel)
+	}
+
+	return channels
+}
+
+// send queues a LISTEN or UNLISTEN statement to run on the Listener's connection and waits for
+// the result.
+func (l *Listener) send(ctx context.Context, sql string) error {
+	cmd := listenCommand{sql: sql, result: make(chan error, 1)}
+
+	l.mutex.Lock()
+	l.pending = append(l.pending, cmd)
+	wait := l.wait
+	l.mutex.Unlock()
+
+	if wait != nil {
+		wait()
+	}
+
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case err := <-cmd.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-l.done:
+		return ErrListenerClosed
+	}
+}
+
+// connect opens a new database connection for the Listener and LISTENs on the channels.
+func (l *Listener) connect(ctx context.Context, channels []string) (*pgx.Conn, error) {
+	conn, err := pgx.ConnectConfig(ctx, l.db.Config().ConnConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(channels) > 0 {
+		if _, err := conn.Exec(ctx, listenSQL("LISTEN", channels)); err != nil {
+			_ = conn.Close(context.Background())
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// run waits for notifications on the connection and delivers them, reconnecting as necessary,
+// until the Listener is closed.
+func (l *Listener) run(conn *pgx.Conn) {
+	defer close(l.done)
+	defer close(l.notifications)
+	defer l.abandon()
+
+	delay := minReconnectDelay
+
+	for {
+		if conn == nil {
+			if !l.sleep(delay) {
+				return
+			}
+
+			// Commands queued while disconnected are covered by the reconnect
+			l.reply(nil)
+
+			l.mutex.Lock()
+			channels := l.listening()
+			l.mutex.Unlock()
+
+			var err error
+			if conn, err = l.connect(l.ctx, channels); err != nil {
+				if l.ctx.Err() != nil {
+					return
+				}
+
+				l.report(err)
+
+				if delay *= 2; delay > maxReconnectDelay {
+					delay = maxReconnectDelay
+				}
+				continue
+			}
+
+			delay = minReconnectDelay
+
+			if l.OnReconnect != nil {
+				if err := l.OnReconnect(l.ctx); err != nil {
+					l.report(err)
+				}
+			}
+		}
+
+		stop, err := l.exec(conn)
+		if stop {
+			l.flush(conn)
+			_ = conn.Close(context.Background())
+			return
+		}
+
+		if err != nil {
+			if l.ctx.Err() != nil {
+				_ = conn.Close(context.Background())
+				return
+			}
+
+			if conn.IsClosed() || IsDisconnected(err) {
+				l.report(err)
+				_ = conn.Close(context.Background())
+				conn = nil
+				continue
+			}
+		}
+
+		waitCtx, cancel := context.WithCancel(l.ctx)
+
+		l.mutex.Lock()
+		if len(l.pending) > 0 {
+			l.mutex.Unlock()
+			cancel()
+			continue
+		}
+		l.wait = cancel
+		l.mutex.Unlock()
+
+		n, err := conn.WaitForNotification(waitCtx)
+
+		l.mutex.Lock()
+		l.wait = nil
+		l.mutex.Unlock()
+
+		woken := waitCtx.Err() != nil
+		cancel()
+
+		if err != nil {
+			if l.ctx.Err() != nil {
+				_ = conn.Close(context.Background())
+				return
+			}
+
+			// Interrupted to run a pending LISTEN or UNLISTEN
+			if woken && !conn.IsClosed() {
+				continue
+			}
+
+			l.report(err)
+			_ = conn.Close(context.Background())
+			conn = nil
+			continue
+		}
+
+		if !l.deliver(&Notification{PID: n.PID, Channel: n.Channel, Payload: n.Payload}) {
+			_ = conn.Close(context.Background())
+			return
+		}
+	}
+}
+
+// deliver buffers the notification according to the Listener's overflow policy.  Returns false
+// if the Listener was closed.
+func (l *Listener) deliver(n *Notification) bool {
+	atomic.AddInt64(&l.received, 1)
+
+	switch l.overflow {
+	case OverflowDropOldest:
+		for {
+			select {
+			case l.notifications <- n:
+				l.observe()
+				return true
+			default:
+			}
+
+			select {
+			case <-l.notifications:
+				atomic.AddInt64(&l.dropped, 1)
+			default:
+			}
+		}
+
+	case OverflowDropNewest:
+		select {
+		case l.notifications <- n:
+			l.observe()
+		default:
+			atomic.AddInt64(&l.dropped, 1)
+		}
+		return true
+
+	case OverflowDisconnect:
+		select {
+		case l.notifications <- n:
+			l.observe()
+			return true
+		default:
+		}
+
+		atomic.AddInt64(&l.dropped, 1)
+
+		l.mutex.Lock()
+		l.closed = true
+		l.err = ErrSlowConsumer
+		l.mutex.Unlock()
+
+		l.cancel()
+		return false
+
+	default:
+		select {
+		case l.notifications <- n:
+			l.observe()
+			return true
+		case <-l.ctx.Done():
+			return false
+		}
+	}
+}
+
+// observe records the buffer's high-water mark.  Only called from the run goroutine.
+func (l *Listener) observe() {
+	if buffered := int64(len(l.notifications)); buffered > atomic.LoadInt64(&l.highWater) {
+		atomic.StoreInt64(&l.highWater, buffered)
+	}
+}
+
+// exec runs the pending LISTEN and UNLISTEN commands on the connection.  Returns true if the
+// Listener is shutting down, and the first error encountered, after reporting it to the command
+// that failed.
+func (l *Listener) exec(conn *pgx.Conn) (bool, error) {
+	l.mutex.Lock()
+	pending := l.pending
+	l.pending = nil
+	stop := l.stopping
+	l.mutex.Unlock()
+
+	var failed error
+	for _, cmd := range pending {
+		if failed != nil {
+			cmd.result <- failed
+			continue
+		}
+
+		_, err := conn.Exec(l.ctx, cmd.sql)
+		cmd.result <- err
+
+		if err != nil {
+			failed = err
+		}
+	}
+
+	return stop, failed
+}
+
+// flush delivers the notifications the connection received before it stopped listening.
+func (l *Listener) flush(conn *pgx.Conn) {
+	// Don't wait for new notifications; only return those already received
+	ctx, cancel := context.WithCancel(l.ctx)
+	cancel()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil || n == nil {
+			return
+		}
+
+		if !l.deliver(&Notification{PID: n.PID, Channel: n.Channel, Payload: n.Payload}) {
+			return
+		}
+	}
+}
+
+// sleep waits for the reconnect delay.  Returns false if the Listener was closed while waiting.
+func (l *Listener) sleep(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case <-l.wake:
+			// The reconnect will LISTEN on the latest channels, so there's no need to
+			// hold up commands sent while disconnected
+			l.reply(nil)
+
+			// Nothing to flush while disconnected
+			if l.isStopping() {
+				return false
+			}
+		case <-l.ctx.Done():
+			return false
+		}
+	}
+}
+
+// isStopping returns true if the Listener is shutting down.
+func (l *Listener) isStopping() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.stopping
+}
+
+// reply sends err to every pending command.
+func (l *Listener) reply(err error) {
+	l.mutex.Lock()
+	pending := l.pending
+	l.pending = nil
+	l.mutex.Unlock()
+
+	for _, cmd := range pending {
+		cmd.result <- err
+	}
+}
+
+// abandon fails any commands still pending when the Listener shuts down.
+func (l *Listener) abandon() {
+	l.reply(ErrListenerClosed)
+}
+
+// report passes a recovered error to OnError.
+func (l *Listener) report(err error) {
+	if l.OnError != nil {
+		l.OnError(err)
+	}
+}
+
+// listenSQL builds a LISTEN or UNLISTEN statement for each channel.
+func listenSQL(command string, channels []string) string {
+	statements := make([]string, len(channels))
+	for i, channel := range channels {
+		statements[i] = command + " " + pgx.Identifier{channel}.Sanitize()
+	}
+
+	return strings.Join(statements, "; ")
+}
@@ -0,0 +1,108 @@
+package hermes
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestListenerDropOldest(t *testing.T) {
+	l := NewListenerWithOptions(nil, ListenerOptions{Buffer: 2, Overflow: OverflowDropOldest})
+
+	for _, payload := range []string{"1", "2", "3"} {
+		if !l.deliver(&Notification{Channel: "test", Payload: payload}) {
+			t.Fatalf("Expected notification %s to be delivered", payload)
+		}
+	}
+
+	stats := l.Stats()
+	if stats.Received != 3 || stats.Dropped != 1 || stats.Buffered != 2 || stats.HighWater != 2 {
+		t.Errorf("Expected 3 received, 1 dropped, 2 buffered, high water 2; was %+v", stats)
+	}
+
+	if n := <-l.Notifications(); n.Payload != "2" {
+		t.Errorf("Expected oldest notification to be dropped; received %s", n.Payload)
+	}
+}
+
+func TestListenerDropNewest(t *testing.T) {
+	l := NewListenerWithOptions(nil, ListenerOptions{Buffer: 1, Overflow: OverflowDropNewest})
+
+	l.deliver(&Notification{Channel: "test", Payload: "1"})
+	l.deliver(&Notification{Channel: "test", Payload: "2"})
+
+	if n := <-l.Notifications(); n.Payload != "1" {
+		t.Errorf("Expected oldest notification to be kept; received %s", n.Payload)
+	}
+
+	if stats := l.Stats(); stats.Dropped != 1 || stats.Buffered != 0 || stats.HighWater != 1 {
+		t.Errorf("Expected 1 dropped, 0 buffered, high water 1; was %+v", stats)
+	}
+}
+
+func TestListenerDisconnect(t *testing.T) {
+	l := NewListenerWithOptions(nil, ListenerOptions{Buffer: 1, Overflow: OverflowDisconnect})
+
+	if !l.deliver(&Notification{Channel: "test", Payload: "1"}) {
+		t.Fatal("Expected first notification to be delivered")
+	}
+
+	if l.deliver(&Notification{Channel: "test", Payload: "2"}) {
+		t.Fatal("Expected listener to disconnect when the buffer overflowed")
+	}
+
+	if !errors.Is(l.Err(), ErrSlowConsumer) {
+		t.Errorf("Expected ErrSlowConsumer; was %v", l.Err())
+	}
+
+	if err := l.Listen(nil, "test"); err != ErrListenerClosed {
+		t.Errorf("Expected ErrListenerClosed; was %v", err)
+	}
+}
+
+func TestListenerDefaultBuffer(t *testing.T) {
+	l := NewListener(nil)
+
+	if cap(l.notifications) != notificationBuffer {
+		t.Errorf("Expected default buffer of %d; was %d", notificationBuffer, cap(l.notifications))
+	}
+}
+
+func TestListenerShutdownUnstarted(t *testing.T) {
+	l := NewListener(nil)
+
+	if err := l.Shutdown(nil); err != nil {
+		t.Fatalf("Unable to shut down listener: %s", err)
+	}
+
+	if _, ok := <-l.Notifications(); ok {
+		t.Error("Expected notifications channel to be closed")
+	}
+
+	if err := l.Listen(nil, "test"); err != ErrListenerClosed {
+		t.Errorf("Expected ErrListenerClosed; was %v", err)
+	}
+
+	if err := l.Shutdown(nil); err != nil {
+		t.Errorf("Expected repeated shutdown to succeed; was %s", err)
+	}
+}
+
+func TestListenerStopping(t *testing.T) {
+	l := NewListener(nil)
+	defer l.Close()
+
+	l.stopping = true
+
+	if err := l.Listen(nil, "test"); err != ErrListenerClosed {
+		t.Errorf("Expected Listen to fail while stopping; was %v", err)
+	}
+
+	if err := l.Unlisten(nil, "test"); err != ErrListenerClosed {
+		t.Errorf("Expected Unlisten to fail while stopping; was %v", err)
+	}
+
+	ps := NewPubSub(l)
+	if _, err := ps.Subscribe(nil, "test", SubscribeOptions{}); err != ErrListenerClosed {
+		t.Errorf("Expected Subscribe to fail while stopping; was %v", err)
+	}
+}
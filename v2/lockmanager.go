@@ -0,0 +1,384 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrLockLost is the error of a Lease whose advisory lock was lost with its connection.
+	ErrLockLost = errors.New("advisory lock lost")
+
+	// ErrLockTimeout is returned by LockManager.Acquire if the lock isn't available within the
+	// manager's WaitTimeout.
+	ErrLockTimeout = errors.New("timed out waiting for the advisory lock")
+)
+
+const (
+	// Default time between heartbeats on the leased locks' connections.
+	defaultHeartbeatInterval = 5 * time.Second
+
+	// Default time to wait for a heartbeat to answer.
+	defaultHeartbeatTimeout = time.Second
+)
+
+// LockManager holds session-wide advisory locks as leases, checking each lease's connection with
+// a heartbeat so the loss of a lock is noticed, e.g. when the database restarts or the network
+// drops.  That makes the locks safe to use for leader election across the instances of a
+// service:
+//
+//	locks := hermes.NewLockManager(db)
+//	go locks.Run(ctx)
+//
+//	lease, err := locks.Acquire(ctx, leaderLockID)
+//	if err != nil {
+//		return err
+//	}
+//	defer lease.Release()
+//
+//	select {
+//	case <-lease.Done():
+//		// No longer the leader; lease.Err() says why
+//	case <-ctx.Done():
+//	}
+//
+// Call Run in a goroutine to send the heartbeats.  A lease that hasn't had a heartbeat answered
+// in HeartbeatInterval plus HeartbeatTimeout is no longer Held, so a stalled leader stops acting
+// as one before another instance can take over.
+type LockManager struct {
+	// HeartbeatInterval is the time between heartbeats.  Defaults to 5 seconds.
+	HeartbeatInterval time.Duration
+
+	// HeartbeatTimeout is how long to wait for a heartbeat to answer.  Defaults to 1 second.
+	HeartbeatTimeout time.Duration
+
+	// WaitTimeout, if set, limits how long Acquire waits for the lock before returning
+	// ErrLockTimeout.
+	WaitTimeout time.Duration
+
+	// Reacquire, if true, takes a lock lost with its connection again on a new connection,
+	// retrying each heartbeat until the database is back.  The lease only ends if another
+	// session takes the lock first.  Otherwise, a lease ends as soon as its lock is lost.
+	Reacquire bool
+
+	db *DB
+
+	mutex  sync.Mutex
+	leases map[*Lease]struct{}
+}
+
+// Lease is an advisory lock held by a LockManager.  Release the lease when you're done with it.
+type Lease struct {
+	// ID is the advisory lock's ID.
+	ID uint64
+
+	manager *LockManager
+
+	mutex     sync.Mutex
+	conn      *pgxpool.Conn
+	confirmed time.Time
+	ended     bool
+	err       error
+	done      chan struct{}
+}
+
+// NewLockManager creates a LockManager taking its locks on the DB's connections.
+func NewLockManager(db *DB) *LockManager {
+	return &LockManager{db: db, leases: make(map[*Lease]struct{})}
+}
+
+// Acquire waits for the session-wide advisory lock on the ID, up to the WaitTimeout if set, and
+// returns the lease on it.  Returns ErrLockTimeout if the WaitTimeout passes first.  The lease
+// holds one of the pool's connections until it's released or lost.
+func (m *LockManager) Acquire(ctx context.Context, id uint64) (*Lease, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wait, cancel := ctx, func() {}
+	if m.WaitTimeout > 0 {
+		wait, cancel = context.WithTimeout(ctx, m.WaitTimeout)
+	}
+	defer cancel()
+
+	if _, err := conn.Exec(wait, "SELECT pg_advisory_lock($1)", id); err != nil {
+		// The lock may have been granted as the wait was canceled; closing the session is the
+		// only way to be sure it's released
+		_ = conn.Conn().Close(context.Background())
+		conn.Release()
+
+		if wait.Err() != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("%w: %d", ErrLockTimeout, id)
+		}
+		return nil, err
+	}
+
+	return m.lease(id, conn), nil
+}
+
+// TryAcquire tries to take the session-wide advisory lock on the ID, returning the lease on it if
+// successful.  If the lock is in use, returns ErrLocked.
+func (m *LockManager) TryAcquire(ctx context.Context, id uint64) (*Lease, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := m.tryLock(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.lease(id, conn), nil
+}
+
+// Leases returns the leases currently held or being reacquired.
+func (m *LockManager) Leases() []*Lease {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	leases := make([]*Lease, 0, len(m.leases))
+	for lease := range m.leases {
+		leases = append(leases, lease)
+	}
+
+	return leases
+}
+
+// Run sends the heartbeats every HeartbeatInterval until ctx is canceled.
+func (m *LockManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m.Heartbeat(ctx)
+	}
+}
+
+// Heartbeat checks the connection of every lease, ending the leases whose locks were lost, or
+// reacquiring them if Reacquire is set.  Run calls this on each interval; call it directly to
+// send heartbeats on your own schedule.
+func (m *LockManager) Heartbeat(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var wg sync.WaitGroup
+	for _, lease := range m.Leases() {
+		wg.Add(1)
+		go func(lease *Lease) {
+			defer wg.Done()
+			lease.heartbeat(ctx)
+		}(lease)
+	}
+
+	wg.Wait()
+}
+
+// Close releases all the leases.  Returns the first error releasing them.
+func (m *LockManager) Close() error {
+	var failed error
+	for _, lease := range m.Leases() {
+		if err := lease.Release(); err != nil && failed == nil {
+			failed = err
+		}
+	}
+
+	return failed
+}
+
+// lease starts tracking the lock held on the connection.
+func (m *LockManager) lease(id uint64, conn *pgxpool.Conn) *Lease {
+	atomic.AddInt64(&m.db.locksHeld, 1)
+
+	lease := &Lease{
+		ID:        id,
+		manager:   m,
+		conn:      conn,
+		confirmed: time.Now(),
+		done:      make(chan struct{}),
+	}
+
+	m.mutex.Lock()
+	m.leases[lease] = struct{}{}
+	m.mutex.Unlock()
+
+	return lease
+}
+
+// tryLock acquires a connection and tries to take the lock on it.  Returns the connection
+// holding the lock, or ErrLocked.
+func (m *LockManager) tryLock(ctx context.Context, id uint64) (*pgxpool.Conn, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var available bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", id).Scan(&available); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	if !available {
+		conn.Release()
+		return nil, ErrLocked
+	}
+
+	return conn, nil
+}
+
+// interval returns the time between heartbeats.
+func (m *LockManager) interval() time.Duration {
+	if m.HeartbeatInterval > 0 {
+		return m.HeartbeatInterval
+	}
+
+	return defaultHeartbeatInterval
+}
+
+// timeout returns how long to wait for a heartbeat.
+func (m *LockManager) timeout() time.Duration {
+	if m.HeartbeatTimeout > 0 {
+		return m.HeartbeatTimeout
+	}
+
+	return defaultHeartbeatTimeout
+}
+
+// Held returns true if the lease holds its lock, as of a heartbeat answered within the last
+// HeartbeatInterval plus HeartbeatTimeout.
+func (lease *Lease) Held() bool {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+
+	expiry := lease.manager.interval() + lease.manager.timeout()
+	return !lease.ended && lease.conn != nil && time.Since(lease.confirmed) < expiry
+}
+
+// Done returns a channel that's closed when the lease ends, because it was released or its lock
+// was lost.
+func (lease *Lease) Done() <-chan struct{} {
+	return lease.done
+}
+
+// Err returns nil while the lease is held or once it's released, or an error wrapping
+// ErrLockLost if the lock was lost.
+func (lease *Lease) Err() error {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+
+	return lease.err
+}
+
+// Release unlocks the advisory lock and returns its connection to the pool.  Safe to call more
+// than once, and after the lock was lost.
+func (lease *Lease) Release() error {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+
+	if lease.ended {
+		return nil
+	}
+
+	if conn := lease.conn; conn != nil {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lease.ID); err != nil {
+			// Closing the session releases the lock instead
+			_ = conn.Conn().Close(context.Background())
+		}
+
+		lease.drop()
+	}
+
+	lease.end(nil)
+
+	return nil
+}
+
+// heartbeat checks the lease's connection, ending or reacquiring the lease if the lock was lost.
+func (lease *Lease) heartbeat(ctx context.Context) {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+
+	if lease.ended {
+		return
+	}
+
+	m := lease.manager
+
+	if conn := lease.conn; conn != nil {
+		hbCtx, cancel := context.WithTimeout(ctx, m.timeout())
+		_, err := conn.Exec(hbCtx, "SELECT 1")
+		cancel()
+
+		if err == nil {
+			lease.confirmed = time.Now()
+			return
+		}
+
+		// A slow heartbeat on a live connection isn't a lost lock; Held expires it if it
+		// keeps up
+		if !IsDisconnected(err) && !conn.Conn().IsClosed() {
+			return
+		}
+
+		lease.drop()
+
+		if !m.Reacquire {
+			lease.end(fmt.Errorf("%w: %d: %s", ErrLockLost, lease.ID, err))
+			return
+		}
+	}
+
+	tryCtx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	conn, err := m.tryLock(tryCtx, lease.ID)
+	if errors.Is(err, ErrLocked) {
+		lease.end(fmt.Errorf("%w: %d: taken by another session", ErrLockLost, lease.ID))
+		return
+	}
+
+	// The database is still unreachable; try again on the next heartbeat
+	if err != nil {
+		return
+	}
+
+	atomic.AddInt64(&m.db.locksHeld, 1)
+	lease.conn, lease.confirmed = conn, time.Now()
+}
+
+// drop returns the lease's connection to the pool, which discards it if it's closed.  Must be
+// called with the mutex locked.
+func (lease *Lease) drop() {
+	lease.conn.Release()
+	lease.conn = nil
+	atomic.AddInt64(&lease.manager.db.locksHeld, -1)
+}
+
+// end the lease with err, and stop tracking it.  Must be called with the mutex locked.
+func (lease *Lease) end(err error) {
+	lease.ended, lease.err = true, err
+	close(lease.done)
+
+	m := lease.manager
+	m.mutex.Lock()
+	delete(m.leases, lease)
+	m.mutex.Unlock()
+}
@@ -0,0 +1,51 @@
+package hermes
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLeaseReacquiring(t *testing.T) {
+	m := NewLockManager(newUnreachableDB(t))
+	m.Reacquire = true
+	m.HeartbeatTimeout = 100 * time.Millisecond
+
+	// A lease whose connection was lost, waiting on the database to come back
+	lease := &Lease{ID: 1, manager: m, done: make(chan struct{})}
+	m.leases[lease] = struct{}{}
+
+	m.Heartbeat(context.Background())
+
+	if lease.Held() {
+		t.Error("Expected the lease not to be held without a connection")
+	}
+
+	select {
+	case <-lease.Done():
+		t.Fatalf("Expected the lease to keep trying to reacquire the lock; ended with %v", lease.Err())
+	default:
+	}
+
+	if err := lease.Release(); err != nil {
+		t.Fatalf("Unable to release the lease: %s", err)
+	}
+
+	select {
+	case <-lease.Done():
+	default:
+		t.Error("Expected the lease to be done once released")
+	}
+
+	if lease.Err() != nil {
+		t.Errorf("Expected no error from a released lease; was %s", lease.Err())
+	}
+
+	if len(m.Leases()) != 0 {
+		t.Errorf("Expected the lease to be forgotten; was %d leases", len(m.Leases()))
+	}
+
+	if err := lease.Release(); err != nil {
+		t.Errorf("Expected releasing again to do nothing; was %s", err)
+	}
+}
@@ -3,9 +3,15 @@ package hermes
 import (
 	"context"
 	"errors"
+	"hash/fnv"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // ErrLocked returned if you try to acquire an advisory lock and it's already in use.
@@ -17,29 +23,154 @@ type AdvisoryLock interface {
 
 // SessionAdvisoryLock creates a session-wide advisory lock.
 type SessionAdvisoryLock struct {
-	mutex sync.Mutex
+	sessionLock
+
+	ID uint64
 
-	ID   uint64
-	conn *pgx.Conn
+	// shared locks are released with pg_advisory_unlock_shared
+	shared bool
 }
 
-// Release the session-wide advisory lock.
+// Release the session-wide advisory lock, returning its connection to the pool.
 func (lock *SessionAdvisoryLock) Release() error {
-	lock.mutex.Lock()
-	defer lock.mutex.Unlock()
+	unlock := "SELECT pg_advisory_unlock($1)"
+	if lock.shared {
+		unlock = "SELECT pg_advisory_unlock_shared($1)"
+	}
+
+	return lock.release(func(conn *pgx.Conn) error {
+		_, err := conn.Exec(context.Background(), unlock, lock.ID)
+		return err
+	})
+}
+
+// SessionAdvisoryLocks are a set of session-wide advisory locks held on the same connection,
+// acquired and released together.
+type SessionAdvisoryLocks struct {
+	sessionLock
+
+	IDs []uint64
+}
+
+// Release the session-wide advisory locks in a single round trip, returning their connection to
+// the pool.
+func (locks *SessionAdvisoryLocks) Release() error {
+	return locks.release(func(conn *pgx.Conn) error {
+		return sendLocks(context.Background(), conn, "pg_advisory_unlock", locks.IDs)
+	})
+}
+
+// sessionLock is the connection holding one or more session-wide advisory locks.  A connection
+// acquired for the locks goes back to the pool when they're released; a PinnedConn's stays with
+// the PinnedConn.
+type sessionLock struct {
+	mutex sync.Mutex
+
+	conn   *pgxpool.Conn
+	pooled bool
+	count  int64
+	db     *DB
+	expiry *time.Timer
+}
+
+// hold records the locks held on the connection.  Set pooled if the connection was acquired for
+// the locks, to return it to the pool on release.
+func (s *sessionLock) hold(db *DB, conn *pgxpool.Conn, pooled bool, count int) {
+	s.db, s.conn, s.pooled, s.count = db, conn, pooled, int64(count)
+	atomic.AddInt64(&db.locksHeld, s.count)
+}
+
+// release calls unlock on the connection, then returns the connection to the pool if it was
+// acquired for the locks.  If unlock fails on a pooled connection, the connection is closed
+// instead, which ends the session and its locks, and the error is returned.  Does nothing if the
+// locks were already released.
+func (s *sessionLock) release(unlock func(conn *pgx.Conn) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
 	// The lock was already released
-	if lock.conn == nil {
+	if s.conn == nil {
 		return nil
 	}
 
-	if _, err := lock.conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lock.ID); err != nil {
+	err := unlock(s.conn.Conn())
+	if err != nil && !s.pooled {
 		return err
 	}
 
-	lock.conn = nil
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
 
-	return nil
+	if s.pooled {
+		if err != nil {
+			// Closing the session releases the locks instead
+			_ = s.conn.Conn().Close(context.Background())
+		}
+		s.conn.Release()
+	}
+
+	s.conn = nil
+	atomic.AddInt64(&s.db.locksHeld, -s.count)
+
+	return err
+}
+
+// held returns true until the locks are released.
+func (s *sessionLock) held() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.conn != nil
+}
+
+// forget marks the locks released without unlocking them, when the session's locks are released
+// some other way, e.g. by closing a PinnedConn.  Returns true if the locks were still held.
+func (s *sessionLock) forget() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.conn == nil {
+		return false
+	}
+
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+
+	s.conn = nil
+	atomic.AddInt64(&s.db.locksHeld, -s.count)
+
+	return true
+}
+
+// SetMaxLockHold releases session-wide advisory locks taken on the DB's own connections, with
+// DB.Lock, DB.TryLock, and so on, if they're still held after dur, returning their connections to
+// the pool.  It's a safety net for locks that are never released, which would otherwise hold a
+// connection from the pool forever.  Locks on a PinnedConn aren't affected.  Defaults to 0, which
+// never expires a lock; a lock that's garbage collected without being released is still released
+// then.
+//
+// Call SetMaxLockHold before using the DB.
+func (db *DB) SetMaxLockHold(dur time.Duration) {
+	db.maxLockHold = dur
+}
+
+// guard protects against forgetting to release a lock holding a connection from the pool: the
+// lock is released if it's garbage collected, or once it's been held for the DB's MaxLockHold.
+func (db *DB) guard(lock AdvisoryLock, s *sessionLock) {
+	if db.maxLockHold > 0 {
+		s.expiry = time.AfterFunc(db.maxLockHold, func() {
+			_ = lock.Release()
+		})
+	}
+
+	// Finalizers run one at a time, so don't hold the others up on the round trip
+	runtime.SetFinalizer(lock, func(lock AdvisoryLock) {
+		go func() {
+			_ = lock.Release()
+		}()
+	})
 }
 
 // Lock creates a session-wide advisory lock in the database.  Call Release() to release the
@@ -55,13 +186,15 @@ func (db *DB) Lock(ctx context.Context, id uint64) (AdvisoryLock, error) {
 	}
 
 	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", id); err != nil {
+		conn.Release()
 		return nil, err
 	}
 
-	return &SessionAdvisoryLock{
-		ID:   id,
-		conn: conn.Conn(),
-	}, nil
+	lock := &SessionAdvisoryLock{ID: id}
+	lock.hold(db, conn, true, 1)
+	db.guard(lock, &lock.sessionLock)
+
+	return lock, nil
 }
 
 // TryLock tries to create a session-wide advisory lock in the database.  If successful, returns the
@@ -79,17 +212,152 @@ func (db *DB) TryLock(ctx context.Context, id uint64) (AdvisoryLock, error) {
 	var available bool
 	row := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", id)
 	if err := row.Scan(&available); err != nil {
+		conn.Release()
 		return nil, err
 	}
 
 	if !available {
+		conn.Release()
 		return nil, ErrLocked
 	}
 
-	return &SessionAdvisoryLock{
-		ID:   id,
-		conn: conn.Conn(),
-	}, nil
+	lock := &SessionAdvisoryLock{ID: id}
+	lock.hold(db, conn, true, 1)
+	db.guard(lock, &lock.sessionLock)
+
+	return lock, nil
+}
+
+// LockShared creates a session-wide shared advisory lock in the database.  Any number of sessions
+// may hold a shared lock on the ID at once, but not while another session holds an exclusive
+// lock on it with Lock, so readers can share a lock that a writer takes exclusively.  Call
+// Release() to release the advisory lock.
+func (db *DB) LockShared(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock_shared($1)", id); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	lock := &SessionAdvisoryLock{ID: id, shared: true}
+	lock.hold(db, conn, true, 1)
+	db.guard(lock, &lock.sessionLock)
+
+	return lock, nil
+}
+
+// TryLockShared tries to create a session-wide shared advisory lock in the database.  If
+// successful, returns the advisory lock.  If another session holds an exclusive lock on the ID,
+// returns ErrLocked.  See LockShared.
+func (db *DB) TryLockShared(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var available bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock_shared($1)", id).Scan(&available); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	if !available {
+		conn.Release()
+		return nil, ErrLocked
+	}
+
+	lock := &SessionAdvisoryLock{ID: id, shared: true}
+	lock.hold(db, conn, true, 1)
+	db.guard(lock, &lock.sessionLock)
+
+	return lock, nil
+}
+
+// LockAll creates session-wide advisory locks on all the IDs, on the same connection, sending
+// the pg_advisory_lock calls in a single round trip rather than one per ID.  The locks are taken
+// in ascending order, so concurrent calls with overlapping IDs can't deadlock each other, and
+// duplicate IDs are locked once.  If any lock fails, those already acquired are released.  Call
+// Release() to release all the locks.
+func (db *DB) LockAll(ctx context.Context, ids ...uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids = lockOrder(ids)
+
+	if err := lockAll(ctx, conn.Conn(), "pg_advisory_lock", ids); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	locks := &SessionAdvisoryLocks{IDs: ids}
+	locks.hold(db, conn, true, len(ids))
+	db.guard(locks, &locks.sessionLock)
+
+	return locks, nil
+}
+
+// lockOrder returns a sorted copy of the IDs, without duplicates.
+func lockOrder(ids []uint64) []uint64 {
+	sorted := append([]uint64{}, ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	unique := sorted[:0]
+	for i, id := range sorted {
+		if i == 0 || id != sorted[i-1] {
+			unique = append(unique, id)
+		}
+	}
+
+	return unique
+}
+
+// batchSender is a connection that can send a batch of queries.
+type batchSender interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// lockAll calls the advisory lock function on each of the IDs in a single batch.  If a session
+// lock fails, the locks already acquired are released, as they outlive the batch's implicit
+// transaction.
+func lockAll(ctx context.Context, conn batchSender, fn string, ids []uint64) error {
+	err := sendLocks(ctx, conn, fn, ids)
+	if err != nil && fn == "pg_advisory_lock" {
+		_ = sendLocks(context.Background(), conn, "pg_advisory_unlock", ids)
+	}
+
+	return err
+}
+
+// sendLocks calls the advisory lock function on each of the IDs in a single batch.
+func sendLocks(ctx context.Context, conn batchSender, fn string, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var batch pgx.Batch
+	for _, id := range ids {
+		batch.Queue("SELECT "+fn+"($1)", id)
+	}
+
+	return conn.SendBatch(ctx, &batch).Close()
 }
 
 // TxAdvisoryLock is a placeholder so the Lock/Release functionality is the same for the
@@ -103,6 +371,16 @@ func (lock *TxAdvisoryLock) Release() error {
 	return nil
 }
 
+// TxAdvisoryLocks is the placeholder for a set of transactional advisory locks acquired together.
+type TxAdvisoryLocks struct {
+	IDs []uint64
+}
+
+// Release does nothing on transactional advisory locks.
+func (locks *TxAdvisoryLocks) Release() error {
+	return nil
+}
+
 // Lock creates an transactional advisory lock in the database.  This lock will be released at the
 // end of the transaction, on either commit or rollback.  You may call AdvisoryLock.Release(), but
 // it does nothing on this type of advisory lock.
@@ -120,6 +398,24 @@ func (tx *Tx) Lock(ctx context.Context, id uint64) (AdvisoryLock, error) {
 	}, nil
 }
 
+// LockAll creates transactional advisory locks on all the IDs, sending the pg_advisory_xact_lock
+// calls in a single round trip rather than one per ID.  The locks are taken in ascending order,
+// so concurrent calls with overlapping IDs can't deadlock each other.  They're released at the
+// end of the transaction.
+func (tx *Tx) LockAll(ctx context.Context, ids ...uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ids = lockOrder(ids)
+
+	if err := lockAll(ctx, tx.Conn(), "pg_advisory_xact_lock", ids); err != nil {
+		return nil, err
+	}
+
+	return &TxAdvisoryLocks{IDs: ids}, nil
+}
+
 // TryLock creates an transactional advisory lock in the database.  You may manually call Release() on
 // the AdvisoryLock, or the lock will release automatically on commit or rollback.
 func (tx *Tx) TryLock(ctx context.Context, id uint64) (AdvisoryLock, error) {
@@ -141,3 +437,169 @@ func (tx *Tx) TryLock(ctx context.Context, id uint64) (AdvisoryLock, error) {
 		ID: id,
 	}, nil
 }
+
+// LockShared creates a transactional shared advisory lock in the database, released at the end
+// of the transaction.  See DB.LockShared.
+func (tx *Tx) LockShared(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, err := tx.Conn().Exec(ctx, "SELECT pg_advisory_xact_lock_shared($1)", id); err != nil {
+		return nil, err
+	}
+
+	return &TxAdvisoryLock{
+		ID: id,
+	}, nil
+}
+
+// TryLockShared tries to create a transactional shared advisory lock in the database.  If
+// successful, returns the advisory lock, released at the end of the transaction.  If another
+// session holds an exclusive lock on the ID, returns ErrLocked.
+func (tx *Tx) TryLockShared(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var available bool
+	row := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock_shared($1)", id)
+	if err := row.Scan(&available); err != nil {
+		return nil, err
+	}
+
+	if !available {
+		return nil, ErrLocked
+	}
+
+	return &TxAdvisoryLock{
+		ID: id,
+	}, nil
+}
+
+// LockKey hashes a string key, such as "invoices:1234", into the two 32-bit keys of Lock2 and
+// TryLock2, so locks can be named rather than numbered.  The same key always hashes to the same
+// pair.  Different keys may collide, if rarely, so namespace them, e.g. by table.
+func LockKey(key string) (class, id int32) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+
+	return int32(sum >> 32), int32(sum)
+}
+
+// SessionAdvisoryLock2 is a session-wide advisory lock on a pair of 32-bit keys, created with
+// DB.Lock2 or DB.TryLock2.
+type SessionAdvisoryLock2 struct {
+	sessionLock
+
+	Class int32
+	ID    int32
+}
+
+// Release the session-wide advisory lock, returning its connection to the pool.
+func (lock *SessionAdvisoryLock2) Release() error {
+	return lock.release(func(conn *pgx.Conn) error {
+		_, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1, $2)", lock.Class, lock.ID)
+		return err
+	})
+}
+
+// TxAdvisoryLock2 is the placeholder for a transactional advisory lock on a pair of 32-bit keys.
+type TxAdvisoryLock2 struct {
+	Class int32
+	ID    int32
+}
+
+// Release does nothing on a transactional advisory lock.
+func (lock *TxAdvisoryLock2) Release() error {
+	return nil
+}
+
+// Lock2 creates a session-wide advisory lock on the pair of keys, e.g. a table's class and a
+// row's ID, or the pair from LockKey.  Locks on a pair of keys never conflict with locks on a
+// single 64-bit ID.  Call Release() to release the advisory lock.
+func (db *DB) Lock2(ctx context.Context, class, id int32) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1, $2)", class, id); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	lock := &SessionAdvisoryLock2{Class: class, ID: id}
+	lock.hold(db, conn, true, 1)
+	db.guard(lock, &lock.sessionLock)
+
+	return lock, nil
+}
+
+// TryLock2 tries to create a session-wide advisory lock on the pair of keys.  If successful,
+// returns the advisory lock.  If not, returns ErrLocked.  See Lock2.
+func (db *DB) TryLock2(ctx context.Context, class, id int32) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var available bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1, $2)", class, id).Scan(&available); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	if !available {
+		conn.Release()
+		return nil, ErrLocked
+	}
+
+	lock := &SessionAdvisoryLock2{Class: class, ID: id}
+	lock.hold(db, conn, true, 1)
+	db.guard(lock, &lock.sessionLock)
+
+	return lock, nil
+}
+
+// Lock2 creates a transactional advisory lock on the pair of keys, released at the end of the
+// transaction.  See DB.Lock2.
+func (tx *Tx) Lock2(ctx context.Context, class, id int32) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, err := tx.Conn().Exec(ctx, "SELECT pg_advisory_xact_lock($1, $2)", class, id); err != nil {
+		return nil, err
+	}
+
+	return &TxAdvisoryLock2{Class: class, ID: id}, nil
+}
+
+// TryLock2 tries to create a transactional advisory lock on the pair of keys.  If successful,
+// returns the advisory lock, released at the end of the transaction.  If not, returns ErrLocked.
+func (tx *Tx) TryLock2(ctx context.Context, class, id int32) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var available bool
+	if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1, $2)", class, id).Scan(&available); err != nil {
+		return nil, err
+	}
+
+	if !available {
+		return nil, ErrLocked
+	}
+
+	return &TxAdvisoryLock2{Class: class, ID: id}, nil
+}
@@ -1,8 +1,10 @@
 package hermes_test
 
 import (
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/sbowman/hermes-pgx/v2"
 )
@@ -140,3 +142,264 @@ func TestTransactionalLock(t *testing.T) {
 	}
 	wg3.Wait()
 }
+
+func TestLockAll(t *testing.T) {
+	db := connect(t)
+
+	held := db.HeldLocks()
+
+	lock, err := db.LockAll(nil, 23, 21, 22, 21)
+	if err != nil {
+		t.Fatalf("Failed to acquire the locks: %s", err)
+	}
+
+	if locks := db.HeldLocks() - held; locks != 3 {
+		t.Errorf("Expected 3 locks held; was %d", locks)
+	}
+
+	if _, err := db.TryLock(nil, 22); err != hermes.ErrLocked {
+		t.Errorf("Expected lock 22 to be taken; was %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Failed to release the locks: %s", err)
+	}
+
+	if locks := db.HeldLocks() - held; locks != 0 {
+		t.Errorf("Expected the locks to be released; was %d held", locks)
+	}
+
+	other, err := db.TryLock(nil, 22)
+	if err != nil {
+		t.Fatalf("Expected lock 22 to be available: %s", err)
+	}
+
+	if err := other.Release(); err != nil {
+		t.Errorf("Problem releasing lock 22: %s", err)
+	}
+}
+
+func TestLock2(t *testing.T) {
+	db := connect(t)
+
+	class, id := hermes.LockKey("accounts:12")
+
+	lock, err := db.Lock2(nil, class, id)
+	if err != nil {
+		t.Fatalf("Failed to acquire the lock: %s", err)
+	}
+
+	if _, err := db.TryLock2(nil, class, id); err != hermes.ErrLocked {
+		t.Errorf("Expected the lock to be taken; was %v", err)
+	}
+
+	// The single-key lock space is separate
+	single, err := db.TryLock(nil, uint64(class)<<32|uint64(uint32(id)))
+	if err != nil {
+		t.Errorf("Expected the single-key lock to be available: %s", err)
+	} else if err := single.Release(); err != nil {
+		t.Errorf("Problem releasing the single-key lock: %s", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Failed to release the lock: %s", err)
+	}
+
+	other, err := db.TryLock2(nil, class, id)
+	if err != nil {
+		t.Fatalf("Expected the lock to be available: %s", err)
+	}
+
+	if err := other.Release(); err != nil {
+		t.Errorf("Problem releasing the lock: %s", err)
+	}
+}
+
+func TestLockKey(t *testing.T) {
+	class, id := hermes.LockKey("accounts:12")
+
+	if c, i := hermes.LockKey("accounts:12"); c != class || i != id {
+		t.Errorf("Expected the same key to hash the same; was (%d, %d) and (%d, %d)", class, id, c, i)
+	}
+
+	if c, i := hermes.LockKey("accounts:13"); c == class && i == id {
+		t.Error("Expected different keys to hash differently")
+	}
+}
+
+func TestLockManager(t *testing.T) {
+	db := connect(t)
+
+	leader := hermes.NewLockManager(db)
+	follower := hermes.NewLockManager(db)
+
+	lease, err := leader.Acquire(nil, 31)
+	if err != nil {
+		t.Fatalf("Failed to acquire the lease: %s", err)
+	}
+
+	if !lease.Held() {
+		t.Error("Expected the lease to be held")
+	}
+
+	if _, err := follower.TryAcquire(nil, 31); err != hermes.ErrLocked {
+		t.Errorf("Expected the lock to be taken; was %v", err)
+	}
+
+	follower.WaitTimeout = 100 * time.Millisecond
+	if _, err := follower.Acquire(nil, 31); !errors.Is(err, hermes.ErrLockTimeout) {
+		t.Errorf("Expected ErrLockTimeout; was %v", err)
+	}
+
+	leader.Heartbeat(nil)
+
+	if !lease.Held() {
+		t.Error("Expected the lease to be held after a heartbeat")
+	}
+
+	if err := lease.Release(); err != nil {
+		t.Fatalf("Failed to release the lease: %s", err)
+	}
+
+	<-lease.Done()
+
+	other, err := follower.TryAcquire(nil, 31)
+	if err != nil {
+		t.Fatalf("Expected the lock to be available: %s", err)
+	}
+
+	if err := follower.Close(); err != nil {
+		t.Errorf("Problem releasing the leases: %s", err)
+	}
+
+	if other.Held() {
+		t.Error("Expected the lease to be released")
+	}
+}
+
+func TestSharedLock(t *testing.T) {
+	db := connect(t)
+
+	const id uint64 = 41
+
+	reader, err := db.LockShared(nil, id)
+	if err != nil {
+		t.Fatalf("Failed to acquire the shared lock: %s", err)
+	}
+
+	other, err := db.TryLockShared(nil, id)
+	if err != nil {
+		t.Fatalf("Expected readers to share the lock: %s", err)
+	}
+
+	if _, err := db.TryLock(nil, id); err != hermes.ErrLocked {
+		t.Errorf("Expected the exclusive lock to wait on the readers; was %v", err)
+	}
+
+	if err := reader.Release(); err != nil {
+		t.Errorf("Problem releasing the shared lock: %s", err)
+	}
+
+	if err := other.Release(); err != nil {
+		t.Errorf("Problem releasing the shared lock: %s", err)
+	}
+
+	writer, err := db.TryLock(nil, id)
+	if err != nil {
+		t.Fatalf("Expected the exclusive lock once the readers released: %s", err)
+	}
+	defer writer.Release()
+
+	if _, err := db.TryLockShared(nil, id); err != hermes.ErrLocked {
+		t.Errorf("Expected the shared lock to wait on the writer; was %v", err)
+	}
+}
+
+func TestLockReleasesConnection(t *testing.T) {
+	db := connect(t)
+
+	const id uint64 = 42
+
+	lock, err := db.Lock(nil, id)
+	if err != nil {
+		t.Fatalf("Failed to acquire a lock: %s", err)
+	}
+
+	if acquired := db.Stat().AcquiredConns(); acquired != 1 {
+		t.Errorf("Expected the lock to hold 1 connection; was %d", acquired)
+	}
+
+	if _, err := db.TryLock(nil, id); err != hermes.ErrLocked {
+		t.Errorf("Expected ErrLocked; was %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Errorf("Failed to release the lock: %s", err)
+	}
+
+	if acquired := db.Stat().AcquiredConns(); acquired != 0 {
+		t.Errorf("Expected the connections returned to the pool; was %d acquired", acquired)
+	}
+
+	if held := db.HeldLocks(); held != 0 {
+		t.Errorf("Expected no locks held; was %d", held)
+	}
+}
+
+func TestMaxLockHold(t *testing.T) {
+	db := connect(t)
+
+	db.SetMaxLockHold(50 * time.Millisecond)
+
+	const id uint64 = 43
+
+	if _, err := db.Lock(nil, id); err != nil {
+		t.Fatalf("Failed to acquire a lock: %s", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	lock, err := db.TryLock(nil, id)
+	if err != nil {
+		t.Fatalf("Expected the forgotten lock to expire: %s", err)
+	}
+	defer lock.Release()
+
+	if acquired := db.Stat().AcquiredConns(); acquired != 1 {
+		t.Errorf("Expected the expired lock's connection returned to the pool; was %d acquired", acquired)
+	}
+}
+
+func TestPinnedLockClose(t *testing.T) {
+	db := connect(t)
+
+	const id uint64 = 44
+
+	conn, err := db.Pin(nil)
+	if err != nil {
+		t.Fatalf("Unable to pin a connection: %s", err)
+	}
+
+	lock, err := conn.Lock(nil, id)
+	if err != nil {
+		t.Fatalf("Failed to acquire a lock: %s", err)
+	}
+
+	if err := conn.Close(nil); err != nil {
+		t.Fatalf("Unable to close the pinned connection: %s", err)
+	}
+
+	if held := db.HeldLocks(); held != 0 {
+		t.Errorf("Expected no locks held after closing; was %d", held)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Errorf("Expected releasing after closing to do nothing; was %s", err)
+	}
+
+	other, err := db.TryLock(nil, id)
+	if err != nil {
+		t.Fatalf("Expected the lock to be released when the connection closed: %s", err)
+	}
+	defer other.Release()
+}
@@ -0,0 +1,149 @@
+package hermes
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ltreeVersion prefixes ltree and lquery values in the binary format.
+const ltreeVersion = 1
+
+// Ltree is a path in the ltree extension's hierarchical label tree, e.g. "top.science.astronomy".
+// Ltrees are registered automatically when the extension is installed, along with Lquery, so
+// paths and queries may be passed as arguments and scanned from results:
+//
+//	rows, err := conn.Query(ctx, "select path from categories where path ~ $1",
+//		hermes.Lquery("top.*.astronomy"))
+type Ltree string
+
+// NewLtree returns the path made up of the labels.
+func NewLtree(labels ...string) Ltree {
+	return Ltree(strings.Join(labels, "."))
+}
+
+// Labels returns the labels in the path, from the root down.  Returns nil for an empty path.
+func (t Ltree) Labels() []string {
+	if t == "" {
+		return nil
+	}
+	return strings.Split(string(t), ".")
+}
+
+// Level returns the number of labels in the path, like the nlevel function.
+func (t Ltree) Level() int {
+	if t == "" {
+		return 0
+	}
+	return strings.Count(string(t), ".") + 1
+}
+
+// Leaf returns the last label in the path.
+func (t Ltree) Leaf() string {
+	return string(t[strings.LastIndex(string(t), ".")+1:])
+}
+
+// Parent returns the path without its last label.  The parent of a single label is the empty
+// path.
+func (t Ltree) Parent() Ltree {
+	if idx := strings.LastIndex(string(t), "."); idx >= 0 {
+		return t[:idx]
+	}
+	return ""
+}
+
+// Child returns the path with the labels appended.
+func (t Ltree) Child(labels ...string) Ltree {
+	if t == "" {
+		return NewLtree(labels...)
+	}
+	return Ltree(string(t) + "." + strings.Join(labels, "."))
+}
+
+// IsAncestorOf returns true if the path is an ancestor of other, or the same path, like the @>
+// operator.
+func (t Ltree) IsAncestorOf(other Ltree) bool {
+	return t == "" || t == other || strings.HasPrefix(string(other), string(t)+".")
+}
+
+// Lquery is an ltree extension pattern for matching paths, e.g. "*.astronomy.*", used with the ~
+// operator.
+type Lquery string
+
+// ltreeCodec encodes and decodes ltree and lquery values.  They're text with a version byte in
+// front in the binary format.  The text format is preferred, since the binary format requires
+// PostgreSQL 13 or later.
+type ltreeCodec struct {
+	pgtype.TextCodec
+}
+
+func (ltreeCodec) PreferredFormat() int16 {
+	return pgtype.TextFormatCode
+}
+
+func (c ltreeCodec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value interface{}) pgtype.EncodePlan {
+	if format == pgtype.BinaryFormatCode {
+		if next := m.PlanEncode(oid, pgtype.TextFormatCode, value); next != nil {
+			return &ltreeEncodePlan{next: next}
+		}
+		return nil
+	}
+
+	return c.TextCodec.PlanEncode(m, oid, format, value)
+}
+
+func (c ltreeCodec) PlanScan(m *pgtype.Map, oid uint32, format int16, target interface{}) pgtype.ScanPlan {
+	if format == pgtype.BinaryFormatCode {
+		return &ltreeScanPlan{next: m.PlanScan(oid, pgtype.TextFormatCode, target)}
+	}
+
+	return c.TextCodec.PlanScan(m, oid, format, target)
+}
+
+func (c ltreeCodec) DecodeValue(m *pgtype.Map, oid uint32, format int16, src []byte) (interface{}, error) {
+	if format == pgtype.BinaryFormatCode && src != nil {
+		text, err := ltreeText(src)
+		if err != nil {
+			return nil, err
+		}
+		return c.TextCodec.DecodeValue(m, oid, pgtype.TextFormatCode, text)
+	}
+
+	return c.TextCodec.DecodeValue(m, oid, format, src)
+}
+
+type ltreeEncodePlan struct {
+	next pgtype.EncodePlan
+}
+
+func (plan *ltreeEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	// A nil result, i.e. NULL, drops the version
+	return plan.next.Encode(value, append(buf, ltreeVersion))
+}
+
+type ltreeScanPlan struct {
+	next pgtype.ScanPlan
+}
+
+func (plan *ltreeScanPlan) Scan(src []byte, target interface{}) error {
+	if src == nil {
+		return plan.next.Scan(nil, target)
+	}
+
+	text, err := ltreeText(src)
+	if err != nil {
+		return err
+	}
+
+	return plan.next.Scan(text, target)
+}
+
+// ltreeText strips the version from an ltree or lquery in the binary format.
+func ltreeText(src []byte) ([]byte, error) {
+	if len(src) == 0 || src[0] != ltreeVersion {
+		return nil, errors.New("unsupported ltree binary format")
+	}
+
+	return src[1:], nil
+}
@@ -0,0 +1,114 @@
+package hermes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestLtree(t *testing.T) {
+	path := NewLtree("top", "science", "astronomy")
+
+	if path != "top.science.astronomy" {
+		t.Errorf("Expected top.science.astronomy; was %s", path)
+	}
+
+	if labels := path.Labels(); !reflect.DeepEqual(labels, []string{"top", "science", "astronomy"}) {
+		t.Errorf("Expected [top science astronomy]; was %v", labels)
+	}
+
+	if path.Level() != 3 || Ltree("").Level() != 0 {
+		t.Errorf("Expected levels 3 and 0; was %d and %d", path.Level(), Ltree("").Level())
+	}
+
+	if path.Leaf() != "astronomy" || Ltree("top").Leaf() != "top" {
+		t.Errorf("Expected leaves astronomy and top; was %s and %s", path.Leaf(), Ltree("top").Leaf())
+	}
+
+	if path.Parent() != "top.science" || Ltree("top").Parent() != "" {
+		t.Errorf("Expected parents top.science and empty; was %s and %s", path.Parent(), Ltree("top").Parent())
+	}
+
+	if child := path.Child("stars", "novae"); child != "top.science.astronomy.stars.novae" {
+		t.Errorf("Expected top.science.astronomy.stars.novae; was %s", child)
+	}
+
+	if child := Ltree("").Child("top"); child != "top" {
+		t.Errorf("Expected top; was %s", child)
+	}
+
+	for ancestor, expected := range map[Ltree]bool{
+		"":                            true,
+		"top":                         true,
+		"top.science":                 true,
+		"top.science.astronomy":       true,
+		"top.sci":                     false,
+		"top.science.astronomy.stars": false,
+	} {
+		if check := ancestor.IsAncestorOf(path); check != expected {
+			t.Errorf("Expected %q ancestor of %s to be %t", ancestor, path, expected)
+		}
+	}
+}
+
+func TestLtreeCodec(t *testing.T) {
+	m := pgtype.NewMap()
+	for i, dt := range extensionTypes[1:3] {
+		oid := uint32(100001 + 2*i)
+		registerType(m, dt, dt.codec, oid, oid+1)
+	}
+
+	path := NewLtree("top", "science")
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(100001, format, path, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode ltree in format %d: %s", format, err)
+		}
+
+		if format == pgtype.BinaryFormatCode && (len(buf) == 0 || buf[0] != ltreeVersion) {
+			t.Errorf("Expected binary ltree to start with the version; was %v", buf)
+		}
+
+		var check Ltree
+		if err := m.Scan(100001, format, buf, &check); err != nil {
+			t.Fatalf("Unable to scan ltree in format %d: %s", format, err)
+		}
+
+		if check != path {
+			t.Errorf("Expected %s; was %s", path, check)
+		}
+
+		var s string
+		if err := m.Scan(100001, format, buf, &s); err != nil {
+			t.Fatalf("Unable to scan ltree into string in format %d: %s", format, err)
+		}
+
+		if s != string(path) {
+			t.Errorf("Expected %s; was %s", path, s)
+		}
+	}
+
+	if dt, ok := m.TypeForValue(Lquery("")); !ok || dt.OID != 100003 {
+		t.Error("Expected Lquery to map to the lquery type")
+	}
+
+	if dt, ok := m.TypeForValue([]Ltree{}); !ok || dt.OID != 100002 {
+		t.Error("Expected []Ltree to map to the ltree array type")
+	}
+
+	var paths []Ltree
+	if err := m.Scan(100002, pgtype.TextFormatCode, []byte("{top.a,top.b}"), &paths); err != nil {
+		t.Fatalf("Unable to scan ltree array: %s", err)
+	}
+
+	if len(paths) != 2 || paths[1] != "top.b" {
+		t.Errorf("Expected [top.a top.b]; was %v", paths)
+	}
+
+	var check Ltree
+	if err := m.Scan(100001, pgtype.BinaryFormatCode, []byte{2, 'a'}, &check); err == nil {
+		t.Error("Expected error scanning an unknown ltree version")
+	}
+}
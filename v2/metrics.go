@@ -0,0 +1,287 @@
+package hermes
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Metrics receives the timings of the statements, commits, and connection acquisitions of a DB,
+// and the DB's Stats.  Set it with DB.SetMetrics.  The methods are called from the goroutines
+// running the queries, so must be safe for concurrent use, and should return quickly.
+type Metrics interface {
+	// ObserveQuery is called as each statement run through the DB or its transactions finishes,
+	// i.e. Exec, Query, QueryRow, and each statement in a SendBatch.  For Query, the duration
+	// includes reading the rows, up to when they're closed; for QueryRow, up to when the row is
+	// scanned.  The error is nil if the statement succeeded.
+	ObserveQuery(sql string, duration time.Duration, err error)
+
+	// ObserveTxCommit is called after a transaction commits, or fails to, with how long the
+	// commit took.  Pseudo nested transactions (savepoints) aren't observed.
+	ObserveTxCommit(duration time.Duration, err error)
+
+	// ObservePoolAcquire is called with how long a statement or transaction waited for a
+	// connection from the pool, including any wait for a slot if the DB's concurrency is
+	// limited.
+	ObservePoolAcquire(duration time.Duration)
+
+	// ObserveReconnect is called before a statement is retried after a disconnect, with the
+	// number of attempts so far and the error.  See DB.SetReconnectPolicy.
+	ObserveReconnect(sql string, attempts int, err error)
+
+	// ObserveStats is called with a snapshot of the DB's connection pool and counters every
+	// interval while DB.PublishStats runs.
+	ObserveStats(stats Stats)
+}
+
+// NoMetrics discards the observations.  It's the default for a DB.
+type NoMetrics struct{}
+
+// ObserveQuery does nothing.
+func (NoMetrics) ObserveQuery(string, time.Duration, error) {}
+
+// ObserveTxCommit does nothing.
+func (NoMetrics) ObserveTxCommit(time.Duration, error) {}
+
+// ObservePoolAcquire does nothing.
+func (NoMetrics) ObservePoolAcquire(time.Duration) {}
+
+// ObserveReconnect does nothing.
+func (NoMetrics) ObserveReconnect(string, int, error) {}
+
+// ObserveStats does nothing.
+func (NoMetrics) ObserveStats(Stats) {}
+
+// SetMetrics sends the DB's timings to m.  Pass nil to stop observing.  Statements are timed
+// through the connections' tracer and connection waits through the pool's BeforeAcquire hook,
+// so only transaction commits are observed for a DB that wasn't created by Connect or
+// ConnectConfig.
+func (db *DB) SetMetrics(m Metrics) {
+	if db.observer == nil {
+		db.observer = &metricsHook{}
+	}
+
+	db.observer.set(m)
+}
+
+// metrics returns the DB's Metrics, or NoMetrics if none are set.
+func (db *DB) metrics() Metrics {
+	if db == nil || db.observer == nil {
+		return NoMetrics{}
+	}
+
+	return db.observer.get()
+}
+
+// timeAcquire notes when the statement or transaction started waiting for a connection, so the
+// wait may be observed once the pool hands over the connection.
+func (db *DB) timeAcquire(ctx context.Context) context.Context {
+	if db.observer == nil || !db.observer.enabled() {
+		return ctx
+	}
+
+	return context.WithValue(ctx, acquireKey{}, time.Now())
+}
+
+// metricsHook times the statements run on a pool's connections and the waits for those
+// connections, reporting them to the DB's Metrics and query logger.  It's installed as the
+// connections' tracer, passing trace calls along to the configured tracer.
+type metricsHook struct {
+	next    pgx.QueryTracer
+	metrics atomic.Value
+	logger  atomic.Value
+}
+
+// metricsBox holds the Metrics in the atomic.Value, which requires a consistent concrete type.
+type metricsBox struct {
+	Metrics
+}
+
+type metricsKey struct{}
+
+type acquireKey struct{}
+
+// queryTiming tracks when a statement started.
+type queryTiming struct {
+	sql     string
+	args    []interface{}
+	started time.Time
+}
+
+// batchTiming tracks when the previous statement of a batch finished, to time the next one.
+type batchTiming struct {
+	last time.Time
+}
+
+func newMetricsHook(config *pgxpool.Config) *metricsHook {
+	return &metricsHook{next: config.ConnConfig.Tracer}
+}
+
+// install the metrics hooks in the pool configuration.
+func (h *metricsHook) install(config *pgxpool.Config) {
+	config.ConnConfig.Tracer = h
+	config.BeforeAcquire = h.beforeAcquire(config.BeforeAcquire)
+}
+
+// set the Metrics to report to.  Nil turns off reporting.
+func (h *metricsHook) set(m Metrics) {
+	if _, ok := m.(NoMetrics); ok {
+		m = nil
+	}
+
+	h.metrics.Store(metricsBox{m})
+}
+
+// get the Metrics to report to, or NoMetrics if there are none.
+func (h *metricsHook) get() Metrics {
+	if box, ok := h.metrics.Load().(metricsBox); ok && box.Metrics != nil {
+		return box.Metrics
+	}
+
+	return NoMetrics{}
+}
+
+// enabled returns true if there are Metrics to report to.
+func (h *metricsHook) enabled() bool {
+	box, ok := h.metrics.Load().(metricsBox)
+	return ok && box.Metrics != nil
+}
+
+// timed returns true if the statements are being timed, for the Metrics or the query logger.
+func (h *metricsHook) timed() bool {
+	return h.enabled() || h.queryLogger() != nil
+}
+
+// finished reports the statement to the Metrics and query logger.
+func (h *metricsHook) finished(ctx context.Context, sql string, args []interface{}, duration time.Duration, err error) {
+	h.get().ObserveQuery(sql, duration, err)
+
+	if logger := h.queryLogger(); logger != nil {
+		logger.log(ctx, sql, args, duration, err)
+	}
+}
+
+// beforeAcquire observes the wait for a connection once the pool hands one over, if the wait was
+// timed with DB.timeAcquire, before calling the next function.
+func (h *metricsHook) beforeAcquire(next func(context.Context, *pgx.Conn) bool) func(context.Context, *pgx.Conn) bool {
+	return func(ctx context.Context, conn *pgx.Conn) bool {
+		if next != nil && !next(ctx, conn) {
+			return false
+		}
+
+		if started, ok := ctx.Value(acquireKey{}).(time.Time); ok {
+			h.get().ObservePoolAcquire(time.Since(started))
+		}
+
+		return true
+	}
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (h *metricsHook) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if h.timed() {
+		ctx = context.WithValue(ctx, metricsKey{}, &queryTiming{sql: data.SQL, args: data.Args, started: time.Now()})
+	}
+
+	if h.next != nil {
+		return h.next.TraceQueryStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (h *metricsHook) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if timing, ok := ctx.Value(metricsKey{}).(*queryTiming); ok {
+		h.finished(ctx, timing.sql, timing.args, time.Since(timing.started), data.Err)
+	}
+
+	if h.next != nil {
+		h.next.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+// TracePrepareStart implements pgx.PrepareTracer.
+func (h *metricsHook) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	if next, ok := h.next.(pgx.PrepareTracer); ok {
+		return next.TracePrepareStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+// TracePrepareEnd implements pgx.PrepareTracer.
+func (h *metricsHook) TracePrepareEnd(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareEndData) {
+	if next, ok := h.next.(pgx.PrepareTracer); ok {
+		next.TracePrepareEnd(ctx, conn, data)
+	}
+}
+
+// TraceBatchStart implements pgx.BatchTracer.
+func (h *metricsHook) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if h.timed() {
+		ctx = context.WithValue(ctx, metricsKey{}, &batchTiming{last: time.Now()})
+	}
+
+	if next, ok := h.next.(pgx.BatchTracer); ok {
+		return next.TraceBatchStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+// TraceBatchQuery implements pgx.BatchTracer.  Each statement is timed from when the previous
+// one finished, or the batch was sent.
+func (h *metricsHook) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if timing, ok := ctx.Value(metricsKey{}).(*batchTiming); ok {
+		now := time.Now()
+		h.finished(ctx, data.SQL, data.Args, now.Sub(timing.last), data.Err)
+		timing.last = now
+	}
+
+	if next, ok := h.next.(pgx.BatchTracer); ok {
+		next.TraceBatchQuery(ctx, conn, data)
+	}
+}
+
+// TraceBatchEnd implements pgx.BatchTracer.
+func (h *metricsHook) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	if next, ok := h.next.(pgx.BatchTracer); ok {
+		next.TraceBatchEnd(ctx, conn, data)
+	}
+}
+
+// TraceCopyFromStart implements pgx.CopyFromTracer.
+func (h *metricsHook) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	if next, ok := h.next.(pgx.CopyFromTracer); ok {
+		return next.TraceCopyFromStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+// TraceCopyFromEnd implements pgx.CopyFromTracer.
+func (h *metricsHook) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	if next, ok := h.next.(pgx.CopyFromTracer); ok {
+		next.TraceCopyFromEnd(ctx, conn, data)
+	}
+}
+
+// TraceConnectStart implements pgx.ConnectTracer.
+func (h *metricsHook) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	if next, ok := h.next.(pgx.ConnectTracer); ok {
+		return next.TraceConnectStart(ctx, data)
+	}
+
+	return ctx
+}
+
+// TraceConnectEnd implements pgx.ConnectTracer.
+func (h *metricsHook) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	if next, ok := h.next.(pgx.ConnectTracer); ok {
+		next.TraceConnectEnd(ctx, data)
+	}
+}
@@ -0,0 +1,163 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// recordedMetrics records the observations.
+type recordedMetrics struct {
+	mutex      sync.Mutex
+	queries    []string
+	errors     []error
+	commits    int
+	acquires   int
+	reconnects int
+	stats      []Stats
+}
+
+func (m *recordedMetrics) ObserveQuery(sql string, _ time.Duration, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.queries = append(m.queries, sql)
+	m.errors = append(m.errors, err)
+}
+
+func (m *recordedMetrics) ObserveTxCommit(time.Duration, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.commits++
+}
+
+func (m *recordedMetrics) ObservePoolAcquire(time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.acquires++
+}
+
+func (m *recordedMetrics) ObserveReconnect(string, int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.reconnects++
+}
+
+func (m *recordedMetrics) ObserveStats(stats Stats) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stats = append(m.stats, stats)
+}
+
+func TestMetricsHookQueries(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://localhost/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook := newMetricsHook(config)
+	ctx := context.Background()
+
+	// Nothing is timed until there are metrics
+	if traced := hook.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "select 1"}); traced != ctx {
+		t.Error("Expected the context to be left alone without metrics")
+	}
+
+	m := &recordedMetrics{}
+	hook.set(m)
+
+	traced := hook.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "select 1"})
+	hook.TraceQueryEnd(traced, nil, pgx.TraceQueryEndData{})
+
+	failed := errors.New("failed")
+
+	traced = hook.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{})
+	hook.TraceBatchQuery(traced, nil, pgx.TraceBatchQueryData{SQL: "insert 1"})
+	hook.TraceBatchQuery(traced, nil, pgx.TraceBatchQueryData{SQL: "insert 2", Err: failed})
+	hook.TraceBatchEnd(traced, nil, pgx.TraceBatchEndData{})
+
+	expected := []string{"select 1", "insert 1", "insert 2"}
+	if len(m.queries) != len(expected) {
+		t.Fatalf("Expected %d queries; was %d", len(expected), len(m.queries))
+	}
+
+	for i, sql := range expected {
+		if m.queries[i] != sql {
+			t.Errorf("Expected query %d to be %q; was %q", i, sql, m.queries[i])
+		}
+	}
+
+	if m.errors[0] != nil || m.errors[2] != failed {
+		t.Errorf("Expected the statements' errors; was %v", m.errors)
+	}
+
+	hook.set(nil)
+
+	if _, ok := hook.get().(NoMetrics); !ok {
+		t.Errorf("Expected NoMetrics after clearing the metrics; was %T", hook.get())
+	}
+}
+
+func TestMetricsHookAcquire(t *testing.T) {
+	m := &recordedMetrics{}
+	db := &DB{}
+	db.SetMetrics(m)
+
+	rejected := false
+	before := db.observer.beforeAcquire(func(context.Context, *pgx.Conn) bool {
+		return !rejected
+	})
+
+	// Only waits timed by the DB are observed
+	before(context.Background(), nil)
+
+	ctx := db.timeAcquire(context.Background())
+	before(ctx, nil)
+
+	rejected = true
+	before(ctx, nil)
+
+	if m.acquires != 1 {
+		t.Errorf("Expected 1 acquire; was %d", m.acquires)
+	}
+}
+
+func TestMetricsCommit(t *testing.T) {
+	m := &recordedMetrics{}
+	db := &DB{}
+	db.SetMetrics(m)
+
+	ctx := context.Background()
+
+	tx := &Tx{Tx: &fakeTx{}, db: db}
+
+	nested, err := tx.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nested.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.commits != 0 {
+		t.Errorf("Expected savepoints not to be observed; was %d commits", m.commits)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.commits != 1 {
+		t.Errorf("Expected 1 commit; was %d", m.commits)
+	}
+}
@@ -0,0 +1,308 @@
+// Package migrate applies versioned SQL migrations to a PostgreSQL database over a hermes.Conn.
+// Migrations are .sql files, typically embedded with embed.FS, named with a version number and a
+// description:
+//
+//	migrations/0001_create_users.sql
+//	migrations/0002_add_users_email_index.sql
+//
+// Each migration runs in its own transaction and is recorded in a schema_migrations table, so
+// only pending migrations run on the next deploy.  An advisory lock serializes concurrent
+// deployers:  the second waits for the first to finish, then finds nothing left to do.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// DefaultTable is the table recording the applied migrations, if Migrator.Table isn't set.
+const DefaultTable = "schema_migrations"
+
+// NoTransaction is the directive that runs a migration outside a transaction, for statements
+// PostgreSQL won't run in one, such as CREATE INDEX CONCURRENTLY.  It must be the migration's
+// first line.
+const NoTransaction = "-- migrate:no-transaction"
+
+var (
+	// ErrInvalidName is returned when a migration's file name doesn't start with a version
+	// number.
+	ErrInvalidName = errors.New("migrate: migration name must start with a version number")
+
+	// ErrDuplicateVersion is returned when two migrations share a version number.
+	ErrDuplicateVersion = errors.New("migrate: duplicate migration version")
+
+	// ErrTransactionRequired is returned when a migration marked NoTransaction is run on a
+	// transaction.
+	ErrTransactionRequired = errors.New("migrate: migration can't run in a transaction")
+)
+
+// Migration is a single versioned SQL migration.
+type Migration struct {
+	// Version orders the migrations, and identifies them in the migrations table.
+	Version int64
+
+	// Name is the migration's file name, without the .sql extension.
+	Name string
+
+	// SQL is the migration's statements.
+	SQL string
+
+	// NoTransaction is set if the migration starts with the NoTransaction directive.
+	NoTransaction bool
+}
+
+// Migrator applies migrations.  Configure its fields before calling Up.
+type Migrator struct {
+	// Table records the applied migrations.  May be schema qualified.  Defaults to
+	// DefaultTable.
+	Table string
+
+	// LockID is the advisory lock held while migrating.  Defaults to an ID derived from the
+	// table name, so migrators sharing a table share a lock.
+	LockID uint64
+
+	// OnApply is called after each migration is applied, for logging.
+	OnApply func(m Migration, elapsed time.Duration)
+
+	migrations []Migration
+}
+
+// New loads the .sql files in dir of fsys as migrations, ordered by version.  Use "." for the
+// root of fsys.
+func New(fsys fs.FS, dir string) (*Migrator, error) {
+	migrations, err := Load(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{migrations: migrations}, nil
+}
+
+// Load reads the .sql files in dir of fsys as migrations, ordered by version.  Each file name
+// must start with the version number, followed by an underscore or the extension, e.g.
+// 0001_create_users.sql.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+
+		version, err := parseVersion(name)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		sql := string(content)
+
+		migrations = append(migrations, Migration{
+			Version:       version,
+			Name:          name,
+			SQL:           sql,
+			NoTransaction: strings.HasPrefix(strings.TrimSpace(sql), NoTransaction),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("%w: %s and %s", ErrDuplicateVersion, migrations[i-1].Name, migrations[i].Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// parseVersion returns the version number at the start of the migration name.
+func parseVersion(name string) (int64, error) {
+	digits := name
+	if i := strings.IndexByte(name, '_'); i >= 0 {
+		digits = name[:i]
+	}
+
+	version, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidName, name)
+	}
+
+	return version, nil
+}
+
+// Migrations returns the loaded migrations, ordered by version.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Pending returns the migrations that haven't been applied to the database, ordered by version.
+func (m *Migrator) Pending(ctx context.Context, conn hermes.Conn) ([]Migration, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := m.createTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	return m.pending(ctx, conn)
+}
+
+// Up applies the pending migrations in version order, returning the number applied.  Each
+// migration runs in its own transaction, along with the row recording it, so a failed migration
+// leaves nothing behind; the ones before it stay applied.  Migrations marked NoTransaction run
+// outside a transaction, and are recorded once they succeed.
+//
+// Up holds an advisory lock while it runs, so concurrent deployers apply each migration once.  On
+// a *hermes.DB the lock is session-wide.  On a transaction, it's a transactional lock, and the
+// migrations run in savepoints; they're only applied if the transaction commits, and
+// NoTransaction migrations fail with ErrTransactionRequired.
+func (m *Migrator) Up(ctx context.Context, conn hermes.Conn) (int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	lock, err := conn.Lock(ctx, m.lockID())
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Release()
+
+	if err := m.createTable(ctx, conn); err != nil {
+		return 0, err
+	}
+
+	pending, err := m.pending(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	_, inTx := conn.(*hermes.Tx)
+
+	for i, migration := range pending {
+		start := time.Now()
+
+		if migration.NoTransaction {
+			if inTx {
+				return i, fmt.Errorf("%w: %s", ErrTransactionRequired, migration.Name)
+			}
+			err = m.apply(ctx, conn, migration)
+		} else {
+			err = m.applyInTx(ctx, conn, migration)
+		}
+
+		if err != nil {
+			return i, fmt.Errorf("migrate: %s: %w", migration.Name, err)
+		}
+
+		if m.OnApply != nil {
+			m.OnApply(migration, time.Since(start))
+		}
+	}
+
+	return len(pending), nil
+}
+
+// applyInTx runs the migration and records it in a transaction.
+func (m *Migrator) applyInTx(ctx context.Context, conn hermes.Conn, migration Migration) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Close(ctx)
+
+	if err := m.apply(ctx, tx, migration); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// apply runs the migration's statements and records it as applied.  The statements are sent
+// without arguments, so pgx uses the simple protocol and a migration may hold several of them.
+func (m *Migrator) apply(ctx context.Context, conn hermes.Conn, migration Migration) error {
+	if _, err := conn.Exec(ctx, migration.SQL); err != nil {
+		return err
+	}
+
+	_, err := conn.Exec(ctx, "INSERT INTO "+m.table()+" (version, name) VALUES ($1, $2)",
+		migration.Version, migration.Name)
+	return err
+}
+
+// pending returns the migrations missing from the migrations table.
+func (m *Migrator) pending(ctx context.Context, conn hermes.Conn) ([]Migration, error) {
+	versions, err := hermes.SelectValues[int64](ctx, conn, "SELECT version FROM "+m.table())
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// createTable creates the migrations table if it doesn't exist.
+func (m *Migrator) createTable(ctx context.Context, conn hermes.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+m.table()+` (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+// table returns the quoted name of the migrations table.
+func (m *Migrator) table() string {
+	table := m.Table
+	if table == "" {
+		table = DefaultTable
+	}
+
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
+
+// lockID returns the advisory lock ID, derived from the table name if LockID isn't set.
+func (m *Migrator) lockID() uint64 {
+	if m.LockID != 0 {
+		return m.LockID
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("hermes.migrate:" + m.table()))
+
+	// Advisory lock IDs are bigints
+	return h.Sum64() >> 1
+}
@@ -0,0 +1,229 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+var migrations = fstest.MapFS{
+	"migrations/0002_add_email_index.sql": {Data: []byte(NoTransaction + "\nCREATE INDEX CONCURRENTLY users_email ON users (email);")},
+	"migrations/0001_create_users.sql":    {Data: []byte("CREATE TABLE users (id bigint PRIMARY KEY, email text);")},
+	"migrations/0003.sql":                 {Data: []byte("ALTER TABLE users ADD name text;")},
+	"migrations/README.md":                {Data: []byte("Not a migration")},
+}
+
+// migrateConn records the statements run by the Migrator, reporting the given versions as
+// already applied.
+type migrateConn struct {
+	hermes.Conn
+
+	applied    []int64
+	statements []string
+	locked     bool
+	fail       string
+}
+
+func (c *migrateConn) Begin(context.Context) (hermes.Conn, error) {
+	c.statements = append(c.statements, "BEGIN")
+	return c, nil
+}
+
+func (c *migrateConn) Commit(context.Context) error {
+	c.statements = append(c.statements, "COMMIT")
+	return nil
+}
+
+func (c *migrateConn) Close(context.Context) error {
+	return nil
+}
+
+func (c *migrateConn) Lock(context.Context, uint64) (hermes.AdvisoryLock, error) {
+	c.locked = true
+	return &hermes.TxAdvisoryLock{}, nil
+}
+
+func (c *migrateConn) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if !c.locked {
+		return pgconn.CommandTag{}, errors.New("expected the lock to be held")
+	}
+
+	if c.fail != "" && strings.Contains(sql, c.fail) {
+		return pgconn.CommandTag{}, errors.New("syntax error")
+	}
+
+	switch {
+	case strings.HasPrefix(sql, "CREATE TABLE IF NOT EXISTS"):
+	case strings.HasPrefix(sql, "INSERT INTO"):
+		c.statements = append(c.statements, "RECORD "+args[1].(string))
+	default:
+		c.statements = append(c.statements, sql)
+	}
+
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *migrateConn) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return &versionRows{versions: c.applied}, nil
+}
+
+// versionRows returns the applied migration versions.
+type versionRows struct {
+	pgx.Rows
+
+	versions []int64
+	row      int
+}
+
+func (r *versionRows) Close()     {}
+func (r *versionRows) Err() error { return nil }
+
+func (r *versionRows) FieldDescriptions() []pgconn.FieldDescription {
+	return []pgconn.FieldDescription{{Name: "version"}}
+}
+
+func (r *versionRows) Next() bool {
+	r.row++
+	return r.row <= len(r.versions)
+}
+
+func (r *versionRows) Scan(dest ...interface{}) error {
+	*dest[0].(*int64) = r.versions[r.row-1]
+	return nil
+}
+
+func TestLoad(t *testing.T) {
+	loaded, err := Load(migrations, "migrations")
+	if err != nil {
+		t.Fatalf("Unable to load migrations: %s", err)
+	}
+
+	var names []string
+	for _, m := range loaded {
+		names = append(names, m.Name)
+	}
+
+	expected := []string{"0001_create_users", "0002_add_email_index", "0003"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v; was %v", expected, names)
+	}
+
+	if loaded[0].NoTransaction || !loaded[1].NoTransaction {
+		t.Errorf("Expected only the index migration outside a transaction; was %#v", loaded)
+	}
+
+	if loaded[2].Version != 3 {
+		t.Errorf("Expected version 3; was %d", loaded[2].Version)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	duplicate := fstest.MapFS{
+		"1_users.sql":    {Data: []byte("SELECT 1")},
+		"0001_users.sql": {Data: []byte("SELECT 1")},
+	}
+
+	if _, err := Load(duplicate, "."); !errors.Is(err, ErrDuplicateVersion) {
+		t.Errorf("Expected ErrDuplicateVersion; was %v", err)
+	}
+
+	unversioned := fstest.MapFS{
+		"create_users.sql": {Data: []byte("SELECT 1")},
+	}
+
+	if _, err := Load(unversioned, "."); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Expected ErrInvalidName; was %v", err)
+	}
+}
+
+func TestUp(t *testing.T) {
+	m, err := New(migrations, "migrations")
+	if err != nil {
+		t.Fatalf("Unable to load migrations: %s", err)
+	}
+
+	var logged []string
+	m.OnApply = func(migration Migration, _ time.Duration) {
+		logged = append(logged, migration.Name)
+	}
+
+	conn := &migrateConn{applied: []int64{1}}
+
+	applied, err := m.Up(context.Background(), conn)
+	if err != nil {
+		t.Fatalf("Unable to migrate: %s", err)
+	}
+
+	if applied != 2 {
+		t.Errorf("Expected 2 migrations applied; was %d", applied)
+	}
+
+	expected := []string{
+		NoTransaction + "\nCREATE INDEX CONCURRENTLY users_email ON users (email);",
+		"RECORD 0002_add_email_index",
+		"BEGIN",
+		"ALTER TABLE users ADD name text;",
+		"RECORD 0003",
+		"COMMIT",
+	}
+
+	if !reflect.DeepEqual(conn.statements, expected) {
+		t.Errorf("Expected %q; was %q", expected, conn.statements)
+	}
+
+	if !reflect.DeepEqual(logged, []string{"0002_add_email_index", "0003"}) {
+		t.Errorf("Expected both migrations logged; was %v", logged)
+	}
+}
+
+func TestUpFailure(t *testing.T) {
+	m, err := New(migrations, "migrations")
+	if err != nil {
+		t.Fatalf("Unable to load migrations: %s", err)
+	}
+
+	conn := &migrateConn{applied: []int64{1}, fail: "ALTER TABLE"}
+
+	applied, err := m.Up(context.Background(), conn)
+	if err == nil || !strings.Contains(err.Error(), "0003") {
+		t.Errorf("Expected the third migration to fail; was %v", err)
+	}
+
+	if applied != 1 {
+		t.Errorf("Expected 1 migration applied; was %d", applied)
+	}
+
+	expected := []string{
+		NoTransaction + "\nCREATE INDEX CONCURRENTLY users_email ON users (email);",
+		"RECORD 0002_add_email_index",
+		"BEGIN",
+	}
+
+	if !reflect.DeepEqual(conn.statements, expected) {
+		t.Errorf("Expected the failed migration not to be recorded or committed; was %q", conn.statements)
+	}
+}
+
+func TestTable(t *testing.T) {
+	m := &Migrator{Table: "app.migrations"}
+
+	if table := m.table(); table != `"app"."migrations"` {
+		t.Errorf(`Expected "app"."migrations"; was %s`, table)
+	}
+
+	if m.lockID() == (&Migrator{}).lockID() {
+		t.Error("Expected migrators with different tables to use different locks")
+	}
+
+	if id := m.lockID(); int64(id) < 0 {
+		t.Errorf("Expected the lock ID to fit in a bigint; was %d", id)
+	}
+}
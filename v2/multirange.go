@@ -0,0 +1,94 @@
+package hermes
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Multirange is a PostgreSQL multirange, such as an int8multirange or tstzmultirange:  an ordered
+// list of non-overlapping ranges of values of type T.  Multiranges were added in PostgreSQL 14.
+//
+//	var booked hermes.Multirange[time.Time]
+//	err := conn.QueryRow(ctx, "select range_agg(hours) from shifts where room = $1", room).Scan(&booked)
+//
+// An SQL NULL scans as a nil Multirange, and a nil Multirange is written as NULL.
+type Multirange[T any] []Range[T]
+
+// IsNull implements pgtype.MultirangeGetter.
+func (r Multirange[T]) IsNull() bool {
+	return r == nil
+}
+
+// Len implements pgtype.MultirangeGetter.
+func (r Multirange[T]) Len() int {
+	return len(r)
+}
+
+// Index implements pgtype.MultirangeGetter.
+func (r Multirange[T]) Index(i int) interface{} {
+	return r[i]
+}
+
+// IndexType implements pgtype.MultirangeGetter.
+func (r Multirange[T]) IndexType() interface{} {
+	return Range[T]{}
+}
+
+// ScanNull implements pgtype.MultirangeSetter.
+func (r *Multirange[T]) ScanNull() error {
+	*r = nil
+	return nil
+}
+
+// SetLen implements pgtype.MultirangeSetter.
+func (r *Multirange[T]) SetLen(n int) error {
+	*r = make(Multirange[T], n)
+	return nil
+}
+
+// ScanIndex implements pgtype.MultirangeSetter.
+func (r *Multirange[T]) ScanIndex(i int) interface{} {
+	return &(*r)[i]
+}
+
+// ScanIndexType implements pgtype.MultirangeSetter.
+func (r *Multirange[T]) ScanIndexType() interface{} {
+	return new(Range[T])
+}
+
+// multirangeTypes map Multiranges of Go types to the PostgreSQL multirange types they're encoded
+// as by default.
+var multirangeTypes = []struct {
+	value    interface{}
+	name     string
+	arrayOID uint32
+}{
+	{Multirange[int32]{}, "int4multirange", pgtype.Int4multirangeArrayOID},
+	{Multirange[int64]{}, "int8multirange", pgtype.Int8multirangeArrayOID},
+	{Multirange[int]{}, "int8multirange", pgtype.Int8multirangeArrayOID},
+	{Multirange[float64]{}, "nummultirange", pgtype.NummultirangeArrayOID},
+	{Multirange[time.Time]{}, "tstzmultirange", pgtype.TstzmultirangeArrayOID},
+	{Multirange[pgtype.Date]{}, "datemultirange", pgtype.DatemultirangeArrayOID},
+}
+
+// registerMultiranges adds the built-in multiranges' array types, which pgx doesn't know, to the
+// type map, and maps the common Multirange types, and slices of them, to the multirange types.
+// Only call on connections to PostgreSQL 14 or later; earlier servers don't have multirange
+// types.
+func registerMultiranges(m *pgtype.Map) {
+	for _, mt := range multirangeTypes {
+		if _, ok := m.TypeForOID(mt.arrayOID); !ok {
+			if t, ok := m.TypeForName(mt.name); ok {
+				m.RegisterType(&pgtype.Type{Name: arrayName(mt.name), OID: mt.arrayOID,
+					Codec: &pgtype.ArrayCodec{ElementType: t}})
+			}
+		}
+
+		m.RegisterDefaultPgType(mt.value, mt.name)
+
+		slice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(mt.value)), 0, 0).Interface()
+		m.RegisterDefaultPgType(slice, arrayName(mt.name))
+	}
+}
@@ -0,0 +1,100 @@
+package hermes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestMultirange(t *testing.T) {
+	m := pgtype.NewMap()
+	registerRanges(m)
+	registerMultiranges(m)
+
+	mr := Multirange[int64]{NewRange[int64](1, 5), RangeFrom[int64](10)}
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(pgtype.Int8multirangeOID, format, mr, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode multirange in format %d: %s", format, err)
+		}
+
+		var check Multirange[int64]
+		if err := m.Scan(pgtype.Int8multirangeOID, format, buf, &check); err != nil {
+			t.Fatalf("Unable to scan multirange in format %d: %s", format, err)
+		}
+
+		if len(check) != 2 || check[0] != mr[0] || check[1] != mr[1] {
+			t.Errorf("Expected %v; was %v", mr, check)
+		}
+	}
+
+	var check Multirange[int64]
+	if err := m.Scan(pgtype.Int8multirangeOID, pgtype.TextFormatCode, []byte("{}"), &check); err != nil {
+		t.Fatalf("Unable to scan empty multirange: %s", err)
+	}
+
+	if check == nil || len(check) != 0 {
+		t.Errorf("Expected empty multirange; was %#v", check)
+	}
+
+	if err := m.Scan(pgtype.Int8multirangeOID, pgtype.TextFormatCode, nil, &check); err != nil {
+		t.Fatalf("Unable to scan NULL multirange: %s", err)
+	}
+
+	if check != nil {
+		t.Errorf("Expected nil multirange; was %v", check)
+	}
+
+	buf, err := m.Encode(pgtype.Int8multirangeOID, pgtype.BinaryFormatCode, Multirange[int64](nil), nil)
+	if err != nil {
+		t.Fatalf("Unable to encode nil multirange: %s", err)
+	}
+
+	if buf != nil {
+		t.Error("Expected nil multirange to encode as NULL")
+	}
+}
+
+func TestMultirangeDefaults(t *testing.T) {
+	m := pgtype.NewMap()
+	registerRanges(m)
+	registerMultiranges(m)
+
+	if dt, ok := m.TypeForValue(Multirange[time.Time]{}); !ok || dt.OID != pgtype.TstzmultirangeOID {
+		t.Error("Expected Multirange[time.Time] to map to tstzmultirange")
+	}
+
+	dt, ok := m.TypeForValue([]Multirange[int32]{})
+	if !ok || dt.OID != pgtype.Int4multirangeArrayOID {
+		t.Fatal("Expected []Multirange[int32] to map to the int4multirange array type")
+	}
+
+	var check []Multirange[int32]
+	if err := m.Scan(dt.OID, pgtype.TextFormatCode, []byte(`{"{[1,3)}","{}"}`), &check); err != nil {
+		t.Fatalf("Unable to scan multirange array: %s", err)
+	}
+
+	if len(check) != 2 || len(check[0]) != 1 || check[0][0] != NewRange[int32](1, 3) || len(check[1]) != 0 {
+		t.Errorf("Expected [{[1,3)} {}]; was %v", check)
+	}
+}
+
+func TestParseServerVersion(t *testing.T) {
+	expected := map[string]int{
+		"14.5":                           140005,
+		"9.6.24":                         90624,
+		"15beta1":                        150000,
+		"13.8 (Debian 13.8-1.pgdg110+1)": 130008,
+		"10":                             100000,
+		"":                               0,
+		"unknown":                        0,
+	}
+
+	for version, num := range expected {
+		if check := parseServerVersion(version); check != num {
+			t.Errorf("Expected %q to be %d; was %d", version, num, check)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrMissingNamedArg is returned by Named when the SQL names a parameter missing from the
+// arguments.
+var ErrMissingNamedArg = errors.New("missing named argument")
+
+// Named translates the :name placeholders in the SQL to PostgreSQL's positional $1, $2, ...
+// parameters, returning the translated SQL and the arguments in position order.  A name used more
+// than once gets the same position.  Names start with a letter or underscore, followed by letters,
+// digits, or underscores.
+//
+// Placeholders aren't recognized in string literals, quoted identifiers, dollar-quoted strings,
+// or comments, and type casts such as "::text" are left alone:
+//
+//	sql, args, err := hermes.Named(`
+//		select * from users
+//		where org_id = :org and created_at > :since::timestamptz`,
+//		map[string]interface{}{"org": orgID, "since": since})
+//
+// Returns ErrMissingNamedArg if a placeholder's name isn't in args.  Arguments the SQL doesn't
+// use are ignored.
+func Named(sql string, args map[string]interface{}) (string, []interface{}, error) {
+	var out strings.Builder
+	out.Grow(len(sql))
+
+	var values []interface{}
+	positions := make(map[string]int)
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '\'':
+			// Backslashes escape quotes in E'...' strings
+			escapes := i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && (i < 2 || !isNameChar(sql[i-2]))
+			end := skipQuoted(sql, i, '\'', escapes)
+			out.WriteString(sql[i:end])
+			i = end
+
+		case c == '"':
+			end := skipQuoted(sql, i, '"', false)
+			out.WriteString(sql[i:end])
+			i = end
+
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i
+			}
+			out.WriteString(sql[i:end])
+			i = end
+
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := skipBlockComment(sql, i)
+			out.WriteString(sql[i:end])
+			i = end
+
+		case c == '$' && (i == 0 || !isNameChar(sql[i-1])):
+			end := skipDollarQuoted(sql, i)
+			out.WriteString(sql[i:end])
+			i = end
+
+		case c == ':' && strings.HasPrefix(sql[i:], "::"):
+			out.WriteString("::")
+			i += 2
+
+		case c == ':' && i+1 < len(sql) && isNameStart(sql[i+1]):
+			end := i + 1
+			for end < len(sql) && isNameChar(sql[end]) {
+				end++
+			}
+
+			name := sql[i+1 : end]
+
+			position, ok := positions[name]
+			if !ok {
+				value, found := args[name]
+				if !found {
+					return "", nil, fmt.Errorf("%w: %s", ErrMissingNamedArg, name)
+				}
+
+				values = append(values, value)
+				position = len(values)
+				positions[name] = position
+			}
+
+			out.WriteByte('$')
+			out.WriteString(strconv.Itoa(position))
+			i = end
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String(), values, nil
+}
+
+// skipQuoted returns the index just past the string or identifier starting at start, quoted with
+// quote.  Doubled quotes are part of the string.  If escapes is true, a backslash escapes the next
+// character.
+func skipQuoted(sql string, start int, quote byte, escapes bool) int {
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+
+	return len(sql)
+}
+
+// skipBlockComment returns the index just past the /* comment */ starting at start.  Block
+// comments nest in PostgreSQL.
+func skipBlockComment(sql string, start int) int {
+	depth := 0
+
+	for i := start; i < len(sql)-1; i++ {
+		switch {
+		case sql[i] == '/' && sql[i+1] == '*':
+			depth++
+			i++
+		case sql[i] == '*' && sql[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return len(sql)
+}
+
+// skipDollarQuoted returns the index just past the $tag$...$tag$ string starting at start.  If
+// the $ doesn't start a dollar-quoted string, e.g. it's a positional parameter, returns the index
+// just past the $.
+func skipDollarQuoted(sql string, start int) int {
+	end := start + 1
+	for end < len(sql) && sql[end] != '$' {
+		if !isNameChar(sql[end]) || (end == start+1 && !isNameStart(sql[end])) {
+			return start + 1
+		}
+		end++
+	}
+
+	if end >= len(sql) {
+		return start + 1
+	}
+
+	tag := sql[start : end+1]
+
+	closing := strings.Index(sql[end+1:], tag)
+	if closing < 0 {
+		return len(sql)
+	}
+
+	return end + 1 + closing + len(tag)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// execNamed translates the named parameters and runs the command on conn.
+func execNamed(ctx context.Context, conn Conn, sql string, args map[string]interface{}) (pgconn.CommandTag, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	query, values, err := Named(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	return conn.Exec(ctx, query, values...)
+}
+
+// queryNamed translates the named parameters and runs the query on conn.
+func queryNamed(ctx context.Context, conn Conn, sql string, args map[string]interface{}) (pgx.Rows, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	query, values, err := Named(sql, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.Query(ctx, query, values...)
+}
+
+// ExecNamed runs the SQL command with :name placeholders for the arguments.  See Named.
+func (db *DB) ExecNamed(ctx context.Context, sql string, args map[string]interface{}) (pgconn.CommandTag, error) {
+	return execNamed(ctx, db, sql, args)
+}
+
+// QueryNamed runs the SQL query with :name placeholders for the arguments.  See Named.
+func (db *DB) QueryNamed(ctx context.Context, sql string, args map[string]interface{}) (pgx.Rows, error) {
+	return queryNamed(ctx, db, sql, args)
+}
+
+// ExecNamed runs the SQL command with :name placeholders for the arguments.  See Named.
+func (tx *Tx) ExecNamed(ctx context.Context, sql string, args map[string]interface{}) (pgconn.CommandTag, error) {
+	return execNamed(ctx, tx, sql, args)
+}
+
+// QueryNamed runs the SQL query with :name placeholders for the arguments.  See Named.
+func (tx *Tx) QueryNamed(ctx context.Context, sql string, args map[string]interface{}) (pgx.Rows, error) {
+	return queryNamed(ctx, tx, sql, args)
+}
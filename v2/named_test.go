@@ -0,0 +1,69 @@
+package hermes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	args := map[string]interface{}{"id": 1, "name": "Alice", "unused": true}
+
+	tests := []struct {
+		sql      string
+		expected string
+		values   []interface{}
+	}{
+		{
+			sql:      "select * from users where id = :id",
+			expected: "select * from users where id = $1",
+			values:   []interface{}{1},
+		},
+		{
+			sql:      "update users set name = :name where id = :id or parent_id = :id",
+			expected: "update users set name = $1 where id = $2 or parent_id = $2",
+			values:   []interface{}{"Alice", 1},
+		},
+		{
+			sql:      "select :name::text, ':id', \":id\", E'it\\'s :id', $$ :id $$, $tag$ :id $tag$",
+			expected: "select $1::text, ':id', \":id\", E'it\\'s :id', $$ :id $$, $tag$ :id $tag$",
+			values:   []interface{}{"Alice"},
+		},
+		{
+			sql:      "select 'it''s :id' -- :id\n, /* :id /* :id */ */ :id",
+			expected: "select 'it''s :id' -- :id\n, /* :id /* :id */ */ $1",
+			values:   []interface{}{1},
+		},
+		{
+			sql:      "select arr[1:2], :id",
+			expected: "select arr[1:2], $1",
+			values:   []interface{}{1},
+		},
+		{
+			sql:      "select 1",
+			expected: "select 1",
+		},
+	}
+
+	for _, test := range tests {
+		sql, values, err := Named(test.sql, args)
+		if err != nil {
+			t.Errorf("Unable to translate %q: %s", test.sql, err)
+			continue
+		}
+
+		if sql != test.expected {
+			t.Errorf("Expected %q; was %q", test.expected, sql)
+		}
+
+		if !reflect.DeepEqual(values, test.values) {
+			t.Errorf("Expected values %v for %q; was %v", test.values, test.sql, values)
+		}
+	}
+}
+
+func TestNamedMissing(t *testing.T) {
+	if _, _, err := Named("select :missing", nil); !errors.Is(err, ErrMissingNamedArg) {
+		t.Errorf("Expected ErrMissingNamedArg; was %v", err)
+	}
+}
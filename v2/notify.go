@@ -0,0 +1,117 @@
+package hermes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MaxPayloadSize is the largest notification payload PostgreSQL accepts, in bytes.
+const MaxPayloadSize = 7999
+
+// ErrPayloadTooLarge is returned by Notify if the JSON-encoded payload exceeds MaxPayloadSize.
+var ErrPayloadTooLarge = errors.New("notification payload too large")
+
+// Notify sends a notification on the channel.  The payload is marshaled to JSON; pass a
+// json.RawMessage to send a pre-encoded payload, or nil to send an empty payload.
+func (db *DB) Notify(ctx context.Context, channel string, payload interface{}) error {
+	return notify(ctx, db, channel, payload)
+}
+
+// Notify queues a notification on the channel, to be delivered when the transaction commits.  If
+// the transaction rolls back, the notification is never sent.  The payload is marshaled to JSON;
+// pass a json.RawMessage to send a pre-encoded payload, or nil to send an empty payload.
+func (tx *Tx) Notify(ctx context.Context, channel string, payload interface{}) error {
+	return notify(ctx, tx, channel, payload)
+}
+
+// pendingNotification is a notification queued by NotifyOnCommit.
+type pendingNotification struct {
+	channel string
+	payload string
+}
+
+// NotifyOnCommit queues a notification on the channel to be sent when the transaction commits.
+// Unlike Notify, no statement is executed until commit, when every queued notification is sent in
+// a single round trip just before the COMMIT.  If the transaction rolls back, the notifications
+// are discarded.
+//
+// Notifications queued in a pseudo nested transaction are passed up to the enclosing
+// transaction when the savepoint is released, and discarded if it's rolled back.
+//
+// The payload is marshaled to JSON.  Returns ErrPayloadTooLarge if the payload won't fit in a
+// notification.
+func (tx *Tx) NotifyOnCommit(channel string, payload interface{}) error {
+	encoded, err := encodePayload(payload)
+	if err != nil {
+		return err
+	}
+
+	tx.notifications = append(tx.notifications, pendingNotification{channel, encoded})
+	return nil
+}
+
+// sendNotifications sends the notifications queued by NotifyOnCommit.
+func (tx *Tx) sendNotifications(ctx context.Context) error {
+	if len(tx.notifications) == 0 {
+		return nil
+	}
+
+	channels := make([]string, len(tx.notifications))
+	payloads := make([]string, len(tx.notifications))
+
+	for i, n := range tx.notifications {
+		channels[i] = n.channel
+		payloads[i] = n.payload
+	}
+
+	_, err := tx.Tx.Exec(ctx, `
+		SELECT pg_notify(n.channel, n.payload)
+		FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS n(channel, payload, ord)
+		ORDER BY n.ord`, channels, payloads)
+	if err != nil {
+		return err
+	}
+
+	tx.notifications = nil
+	return nil
+}
+
+// notify encodes the payload and calls pg_notify.
+func notify(ctx context.Context, conn Conn, channel string, payload interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	encoded, err := encodePayload(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(ctx, "SELECT pg_notify($1, $2)", channel, encoded)
+	return err
+}
+
+// encodePayload marshals the payload to JSON, checking it fits in a notification.
+func encodePayload(payload interface{}) (string, error) {
+	if payload == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) > MaxPayloadSize {
+		return "", fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrPayloadTooLarge, len(data), MaxPayloadSize)
+	}
+
+	return string(data), nil
+}
+
+// Decode unmarshals the notification's JSON payload into v.
+func (n *Notification) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(n.Payload), v)
+}
@@ -0,0 +1,47 @@
+package hermes
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncodePayload(t *testing.T) {
+	encoded, err := encodePayload(map[string]int{"id": 12})
+	if err != nil {
+		t.Fatalf("Unable to encode payload: %s", err)
+	}
+
+	if encoded != `{"id":12}` {
+		t.Errorf("Expected JSON payload; was %s", encoded)
+	}
+
+	if encoded, _ := encodePayload(nil); encoded != "" {
+		t.Errorf("Expected nil payload to be empty; was %s", encoded)
+	}
+
+	if encoded, _ := encodePayload(json.RawMessage(`[1,2]`)); encoded != "[1,2]" {
+		t.Errorf("Expected raw payload to pass through; was %s", encoded)
+	}
+
+	if _, err := encodePayload(strings.Repeat("x", MaxPayloadSize)); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("Expected ErrPayloadTooLarge; was %v", err)
+	}
+}
+
+func TestNotificationDecode(t *testing.T) {
+	n := &Notification{Channel: "users", Payload: `{"id":12}`}
+
+	var payload struct {
+		ID int `json:"id"`
+	}
+
+	if err := n.Decode(&payload); err != nil {
+		t.Fatalf("Unable to decode payload: %s", err)
+	}
+
+	if payload.ID != 12 {
+		t.Errorf("Expected ID 12; was %d", payload.ID)
+	}
+}
@@ -0,0 +1,175 @@
+package hermes
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Null holds a value of type T that may be NULL in the database, in place of sql.NullString,
+// sql.NullInt64, pointers, and the like.  A Null scans from and encodes to any column T can:
+//
+//	var email hermes.Null[string]
+//	err := conn.QueryRow(ctx, "select email from users where id = $1", id).Scan(&email)
+//
+// An SQL NULL scans as an invalid Null with the zero value of T, and an invalid Null is written as
+// NULL.  Null is supported on connections made with Connect or ConnectConfig.
+type Null[T any] struct {
+	Value T
+	Valid bool
+}
+
+// NewNull returns a valid Null holding the value.
+func NewNull[T any](value T) Null[T] {
+	return Null[T]{Value: value, Valid: true}
+}
+
+// NullOf returns a valid Null holding the value the pointer references, or an invalid Null if the
+// pointer is nil.
+func NullOf[T any](ptr *T) Null[T] {
+	if ptr == nil {
+		return Null[T]{}
+	}
+
+	return Null[T]{Value: *ptr, Valid: true}
+}
+
+// Ptr returns a pointer to the value, or nil if the Null is invalid.
+func (n Null[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+
+	value := n.Value
+	return &value
+}
+
+// MarshalJSON implements json.Marshaler.  An invalid Null marshals as null.
+func (n Null[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(n.Value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  A JSON null is invalid.
+func (n *Null[T]) UnmarshalJSON(data []byte) error {
+	var value T
+
+	if string(data) == "null" {
+		*n = Null[T]{Value: value}
+		return nil
+	}
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	*n = Null[T]{Value: value, Valid: true}
+	return nil
+}
+
+// nullValue returns the value and whether it's valid, for encoding.
+func (n Null[T]) nullValue() (interface{}, bool) {
+	return n.Value, n.Valid
+}
+
+// nullTarget returns a pointer to the value, for scanning.
+func (n *Null[T]) nullTarget() interface{} {
+	return &n.Value
+}
+
+// setNull marks the Null valid or, clearing the value, invalid.
+func (n *Null[T]) setNull(valid bool) {
+	if !valid {
+		var value T
+		n.Value = value
+	}
+
+	n.Valid = valid
+}
+
+// nullValuer is implemented by Null[T] for any T.
+type nullValuer interface {
+	nullValue() (interface{}, bool)
+}
+
+// nullScanner is implemented by *Null[T] for any T.
+type nullScanner interface {
+	nullTarget() interface{}
+	setNull(valid bool)
+}
+
+// registerNull adds support for Null values to the type map, ahead of pgx's own wrappers, which
+// would otherwise treat Null as a composite type.
+func registerNull(m *pgtype.Map) {
+	m.TryWrapEncodePlanFuncs = append([]pgtype.TryWrapEncodePlanFunc{tryWrapNullEncodePlan},
+		m.TryWrapEncodePlanFuncs...)
+	m.TryWrapScanPlanFuncs = append([]pgtype.TryWrapScanPlanFunc{tryWrapNullScanPlan},
+		m.TryWrapScanPlanFuncs...)
+}
+
+// tryWrapNullEncodePlan encodes a Null as its value, or as NULL if it's invalid.
+func tryWrapNullEncodePlan(value interface{}) (pgtype.WrappedEncodePlanNextSetter, interface{}, bool) {
+	if n, ok := value.(nullValuer); ok {
+		next, _ := n.nullValue()
+		return &nullEncodePlan{}, next, true
+	}
+
+	return nil, nil, false
+}
+
+type nullEncodePlan struct {
+	next pgtype.EncodePlan
+}
+
+func (plan *nullEncodePlan) SetNext(next pgtype.EncodePlan) {
+	plan.next = next
+}
+
+func (plan *nullEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	next, valid := value.(nullValuer).nullValue()
+	if !valid {
+		return nil, nil
+	}
+
+	return plan.next.Encode(next, buf)
+}
+
+// tryWrapNullScanPlan scans into a Null's value, or marks the Null invalid if the column is NULL.
+func tryWrapNullScanPlan(target interface{}) (pgtype.WrappedScanPlanNextSetter, interface{}, bool) {
+	if n, ok := target.(nullScanner); ok {
+		return &nullScanPlan{}, n.nullTarget(), true
+	}
+
+	return nil, nil, false
+}
+
+type nullScanPlan struct {
+	next pgtype.ScanPlan
+}
+
+func (plan *nullScanPlan) SetNext(next pgtype.ScanPlan) {
+	plan.next = next
+}
+
+func (plan *nullScanPlan) Scan(src []byte, target interface{}) error {
+	n, ok := target.(nullScanner)
+	if !ok {
+		return fmt.Errorf("cannot scan into %T", target)
+	}
+
+	if src == nil {
+		n.setNull(false)
+		return nil
+	}
+
+	if err := plan.next.Scan(src, n.nullTarget()); err != nil {
+		return err
+	}
+
+	n.setNull(true)
+	return nil
+}
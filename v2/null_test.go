@@ -0,0 +1,148 @@
+package hermes
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestNull(t *testing.T) {
+	m := pgtype.NewMap()
+	registerNull(m)
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(pgtype.TextOID, format, NewNull("hello"), nil)
+		if err != nil {
+			t.Fatalf("Unable to encode Null[string] in format %d: %s", format, err)
+		}
+
+		var s Null[string]
+		if err := m.Scan(pgtype.TextOID, format, buf, &s); err != nil {
+			t.Fatalf("Unable to scan Null[string] in format %d: %s", format, err)
+		}
+
+		if !s.Valid || s.Value != "hello" {
+			t.Errorf("Expected valid \"hello\"; was %#v", s)
+		}
+
+		buf, err = m.Encode(pgtype.Int8OID, format, NewNull(int64(42)), nil)
+		if err != nil {
+			t.Fatalf("Unable to encode Null[int64] in format %d: %s", format, err)
+		}
+
+		var i Null[int64]
+		if err := m.Scan(pgtype.Int8OID, format, buf, &i); err != nil {
+			t.Fatalf("Unable to scan Null[int64] in format %d: %s", format, err)
+		}
+
+		if !i.Valid || i.Value != 42 {
+			t.Errorf("Expected valid 42; was %#v", i)
+		}
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+
+		buf, err = m.Encode(pgtype.TimestamptzOID, format, NewNull(now), nil)
+		if err != nil {
+			t.Fatalf("Unable to encode Null[time.Time] in format %d: %s", format, err)
+		}
+
+		var ts Null[time.Time]
+		if err := m.Scan(pgtype.TimestamptzOID, format, buf, &ts); err != nil {
+			t.Fatalf("Unable to scan Null[time.Time] in format %d: %s", format, err)
+		}
+
+		if !ts.Valid || !ts.Value.Equal(now) {
+			t.Errorf("Expected valid %s; was %#v", now, ts)
+		}
+	}
+}
+
+func TestNullInvalid(t *testing.T) {
+	m := pgtype.NewMap()
+	registerNull(m)
+
+	buf, err := m.Encode(pgtype.Int4OID, pgtype.BinaryFormatCode, Null[int32]{}, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode invalid Null: %s", err)
+	}
+
+	if buf != nil {
+		t.Error("Expected invalid Null to encode as NULL")
+	}
+
+	n := NewNull(int32(7))
+	if err := m.Scan(pgtype.Int4OID, pgtype.BinaryFormatCode, nil, &n); err != nil {
+		t.Fatalf("Unable to scan NULL into Null[int32]: %s", err)
+	}
+
+	if n.Valid || n.Value != 0 {
+		t.Errorf("Expected NULL to scan as invalid Null with a zero value; was %#v", n)
+	}
+}
+
+func TestNullArray(t *testing.T) {
+	m := pgtype.NewMap()
+	registerNull(m)
+
+	values := []Null[int32]{NewNull(int32(1)), {}, NewNull(int32(3))}
+
+	buf, err := m.Encode(pgtype.Int4ArrayOID, pgtype.BinaryFormatCode, values, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode array of Null: %s", err)
+	}
+
+	var check []Null[int32]
+	if err := m.Scan(pgtype.Int4ArrayOID, pgtype.BinaryFormatCode, buf, &check); err != nil {
+		t.Fatalf("Unable to scan array of Null: %s", err)
+	}
+
+	if len(check) != 3 || check[0] != values[0] || check[1].Valid || check[2] != values[2] {
+		t.Errorf("Expected %v; was %v", values, check)
+	}
+
+	var tags Null[[]string]
+	if err := m.Scan(pgtype.TextArrayOID, pgtype.TextFormatCode, []byte("{a,b}"), &tags); err != nil {
+		t.Fatalf("Unable to scan text array into Null: %s", err)
+	}
+
+	if !tags.Valid || len(tags.Value) != 2 || tags.Value[1] != "b" {
+		t.Errorf("Expected valid [a b]; was %#v", tags)
+	}
+}
+
+func TestNullPtr(t *testing.T) {
+	if ptr := (Null[string]{}).Ptr(); ptr != nil {
+		t.Errorf("Expected nil pointer; was %v", *ptr)
+	}
+
+	s := "hello"
+	if n := NullOf(&s); !n.Valid || *n.Ptr() != s {
+		t.Errorf("Expected valid %q; was %#v", s, n)
+	}
+
+	if n := NullOf[string](nil); n.Valid {
+		t.Error("Expected nil pointer to be invalid")
+	}
+}
+
+func TestNullJSON(t *testing.T) {
+	data, err := json.Marshal([]Null[int]{NewNull(1), {}})
+	if err != nil {
+		t.Fatalf("Unable to marshal Null: %s", err)
+	}
+
+	if string(data) != "[1,null]" {
+		t.Errorf("Expected [1,null]; was %s", data)
+	}
+
+	var check []Null[int]
+	if err := json.Unmarshal(data, &check); err != nil {
+		t.Fatalf("Unable to unmarshal Null: %s", err)
+	}
+
+	if len(check) != 2 || check[0] != NewNull(1) || check[1].Valid {
+		t.Errorf("Expected [1 null]; was %v", check)
+	}
+}
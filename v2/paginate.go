@@ -0,0 +1,318 @@
+package hermes
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrInvalidCursor is returned when a page cursor can't be decoded or doesn't match the
+	// query's sort order.
+	ErrInvalidCursor = errors.New("invalid page cursor")
+
+	// ErrUnstableOrder is returned when two rows in a page have the same values in every sort
+	// column, so rows could be skipped or repeated between pages.  Add a unique column, such as
+	// the primary key, to the end of the sort order.
+	ErrUnstableOrder = errors.New("sort order isn't unique")
+)
+
+// DefaultPageSize is the number of rows in a page if PageQuery.Limit isn't set.
+const DefaultPageSize = 50
+
+// SortKey is a column a paginated query is sorted by.
+type SortKey struct {
+	// Column is the name of the column in the query's results.
+	Column string
+
+	// Desc sorts the column in descending order.
+	Desc bool
+}
+
+// Asc sorts by the column in ascending order.
+func Asc(column string) SortKey {
+	return SortKey{Column: column}
+}
+
+// Desc sorts by the column in descending order.
+func Desc(column string) SortKey {
+	return SortKey{Column: column, Desc: true}
+}
+
+// PageQuery is a query paginated with Paginate.
+type PageQuery struct {
+	// SQL selects the rows to paginate, without an ORDER BY or LIMIT.
+	SQL string
+
+	// Args are the arguments to the SQL.
+	Args []interface{}
+
+	// OrderBy is the sort order.  The combination of columns must be unique, so end with a
+	// unique column such as the primary key.
+	OrderBy []SortKey
+
+	// Limit is the number of rows in a page.  Defaults to DefaultPageSize.
+	Limit int
+
+	// Cursor is the Next cursor from the previous page.  Leave it blank for the first page.
+	Cursor string
+}
+
+// Page is a page of results from Paginate.
+type Page[T any] struct {
+	// Items are the rows in the page.
+	Items []T
+
+	// HasMore is true if there are more rows after this page.
+	HasMore bool
+
+	// Next is the cursor for the next page, or blank if this is the last page.
+	Next string
+}
+
+// Paginate returns a page of the query's rows as structs of type T, using keyset (or "seek")
+// pagination.  Rather than skipping rows with OFFSET, which gets slower the deeper the page,
+// each page picks up after the sort values of the last row of the previous page, which the
+// database can find using an index on the sort columns.
+//
+//	page, err := hermes.Paginate[User](ctx, db, hermes.PageQuery{
+//	    SQL:     "select id, name, created_at from users where org_id = $1",
+//	    Args:    []interface{}{orgID},
+//	    OrderBy: []hermes.SortKey{hermes.Desc("created_at"), hermes.Asc("id")},
+//	    Cursor:  r.URL.Query().Get("cursor"),
+//	})
+//
+// The sort columns must be mapped to fields of T and must not be NULL.  See ScanStruct for how
+// columns map to fields.
+func Paginate[T any](ctx context.Context, conn Conn, q PageQuery) (*Page[T], error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(q.OrderBy) == 0 {
+		return nil, fmt.Errorf("%w: no sort columns", ErrUnstableOrder)
+	}
+
+	var zero T
+	fields, err := sortFields(reflect.TypeOf(zero), q.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	var after []string
+	if q.Cursor != "" {
+		if after, err = decodeCursor(q.Cursor, len(q.OrderBy)); err != nil {
+			return nil, err
+		}
+	}
+
+	sql, args := pageSQL(q, after, limit)
+
+	items, err := Select[T](ctx, conn, sql, append(args, ExpectRows(limit+1))...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: items}
+	if len(items) > limit {
+		page.Items, page.HasMore = items[:limit], true
+	}
+
+	var last []string
+	for i := range page.Items {
+		values, err := sortValues(reflect.ValueOf(&page.Items[i]).Elem(), fields, q.OrderBy)
+		if err != nil {
+			return nil, err
+		}
+
+		if last != nil && equalValues(values, last) {
+			return nil, fmt.Errorf("%w: %v", ErrUnstableOrder, values)
+		}
+		last = values
+	}
+
+	if page.HasMore {
+		page.Next = encodeCursor(last)
+	}
+
+	return page, nil
+}
+
+// pageSQL wraps the query to return the page of rows after the cursor values, plus one more row
+// to tell if there are more pages.
+func pageSQL(q PageQuery, after []string, limit int) (string, []interface{}) {
+	args := append([]interface{}{}, q.Args...)
+
+	var sql strings.Builder
+	sql.WriteString("SELECT * FROM (")
+	sql.WriteString(q.SQL)
+	sql.WriteString(") AS page")
+
+	if after != nil {
+		params := make([]string, len(after))
+		for i, value := range after {
+			args = append(args, value)
+			params[i] = fmt.Sprintf("$%d", len(args))
+		}
+
+		sql.WriteString(" WHERE ")
+		sql.WriteString(seekCondition(q.OrderBy, params))
+	}
+
+	sql.WriteString(" ORDER BY ")
+	for i, key := range q.OrderBy {
+		if i > 0 {
+			sql.WriteString(", ")
+		}
+
+		sql.WriteString(pgx.Identifier{key.Column}.Sanitize())
+		if key.Desc {
+			sql.WriteString(" DESC")
+		}
+	}
+
+	fmt.Fprintf(&sql, " LIMIT %d", limit+1)
+
+	return sql.String(), args
+}
+
+// seekCondition returns the condition for rows after the cursor.  When every column sorts in the
+// same direction, it's a row comparison the database can satisfy with an index; otherwise each
+// column is compared in turn.
+func seekCondition(keys []SortKey, params []string) string {
+	columns := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = pgx.Identifier{key.Column}.Sanitize()
+	}
+
+	mixed := false
+	for _, key := range keys[1:] {
+		if key.Desc != keys[0].Desc {
+			mixed = true
+		}
+	}
+
+	if !mixed {
+		op := ">"
+		if keys[0].Desc {
+			op = "<"
+		}
+
+		if len(keys) == 1 {
+			return fmt.Sprintf("%s %s %s", columns[0], op, params[0])
+		}
+
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, strings.Join(params, ", "))
+	}
+
+	// (a > $1) OR (a = $1 AND b < $2) OR ...
+	terms := make([]string, len(keys))
+	for i, key := range keys {
+		op := ">"
+		if key.Desc {
+			op = "<"
+		}
+
+		conditions := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, fmt.Sprintf("%s = %s", columns[j], params[j]))
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s %s", columns[i], op, params[i]))
+
+		terms[i] = "(" + strings.Join(conditions, " AND ") + ")"
+	}
+
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
+
+// sortFields returns the indexes of the struct fields the sort columns map to.
+func sortFields(structType reflect.Type, keys []SortKey) ([][]int, error) {
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot paginate %s; expected a struct", structType)
+	}
+
+	fields := make(map[string][]int)
+	structFields(structType, nil, fields)
+
+	indexes := make([][]int, len(keys))
+	for i, key := range keys {
+		index, ok := fields[key.Column]
+		if !ok {
+			return nil, fmt.Errorf("%w: sort column %s in %s", ErrUnmappedColumn, key.Column, structType)
+		}
+		indexes[i] = index
+	}
+
+	return indexes, nil
+}
+
+// sortValues returns the values of the sort columns in the row, formatted for the cursor.
+func sortValues(row reflect.Value, fields [][]int, keys []SortKey) ([]string, error) {
+	values := make([]string, len(fields))
+
+	for i, index := range fields {
+		value := row.FieldByIndex(index)
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return nil, fmt.Errorf("%w: sort column %s is NULL", ErrInvalidCursor, keys[i].Column)
+			}
+			value = value.Elem()
+		}
+
+		switch v := value.Interface().(type) {
+		case time.Time:
+			values[i] = v.Format(time.RFC3339Nano)
+		case fmt.Stringer:
+			values[i] = v.String()
+		default:
+			values[i] = fmt.Sprint(v)
+		}
+	}
+
+	return values, nil
+}
+
+// encodeCursor encodes the sort values of the last row in a page.
+func encodeCursor(values []string) string {
+	encoded, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeCursor decodes the sort values in the cursor, checking there's a value for every sort
+// column.
+func decodeCursor(cursor string, columns int) ([]string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var values []string
+	if err := json.Unmarshal(decoded, &values); err != nil || len(values) != columns {
+		return nil, ErrInvalidCursor
+	}
+
+	return values, nil
+}
+
+// equalValues returns true if the two rows' sort values are the same.
+func equalValues(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,134 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPageSQL(t *testing.T) {
+	q := PageQuery{
+		SQL:     "select id, created_at from users where org_id = $1",
+		Args:    []interface{}{7},
+		OrderBy: []SortKey{Desc("created_at"), Desc("id")},
+	}
+
+	sql, args := pageSQL(q, nil, 10)
+	expected := `SELECT * FROM (select id, created_at from users where org_id = $1) AS page ORDER BY "created_at" DESC, "id" DESC LIMIT 11`
+	if sql != expected {
+		t.Errorf("Expected %s; was %s", expected, sql)
+	}
+
+	if len(args) != 1 {
+		t.Errorf("Expected 1 argument; was %v", args)
+	}
+
+	sql, args = pageSQL(q, []string{"2022-11-30T12:00:00Z", "12"}, 10)
+	expected = `SELECT * FROM (select id, created_at from users where org_id = $1) AS page WHERE ("created_at", "id") < ($2, $3) ORDER BY "created_at" DESC, "id" DESC LIMIT 11`
+	if sql != expected {
+		t.Errorf("Expected %s; was %s", expected, sql)
+	}
+
+	if len(args) != 3 || args[2] != "12" {
+		t.Errorf("Expected the cursor values after the query's arguments; was %v", args)
+	}
+}
+
+func TestSeekConditionMixed(t *testing.T) {
+	check := seekCondition([]SortKey{Desc("score"), Asc("name"), Asc("id")}, []string{"$1", "$2", "$3"})
+	expected := `(("score" < $1) OR ("score" = $1 AND "name" > $2) OR ("score" = $1 AND "name" = $2 AND "id" > $3))`
+
+	if check != expected {
+		t.Errorf("Expected %s; was %s", expected, check)
+	}
+
+	if check := seekCondition([]SortKey{Asc("id")}, []string{"$1"}); check != `"id" > $1` {
+		t.Errorf("Expected a simple comparison; was %s", check)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	cursor := encodeCursor([]string{"2022-11-30T12:00:00Z", "12"})
+
+	values, err := decodeCursor(cursor, 2)
+	if err != nil {
+		t.Fatalf("Unable to decode cursor: %s", err)
+	}
+
+	if len(values) != 2 || values[1] != "12" {
+		t.Errorf("Expected the cursor values; was %v", values)
+	}
+
+	if _, err := decodeCursor(cursor, 3); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected a cursor for a different sort order to be invalid; was %v", err)
+	}
+
+	if _, err := decodeCursor("not a cursor!", 2); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor; was %v", err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	created := time.Date(2022, 11, 30, 12, 0, 0, 0, time.UTC)
+	conn := &queryConn{
+		columns: []string{"id", "display_name", "created_at"},
+		values: [][]interface{}{
+			{int64(3), "Carol", created},
+			{int64(2), "Bob", created},
+			{int64(1), "Alice", created.Add(-time.Hour)},
+		},
+	}
+
+	q := PageQuery{
+		SQL:     "select id, display_name, created_at from accounts",
+		OrderBy: []SortKey{Desc("created_at"), Desc("id")},
+		Limit:   2,
+	}
+
+	page, err := Paginate[account](context.Background(), conn, q)
+	if err != nil {
+		t.Fatalf("Unable to paginate: %s", err)
+	}
+
+	if len(page.Items) != 2 || !page.HasMore {
+		t.Fatalf("Expected 2 items and more to come; was %d, %v", len(page.Items), page.HasMore)
+	}
+
+	values, err := decodeCursor(page.Next, 2)
+	if err != nil {
+		t.Fatalf("Unable to decode the next cursor: %s", err)
+	}
+
+	if values[0] != "2022-11-30T12:00:00Z" || values[1] != "2" {
+		t.Errorf("Expected the cursor to hold the last row's sort values; was %v", values)
+	}
+
+	q.Limit = 3
+	if page, err = Paginate[account](context.Background(), conn, q); err != nil {
+		t.Fatalf("Unable to paginate: %s", err)
+	}
+
+	if page.HasMore || page.Next != "" {
+		t.Errorf("Expected the last page; was %v, %q", page.HasMore, page.Next)
+	}
+}
+
+func TestPaginateUnstable(t *testing.T) {
+	created := time.Date(2022, 11, 30, 12, 0, 0, 0, time.UTC)
+	conn := &queryConn{
+		columns: []string{"id", "created_at"},
+		values:  [][]interface{}{{int64(2), created}, {int64(1), created}},
+	}
+
+	q := PageQuery{SQL: "select id, created_at from accounts", OrderBy: []SortKey{Desc("created_at")}}
+
+	if _, err := Paginate[account](context.Background(), conn, q); !errors.Is(err, ErrUnstableOrder) {
+		t.Errorf("Expected ErrUnstableOrder; was %v", err)
+	}
+
+	q.OrderBy = []SortKey{Desc("missing")}
+	if _, err := Paginate[account](context.Background(), conn, q); !errors.Is(err, ErrUnmappedColumn) {
+		t.Errorf("Expected ErrUnmappedColumn; was %v", err)
+	}
+}
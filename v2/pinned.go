@@ -0,0 +1,359 @@
+package hermes
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PinnedConn is a single connection acquired from the database pool, wrapped to support the
+// hermes.Conn interface.  Every call made on a PinnedConn runs on the same database session, so
+// session state such as LISTEN, SET, or session advisory locks carries from one call to the next.
+//
+// Unlike a DB, calling Close on a PinnedConn releases the connection back to the pool, so be sure
+// to call it when you're done.  Commit and Rollback do nothing.
+type PinnedConn struct {
+	*pgxpool.Conn
+	defaultTimeout time.Duration
+
+	db        *DB
+	group     *StatementGroup
+	listening bool
+	released  int32
+
+	// session advisory locks taken through the connection, unlocked when it's closed
+	locks []*sessionLock
+
+	// limited connections hold one of the DB's concurrency slots until they're closed
+	limited bool
+}
+
+// Pin acquires a connection from the pool for your exclusive use until you Close it.
+func (db *DB) Pin(ctx context.Context) (*PinnedConn, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := db.acquireSlot(ctx); err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Acquire(db.timeAcquire(ctx))
+	if err != nil {
+		db.releaseSlot()
+		return nil, err
+	}
+
+	return &PinnedConn{Conn: conn, defaultTimeout: db.defaultTimeout, db: db, limited: true}, nil
+}
+
+// Begin starts a transaction on the pinned connection.
+func (conn *PinnedConn) Begin(ctx context.Context) (Conn, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if conn.db.shuttingDown() {
+		return nil, ErrShuttingDown
+	}
+
+	tx, err := conn.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := &Tx{Tx: tx, defaultTimeout: conn.defaultTimeout, db: conn.db}
+	conn.db.txBegan(&wrapped.ended, tx.Conn())
+
+	return wrapped, nil
+}
+
+// BeginWithTimeout starts a custom transaction that manages the timeout context for you.  This is
+// experimental; use at your own risk!
+func (conn *PinnedConn) BeginWithTimeout(ctx context.Context) (*ContextualTx, error) {
+	if conn.db.shuttingDown() {
+		return nil, ErrShuttingDown
+	}
+
+	ctx, cancel := conn.WithTimeout(ctx)
+
+	tx, err := conn.Conn.Begin(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	ctxTx := &ContextualTx{Tx: tx, ctx: ctx, cancel: cancel, db: conn.db}
+	conn.db.txBegan(&ctxTx.ended, tx.Conn())
+
+	return ctxTx, nil
+}
+
+// Commit does nothing.
+func (conn *PinnedConn) Commit(context.Context) error {
+	return nil
+}
+
+// Rollback does nothing.
+func (conn *PinnedConn) Rollback(context.Context) error {
+	return nil
+}
+
+// Close releases the connection back to the pool, or to its StatementGroup.  Any session advisory
+// locks taken through Lock, TryLock, or LockAll and not yet released are unlocked, and if you
+// LISTENed on any channels through Listen, the connection stops listening first.  Safe to call
+// multiple times.
+func (conn *PinnedConn) Close(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&conn.released, 0, 1) {
+		return nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if conn.limited {
+		defer conn.db.releaseSlot()
+	}
+
+	if conn.group != nil {
+		defer conn.group.release(conn.Conn)
+	} else {
+		defer conn.Conn.Release()
+	}
+
+	if err := conn.releaseLocks(ctx); err != nil {
+		return err
+	}
+
+	if conn.listening {
+		if _, err := conn.Conn.Exec(ctx, "UNLISTEN *"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// track the session advisory locks taken through the connection, dropping those already released.
+func (conn *PinnedConn) track(lock *sessionLock) {
+	held := conn.locks[:0]
+	for _, l := range conn.locks {
+		if l.held() {
+			held = append(held, l)
+		}
+	}
+
+	conn.locks = append(held, lock)
+}
+
+// releaseLocks unlocks the session advisory locks still held through the connection, so they
+// don't stay held once the pooled connection is reused.  The locks are marked released, so
+// calling Release on them afterwards does nothing.  If unlocking fails, the connection is closed
+// instead, which ends the session and its locks, and the pool discards it.
+func (conn *PinnedConn) releaseLocks(ctx context.Context) error {
+	var held bool
+	for _, lock := range conn.locks {
+		if lock.forget() {
+			held = true
+		}
+	}
+	conn.locks = nil
+
+	if !held {
+		return nil
+	}
+
+	if _, err := conn.Conn.Exec(ctx, "SELECT pg_advisory_unlock_all()"); err != nil {
+		_ = conn.Conn.Conn().Close(context.Background())
+		return err
+	}
+
+	return nil
+}
+
+// Lock creates a session-wide advisory lock on the pinned connection.  Call Release() to
+// release the advisory lock; the lock is also released when the PinnedConn is closed.
+func (conn *PinnedConn) Lock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, err := conn.Conn.Exec(ctx, "SELECT pg_advisory_lock($1)", id); err != nil {
+		return nil, err
+	}
+
+	lock := &SessionAdvisoryLock{ID: id}
+	lock.hold(conn.db, conn.Conn, false, 1)
+	conn.track(&lock.sessionLock)
+
+	return lock, nil
+}
+
+// LockAll creates session-wide advisory locks on all the IDs in a single round trip.  See
+// DB.LockAll.  The locks are also released when the PinnedConn is closed.
+func (conn *PinnedConn) LockAll(ctx context.Context, ids ...uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ids = lockOrder(ids)
+
+	if err := lockAll(ctx, conn.Conn.Conn(), "pg_advisory_lock", ids); err != nil {
+		return nil, err
+	}
+
+	locks := &SessionAdvisoryLocks{IDs: ids}
+	locks.hold(conn.db, conn.Conn, false, len(ids))
+	conn.track(&locks.sessionLock)
+
+	return locks, nil
+}
+
+// TryLock tries to create a session-wide advisory lock on the pinned connection.  If successful,
+// returns the advisory lock, which is also released when the PinnedConn is closed.  If not,
+// returns ErrLocked.
+func (conn *PinnedConn) TryLock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var available bool
+	row := conn.Conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", id)
+	if err := row.Scan(&available); err != nil {
+		return nil, err
+	}
+
+	if !available {
+		return nil, ErrLocked
+	}
+
+	lock := &SessionAdvisoryLock{ID: id}
+	lock.hold(conn.db, conn.Conn, false, 1)
+	conn.track(&lock.sessionLock)
+
+	return lock, nil
+}
+
+// SetTimeout sets the default timeout for the pinned connection.
+func (conn *PinnedConn) SetTimeout(dur time.Duration) {
+	conn.defaultTimeout = dur
+}
+
+// WithTimeout creates a context with a timeout, assigning ctx as the parent of the timeout context.
+// If ctx already has a deadline, simply returns ctx.  Defaults to a 1 second timeout.
+//
+// Be sure to call the cancel function when you're done to clean up any resources in use!
+func (conn *PinnedConn) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, fakeCancel
+	}
+
+	timeout := conn.defaultTimeout
+	if timeout == 0 {
+		timeout = time.Second
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Notify sends a notification with a JSON-encoded payload on the channel.
+func (conn *PinnedConn) Notify(ctx context.Context, channel string, payload interface{}) error {
+	return notify(ctx, conn, channel, payload)
+}
+
+// ExecNamed runs the SQL command with :name placeholders for the arguments.  See Named.
+func (conn *PinnedConn) ExecNamed(ctx context.Context, sql string, args map[string]interface{}) (pgconn.CommandTag, error) {
+	return execNamed(ctx, conn, sql, args)
+}
+
+// QueryNamed runs the SQL query with :name placeholders for the arguments.  See Named.
+func (conn *PinnedConn) QueryNamed(ctx context.Context, sql string, args map[string]interface{}) (pgx.Rows, error) {
+	return queryNamed(ctx, conn, sql, args)
+}
+
+// Prepare creates a named prepared statement on the pinned connection.  The statement stays on
+// the connection after it's returned to the pool, but won't be found on the pool's other
+// connections; see DB.Prepare for that.
+func (conn *PinnedConn) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return conn.Conn.Conn().Prepare(ctx, name, sql)
+}
+
+// Deallocate drops the named prepared statement from the pinned connection.
+func (conn *PinnedConn) Deallocate(ctx context.Context, name string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return conn.Conn.Conn().Deallocate(ctx, name)
+}
+
+// QueryCursor runs the query through a server-side cursor on the pinned connection.  See
+// DB.QueryCursor.
+func (conn *PinnedConn) QueryCursor(ctx context.Context, sql string, batchSize int, args ...interface{}) (pgx.Rows, error) {
+	return queryCursor(ctx, conn, sql, batchSize, args)
+}
+
+// Listen starts listening for notifications on the channels.  Use WaitForNotification to receive
+// them.  The connection stops listening when it's closed and returned to the pool.
+func (conn *PinnedConn) Listen(ctx context.Context, channels ...string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(channels) == 0 {
+		return nil
+	}
+
+	conn.listening = true
+
+	_, err := conn.Conn.Exec(ctx, listenSQL("LISTEN", channels))
+	return err
+}
+
+// Unlisten stops listening for notifications on the channels.
+func (conn *PinnedConn) Unlisten(ctx context.Context, channels ...string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(channels) == 0 {
+		return nil
+	}
+
+	_, err := conn.Conn.Exec(ctx, listenSQL("UNLISTEN", channels))
+	return err
+}
+
+// WaitForNotification waits for a notification on any channel the connection is listening on.
+// Notifications received while running other statements are returned immediately.
+//
+// If ctx doesn't have a deadline, waits up to the connection's default timeout (see
+// WithTimeout); pass a context with a deadline to wait longer.  If the wait times out, returns the
+// context's error and the connection remains usable.
+//
+// For anything more than a simple, one-shot wait, consider a Listener, which reconnects if the
+// connection is lost.
+func (conn *PinnedConn) WaitForNotification(ctx context.Context) (*Notification, error) {
+	ctx, cancel := conn.WithTimeout(ctx)
+	defer cancel()
+
+	n, err := conn.Conn.Conn().WaitForNotification(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notification{PID: n.PID, Channel: n.Channel, Payload: n.Payload}, nil
+}
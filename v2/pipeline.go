@@ -0,0 +1,425 @@
+package hermes
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Pipeline wraps a Conn, transparently pipelining consecutive queries to cut round trips to the
+// database.  Rows requested with QueryRow are queued rather than run, and the queued queries are
+// sent together, in a single round trip, when one of the rows is scanned or another query is run.
+// Queries run with Exec or Query are sent along with anything queued.  Queue adds a statement to
+// the pipeline without waiting for its result at all.
+//
+//	p := hermes.NewPipeline(db)
+//
+//	user := p.QueryRow(ctx, "select name from users where id = $1", userID)
+//	org := p.QueryRow(ctx, "select name from orgs where id = $1", orgID)
+//
+//	// Both queries are sent in a single round trip
+//	err := user.Scan(&userName)
+//	err = org.Scan(&orgName)
+//
+// The queries sent together run in an implicit transaction, as in a pgx.Batch:  if one fails,
+// the queries sent with it fail too.  A Pipeline isn't safe for concurrent use.
+type Pipeline struct {
+	conn    Conn
+	pending []pipelined
+}
+
+// pipelined is a query waiting to be sent.
+type pipelined struct {
+	sql  string
+	args []interface{}
+	row  *PipelineRow
+	exec *PipelineResult
+}
+
+// NewPipeline wraps the connection to pipeline its queries.
+func NewPipeline(conn Conn) *Pipeline {
+	return &Pipeline{conn: conn}
+}
+
+// Pipelined calls fn with a pipelined wrapper around conn, then sends anything left in the
+// pipeline.  Returns fn's error, or the first error from the queries left in the pipeline.
+func Pipelined(ctx context.Context, conn Conn, fn func(conn Conn) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	p := NewPipeline(conn)
+	if err := fn(p); err != nil {
+		return err
+	}
+
+	return p.Flush(ctx)
+}
+
+// Queue adds a statement to the pipeline.  It's sent with the next query that's run, or when the
+// pipeline is flushed.  Its result is available from the returned PipelineResult.
+func (p *Pipeline) Queue(sql string, args ...interface{}) *PipelineResult {
+	result := &PipelineResult{p: p}
+	p.pending = append(p.pending, pipelined{sql: sql, args: args, exec: result})
+
+	return result
+}
+
+// Pending returns the number of queries waiting to be sent.
+func (p *Pipeline) Pending() int {
+	return len(p.pending)
+}
+
+// Flush sends the queued queries.  Returns the first error from the queries.
+func (p *Pipeline) Flush(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(p.pending) == 0 {
+		return nil
+	}
+
+	br, pending := p.send(ctx, nil)
+	err := p.receive(br, pending)
+
+	if closeErr := br.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// send the queued queries along with the final query, if any.
+func (p *Pipeline) send(ctx context.Context, final *pipelined) (pgx.BatchResults, []pipelined) {
+	pending := p.pending
+	p.pending = nil
+
+	batch := &pgx.Batch{}
+	for _, query := range pending {
+		batch.Queue(query.sql, query.args...)
+	}
+
+	if final != nil {
+		batch.Queue(final.sql, final.args...)
+	}
+
+	return p.conn.SendBatch(ctx, batch), pending
+}
+
+// receive the results of the pending queries.  Returns the first error.
+func (p *Pipeline) receive(br pgx.BatchResults, pending []pipelined) error {
+	var first error
+
+	for _, query := range pending {
+		var err error
+		if query.row != nil {
+			err = query.row.receive(br)
+		} else {
+			query.exec.tag, err = br.Exec()
+			query.exec.err, query.exec.done = err, true
+		}
+
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Exec sends the statement, along with any queued queries, and returns its result.
+func (p *Pipeline) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(p.pending) == 0 {
+		return p.conn.Exec(ctx, sql, args...)
+	}
+
+	br, pending := p.send(ctx, &pipelined{sql: sql, args: args})
+	defer br.Close()
+
+	_ = p.receive(br, pending)
+
+	return br.Exec()
+}
+
+// Query sends the query, along with any queued queries, and returns its rows.
+func (p *Pipeline) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if len(p.pending) == 0 {
+		return p.conn.Query(ctx, sql, args...)
+	}
+
+	br, pending := p.send(ctx, &pipelined{sql: sql, args: args})
+	_ = p.receive(br, pending)
+
+	rows, err := br.Query()
+	if err != nil {
+		br.Close()
+		return nil, err
+	}
+
+	return &pipelineRows{Rows: rows, br: br}, nil
+}
+
+// QueryRow queues the query.  It's sent, along with any other queued queries, when the row is
+// scanned or another query is run.
+func (p *Pipeline) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	row := &PipelineRow{p: p, ctx: ctx}
+	p.pending = append(p.pending, pipelined{sql: sql, args: args, row: row})
+
+	return row
+}
+
+// SendBatch sends the batch, after sending any queued queries.
+func (p *Pipeline) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	_ = p.Flush(ctx)
+	return p.conn.SendBatch(ctx, b)
+}
+
+// CopyFrom copies the rows into the table, after sending any queued queries.
+func (p *Pipeline) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if err := p.Flush(ctx); err != nil {
+		return 0, err
+	}
+
+	return p.conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// Begin sends any queued queries, then starts a transaction, pipelining its queries as well.
+func (p *Pipeline) Begin(ctx context.Context) (Conn, error) {
+	if err := p.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	tx, err := p.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPipeline(tx), nil
+}
+
+// BeginWithTimeout sends any queued queries, then starts a custom transaction that manages the
+// timeout context for you.  The transaction's queries aren't pipelined.
+func (p *Pipeline) BeginWithTimeout(ctx context.Context) (*ContextualTx, error) {
+	if err := p.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	return p.conn.BeginWithTimeout(ctx)
+}
+
+// Commit sends any queued queries, then commits the transaction.
+func (p *Pipeline) Commit(ctx context.Context) error {
+	if err := p.Flush(ctx); err != nil {
+		return err
+	}
+
+	return p.conn.Commit(ctx)
+}
+
+// Rollback discards any queued queries and rolls back the transaction.
+func (p *Pipeline) Rollback(ctx context.Context) error {
+	p.discard()
+	return p.conn.Rollback(ctx)
+}
+
+// Close discards any queued queries and closes the connection.  See Conn.Close.
+func (p *Pipeline) Close(ctx context.Context) error {
+	p.discard()
+	return p.conn.Close(ctx)
+}
+
+// Lock sends any queued queries, then creates an advisory lock.
+func (p *Pipeline) Lock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if err := p.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	return p.conn.Lock(ctx, id)
+}
+
+// TryLock sends any queued queries, then tries to create an advisory lock.
+func (p *Pipeline) TryLock(ctx context.Context, id uint64) (AdvisoryLock, error) {
+	if err := p.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	return p.conn.TryLock(ctx, id)
+}
+
+// WithTimeout returns a timeout context from the wrapped connection.
+func (p *Pipeline) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return p.conn.WithTimeout(ctx)
+}
+
+// SetTimeout sets the wrapped connection's default timeout.
+func (p *Pipeline) SetTimeout(dur time.Duration) {
+	p.conn.SetTimeout(dur)
+}
+
+// Notify sends a notification with a JSON-encoded payload on the channel, along with any queued
+// queries.
+func (p *Pipeline) Notify(ctx context.Context, channel string, payload interface{}) error {
+	return notify(ctx, p, channel, payload)
+}
+
+// ExecNamed sends the statement with :name placeholders for the arguments, along with any queued
+// queries.  See Named.
+func (p *Pipeline) ExecNamed(ctx context.Context, sql string, args map[string]interface{}) (pgconn.CommandTag, error) {
+	return execNamed(ctx, p, sql, args)
+}
+
+// QueryNamed sends the query with :name placeholders for the arguments, along with any queued
+// queries.  See Named.
+func (p *Pipeline) QueryNamed(ctx context.Context, sql string, args map[string]interface{}) (pgx.Rows, error) {
+	return queryNamed(ctx, p, sql, args)
+}
+
+// Prepare sends any queued queries, then creates a named prepared statement on the wrapped
+// connection.
+func (p *Pipeline) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	if err := p.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	return p.conn.Prepare(ctx, name, sql)
+}
+
+// Deallocate sends any queued queries, then drops the named prepared statement from the wrapped
+// connection.
+func (p *Pipeline) Deallocate(ctx context.Context, name string) error {
+	if err := p.Flush(ctx); err != nil {
+		return err
+	}
+
+	return p.conn.Deallocate(ctx, name)
+}
+
+// QueryCursor sends any queued queries, then runs the query through a server-side cursor.  See
+// DB.QueryCursor.
+func (p *Pipeline) QueryCursor(ctx context.Context, sql string, batchSize int, args ...interface{}) (pgx.Rows, error) {
+	return queryCursor(ctx, p, sql, batchSize, args)
+}
+
+// discard the queued queries, failing them with ErrTxClosed.
+func (p *Pipeline) discard() {
+	for _, query := range p.pending {
+		if query.row != nil {
+			query.row.err, query.row.done = pgx.ErrTxClosed, true
+		} else {
+			query.exec.err, query.exec.done = pgx.ErrTxClosed, true
+		}
+	}
+
+	p.pending = nil
+}
+
+// PipelineRow is a row queued in a Pipeline.
+type PipelineRow struct {
+	p   *Pipeline
+	ctx context.Context
+
+	done   bool
+	err    error
+	m      *pgtype.Map
+	fields []pgconn.FieldDescription
+	values [][]byte
+}
+
+// Scan sends the queued queries, if the row hasn't been sent yet, then scans the row into dest.
+// Returns pgx.ErrNoRows if the query didn't return a row.
+func (row *PipelineRow) Scan(dest ...interface{}) error {
+	if !row.done {
+		_ = row.p.Flush(row.ctx)
+	}
+
+	if row.err != nil {
+		return row.err
+	}
+
+	if row.values == nil {
+		return pgx.ErrNoRows
+	}
+
+	return pgx.ScanRow(row.m, row.fields, row.values, dest...)
+}
+
+// receive the row's result, copying the first row so it can be scanned later.
+func (row *PipelineRow) receive(br pgx.BatchResults) error {
+	row.done = true
+
+	rows, err := br.Query()
+	if err != nil {
+		row.err = err
+		return err
+	}
+
+	if rows.Next() {
+		row.fields = append([]pgconn.FieldDescription(nil), rows.FieldDescriptions()...)
+
+		raw := rows.RawValues()
+		row.values = make([][]byte, len(raw))
+		for i, value := range raw {
+			if value != nil {
+				row.values[i] = append(make([]byte, 0, len(value)), value...)
+			}
+		}
+
+		if conn := rows.Conn(); conn != nil {
+			row.m = conn.TypeMap()
+		} else {
+			row.m = pgtype.NewMap()
+		}
+	}
+
+	rows.Close()
+	row.err = rows.Err()
+
+	return row.err
+}
+
+// PipelineResult is the result of a statement queued in a Pipeline.
+type PipelineResult struct {
+	p *Pipeline
+
+	done bool
+	tag  pgconn.CommandTag
+	err  error
+}
+
+// Result sends the queued queries, if the statement hasn't been sent yet, and returns the
+// statement's result.
+func (result *PipelineResult) Result(ctx context.Context) (pgconn.CommandTag, error) {
+	if !result.done {
+		_ = result.p.Flush(ctx)
+	}
+
+	return result.tag, result.err
+}
+
+// pipelineRows closes the batch the rows were sent in once they're closed.
+type pipelineRows struct {
+	pgx.Rows
+	br pgx.BatchResults
+}
+
+// Close the rows and the batch.
+func (rows *pipelineRows) Close() {
+	rows.Rows.Close()
+	rows.br.Close()
+}
@@ -0,0 +1,185 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var errBatch = errors.New("batch failed")
+
+// batchConn fakes the results of batches:  each query returns a single text row numbering the
+// results, each statement inserts a row.
+type batchConn struct {
+	Conn
+
+	sent    []int
+	results int
+	failAt  int
+}
+
+func (c *batchConn) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	c.sent = append(c.sent, b.Len())
+	return &batchResults{conn: c}
+}
+
+func (c *batchConn) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	c.sent = append(c.sent, 1)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (c *batchConn) Rollback(context.Context) error { return nil }
+
+type batchResults struct {
+	conn *batchConn
+}
+
+func (br *batchResults) next() error {
+	br.conn.results++
+	if br.conn.results == br.conn.failAt {
+		return errBatch
+	}
+	return nil
+}
+
+func (br *batchResults) Exec() (pgconn.CommandTag, error) {
+	if err := br.next(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (br *batchResults) Query() (pgx.Rows, error) {
+	if err := br.next(); err != nil {
+		return nil, err
+	}
+
+	value := fmt.Sprintf("row %d", br.conn.results)
+	return &textRows{fakeRows: fakeRows{columns: []string{"value"}, values: [][]interface{}{{value}}}}, nil
+}
+
+func (br *batchResults) QueryRow() pgx.Row { return nil }
+func (br *batchResults) Close() error      { return nil }
+
+// textRows returns the fake rows' values in the text format.
+type textRows struct {
+	fakeRows
+}
+
+func (r *textRows) FieldDescriptions() []pgconn.FieldDescription {
+	fields := r.fakeRows.FieldDescriptions()
+	for i := range fields {
+		fields[i].DataTypeOID = pgtype.TextOID
+	}
+	return fields
+}
+
+func (r *textRows) RawValues() [][]byte {
+	values := r.values[r.row-1]
+
+	raw := make([][]byte, len(values))
+	for i, value := range values {
+		raw[i] = []byte(value.(string))
+	}
+	return raw
+}
+
+func TestPipelineQueryRow(t *testing.T) {
+	conn := &batchConn{}
+	p := NewPipeline(conn)
+	ctx := context.Background()
+
+	first := p.QueryRow(ctx, "select name from users where id = $1", 1)
+	second := p.QueryRow(ctx, "select name from orgs where id = $1", 2)
+
+	if p.Pending() != 2 || len(conn.sent) != 0 {
+		t.Fatalf("Expected 2 queries queued and none sent; was %d and %v", p.Pending(), conn.sent)
+	}
+
+	var a, b string
+	if err := first.Scan(&a); err != nil {
+		t.Fatalf("Unable to scan the first row: %s", err)
+	}
+
+	if err := second.Scan(&b); err != nil {
+		t.Fatalf("Unable to scan the second row: %s", err)
+	}
+
+	if a != "row 1" || b != "row 2" {
+		t.Errorf("Expected row 1 and row 2; was %s and %s", a, b)
+	}
+
+	if len(conn.sent) != 1 || conn.sent[0] != 2 {
+		t.Errorf("Expected a single round trip with 2 queries; was %v", conn.sent)
+	}
+}
+
+func TestPipelineExec(t *testing.T) {
+	conn := &batchConn{}
+	p := NewPipeline(conn)
+	ctx := context.Background()
+
+	queued := p.Queue("insert into audit (action) values ($1)", "login")
+	row := p.QueryRow(ctx, "select name from users where id = $1", 1)
+
+	tag, err := p.Exec(ctx, "update users set seen_at = now() where id = $1", 1)
+	if err != nil {
+		t.Fatalf("Unable to exec: %s", err)
+	}
+
+	if tag.String() != "INSERT 0 1" {
+		t.Errorf("Expected the exec's command tag; was %s", tag)
+	}
+
+	if len(conn.sent) != 1 || conn.sent[0] != 3 {
+		t.Errorf("Expected a single round trip with 3 queries; was %v", conn.sent)
+	}
+
+	var name string
+	if err := row.Scan(&name); err != nil || name != "row 2" {
+		t.Errorf("Expected row 2; was %q, %v", name, err)
+	}
+
+	if _, err := queued.Result(ctx); err != nil {
+		t.Errorf("Expected the queued statement to succeed; was %s", err)
+	}
+
+	// Nothing queued, so sent directly
+	if _, err := p.Exec(ctx, "vacuum"); err != nil || len(conn.sent) != 2 {
+		t.Errorf("Expected the exec to be sent on its own; was %v, %v", conn.sent, err)
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	conn := &batchConn{failAt: 1}
+	ctx := context.Background()
+
+	var row pgx.Row
+	err := Pipelined(ctx, conn, func(conn Conn) error {
+		conn.(*Pipeline).Queue("insert into audit (action) values ($1)", "login")
+		row = conn.QueryRow(ctx, "select 1")
+		return nil
+	})
+
+	if !errors.Is(err, errBatch) {
+		t.Errorf("Expected the batch error from flushing; was %v", err)
+	}
+
+	var name string
+	if err := row.Scan(&name); err != nil {
+		t.Errorf("Expected the row to be received; was %s", err)
+	}
+
+	p := NewPipeline(conn)
+	discarded := p.QueryRow(ctx, "select 1")
+	_ = p.Rollback(ctx)
+
+	if err := discarded.Scan(&name); !errors.Is(err, pgx.ErrTxClosed) {
+		t.Errorf("Expected ErrTxClosed for a discarded row; was %v", err)
+	}
+}
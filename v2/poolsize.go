@@ -0,0 +1,246 @@
+package hermes
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// How often the PoolSizer adjusts the pool by default.
+	defaultSizerInterval = 10 * time.Second
+
+	// The average time a query may wait for a connection before the PoolSizer grows the pool,
+	// by default.
+	defaultTargetWait = 10 * time.Millisecond
+)
+
+// PoolSizer adjusts the number of connections a DB uses, between Min and Max, based on how long
+// queries wait for a connection and how many connections the database server has to spare.  When
+// queries wait longer than TargetWait on average, the PoolSizer grows the pool by a quarter, as far
+// as the server's headroom allows; when queries don't wait and less than half the pool is in use,
+// it shrinks the pool by an eighth.  If the server runs short of connections, the pool shrinks to
+// give them back.
+//
+// A pgxpool.Pool's MaxConns can't be changed once the pool is created, so the PoolSizer sizes the
+// pool through the DB's concurrency limit instead (see DB.LimitConcurrency).  Configure the pool's
+// MaxConns as the upper bound; connections idle beyond the current size are closed after the
+// pool's MaxConnIdleTime.
+//
+// Configure the PoolSizer's fields before calling Run.
+type PoolSizer struct {
+	// Min is the fewest connections the pool shrinks to.  Defaults to the pool's MinConns, or 1.
+	Min int
+
+	// Max is the most connections the pool grows to.  Defaults to the pool's MaxConns.
+	Max int
+
+	// Interval is how often the PoolSizer adjusts the pool.  Defaults to 10 seconds.
+	Interval time.Duration
+
+	// TargetWait is the average time a query may wait for a connection before the pool grows.
+	// Defaults to 10ms.
+	TargetWait time.Duration
+
+	// Reserve is the number of connections to leave free on the database server for other
+	// clients, in addition to the superuser reserved connections.
+	Reserve int
+
+	// OnResize is called when the PoolSizer changes the size of the pool, for logging.
+	OnResize func(from, to int)
+
+	// OnError is called when the PoolSizer can't check the server's connections, for logging.
+	OnError func(err error)
+
+	db *DB
+
+	mutex sync.Mutex
+	size  int
+	last  waitSample
+}
+
+// waitSample is a snapshot of the cumulative wait counters of the DB's limiter and pool.
+type waitSample struct {
+	acquired     int64
+	waitDuration time.Duration
+
+	poolAcquired int64
+	poolWait     time.Duration
+}
+
+// NewPoolSizer creates a PoolSizer for the DB, bounded by the pool's MinConns and MaxConns.  If
+// the DB's concurrency isn't already limited, limits it to MaxConns.
+func NewPoolSizer(db *DB) *PoolSizer {
+	config := db.Config()
+
+	minConns := int(config.MinConns)
+	if minConns < 1 {
+		minConns = 1
+	}
+
+	maxConns := int(config.MaxConns)
+
+	if db.limiter == nil {
+		db.LimitConcurrency(ConcurrencyLimit{MaxInFlight: maxConns})
+	}
+
+	return &PoolSizer{
+		Min:  minConns,
+		Max:  maxConns,
+		db:   db,
+		size: maxConns,
+	}
+}
+
+// Size returns the current size of the pool.
+func (s *PoolSizer) Size() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.size
+}
+
+// Run adjusts the pool on each interval until ctx is done.
+func (s *PoolSizer) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultSizerInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Adjust(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Adjust resizes the pool based on the waits observed since the last call.  Run calls this on
+// each interval; call it directly to adjust on your own schedule.  Returns the new size.
+func (s *PoolSizer) Adjust(ctx context.Context) int {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	stats := s.db.ConcurrencyStats()
+	pool := s.db.Stat()
+
+	sample := waitSample{
+		acquired:     stats.Acquired,
+		waitDuration: stats.WaitDuration,
+		poolAcquired: pool.AcquireCount(),
+		poolWait:     pool.AcquireDuration(),
+	}
+
+	headroom, err := s.headroom(ctx)
+	if err != nil {
+		s.report(err)
+
+		// Without the server's numbers, don't grow, but still shrink if idle
+		headroom = 0
+	}
+
+	s.mutex.Lock()
+	wait := sample.since(s.last)
+	s.last = sample
+
+	from := s.size
+	s.size = s.next(from, wait, stats.InFlight, headroom)
+	to := s.size
+	s.mutex.Unlock()
+
+	if to != from {
+		s.db.limiter.resize(to)
+
+		if s.OnResize != nil {
+			s.OnResize(from, to)
+		}
+	}
+
+	return to
+}
+
+// next returns the new size of the pool, given its current size, the average wait for a
+// connection, the number of connections in use, and the number of connections the server can
+// spare.
+func (s *PoolSizer) next(size int, wait time.Duration, inFlight, headroom int) int {
+	target := s.TargetWait
+	if target <= 0 {
+		target = defaultTargetWait
+	}
+
+	switch {
+	case headroom < 0:
+		size += headroom
+	case wait > target:
+		grow := size / 4
+		if grow < 1 {
+			grow = 1
+		}
+		if grow > headroom {
+			grow = headroom
+		}
+		size += grow
+	case wait < target/4 && inFlight < size/2:
+		shrink := size / 8
+		if shrink < 1 {
+			shrink = 1
+		}
+		size -= shrink
+	}
+
+	if size > s.Max {
+		size = s.Max
+	}
+
+	if size < s.Min {
+		size = s.Min
+	}
+
+	return size
+}
+
+// headroom returns the number of connections the server can spare, less the Reserve.  Queries
+// the pool directly, so the check isn't held up by the concurrency limit.
+func (s *PoolSizer) headroom(ctx context.Context) (int, error) {
+	var free int
+
+	row := s.db.Pool.QueryRow(ctx, `
+		SELECT current_setting('max_connections')::int
+			- current_setting('superuser_reserved_connections')::int
+			- (SELECT count(*) FROM pg_stat_activity)::int`)
+	if err := row.Scan(&free); err != nil {
+		return 0, err
+	}
+
+	return free - s.Reserve, nil
+}
+
+// report the error to OnError, if set.
+func (s *PoolSizer) report(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// since returns the average time a query waited for a connection between the two samples,
+// combining the wait for a concurrency slot with the wait on the pool.
+func (sample waitSample) since(last waitSample) time.Duration {
+	var wait time.Duration
+
+	if acquired := sample.acquired - last.acquired; acquired > 0 {
+		wait += (sample.waitDuration - last.waitDuration) / time.Duration(acquired)
+	}
+
+	if acquired := sample.poolAcquired - last.poolAcquired; acquired > 0 {
+		wait += (sample.poolWait - last.poolWait) / time.Duration(acquired)
+	}
+
+	return wait
+}
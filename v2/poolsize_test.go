@@ -0,0 +1,53 @@
+package hermes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPoolSizerNext(t *testing.T) {
+	s := &PoolSizer{Min: 2, Max: 20, TargetWait: 10 * time.Millisecond}
+
+	tests := []struct {
+		name     string
+		size     int
+		wait     time.Duration
+		inFlight int
+		headroom int
+		expected int
+	}{
+		{"grows when queries wait", 8, 50 * time.Millisecond, 8, 100, 10},
+		{"grows by at least one", 2, 50 * time.Millisecond, 2, 100, 3},
+		{"grows within headroom", 16, 50 * time.Millisecond, 16, 1, 17},
+		{"grows no further than Max", 19, 50 * time.Millisecond, 19, 100, 20},
+		{"holds when busy without waiting", 8, time.Millisecond, 6, 100, 8},
+		{"holds while waits are near the target", 8, 5 * time.Millisecond, 2, 100, 8},
+		{"shrinks when idle", 16, 0, 3, 100, 14},
+		{"shrinks no further than Min", 2, 0, 0, 100, 2},
+		{"gives back connections the server needs", 10, 50 * time.Millisecond, 10, -3, 7},
+	}
+
+	for _, test := range tests {
+		if size := s.next(test.size, test.wait, test.inFlight, test.headroom); size != test.expected {
+			t.Errorf("%s: expected %d; was %d", test.name, test.expected, size)
+		}
+	}
+}
+
+func TestWaitSampleSince(t *testing.T) {
+	last := waitSample{acquired: 10, waitDuration: time.Second, poolAcquired: 10, poolWait: time.Second}
+	sample := waitSample{
+		acquired:     20,
+		waitDuration: time.Second + 100*time.Millisecond,
+		poolAcquired: 30,
+		poolWait:     time.Second + 40*time.Millisecond,
+	}
+
+	if wait := sample.since(last); wait != 12*time.Millisecond {
+		t.Errorf("Expected 12ms; was %s", wait)
+	}
+
+	if wait := last.since(last); wait != 0 {
+		t.Errorf("Expected no wait without queries; was %s", wait)
+	}
+}
@@ -0,0 +1,165 @@
+package hermes
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/ewkb"
+)
+
+// geomType is the geom.T interface type.
+var geomType = reflect.TypeOf((*geom.T)(nil)).Elem()
+
+// UsePostGIS registers codecs on every connection for the PostGIS geometry and geography types,
+// encoding and scanning github.com/twpayne/go-geom geometries as extended well-known binary
+// (EWKB), SRIDs included.  Columns may be scanned into a geom.T or a specific geometry, such as a
+// *geom.Point; geometries passed as arguments are encoded as geometry by default.  If PostGIS
+// isn't installed in the database, the types are skipped.  Best to call this before calling
+// Connect.
+func UsePostGIS() {
+	register(dataType{
+		name:  "geometry",
+		codec: geomCodec{},
+		values: []interface{}{
+			&geom.Point{}, &geom.LineString{}, &geom.Polygon{}, &geom.MultiPoint{},
+			&geom.MultiLineString{}, &geom.MultiPolygon{}, &geom.GeometryCollection{},
+		},
+		optional: true,
+	})
+
+	register(dataType{
+		name:     "geography",
+		codec:    geomCodec{},
+		optional: true,
+	})
+}
+
+// geomCodec encodes and decodes go-geom geometries as EWKB:  raw in the binary format, and
+// hex-encoded in the text format, as PostGIS outputs it.
+type geomCodec struct{}
+
+func (geomCodec) FormatSupported(format int16) bool {
+	return format == pgtype.BinaryFormatCode || format == pgtype.TextFormatCode
+}
+
+func (geomCodec) PreferredFormat() int16 {
+	return pgtype.BinaryFormatCode
+}
+
+func (geomCodec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value interface{}) pgtype.EncodePlan {
+	if _, ok := value.(geom.T); !ok {
+		return nil
+	}
+
+	return &geomEncodePlan{format: format}
+}
+
+func (geomCodec) PlanScan(m *pgtype.Map, oid uint32, format int16, target interface{}) pgtype.ScanPlan {
+	if _, ok := target.(*geom.T); ok {
+		return &geomScanPlan{format: format}
+	}
+
+	// A specific geometry, e.g. a *geom.Point scanned into with Scan(point)
+	t := reflect.TypeOf(target)
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct && t.Implements(geomType) {
+		return &geomScanPlan{format: format}
+	}
+
+	return nil
+}
+
+func (c geomCodec) DecodeDatabaseSQLValue(m *pgtype.Map, oid uint32, format int16, src []byte) (driver.Value, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	if format == pgtype.TextFormatCode {
+		return string(src), nil
+	}
+
+	buf := make([]byte, len(src))
+	copy(buf, src)
+
+	return buf, nil
+}
+
+func (c geomCodec) DecodeValue(m *pgtype.Map, oid uint32, format int16, src []byte) (interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	return decodeGeom(format, src)
+}
+
+type geomEncodePlan struct {
+	format int16
+}
+
+func (plan *geomEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	data, err := ewkb.Marshal(value.(geom.T), ewkb.NDR)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.format == pgtype.TextFormatCode {
+		return append(buf, hex.EncodeToString(data)...), nil
+	}
+
+	return append(buf, data...), nil
+}
+
+type geomScanPlan struct {
+	format int16
+}
+
+func (plan *geomScanPlan) Scan(src []byte, target interface{}) error {
+	if t, ok := target.(*geom.T); ok {
+		if src == nil {
+			*t = nil
+			return nil
+		}
+
+		g, err := decodeGeom(plan.format, src)
+		if err != nil {
+			return err
+		}
+
+		*t = g
+		return nil
+	}
+
+	if src == nil {
+		return fmt.Errorf("cannot scan NULL into %T", target)
+	}
+
+	g, err := decodeGeom(plan.format, src)
+	if err != nil {
+		return err
+	}
+
+	dst := reflect.ValueOf(target)
+	value := reflect.ValueOf(g)
+	if value.Type() != dst.Type() {
+		return fmt.Errorf("cannot scan %T into %T", g, target)
+	}
+
+	dst.Elem().Set(value.Elem())
+	return nil
+}
+
+// decodeGeom unmarshals the EWKB geometry, hex-decoding it first if it's in the text format.
+func decodeGeom(format int16, src []byte) (geom.T, error) {
+	if format == pgtype.TextFormatCode {
+		data := make([]byte, hex.DecodedLen(len(src)))
+		if _, err := hex.Decode(data, src); err != nil {
+			return nil, fmt.Errorf("invalid geometry: %w", err)
+		}
+		src = data
+	}
+
+	return ewkb.Unmarshal(src)
+}
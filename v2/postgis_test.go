@@ -0,0 +1,110 @@
+package hermes
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/twpayne/go-geom"
+)
+
+const geometryOID = 100001
+
+// pointHex is SELECT 'SRID=4326;POINT(1 2)'::geometry, as output by PostGIS.
+const pointHex = "0101000020E6100000000000000000F03F0000000000000040"
+
+func newGeomMap(t *testing.T) *pgtype.Map {
+	resetTypes(t)
+	UsePostGIS()
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], geomCodec{}, geometryOID, geometryOID+1)
+
+	return m
+}
+
+func TestGeometry(t *testing.T) {
+	m := newGeomMap(t)
+
+	point := geom.NewPointFlat(geom.XY, []float64{1, 2}).SetSRID(4326)
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(geometryOID, format, point, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode point in format %d: %s", format, err)
+		}
+
+		var g geom.T
+		if err := m.Scan(geometryOID, format, buf, &g); err != nil {
+			t.Fatalf("Unable to scan point into geom.T in format %d: %s", format, err)
+		}
+
+		check, ok := g.(*geom.Point)
+		if !ok {
+			t.Fatalf("Expected *geom.Point; was %T", g)
+		}
+
+		if check.X() != 1 || check.Y() != 2 || check.SRID() != 4326 {
+			t.Errorf("Expected POINT(1 2) with SRID 4326; was %v with SRID %d", check.Coords(), check.SRID())
+		}
+
+		var p geom.Point
+		if err := m.Scan(geometryOID, format, buf, &p); err != nil {
+			t.Fatalf("Unable to scan point into geom.Point in format %d: %s", format, err)
+		}
+
+		if p.X() != 1 || p.Y() != 2 {
+			t.Errorf("Expected POINT(1 2); was %v", p.Coords())
+		}
+
+		var line geom.LineString
+		if err := m.Scan(geometryOID, format, buf, &line); err == nil {
+			t.Error("Expected error scanning a point into a line string")
+		}
+	}
+}
+
+func TestGeometryText(t *testing.T) {
+	m := newGeomMap(t)
+
+	var p *geom.Point
+	if err := m.Scan(geometryOID, pgtype.TextFormatCode, []byte(pointHex), &p); err != nil {
+		t.Fatalf("Unable to scan PostGIS point: %s", err)
+	}
+
+	if p == nil || p.X() != 1 || p.Y() != 2 || p.SRID() != 4326 {
+		t.Errorf("Expected POINT(1 2) with SRID 4326; was %v", p)
+	}
+
+	if err := m.Scan(geometryOID, pgtype.TextFormatCode, nil, &p); err != nil {
+		t.Fatalf("Unable to scan NULL into *geom.Point: %s", err)
+	}
+
+	if p != nil {
+		t.Errorf("Expected NULL to scan as nil; was %v", p)
+	}
+}
+
+func TestGeometryDefault(t *testing.T) {
+	m := newGeomMap(t)
+
+	dt, ok := m.TypeForValue(&geom.Polygon{})
+	if !ok || dt.OID != geometryOID {
+		t.Error("Expected *geom.Polygon to map to the geometry type")
+	}
+
+	dt, ok = m.TypeForValue([]*geom.Point{})
+	if !ok || dt.OID != geometryOID+1 {
+		t.Error("Expected []*geom.Point to map to the geometry array type")
+	}
+
+	decoded, err := dt.Codec.DecodeValue(m, geometryOID+1, pgtype.TextFormatCode, []byte("{"+pointHex+"}"))
+	if err != nil {
+		t.Fatalf("Unable to decode geometry array: %s", err)
+	}
+
+	if values, ok := decoded.([]interface{}); !ok || len(values) != 1 {
+		t.Errorf("Expected one geometry; was %#v", decoded)
+	} else if _, ok := values[0].(*geom.Point); !ok {
+		t.Errorf("Expected *geom.Point; was %T", values[0])
+	}
+}
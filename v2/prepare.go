@@ -0,0 +1,217 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrPrepareUnavailable is returned by DB.Prepare and DB.Deallocate if the DB wasn't created with
+// Connect or ConnectConfig, which install the connection hooks that prepare the statements.
+var ErrPrepareUnavailable = errors.New("prepared statements require a DB from Connect or ConnectConfig")
+
+// Prepare creates a named prepared statement on every connection in the pool.  The statement is
+// prepared on the idle connections immediately, on connections in use the next time they're
+// acquired, and on new connections as they're opened.  Run the statement by passing its name in
+// place of the SQL:
+//
+//	if _, err := db.Prepare(ctx, "user_by_email", "SELECT id, name FROM users WHERE email = $1"); err != nil {
+//		return err
+//	}
+//
+//	row := db.QueryRow(ctx, "user_by_email", email)
+//
+// Preparing a name again with different SQL replaces the statement.  Returns the statement's
+// description, or the error preparing it, in which case it isn't registered.  Returns
+// ErrPrepareUnavailable if the DB wasn't created with Connect or ConnectConfig.
+func (db *DB) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if db.stmtCache == nil {
+		return nil, ErrPrepareUnavailable
+	}
+
+	previous, replaced := db.stmtCache.register(name, sql)
+
+	conn, err := db.Acquire(db.timeAcquire(ctx))
+	if err != nil {
+		db.stmtCache.restore(name, previous, replaced)
+		return nil, err
+	}
+	defer conn.Release()
+
+	if err := db.stmtCache.prepare(ctx, conn.Conn()); err != nil {
+		db.stmtCache.restore(name, previous, replaced)
+		return nil, err
+	}
+
+	// Already prepared, so this only looks up the description
+	sd, err := conn.Conn().Prepare(ctx, name, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	db.refreshIdle(ctx)
+
+	return sd, nil
+}
+
+// Deallocate drops the named statement created with Prepare from every connection in the pool:
+// idle connections immediately, and connections in use the next time they're acquired.  Does
+// nothing if there's no statement by that name.
+func (db *DB) Deallocate(ctx context.Context, name string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if db.stmtCache == nil {
+		return ErrPrepareUnavailable
+	}
+
+	if !db.stmtCache.unregister(name) {
+		return nil
+	}
+
+	db.refreshIdle(ctx)
+
+	return ctx.Err()
+}
+
+// refreshIdle acquires and releases the idle connections, bringing their prepared statements up
+// to date.
+func (db *DB) refreshIdle(ctx context.Context) {
+	for _, conn := range db.Pool.AcquireAllIdle(ctx) {
+		conn.Release()
+	}
+}
+
+// Prepare creates a named prepared statement on the transaction's connection.  Prepared
+// statements belong to the connection rather than the transaction, so the statement outlives the
+// transaction, but won't be found on the pool's other connections; see DB.Prepare for that.
+func (tx *Tx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return tx.Tx.Prepare(ctx, name, sql)
+}
+
+// Deallocate drops the named statement created with Tx.Prepare from the transaction's
+// connection.
+func (tx *Tx) Deallocate(ctx context.Context, name string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return tx.Tx.Conn().Deallocate(ctx, name)
+}
+
+// register adds the named statement for every connection to prepare.  Returns the SQL it
+// replaced, if any.
+func (s *statementCache) register(name, sql string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	previous, replaced := s.statements[name]
+	s.statements[name] = sql
+	s.version++
+
+	return previous, replaced
+}
+
+// restore puts back the named statement that register replaced, after failing to prepare the
+// new one.
+func (s *statementCache) restore(name, previous string, replaced bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if replaced {
+		s.statements[name] = previous
+	} else {
+		delete(s.statements, name)
+	}
+	s.version++
+}
+
+// unregister removes the named statement for every connection to deallocate.  Returns false if
+// there's no statement by that name.
+func (s *statementCache) unregister(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.statements[name]; !ok {
+		return false
+	}
+
+	delete(s.statements, name)
+	s.version++
+
+	return true
+}
+
+// prepare brings the connection's DB.Prepare statements up to date, deallocating those removed
+// or changed and preparing those added.  Returns the first error; the statements that failed are
+// tried again the next time.
+func (s *statementCache) prepare(ctx context.Context, conn *pgx.Conn) error {
+	s.mutex.Lock()
+	cc := s.conn(conn)
+	if cc.version == s.version {
+		s.mutex.Unlock()
+		return nil
+	}
+
+	version := s.version
+	current := cc.prepared
+
+	statements := make(map[string]string, len(s.statements))
+	for name, sql := range s.statements {
+		statements[name] = sql
+	}
+	s.mutex.Unlock()
+
+	var failed error
+	prepared := make(map[string]string, len(statements))
+
+	for name, sql := range current {
+		if statements[name] == sql {
+			prepared[name] = sql
+			continue
+		}
+
+		if err := conn.Deallocate(ctx, name); err != nil {
+			prepared[name] = sql
+			if failed == nil {
+				failed = fmt.Errorf("unable to deallocate %s: %w", name, err)
+			}
+		}
+	}
+
+	for name, sql := range statements {
+		if _, ok := prepared[name]; ok {
+			continue
+		}
+
+		if _, err := conn.Prepare(ctx, name, sql); err != nil {
+			if failed == nil {
+				failed = fmt.Errorf("unable to prepare %s: %w", name, err)
+			}
+			continue
+		}
+
+		prepared[name] = sql
+	}
+
+	s.mutex.Lock()
+	cc.prepared = prepared
+	if failed == nil {
+		cc.version = version
+	}
+	s.mutex.Unlock()
+
+	return failed
+}
@@ -0,0 +1,90 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestPrepareUnavailable(t *testing.T) {
+	db := &DB{}
+
+	if _, err := db.Prepare(context.Background(), "one", "select 1"); !errors.Is(err, ErrPrepareUnavailable) {
+		t.Errorf("Expected ErrPrepareUnavailable; was %v", err)
+	}
+
+	if err := db.Deallocate(context.Background(), "one"); !errors.Is(err, ErrPrepareUnavailable) {
+		t.Errorf("Expected ErrPrepareUnavailable; was %v", err)
+	}
+}
+
+func TestPreparedStatements(t *testing.T) {
+	s, _ := newTestStatementCache(t, 10)
+
+	// Nothing to prepare, so the connection isn't touched
+	if err := s.prepare(context.Background(), nil); err != nil {
+		t.Fatalf("Expected nothing to prepare; was %s", err)
+	}
+
+	if _, replaced := s.register("one", "select 1"); replaced {
+		t.Error("Expected a new statement")
+	}
+
+	previous, replaced := s.register("one", "select 2")
+	if !replaced || previous != "select 1" {
+		t.Errorf("Expected to replace select 1; was %q", previous)
+	}
+
+	s.restore("one", previous, replaced)
+
+	if sql := s.statements["one"]; sql != "select 1" {
+		t.Errorf("Expected select 1 restored; was %q", sql)
+	}
+
+	s.mutex.Lock()
+	cc := s.conn(nil)
+	stale := cc.version != s.version
+	s.mutex.Unlock()
+
+	if !stale {
+		t.Error("Expected the connection's statements to be out of date")
+	}
+
+	if !s.unregister("one") {
+		t.Error("Expected the statement to be removed")
+	}
+
+	if s.unregister("one") {
+		t.Error("Expected the statement to be gone")
+	}
+
+	s.register("two", "select 2")
+	s.restore("two", "", false)
+
+	if len(s.statements) != 0 {
+		t.Errorf("Expected no statements; was %v", s.statements)
+	}
+}
+
+func TestPrepareUnreachable(t *testing.T) {
+	config, err := pgxpool.ParseConfig(unreachableURI)
+	if err != nil {
+		t.Fatalf("Unable to parse the configuration: %s", err)
+	}
+
+	db, err := ConnectConfig(config)
+	if err != nil {
+		t.Fatalf("Unable to create the pool: %s", err)
+	}
+	defer db.Shutdown()
+
+	if _, err := db.Prepare(context.Background(), "one", "select 1"); err == nil {
+		t.Error("Expected an error without a database")
+	}
+
+	if len(db.stmtCache.statements) != 0 {
+		t.Errorf("Expected the failed statement not to be registered; was %v", db.stmtCache.statements)
+	}
+}
@@ -0,0 +1,268 @@
+// Package prometheus collects a hermes DB's timings as Prometheus histograms.  Metrics is a
+// prometheus.Collector; register it with a registry, e.g. prometheus.MustRegister(metrics), or
+// serve it on its own path with ServeHTTP.
+//
+// With the default "hermes" namespace, the histograms are:
+//
+//	hermes_query_duration_seconds{operation, result}    each statement, by SQL command
+//	hermes_tx_commit_duration_seconds{result}           each transaction commit
+//	hermes_pool_acquire_duration_seconds                each wait for a pool connection
+//
+// And the counter:
+//
+//	hermes_reconnects_total{operation}                  statements retried after a disconnect
+//
+// Once the DB publishes its stats with DB.PublishStats, the pool's gauges and counters, e.g.
+// hermes_pool_acquired_conns and hermes_pool_acquires_total, and the DB's
+// hermes_tx_begun_total, hermes_tx_committed_total, hermes_tx_rolled_back_total, hermes_tx_open,
+// hermes_locks_held, and hermes_retries_total are collected as well.
+//
+// The operation label is the statement's command, one of select, insert, update, delete, or other,
+// to keep the number of series small.  The result label is "success" or "error".
+package prometheus
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// DefaultNamespace prefixes the metric names by default.
+const DefaultNamespace = "hermes"
+
+// DefaultBuckets are the upper bounds of the histogram buckets, in seconds, by default.  They're
+// the Prometheus client's default buckets.
+var DefaultBuckets = prom.DefBuckets
+
+// Options configure the Metrics.
+type Options struct {
+	// Namespace prefixes the metric names.  Defaults to DefaultNamespace.
+	Namespace string
+
+	// Buckets are the upper bounds of the histogram buckets, in seconds, in increasing order.
+	// Defaults to DefaultBuckets.
+	Buckets []float64
+}
+
+// Metrics implements hermes.Metrics, collecting the timings as histograms.  It's a
+// prometheus.Collector, so register it with a registry, or serve it on its own with ServeHTTP,
+// e.g. http.Handle("/metrics/db", metrics).
+type Metrics struct {
+	queries    *prom.HistogramVec
+	commits    *prom.HistogramVec
+	acquire    prom.Histogram
+	reconnects *prom.CounterVec
+
+	stats []stat
+
+	mutex  sync.Mutex
+	latest *hermes.Stats
+
+	handler http.Handler
+}
+
+var (
+	_ hermes.Metrics = (*Metrics)(nil)
+	_ prom.Collector = (*Metrics)(nil)
+)
+
+// stat describes one of the DB's stats, and how to read it.
+type stat struct {
+	desc      *prom.Desc
+	valueType prom.ValueType
+	value     func(stats *hermes.Stats) float64
+}
+
+// New creates the Metrics.  Set them on a DB with DB.SetMetrics.
+func New(opts Options) *Metrics {
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	buckets := opts.Buckets
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	m := &Metrics{
+		queries: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "query_duration_seconds",
+			Help:      "Time taken by each statement, by SQL command.",
+			Buckets:   buckets,
+		}, []string{"operation", "result"}),
+		commits: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tx_commit_duration_seconds",
+			Help:      "Time taken to commit each transaction.",
+			Buckets:   buckets,
+		}, []string{"result"}),
+		acquire: prom.NewHistogram(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "pool_acquire_duration_seconds",
+			Help:      "Time spent waiting for a connection from the pool.",
+			Buckets:   buckets,
+		}),
+		reconnects: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconnects_total",
+			Help:      "Statements retried after a disconnect.",
+		}, []string{"operation"}),
+		stats: newStats(namespace),
+	}
+
+	registry := prom.NewRegistry()
+	registry.MustRegister(m)
+	m.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+
+	return m
+}
+
+// newStats describes the DB's stats as gauges and counters.
+func newStats(namespace string) []stat {
+	gauge := func(name, help string, value func(stats *hermes.Stats) float64) stat {
+		return stat{prom.NewDesc(prom.BuildFQName(namespace, "", name), help, nil, nil), prom.GaugeValue, value}
+	}
+
+	counter := func(name, help string, value func(stats *hermes.Stats) float64) stat {
+		return stat{prom.NewDesc(prom.BuildFQName(namespace, "", name), help, nil, nil), prom.CounterValue, value}
+	}
+
+	return []stat{
+		gauge("pool_acquired_conns", "Connections currently in use.",
+			func(s *hermes.Stats) float64 { return float64(s.AcquiredConns) }),
+		gauge("pool_idle_conns", "Connections currently idle.",
+			func(s *hermes.Stats) float64 { return float64(s.IdleConns) }),
+		gauge("pool_constructing_conns", "Connections currently being opened.",
+			func(s *hermes.Stats) float64 { return float64(s.ConstructingConns) }),
+		gauge("pool_total_conns", "Connections currently open or being opened.",
+			func(s *hermes.Stats) float64 { return float64(s.TotalConns) }),
+		gauge("pool_max_conns", "Most connections the pool will open.",
+			func(s *hermes.Stats) float64 { return float64(s.MaxConns) }),
+		gauge("tx_open", "Transactions currently open.",
+			func(s *hermes.Stats) float64 { return float64(s.TxOpen) }),
+		gauge("locks_held", "Session-wide advisory locks currently held.",
+			func(s *hermes.Stats) float64 { return float64(s.LocksHeld) }),
+
+		counter("pool_acquires_total", "Connections acquired from the pool.",
+			func(s *hermes.Stats) float64 { return float64(s.AcquireCount) }),
+		counter("pool_canceled_acquires_total", "Waits for a connection canceled by their context.",
+			func(s *hermes.Stats) float64 { return float64(s.CanceledAcquireCount) }),
+		counter("pool_empty_acquires_total", "Connections acquired after waiting on an empty pool.",
+			func(s *hermes.Stats) float64 { return float64(s.EmptyAcquireCount) }),
+		counter("pool_new_conns_total", "Connections opened.",
+			func(s *hermes.Stats) float64 { return float64(s.NewConnsCount) }),
+		counter("pool_max_lifetime_destroys_total", "Connections closed for exceeding their maximum lifetime.",
+			func(s *hermes.Stats) float64 { return float64(s.MaxLifetimeDestroyCount) }),
+		counter("pool_max_idle_destroys_total", "Connections closed for exceeding their maximum idle time.",
+			func(s *hermes.Stats) float64 { return float64(s.MaxIdleDestroyCount) }),
+		counter("pool_acquire_wait_seconds_total", "Total time spent waiting for connections from the pool.",
+			func(s *hermes.Stats) float64 { return s.AcquireDuration.Seconds() }),
+		counter("tx_begun_total", "Transactions started.",
+			func(s *hermes.Stats) float64 { return float64(s.TxBegun) }),
+		counter("tx_committed_total", "Transactions committed.",
+			func(s *hermes.Stats) float64 { return float64(s.TxCommitted) }),
+		counter("tx_rolled_back_total", "Transactions rolled back, including failed commits.",
+			func(s *hermes.Stats) float64 { return float64(s.TxRolledBack) }),
+		counter("retries_total", "Transactions and statements retried.",
+			func(s *hermes.Stats) float64 { return float64(s.Retries) }),
+	}
+}
+
+// ObserveQuery implements hermes.Metrics.
+func (m *Metrics) ObserveQuery(sql string, duration time.Duration, err error) {
+	m.queries.WithLabelValues(operation(sql), result(err)).Observe(duration.Seconds())
+}
+
+// ObserveTxCommit implements hermes.Metrics.
+func (m *Metrics) ObserveTxCommit(duration time.Duration, err error) {
+	m.commits.WithLabelValues(result(err)).Observe(duration.Seconds())
+}
+
+// ObservePoolAcquire implements hermes.Metrics.
+func (m *Metrics) ObservePoolAcquire(duration time.Duration) {
+	m.acquire.Observe(duration.Seconds())
+}
+
+// ObserveReconnect implements hermes.Metrics.
+func (m *Metrics) ObserveReconnect(sql string, _ int, _ error) {
+	m.reconnects.WithLabelValues(operation(sql)).Inc()
+}
+
+// ObserveStats implements hermes.Metrics, keeping the latest stats to collect.
+func (m *Metrics) ObserveStats(stats hermes.Stats) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.latest = &stats
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prom.Desc) {
+	m.queries.Describe(ch)
+	m.commits.Describe(ch)
+	m.acquire.Describe(ch)
+	m.reconnects.Describe(ch)
+
+	for _, s := range m.stats {
+		ch <- s.desc
+	}
+}
+
+// Collect implements prometheus.Collector.  The DB's stats are only collected once they've been
+// observed.
+func (m *Metrics) Collect(ch chan<- prom.Metric) {
+	m.queries.Collect(ch)
+	m.commits.Collect(ch)
+	m.acquire.Collect(ch)
+	m.reconnects.Collect(ch)
+
+	m.mutex.Lock()
+	latest := m.latest
+	m.mutex.Unlock()
+
+	if latest == nil {
+		return
+	}
+
+	for _, s := range m.stats {
+		ch <- prom.MustNewConstMetric(s.desc, s.valueType, s.value(latest))
+	}
+}
+
+// ServeHTTP serves just these metrics, in whichever exposition format the scraper asks for.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.handler.ServeHTTP(w, r)
+}
+
+// operation returns the SQL command of the statement, for the operation label.
+func operation(sql string) string {
+	sql = strings.TrimLeft(sql, " \t\r\n(")
+
+	end := strings.IndexAny(sql, " \t\r\n(;")
+	if end < 0 {
+		end = len(sql)
+	}
+
+	switch command := strings.ToLower(sql[:end]); command {
+	case "select", "insert", "update", "delete":
+		return command
+	default:
+		return "other"
+	}
+}
+
+// result returns the result label for the error.
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "success"
+}
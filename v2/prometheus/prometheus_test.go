@@ -0,0 +1,150 @@
+package prometheus
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New(Options{Buckets: []float64{0.01, 0.1}})
+
+	m.ObserveQuery("SELECT * FROM accounts", 5*time.Millisecond, nil)
+	m.ObserveQuery("  select 1", 50*time.Millisecond, nil)
+	m.ObserveQuery("insert into accounts values ($1)", time.Second, errors.New("failed"))
+	m.ObserveQuery("vacuum", time.Millisecond, nil)
+	m.ObserveTxCommit(2*time.Millisecond, nil)
+	m.ObservePoolAcquire(time.Millisecond)
+	m.ObserveReconnect("select 1", 1, errors.New("disconnected"))
+	m.ObserveReconnect("select 2", 2, errors.New("disconnected"))
+
+	out := scrape(t, m)
+
+	expected := []string{
+		"# TYPE hermes_query_duration_seconds histogram",
+		`hermes_query_duration_seconds_bucket{operation="select",result="success",le="0.01"} 1`,
+		`hermes_query_duration_seconds_bucket{operation="select",result="success",le="0.1"} 2`,
+		`hermes_query_duration_seconds_bucket{operation="select",result="success",le="+Inf"} 2`,
+		`hermes_query_duration_seconds_sum{operation="select",result="success"} 0.055`,
+		`hermes_query_duration_seconds_count{operation="select",result="success"} 2`,
+		`hermes_query_duration_seconds_bucket{operation="insert",result="error",le="0.1"} 0`,
+		`hermes_query_duration_seconds_bucket{operation="insert",result="error",le="+Inf"} 1`,
+		`hermes_query_duration_seconds_count{operation="other",result="success"} 1`,
+		`hermes_tx_commit_duration_seconds_count{result="success"} 1`,
+		`hermes_pool_acquire_duration_seconds_bucket{le="0.01"} 1`,
+		"hermes_pool_acquire_duration_seconds_count 1",
+		"# TYPE hermes_reconnects_total counter",
+		`hermes_reconnects_total{operation="select"} 2`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected %q in the output; was\n%s", line, out)
+		}
+	}
+}
+
+func TestOperation(t *testing.T) {
+	tests := map[string]string{
+		"select 1":                      "select",
+		"\n\tUPDATE accounts SET x = 1": "update",
+		"(select 1) union (select 2)":   "select",
+		"delete;":                       "delete",
+		"with x as (select 1) select":   "other",
+		"":                              "other",
+	}
+
+	for sql, expected := range tests {
+		if op := operation(sql); op != expected {
+			t.Errorf("Expected %q for %q; was %q", expected, sql, op)
+		}
+	}
+}
+
+func TestMetricsStats(t *testing.T) {
+	m := New(Options{})
+
+	if out := scrape(t, m); strings.Contains(out, "hermes_tx_begun_total") {
+		t.Errorf("Expected no stats before they're observed; was\n%s", out)
+	}
+
+	m.ObserveStats(hermes.Stats{
+		AcquiredConns:   3,
+		MaxConns:        10,
+		AcquireCount:    42,
+		AcquireDuration: 1500 * time.Millisecond,
+		TxBegun:         7,
+		TxCommitted:     5,
+		TxRolledBack:    1,
+		TxOpen:          1,
+		Retries:         2,
+	})
+
+	out := scrape(t, m)
+
+	expected := []string{
+		"# TYPE hermes_pool_acquired_conns gauge",
+		"hermes_pool_acquired_conns 3",
+		"hermes_pool_max_conns 10",
+		"# TYPE hermes_pool_acquires_total counter",
+		"hermes_pool_acquires_total 42",
+		"hermes_pool_acquire_wait_seconds_total 1.5",
+		"hermes_tx_begun_total 7",
+		"hermes_tx_committed_total 5",
+		"hermes_tx_rolled_back_total 1",
+		"hermes_tx_open 1",
+		"hermes_retries_total 2",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected %q in the output; was\n%s", line, out)
+		}
+	}
+}
+
+func TestMetricsRegister(t *testing.T) {
+	m := New(Options{Namespace: "app"})
+	m.ObserveQuery("select 1", time.Millisecond, nil)
+	m.ObserveStats(hermes.Stats{TxBegun: 1})
+
+	registry := prom.NewRegistry()
+	if err := registry.Register(m); err != nil {
+		t.Fatalf("Unable to register the metrics: %s", err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Unable to gather the metrics: %s", err)
+	}
+
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+
+	for _, name := range []string{"app_query_duration_seconds", "app_tx_begun_total", "app_pool_acquire_wait_seconds_total"} {
+		if !names[name] {
+			t.Errorf("Expected %s to be gathered; was %v", name, names)
+		}
+	}
+}
+
+// scrape serves the metrics and returns them in the text exposition format.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected the text exposition format; was %q", ct)
+	}
+
+	return rec.Body.String()
+}
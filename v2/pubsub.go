@@ -0,0 +1,344 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrSlowConsumer is returned by Subscription.Err when the subscription was closed because the
+// subscriber fell too far behind, under the OverflowDisconnect policy.  Listener.Err returns it
+// when a Listener closes itself under the same policy.
+var ErrSlowConsumer = errors.New("subscriber too slow; disconnected")
+
+// Default number of notifications buffered per subscription.
+const subscriptionBuffer = 16
+
+// OverflowPolicy determines what happens when a notification arrives and a buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the buffer.  A slow subscriber will hold up delivery to
+	// every other subscriber.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest buffered notification to make room for the new
+	// one.
+	OverflowDropOldest
+
+	// OverflowDisconnect closes the subscription.  Subscription.Err returns ErrSlowConsumer.
+	OverflowDisconnect
+
+	// OverflowDropNewest discards the new notification, keeping those already buffered.
+	OverflowDropNewest
+)
+
+// SubscribeOptions configure a subscription.
+type SubscribeOptions struct {
+	// Buffer is the number of notifications buffered for the subscriber.  Defaults to 16.
+	Buffer int
+
+	// Overflow is what to do when the buffer is full.  Defaults to OverflowBlock.
+	Overflow OverflowPolicy
+}
+
+// PubSub fans notifications from a single Listener out to any number of subscribers, so multiple
+// goroutines may each receive a copy of the notifications on a channel without each holding a
+// dedicated database connection.
+type PubSub struct {
+	listener *Listener
+
+	mutex sync.Mutex
+	subs  map[string]map[*Subscription]struct{}
+	done  chan struct{}
+
+	// listen is held while LISTENing or UNLISTENing, so a channel's commands reach the server in
+	// the order its subscriptions change; listening is the channels LISTENed on
+	listen    sync.Mutex
+	listening map[string]bool
+}
+
+// Subscription receives a copy of every notification on a channel.  Call Close when done.
+type Subscription struct {
+	Channel string
+
+	ps       *PubSub
+	overflow OverflowPolicy
+
+	notifications chan *Notification
+	done          chan struct{}
+
+	// send is held while delivering a notification, so the notifications channel isn't closed
+	// mid-send
+	send   sync.Mutex
+	mutex  sync.Mutex
+	closed bool
+	err    error
+}
+
+// NewPubSub creates a PubSub delivering notifications from the listener.  The PubSub takes over
+// the listener's Notifications channel; configure OnReconnect or OnError on the listener before
+// subscribing, if needed.  Closing the listener closes every subscription.
+func NewPubSub(listener *Listener) *PubSub {
+	ps := &PubSub{
+		listener:  listener,
+		subs:      make(map[string]map[*Subscription]struct{}),
+		done:      make(chan struct{}),
+		listening: make(map[string]bool),
+	}
+
+	go ps.dispatch()
+
+	return ps
+}
+
+// Subscribe to notifications on the channel.  The first subscription to a channel LISTENs on it;
+// when the last subscription to the channel is closed, the PubSub UNLISTENs.
+func (ps *PubSub) Subscribe(ctx context.Context, channel string, opts SubscribeOptions) (*Subscription, error) {
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = subscriptionBuffer
+	}
+
+	sub := &Subscription{
+		Channel:       channel,
+		ps:            ps,
+		overflow:      opts.Overflow,
+		notifications: make(chan *Notification, buffer),
+		done:          make(chan struct{}),
+	}
+
+	if ps.listener.isStopping() {
+		return nil, ErrListenerClosed
+	}
+
+	ps.mutex.Lock()
+
+	select {
+	case <-ps.done:
+		ps.mutex.Unlock()
+		return nil, ErrListenerClosed
+	default:
+	}
+
+	subs, ok := ps.subs[channel]
+	if !ok {
+		subs = make(map[*Subscription]struct{})
+		ps.subs[channel] = subs
+	}
+	subs[sub] = struct{}{}
+
+	ps.mutex.Unlock()
+
+	if err := ps.sync(ctx, channel); err != nil {
+		sub.close(err)
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// sync LISTENs on the channel if it has subscribers and isn't LISTENed on, or UNLISTENs if it
+// has none and is.  The subscribers are checked while holding the listen lock, so a LISTEN for
+// a new subscription can't be overtaken by an UNLISTEN for the last one closed.
+func (ps *PubSub) sync(ctx context.Context, channel string) error {
+	ps.listen.Lock()
+	defer ps.listen.Unlock()
+
+	ps.mutex.Lock()
+	wanted := len(ps.subs[channel]) > 0
+	ps.mutex.Unlock()
+
+	switch {
+	case wanted && !ps.listening[channel]:
+		if err := ps.listener.Listen(ctx, channel); err != nil {
+			return err
+		}
+		ps.listening[channel] = true
+
+	case !wanted && ps.listening[channel]:
+		delete(ps.listening, channel)
+		return ps.listener.Unlisten(ctx, channel)
+	}
+
+	return nil
+}
+
+// Close the PubSub and its Listener, closing every subscription.
+func (ps *PubSub) Close() error {
+	err := ps.listener.Close()
+	<-ps.done
+
+	return err
+}
+
+// Shutdown stops the PubSub gracefully: new subscriptions are refused, and notifications already
+// received are delivered to the subscribers before every subscription is closed.  Waits up to
+// ctx's deadline.  See Listener.Shutdown.
+func (ps *PubSub) Shutdown(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := ps.listener.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	select {
+	case <-ps.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dispatch delivers each notification from the Listener to the channel's subscribers.
+func (ps *PubSub) dispatch() {
+	defer ps.closeAll()
+
+	for n := range ps.listener.Notifications() {
+		ps.mutex.Lock()
+		subs := make([]*Subscription, 0, len(ps.subs[n.Channel]))
+		for sub := range ps.subs[n.Channel] {
+			subs = append(subs, sub)
+		}
+		ps.mutex.Unlock()
+
+		for _, sub := range subs {
+			sub.deliver(n)
+		}
+	}
+}
+
+// closeAll closes every subscription once the Listener is closed.
+func (ps *PubSub) closeAll() {
+	ps.mutex.Lock()
+	close(ps.done)
+
+	var subs []*Subscription
+	for _, channelSubs := range ps.subs {
+		for sub := range channelSubs {
+			subs = append(subs, sub)
+		}
+	}
+	ps.mutex.Unlock()
+
+	for _, sub := range subs {
+		sub.close(ErrListenerClosed)
+	}
+}
+
+// unsubscribe removes the subscription, UNLISTENing on its channel if it was the last one.
+func (ps *PubSub) unsubscribe(sub *Subscription) {
+	ps.mutex.Lock()
+
+	subs := ps.subs[sub.Channel]
+	delete(subs, sub)
+
+	if len(subs) == 0 {
+		delete(ps.subs, sub.Channel)
+	}
+
+	select {
+	case <-ps.done:
+		ps.mutex.Unlock()
+		return
+	default:
+	}
+
+	ps.mutex.Unlock()
+
+	_ = ps.sync(context.Background(), sub.Channel)
+}
+
+// Notifications returns the Go channel notifications are delivered on.  The channel is closed
+// when the subscription is closed.
+func (sub *Subscription) Notifications() <-chan *Notification {
+	return sub.notifications
+}
+
+// Err returns the reason the subscription was closed by the PubSub, such as ErrSlowConsumer, or
+// nil if the subscription is open or was closed by the subscriber.
+func (sub *Subscription) Err() error {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+
+	return sub.err
+}
+
+// Close the subscription.  Safe to call multiple times.
+func (sub *Subscription) Close() error {
+	sub.close(nil)
+	return nil
+}
+
+// close the subscription, recording the reason.
+func (sub *Subscription) close(reason error) {
+	sub.mutex.Lock()
+	if sub.closed {
+		sub.mutex.Unlock()
+		return
+	}
+	sub.closed = true
+	sub.err = reason
+	close(sub.done)
+	sub.mutex.Unlock()
+
+	sub.send.Lock()
+	close(sub.notifications)
+	sub.send.Unlock()
+
+	sub.ps.unsubscribe(sub)
+}
+
+// deliver the notification according to the subscription's overflow policy.
+func (sub *Subscription) deliver(n *Notification) {
+	sub.send.Lock()
+
+	select {
+	case <-sub.done:
+		sub.send.Unlock()
+		return
+	default:
+	}
+
+	switch sub.overflow {
+	case OverflowDropOldest:
+		for {
+			select {
+			case sub.notifications <- n:
+				sub.send.Unlock()
+				return
+			default:
+			}
+
+			select {
+			case <-sub.notifications:
+			default:
+			}
+		}
+
+	case OverflowDisconnect:
+		select {
+		case sub.notifications <- n:
+			sub.send.Unlock()
+		default:
+			sub.send.Unlock()
+			sub.close(ErrSlowConsumer)
+		}
+
+	case OverflowDropNewest:
+		select {
+		case sub.notifications <- n:
+		default:
+		}
+		sub.send.Unlock()
+
+	default:
+		select {
+		case sub.notifications <- n:
+		case <-sub.done:
+		}
+		sub.send.Unlock()
+	}
+}
@@ -0,0 +1,162 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestSubscription(overflow OverflowPolicy, buffer int) *Subscription {
+	ps := &PubSub{
+		listener:  NewListener(nil),
+		subs:      make(map[string]map[*Subscription]struct{}),
+		done:      make(chan struct{}),
+		listening: make(map[string]bool),
+	}
+
+	sub := &Subscription{
+		Channel:       "test",
+		ps:            ps,
+		overflow:      overflow,
+		notifications: make(chan *Notification, buffer),
+		done:          make(chan struct{}),
+	}
+	ps.subs["test"] = map[*Subscription]struct{}{sub: {}}
+
+	return sub
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	sub := newTestSubscription(OverflowDropOldest, 2)
+
+	for _, payload := range []string{"1", "2", "3"} {
+		sub.deliver(&Notification{Channel: "test", Payload: payload})
+	}
+
+	if n := <-sub.Notifications(); n.Payload != "2" {
+		t.Errorf("Expected oldest notification to be dropped; received %s", n.Payload)
+	}
+
+	if n := <-sub.Notifications(); n.Payload != "3" {
+		t.Errorf("Expected newest notification; received %s", n.Payload)
+	}
+}
+
+func TestOverflowDisconnect(t *testing.T) {
+	sub := newTestSubscription(OverflowDisconnect, 1)
+
+	sub.deliver(&Notification{Channel: "test", Payload: "1"})
+	sub.deliver(&Notification{Channel: "test", Payload: "2"})
+
+	if !errors.Is(sub.Err(), ErrSlowConsumer) {
+		t.Errorf("Expected ErrSlowConsumer; was %v", sub.Err())
+	}
+
+	count := 0
+	for range sub.Notifications() {
+		count++
+	}
+
+	if count != 1 {
+		t.Errorf("Expected 1 buffered notification before disconnect; received %d", count)
+	}
+
+	if len(sub.ps.subs) != 0 {
+		t.Errorf("Expected subscription to be removed from the PubSub")
+	}
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	sub := newTestSubscription(OverflowDropNewest, 2)
+
+	for _, payload := range []string{"1", "2", "3"} {
+		sub.deliver(&Notification{Channel: "test", Payload: payload})
+	}
+
+	if n := <-sub.Notifications(); n.Payload != "1" {
+		t.Errorf("Expected oldest notification to be kept; received %s", n.Payload)
+	}
+
+	if n := <-sub.Notifications(); n.Payload != "2" {
+		t.Errorf("Expected second notification; received %s", n.Payload)
+	}
+
+	if len(sub.Notifications()) != 0 {
+		t.Error("Expected newest notification to be dropped")
+	}
+}
+
+// nextCommand waits for the listener to be sent a LISTEN or UNLISTEN, standing in for its
+// connection.
+func nextCommand(t *testing.T, l *Listener) listenCommand {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		l.mutex.Lock()
+		if len(l.pending) > 0 {
+			cmd := l.pending[0]
+			l.pending = l.pending[1:]
+			l.mutex.Unlock()
+			return cmd
+		}
+		l.mutex.Unlock()
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatal("Expected a command to be sent to the listener")
+	return listenCommand{}
+}
+
+func TestSubscribeWhileUnsubscribing(t *testing.T) {
+	sub := newTestSubscription(OverflowBlock, 1)
+	ps := sub.ps
+	ps.listening["test"] = true
+
+	// Pretend the listener is connected, so commands wait for nextCommand
+	ps.listener.started = true
+	defer close(ps.listener.done)
+
+	go sub.Close()
+
+	unlisten := nextCommand(t, ps.listener)
+	if unlisten.sql != listenSQL("UNLISTEN", []string{"test"}) {
+		t.Fatalf("Expected the last subscription to UNLISTEN; was %s", unlisten.sql)
+	}
+
+	subscribed := make(chan error, 1)
+	go func() {
+		_, err := ps.Subscribe(context.Background(), "test", SubscribeOptions{})
+		subscribed <- err
+	}()
+
+	// The new subscription mustn't LISTEN until the UNLISTEN is done, or the UNLISTEN would
+	// silence it
+	time.Sleep(20 * time.Millisecond)
+
+	ps.listener.mutex.Lock()
+	pending := len(ps.listener.pending)
+	ps.listener.mutex.Unlock()
+
+	if pending != 0 {
+		t.Fatal("Expected the LISTEN to wait for the UNLISTEN")
+	}
+
+	unlisten.result <- nil
+
+	listen := nextCommand(t, ps.listener)
+	if listen.sql != listenSQL("LISTEN", []string{"test"}) {
+		t.Fatalf("Expected the new subscription to LISTEN; was %s", listen.sql)
+	}
+	listen.result <- nil
+
+	if err := <-subscribed; err != nil {
+		t.Fatalf("Unable to subscribe: %s", err)
+	}
+
+	if !ps.listening["test"] {
+		t.Error("Expected the channel to be listened on")
+	}
+}
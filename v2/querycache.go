@@ -0,0 +1,226 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QueryCache is a read cache for the decoded results of hot queries, such as reference data
+// that's read far more often than it changes.  Entries expire after their time-to-live, and, if
+// the cache is attached to an InvalidationBus, are purged across every process when a writer
+// calls Invalidate.
+//
+// Use QueryCached and QueryCachedValues to run queries through the cache.
+type QueryCache struct {
+	bus *InvalidationBus
+
+	mutex    sync.Mutex
+	entries  map[string]cacheEntry
+	loading  map[string]*cacheLoad
+	version  uint64
+	sweepLen int
+}
+
+// cacheEntry is a cached result.
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// cacheLoad is a query in progress for a key, shared by every caller that misses the cache while
+// it runs.
+type cacheLoad struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// NewQueryCache creates a query cache.  If bus is not nil, keys invalidated on the bus are purged
+// from the cache; an invalidation of the empty key, sent after the bus reconnects, purges
+// everything.
+func NewQueryCache(bus *InvalidationBus) *QueryCache {
+	cache := &QueryCache{
+		bus:     bus,
+		entries: make(map[string]cacheEntry),
+		loading: make(map[string]*cacheLoad),
+	}
+
+	if bus != nil {
+		bus.OnInvalidate("", func(key string) {
+			if key == "" {
+				cache.PurgeAll()
+			} else {
+				cache.Purge(key)
+			}
+		})
+	}
+
+	return cache
+}
+
+// QueryCached returns the structs cached under the key, or runs the query and caches its rows as
+// structs of type T for the ttl.  Concurrent calls that miss the cache for the same key share a
+// single query, run with the first caller's context.  See Select for how columns map to fields.
+//
+// The returned slice is shared with every other caller reading the key, so don't modify it.
+func QueryCached[T any](ctx context.Context, cache *QueryCache, conn Conn, key string, ttl time.Duration, sql string, args ...interface{}) ([]T, error) {
+	value, err := cache.load(key, ttl, func() (interface{}, error) {
+		return Select[T](ctx, conn, sql, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]T), nil
+}
+
+// QueryCachedValues returns the values cached under the key, or runs a query returning a single
+// column and caches the values for the ttl.  See QueryCached.
+func QueryCachedValues[T any](ctx context.Context, cache *QueryCache, conn Conn, key string, ttl time.Duration, sql string, args ...interface{}) ([]T, error) {
+	value, err := cache.load(key, ttl, func() (interface{}, error) {
+		return SelectValues[T](ctx, conn, sql, args...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]T), nil
+}
+
+// Invalidate purges the keys from this cache immediately and, if the cache is attached to an
+// InvalidationBus, from every other process's cache.  If conn is a transaction, the other
+// processes are notified when it commits.
+func (cache *QueryCache) Invalidate(ctx context.Context, conn Conn, keys ...string) error {
+	for _, key := range keys {
+		cache.Purge(key)
+	}
+
+	if cache.bus == nil {
+		return nil
+	}
+
+	return cache.bus.Invalidate(ctx, conn, keys...)
+}
+
+// Get returns the value cached under the key, if it hasn't expired.
+func (cache *QueryCache) Get(key string) (interface{}, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return cache.get(key, time.Now())
+}
+
+// Set caches the value under the key for the ttl.
+func (cache *QueryCache) Set(key string, value interface{}, ttl time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.set(key, value, ttl)
+}
+
+// Purge removes the key from this cache only.  See Invalidate to purge it from every process.
+func (cache *QueryCache) Purge(key string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	delete(cache.entries, key)
+	cache.version++
+}
+
+// PurgeAll empties this cache.
+func (cache *QueryCache) PurgeAll() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.entries = make(map[string]cacheEntry)
+	cache.version++
+}
+
+// Len returns the number of entries in the cache, including any that have expired but haven't
+// been removed yet.
+func (cache *QueryCache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	return len(cache.entries)
+}
+
+// load returns the value cached under the key, or calls fn to load it.  The loaded value isn't
+// cached if anything was purged while it loaded, since it may be stale.
+func (cache *QueryCache) load(key string, ttl time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	cache.mutex.Lock()
+
+	if value, ok := cache.get(key, time.Now()); ok {
+		cache.mutex.Unlock()
+		return value, nil
+	}
+
+	if load, ok := cache.loading[key]; ok {
+		cache.mutex.Unlock()
+		<-load.done
+		return load.value, load.err
+	}
+
+	load := &cacheLoad{done: make(chan struct{})}
+	cache.loading[key] = load
+	version := cache.version
+	cache.mutex.Unlock()
+
+	finished := false
+	defer func() {
+		cache.mutex.Lock()
+		delete(cache.loading, key)
+		if finished && load.err == nil && cache.version == version {
+			cache.set(key, load.value, ttl)
+		}
+		cache.mutex.Unlock()
+
+		// Don't leave callers waiting on the query empty-handed if it panicked
+		if !finished {
+			load.err = fmt.Errorf("query for cache key %s panicked", key)
+		}
+
+		close(load.done)
+	}()
+
+	load.value, load.err = fn()
+	finished = true
+
+	return load.value, load.err
+}
+
+// get returns the unexpired value cached under the key.  Must be called with the mutex locked.
+func (cache *QueryCache) get(key string, now time.Time) (interface{}, bool) {
+	entry, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if now.After(entry.expires) {
+		delete(cache.entries, key)
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
+// set caches the value, sweeping out expired entries whenever the cache has doubled in size
+// since the last sweep.  Must be called with the mutex locked.
+func (cache *QueryCache) set(key string, value interface{}, ttl time.Duration) {
+	now := time.Now()
+	cache.entries[key] = cacheEntry{value: value, expires: now.Add(ttl)}
+
+	if len(cache.entries) < 2*cache.sweepLen || len(cache.entries) < 64 {
+		return
+	}
+
+	for k, entry := range cache.entries {
+		if now.After(entry.expires) {
+			delete(cache.entries, k)
+		}
+	}
+
+	cache.sweepLen = len(cache.entries)
+}
@@ -0,0 +1,153 @@
+package hermes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryConn returns the same rows for every query, counting the queries.
+type queryConn struct {
+	Conn
+
+	columns []string
+	values  [][]interface{}
+	queries int32
+	wait    chan struct{}
+}
+
+func (c *queryConn) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	atomic.AddInt32(&c.queries, 1)
+
+	if c.wait != nil {
+		<-c.wait
+	}
+
+	return &fakeRows{columns: c.columns, values: c.values}, nil
+}
+
+func TestQueryCached(t *testing.T) {
+	cache := NewQueryCache(nil)
+	conn := &queryConn{columns: []string{"id", "display_name"}, values: [][]interface{}{{int64(1), "Alice"}}}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		accounts, err := QueryCached[account](ctx, cache, conn, "accounts", time.Minute, "select id, display_name from accounts")
+		if err != nil {
+			t.Fatalf("Unable to query accounts: %s", err)
+		}
+
+		if len(accounts) != 1 || accounts[0].Name != "Alice" {
+			t.Fatalf("Expected [Alice]; was %v", accounts)
+		}
+	}
+
+	if conn.queries != 1 {
+		t.Errorf("Expected 1 query; was %d", conn.queries)
+	}
+
+	if err := cache.Invalidate(ctx, conn, "accounts"); err != nil {
+		t.Fatalf("Unable to invalidate the cache: %s", err)
+	}
+
+	if _, err := QueryCached[account](ctx, cache, conn, "accounts", time.Minute, "select id, display_name from accounts"); err != nil {
+		t.Fatalf("Unable to query accounts: %s", err)
+	}
+
+	if conn.queries != 2 {
+		t.Errorf("Expected the invalidated key to be queried again; was %d queries", conn.queries)
+	}
+}
+
+func TestQueryCachedExpires(t *testing.T) {
+	cache := NewQueryCache(nil)
+	conn := &queryConn{columns: []string{"name"}, values: [][]interface{}{{"Alice"}, {"Bob"}}}
+	ctx := context.Background()
+
+	names, err := QueryCachedValues[string](ctx, cache, conn, "names", time.Millisecond, "select name from users")
+	if err != nil || len(names) != 2 {
+		t.Fatalf("Expected 2 names; was %v, %v", names, err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := cache.Get("names"); ok {
+		t.Error("Expected the entry to expire")
+	}
+
+	if _, err := QueryCachedValues[string](ctx, cache, conn, "names", time.Minute, "select name from users"); err != nil {
+		t.Fatalf("Unable to query names: %s", err)
+	}
+
+	if conn.queries != 2 {
+		t.Errorf("Expected the expired key to be queried again; was %d queries", conn.queries)
+	}
+}
+
+func TestQueryCachedShared(t *testing.T) {
+	cache := NewQueryCache(nil)
+	conn := &queryConn{columns: []string{"name"}, values: [][]interface{}{{"Alice"}}, wait: make(chan struct{})}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := QueryCachedValues[string](ctx, cache, conn, "names", time.Minute, "select name from users"); err != nil {
+				t.Errorf("Unable to query names: %s", err)
+			}
+		}()
+	}
+
+	// Let the callers pile up on the first query
+	time.Sleep(10 * time.Millisecond)
+	close(conn.wait)
+	wg.Wait()
+
+	if queries := atomic.LoadInt32(&conn.queries); queries != 1 {
+		t.Errorf("Expected concurrent misses to share 1 query; was %d", queries)
+	}
+}
+
+func TestQueryCachePurgedWhileLoading(t *testing.T) {
+	cache := NewQueryCache(nil)
+
+	value, err := cache.load("key", time.Minute, func() (interface{}, error) {
+		cache.PurgeAll()
+		return "stale", nil
+	})
+	if err != nil || value != "stale" {
+		t.Fatalf("Expected the loaded value; was %v, %v", value, err)
+	}
+
+	if _, ok := cache.Get("key"); ok {
+		t.Error("Expected a value loaded across a purge not to be cached")
+	}
+}
+
+func TestQueryCacheBus(t *testing.T) {
+	bus := &InvalidationBus{callbacks: make(map[string][]func(key string))}
+	cache := NewQueryCache(bus)
+
+	cache.Set("a", 1, time.Minute)
+	cache.Set("b", 2, time.Minute)
+
+	bus.invalidate("a")
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected the bus to purge a")
+	}
+
+	if _, ok := cache.Get("b"); !ok {
+		t.Error("Expected b to remain cached")
+	}
+
+	bus.invalidateAll()
+	if cache.Len() != 0 {
+		t.Errorf("Expected invalidating everything to empty the cache; was %d entries", cache.Len())
+	}
+}
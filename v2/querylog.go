@@ -0,0 +1,133 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// QueryLogLevel is the severity of a logged statement.
+type QueryLogLevel int
+
+const (
+	// QueryLogDebug statements ran normally.
+	QueryLogDebug QueryLogLevel = iota
+
+	// QueryLogWarn statements took longer than the slow query threshold.
+	QueryLogWarn
+
+	// QueryLogError statements failed.
+	QueryLogError
+)
+
+// String returns the level's name.
+func (level QueryLogLevel) String() string {
+	switch level {
+	case QueryLogDebug:
+		return "debug"
+	case QueryLogWarn:
+		return "warn"
+	case QueryLogError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(level))
+	}
+}
+
+// redactedArg replaces the arguments of logged statements when the QueryLogger redacts them.
+const redactedArg = "xxxxx"
+
+// QueryLog describes a statement for the QueryLogger.
+type QueryLog struct {
+	Level    QueryLogLevel
+	SQL      string
+	Args     []interface{}
+	Duration time.Duration
+	Err      error
+
+	// Slow is true if the statement took longer than the slow query threshold, even if it failed.
+	Slow bool
+}
+
+// String formats the statement for a log line.
+func (entry QueryLog) String() string {
+	if entry.Err != nil {
+		return fmt.Sprintf("%s %q %v (%s): %s", entry.Level, entry.SQL, entry.Args, entry.Duration, entry.Err)
+	}
+
+	return fmt.Sprintf("%s %q %v (%s)", entry.Level, entry.SQL, entry.Args, entry.Duration)
+}
+
+// QueryLogger logs the statements run through a DB.  See DB.SetQueryLogger.
+type QueryLogger struct {
+	// Log is called as each statement finishes, from the goroutine running the statement.  For
+	// Query, the duration includes reading the rows, up to when they're closed; for QueryRow, up
+	// to when the row is scanned.
+	Log func(ctx context.Context, entry QueryLog)
+
+	// SlowThreshold escalates statements that take at least this long to QueryLogWarn.  Zero
+	// doesn't treat any statement as slow.
+	SlowThreshold time.Duration
+
+	// MinLevel skips statements logged below the level, e.g. QueryLogWarn to log only slow and
+	// failed statements.
+	MinLevel QueryLogLevel
+
+	// RedactArgs replaces the statements' arguments with "xxxxx", to keep personal information
+	// and secrets out of the logs.
+	RedactArgs bool
+}
+
+// SetQueryLogger logs every statement run through the DB, its transactions, including
+// ContextualTx, and its pinned connections.  Pass a QueryLogger without a Log function to stop
+// logging.  Statements are timed through the connections' tracer, so nothing is logged for a DB
+// that wasn't created by Connect or ConnectConfig.
+func (db *DB) SetQueryLogger(logger QueryLogger) {
+	if db.observer == nil {
+		db.observer = &metricsHook{}
+	}
+
+	if logger.Log == nil {
+		db.observer.logger.Store((*QueryLogger)(nil))
+		return
+	}
+
+	db.observer.logger.Store(&logger)
+}
+
+// queryLogger returns the query logger, or nil if statements aren't logged.
+func (h *metricsHook) queryLogger() *QueryLogger {
+	logger, _ := h.logger.Load().(*QueryLogger)
+	return logger
+}
+
+// log the statement at the appropriate level.
+func (logger *QueryLogger) log(ctx context.Context, sql string, args []interface{}, duration time.Duration, err error) {
+	entry := QueryLog{
+		Level:    QueryLogDebug,
+		SQL:      sql,
+		Args:     args,
+		Duration: duration,
+		Err:      err,
+		Slow:     logger.SlowThreshold > 0 && duration >= logger.SlowThreshold,
+	}
+
+	if err != nil {
+		entry.Level = QueryLogError
+	} else if entry.Slow {
+		entry.Level = QueryLogWarn
+	}
+
+	if entry.Level < logger.MinLevel {
+		return
+	}
+
+	if logger.RedactArgs && len(args) > 0 {
+		entry.Args = make([]interface{}, len(args))
+		for i := range entry.Args {
+			entry.Args[i] = redactedArg
+		}
+	}
+
+	logger.Log(ctx, entry)
+}
@@ -0,0 +1,99 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestQueryLogger(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://localhost/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := &DB{observer: newMetricsHook(config)}
+
+	var logged []QueryLog
+	db.SetQueryLogger(QueryLogger{
+		Log: func(_ context.Context, entry QueryLog) {
+			logged = append(logged, entry)
+		},
+		SlowThreshold: time.Hour,
+		RedactArgs:    true,
+	})
+
+	hook := db.observer
+	ctx := context.Background()
+
+	traced := hook.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "select $1", Args: []interface{}{"secret"}})
+	hook.TraceQueryEnd(traced, nil, pgx.TraceQueryEndData{})
+
+	failed := errors.New("failed")
+
+	traced = hook.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{})
+	hook.TraceBatchQuery(traced, nil, pgx.TraceBatchQueryData{SQL: "insert 1", Err: failed})
+
+	if len(logged) != 2 {
+		t.Fatalf("Expected 2 statements logged; was %d", len(logged))
+	}
+
+	if logged[0].Level != QueryLogDebug || logged[0].SQL != "select $1" {
+		t.Errorf("Expected the select at debug; was %s", logged[0])
+	}
+
+	if len(logged[0].Args) != 1 || logged[0].Args[0] != redactedArg {
+		t.Errorf("Expected the arguments to be redacted; was %v", logged[0].Args)
+	}
+
+	if logged[1].Level != QueryLogError || logged[1].Err != failed {
+		t.Errorf("Expected the failed insert at error; was %s", logged[1])
+	}
+
+	db.SetQueryLogger(QueryLogger{})
+
+	traced = hook.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "select 1"})
+	hook.TraceQueryEnd(traced, nil, pgx.TraceQueryEndData{})
+
+	if len(logged) != 2 {
+		t.Errorf("Expected nothing logged after removing the logger; was %d statements", len(logged))
+	}
+}
+
+func TestQueryLoggerLevels(t *testing.T) {
+	var logged []QueryLog
+	logger := &QueryLogger{
+		Log: func(_ context.Context, entry QueryLog) {
+			logged = append(logged, entry)
+		},
+		SlowThreshold: 100 * time.Millisecond,
+		MinLevel:      QueryLogWarn,
+	}
+
+	ctx := context.Background()
+	args := []interface{}{1}
+
+	logger.log(ctx, "select 1", args, time.Millisecond, nil)
+	logger.log(ctx, "select 2", args, time.Second, nil)
+	logger.log(ctx, "select 3", args, time.Second, errors.New("failed"))
+
+	if len(logged) != 2 {
+		t.Fatalf("Expected the fast statement to be skipped; was %d statements", len(logged))
+	}
+
+	if logged[0].Level != QueryLogWarn || !logged[0].Slow {
+		t.Errorf("Expected a slow statement at warn; was %s", logged[0])
+	}
+
+	if logged[1].Level != QueryLogError || !logged[1].Slow {
+		t.Errorf("Expected a slow, failed statement at error; was %s", logged[1])
+	}
+
+	if logged[0].Args[0] != 1 {
+		t.Errorf("Expected the arguments to be logged; was %v", logged[0].Args)
+	}
+}
@@ -0,0 +1,739 @@
+package hermes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DefaultQueueTable is the table jobs are stored in if the Queue doesn't specify one.
+	DefaultQueueTable = "hermes_jobs"
+
+	// DefaultQueueName is the name of the queue if NewQueue isn't given one.
+	DefaultQueueName = "default"
+
+	// Defaults for the Queue settings.
+	defaultPollInterval = time.Second
+	defaultMaxAttempts  = 10
+	defaultBackoffBase  = time.Second
+	defaultBackoffMax   = time.Hour
+
+	// Longest a worker waits before trying the queue again after an error.
+	defaultWorkerBackoffMax = time.Minute
+)
+
+// ErrLeaseExpired is returned by RunNext when a job's visibility timeout expired before the job
+// finished, and another worker may have claimed it.  The job's changes are rolled back.
+var ErrLeaseExpired = errors.New("job lease expired")
+
+// DeadLetterSuffix is appended to the queue's table name to name the dead-letter table, if the
+// Queue doesn't specify one.
+const DeadLetterSuffix = "_dead"
+
+// Job is a unit of work stored in a Queue.
+type Job struct {
+	ID        int64
+	Queue     string
+	Kind      string
+	Payload   json.RawMessage
+	Attempts  int
+	RunAt     time.Time
+	CreatedAt time.Time
+	LastError string
+
+	// FailedAt is when the job was moved to the dead-letter table.  Zero for jobs in the queue.
+	FailedAt time.Time
+
+	// lease identifies the claim on the job when the queue uses a visibility timeout
+	lease int64
+}
+
+// Decode unmarshals the job's JSON payload into v.
+func (job *Job) Decode(v interface{}) error {
+	return json.Unmarshal(job.Payload, v)
+}
+
+// RetryPolicy controls how a kind of job is retried.  See Queue.SetRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a job is tried before it's moved to the dead-letter
+	// table.  Defaults to the Queue's MaxAttempts.
+	MaxAttempts int
+
+	// Backoff returns how long to wait before retrying a job that has failed the given number of
+	// attempts.  Defaults to the Queue's Backoff.
+	Backoff func(attempts int) time.Duration
+}
+
+// JobHandler processes a job.  The conn is the transaction the job was claimed in, so any changes
+// the handler makes are committed along with the job's completion, or rolled back if the handler
+// returns an error.
+type JobHandler func(ctx context.Context, conn Conn, job *Job) error
+
+// QueueStats are counters describing the work a Queue has done since it was created.
+type QueueStats struct {
+	Enqueued  int64
+	Completed int64
+	Retried   int64
+	Failed    int64
+}
+
+// Queue is a job queue stored in a PostgreSQL table.  Jobs may be enqueued through any Conn,
+// including a transaction, so a job is only created if the work that spawned it is saved.  Workers
+// claim jobs with SELECT ... FOR UPDATE SKIP LOCKED, so any number of workers across any number of
+// processes can pull from the same queue without blocking one another.
+//
+// Failed jobs are retried with exponential backoff, up to MaxAttempts, after which they're moved
+// to a dead-letter table along with the last error.  Dead-lettered jobs may be listed with
+// DeadJobs and returned to the queue with Requeue.
+//
+// By default a job is held by the transaction that claimed it, so the job is released if the
+// worker's connection is lost.  Long-running jobs may instead set a VisibilityTimeout, to claim
+// jobs with a lease rather than hold a transaction open for the length of the job.
+//
+// Configure the Queue's fields before calling Work.
+type Queue struct {
+	// Name of the queue.  Multiple queues may share a table.
+	Name string
+
+	// Table jobs are stored in.  Defaults to DefaultQueueTable.  See CreateTable.
+	Table string
+
+	// PollInterval is how long a worker waits before checking for jobs again when the queue is
+	// empty.  Defaults to 1 second.
+	PollInterval time.Duration
+
+	// MaxAttempts is the number of times a job is tried before it's moved to the dead-letter
+	// table.  Defaults to 10.  Override for a kind of job with SetRetryPolicy.
+	MaxAttempts int
+
+	// Backoff returns how long to wait before retrying a job that has failed the given number of
+	// attempts.  Defaults to exponential backoff from 1 second up to 1 hour.
+	Backoff func(attempts int) time.Duration
+
+	// VisibilityTimeout, if set, claims each job with a lease that expires after this long,
+	// rather than holding a transaction open while the job runs.  The worker extends the lease
+	// periodically while the handler runs; if the worker crashes, the job may be claimed again
+	// once the lease expires.  A job whose lease expires counts as a failed attempt.  Every worker
+	// on a queue should use the same setting.
+	VisibilityTimeout time.Duration
+
+	// DeadLetterTable holds jobs that have run out of attempts.  Defaults to Table followed by
+	// DeadLetterSuffix, e.g. "hermes_jobs_dead".
+	DeadLetterTable string
+
+	// OnError is called with errors a worker recovers from, such as losing the connection while
+	// claiming a job, for logging.  The worker backs off before trying again, doubling its wait
+	// from PollInterval up to a minute.
+	OnError func(err error)
+
+	db *DB
+
+	mutex    sync.RWMutex
+	handlers map[string]JobHandler
+	policies map[string]RetryPolicy
+
+	enqueued  int64
+	completed int64
+	retried   int64
+	failed    int64
+}
+
+// NewQueue creates a queue with the given name, stored in DefaultQueueTable.  Use a blank name for
+// DefaultQueueName.
+func NewQueue(db *DB, name string) *Queue {
+	if name == "" {
+		name = DefaultQueueName
+	}
+
+	return &Queue{
+		Name:     name,
+		Table:    DefaultQueueTable,
+		db:       db,
+		handlers: make(map[string]JobHandler),
+		policies: make(map[string]RetryPolicy),
+	}
+}
+
+// CreateTable creates the queue's table, dead-letter table, and index if they don't exist.  You may prefer to create
+// the table in your own migrations; see the SQL in this function for the required columns.
+func (q *Queue) CreateTable(ctx context.Context, conn Conn) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	table := q.table()
+	dead := q.deadTable()
+	index := pgx.Identifier{q.tableName() + "_run_at_idx"}.Sanitize()
+
+	_, err := conn.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id         bigserial PRIMARY KEY,
+			queue      text NOT NULL,
+			kind       text NOT NULL,
+			payload    jsonb NOT NULL DEFAULT '{}',
+			attempts   integer NOT NULL DEFAULT 0,
+			run_at     timestamptz NOT NULL DEFAULT now(),
+			created_at timestamptz NOT NULL DEFAULT now(),
+			failed_at  timestamptz,
+			last_error text
+		);
+		ALTER TABLE %s ADD COLUMN IF NOT EXISTS lease bigint NOT NULL DEFAULT 0;
+		ALTER TABLE %s ADD COLUMN IF NOT EXISTS locked_until timestamptz;
+		CREATE INDEX IF NOT EXISTS %s ON %s (queue, run_at) WHERE failed_at IS NULL;
+		CREATE TABLE IF NOT EXISTS %s (
+			id         bigint PRIMARY KEY,
+			queue      text NOT NULL,
+			kind       text NOT NULL,
+			payload    jsonb NOT NULL,
+			attempts   integer NOT NULL,
+			created_at timestamptz NOT NULL,
+			failed_at  timestamptz NOT NULL DEFAULT now(),
+			last_error text NOT NULL
+		)`,
+		table, table, table, index, table, dead))
+
+	return err
+}
+
+// Handle registers the handler for jobs of the given kind.
+func (q *Queue) Handle(kind string, handler JobHandler) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.handlers[kind] = handler
+}
+
+// SetRetryPolicy overrides the queue's MaxAttempts and Backoff for jobs of the given kind.
+func (q *Queue) SetRetryPolicy(kind string, policy RetryPolicy) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.policies[kind] = policy
+}
+
+// DeadJobs returns up to limit jobs from the queue that have been moved to the dead-letter table,
+// most recently failed first.
+func (q *Queue) DeadJobs(ctx context.Context, conn Conn, limit int) ([]*Job, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	rows, err := conn.Query(ctx, fmt.Sprintf(`
+		SELECT id, queue, kind, payload, attempts, created_at, failed_at, last_error
+		FROM %s
+		WHERE queue = $1
+		ORDER BY failed_at DESC, id DESC
+		LIMIT $2`, q.deadTable()), q.Name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		var job Job
+		if err := rows.Scan(&job.ID, &job.Queue, &job.Kind, &job.Payload, &job.Attempts,
+			&job.CreatedAt, &job.FailedAt, &job.LastError); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// Requeue moves the dead-lettered jobs back into the queue, to run as soon as possible with a
+// fresh set of attempts.  Returns the number of jobs requeued; IDs not found in the dead-letter
+// table are ignored.
+func (q *Queue) Requeue(ctx context.Context, conn Conn, ids ...int64) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tag, err := conn.Exec(ctx, fmt.Sprintf(`
+		WITH requeued AS (
+			DELETE FROM %s WHERE queue = $1 AND id = ANY($2)
+			RETURNING id, queue, kind, payload, created_at)
+		INSERT INTO %s (id, queue, kind, payload, created_at)
+		SELECT id, queue, kind, payload, created_at FROM requeued`,
+		q.deadTable(), q.table()), q.Name, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// Enqueue adds a job to the queue to run as soon as a worker is available.  The payload is
+// marshaled to JSON.  If conn is a transaction, the job won't be visible to workers until the
+// transaction commits.  Returns the job ID.
+func (q *Queue) Enqueue(ctx context.Context, conn Conn, kind string, payload interface{}) (int64, error) {
+	return q.EnqueueAt(ctx, conn, kind, payload, time.Time{})
+}
+
+// EnqueueAt adds a job to the queue to run no earlier than runAt.  A zero runAt runs the job as
+// soon as possible.
+func (q *Queue) EnqueueAt(ctx context.Context, conn Conn, kind string, payload interface{}, runAt time.Time) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	var when *time.Time
+	if !runAt.IsZero() {
+		when = &runAt
+	}
+
+	var id int64
+	row := queryRowOnce(ctx, conn, fmt.Sprintf(`
+		INSERT INTO %s (queue, kind, payload, run_at)
+		VALUES ($1, $2, $3, coalesce($4, now()))
+		RETURNING id`, q.table()),
+		q.Name, kind, data, when)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+
+	atomic.AddInt64(&q.enqueued, 1)
+
+	return id, nil
+}
+
+// Stats returns the queue's counters.
+func (q *Queue) Stats() QueueStats {
+	return QueueStats{
+		Enqueued:  atomic.LoadInt64(&q.enqueued),
+		Completed: atomic.LoadInt64(&q.completed),
+		Retried:   atomic.LoadInt64(&q.retried),
+		Failed:    atomic.LoadInt64(&q.failed),
+	}
+}
+
+// Work starts the given number of workers pulling jobs from the queue, and blocks until ctx is
+// canceled and every worker has stopped.  A job that's running when ctx is canceled is rolled
+// back and will be claimed again later, so handlers should be safe to retry.
+func (q *Queue) Work(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			q.worker(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// worker claims and runs jobs until ctx is canceled.
+func (q *Queue) worker(ctx context.Context) {
+	interval := q.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	max := defaultWorkerBackoffMax
+	if interval > max {
+		max = interval
+	}
+	backoff := ExponentialBackoff(interval, max)
+
+	var failures int
+	for {
+		found, err := q.RunNext(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			q.report(err)
+		}
+
+		wait := interval
+		switch {
+		case err != nil && err != ErrLeaseExpired:
+			// A job outliving its lease is the job's problem; anything else is the queue's
+			failures++
+			wait = jitter(backoff(failures))
+		case found:
+			failures = 0
+			continue
+		default:
+			failures = 0
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RunNext claims the next available job and runs it.  Returns false if there were no jobs
+// available.  Returns an error if the job couldn't be claimed or its outcome couldn't be
+// recorded; a handler error is recorded against the job for retry and not returned.  Work calls
+// this in a loop; call it directly for finer control, or in tests.
+func (q *Queue) RunNext(ctx context.Context) (bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if q.VisibilityTimeout > 0 {
+		return q.runLeased(ctx)
+	}
+
+	tx, err := q.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Close(ctx)
+
+	var job Job
+	row := tx.QueryRow(ctx, fmt.Sprintf(`
+		SELECT id, queue, kind, payload, attempts, run_at, created_at, coalesce(last_error, '')
+		FROM %s
+		WHERE queue = $1 AND run_at <= now() AND failed_at IS NULL
+		ORDER BY run_at, id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, q.table()), q.Name)
+	if err := row.Scan(&job.ID, &job.Queue, &job.Kind, &job.Payload, &job.Attempts, &job.RunAt,
+		&job.CreatedAt, &job.LastError); err != nil {
+		if NoRows(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := q.run(ctx, tx, &job); err != nil {
+		if err := q.retry(ctx, tx, &job, err); err != nil {
+			return true, err
+		}
+		return true, tx.Commit(ctx)
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", q.table()), job.ID); err != nil {
+		return true, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return true, err
+	}
+
+	atomic.AddInt64(&q.completed, 1)
+
+	return true, nil
+}
+
+// runLeased claims the next available job with a lease, then runs it in a separate transaction,
+// extending the lease until the job completes.
+func (q *Queue) runLeased(ctx context.Context) (bool, error) {
+	job, err := q.claim(ctx)
+	if err != nil {
+		if NoRows(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// A worker ran out of time on the job's last attempt
+	if maxAttempts, _ := q.policy(job.Kind); job.Attempts >= maxAttempts {
+		job.Attempts--
+		return true, q.retry(ctx, q.db, job, ErrLeaseExpired)
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	heartbeat := make(chan error, 1)
+	go func() {
+		heartbeat <- q.heartbeat(jobCtx, cancel, job)
+	}()
+
+	err = q.finish(jobCtx, job)
+
+	cancel()
+	if hbErr := <-heartbeat; hbErr != nil {
+		return true, hbErr
+	}
+
+	if err == nil {
+		atomic.AddInt64(&q.completed, 1)
+	}
+
+	return true, err
+}
+
+// queryRowOnce runs a query that changes the queue without the DB's reconnect policy, so a job
+// isn't added or claimed twice if the connection drops after the query is sent.
+func queryRowOnce(ctx context.Context, conn Conn, sql string, args ...interface{}) pgx.Row {
+	if db, ok := conn.(*DB); ok {
+		return db.queryRow(ctx, sql, args)
+	}
+
+	return conn.QueryRow(ctx, sql, args...)
+}
+
+// claim leases the next available job.  A job whose lease has expired is available again, and
+// its expired attempt is counted.
+func (q *Queue) claim(ctx context.Context) (*Job, error) {
+	table := q.table()
+
+	var job Job
+	row := queryRowOnce(ctx, q.db, fmt.Sprintf(`
+		UPDATE %s
+		SET attempts = attempts + (locked_until IS NOT NULL)::int,
+			lease = lease + 1,
+			locked_until = now() + $2::interval
+		WHERE id = (
+			SELECT id FROM %s
+			WHERE queue = $1 AND run_at <= now() AND failed_at IS NULL
+				AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING id, queue, kind, payload, attempts, run_at, created_at, coalesce(last_error, ''), lease`,
+		table, table), q.Name, q.VisibilityTimeout)
+	if err := row.Scan(&job.ID, &job.Queue, &job.Kind, &job.Payload, &job.Attempts, &job.RunAt,
+		&job.CreatedAt, &job.LastError, &job.lease); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// finish runs the leased job in a transaction and records the outcome, provided the lease is
+// still held.
+func (q *Queue) finish(ctx context.Context, job *Job) error {
+	tx, err := q.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Close(ctx)
+
+	if err := q.run(ctx, tx, job); err != nil {
+		if err := q.retry(ctx, tx, job, err); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1 AND lease = $2", q.table()),
+		job.ID, job.lease)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseExpired
+	}
+
+	return tx.Commit(ctx)
+}
+
+// heartbeat extends the job's lease every third of the visibility timeout until ctx is canceled.
+// If the lease has been lost, cancels the job and returns ErrLeaseExpired.
+func (q *Queue) heartbeat(ctx context.Context, cancel context.CancelFunc, job *Job) error {
+	ticker := time.NewTicker(q.VisibilityTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		tag, err := q.db.Exec(ctx, fmt.Sprintf(`
+			UPDATE %s SET locked_until = now() + $3::interval
+			WHERE id = $1 AND lease = $2`, q.table()),
+			job.ID, job.lease, q.VisibilityTimeout)
+		if err != nil {
+			// Try again on the next tick; the lease is long enough to survive a missed beat
+			continue
+		}
+
+		if tag.RowsAffected() == 0 {
+			cancel()
+			return ErrLeaseExpired
+		}
+	}
+}
+
+// run calls the job's handler in a savepoint, so the handler's changes can be rolled back
+// without losing the claim on the job.
+func (q *Queue) run(ctx context.Context, tx Conn, job *Job) (err error) {
+	q.mutex.RLock()
+	handler, ok := q.handlers[job.Kind]
+	q.mutex.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("no handler registered for %q jobs", job.Kind)
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer savepoint.Close(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	if err := handler(ctx, savepoint, job); err != nil {
+		return err
+	}
+
+	return savepoint.Commit(ctx)
+}
+
+// retry records the failure and schedules the job to run again, or moves it to the dead-letter
+// table if it has run out of attempts.
+func (q *Queue) retry(ctx context.Context, tx Conn, job *Job, cause error) error {
+	attempts := job.Attempts + 1
+	maxAttempts, backoff := q.policy(job.Kind)
+
+	if attempts >= maxAttempts {
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`
+			WITH dead AS (
+				DELETE FROM %s WHERE id = $1%s
+				RETURNING id, queue, kind, payload, created_at)
+			INSERT INTO %s (id, queue, kind, payload, attempts, created_at, last_error)
+			SELECT id, queue, kind, payload, $2::integer, created_at, $3::text FROM dead`,
+			q.table(), q.leased(job), q.deadTable()),
+			job.ID, attempts, cause.Error())
+		if err != nil {
+			return err
+		} else if tag.RowsAffected() == 0 {
+			return ErrLeaseExpired
+		}
+
+		atomic.AddInt64(&q.failed, 1)
+		return nil
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE %s SET attempts = $2, last_error = $3, run_at = now() + $4::interval%s`,
+		q.table(), q.release(job)),
+		job.ID, attempts, cause.Error(), backoff(attempts))
+	if err != nil {
+		return err
+	} else if tag.RowsAffected() == 0 {
+		return ErrLeaseExpired
+	}
+
+	atomic.AddInt64(&q.retried, 1)
+	return nil
+}
+
+// policy returns the maximum attempts and backoff for the kind of job.
+func (q *Queue) policy(kind string) (int, func(attempts int) time.Duration) {
+	q.mutex.RLock()
+	policy := q.policies[kind]
+	q.mutex.RUnlock()
+
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = q.MaxAttempts
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	backoff := policy.Backoff
+	if backoff == nil {
+		backoff = q.Backoff
+	}
+	if backoff == nil {
+		backoff = ExponentialBackoff(defaultBackoffBase, defaultBackoffMax)
+	}
+
+	return maxAttempts, backoff
+}
+
+// release returns the end of the UPDATE statement recording a job's outcome.  A leased job's
+// lease is released, but only if it's still held.
+func (q *Queue) release(job *Job) string {
+	if job.lease == 0 {
+		return " WHERE id = $1"
+	}
+
+	return ", locked_until = NULL WHERE id = $1" + q.leased(job)
+}
+
+// leased returns the condition matching a leased job only while the lease is held.
+func (q *Queue) leased(job *Job) string {
+	if job.lease == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" AND lease = %d", job.lease)
+}
+
+// deadTable returns the sanitized dead-letter table name.
+func (q *Queue) deadTable() string {
+	if q.DeadLetterTable == "" {
+		return pgx.Identifier{q.tableName() + DeadLetterSuffix}.Sanitize()
+	}
+	return pgx.Identifier{q.DeadLetterTable}.Sanitize()
+}
+
+// table returns the sanitized table name.
+func (q *Queue) table() string {
+	return pgx.Identifier{q.tableName()}.Sanitize()
+}
+
+// tableName returns the configured table name, or the default.
+func (q *Queue) tableName() string {
+	if q.Table == "" {
+		return DefaultQueueTable
+	}
+	return q.Table
+}
+
+// report passes a recovered error to OnError.
+func (q *Queue) report(err error) {
+	if q.OnError != nil {
+		q.OnError(err)
+	}
+}
+
+// ExponentialBackoff returns a backoff function that doubles the delay with each attempt,
+// starting at base and never exceeding max.
+func ExponentialBackoff(base, max time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		delay := base
+		for i := 1; i < attempts; i++ {
+			delay *= 2
+			if delay >= max || delay <= 0 {
+				return max
+			}
+		}
+
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
@@ -0,0 +1,303 @@
+package hermes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// connectQueue opens the test database, skipping the test if it isn't available, and creates a
+// queue in its own tables, dropped when the test completes.
+func connectQueue(t *testing.T) (*hermes.DB, *hermes.Queue) {
+	t.Helper()
+
+	db := connect(t)
+
+	q := hermes.NewQueue(db, "test")
+	q.Table = "hermes_test_jobs"
+
+	if err := q.CreateTable(nil, db); err != nil {
+		t.Fatalf("Unable to create the queue tables: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := db.Exec(nil, "DROP TABLE hermes_test_jobs, hermes_test_jobs_dead"); err != nil {
+			t.Errorf("Unable to drop the queue tables: %s", err)
+		}
+	})
+
+	return db, q
+}
+
+// countJobs returns the number of jobs in the table matching the condition.
+func countJobs(t *testing.T, db *hermes.DB, table, where string, args ...interface{}) int {
+	t.Helper()
+
+	var n int
+	if err := db.QueryRow(nil, "SELECT count(*) FROM "+table+" WHERE "+where, args...).Scan(&n); err != nil {
+		t.Fatalf("Unable to count jobs: %s", err)
+	}
+
+	return n
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := hermes.ExponentialBackoff(time.Second, time.Minute)
+
+	expected := map[int]time.Duration{
+		1:   time.Second,
+		2:   2 * time.Second,
+		3:   4 * time.Second,
+		6:   32 * time.Second,
+		7:   time.Minute,
+		100: time.Minute,
+	}
+
+	for attempts, delay := range expected {
+		if check := backoff(attempts); check != delay {
+			t.Errorf("Expected attempt %d to back off %s; was %s", attempts, delay, check)
+		}
+	}
+}
+
+func TestQueueWorkerBackoff(t *testing.T) {
+	q := hermes.NewQueue(hermes.NewUnreachableDB(t), "test")
+	q.PollInterval = 5 * time.Millisecond
+
+	var errs []error
+	q.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	q.Work(ctx, 1)
+
+	// Polling every 5ms would fail about 20 times; backing off fails about 5
+	if len(errs) == 0 || len(errs) > 10 {
+		t.Errorf("Expected the worker to report its errors and back off; was %d errors", len(errs))
+	}
+}
+
+func TestQueueClaim(t *testing.T) {
+	db, q := connectQueue(t)
+
+	var ran []string
+	q.Handle("email", func(ctx context.Context, conn hermes.Conn, job *hermes.Job) error {
+		var to string
+		if err := job.Decode(&to); err != nil {
+			return err
+		}
+		ran = append(ran, to)
+		return nil
+	})
+
+	first, err := q.Enqueue(nil, db, "email", "alice")
+	if err != nil {
+		t.Fatalf("Unable to enqueue a job: %s", err)
+	}
+
+	if _, err := q.Enqueue(nil, db, "email", "bob"); err != nil {
+		t.Fatalf("Unable to enqueue a job: %s", err)
+	}
+
+	// Another worker holding the first job is skipped over
+	tx, err := db.Begin(nil)
+	if err != nil {
+		t.Fatalf("Unable to begin a transaction: %s", err)
+	}
+	defer tx.Close(nil)
+
+	if _, err := tx.Exec(nil, "SELECT id FROM hermes_test_jobs WHERE id = $1 FOR UPDATE", first); err != nil {
+		t.Fatalf("Unable to lock the first job: %s", err)
+	}
+
+	if found, err := q.RunNext(nil); err != nil || !found {
+		t.Fatalf("Expected to claim the second job; was %v, %v", found, err)
+	}
+
+	if len(ran) != 1 || ran[0] != "bob" {
+		t.Errorf("Expected the second job to run; was %v", ran)
+	}
+
+	if found, err := q.RunNext(nil); err != nil || found {
+		t.Errorf("Expected no unlocked jobs; was %v, %v", found, err)
+	}
+
+	if err := tx.Rollback(nil); err != nil {
+		t.Fatalf("Unable to release the first job: %s", err)
+	}
+
+	if found, err := q.RunNext(nil); err != nil || !found {
+		t.Fatalf("Expected to claim the first job; was %v, %v", found, err)
+	}
+
+	if len(ran) != 2 || ran[1] != "alice" {
+		t.Errorf("Expected the first job to run; was %v", ran)
+	}
+
+	if n := countJobs(t, db, "hermes_test_jobs", "true"); n != 0 {
+		t.Errorf("Expected the completed jobs to be deleted; %d left", n)
+	}
+
+	if stats := q.Stats(); stats.Enqueued != 2 || stats.Completed != 2 {
+		t.Errorf("Expected 2 jobs enqueued and completed; was %+v", stats)
+	}
+}
+
+func TestQueueLeaseExpired(t *testing.T) {
+	db, q := connectQueue(t)
+	q.VisibilityTimeout = time.Minute
+
+	var attempts []int
+	q.Handle("report", func(ctx context.Context, conn hermes.Conn, job *hermes.Job) error {
+		attempts = append(attempts, job.Attempts)
+		return nil
+	})
+
+	id, err := q.Enqueue(nil, db, "report", nil)
+	if err != nil {
+		t.Fatalf("Unable to enqueue a job: %s", err)
+	}
+
+	// Another worker claimed the job, and its lease is still good
+	if _, err := db.Exec(nil, `UPDATE hermes_test_jobs SET lease = 1, locked_until = now() + interval '1 hour'
+		WHERE id = $1`, id); err != nil {
+		t.Fatalf("Unable to lease the job: %s", err)
+	}
+
+	if found, err := q.RunNext(nil); err != nil || found {
+		t.Fatalf("Expected the leased job not to be claimed; was %v, %v", found, err)
+	}
+
+	// The worker crashed, and its lease expired
+	if _, err := db.Exec(nil, "UPDATE hermes_test_jobs SET locked_until = now() - interval '1 second' WHERE id = $1",
+		id); err != nil {
+		t.Fatalf("Unable to expire the lease: %s", err)
+	}
+
+	if found, err := q.RunNext(nil); err != nil || !found {
+		t.Fatalf("Expected the expired job to be claimed again; was %v, %v", found, err)
+	}
+
+	if len(attempts) != 1 || attempts[0] != 1 {
+		t.Errorf("Expected the expired lease to count as an attempt; was %v", attempts)
+	}
+
+	if n := countJobs(t, db, "hermes_test_jobs", "id = $1", id); n != 0 {
+		t.Errorf("Expected the job to be completed; %d left", n)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	db, q := connectQueue(t)
+	q.Backoff = func(attempts int) time.Duration {
+		return time.Duration(attempts) * time.Hour
+	}
+
+	q.Handle("sync", func(ctx context.Context, conn hermes.Conn, job *hermes.Job) error {
+		return errors.New("remote unavailable")
+	})
+
+	id, err := q.Enqueue(nil, db, "sync", nil)
+	if err != nil {
+		t.Fatalf("Unable to enqueue a job: %s", err)
+	}
+
+	if found, err := q.RunNext(nil); err != nil || !found {
+		t.Fatalf("Expected the job to run; was %v, %v", found, err)
+	}
+
+	var attempts int
+	var lastError string
+	var backoff float64
+	if err := db.QueryRow(nil, `
+		SELECT attempts, last_error, extract(epoch FROM run_at - now())::float8
+		FROM hermes_test_jobs
+		WHERE id = $1`, id).Scan(&attempts, &lastError, &backoff); err != nil {
+		t.Fatalf("Unable to load the job: %s", err)
+	}
+
+	if attempts != 1 || lastError != "remote unavailable" {
+		t.Errorf("Expected 1 attempt failing with remote unavailable; was %d with %q", attempts, lastError)
+	}
+
+	if backoff < 3540 || backoff > 3600 {
+		t.Errorf("Expected the job to back off an hour; was %.0f seconds", backoff)
+	}
+
+	// Not due to run again yet
+	if found, err := q.RunNext(nil); err != nil || found {
+		t.Errorf("Expected the job to wait out its backoff; was %v, %v", found, err)
+	}
+
+	if stats := q.Stats(); stats.Retried != 1 {
+		t.Errorf("Expected 1 retry; was %d", stats.Retried)
+	}
+}
+
+func TestQueueDeadLetter(t *testing.T) {
+	db, q := connectQueue(t)
+	q.SetRetryPolicy("charge", hermes.RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     func(int) time.Duration { return 0 },
+	})
+
+	fail := true
+	q.Handle("charge", func(ctx context.Context, conn hermes.Conn, job *hermes.Job) error {
+		if fail {
+			return errors.New("card declined")
+		}
+		return nil
+	})
+
+	id, err := q.Enqueue(nil, db, "charge", map[string]int{"amount": 5})
+	if err != nil {
+		t.Fatalf("Unable to enqueue a job: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if found, err := q.RunNext(nil); err != nil || !found {
+			t.Fatalf("Expected attempt %d to run; was %v, %v", i+1, found, err)
+		}
+	}
+
+	if found, err := q.RunNext(nil); err != nil || found {
+		t.Errorf("Expected the job to be out of the queue; was %v, %v", found, err)
+	}
+
+	dead, err := q.DeadJobs(nil, db, 10)
+	if err != nil {
+		t.Fatalf("Unable to list the dead jobs: %s", err)
+	}
+
+	if len(dead) != 1 || dead[0].ID != id {
+		t.Fatalf("Expected job %d to be dead-lettered; was %v", id, dead)
+	}
+
+	if dead[0].Attempts != 2 || dead[0].LastError != "card declined" {
+		t.Errorf("Expected 2 attempts failing with card declined; was %d with %q", dead[0].Attempts, dead[0].LastError)
+	}
+
+	if stats := q.Stats(); stats.Retried != 1 || stats.Failed != 1 {
+		t.Errorf("Expected 1 retry and 1 failure; was %+v", stats)
+	}
+
+	requeued, err := q.Requeue(nil, db, id)
+	if err != nil || requeued != 1 {
+		t.Fatalf("Expected the job to be requeued; was %d, %v", requeued, err)
+	}
+
+	fail = false
+	if found, err := q.RunNext(nil); err != nil || !found {
+		t.Fatalf("Expected the requeued job to run; was %v, %v", found, err)
+	}
+
+	if n := countJobs(t, db, "hermes_test_jobs_dead", "true"); n != 0 {
+		t.Errorf("Expected the dead-letter table to be empty; %d left", n)
+	}
+}
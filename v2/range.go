@@ -0,0 +1,217 @@
+package hermes
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrRangeBounds is returned by NewRangeBounds if the bounds aren't one of "[)", "[]", "(]", or
+// "()".
+var ErrRangeBounds = errors.New("invalid range bounds")
+
+// Range is a PostgreSQL range of values of type T, such as an int4range, int8range, or tstzrange.
+// Ranges scan from and encode to any range type whose elements T can represent:
+//
+//	var hours hermes.Range[time.Time]
+//	err := conn.QueryRow(ctx, "select hours from shifts where id = $1", id).Scan(&hours)
+//
+//	if hours.HasUpper() { ... }
+//
+// The bounds' types are pgtype.Inclusive, pgtype.Exclusive, or pgtype.Unbounded, or
+// pgtype.Empty for an empty range.  An SQL NULL scans as an invalid Range.
+type Range[T any] struct {
+	Lower     T
+	Upper     T
+	LowerType pgtype.BoundType
+	UpperType pgtype.BoundType
+	Valid     bool
+}
+
+// NewRange returns the range including lower and excluding upper, i.e. [lower,upper), the form
+// PostgreSQL uses for discrete ranges.
+func NewRange[T any](lower, upper T) Range[T] {
+	return Range[T]{Lower: lower, Upper: upper, LowerType: pgtype.Inclusive, UpperType: pgtype.Exclusive,
+		Valid: true}
+}
+
+// NewRangeBounds returns the range between lower and upper, with bounds in the same form as
+// PostgreSQL's range constructors:  "[)", "[]", "(]", or "()", where a square bracket includes the
+// bound and a parenthesis excludes it.
+func NewRangeBounds[T any](lower, upper T, bounds string) (Range[T], error) {
+	if len(bounds) != 2 {
+		return Range[T]{}, fmt.Errorf("%w: %q", ErrRangeBounds, bounds)
+	}
+
+	r := Range[T]{Lower: lower, Upper: upper, Valid: true}
+
+	switch bounds[0] {
+	case '[':
+		r.LowerType = pgtype.Inclusive
+	case '(':
+		r.LowerType = pgtype.Exclusive
+	default:
+		return Range[T]{}, fmt.Errorf("%w: %q", ErrRangeBounds, bounds)
+	}
+
+	switch bounds[1] {
+	case ']':
+		r.UpperType = pgtype.Inclusive
+	case ')':
+		r.UpperType = pgtype.Exclusive
+	default:
+		return Range[T]{}, fmt.Errorf("%w: %q", ErrRangeBounds, bounds)
+	}
+
+	return r, nil
+}
+
+// RangeFrom returns the range of values greater than or equal to lower, i.e. [lower,).
+func RangeFrom[T any](lower T) Range[T] {
+	return Range[T]{Lower: lower, LowerType: pgtype.Inclusive, UpperType: pgtype.Unbounded, Valid: true}
+}
+
+// RangeTo returns the range of values less than upper, i.e. (,upper).
+func RangeTo[T any](upper T) Range[T] {
+	return Range[T]{Upper: upper, LowerType: pgtype.Unbounded, UpperType: pgtype.Exclusive, Valid: true}
+}
+
+// EmptyRange returns a range containing no values.
+func EmptyRange[T any]() Range[T] {
+	return Range[T]{LowerType: pgtype.Empty, UpperType: pgtype.Empty, Valid: true}
+}
+
+// IsEmpty returns true if the range contains no values.
+func (r Range[T]) IsEmpty() bool {
+	return r.LowerType == pgtype.Empty
+}
+
+// HasLower returns true if the range has a lower bound.
+func (r Range[T]) HasLower() bool {
+	return r.LowerType == pgtype.Inclusive || r.LowerType == pgtype.Exclusive
+}
+
+// HasUpper returns true if the range has an upper bound.
+func (r Range[T]) HasUpper() bool {
+	return r.UpperType == pgtype.Inclusive || r.UpperType == pgtype.Exclusive
+}
+
+// LowerInclusive returns true if the range includes its lower bound.
+func (r Range[T]) LowerInclusive() bool {
+	return r.LowerType == pgtype.Inclusive
+}
+
+// UpperInclusive returns true if the range includes its upper bound.
+func (r Range[T]) UpperInclusive() bool {
+	return r.UpperType == pgtype.Inclusive
+}
+
+// String returns the range in PostgreSQL's format, e.g. "[1,10)", "empty", or "NULL".
+func (r Range[T]) String() string {
+	if !r.Valid {
+		return "NULL"
+	}
+
+	if r.IsEmpty() {
+		return "empty"
+	}
+
+	var b strings.Builder
+
+	if r.LowerInclusive() {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte('(')
+	}
+
+	if r.HasLower() {
+		fmt.Fprint(&b, r.Lower)
+	}
+
+	b.WriteByte(',')
+
+	if r.HasUpper() {
+		fmt.Fprint(&b, r.Upper)
+	}
+
+	if r.UpperInclusive() {
+		b.WriteByte(']')
+	} else {
+		b.WriteByte(')')
+	}
+
+	return b.String()
+}
+
+// IsNull implements pgtype.RangeValuer.
+func (r Range[T]) IsNull() bool {
+	return !r.Valid
+}
+
+// BoundTypes implements pgtype.RangeValuer.
+func (r Range[T]) BoundTypes() (lower, upper pgtype.BoundType) {
+	return r.LowerType, r.UpperType
+}
+
+// Bounds implements pgtype.RangeValuer.
+func (r Range[T]) Bounds() (lower, upper interface{}) {
+	return &r.Lower, &r.Upper
+}
+
+// ScanNull implements pgtype.RangeScanner.
+func (r *Range[T]) ScanNull() error {
+	*r = Range[T]{}
+	return nil
+}
+
+// ScanBounds implements pgtype.RangeScanner.
+func (r *Range[T]) ScanBounds() (lower, upper interface{}) {
+	return &r.Lower, &r.Upper
+}
+
+// SetBoundTypes implements pgtype.RangeScanner.
+func (r *Range[T]) SetBoundTypes(lower, upper pgtype.BoundType) error {
+	var zero T
+
+	if lower == pgtype.Unbounded || lower == pgtype.Empty {
+		r.Lower = zero
+	}
+
+	if upper == pgtype.Unbounded || upper == pgtype.Empty {
+		r.Upper = zero
+	}
+
+	r.LowerType = lower
+	r.UpperType = upper
+	r.Valid = true
+
+	return nil
+}
+
+// rangeTypes map Ranges of Go types to the PostgreSQL range types they're encoded as by default.
+var rangeTypes = []struct {
+	value interface{}
+	name  string
+}{
+	{Range[int32]{}, "int4range"},
+	{Range[int64]{}, "int8range"},
+	{Range[int]{}, "int8range"},
+	{Range[float64]{}, "numrange"},
+	{Range[time.Time]{}, "tstzrange"},
+	{Range[pgtype.Date]{}, "daterange"},
+}
+
+// registerRanges maps the common Range types, and slices of them, to the built-in range types in
+// the type map.
+func registerRanges(m *pgtype.Map) {
+	for _, rt := range rangeTypes {
+		m.RegisterDefaultPgType(rt.value, rt.name)
+
+		slice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(rt.value)), 0, 0).Interface()
+		m.RegisterDefaultPgType(slice, arrayName(rt.name))
+	}
+}
@@ -0,0 +1,131 @@
+package hermes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRange(t *testing.T) {
+	m := pgtype.NewMap()
+	registerRanges(m)
+
+	r := NewRange[int32](1, 10)
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(pgtype.Int4rangeOID, format, r, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode range in format %d: %s", format, err)
+		}
+
+		var check Range[int32]
+		if err := m.Scan(pgtype.Int4rangeOID, format, buf, &check); err != nil {
+			t.Fatalf("Unable to scan range in format %d: %s", format, err)
+		}
+
+		if check != r {
+			t.Errorf("Expected %s; was %s", r, check)
+		}
+	}
+
+	var check Range[int64]
+	if err := m.Scan(pgtype.Int8rangeOID, pgtype.TextFormatCode, []byte("[5,)"), &check); err != nil {
+		t.Fatalf("Unable to scan unbounded range: %s", err)
+	}
+
+	if check != RangeFrom[int64](5) {
+		t.Errorf("Expected [5,); was %s", check)
+	}
+
+	if err := m.Scan(pgtype.Int8rangeOID, pgtype.TextFormatCode, []byte("empty"), &check); err != nil {
+		t.Fatalf("Unable to scan empty range: %s", err)
+	}
+
+	if !check.IsEmpty() {
+		t.Errorf("Expected empty; was %s", check)
+	}
+
+	if err := m.Scan(pgtype.Int8rangeOID, pgtype.TextFormatCode, nil, &check); err != nil {
+		t.Fatalf("Unable to scan NULL range: %s", err)
+	}
+
+	if check.Valid {
+		t.Errorf("Expected NULL; was %s", check)
+	}
+}
+
+func TestRangeTime(t *testing.T) {
+	m := pgtype.NewMap()
+	registerRanges(m)
+
+	start := time.Date(2022, 10, 1, 9, 0, 0, 0, time.UTC)
+	r, err := NewRangeBounds(start, start.Add(8*time.Hour), "[]")
+	if err != nil {
+		t.Fatalf("Unable to create range: %s", err)
+	}
+
+	dt, ok := m.TypeForValue(r)
+	if !ok || dt.OID != pgtype.TstzrangeOID {
+		t.Fatal("Expected Range[time.Time] to map to tstzrange")
+	}
+
+	buf, err := m.Encode(pgtype.TstzrangeOID, pgtype.BinaryFormatCode, r, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode range: %s", err)
+	}
+
+	var check Range[time.Time]
+	if err := m.Scan(pgtype.TstzrangeOID, pgtype.BinaryFormatCode, buf, &check); err != nil {
+		t.Fatalf("Unable to scan range: %s", err)
+	}
+
+	if !check.Lower.Equal(r.Lower) || !check.Upper.Equal(r.Upper) || !check.LowerInclusive() || !check.UpperInclusive() {
+		t.Errorf("Expected %s; was %s", r, check)
+	}
+
+	if dt, ok := m.TypeForValue([]Range[time.Time]{}); !ok || dt.OID != pgtype.TstzrangeArrayOID {
+		t.Error("Expected []Range[time.Time] to map to the tstzrange array type")
+	}
+}
+
+func TestRangeBounds(t *testing.T) {
+	tests := []struct {
+		bounds   string
+		expected string
+	}{
+		{"[)", "[1,5)"},
+		{"[]", "[1,5]"},
+		{"(]", "(1,5]"},
+		{"()", "(1,5)"},
+	}
+
+	for _, test := range tests {
+		r, err := NewRangeBounds(1, 5, test.bounds)
+		if err != nil {
+			t.Errorf("Unable to create range with bounds %s: %s", test.bounds, err)
+			continue
+		}
+
+		if r.String() != test.expected {
+			t.Errorf("Expected %s; was %s", test.expected, r)
+		}
+	}
+
+	if _, err := NewRangeBounds(1, 5, "[["); !errors.Is(err, ErrRangeBounds) {
+		t.Errorf("Expected ErrRangeBounds; was %v", err)
+	}
+
+	if r := RangeTo(5); r.HasLower() || !r.HasUpper() || r.String() != "(,5)" {
+		t.Errorf("Expected (,5); was %s", r)
+	}
+
+	if r := EmptyRange[int](); r.String() != "empty" || r.HasLower() || r.HasUpper() {
+		t.Errorf("Expected empty; was %s", r)
+	}
+
+	if r := (Range[int]{}); r.String() != "NULL" {
+		t.Errorf("Expected NULL; was %s", r)
+	}
+}
@@ -0,0 +1,243 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// Default delays before retrying a statement after a disconnect, doubling from the base up
+	// to the max, giving a restarting or failing over server time to come back.
+	defaultReconnectBase = 100 * time.Millisecond
+	defaultReconnectMax  = 2 * time.Second
+)
+
+// ReconnectPolicy configures DB.SetReconnectPolicy.
+type ReconnectPolicy struct {
+	// MaxAttempts is the most times a statement is run, including the first.  Defaults to 5; set
+	// it to 1 to turn off reconnecting.
+	MaxAttempts int
+
+	// Backoff returns how long to wait before the next attempt, given the number of attempts so
+	// far.  The wait is randomized between half and all of the backoff.  Defaults to exponential
+	// backoff from 100ms up to 2 seconds.
+	Backoff func(attempts int) time.Duration
+
+	// OnRetry is called before each retry with the statement, the number of attempts so far, and
+	// the error, for logging.
+	OnRetry func(sql string, attempts int, err error)
+}
+
+type idempotentKey struct{}
+
+// Idempotent marks the statements run with the context as safe to run more than once, so DB.Exec,
+// DB.Query and DB.QueryRow retry them after any disconnect.  See DB.SetReconnectPolicy.
+func Idempotent(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// isIdempotent returns true if the context was marked with Idempotent.
+func isIdempotent(ctx context.Context) bool {
+	idempotent, _ := ctx.Value(idempotentKey{}).(bool)
+	return idempotent
+}
+
+// SetReconnectPolicy retries the DB's queries when the connection is lost, e.g. because the
+// server restarted or failed over, or can't be reached.  Each attempt acquires a fresh
+// connection from the pool, which discards the broken one.  Retries wait with exponential backoff
+// and jitter, and stop once policy.MaxAttempts is reached or ctx is done.
+//
+// Query and QueryRow are retried if the connection failed before the query was sent, e.g. the
+// server couldn't be reached, as a query may write too, e.g. "INSERT ... RETURNING".  If ctx is
+// marked with Idempotent, they're also retried if the connection was lost afterwards.  A query's
+// rows are only retried until the first row is read; Query waits for the first row before
+// returning.  Exec is only retried if its context is marked with Idempotent, since the command
+// may have taken effect before the connection was lost:
+//
+//	_, err := db.Exec(hermes.Idempotent(ctx), "UPDATE accounts SET active = true WHERE id = $1", id)
+//
+// Statements in transactions aren't retried, as the transaction is lost with its connection; see
+// DB.WithRetry to retry whole transactions.  Each retry is reported to the DB's Metrics.
+//
+// Call SetReconnectPolicy before using the DB.
+func (db *DB) SetReconnectPolicy(policy ReconnectPolicy) {
+	db.reconnect = &policy
+}
+
+// retryOptions returns the options for retrying the statement when retryable returns true for its
+// error.
+func (db *DB) retryOptions(sql string, retryable func(error) bool) RetryOptions {
+	policy := db.reconnect
+
+	backoff := policy.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(defaultReconnectBase, defaultReconnectMax)
+	}
+
+	return RetryOptions{
+		MaxAttempts: policy.MaxAttempts,
+		Backoff:     backoff,
+		Retryable:   retryable,
+		OnRetry: func(attempts int, err error) {
+			atomic.AddInt64(&db.retries, 1)
+			db.metrics().ObserveReconnect(sql, attempts, err)
+
+			if policy.OnRetry != nil {
+				policy.OnRetry(sql, attempts, err)
+			}
+		},
+	}
+}
+
+// isReconnectable returns true if the error, or any error it wraps, means the connection was
+// lost or couldn't be made, rather than the statement failed:  a disconnect error from the
+// server, other than a canceled query, an unexpected EOF, a network error, including failing to
+// connect, or an error pgconn reports happened before anything was sent to the server.
+func isReconnectable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// A canceled query was canceled on purpose, e.g. by statement_timeout
+		if pgErr.Code == QueryCanceled {
+			return false
+		}
+
+		for _, code := range Disconnects {
+			if pgErr.Code == code {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var safeErr interface{ SafeToRetry() bool }
+	return errors.As(err, &safeErr) && safeErr.SafeToRetry()
+}
+
+// isUnsent returns true if the error, or any error it wraps, means the connection failed before
+// the statement was sent to the server, so it can't have taken effect:  an error pgconn reports
+// as safe to retry, failing to dial the server, or the server refusing connections while it
+// starts up.
+func isUnsent(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var safeErr interface{ SafeToRetry() bool }
+	if errors.As(err, &safeErr) && safeErr.SafeToRetry() {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == CannotConnectNow
+}
+
+// queryRetryable returns the check for whether a query run with ctx may be retried:  any
+// disconnect if ctx is marked with Idempotent, otherwise only if the query wasn't sent.
+func queryRetryable(ctx context.Context) func(error) bool {
+	if isIdempotent(ctx) {
+		return isReconnectable
+	}
+
+	return isUnsent
+}
+
+// reconnectExec runs the command, retrying it after a disconnect.
+func (db *DB) reconnectExec(ctx context.Context, sql string, args []interface{}) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+
+	err := retry(ctx, db.retryOptions(sql, isReconnectable), func() error {
+		var err error
+		tag, err = db.exec(ctx, sql, args)
+		return err
+	})
+
+	return tag, err
+}
+
+// reconnectQuery runs the query and reads the first row, retrying after a disconnect.
+func (db *DB) reconnectQuery(ctx context.Context, sql string, args []interface{}) (pgx.Rows, error) {
+	var rows pgx.Rows
+	var peeked bool
+
+	err := retry(ctx, db.retryOptions(sql, queryRetryable(ctx)), func() error {
+		var err error
+
+		rows, err = db.query(ctx, sql, args)
+		if err != nil {
+			return err
+		}
+
+		peeked = rows.Next()
+		if !peeked {
+			return rows.Err()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reconnectRows{Rows: rows, peeked: peeked}, nil
+}
+
+// reconnectRows hold the first row, read by the query to check the connection.
+type reconnectRows struct {
+	pgx.Rows
+	peeked bool
+}
+
+// Next prepares the next row for reading, starting with the row read by the query.
+func (rows *reconnectRows) Next() bool {
+	if rows.peeked {
+		rows.peeked = false
+		return true
+	}
+
+	return rows.Rows.Next()
+}
+
+// reconnectRow runs the query when scanned, retrying after a disconnect.
+type reconnectRow struct {
+	db   *DB
+	ctx  context.Context
+	sql  string
+	args []interface{}
+}
+
+// Scan runs the query and scans the row.
+func (row *reconnectRow) Scan(dest ...interface{}) error {
+	return retry(row.ctx, row.db.retryOptions(row.sql, queryRetryable(row.ctx)), func() error {
+		return row.db.queryRow(row.ctx, row.sql, row.args).Scan(dest...)
+	})
+}
@@ -0,0 +1,218 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIdempotent(t *testing.T) {
+	if isIdempotent(context.Background()) {
+		t.Error("Expected an unmarked context not to be idempotent")
+	}
+
+	if !isIdempotent(Idempotent(context.Background())) {
+		t.Error("Expected the marked context to be idempotent")
+	}
+}
+
+func TestReconnectRetryOptions(t *testing.T) {
+	m := &recordedMetrics{}
+	db := &DB{}
+	db.SetMetrics(m)
+
+	var retried []int
+	db.SetReconnectPolicy(ReconnectPolicy{
+		OnRetry: func(sql string, attempts int, err error) {
+			retried = append(retried, attempts)
+		},
+	})
+
+	opts := db.retryOptions("select 1", isReconnectable)
+
+	if opts.Backoff(1) != defaultReconnectBase {
+		t.Errorf("Expected a %s backoff; was %s", defaultReconnectBase, opts.Backoff(1))
+	}
+
+	if opts.Retryable(&pgconn.PgError{Code: UniqueViolation}) {
+		t.Error("Expected a unique violation not to be retried")
+	}
+
+	if !opts.Retryable(&pgconn.PgError{Code: AdminShutdown}) {
+		t.Error("Expected an admin shutdown to be retried")
+	}
+
+	opts.OnRetry(1, &pgconn.PgError{Code: AdminShutdown})
+
+	if m.reconnects != 1 {
+		t.Errorf("Expected 1 reconnect observed; was %d", m.reconnects)
+	}
+
+	if len(retried) != 1 || retried[0] != 1 {
+		t.Errorf("Expected OnRetry to be called; was %v", retried)
+	}
+}
+
+func TestReconnectRows(t *testing.T) {
+	rows := &reconnectRows{
+		Rows:   &fakeRows{columns: []string{"id"}, values: [][]interface{}{{1}, {2}}},
+		peeked: true,
+	}
+
+	// The query read the first row already
+	rows.Rows.Next()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Expected ids 1 and 2; was %v", ids)
+	}
+}
+
+func TestReconnectUnavailable(t *testing.T) {
+	ctx := context.Background()
+
+	m := &recordedMetrics{}
+	db := newUnreachableDB(t)
+	db.SetMetrics(m)
+	db.SetReconnectPolicy(ReconnectPolicy{
+		MaxAttempts: 2,
+		Backoff:     func(int) time.Duration { return time.Millisecond },
+	})
+
+	// Failing to connect is retried, in case the server is restarting
+	if _, err := db.Exec(Idempotent(ctx), "select 1"); err == nil {
+		t.Error("Expected an error without a database")
+	}
+
+	if _, err := db.Query(ctx, "select 1"); err == nil {
+		t.Error("Expected an error without a database")
+	}
+
+	var n int
+	if err := db.QueryRow(ctx, "select 1").Scan(&n); err == nil {
+		t.Error("Expected an error without a database")
+	}
+
+	if m.reconnects != 3 {
+		t.Errorf("Expected 3 reconnects; was %d", m.reconnects)
+	}
+}
+
+func TestQueueUnavailableNotRetried(t *testing.T) {
+	m := &recordedMetrics{}
+	db := newUnreachableDB(t)
+	db.SetMetrics(m)
+	db.SetReconnectPolicy(ReconnectPolicy{
+		MaxAttempts: 3,
+		Backoff:     func(int) time.Duration { return time.Millisecond },
+	})
+
+	q := NewQueue(db, "test")
+
+	// Adding and claiming jobs bypass the reconnect policy, so they're never run twice
+	if _, err := q.Enqueue(nil, db, "email", nil); err == nil {
+		t.Error("Expected an error without a database")
+	}
+
+	if _, err := q.claim(context.Background()); err == nil {
+		t.Error("Expected an error without a database")
+	}
+
+	if m.reconnects != 0 {
+		t.Errorf("Expected no reconnects; was %d", m.reconnects)
+	}
+}
+
+func TestIsReconnectable(t *testing.T) {
+	netErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"admin shutdown", &pgconn.PgError{Code: AdminShutdown}, true},
+		{"wrapped admin shutdown", fmt.Errorf("query: %w", &pgconn.PgError{Code: AdminShutdown}), true},
+		{"wrapped cannot connect now", fmt.Errorf("query: %w", &pgconn.PgError{Code: CannotConnectNow}), true},
+		{"query canceled", &pgconn.PgError{Code: QueryCanceled}, false},
+		{"wrapped query canceled", fmt.Errorf("query: %w", &pgconn.PgError{Code: QueryCanceled}), false},
+		{"unique violation", &pgconn.PgError{Code: UniqueViolation}, false},
+		{"EOF", io.EOF, true},
+		{"wrapped EOF", fmt.Errorf("read: %w", io.EOF), true},
+		{"wrapped unexpected EOF", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"network error", netErr, true},
+		{"wrapped network error", fmt.Errorf("failed to connect: %w", netErr), true},
+		{"wrapped safe to retry", fmt.Errorf("send: %w", safeToRetry(true)), true},
+		{"wrapped unsafe to retry", fmt.Errorf("send: %w", safeToRetry(false)), false},
+		{"other", errors.New("no rows"), false},
+	}
+
+	for _, test := range tests {
+		if isReconnectable(test.err) != test.expected {
+			t.Errorf("Expected %s to be reconnectable: %v; was %v", test.name, test.expected, !test.expected)
+		}
+	}
+}
+
+func TestQueryRetryable(t *testing.T) {
+	dialErr := fmt.Errorf("failed to connect: %w",
+		&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name       string
+		err        error
+		unsent     bool
+		idempotent bool
+	}{
+		{"nil", nil, false, false},
+		{"dial error", dialErr, true, true},
+		{"cannot connect now", &pgconn.PgError{Code: CannotConnectNow}, true, true},
+		{"wrapped safe to retry", fmt.Errorf("send: %w", safeToRetry(true)), true, true},
+		{"wrapped unsafe to retry", fmt.Errorf("send: %w", safeToRetry(false)), false, false},
+		{"read error", readErr, false, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, false, true},
+		{"admin shutdown", &pgconn.PgError{Code: AdminShutdown}, false, true},
+		{"unique violation", &pgconn.PgError{Code: UniqueViolation}, false, false},
+	}
+
+	unmarked := queryRetryable(context.Background())
+	marked := queryRetryable(Idempotent(context.Background()))
+
+	for _, test := range tests {
+		if unmarked(test.err) != test.unsent {
+			t.Errorf("Expected %s to be retried: %v; was %v", test.name, test.unsent, !test.unsent)
+		}
+
+		if marked(test.err) != test.idempotent {
+			t.Errorf("Expected %s to be retried when idempotent: %v; was %v", test.name,
+				test.idempotent, !test.idempotent)
+		}
+	}
+}
+
+// safeToRetry is an error pgconn reports as happening before, or after, anything was sent.
+type safeToRetry bool
+
+func (e safeToRetry) Error() string {
+	return "safe to retry"
+}
+
+func (e safeToRetry) SafeToRetry() bool {
+	return bool(e)
+}
@@ -0,0 +1,114 @@
+package hermes
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// redacted replaces passwords in connection details.
+const redacted = "xxxxx"
+
+var (
+	// dsnSecret matches a password setting in a keyword/value connection string.
+	dsnSecret = regexp.MustCompile(`\b((?:ssl)?password)\s*=\s*(?:'(?:[^'\\]|\\.)*'|\S+)`)
+
+	// urlUserinfo matches the user and password of a URL that couldn't be parsed.
+	urlUserinfo = regexp.MustCompile(`^([a-z]+://[^:/@]*):[^@]*@`)
+)
+
+// RedactURI returns the connection string with its passwords masked, so it can be logged.
+// Handles both URLs, e.g. "postgres://app:secret@db/app", and keyword/value connection strings,
+// e.g. "host=db user=app password=secret".
+func RedactURI(uri string) string {
+	if !strings.HasPrefix(uri, "postgres://") && !strings.HasPrefix(uri, "postgresql://") {
+		return dsnSecret.ReplaceAllString(uri, "${1}="+redacted)
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return urlUserinfo.ReplaceAllString(uri, "${1}:"+redacted+"@")
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+
+	query := u.Query()
+	for _, key := range []string{"password", "sslpassword"} {
+		if query.Has(key) {
+			query.Set(key, redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// Config describes a connection pool configuration for logging:  the hosts, database, user, TLS,
+// and pool settings, with the password masked.
+//
+//	log.Printf("Connecting to %s", hermes.Config{config})
+type Config struct {
+	*pgxpool.Config
+}
+
+// String returns the configuration with the password masked.
+func (c Config) String() string {
+	if c.Config == nil || c.ConnConfig == nil {
+		return "<nil>"
+	}
+
+	cc := c.ConnConfig
+
+	hosts := []string{hostPort(cc.Host, cc.Port)}
+	tls := cc.TLSConfig != nil
+	for _, fallback := range cc.Fallbacks {
+		if host := hostPort(fallback.Host, fallback.Port); host != hosts[len(hosts)-1] {
+			hosts = append(hosts, host)
+		}
+		tls = tls || fallback.TLSConfig != nil
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "host=%s database=%s user=%s", strings.Join(hosts, ","), cc.Database, cc.User)
+	if cc.Password != "" {
+		b.WriteString(" password=" + redacted)
+	}
+	fmt.Fprintf(&b, " tls=%t", tls)
+
+	fmt.Fprintf(&b, " min_conns=%d max_conns=%d max_conn_lifetime=%s max_conn_idle_time=%s health_check_period=%s",
+		c.MinConns, c.MaxConns, c.MaxConnLifetime, c.MaxConnIdleTime, c.HealthCheckPeriod)
+
+	return b.String()
+}
+
+// hostPort joins the host and port, leaving off the port for Unix sockets.
+func hostPort(host string, port uint16) string {
+	if strings.HasPrefix(host, "/") {
+		return host
+	}
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	return host + ":" + strconv.Itoa(int(port))
+}
+
+// LogConfig prints the pool's configuration, with the password masked, through printf, e.g.
+// log.Printf, for a record of the connection details at startup.  Reflects any settings applied
+// by Reload.
+func (db *DB) LogConfig(printf func(format string, args ...interface{})) {
+	config := db.Config()
+	if db.reloader != nil {
+		db.reloader.current(config.ConnConfig)
+	}
+
+	printf("Database connection pool: %s", Config{config})
+}
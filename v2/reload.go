@@ -0,0 +1,276 @@
+package hermes
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNotReloadable is returned when reloading the configuration of a DB that wasn't created by
+// Connect or ConnectConfig.
+var ErrNotReloadable = errors.New("connection pool doesn't support reloading")
+
+// Reload applies new connection settings to the pool without interrupting it, e.g. to rotate
+// the database password or TLS certificates.  New connections use the new settings right away;
+// existing connections are retired gradually, closed as they're released back to the pool, or
+// when they're next acquired if they're idle, so queries in flight finish on their connections.
+//
+// The host, port, database, user, password, TLS configuration, fallbacks, connect timeout, and
+// runtime parameters are taken from config.ConnConfig.  Of the pool settings, a MaxConnLifetime
+// retires connections sooner than the pool would; and a MaxConns is applied through the DB's
+// concurrency limit, if it has one, capped at the pool's MaxConns (see LimitConcurrency).  The
+// other pool settings can't be changed once the pool is created, and are ignored.
+//
+// Before applying the settings, Reload opens a connection with them to check they work; if it
+// can't connect, it returns the error and the pool carries on with the old settings.
+func (db *DB) Reload(ctx context.Context, config *pgxpool.Config) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if db.reloader == nil {
+		return ErrNotReloadable
+	}
+
+	settings := newConnSettings(config.ConnConfig)
+
+	check := db.Config().ConnConfig
+	settings.apply(check)
+
+	conn, err := pgx.ConnectConfig(ctx, check)
+	if err != nil {
+		return err
+	}
+	_ = conn.Close(ctx)
+
+	db.reloader.reload(settings, config.MaxConnLifetime)
+
+	if config.MaxConns > 0 && db.limiter != nil {
+		size := int(config.MaxConns)
+		if poolMax := int(db.Config().MaxConns); size > poolMax {
+			size = poolMax
+		}
+
+		db.limiter.resize(size)
+	}
+
+	return nil
+}
+
+// connSettings are the connection settings Reload changes.
+type connSettings struct {
+	host           string
+	port           uint16
+	database       string
+	user           string
+	password       string
+	tlsConfig      *tls.Config
+	connectTimeout time.Duration
+	runtimeParams  map[string]string
+	fallbacks      []*pgconn.FallbackConfig
+}
+
+// newConnSettings copies the settings from the connection configuration.
+func newConnSettings(cc *pgx.ConnConfig) *connSettings {
+	settings := &connSettings{
+		host:           cc.Host,
+		port:           cc.Port,
+		database:       cc.Database,
+		user:           cc.User,
+		password:       cc.Password,
+		tlsConfig:      cc.TLSConfig,
+		connectTimeout: cc.ConnectTimeout,
+		runtimeParams:  make(map[string]string, len(cc.RuntimeParams)),
+	}
+
+	for name, value := range cc.RuntimeParams {
+		settings.runtimeParams[name] = value
+	}
+
+	for _, fallback := range cc.Fallbacks {
+		copied := *fallback
+		settings.fallbacks = append(settings.fallbacks, &copied)
+	}
+
+	return settings
+}
+
+// apply the settings to the connection configuration.
+func (settings *connSettings) apply(cc *pgx.ConnConfig) {
+	cc.Host, cc.Port = settings.host, settings.port
+	cc.Database, cc.User, cc.Password = settings.database, settings.user, settings.password
+	cc.TLSConfig, cc.ConnectTimeout = settings.tlsConfig, settings.connectTimeout
+
+	cc.RuntimeParams = make(map[string]string, len(settings.runtimeParams))
+	for name, value := range settings.runtimeParams {
+		cc.RuntimeParams[name] = value
+	}
+
+	cc.Fallbacks = nil
+	for _, fallback := range settings.fallbacks {
+		copied := *fallback
+		cc.Fallbacks = append(cc.Fallbacks, &copied)
+	}
+}
+
+// reloader retires a pool's connections made with settings that have since been reloaded.  It's
+// installed in the pool's connection hooks.
+type reloader struct {
+	// poolLifetime is how long a connection may live before the pool closes it
+	poolLifetime time.Duration
+
+	mutex      sync.Mutex
+	generation int64
+	settings   *connSettings
+	lifetime   time.Duration
+	conns      map[*pgx.Conn]*reloadedConn
+}
+
+// reloadedConn tracks the settings a connection was made with.
+type reloadedConn struct {
+	created    time.Time
+	generation int64
+}
+
+func newReloader(config *pgxpool.Config) *reloader {
+	return &reloader{
+		poolLifetime: config.MaxConnLifetime + config.MaxConnLifetimeJitter + config.HealthCheckPeriod,
+		conns:        make(map[*pgx.Conn]*reloadedConn),
+	}
+}
+
+// install the reloader in the pool configuration.
+func (r *reloader) install(config *pgxpool.Config) {
+	config.BeforeConnect = r.beforeConnect(config.BeforeConnect)
+	config.AfterConnect = r.afterConnect(config.AfterConnect)
+	config.BeforeAcquire = r.beforeAcquire(config.BeforeAcquire)
+	config.AfterRelease = r.afterRelease(config.AfterRelease)
+}
+
+// reload switches new connections to the settings, and marks the existing connections to be
+// retired.
+func (r *reloader) reload(settings *connSettings, lifetime time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.generation++
+	r.settings = settings
+
+	if lifetime > 0 {
+		r.lifetime = lifetime
+	}
+}
+
+// current applies the reloaded settings, if any, to the connection configuration.
+func (r *reloader) current(cc *pgx.ConnConfig) {
+	r.mutex.Lock()
+	settings := r.settings
+	r.mutex.Unlock()
+
+	if settings != nil {
+		settings.apply(cc)
+	}
+}
+
+// beforeConnect returns a BeforeConnect function that applies the reloaded settings to the new
+// connection's configuration before calling next, if any.
+func (r *reloader) beforeConnect(next func(context.Context, *pgx.ConnConfig) error) func(context.Context, *pgx.ConnConfig) error {
+	return func(ctx context.Context, cc *pgx.ConnConfig) error {
+		r.current(cc)
+
+		if next != nil {
+			return next(ctx, cc)
+		}
+
+		return nil
+	}
+}
+
+// afterConnect returns an AfterConnect function that records the settings the connection was
+// made with before calling next, if any.
+func (r *reloader) afterConnect(next func(context.Context, *pgx.Conn) error) func(context.Context, *pgx.Conn) error {
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		r.mutex.Lock()
+		r.prune()
+		r.conns[conn] = &reloadedConn{created: time.Now(), generation: r.generation}
+		r.mutex.Unlock()
+
+		if next != nil {
+			return next(ctx, conn)
+		}
+
+		return nil
+	}
+}
+
+// beforeAcquire returns a BeforeAcquire function that retires an idle connection made with old
+// settings, so the pool makes a new one, before calling next, if any.
+func (r *reloader) beforeAcquire(next func(context.Context, *pgx.Conn) bool) func(context.Context, *pgx.Conn) bool {
+	return func(ctx context.Context, conn *pgx.Conn) bool {
+		if r.retire(conn) {
+			return false
+		}
+
+		if next != nil {
+			return next(ctx, conn)
+		}
+
+		return true
+	}
+}
+
+// afterRelease returns an AfterRelease function that retires a connection made with old
+// settings when it's released, before calling next, if any.
+func (r *reloader) afterRelease(next func(*pgx.Conn) bool) func(*pgx.Conn) bool {
+	return func(conn *pgx.Conn) bool {
+		if r.retire(conn) {
+			return false
+		}
+
+		if next != nil {
+			return next(conn)
+		}
+
+		return true
+	}
+}
+
+// retire returns true if the connection was made with settings that have since been reloaded,
+// or has outlived the reloaded MaxConnLifetime, and stops tracking it.
+func (r *reloader) retire(conn *pgx.Conn) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	rc, ok := r.conns[conn]
+	if !ok {
+		return false
+	}
+
+	stale := rc.generation < r.generation || (r.lifetime > 0 && time.Since(rc.created) > r.lifetime)
+	if stale {
+		delete(r.conns, conn)
+	}
+
+	return stale
+}
+
+// prune stops tracking connections old enough that the pool must have closed them.  Must be
+// called with the mutex locked.
+func (r *reloader) prune() {
+	if r.poolLifetime <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-r.poolLifetime - time.Minute)
+	for conn, rc := range r.conns {
+		if rc.created.Before(cutoff) {
+			delete(r.conns, conn)
+		}
+	}
+}
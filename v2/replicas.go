@@ -0,0 +1,349 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrReplicaLagging is the status error of a replica ejected for lagging too far behind the
+// primary.
+var ErrReplicaLagging = errors.New("replica lagging behind the primary")
+
+const (
+	// Default time between health probes of the replicas.
+	defaultProbeInterval = 5 * time.Second
+
+	// Default time to wait for a replica to respond to a probe.
+	defaultProbeTimeout = time.Second
+
+	// Default replay lag at which a replica is ejected.
+	defaultMaxLag = 30 * time.Second
+
+	// latencyWeight is the weight given to each new latency sample in a replica's moving average.
+	latencyWeight = 0.3
+
+	// minLatency keeps a replica on a fast network from drowning out the others.
+	minLatency = 100 * time.Microsecond
+)
+
+// ReplicaSet balances read traffic across read-replica connection pools.  Health probes measure
+// each replica's latency and how far it lags behind the primary; reads are weighted towards the
+// fastest replicas, and replicas that fail their probes or lag too far behind are ejected until
+// they recover.
+//
+// Call Run in a goroutine to probe the replicas periodically, then Pick a replica for each read.
+type ReplicaSet struct {
+	// ProbeInterval is the time between health probes.  Defaults to 5 seconds.
+	ProbeInterval time.Duration
+
+	// ProbeTimeout is how long to wait for a replica to respond to a probe before ejecting it.
+	// Defaults to 1 second.
+	ProbeTimeout time.Duration
+
+	// MaxLag ejects a replica once it's replaying transactions this far behind the primary.
+	// Defaults to 30 seconds.
+	MaxLag time.Duration
+
+	// MaxLagBytes ejects a replica once it's replayed this many bytes of WAL fewer than the
+	// primary has written.  Requires Primary.  Zero disables the check.
+	MaxLagBytes uint64
+
+	// Primary, if set, is queried for its current WAL position each probe, to measure the
+	// replicas' lag in bytes.
+	Primary *DB
+
+	replicas []*Replica
+
+	mutex  sync.Mutex
+	random *rand.Rand
+}
+
+// Replica is a read-replica connection pool in a ReplicaSet.
+type Replica struct {
+	// reads counts the Cluster reads in flight on the replica, first in the struct for 64-bit
+	// atomic alignment on 32-bit platforms
+	reads int64
+
+	// DB is the replica's connection pool.
+	DB *DB
+
+	mutex  sync.Mutex
+	status ReplicaStatus
+}
+
+// ReplicaStatus describes a replica's health as of its last probe.
+type ReplicaStatus struct {
+	// Healthy is true if the replica passed its last probe and is receiving reads.
+	Healthy bool
+
+	// Latency is the moving average of the replica's probe round trips.
+	Latency time.Duration
+
+	// Lag is how far behind the primary the replica is replaying transactions.  Zero when the
+	// replica has replayed everything it's received.
+	Lag time.Duration
+
+	// LagBytes is how many bytes of WAL the replica has yet to replay.  Only measured when the
+	// ReplicaSet has a Primary.
+	LagBytes uint64
+
+	// ReplayLSN is the last WAL position the replica replayed.
+	ReplayLSN LSN
+
+	// Err is the reason the replica was ejected, if it isn't healthy.
+	Err error
+
+	// Checked is when the replica was last probed.
+	Checked time.Time
+}
+
+// NewReplicaSet creates a replica set from the replicas' connection pools.  The replicas are
+// considered healthy until they're probed.
+func NewReplicaSet(replicas ...*DB) *ReplicaSet {
+	rs := &ReplicaSet{random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+	for _, db := range replicas {
+		rs.replicas = append(rs.replicas, &Replica{DB: db, status: ReplicaStatus{Healthy: true}})
+	}
+
+	return rs
+}
+
+// Replicas returns the replicas in the set.
+func (rs *ReplicaSet) Replicas() []*Replica {
+	return rs.replicas
+}
+
+// Run probes the replicas every ProbeInterval until ctx is canceled.
+func (rs *ReplicaSet) Run(ctx context.Context) {
+	interval := rs.ProbeInterval
+	if interval <= 0 {
+		interval = defaultProbeInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		rs.Probe(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe checks the health, latency, and lag of every replica concurrently.  Run calls this on
+// each interval; call it directly to probe on your own schedule.
+func (rs *ReplicaSet) Probe(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var primary LSN
+	if rs.Primary != nil && rs.MaxLagBytes > 0 {
+		primary, _ = rs.primaryLSN(ctx)
+	}
+
+	var wg sync.WaitGroup
+	for _, replica := range rs.replicas {
+		wg.Add(1)
+		go func(replica *Replica) {
+			defer wg.Done()
+			rs.probe(ctx, replica, primary)
+		}(replica)
+	}
+
+	wg.Wait()
+}
+
+// Pick returns a healthy replica, chosen at random weighted by the inverse of each replica's
+// latency, so faster replicas receive more reads.  Returns nil if no replicas are healthy.
+func (rs *ReplicaSet) Pick() *Replica {
+	return rs.pick(0)
+}
+
+// PickAfter returns a healthy replica that had replayed the WAL at least as far as lsn as of its
+// last probe, weighted as with Pick.  Returns nil if no replicas have caught up.
+func (rs *ReplicaSet) PickAfter(lsn LSN) *Replica {
+	return rs.pick(lsn)
+}
+
+// pick returns a healthy replica that has replayed past after, weighted by latency.
+func (rs *ReplicaSet) pick(after LSN) *Replica {
+	var total float64
+	weights := make([]float64, len(rs.replicas))
+
+	for i, replica := range rs.replicas {
+		status := replica.Status()
+		if !status.Healthy || status.ReplayLSN < after {
+			continue
+		}
+
+		latency := status.Latency
+		if latency < minLatency {
+			latency = minLatency
+		}
+
+		weights[i] = 1 / latency.Seconds()
+		total += weights[i]
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	rs.mutex.Lock()
+	n := rs.random.Float64() * total
+	rs.mutex.Unlock()
+
+	for i, weight := range weights {
+		if weight == 0 {
+			continue
+		}
+
+		if n < weight {
+			return rs.replicas[i]
+		}
+		n -= weight
+	}
+
+	// Rounding; fall back on the last healthy replica
+	for i := len(rs.replicas) - 1; i >= 0; i-- {
+		if weights[i] > 0 {
+			return rs.replicas[i]
+		}
+	}
+
+	return nil
+}
+
+// Status returns the replica's health as of its last probe.
+func (r *Replica) Status() ReplicaStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.status
+}
+
+// probe the replica, updating its status.
+func (rs *ReplicaSet) probe(ctx context.Context, replica *Replica, primary LSN) {
+	timeout := rs.ProbeTimeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var replay string
+	var lag float64
+
+	start := time.Now()
+	err := replica.DB.QueryRow(ctx, `
+		SELECT coalesce(pg_last_wal_replay_lsn()::text, ''),
+			CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE coalesce(extract(epoch FROM now() - pg_last_xact_replay_timestamp()), 0)
+			END`).Scan(&replay, &lag)
+	latency := time.Since(start)
+
+	var lsn LSN
+	if err == nil && replay != "" {
+		lsn, err = ParseLSN(replay)
+	}
+
+	rs.observe(replica, latency, time.Duration(lag*float64(time.Second)), lsn, primary, err)
+}
+
+// observe records the results of a probe, ejecting the replica if the probe failed or the
+// replica is lagging too far behind.
+func (rs *ReplicaSet) observe(replica *Replica, latency, lag time.Duration, lsn, primary LSN, err error) {
+	maxLag := rs.MaxLag
+	if maxLag <= 0 {
+		maxLag = defaultMaxLag
+	}
+
+	replica.mutex.Lock()
+	defer replica.mutex.Unlock()
+
+	status := &replica.status
+	status.Checked = time.Now()
+
+	if err != nil {
+		status.Healthy, status.Err = false, err
+		return
+	}
+
+	if status.Latency == 0 {
+		status.Latency = latency
+	} else {
+		status.Latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(status.Latency))
+	}
+
+	status.Lag, status.ReplayLSN, status.LagBytes = lag, lsn, 0
+	if primary > lsn && lsn != 0 {
+		status.LagBytes = uint64(primary - lsn)
+	}
+
+	if lag > maxLag || (rs.MaxLagBytes > 0 && status.LagBytes > rs.MaxLagBytes) {
+		status.Healthy = false
+		status.Err = fmt.Errorf("%w: %s, %d bytes", ErrReplicaLagging, lag, status.LagBytes)
+		return
+	}
+
+	status.Healthy, status.Err = true, nil
+}
+
+// primaryLSN returns the primary's current WAL position.
+func (rs *ReplicaSet) primaryLSN(ctx context.Context) (LSN, error) {
+	timeout := rs.ProbeTimeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var current string
+	if err := rs.Primary.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&current); err != nil {
+		return 0, err
+	}
+
+	return ParseLSN(current)
+}
+
+// LSN is a position in the PostgreSQL write-ahead log.
+type LSN uint64
+
+// ParseLSN parses a WAL position in PostgreSQL's text format, e.g. "16/B374D848".
+func ParseLSN(s string) (LSN, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid LSN %q", s)
+	}
+
+	hi, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q: %w", s, err)
+	}
+
+	lo, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid LSN %q: %w", s, err)
+	}
+
+	return LSN(hi<<32 | lo), nil
+}
+
+// String returns the WAL position in PostgreSQL's text format.
+func (lsn LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(lsn>>32), uint32(lsn))
+}
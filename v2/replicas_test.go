@@ -0,0 +1,198 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLSN(t *testing.T) {
+	lsn, err := ParseLSN("16/B374D848")
+	if err != nil {
+		t.Fatalf("Unable to parse LSN: %s", err)
+	}
+
+	if lsn != LSN(0x16B374D848) {
+		t.Errorf("Expected 0x16B374D848; was %#x", uint64(lsn))
+	}
+
+	if lsn.String() != "16/B374D848" {
+		t.Errorf("Expected 16/B374D848; was %s", lsn)
+	}
+
+	for _, invalid := range []string{"", "16", "16/", "G/0", "1/2/3"} {
+		if _, err := ParseLSN(invalid); err == nil {
+			t.Errorf("Expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestReplicaSetEject(t *testing.T) {
+	rs := NewReplicaSet(&DB{}, &DB{})
+	rs.MaxLag = time.Second
+	rs.MaxLagBytes = 1000
+
+	fast, slow := rs.Replicas()[0], rs.Replicas()[1]
+
+	rs.observe(fast, time.Millisecond, 0, 5000, 5500, nil)
+	rs.observe(slow, time.Millisecond, 2*time.Second, 5000, 5500, nil)
+
+	if !fast.Status().Healthy {
+		t.Errorf("Expected the replica to be healthy; was %v", fast.Status().Err)
+	}
+
+	if status := slow.Status(); status.Healthy || !errors.Is(status.Err, ErrReplicaLagging) {
+		t.Errorf("Expected the lagging replica to be ejected; was %v", status.Err)
+	}
+
+	rs.observe(fast, time.Millisecond, 0, 5000, 7000, nil)
+	if status := fast.Status(); status.Healthy || status.LagBytes != 2000 {
+		t.Errorf("Expected the replica to be ejected 2000 bytes behind; was %d", status.LagBytes)
+	}
+
+	rs.observe(fast, time.Millisecond, 0, 7000, 7000, nil)
+	if !fast.Status().Healthy {
+		t.Error("Expected the replica to recover once it caught up")
+	}
+
+	rs.observe(slow, 0, 0, 0, 0, errors.New("connection refused"))
+	if slow.Status().Healthy {
+		t.Error("Expected a failed probe to eject the replica")
+	}
+
+	for i := 0; i < 10; i++ {
+		if rs.Pick() != fast {
+			t.Fatal("Expected only the healthy replica to be picked")
+		}
+	}
+
+	rs.observe(fast, 0, 0, 0, 0, errors.New("connection refused"))
+	if rs.Pick() != nil {
+		t.Error("Expected no replica when none are healthy")
+	}
+}
+
+func TestReplicaSetWeights(t *testing.T) {
+	rs := NewReplicaSet(&DB{}, &DB{})
+	fast, slow := rs.Replicas()[0], rs.Replicas()[1]
+
+	rs.observe(fast, time.Millisecond, 0, 0, 0, nil)
+	rs.observe(slow, 9*time.Millisecond, 0, 0, 0, nil)
+
+	picks := make(map[*Replica]int)
+	for i := 0; i < 10000; i++ {
+		picks[rs.Pick()]++
+	}
+
+	// Expect about 90% of reads on the fast replica
+	if picks[fast] < 8500 || picks[fast] > 9500 {
+		t.Errorf("Expected about 9000 reads on the fast replica; was %d", picks[fast])
+	}
+
+	// Latency is a moving average
+	rs.observe(slow, time.Millisecond, 0, 0, 0, nil)
+	if latency := slow.Status().Latency; latency <= time.Millisecond || latency >= 9*time.Millisecond {
+		t.Errorf("Expected the latency to move towards 1ms; was %s", latency)
+	}
+}
+
+func TestReplicaSetReader(t *testing.T) {
+	primary := &DB{}
+	rs := NewReplicaSet(&DB{}, &DB{})
+	rs.Primary = primary
+
+	behind, ahead := rs.Replicas()[0], rs.Replicas()[1]
+
+	rs.observe(behind, time.Millisecond, 0, 5000, 0, nil)
+	rs.observe(ahead, time.Millisecond, 0, 7000, 0, nil)
+
+	if db := rs.Reader(context.Background()); db == primary {
+		t.Error("Expected an untracked read to go to a replica")
+	}
+
+	ctx := TrackWrites(context.Background(), 6000)
+	if lsn := WrittenLSN(ctx); lsn != 6000 {
+		t.Errorf("Expected the session to have written to 0/1770; was %s", lsn)
+	}
+
+	for i := 0; i < 10; i++ {
+		if db := rs.Reader(ctx); db != ahead.DB {
+			t.Fatal("Expected reads to go to the replica that had caught up")
+		}
+	}
+
+	// Tracking again keeps the furthest position
+	if TrackWrites(ctx, 1000) != ctx || WrittenLSN(ctx) != 6000 {
+		t.Errorf("Expected the existing tracker at 0/1770; was %s", WrittenLSN(ctx))
+	}
+
+	TrackWrites(ctx, 8000)
+	if db := rs.Reader(ctx); db != primary {
+		t.Error("Expected reads to fall back to the primary until a replica caught up")
+	}
+
+	rs.observe(behind, time.Millisecond, 0, 9000, 0, nil)
+	if db := rs.Reader(ctx); db != behind.DB {
+		t.Error("Expected reads to go to the replica once it caught up")
+	}
+
+	if WrittenLSN(context.Background()) != 0 {
+		t.Error("Expected no written position without tracking")
+	}
+}
+
+func TestRecordWriteWithoutPrimary(t *testing.T) {
+	rs := NewReplicaSet(&DB{})
+	if _, err := rs.RecordWrite(context.Background()); !errors.Is(err, ErrNoPrimary) {
+		t.Errorf("Expected ErrNoPrimary; was %v", err)
+	}
+}
+
+func TestRecordWriteLost(t *testing.T) {
+	primary := newUnreachableDB(t)
+	rs := NewReplicaSet(&DB{})
+	rs.Primary = primary
+	rs.observe(rs.Replicas()[0], time.Millisecond, 0, 7000, 0, nil)
+
+	// Without tracking, nothing is recorded, so the database isn't asked
+	primary.recordWrite(context.Background())
+
+	ctx := TrackWrites(context.Background(), 6000)
+	if db := rs.Reader(ctx); db == primary {
+		t.Fatal("Expected reads to go to the replica that had caught up")
+	}
+
+	// The position can't be read without a database, so the session reads from the primary
+	primary.recordWrite(ctx)
+
+	if db := rs.Reader(ctx); db != primary {
+		t.Error("Expected reads to go to the primary once a write's position was lost")
+	}
+
+	if lsn := WrittenLSN(ctx); lsn != 6000 {
+		t.Errorf("Expected the session to have written to 0/1770; was %s", lsn)
+	}
+}
+
+func TestTrackedBatchResults(t *testing.T) {
+	ctx := TrackWrites(context.Background(), 0)
+	results := &closeCounter{}
+	br := &trackedBatchResults{BatchResults: results, db: newUnreachableDB(t), ctx: ctx}
+
+	if err := br.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if results.closed != 1 {
+		t.Errorf("Expected the results to be closed; closed %d times", results.closed)
+	}
+
+	if !br.recorded {
+		t.Error("Expected the batch to be recorded as a write")
+	}
+
+	if readAfter(ctx) != ^LSN(0) {
+		t.Errorf("Expected the unreadable position to send reads to the primary; was %s", readAfter(ctx))
+	}
+}
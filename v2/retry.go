@@ -0,0 +1,120 @@
+package hermes
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Default attempts at a transaction before WithRetry gives up.
+	defaultRetryAttempts = 5
+
+	// Default delays before retrying a transaction, doubling from the base up to the max.
+	defaultRetryBase = 10 * time.Millisecond
+	defaultRetryMax  = time.Second
+)
+
+// RetryOptions configure DB.WithRetry.
+type RetryOptions struct {
+	// MaxAttempts is the most times the transaction is run, including the first.  Defaults to 5.
+	MaxAttempts int
+
+	// Backoff returns how long to wait before the next attempt, given the number of attempts so
+	// far.  The wait is randomized between half and all of the backoff, so transactions that
+	// conflicted don't retry in lockstep.  Defaults to exponential backoff from 10ms up to 1
+	// second.
+	Backoff func(attempts int) time.Duration
+
+	// Retryable returns true if the transaction should be retried after the error.  Defaults to
+	// IsSerializationFailure.
+	Retryable func(err error) bool
+
+	// OnRetry is called before each retry with the number of attempts so far and the error, for
+	// logging.
+	OnRetry func(attempts int, err error)
+}
+
+// WithRetry runs fn in a transaction, as BeginFunc does, retrying the transaction from the start
+// if it fails with a serialization failure or deadlock (SQLSTATE 40001 or 40P01), which
+// SERIALIZABLE and REPEATABLE READ transactions must expect.  Retries wait with exponential
+// backoff and jitter, and stop once opts.MaxAttempts is reached or ctx is done.  Returns nil once
+// the transaction commits, or the last error.
+//
+// fn may run more than once, so it shouldn't have side effects outside the transaction.  To set
+// the isolation level, run SET TRANSACTION first thing in fn:
+//
+//	err := db.WithRetry(ctx, hermes.RetryOptions{}, func(tx hermes.Conn) error {
+//		if _, err := tx.Exec(ctx, "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE"); err != nil {
+//			return err
+//		}
+//		// ...
+//	})
+func (db *DB) WithRetry(ctx context.Context, opts RetryOptions, fn func(tx Conn) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	onRetry := opts.OnRetry
+	opts.OnRetry = func(attempts int, err error) {
+		atomic.AddInt64(&db.retries, 1)
+
+		if onRetry != nil {
+			onRetry(attempts, err)
+		}
+	}
+
+	return retry(ctx, opts, func() error {
+		return db.BeginFunc(ctx, fn)
+	})
+}
+
+// retry calls run until it succeeds, fails with an error that isn't retryable, or runs out of
+// attempts.
+func retry(ctx context.Context, opts RetryOptions, run func() error) error {
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryAttempts
+	}
+
+	backoff := opts.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(defaultRetryBase, defaultRetryMax)
+	}
+
+	retryable := opts.Retryable
+	if retryable == nil {
+		retryable = IsSerializationFailure
+	}
+
+	for attempts := 1; ; attempts++ {
+		err := run()
+		if err == nil || attempts >= maxAttempts || !retryable(err) {
+			return err
+		}
+
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempts, err)
+		}
+
+		timer := time.NewTimer(jitter(backoff(attempts)))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// jitter returns a random duration between half and all of the delay.
+func jitter(delay time.Duration) time.Duration {
+	if delay <= 1 {
+		return delay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
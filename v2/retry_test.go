@@ -0,0 +1,96 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetry(t *testing.T) {
+	conflict := fmt.Errorf("saving: %w", &pgconn.PgError{Code: SerializationFailure})
+
+	var runs int
+	var retried []int
+
+	opts := RetryOptions{
+		Backoff: func(int) time.Duration { return time.Millisecond },
+		OnRetry: func(attempts int, err error) {
+			if err != conflict {
+				t.Errorf("Expected the conflict; was %v", err)
+			}
+			retried = append(retried, attempts)
+		},
+	}
+
+	err := retry(context.Background(), opts, func() error {
+		runs++
+		if runs < 3 {
+			return conflict
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("Expected the third attempt to succeed; was %s", err)
+	}
+
+	if runs != 3 || len(retried) != 2 || retried[1] != 2 {
+		t.Errorf("Expected 3 runs and 2 retries; was %d and %v", runs, retried)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	deadlock := &pgconn.PgError{Code: DeadlockDetected}
+	opts := RetryOptions{MaxAttempts: 3, Backoff: func(int) time.Duration { return 0 }}
+
+	var runs int
+	err := retry(context.Background(), opts, func() error {
+		runs++
+		return deadlock
+	})
+
+	if err != deadlock || runs != 3 {
+		t.Errorf("Expected the deadlock after 3 runs; was %v after %d", err, runs)
+	}
+
+	failed := errors.New("failed")
+
+	runs = 0
+	err = retry(context.Background(), opts, func() error {
+		runs++
+		return failed
+	})
+
+	if err != failed || runs != 1 {
+		t.Errorf("Expected other errors not to be retried; was %v after %d runs", err, runs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runs = 0
+	err = retry(ctx, RetryOptions{Backoff: func(int) time.Duration { return time.Hour }}, func() error {
+		runs++
+		return deadlock
+	})
+
+	if err != deadlock || runs != 1 {
+		t.Errorf("Expected to stop once the context is done; was %v after %d runs", err, runs)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if delay := jitter(100 * time.Millisecond); delay < 50*time.Millisecond || delay > 100*time.Millisecond {
+			t.Fatalf("Expected a delay between 50ms and 100ms; was %s", delay)
+		}
+	}
+
+	if delay := jitter(0); delay != 0 {
+		t.Errorf("Expected no delay; was %s", delay)
+	}
+}
@@ -0,0 +1,209 @@
+package hermes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnmappedColumn is returned when scanning a row into a struct if one of the columns doesn't
+// match any of the struct's fields.
+var ErrUnmappedColumn = errors.New("column doesn't match a struct field")
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// scanPlans caches the scanPlan for each struct type and set of columns.
+var scanPlans sync.Map
+
+// scanKey identifies a scanPlan:  the struct type and the query's column names, separated by NUL
+// characters, which can't appear in a column name.
+type scanKey struct {
+	structType reflect.Type
+	columns    string
+}
+
+// scanPlan maps a query's columns to the fields of a struct.
+type scanPlan struct {
+	// fields holds the index of the struct field for each column, suitable for
+	// reflect.Value.FieldByIndex
+	fields [][]int
+}
+
+// ScanStruct scans the current row into the struct dest points to.  Columns are matched to struct
+// fields by the fields' `db` tags, or their lowercased names; fields of embedded structs are
+// matched as if they were fields of the outer struct.  Fields without a matching column are left
+// alone.  Returns ErrUnmappedColumn if a column doesn't match any field.
+//
+// How the columns map to the struct's fields is worked out the first time a struct type is
+// scanned from a particular set of columns, then cached.  See PrepareStruct.
+func ScanStruct(rows pgx.Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot scan into %T; expected a pointer to a struct", dest)
+	}
+
+	plan, err := planScan(v.Elem().Type(), fieldNames(rows))
+	if err != nil {
+		return err
+	}
+
+	return rows.Scan(plan.targets(v.Elem(), nil)...)
+}
+
+// CollectStructs scans every row into a struct of type T, then closes the rows.  See ScanStruct.
+// If given, hint is the number of rows expected, used to size the results up front.
+func CollectStructs[T any](rows pgx.Rows, hint ...int) ([]T, error) {
+	return AppendStructs(preallocate[T](hint), rows)
+}
+
+// AppendStructs scans every row into a struct of type T, appending them to dest, then closes the
+// rows.  Pass dest[:0] to reuse a slice from an earlier call.  Rows are scanned directly into the
+// slice, so the only allocations are from growing it.  See ScanStruct.
+func AppendStructs[T any](dest []T, rows pgx.Rows) ([]T, error) {
+	defer rows.Close()
+
+	var zero T
+	t := reflect.TypeOf(zero)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot scan into %T; expected a struct", zero)
+	}
+
+	plan, err := planScan(t, fieldNames(rows))
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []interface{}
+
+	for rows.Next() {
+		dest = append(dest, zero)
+		targets = plan.targets(reflect.ValueOf(&dest[len(dest)-1]).Elem(), targets)
+
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dest, nil
+}
+
+// PrepareStruct works out how the columns map to the fields of value's struct type and caches
+// the result, so the first ScanStruct or CollectStructs call from those columns doesn't have to.
+// Call it at startup for structs scanned on hot paths.  Value may be a struct or a pointer to
+// one.  Returns ErrUnmappedColumn if a column doesn't match any field.
+func PrepareStruct(value interface{}, columns ...string) error {
+	t := reflect.TypeOf(value)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot scan into %T; expected a struct", value)
+	}
+
+	_, err := planScan(t, columns)
+	return err
+}
+
+// fieldNames returns the names of the columns in the rows.
+func fieldNames(rows pgx.Rows) []string {
+	fields := rows.FieldDescriptions()
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+
+	return names
+}
+
+// planScan returns the cached scan plan for the struct type and columns, creating it if
+// necessary.
+func planScan(structType reflect.Type, columns []string) (*scanPlan, error) {
+	key := scanKey{structType: structType, columns: strings.Join(columns, "\x00")}
+
+	if plan, ok := scanPlans.Load(key); ok {
+		return plan.(*scanPlan), nil
+	}
+
+	fields := make(map[string][]int)
+	structFields(structType, nil, fields)
+
+	plan := &scanPlan{fields: make([][]int, len(columns))}
+	for i, column := range columns {
+		index, ok := fields[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s in %s", ErrUnmappedColumn, column, structType)
+		}
+		plan.fields[i] = index
+	}
+
+	scanPlans.Store(key, plan)
+	return plan, nil
+}
+
+// structFields adds the struct's fields to the map by column name, along with the fields of any
+// embedded structs.  The outer struct's fields take precedence.
+func structFields(structType reflect.Type, parent []int, fields map[string][]int) {
+	var embedded []int
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("db") == "" {
+			embedded = append(embedded, i)
+			continue
+		}
+
+		name := columnName(field)
+		if name == "" {
+			continue
+		}
+
+		if _, ok := fields[name]; !ok {
+			fields[name] = append(append([]int{}, parent...), i)
+		}
+	}
+
+	for _, i := range embedded {
+		structFields(structType.Field(i).Type, append(append([]int{}, parent...), i), fields)
+	}
+}
+
+// targets returns pointers to the struct's fields in column order, reusing the buf slice if it's
+// big enough.
+func (plan *scanPlan) targets(v reflect.Value, buf []interface{}) []interface{} {
+	if cap(buf) < len(plan.fields) {
+		buf = make([]interface{}, len(plan.fields))
+	}
+	buf = buf[:len(plan.fields)]
+
+	for i, index := range plan.fields {
+		buf[i] = v.FieldByIndex(index).Addr().Interface()
+	}
+
+	return buf
+}
+
+// scansStruct returns true if values of type t are scanned field by field, rather than as a
+// single value.
+func scansStruct(t reflect.Type) bool {
+	if t == nil || t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+
+	return !reflect.PtrTo(t).Implements(scannerType)
+}
@@ -0,0 +1,162 @@
+package hermes
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeRows returns canned rows, assigning each value to its scan target.
+type fakeRows struct {
+	columns []string
+	values  [][]interface{}
+	row     int
+	closed  bool
+}
+
+func (r *fakeRows) Close()                        { r.closed = true }
+func (r *fakeRows) Err() error                    { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag { return pgconn.CommandTag{} }
+func (r *fakeRows) RawValues() [][]byte           { return nil }
+func (r *fakeRows) Conn() *pgx.Conn               { return nil }
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription {
+	fields := make([]pgconn.FieldDescription, len(r.columns))
+	for i, column := range r.columns {
+		fields[i].Name = column
+	}
+	return fields
+}
+
+func (r *fakeRows) Next() bool {
+	r.row++
+	return r.row <= len(r.values)
+}
+
+func (r *fakeRows) Values() ([]interface{}, error) {
+	return r.values[r.row-1], nil
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	values := r.values[r.row-1]
+	if len(dest) != len(values) {
+		return fmt.Errorf("expected %d targets; was %d", len(values), len(dest))
+	}
+
+	for i, value := range values {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+
+	return nil
+}
+
+type audited struct {
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type account struct {
+	audited
+
+	ID      int64
+	Name    string `db:"display_name"`
+	Balance float64
+	secret  string
+	Ignored string `db:"-"`
+}
+
+func TestScanStruct(t *testing.T) {
+	now := time.Now()
+	rows := &fakeRows{
+		columns: []string{"id", "display_name", "created_at"},
+		values:  [][]interface{}{{int64(12), "Alice", now}},
+	}
+
+	rows.Next()
+
+	var acct account
+	if err := ScanStruct(rows, &acct); err != nil {
+		t.Fatalf("Unable to scan struct: %s", err)
+	}
+
+	if acct.ID != 12 || acct.Name != "Alice" || !acct.CreatedAt.Equal(now) {
+		t.Errorf("Expected 12, Alice, %s; was %#v", now, acct)
+	}
+
+	if err := ScanStruct(rows, acct); err == nil {
+		t.Error("Expected error scanning into a struct value")
+	}
+}
+
+func TestCollectStructs(t *testing.T) {
+	rows := &fakeRows{
+		columns: []string{"display_name", "balance"},
+		values:  [][]interface{}{{"Alice", 10.5}, {"Bob", 2.25}},
+	}
+
+	accounts, err := CollectStructs[account](rows)
+	if err != nil {
+		t.Fatalf("Unable to collect structs: %s", err)
+	}
+
+	if len(accounts) != 2 || accounts[0].Name != "Alice" || accounts[1].Balance != 2.25 {
+		t.Errorf("Expected Alice and Bob; was %#v", accounts)
+	}
+
+	if !rows.closed {
+		t.Error("Expected rows to be closed")
+	}
+}
+
+func TestScanUnmappedColumn(t *testing.T) {
+	for _, column := range []string{"missing", "secret", "ignored", "audited"} {
+		rows := &fakeRows{columns: []string{"id", column}, values: [][]interface{}{{int64(1), "x"}}}
+
+		if _, err := CollectStructs[account](rows); !errors.Is(err, ErrUnmappedColumn) {
+			t.Errorf("Expected ErrUnmappedColumn for %s; was %v", column, err)
+		}
+	}
+}
+
+func TestPrepareStruct(t *testing.T) {
+	if err := PrepareStruct(&account{}, "id", "updated_at"); err != nil {
+		t.Fatalf("Unable to prepare struct: %s", err)
+	}
+
+	plan, ok := scanPlans.Load(scanKey{structType: reflect.TypeOf(account{}), columns: "id\x00updated_at"})
+	if !ok {
+		t.Fatal("Expected scan plan to be cached")
+	}
+
+	fields := plan.(*scanPlan).fields
+	if !reflect.DeepEqual(fields, [][]int{{1}, {0, 1}}) {
+		t.Errorf("Expected [[1] [0 1]]; was %v", fields)
+	}
+
+	if err := PrepareStruct(account{}, "nope"); !errors.Is(err, ErrUnmappedColumn) {
+		t.Errorf("Expected ErrUnmappedColumn; was %v", err)
+	}
+
+	if err := PrepareStruct(12, "id"); err == nil {
+		t.Error("Expected error preparing a non-struct")
+	}
+}
+
+func BenchmarkCollectStructs(b *testing.B) {
+	values := make([][]interface{}, 100)
+	for i := range values {
+		values[i] = []interface{}{int64(i), "name", 1.5}
+	}
+
+	for i := 0; i < b.N; i++ {
+		rows := &fakeRows{columns: []string{"id", "display_name", "balance"}, values: values}
+		if _, err := CollectStructs[account](rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
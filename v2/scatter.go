@@ -0,0 +1,125 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ShardQuery is one of the queries run by Gather:  the connection to run it on, such as the pool
+// for a partition, and the query, which may vary by shard, e.g. to select from the shard's
+// schema.
+type ShardQuery struct {
+	Conn Conn
+	SQL  string
+	Args []interface{}
+}
+
+// Scatter calls fn for each of n shards, running up to workers calls at once.  If workers is zero
+// or less, every shard runs at once.  The first error cancels the ctx passed to the calls still
+// running, no more calls are started, and that error is returned, labeled with its shard.
+//
+// Use Scatter to fan work out across partitioned schemas or databases, e.g. with a DB per
+// partition:
+//
+//	err := hermes.Scatter(ctx, len(partitions), 4, func(ctx context.Context, i int) error {
+//		_, err := partitions[i].Exec(ctx, "delete from events where created_at < $1", cutoff)
+//		return err
+//	})
+func Scatter(ctx context.Context, n, workers int, fn func(ctx context.Context, shard int) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if n <= 0 {
+		return nil
+	}
+
+	if workers <= 0 || workers > n {
+		workers = n
+	}
+
+	scatterCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var failed error
+
+	shards := make(chan int)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for shard := range shards {
+				// Don't start shards once one has failed
+				if scatterCtx.Err() != nil {
+					continue
+				}
+
+				if err := fn(scatterCtx, shard); err != nil {
+					once.Do(func() {
+						failed = fmt.Errorf("shard %d: %w", shard, err)
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+send:
+	for shard := 0; shard < n && scatterCtx.Err() == nil; shard++ {
+		select {
+		case shards <- shard:
+		case <-scatterCtx.Done():
+			break send
+		}
+	}
+
+	close(shards)
+	wg.Wait()
+
+	if failed != nil {
+		return failed
+	}
+
+	return ctx.Err()
+}
+
+// Gather runs the queries concurrently, up to workers at once, and scans every row into a struct
+// of type T.  See Select for how columns are matched to fields.  Returns the rows of every query,
+// in the order of the queries.  The first query to fail cancels the rest, and its error is
+// returned.  See Scatter.
+func Gather[T any](ctx context.Context, workers int, queries ...ShardQuery) ([]T, error) {
+	results := make([][]T, len(queries))
+
+	err := Scatter(ctx, len(queries), workers, func(ctx context.Context, shard int) error {
+		q := queries[shard]
+
+		rows, err := Select[T](ctx, q.Conn, q.SQL, q.Args...)
+		if err != nil {
+			return err
+		}
+
+		results[shard] = rows
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var total int
+	for _, rows := range results {
+		total += len(rows)
+	}
+
+	merged := make([]T, 0, total)
+	for _, rows := range results {
+		merged = append(merged, rows...)
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,94 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScatter(t *testing.T) {
+	var running, peak, calls int32
+
+	err := Scatter(context.Background(), 10, 3, func(ctx context.Context, shard int) error {
+		atomic.AddInt32(&calls, 1)
+
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			high := atomic.LoadInt32(&peak)
+			if now <= high || atomic.CompareAndSwapInt32(&peak, high, now) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unable to scatter: %s", err)
+	}
+
+	if calls != 10 {
+		t.Errorf("Expected 10 calls; was %d", calls)
+	}
+
+	if peak > 3 {
+		t.Errorf("Expected at most 3 workers at once; was %d", peak)
+	}
+}
+
+func TestScatterFirstError(t *testing.T) {
+	failure := errors.New("shard failed")
+	var calls int32
+
+	err := Scatter(context.Background(), 100, 2, func(ctx context.Context, shard int) error {
+		atomic.AddInt32(&calls, 1)
+
+		if shard == 1 {
+			return failure
+		}
+
+		// Other shards block until they're canceled
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the first error; was %v", err)
+	}
+
+	if err.Error() != "shard 1: shard failed" {
+		t.Errorf("Expected the error to name its shard; was %s", err)
+	}
+
+	if calls > 3 {
+		t.Errorf("Expected no more shards to start after the error; %d ran", calls)
+	}
+}
+
+func TestGather(t *testing.T) {
+	columns := []string{"id", "display_name"}
+	east := &queryConn{columns: columns, values: [][]interface{}{{int64(1), "Alice"}, {int64(2), "Bob"}}}
+	west := &queryConn{columns: columns, values: [][]interface{}{{int64(3), "Carol"}}}
+
+	accounts, err := Gather[account](context.Background(), 2,
+		ShardQuery{Conn: east, SQL: "select id, display_name from east.accounts"},
+		ShardQuery{Conn: west, SQL: "select id, display_name from west.accounts"},
+	)
+	if err != nil {
+		t.Fatalf("Unable to gather accounts: %s", err)
+	}
+
+	if len(accounts) != 3 {
+		t.Fatalf("Expected 3 accounts; was %d", len(accounts))
+	}
+
+	for i, name := range []string{"Alice", "Bob", "Carol"} {
+		if accounts[i].Name != name {
+			t.Errorf("Expected account %d to be %s; was %s", i, name, accounts[i].Name)
+		}
+	}
+}
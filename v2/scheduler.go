@@ -0,0 +1,314 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// DefaultScheduleTable is the table schedules are stored in if the Scheduler doesn't
+	// specify one.
+	DefaultScheduleTable = "hermes_schedules"
+
+	// How often the Scheduler checks for due tasks by default.
+	defaultSchedulerInterval = 15 * time.Second
+)
+
+// ErrNeverScheduled is returned when a cron schedule never matches, e.g. "0 0 30 2 *" for
+// February 30th, so the task would never run.
+var ErrNeverScheduled = errors.New("schedule never matches")
+
+// ScheduledTask is a function run by the Scheduler.  The conn is a transaction; any changes the
+// task makes are committed when the task completes without error.
+type ScheduledTask func(ctx context.Context, conn Conn) error
+
+// Scheduler runs registered Go functions on cron schedules stored in a database table.  Any
+// number of application instances may run a Scheduler against the same table; a transactional
+// advisory lock on each task ensures only one instance runs it per tick.
+//
+// Configure the Scheduler's fields before calling Run.
+type Scheduler struct {
+	// Table schedules are stored in.  Defaults to DefaultScheduleTable.  See CreateTable.
+	Table string
+
+	// Interval is how often the Scheduler checks for due tasks.  Defaults to 15 seconds.
+	Interval time.Duration
+
+	// Location is the time zone cron expressions are evaluated in.  Defaults to UTC.
+	Location *time.Location
+
+	// OnError is called when a task fails or the Scheduler can't check the schedules, for
+	// logging.
+	OnError func(name string, err error)
+
+	db *DB
+
+	mutex sync.RWMutex
+	tasks map[string]ScheduledTask
+}
+
+// NewScheduler creates a scheduler storing its schedules in DefaultScheduleTable.
+func NewScheduler(db *DB) *Scheduler {
+	return &Scheduler{
+		Table: DefaultScheduleTable,
+		db:    db,
+		tasks: make(map[string]ScheduledTask),
+	}
+}
+
+// CreateTable creates the scheduler's table if it doesn't exist.
+func (s *Scheduler) CreateTable(ctx context.Context, conn Conn) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	_, err := conn.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			name       text PRIMARY KEY,
+			spec       text NOT NULL,
+			next_run   timestamptz NOT NULL,
+			last_run   timestamptz,
+			last_error text
+		)`, s.table()))
+
+	return err
+}
+
+// Register the function to call for the named task.  Tasks that are scheduled in the table but
+// not registered with this Scheduler are left for other instances to run.
+func (s *Scheduler) Register(name string, task ScheduledTask) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tasks[name] = task
+}
+
+// Schedule saves the cron schedule for the named task, replacing any existing schedule.  See
+// ParseCron for the supported cron expressions.  Returns ErrNeverScheduled if the schedule never
+// matches.
+func (s *Scheduler) Schedule(ctx context.Context, conn Conn, name, spec string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	next, err := s.next(spec)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (name, spec, next_run) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET spec = excluded.spec, next_run = excluded.next_run`,
+		s.table()), name, spec, next)
+
+	return err
+}
+
+// Unschedule removes the named task's schedule.
+func (s *Scheduler) Unschedule(ctx context.Context, conn Conn, name string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	_, err := conn.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE name = $1", s.table()), name)
+	return err
+}
+
+// Run checks for due tasks every Interval and runs them, until ctx is canceled.
+func (s *Scheduler) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultSchedulerInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs every registered task that's due.  Run calls this on each interval; call it directly
+// for finer control, or in tests.
+func (s *Scheduler) Tick(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	rows, err := s.db.Query(ctx, fmt.Sprintf("SELECT name FROM %s WHERE next_run <= now()", s.table()))
+	if err != nil {
+		s.report("", err)
+		return
+	}
+
+	var due []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			s.report("", err)
+			return
+		}
+		due = append(due, name)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		s.report("", err)
+		return
+	}
+
+	for _, name := range due {
+		s.mutex.RLock()
+		task, ok := s.tasks[name]
+		s.mutex.RUnlock()
+
+		if !ok {
+			continue
+		}
+
+		if err := s.run(ctx, name, task); err != nil {
+			s.report(name, err)
+		}
+	}
+}
+
+// run the task if no other instance is running it and it's still due.
+func (s *Scheduler) run(ctx context.Context, name string, task ScheduledTask) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Close(ctx)
+
+	if _, err := tx.TryLock(ctx, scheduleLockID(name)); err == ErrLocked {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Another instance may have run the task between checking the schedules and acquiring the
+	// lock
+	var spec string
+	row := tx.QueryRow(ctx, fmt.Sprintf("SELECT spec FROM %s WHERE name = $1 AND next_run <= now()",
+		s.table()), name)
+	if err := row.Scan(&spec); err != nil {
+		if NoRows(err) {
+			return nil
+		}
+		return err
+	}
+
+	// Refuse to run a task that could never be rescheduled, or it would be due on every tick
+	if _, err := s.next(spec); err != nil {
+		return err
+	}
+
+	taskErr := s.call(ctx, tx, task)
+
+	var lastError *string
+	if taskErr != nil {
+		msg := taskErr.Error()
+		lastError = &msg
+	}
+
+	next, err := s.next(spec)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE %s SET next_run = $2, last_run = now(), last_error = $3 WHERE name = $1`,
+		s.table()), name, next, lastError); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return taskErr
+}
+
+// call runs the task in a savepoint, so a failed task's changes are rolled back without losing
+// the schedule update.
+func (s *Scheduler) call(ctx context.Context, tx Conn, task ScheduledTask) (err error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer savepoint.Close(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+
+	if err := task(ctx, savepoint); err != nil {
+		return err
+	}
+
+	return savepoint.Commit(ctx)
+}
+
+// next returns the next time the cron schedule matches after now, in the Scheduler's time zone.
+// Returns ErrNeverScheduled if it never does.
+func (s *Scheduler) next(spec string) (time.Time, error) {
+	sched, err := ParseCron(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := sched.Next(time.Now().In(s.location()))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q: %w", spec, ErrNeverScheduled)
+	}
+
+	return next, nil
+}
+
+// report passes an error to OnError.
+func (s *Scheduler) report(name string, err error) {
+	if s.OnError != nil {
+		s.OnError(name, err)
+	}
+}
+
+// table returns the sanitized table name.
+func (s *Scheduler) table() string {
+	if s.Table == "" {
+		return pgx.Identifier{DefaultScheduleTable}.Sanitize()
+	}
+	return pgx.Identifier{s.Table}.Sanitize()
+}
+
+// location returns the configured time zone, or UTC.
+func (s *Scheduler) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
+
+// scheduleLockID hashes the task name into an advisory lock ID.  The ID is kept within the
+// positive range of a PostgreSQL bigint.
+func scheduleLockID(name string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("hermes.schedule:" + name))
+
+	return h.Sum64() & 0x7fffffffffffffff
+}
@@ -0,0 +1,155 @@
+package hermes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sbowman/hermes-pgx/v2"
+	"github.com/sbowman/hermes-pgx/v2/hermestest"
+)
+
+func TestSchedule(t *testing.T) {
+	mock := hermestest.NewMock(t)
+	mock.ExpectExec(`INSERT INTO "hermes_schedules"`).WithArgs("report", "@daily", hermestest.AnyArg)
+
+	s := hermes.NewScheduler(nil)
+
+	if err := s.Schedule(nil, mock, "report", "@daily"); err != nil {
+		t.Fatalf("Unable to schedule the task: %s", err)
+	}
+
+	calls := mock.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("Expected the schedule to be saved; was %v", calls)
+	}
+
+	next, ok := calls[0].Args[2].(time.Time)
+	if !ok || !next.After(time.Now()) || next.Hour() != 0 || next.Minute() != 0 {
+		t.Errorf("Expected the next run at midnight; was %v", calls[0].Args[2])
+	}
+
+	if err := s.Schedule(nil, mock, "report", "0 0 * *"); err == nil {
+		t.Error("Expected an invalid cron expression to be refused")
+	}
+
+	if err := s.Schedule(nil, mock, "report", "0 0 31 4 *"); !errors.Is(err, hermes.ErrNeverScheduled) {
+		t.Errorf("Expected ErrNeverScheduled for April 31st; was %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSchedulerTickUnavailable(t *testing.T) {
+	s := hermes.NewScheduler(hermes.NewUnreachableDB(t))
+
+	var errs []error
+	s.OnError = func(name string, err error) {
+		errs = append(errs, err)
+	}
+
+	s.Tick(context.Background())
+
+	if len(errs) != 1 {
+		t.Errorf("Expected the failed check to be reported; was %v", errs)
+	}
+}
+
+func TestSchedulerTick(t *testing.T) {
+	db := connect(t)
+
+	s := hermes.NewScheduler(db)
+	s.Table = "hermes_test_schedules"
+
+	if err := s.CreateTable(nil, db); err != nil {
+		t.Fatalf("Unable to create the schedules table: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := db.Exec(nil, "DROP TABLE hermes_test_schedules"); err != nil {
+			t.Errorf("Unable to drop the schedules table: %s", err)
+		}
+	})
+
+	var errs []error
+	s.OnError = func(name string, err error) {
+		errs = append(errs, err)
+	}
+
+	var runs, failures, never int
+	s.Register("count", func(ctx context.Context, conn hermes.Conn) error {
+		runs++
+		return nil
+	})
+	s.Register("fail", func(ctx context.Context, conn hermes.Conn) error {
+		failures++
+		return errors.New("task failed")
+	})
+	s.Register("never", func(ctx context.Context, conn hermes.Conn) error {
+		never++
+		return nil
+	})
+
+	for _, name := range []string{"count", "fail"} {
+		if err := s.Schedule(nil, db, name, "* * * * *"); err != nil {
+			t.Fatalf("Unable to schedule %s: %s", name, err)
+		}
+	}
+
+	// A schedule saved some other way that can never run again
+	if _, err := db.Exec(nil, `INSERT INTO hermes_test_schedules (name, spec, next_run)
+		VALUES ('never', '0 0 30 2 *', now() + interval '1 hour')`); err != nil {
+		t.Fatalf("Unable to save the schedule: %s", err)
+	}
+
+	// Not due yet
+	s.Tick(nil)
+
+	if runs != 0 || failures != 0 {
+		t.Fatalf("Expected no tasks to run before they're due; was %d and %d", runs, failures)
+	}
+
+	if _, err := db.Exec(nil, "UPDATE hermes_test_schedules SET next_run = now() - interval '1 minute'"); err != nil {
+		t.Fatalf("Unable to make the tasks due: %s", err)
+	}
+
+	s.Tick(nil)
+
+	if runs != 1 || failures != 1 {
+		t.Errorf("Expected the due tasks to run once; was %d and %d", runs, failures)
+	}
+
+	if never != 0 {
+		t.Error("Expected the task that can't be rescheduled not to run")
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("Expected the failed task and the unschedulable task to be reported; was %v", errs)
+	}
+
+	if !errors.Is(errs[0], hermes.ErrNeverScheduled) && !errors.Is(errs[1], hermes.ErrNeverScheduled) {
+		t.Errorf("Expected ErrNeverScheduled for the task that can't be rescheduled; was %v", errs)
+	}
+
+	var lastError string
+	if err := db.QueryRow(nil, "SELECT last_error FROM hermes_test_schedules WHERE name = 'fail'").
+		Scan(&lastError); err != nil || lastError != "task failed" {
+		t.Errorf("Expected the task's error to be recorded; was %q, %v", lastError, err)
+	}
+
+	var pending int
+	if err := db.QueryRow(nil, `SELECT count(*) FROM hermes_test_schedules
+		WHERE name IN ('count', 'fail') AND next_run > now()`).Scan(&pending); err != nil || pending != 2 {
+		t.Errorf("Expected the tasks to be rescheduled; %d were, %v", pending, err)
+	}
+
+	// Rescheduled, so not due again
+	s.Tick(nil)
+
+	if runs != 1 || failures != 1 {
+		t.Errorf("Expected the tasks not to run again until due; ran %d and %d times", runs, failures)
+	}
+}
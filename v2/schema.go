@@ -0,0 +1,364 @@
+package hermes
+
+import (
+	"context"
+	"strings"
+)
+
+// ConstraintKind identifies the type of a table constraint, matching pg_constraint.contype.
+type ConstraintKind string
+
+// Table constraint types.
+const (
+	PrimaryKeyConstraint ConstraintKind = "p"
+	ForeignKeyConstraint ConstraintKind = "f"
+	UniqueConstraint     ConstraintKind = "u"
+	CheckConstraint      ConstraintKind = "c"
+	ExclusionConstraint  ConstraintKind = "x"
+)
+
+// String returns a readable name for the constraint kind.
+func (kind ConstraintKind) String() string {
+	switch kind {
+	case PrimaryKeyConstraint:
+		return "primary key"
+	case ForeignKeyConstraint:
+		return "foreign key"
+	case UniqueConstraint:
+		return "unique"
+	case CheckConstraint:
+		return "check"
+	case ExclusionConstraint:
+		return "exclusion"
+	}
+
+	return string(kind)
+}
+
+// Schema describes the tables in a database, as loaded from the PostgreSQL catalog by
+// DB.Schema.
+type Schema struct {
+	// Tables are ordered by schema, then name.
+	Tables []*SchemaTable
+}
+
+// SchemaTable describes a table, view, or other relation with columns.
+type SchemaTable struct {
+	Schema string
+	Name   string
+
+	// Kind is the type of relation:  "table", "partitioned table", "view", "materialized view",
+	// or "foreign table".
+	Kind string
+
+	// Comment is the table's COMMENT, if any.
+	Comment string
+
+	// Columns are in table order.
+	Columns []SchemaColumn
+
+	// Indexes and Constraints are ordered by name.
+	Indexes     []SchemaIndex
+	Constraints []SchemaConstraint
+}
+
+// SchemaColumn describes a table column.
+type SchemaColumn struct {
+	Name string
+
+	// Position is the column's ordinal position in the table, starting at 1.
+	Position int
+
+	// Type is the formatted type of the column, e.g. "character varying(255)" or "citext".
+	Type string
+
+	// UDTName is the underlying type name, e.g. "varchar" or "int4".
+	UDTName string
+
+	NotNull bool
+
+	// Default is the column's default expression, as reported by PostgreSQL, e.g. "now()" or
+	// "'pending'::text", or nil if it doesn't have one.
+	Default *string
+
+	// Identity is set for GENERATED ... AS IDENTITY columns.
+	Identity bool
+
+	// Comment is the column's COMMENT, if any.
+	Comment string
+}
+
+// SchemaIndex describes an index on a table.
+type SchemaIndex struct {
+	Name string
+
+	// Columns are the indexed columns, in order.  Expressions are left out.
+	Columns []string
+
+	Unique  bool
+	Primary bool
+
+	// Definition is the CREATE INDEX statement for the index.
+	Definition string
+}
+
+// SchemaConstraint describes a table constraint.
+type SchemaConstraint struct {
+	Name string
+	Kind ConstraintKind
+
+	// Columns are the constrained columns, in order.  Empty for check constraints on
+	// expressions.
+	Columns []string
+
+	// Definition is the constraint's definition, e.g. "CHECK ((balance >= 0))".
+	Definition string
+
+	// For foreign keys, References is the referenced table, schema qualified if it isn't on the
+	// search_path, and ReferencedColumns are the referenced columns, in order.
+	References        string
+	ReferencedColumns []string
+}
+
+// Table returns the named table.  The name may be schema qualified, e.g. "billing.invoices";
+// otherwise the first table with the name is returned, in schema order.
+func (s *Schema) Table(name string) (*SchemaTable, bool) {
+	schema, table := "", name
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		schema, table = name[:i], name[i+1:]
+	}
+
+	for _, t := range s.Tables {
+		if t.Name == table && (schema == "" || t.Schema == schema) {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
+
+// Column returns the named column.
+func (t *SchemaTable) Column(name string) (SchemaColumn, bool) {
+	for _, column := range t.Columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+
+	return SchemaColumn{}, false
+}
+
+// PrimaryKey returns the table's primary key columns, or nil if it doesn't have a primary key.
+func (t *SchemaTable) PrimaryKey() []string {
+	for _, constraint := range t.Constraints {
+		if constraint.Kind == PrimaryKeyConstraint {
+			return constraint.Columns
+		}
+	}
+
+	return nil
+}
+
+// relationFilter limits catalog queries to the relations in the schemas given by $1, or outside
+// the system schemas if $1 is NULL.  Expects the relation's pg_class as c and its pg_namespace
+// as n.
+const relationFilter = `
+	c.relkind IN ('r', 'p', 'v', 'm', 'f')
+	AND CASE WHEN $1::text[] IS NULL
+		THEN n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'
+		ELSE n.nspname = ANY($1::text[]) END`
+
+// Schema describes the tables, views, columns, indexes, and constraints in the database, for
+// tooling such as admin interfaces, schema validators, and code generators.  Limit the results to
+// the named schemas, or leave them out to describe everything outside the system schemas.
+// The catalog is read in four queries, regardless of the number of tables.
+func (db *DB) Schema(ctx context.Context, schemas ...string) (*Schema, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return loadSchema(ctx, db, schemas)
+}
+
+// loadSchema reads the catalog for the tables in the schemas.
+func loadSchema(ctx context.Context, conn Conn, schemas []string) (*Schema, error) {
+	var filter []string
+	if len(schemas) > 0 {
+		filter = schemas
+	}
+
+	schema := &Schema{}
+	tables := make(map[uint32]*SchemaTable)
+
+	rows, err := conn.Query(ctx, `
+		SELECT c.oid, n.nspname, c.relname, c.relkind::text, coalesce(obj_description(c.oid, 'pg_class'), '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE `+relationFilter+`
+		ORDER BY n.nspname, c.relname`, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var oid uint32
+		var kind string
+
+		table := &SchemaTable{}
+		if err := rows.Scan(&oid, &table.Schema, &table.Name, &kind, &table.Comment); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		table.Kind = relationKinds[kind]
+
+		schema.Tables = append(schema.Tables, table)
+		tables[oid] = table
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadColumns(ctx, conn, filter, tables); err != nil {
+		return nil, err
+	}
+
+	if err := loadIndexes(ctx, conn, filter, tables); err != nil {
+		return nil, err
+	}
+
+	if err := loadConstraints(ctx, conn, filter, tables); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// relationKinds names the pg_class.relkind values.
+var relationKinds = map[string]string{
+	"r": "table",
+	"p": "partitioned table",
+	"v": "view",
+	"m": "materialized view",
+	"f": "foreign table",
+}
+
+// loadColumns adds the columns to their tables.
+func loadColumns(ctx context.Context, conn Conn, filter []string, tables map[uint32]*SchemaTable) error {
+	rows, err := conn.Query(ctx, `
+		SELECT a.attrelid, a.attname, a.attnum, format_type(a.atttypid, a.atttypmod), t.typname,
+			a.attnotnull, pg_get_expr(d.adbin, d.adrelid), a.attidentity <> '',
+			coalesce(col_description(a.attrelid, a.attnum), '')
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attnum > 0 AND NOT a.attisdropped AND `+relationFilter+`
+		ORDER BY a.attrelid, a.attnum`, filter)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var oid uint32
+		var column SchemaColumn
+
+		if err := rows.Scan(&oid, &column.Name, &column.Position, &column.Type, &column.UDTName,
+			&column.NotNull, &column.Default, &column.Identity, &column.Comment); err != nil {
+			return err
+		}
+
+		if table, ok := tables[oid]; ok {
+			table.Columns = append(table.Columns, column)
+		}
+	}
+
+	return rows.Err()
+}
+
+// loadIndexes adds the indexes to their tables.
+func loadIndexes(ctx context.Context, conn Conn, filter []string, tables map[uint32]*SchemaTable) error {
+	rows, err := conn.Query(ctx, `
+		SELECT i.indrelid, ic.relname, i.indisunique, i.indisprimary,
+			ARRAY(SELECT a.attname::text
+				FROM unnest(i.indkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+				ORDER BY k.ord),
+			pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_class c ON c.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE `+relationFilter+`
+		ORDER BY i.indrelid, ic.relname`, filter)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var oid uint32
+		var index SchemaIndex
+
+		if err := rows.Scan(&oid, &index.Name, &index.Unique, &index.Primary, &index.Columns,
+			&index.Definition); err != nil {
+			return err
+		}
+
+		if table, ok := tables[oid]; ok {
+			table.Indexes = append(table.Indexes, index)
+		}
+	}
+
+	return rows.Err()
+}
+
+// loadConstraints adds the constraints to their tables.
+func loadConstraints(ctx context.Context, conn Conn, filter []string, tables map[uint32]*SchemaTable) error {
+	rows, err := conn.Query(ctx, `
+		SELECT con.conrelid, con.conname, con.contype::text,
+			ARRAY(SELECT a.attname::text
+				FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord),
+			pg_get_constraintdef(con.oid),
+			CASE WHEN con.confrelid <> 0 THEN con.confrelid::regclass::text ELSE '' END,
+			ARRAY(SELECT a.attname::text
+				FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE `+relationFilter+`
+		ORDER BY con.conrelid, con.conname`, filter)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var oid uint32
+		var constraint SchemaConstraint
+		var kind string
+
+		if err := rows.Scan(&oid, &constraint.Name, &kind, &constraint.Columns, &constraint.Definition,
+			&constraint.References, &constraint.ReferencedColumns); err != nil {
+			return err
+		}
+
+		constraint.Kind = ConstraintKind(kind)
+		if len(constraint.ReferencedColumns) == 0 {
+			constraint.ReferencedColumns = nil
+		}
+
+		if table, ok := tables[oid]; ok {
+			table.Constraints = append(table.Constraints, constraint)
+		}
+	}
+
+	return rows.Err()
+}
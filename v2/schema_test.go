@@ -0,0 +1,55 @@
+package hermes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaTable(t *testing.T) {
+	schema := &Schema{Tables: []*SchemaTable{
+		{Schema: "billing", Name: "invoices"},
+		{Schema: "public", Name: "invoices"},
+		{
+			Schema:  "public",
+			Name:    "users",
+			Columns: []SchemaColumn{{Name: "id", Position: 1}, {Name: "email", Position: 2}},
+			Constraints: []SchemaConstraint{
+				{Name: "users_email_key", Kind: UniqueConstraint, Columns: []string{"email"}},
+				{Name: "users_pkey", Kind: PrimaryKeyConstraint, Columns: []string{"id"}},
+			},
+		},
+	}}
+
+	if table, ok := schema.Table("invoices"); !ok || table.Schema != "billing" {
+		t.Errorf("Expected the first invoices table; was %#v", table)
+	}
+
+	if table, ok := schema.Table("public.invoices"); !ok || table.Schema != "public" {
+		t.Errorf("Expected public.invoices; was %#v", table)
+	}
+
+	if _, ok := schema.Table("audit.invoices"); ok {
+		t.Error("Expected audit.invoices not to be found")
+	}
+
+	users, ok := schema.Table("users")
+	if !ok {
+		t.Fatal("Expected the users table")
+	}
+
+	if column, ok := users.Column("email"); !ok || column.Position != 2 {
+		t.Errorf("Expected the email column at position 2; was %#v", column)
+	}
+
+	if _, ok := users.Column("name"); ok {
+		t.Error("Expected the name column not to be found")
+	}
+
+	if pk := users.PrimaryKey(); !reflect.DeepEqual(pk, []string{"id"}) {
+		t.Errorf("Expected primary key (id); was %v", pk)
+	}
+
+	if kind := ForeignKeyConstraint.String(); kind != "foreign key" {
+		t.Errorf("Expected foreign key; was %s", kind)
+	}
+}
@@ -0,0 +1,60 @@
+package hermes
+
+import (
+	"context"
+	"strings"
+)
+
+// ExecScript runs statements that take no arguments, such as migrations or maintenance commands,
+// in a single round trip.  The statements are joined into one script and sent with PostgreSQL's
+// simple query protocol, which runs them in order without parsing, describing, or preparing each
+// one first.
+//
+// pgx already sends an Exec without arguments with the simple protocol, so a single statement
+// gains nothing; the savings come from sending thousands of statements at once instead of waiting
+// on each one.
+//
+// Unless the script has its own BEGIN and COMMIT, PostgreSQL runs the statements in a single
+// implicit transaction:  if one fails, the statements before it are rolled back and the rest
+// don't run.  Statements that can't run in a transaction, such as VACUUM or CREATE INDEX
+// CONCURRENTLY, must be run on their own.
+func ExecScript(ctx context.Context, conn Conn, statements ...string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	script := joinStatements(statements)
+	if script == "" {
+		return nil
+	}
+
+	// Queued queries are sent with the extended protocol, which only allows one statement
+	if p, ok := conn.(*Pipeline); ok {
+		if err := p.Flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	_, err := conn.Exec(ctx, script)
+	return err
+}
+
+// joinStatements joins the statements into a script, separating them with semicolons.  Blank
+// statements are skipped.
+func joinStatements(statements []string) string {
+	var script strings.Builder
+
+	for _, statement := range statements {
+		statement = strings.TrimRight(strings.TrimSpace(statement), ";")
+		if statement == "" {
+			continue
+		}
+
+		if script.Len() > 0 {
+			script.WriteString(";\n")
+		}
+		script.WriteString(statement)
+	}
+
+	return script.String()
+}
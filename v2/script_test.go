@@ -0,0 +1,127 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// scriptConn records the statements executed.
+type scriptConn struct {
+	Conn
+
+	execs []string
+	args  [][]interface{}
+}
+
+func (c *scriptConn) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	c.execs = append(c.execs, sql)
+	c.args = append(c.args, args)
+	return pgconn.CommandTag{}, nil
+}
+
+func TestExecScript(t *testing.T) {
+	conn := &scriptConn{}
+
+	err := ExecScript(context.Background(), conn,
+		"create table widgets (id bigint primary key);",
+		"  ",
+		"create index widgets_name on widgets (name)",
+		"analyze widgets;\n")
+	if err != nil {
+		t.Fatalf("Unable to run script: %s", err)
+	}
+
+	if len(conn.execs) != 1 {
+		t.Fatalf("Expected a single round trip; was %d", len(conn.execs))
+	}
+
+	expected := "create table widgets (id bigint primary key);\ncreate index widgets_name on widgets (name);\nanalyze widgets"
+	if conn.execs[0] != expected {
+		t.Errorf("Expected %q; was %q", expected, conn.execs[0])
+	}
+
+	if len(conn.args[0]) != 0 {
+		t.Errorf("Expected no arguments, for the simple protocol; was %v", conn.args[0])
+	}
+
+	if err := ExecScript(context.Background(), conn, "", ";"); err != nil || len(conn.execs) != 1 {
+		t.Errorf("Expected an empty script to do nothing; was %v", err)
+	}
+}
+
+// statements returns n argument-less statements, like those in a migration script.
+func statements(n int) []string {
+	sqls := make([]string, n)
+	for i := range sqls {
+		sqls[i] = fmt.Sprintf("insert into hermes_script_bench (id) values (%d)", i)
+	}
+	return sqls
+}
+
+// benchDB connects to the test database, skipping the benchmark if it isn't available.
+func benchDB(b *testing.B) *DB {
+	db, err := Connect("postgres://localhost/hermes_test?sslmode=disable")
+	if err != nil {
+		b.Skipf("Unable to connect to database: %s", err)
+	}
+
+	if err := db.Ping(context.Background()); err != nil {
+		db.Shutdown()
+		b.Skipf("Unable to connect to database: %s", err)
+	}
+
+	b.Cleanup(db.Shutdown)
+
+	return db
+}
+
+func BenchmarkExecEach(b *testing.B) {
+	ctx := context.Background()
+	sqls := statements(100)
+
+	conn, err := benchDB(b).Pin(ctx)
+	if err != nil {
+		b.Fatalf("Unable to pin connection: %s", err)
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "create temp table hermes_script_bench (id int)"); err != nil {
+		b.Fatalf("Unable to create table: %s", err)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, sql := range sqls {
+			if _, err := conn.Exec(ctx, sql); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkExecScript(b *testing.B) {
+	ctx := context.Background()
+	sqls := statements(100)
+
+	conn, err := benchDB(b).Pin(ctx)
+	if err != nil {
+		b.Fatalf("Unable to pin connection: %s", err)
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "create temp table hermes_script_bench (id int)"); err != nil {
+		b.Fatalf("Unable to create table: %s", err)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := ExecScript(ctx, conn, sqls...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrShuttingDown is returned when starting a transaction or acquiring a connection on a DB
+// that's shutting down.
+var ErrShuttingDown = errors.New("database shutting down")
+
+// How often Shutdown checks whether the open transactions have finished.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// ShutdownError is returned by DB.Shutdown when transactions were still open at ctx's deadline
+// and had to be aborted.
+type ShutdownError struct {
+	// Aborted is the number of transactions aborted.
+	Aborted int
+
+	// Err is ctx's error.
+	Err error
+}
+
+// Error describes the aborted transactions.
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("aborted %d open transactions: %s", e.Aborted, e.Err)
+}
+
+// Unwrap returns ctx's error.
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// Shutdown the underlying pgx Pool.  You should call this when your application is closing to
+// release all the database pool connections.  New transactions are refused from the moment it's
+// called, and it waits for the open transactions to finish; see ShutdownContext to limit the
+// wait.
+func (db *DB) Shutdown() {
+	_ = db.ShutdownContext(context.Background())
+}
+
+// ShutdownContext shuts down the underlying pgx Pool gracefully.  From the moment it's called,
+// new transactions and connections are refused with ErrShuttingDown, while the transactions
+// already open are left to commit or roll back, up to ctx's deadline.  Then the pool is closed.
+// If ctx expires first, the transactions still open are aborted by closing their connections,
+// which rolls them back, and a *ShutdownError reporting how many were aborted is returned:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//
+//	if err := db.ShutdownContext(ctx); err != nil {
+//		log.Printf("Database shutdown: %s", err)
+//	}
+//
+// Either way, the pool is closed by the time ShutdownContext returns.  Closing the pool waits
+// for every connection to come back, so an aborted transaction's connection must still be
+// released, which happens as soon as its goroutine sees the error and rolls back or closes the
+// transaction.  Statements run outside a transaction aren't refused until the pool closes.  With
+// a nil ctx, ShutdownContext waits for the open transactions indefinitely.
+func (db *DB) ShutdownContext(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	atomic.StoreInt32(&db.closing, 1)
+
+	var shutdownErr error
+	if err := db.drain(ctx); err != nil {
+		shutdownErr = &ShutdownError{Aborted: db.abortTxs(), Err: err}
+	}
+
+	db.Pool.Close()
+
+	return shutdownErr
+}
+
+// Acquire a connection from the pool.  Returns ErrShuttingDown once the DB is shutting down.
+// Release the connection back to the pool when you're done with it.
+func (db *DB) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	if db.shuttingDown() {
+		return nil, ErrShuttingDown
+	}
+
+	return db.Pool.Acquire(ctx)
+}
+
+// shuttingDown returns true once Shutdown or ShutdownContext is called.
+func (db *DB) shuttingDown() bool {
+	return db != nil && atomic.LoadInt32(&db.closing) == 1
+}
+
+// drain waits for the open transactions to finish.  Returns ctx's error if it's done first.
+func (db *DB) drain(ctx context.Context) error {
+	if db.OpenTransactions() == 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if db.OpenTransactions() == 0 {
+				return nil
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if db.OpenTransactions() == 0 {
+			return nil
+		}
+	}
+}
+
+// abortTxs closes the network connections of the open transactions, so the database rolls them
+// back, and the transactions' next statements fail.  Returns the number of transactions aborted.
+func (db *DB) abortTxs() int {
+	var aborted int
+
+	db.openTxs.Range(func(_, value interface{}) bool {
+		aborted++
+
+		// The network connection is safe to close from another goroutine; the pgx.Conn isn't
+		if conn, ok := value.(*pgx.Conn); ok && conn != nil {
+			_ = conn.PgConn().Conn().Close()
+		}
+
+		return true
+	})
+
+	return aborted
+}
@@ -0,0 +1,83 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShutdownRefuses(t *testing.T) {
+	db := newUnreachableDB(t)
+
+	if err := db.ShutdownContext(nil); err != nil {
+		t.Fatalf("Expected a clean shutdown; was %s", err)
+	}
+
+	if _, err := db.Begin(nil); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown from Begin; was %v", err)
+	}
+
+	if err := db.BeginFunc(nil, func(Conn) error { return nil }); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown from BeginFunc; was %v", err)
+	}
+
+	if _, err := db.BeginWithTimeout(nil); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown from BeginWithTimeout; was %v", err)
+	}
+
+	if _, err := db.Acquire(context.Background()); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown from Acquire; was %v", err)
+	}
+}
+
+func TestShutdownDrains(t *testing.T) {
+	db := newUnreachableDB(t)
+
+	var ended int32
+	db.txBegan(&ended, nil)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		db.txEnded(&ended, false, true)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := db.ShutdownContext(ctx); err != nil {
+		t.Errorf("Expected the transaction to finish before the deadline; was %s", err)
+	}
+
+	if open := db.OpenTransactions(); open != 0 {
+		t.Errorf("Expected no open transactions; was %d", open)
+	}
+}
+
+func TestShutdownAborts(t *testing.T) {
+	db := newUnreachableDB(t)
+
+	var first, second, done int32
+	db.txBegan(&first, nil)
+	db.txBegan(&second, nil)
+	db.txBegan(&done, nil)
+	db.txEnded(&done, false, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	err := db.ShutdownContext(ctx)
+
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("Expected a ShutdownError; was %v", err)
+	}
+
+	if shutdownErr.Aborted != 2 {
+		t.Errorf("Expected 2 transactions aborted; was %d", shutdownErr.Aborted)
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context's error; was %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package hermes
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DBTX matches the DBTX interface sqlc generates for the pgx/v5 driver, including the CopyFrom
+// and SendBatch methods it adds for :copyfrom and :batch queries.  Every hermes.Conn is a DBTX,
+// so sqlc-generated code runs against a DB, Tx, or PinnedConn without an adapter:
+//
+//	queries := db.New(conn) // sqlc's generated constructor
+//
+// The assignment below keeps the two interfaces in step.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+var _ DBTX = Conn(nil)
@@ -0,0 +1,134 @@
+package hermes
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Default time between publishing the DB's Stats to its Metrics.
+const defaultStatsInterval = 15 * time.Second
+
+// Stats are a snapshot of a DB's connection pool, from pgxpool.Stat, along with the counts of
+// the transactions, advisory locks, and retries made through the DB.  Counts are totals since
+// the DB was created, unless noted.
+type Stats struct {
+	// AcquireCount is the number of connections acquired from the pool.
+	AcquireCount int64
+
+	// AcquireDuration is the total time spent waiting for connections from the pool.
+	AcquireDuration time.Duration
+
+	// CanceledAcquireCount is the number of waits for a connection canceled by their context.
+	CanceledAcquireCount int64
+
+	// EmptyAcquireCount is the number of connections acquired after waiting on an empty pool.
+	EmptyAcquireCount int64
+
+	// NewConnsCount is the number of connections opened.
+	NewConnsCount int64
+
+	// MaxLifetimeDestroyCount is the number of connections closed for exceeding the pool's
+	// MaxConnLifetime.
+	MaxLifetimeDestroyCount int64
+
+	// MaxIdleDestroyCount is the number of connections closed for exceeding the pool's
+	// MaxConnIdleTime.
+	MaxIdleDestroyCount int64
+
+	// AcquiredConns is the number of connections currently in use.
+	AcquiredConns int32
+
+	// IdleConns is the number of connections currently idle.
+	IdleConns int32
+
+	// ConstructingConns is the number of connections currently being opened.
+	ConstructingConns int32
+
+	// TotalConns is the number of connections currently open or being opened.
+	TotalConns int32
+
+	// MaxConns is the most connections the pool will open.
+	MaxConns int32
+
+	// TxBegun is the number of transactions started.  Savepoints aren't counted.
+	TxBegun int64
+
+	// TxCommitted is the number of transactions committed.
+	TxCommitted int64
+
+	// TxRolledBack is the number of transactions rolled back, including those whose commit
+	// failed.
+	TxRolledBack int64
+
+	// TxOpen is the number of transactions currently open.  See DB.OpenTransactions.
+	TxOpen int64
+
+	// LocksHeld is the number of session-wide advisory locks currently held.  See
+	// DB.HeldLocks.
+	LocksHeld int64
+
+	// Retries is the number of retries made by DB.WithRetry and the DB's ReconnectPolicy.
+	Retries int64
+}
+
+// Stats returns a snapshot of the connection pool's statistics and the DB's counters.
+func (db *DB) Stats() Stats {
+	stat := db.Pool.Stat()
+
+	return Stats{
+		AcquireCount:            stat.AcquireCount(),
+		AcquireDuration:         stat.AcquireDuration(),
+		CanceledAcquireCount:    stat.CanceledAcquireCount(),
+		EmptyAcquireCount:       stat.EmptyAcquireCount(),
+		NewConnsCount:           stat.NewConnsCount(),
+		MaxLifetimeDestroyCount: stat.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     stat.MaxIdleDestroyCount(),
+		AcquiredConns:           stat.AcquiredConns(),
+		IdleConns:               stat.IdleConns(),
+		ConstructingConns:       stat.ConstructingConns(),
+		TotalConns:              stat.TotalConns(),
+		MaxConns:                stat.MaxConns(),
+		TxBegun:                 atomic.LoadInt64(&db.txBegun),
+		TxCommitted:             atomic.LoadInt64(&db.txCommitted),
+		TxRolledBack:            atomic.LoadInt64(&db.txRolledBack),
+		TxOpen:                  atomic.LoadInt64(&db.txOpen),
+		LocksHeld:               atomic.LoadInt64(&db.locksHeld),
+		Retries:                 atomic.LoadInt64(&db.retries),
+	}
+}
+
+// PublishStats sends the DB's Stats to its Metrics every interval, defaulting to 15 seconds,
+// until ctx is done.  Run it in a goroutine alongside SetMetrics:
+//
+//	db.SetMetrics(metrics)
+//	go db.PublishStats(ctx, 10*time.Second)
+//
+// Nothing is published while the DB has no Metrics.
+func (db *DB) PublishStats(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultStatsInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		db.publishStats()
+	}
+}
+
+// publishStats sends the DB's Stats to its Metrics, if it has any.
+func (db *DB) publishStats() {
+	if db.observer == nil || !db.observer.enabled() {
+		return
+	}
+
+	db.metrics().ObserveStats(db.Stats())
+}
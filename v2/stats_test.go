@@ -0,0 +1,71 @@
+package hermes
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	db := newUnreachableDB(t)
+
+	var committed, rolledBack, open int32
+
+	db.txBegan(&committed, nil)
+	db.txBegan(&rolledBack, nil)
+	db.txBegan(&open, nil)
+	db.txEnded(&committed, false, true)
+	db.txEnded(&committed, false, false)
+	db.txEnded(&rolledBack, false, false)
+
+	stats := db.Stats()
+
+	if stats.TxBegun != 3 {
+		t.Errorf("Expected 3 transactions begun; was %d", stats.TxBegun)
+	}
+
+	if stats.TxCommitted != 1 {
+		t.Errorf("Expected 1 transaction committed; was %d", stats.TxCommitted)
+	}
+
+	if stats.TxRolledBack != 1 {
+		t.Errorf("Expected 1 transaction rolled back; was %d", stats.TxRolledBack)
+	}
+
+	if stats.TxOpen != 1 {
+		t.Errorf("Expected 1 transaction open; was %d", stats.TxOpen)
+	}
+
+	if stats.MaxConns != db.Pool.Config().MaxConns {
+		t.Errorf("Expected the pool's max connections, %d; was %d", db.Pool.Config().MaxConns, stats.MaxConns)
+	}
+}
+
+func TestPublishStats(t *testing.T) {
+	db := newUnreachableDB(t)
+
+	// Without Metrics, there's nothing to publish to
+	db.publishStats()
+
+	m := &recordedMetrics{}
+	db.SetMetrics(m)
+
+	var ended int32
+	db.txBegan(&ended, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	db.PublishStats(ctx, 10*time.Millisecond)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.stats) == 0 {
+		t.Fatal("Expected the stats to be published")
+	}
+
+	if m.stats[0].TxBegun != 1 {
+		t.Errorf("Expected 1 transaction begun; was %d", m.stats[0].TxBegun)
+	}
+}
@@ -0,0 +1,426 @@
+package hermes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StatementCacheOptions tune the prepared statement cache on each of a pool's connections.  See
+// ConfigureStatementCache.
+type StatementCacheOptions struct {
+	// Mode is how queries are run by default.  Zero keeps the pool configuration's mode,
+	// pgx.QueryExecModeCacheStatement by default, which prepares each query the first time a
+	// connection runs it and caches the prepared statement.
+	Mode pgx.QueryExecMode
+
+	// Capacity is the number of prepared statements cached on each connection; when the cache is
+	// full, the least recently used statement is evicted.  Zero keeps the pool configuration's
+	// capacity, 512 by default.  Negative disables the cache, which requires a Mode other than
+	// pgx.QueryExecModeCacheStatement.
+	Capacity int
+}
+
+// StatementCacheStats describe how well the pool's prepared statement caches are working.  A
+// high number of evictions compared to hits means the cache is too small for the variety of
+// queries the application runs.
+type StatementCacheStats struct {
+	// Hits is the number of queries that used a cached prepared statement.
+	Hits int64
+
+	// Misses is the number of queries that had to be prepared.
+	Misses int64
+
+	// Evictions is the number of prepared statements dropped to make room in a full cache.
+	Evictions int64
+
+	// Capacity is the number of prepared statements cached on each connection.
+	Capacity int
+
+	// Mode is how queries are run by default.  Only pgx.QueryExecModeCacheStatement uses the
+	// cache.
+	Mode pgx.QueryExecMode
+}
+
+// ConfigureStatementCache applies the options to the pool configuration.  Call it before passing
+// the configuration to ConnectConfig.  The same settings may be made in the connection URI with
+// the default_query_exec_mode and statement_cache_capacity parameters.
+func ConfigureStatementCache(config *pgxpool.Config, opts StatementCacheOptions) {
+	if opts.Mode != 0 {
+		config.ConnConfig.DefaultQueryExecMode = opts.Mode
+	}
+
+	if opts.Capacity > 0 {
+		config.ConnConfig.StatementCacheCapacity = opts.Capacity
+	} else if opts.Capacity < 0 {
+		config.ConnConfig.StatementCacheCapacity = 0
+	}
+}
+
+// StatementCacheStats returns the hits, misses, and evictions of the prepared statement caches
+// on the pool's connections.  Queries run in other modes, batches, and statements without
+// arguments that are found in the cache aren't counted.
+func (db *DB) StatementCacheStats() StatementCacheStats {
+	if db.stmtCache == nil {
+		return StatementCacheStats{}
+	}
+
+	return db.stmtCache.stats()
+}
+
+// FlushStatementCache deallocates the prepared statements on every connection in the pool and
+// empties their caches, e.g. after a migration changes the tables the statements use.  Idle
+// connections are flushed immediately; connections in use are flushed the next time they're
+// acquired.
+func (db *DB) FlushStatementCache(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if db.stmtCache == nil {
+		return nil
+	}
+
+	db.stmtCache.invalidate()
+
+	// Acquiring the connections flushes them
+	for _, conn := range db.Pool.AcquireAllIdle(ctx) {
+		conn.Release()
+	}
+
+	return ctx.Err()
+}
+
+// stmtCachePrefix starts the names of the prepared statements pgx caches.
+const stmtCachePrefix = "stmtcache_"
+
+// statementCache tracks the use of the prepared statement caches on a pool's connections.  It's
+// installed as the connections' tracer, passing trace calls along to the configured tracer.
+type statementCache struct {
+	// Counters are kept first in the struct for 64-bit atomic alignment on 32-bit platforms.
+	hits      int64
+	misses    int64
+	evictions int64
+
+	next     pgx.QueryTracer
+	mode     pgx.QueryExecMode
+	capacity int
+
+	// lifetime is how long a connection may live before the pool closes it
+	lifetime time.Duration
+
+	mutex      sync.Mutex
+	generation int64
+	conns      map[*pgx.Conn]*cachedConn
+
+	// statements are the named statements prepared on every connection with DB.Prepare, and
+	// version counts the changes to them
+	statements map[string]string
+	version    int64
+}
+
+// cachedConn tracks a connection's statement cache.
+type cachedConn struct {
+	created    time.Time
+	size       int
+	generation int64
+
+	// prepared are the DB.Prepare statements prepared on the connection, as of version
+	prepared map[string]string
+	version  int64
+}
+
+// stmtCacheQuery is attached to a query's context to note whether the query prepared a
+// statement for the cache.
+type stmtCacheQuery struct {
+	counted  bool
+	prepared bool
+}
+
+type stmtCacheKey struct{}
+
+func newStatementCache(config *pgxpool.Config) *statementCache {
+	return &statementCache{
+		next:     config.ConnConfig.Tracer,
+		mode:     config.ConnConfig.DefaultQueryExecMode,
+		capacity: config.ConnConfig.StatementCacheCapacity,
+		lifetime: config.MaxConnLifetime + config.MaxConnLifetimeJitter + config.HealthCheckPeriod,
+		conns:    make(map[*pgx.Conn]*cachedConn),
+
+		statements: make(map[string]string),
+	}
+}
+
+// install the statement cache tracking in the pool configuration.
+func (s *statementCache) install(config *pgxpool.Config) {
+	config.ConnConfig.Tracer = s
+	config.AfterConnect = s.afterConnect(config.AfterConnect)
+	config.BeforeAcquire = s.beforeAcquire(config.BeforeAcquire)
+}
+
+// stats returns the current statistics.
+func (s *statementCache) stats() StatementCacheStats {
+	return StatementCacheStats{
+		Hits:      atomic.LoadInt64(&s.hits),
+		Misses:    atomic.LoadInt64(&s.misses),
+		Evictions: atomic.LoadInt64(&s.evictions),
+		Capacity:  s.capacity,
+		Mode:      s.mode,
+	}
+}
+
+// invalidate marks every connection's cache to be flushed before the connection is next used.
+func (s *statementCache) invalidate() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.generation++
+}
+
+// afterConnect returns an AfterConnect function that starts tracking the connection before
+// calling next, if any.
+func (s *statementCache) afterConnect(next func(context.Context, *pgx.Conn) error) func(context.Context, *pgx.Conn) error {
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		s.mutex.Lock()
+		s.prune()
+		s.conn(conn)
+		s.mutex.Unlock()
+
+		// A statement that fails to prepare is retried when the connection's acquired
+		_ = s.prepare(ctx, conn)
+
+		if next != nil {
+			return next(ctx, conn)
+		}
+
+		return nil
+	}
+}
+
+// beforeAcquire returns a BeforeAcquire function that flushes the connection's statements if
+// they've been invalidated and brings its DB.Prepare statements up to date, before calling next,
+// if any.  A connection that can't be flushed is closed.
+func (s *statementCache) beforeAcquire(next func(context.Context, *pgx.Conn) bool) func(context.Context, *pgx.Conn) bool {
+	return func(ctx context.Context, conn *pgx.Conn) bool {
+		if !s.refresh(ctx, conn) {
+			return false
+		}
+
+		// Statements that fail to prepare are reported when they're used
+		_ = s.prepare(ctx, conn)
+
+		if next != nil {
+			return next(ctx, conn)
+		}
+
+		return true
+	}
+}
+
+// refresh deallocates the connection's prepared statements if they've been invalidated since
+// the connection was last used.  Returns false if the statements couldn't be deallocated.
+func (s *statementCache) refresh(ctx context.Context, conn *pgx.Conn) bool {
+	s.mutex.Lock()
+	cc := s.conn(conn)
+	stale := cc.generation < s.generation
+	generation := s.generation
+	s.mutex.Unlock()
+
+	if !stale {
+		return true
+	}
+
+	if err := conn.DeallocateAll(ctx); err != nil {
+		return false
+	}
+
+	s.mutex.Lock()
+	cc.size = 0
+	cc.generation = generation
+
+	// DEALLOCATE ALL dropped the DB.Prepare statements too
+	cc.prepared = nil
+	cc.version = -1
+	s.mutex.Unlock()
+
+	return true
+}
+
+// conn returns the tracking for the connection, starting it if necessary.  Must be called with
+// the mutex locked.
+func (s *statementCache) conn(conn *pgx.Conn) *cachedConn {
+	cc, ok := s.conns[conn]
+	if !ok {
+		cc = &cachedConn{created: time.Now(), generation: s.generation}
+		s.conns[conn] = cc
+	}
+
+	return cc
+}
+
+// prune stops tracking connections old enough that the pool must have closed them.  Must be
+// called with the mutex locked.
+func (s *statementCache) prune() {
+	if s.lifetime <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-s.lifetime - time.Minute)
+	for conn, cc := range s.conns {
+		if cc.created.Before(cutoff) {
+			delete(s.conns, conn)
+		}
+	}
+}
+
+// missed records a statement added to the connection's cache, evicting the least recently used
+// statement if the cache was full.
+func (s *statementCache) missed(conn *pgx.Conn) {
+	atomic.AddInt64(&s.misses, 1)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cc := s.conn(conn)
+	if cc.size < s.capacity {
+		cc.size++
+	} else {
+		atomic.AddInt64(&s.evictions, 1)
+	}
+}
+
+// cached returns true if the query with the given arguments uses the statement cache, and true
+// if it's known to, i.e. it isn't a statement without arguments that Exec runs with the simple
+// protocol.
+func (s *statementCache) cached(sql string, args []interface{}) (bool, bool) {
+	if sql == "" || s.capacity <= 0 {
+		return false, false
+	}
+
+	mode := s.mode
+
+options:
+	for len(args) > 0 {
+		switch arg := args[0].(type) {
+		case pgx.QueryExecMode:
+			mode = arg
+		case pgx.QueryResultFormats, pgx.QueryResultFormatsByOID, pgx.QueryRewriter:
+		default:
+			break options
+		}
+		args = args[1:]
+	}
+
+	if mode != pgx.QueryExecModeCacheStatement {
+		return false, false
+	}
+
+	return true, len(args) > 0
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (s *statementCache) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if cached, counted := s.cached(data.SQL, data.Args); cached {
+		ctx = context.WithValue(ctx, stmtCacheKey{}, &stmtCacheQuery{counted: counted})
+	}
+
+	if s.next != nil {
+		return s.next.TraceQueryStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (s *statementCache) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if query, ok := ctx.Value(stmtCacheKey{}).(*stmtCacheQuery); ok {
+		if query.prepared {
+			s.missed(conn)
+		} else if query.counted {
+			atomic.AddInt64(&s.hits, 1)
+		}
+	}
+
+	if s.next != nil {
+		s.next.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+// TracePrepareStart implements pgx.PrepareTracer.
+func (s *statementCache) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	if next, ok := s.next.(pgx.PrepareTracer); ok {
+		return next.TracePrepareStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+// TracePrepareEnd implements pgx.PrepareTracer.
+func (s *statementCache) TracePrepareEnd(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareEndData) {
+	if query, ok := ctx.Value(stmtCacheKey{}).(*stmtCacheQuery); ok && data.Err == nil && !data.AlreadyPrepared {
+		query.prepared = true
+	}
+
+	if next, ok := s.next.(pgx.PrepareTracer); ok {
+		next.TracePrepareEnd(ctx, conn, data)
+	}
+}
+
+// TraceBatchStart implements pgx.BatchTracer.
+func (s *statementCache) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if next, ok := s.next.(pgx.BatchTracer); ok {
+		return next.TraceBatchStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+// TraceBatchQuery implements pgx.BatchTracer.
+func (s *statementCache) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if next, ok := s.next.(pgx.BatchTracer); ok {
+		next.TraceBatchQuery(ctx, conn, data)
+	}
+}
+
+// TraceBatchEnd implements pgx.BatchTracer.
+func (s *statementCache) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	if next, ok := s.next.(pgx.BatchTracer); ok {
+		next.TraceBatchEnd(ctx, conn, data)
+	}
+}
+
+// TraceCopyFromStart implements pgx.CopyFromTracer.
+func (s *statementCache) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	if next, ok := s.next.(pgx.CopyFromTracer); ok {
+		return next.TraceCopyFromStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+// TraceCopyFromEnd implements pgx.CopyFromTracer.
+func (s *statementCache) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	if next, ok := s.next.(pgx.CopyFromTracer); ok {
+		next.TraceCopyFromEnd(ctx, conn, data)
+	}
+}
+
+// TraceConnectStart implements pgx.ConnectTracer.
+func (s *statementCache) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	if next, ok := s.next.(pgx.ConnectTracer); ok {
+		return next.TraceConnectStart(ctx, data)
+	}
+
+	return ctx
+}
+
+// TraceConnectEnd implements pgx.ConnectTracer.
+func (s *statementCache) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	if next, ok := s.next.(pgx.ConnectTracer); ok {
+		next.TraceConnectEnd(ctx, data)
+	}
+}
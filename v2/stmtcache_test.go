@@ -0,0 +1,158 @@
+package hermes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// countingTracer counts the queries passed along to it.
+type countingTracer struct {
+	starts   int
+	ends     int
+	prepares int
+}
+
+func (t *countingTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	t.starts++
+	return ctx
+}
+
+func (t *countingTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {
+	t.ends++
+}
+
+func (t *countingTracer) TracePrepareStart(ctx context.Context, _ *pgx.Conn, _ pgx.TracePrepareStartData) context.Context {
+	t.prepares++
+	return ctx
+}
+
+func (t *countingTracer) TracePrepareEnd(context.Context, *pgx.Conn, pgx.TracePrepareEndData) {}
+
+func newTestStatementCache(t *testing.T, capacity int) (*statementCache, *countingTracer) {
+	config, err := pgxpool.ParseConfig("postgres://localhost/hermes_test")
+	if err != nil {
+		t.Fatalf("Unable to parse the configuration: %s", err)
+	}
+
+	tracer := &countingTracer{}
+	config.ConnConfig.Tracer = tracer
+	ConfigureStatementCache(config, StatementCacheOptions{Capacity: capacity})
+
+	s := newStatementCache(config)
+	s.install(config)
+
+	if config.ConnConfig.Tracer != s {
+		t.Fatal("Expected the statement cache to be installed as the tracer")
+	}
+
+	return s, tracer
+}
+
+// query simulates pgx running a query, preparing it if prepare is true.
+func query(s *statementCache, sql string, prepare bool, args ...interface{}) {
+	ctx := s.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql, Args: args})
+	if prepare {
+		ctx = s.TracePrepareStart(ctx, nil, pgx.TracePrepareStartData{Name: stmtCachePrefix + "1", SQL: sql})
+		s.TracePrepareEnd(ctx, nil, pgx.TracePrepareEndData{})
+	}
+	s.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+}
+
+func TestStatementCacheStats(t *testing.T) {
+	s, tracer := newTestStatementCache(t, 2)
+
+	query(s, "select $1::int", true, 1)
+	query(s, "select $1::int", false, 2)
+	query(s, "select $1::text", true, "a")
+	query(s, "select $1::bigint", true, 3)
+
+	stats := s.stats()
+	if stats.Hits != 1 {
+		t.Errorf("Expected 1 hit; was %d", stats.Hits)
+	}
+
+	if stats.Misses != 3 {
+		t.Errorf("Expected 3 misses; was %d", stats.Misses)
+	}
+
+	if stats.Evictions != 1 {
+		t.Errorf("Expected 1 eviction; was %d", stats.Evictions)
+	}
+
+	if stats.Capacity != 2 {
+		t.Errorf("Expected a capacity of 2; was %d", stats.Capacity)
+	}
+
+	if tracer.starts != 4 || tracer.ends != 4 || tracer.prepares != 3 {
+		t.Errorf("Expected the configured tracer to see every call; was %d starts, %d ends, %d prepares",
+			tracer.starts, tracer.ends, tracer.prepares)
+	}
+}
+
+func TestStatementCacheUncounted(t *testing.T) {
+	s, _ := newTestStatementCache(t, 0)
+
+	// Without arguments, Exec uses the simple protocol
+	query(s, "vacuum", false)
+
+	// Other modes don't use the cache
+	query(s, "select $1::int", false, pgx.QueryExecModeExec, 1)
+	query(s, "select $1::int", false, pgx.QueryExecModeSimpleProtocol, 1)
+
+	// A query without arguments counts when it has to be prepared
+	query(s, "select 1", true)
+
+	// Options ahead of the arguments don't change the mode
+	query(s, "select $1::int", false, pgx.QueryResultFormats{pgx.TextFormatCode}, 1)
+
+	stats := s.stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss; was %d and %d", stats.Hits, stats.Misses)
+	}
+}
+
+func TestConfigureStatementCache(t *testing.T) {
+	config, err := pgxpool.ParseConfig("postgres://localhost/hermes_test")
+	if err != nil {
+		t.Fatalf("Unable to parse the configuration: %s", err)
+	}
+
+	ConfigureStatementCache(config, StatementCacheOptions{Mode: pgx.QueryExecModeDescribeExec, Capacity: -1})
+
+	if config.ConnConfig.DefaultQueryExecMode != pgx.QueryExecModeDescribeExec {
+		t.Errorf("Expected describe exec mode; was %v", config.ConnConfig.DefaultQueryExecMode)
+	}
+
+	if config.ConnConfig.StatementCacheCapacity != 0 {
+		t.Errorf("Expected the statement cache to be disabled; was %d", config.ConnConfig.StatementCacheCapacity)
+	}
+
+	s := newStatementCache(config)
+	query(s, "select $1::int", true, 1)
+
+	if stats := s.stats(); stats.Misses != 0 {
+		t.Errorf("Expected no misses with the cache disabled; was %d", stats.Misses)
+	}
+}
+
+func TestFlushStatementCache(t *testing.T) {
+	s, _ := newTestStatementCache(t, 10)
+
+	query(s, "select $1::int", true, 1)
+	s.invalidate()
+
+	s.mutex.Lock()
+	cc := s.conns[nil]
+	s.mutex.Unlock()
+
+	if cc == nil || cc.size != 1 {
+		t.Fatalf("Expected the connection to cache 1 statement; was %v", cc)
+	}
+
+	if cc.generation >= s.generation {
+		t.Error("Expected the connection's cache to be stale after invalidating")
+	}
+}
@@ -0,0 +1,150 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrSessionState is returned when session settings such as the search_path can't be changed on
+// a Conn, e.g. a Pipeline, whose statements may run on any connection.
+var ErrSessionState = errors.New("connection doesn't support session settings")
+
+// WithSchema runs fn with the schema first on the search_path, so unqualified table names
+// resolve to the schema's tables, e.g. for a schema-per-tenant application.  The rest of the
+// search_path follows, so shared tables and extensions in other schemas stay visible.
+//
+// On a *DB, a connection is pinned for fn's exclusive use, and its search_path is restored before
+// it goes back to the pool; if it can't be restored, the connection is closed rather than handed
+// to another request with the tenant's schema.  On a *PinnedConn, the search_path is restored
+// once fn returns, or the connection closed if it can't be.  In a transaction, the search_path is set with SET LOCAL semantics and restored
+// once fn returns; if the transaction rolls back, PostgreSQL restores it.
+//
+// Pass the Conn given to fn to the functions you call, not the original.
+func WithSchema(ctx context.Context, conn Conn, schema string, fn func(conn Conn) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	switch c := conn.(type) {
+	case *DB:
+		pinned, err := c.Pin(ctx)
+		if err != nil {
+			return err
+		}
+		defer pinned.Close(ctx)
+
+		return withSchema(ctx, pinned, schema, false, fn)
+	case *PinnedConn:
+		return withSchema(ctx, c, schema, false, fn)
+	case *Tx:
+		return withSchema(ctx, c, schema, true, fn)
+	}
+
+	return fmt.Errorf("%w: %T", ErrSessionState, conn)
+}
+
+// withSchema puts the schema at the front of the connection's search_path, runs fn, and restores
+// the previous search_path.
+func withSchema(ctx context.Context, conn Conn, schema string, local bool, fn func(conn Conn) error) error {
+	var previous string
+	if err := conn.QueryRow(ctx, "SELECT current_setting('search_path')").Scan(&previous); err != nil {
+		return err
+	}
+
+	path := pgx.Identifier{schema}.Sanitize()
+	if previous != "" {
+		path += ", " + previous
+	}
+
+	if err := setConfig(ctx, conn, "search_path", path, local); err != nil {
+		return err
+	}
+
+	fnErr := fn(conn)
+
+	// Use a fresh context, so the search_path is restored even if ctx was canceled
+	if err := setConfig(context.Background(), conn, "search_path", previous, local); err != nil {
+		if pinned, ok := conn.(*PinnedConn); ok && !local {
+			// Don't hand the connection to another request with the wrong search_path
+			_ = pinned.Conn.Conn().Close(context.Background())
+		}
+
+		if fnErr == nil {
+			return err
+		}
+	}
+
+	return fnErr
+}
+
+// setConfig sets the run-time parameter.  If local is set, the setting only lasts until the end
+// of the current transaction, as with SET LOCAL.
+func setConfig(ctx context.Context, conn Conn, name, value string, local bool) error {
+	_, err := conn.Exec(ctx, "SELECT set_config($1, $2, $3)", name, value, local)
+	return err
+}
+
+// DefaultTenantSetting is the run-time parameter AsTenant sets to the tenant ID, unless changed
+// with DB.SetTenantSetting.  Row-level security policies read it with current_setting:
+//
+//	CREATE POLICY tenant_isolation ON invoices
+//	    USING (tenant_id = current_setting('app.tenant_id')::bigint);
+const DefaultTenantSetting = "app.tenant_id"
+
+// SetLocal sets a run-time parameter, such as a custom setting read by a row-level security
+// policy, until the end of the transaction, as with SET LOCAL.  Strings and byte slices are used
+// as is; other values are formatted with fmt.Sprint, so integers and types with a String method
+// such as uuid.UUID work as expected.  A nil value sets the parameter to an empty string.
+func (tx *Tx) SetLocal(ctx context.Context, name string, value interface{}) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return setConfig(ctx, tx, name, settingValue(value), true)
+}
+
+// settingValue formats the value of a run-time parameter.
+func settingValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// SetTenantSetting changes the run-time parameter AsTenant sets to the tenant ID.  Defaults to
+// DefaultTenantSetting.
+func (db *DB) SetTenantSetting(name string) {
+	db.tenantSetting = name
+}
+
+// AsTenant begins a transaction, sets the tenant setting to the tenant ID with SET LOCAL
+// semantics, and runs fn in the transaction, for multi-tenant applications isolating tenants
+// with row-level security.  The transaction commits if fn returns nil, and rolls back otherwise;
+// either way the setting ends with the transaction, so the connection goes back to the pool
+// without it.  See DefaultTenantSetting.
+//
+// Note that row-level security policies don't apply to superusers, to roles with BYPASSRLS, or to
+// a table's owner unless the table is set to FORCE ROW LEVEL SECURITY.
+func (db *DB) AsTenant(ctx context.Context, tenantID interface{}, fn func(tx Conn) error) error {
+	setting := db.tenantSetting
+	if setting == "" {
+		setting = DefaultTenantSetting
+	}
+
+	return db.BeginFunc(ctx, func(tx Conn) error {
+		if err := tx.(*Tx).SetLocal(ctx, setting, tenantID); err != nil {
+			return err
+		}
+
+		return fn(tx)
+	})
+}
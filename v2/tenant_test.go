@@ -0,0 +1,98 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// settingsConn reports the current search_path and records the set_config calls.
+type settingsConn struct {
+	scriptConn
+
+	searchPath string
+}
+
+func (c *settingsConn) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return settingRow(c.searchPath)
+}
+
+type settingRow string
+
+func (r settingRow) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = string(r)
+	return nil
+}
+
+func TestWithSchema(t *testing.T) {
+	conn := &settingsConn{searchPath: `"$user", public`}
+	failed := errors.New("failed")
+
+	err := withSchema(context.Background(), conn, "tenant_42", true, func(c Conn) error {
+		if c != conn {
+			t.Error("Expected fn to run on the connection")
+		}
+		return failed
+	})
+
+	if err != failed {
+		t.Errorf("Expected fn's error; was %v", err)
+	}
+
+	expected := [][]interface{}{
+		{"search_path", `"tenant_42", "$user", public`, true},
+		{"search_path", `"$user", public`, true},
+	}
+
+	if !reflect.DeepEqual(conn.args, expected) {
+		t.Errorf("Expected the search_path to be set and restored; was %v", conn.args)
+	}
+}
+
+func TestWithSchemaUnsupported(t *testing.T) {
+	err := WithSchema(context.Background(), &Pipeline{}, "tenant_42", func(Conn) error {
+		t.Error("Expected fn not to run")
+		return nil
+	})
+
+	if !errors.Is(err, ErrSessionState) {
+		t.Errorf("Expected ErrSessionState; was %v", err)
+	}
+}
+
+// settingsTx records the statements run in the transaction.
+type settingsTx struct {
+	fakeTx
+
+	args [][]interface{}
+}
+
+func (tx *settingsTx) Exec(_ context.Context, _ string, args ...interface{}) (pgconn.CommandTag, error) {
+	tx.args = append(tx.args, args)
+	return pgconn.CommandTag{}, nil
+}
+
+func TestSetLocal(t *testing.T) {
+	pgxTx := &settingsTx{}
+	tx := &Tx{Tx: pgxTx}
+
+	for _, value := range []interface{}{int64(42), "acme", nil} {
+		if err := tx.SetLocal(context.Background(), "app.tenant_id", value); err != nil {
+			t.Fatalf("Unable to set app.tenant_id: %s", err)
+		}
+	}
+
+	expected := [][]interface{}{
+		{"app.tenant_id", "42", true},
+		{"app.tenant_id", "acme", true},
+		{"app.tenant_id", "", true},
+	}
+
+	if !reflect.DeepEqual(pgxTx.args, expected) {
+		t.Errorf("Expected %v; was %v", expected, pgxTx.args)
+	}
+}
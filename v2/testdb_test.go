@@ -0,0 +1,31 @@
+package hermes_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+// testURI is the database the tests that need PostgreSQL run against.
+const testURI = "postgres://localhost/hermes_test?sslmode=disable"
+
+// connect opens the test database, skipping the test if it isn't available.  The database is
+// shut down when the test completes.
+func connect(t *testing.T) *hermes.DB {
+	t.Helper()
+
+	db, err := hermes.Connect(testURI)
+	if err != nil {
+		t.Skipf("Unable to connect to database: %s", err)
+	}
+
+	if err := db.Ping(context.Background()); err != nil {
+		db.Shutdown()
+		t.Skipf("Unable to connect to database: %s", err)
+	}
+
+	t.Cleanup(db.Shutdown)
+
+	return db
+}
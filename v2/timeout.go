@@ -41,14 +41,28 @@ func (db *DB) WithTimeout(ctx context.Context) (context.Context, context.CancelF
 // If Conn already represents a transaction, pgx will create a savepoint instead.  This is
 // experimental; use at your own risk!
 func (db *DB) BeginWithTimeout(ctx context.Context) (*ContextualTx, error) {
+	if db.shuttingDown() {
+		return nil, ErrShuttingDown
+	}
+
 	ctx, cancel := db.WithTimeout(ctx)
 
-	tx, err := db.Pool.Begin(ctx)
+	if err := db.acquireSlot(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	tx, err := db.Pool.Begin(db.timeAcquire(ctx))
 	if err != nil {
+		db.releaseSlot()
+		cancel()
 		return nil, err
 	}
 
-	return &ContextualTx{tx, ctx, cancel}, nil
+	ctxTx := &ContextualTx{Tx: tx, ctx: ctx, cancel: cancel, db: db, limited: true}
+	db.txBegan(&ctxTx.ended, tx.Conn())
+
+	return ctxTx, nil
 }
 
 // SetTimeout sets the default timeout for a transaction.  If never set, the transaction uses the
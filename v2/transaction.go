@@ -0,0 +1,59 @@
+package hermes
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned by WithTransaction when the transaction's function panics.
+type PanicError struct {
+	// Value is the value the function panicked with.
+	Value interface{}
+
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+// Error describes the panic.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("transaction panicked: %v", e.Value)
+}
+
+// Unwrap returns the value the function panicked with, if it was an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithTransaction begins a transaction on conn, or a savepoint if conn is already a transaction,
+// and runs fn in it.  If fn returns nil, the transaction is committed; if it returns an error or
+// panics, the transaction is rolled back.  A panic is recovered and returned as a *PanicError.
+// Returns fn's error, or the error committing.
+//
+// Unlike DB.BeginFunc, WithTransaction works with any Conn, so functions can accept a Conn and
+// run their statements together whether or not they're called in a transaction.
+func WithTransaction(ctx context.Context, conn Conn, fn func(tx Conn) error) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback(ctx)
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,74 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithTransaction(t *testing.T) {
+	parent := &Tx{Tx: &fakeTx{}}
+	ctx := context.Background()
+
+	var committed *fakeTx
+	err := WithTransaction(ctx, parent, func(tx Conn) error {
+		committed = tx.(*Tx).Tx.(*fakeTx)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Unable to run the transaction: %s", err)
+	}
+
+	if committed.committed != 1 || committed.rolledBack != 0 {
+		t.Errorf("Expected the transaction to commit; was %d commits and %d rollbacks", committed.committed, committed.rolledBack)
+	}
+
+	failed := errors.New("failed")
+
+	var rolledBack *fakeTx
+	err = WithTransaction(ctx, parent, func(tx Conn) error {
+		rolledBack = tx.(*Tx).Tx.(*fakeTx)
+		return failed
+	})
+
+	if err != failed {
+		t.Errorf("Expected the function's error; was %v", err)
+	}
+
+	if rolledBack.committed != 0 || rolledBack.rolledBack != 1 {
+		t.Errorf("Expected the transaction to roll back; was %d commits and %d rollbacks", rolledBack.committed, rolledBack.rolledBack)
+	}
+}
+
+func TestWithTransactionPanic(t *testing.T) {
+	parent := &Tx{Tx: &fakeTx{}}
+	failed := errors.New("failed")
+
+	var panicked *fakeTx
+	err := WithTransaction(context.Background(), parent, func(tx Conn) error {
+		panicked = tx.(*Tx).Tx.(*fakeTx)
+		panic(failed)
+	})
+
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != failed || len(pe.Stack) == 0 {
+		t.Fatalf("Expected a PanicError with a stack trace; was %v", err)
+	}
+
+	if !errors.Is(err, failed) {
+		t.Error("Expected the PanicError to unwrap to the panic's error")
+	}
+
+	if panicked.rolledBack != 1 {
+		t.Errorf("Expected the transaction to roll back; was %d rollbacks", panicked.rolledBack)
+	}
+
+	err = WithTransaction(context.Background(), parent, func(tx Conn) error {
+		panic("oops")
+	})
+
+	if err == nil || err.Error() != "transaction panicked: oops" || errors.Unwrap(err) != nil {
+		t.Errorf("Expected a panic error; was %v", err)
+	}
+}
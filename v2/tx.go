@@ -2,6 +2,7 @@ package hermes
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -11,6 +12,27 @@ import (
 type Tx struct {
 	pgx.Tx
 	defaultTimeout time.Duration
+
+	// db is the database pool the root transaction was started on; nil for pseudo nested
+	// transactions, which don't count against the DB's open transactions.
+	db    *DB
+	ended int32
+
+	// limited transactions hold one of the DB's concurrency slots until they end
+	limited bool
+
+	// parent is the enclosing transaction of a pseudo nested transaction
+	parent *Tx
+
+	// notifications queued by NotifyOnCommit
+	notifications []pendingNotification
+}
+
+// txPool recycles the Tx wrappers of transactions run with BeginFunc.
+var txPool = sync.Pool{
+	New: func() interface{} {
+		return new(Tx)
+	},
 }
 
 // Begin starts a pseudo nested transaction.
@@ -24,7 +46,61 @@ func (tx *Tx) Begin(ctx context.Context) (Conn, error) {
 		return nil, err
 	}
 
-	return &Tx{newTx, tx.defaultTimeout}, nil
+	return &Tx{Tx: newTx, defaultTimeout: tx.defaultTimeout, parent: tx}, nil
+}
+
+// Commit the transaction.  If the transaction is a psuedo-transaction, i.e. a savepoint, releases
+// the savepoint.  Otherwise commits the transaction.
+func (tx *Tx) Commit(ctx context.Context) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	defer func() {
+		tx.db.txEnded(&tx.ended, tx.limited, err == nil)
+	}()
+
+	// Notifications from a savepoint are held for the enclosing transaction's commit
+	if tx.parent != nil {
+		if err := tx.Tx.Commit(ctx); err != nil {
+			return err
+		}
+
+		tx.parent.notifications = append(tx.parent.notifications, tx.notifications...)
+		tx.notifications = nil
+
+		return nil
+	}
+
+	started := time.Now()
+
+	err = tx.sendNotifications(ctx)
+	if err != nil {
+		_ = tx.Tx.Rollback(ctx)
+	} else {
+		err = tx.Tx.Commit(ctx)
+	}
+
+	tx.db.metrics().ObserveTxCommit(time.Since(started), err)
+
+	if err == nil && tx.db != nil {
+		tx.db.recordWrite(ctx)
+	}
+
+	return err
+}
+
+// Rollback the transaction, or roll back to the savepoint if this is a pseudo nested
+// transaction.
+func (tx *Tx) Rollback(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	defer tx.db.txEnded(&tx.ended, tx.limited, false)
+
+	tx.notifications = nil
+	return tx.Tx.Rollback(ctx)
 }
 
 // Close rolls back the transaction if this is a real transaction or rolls back to the
@@ -36,9 +112,44 @@ func (tx *Tx) Begin(ctx context.Context) (Conn, error) {
 //
 // Any other failure of a real transaction will result in the connection being closed.
 func (tx *Tx) Close(ctx context.Context) error {
+	return tx.Rollback(ctx)
+}
+
+// BeginFunc starts a pseudo nested transaction and calls fn with it.  If fn returns nil, the
+// savepoint is released; otherwise, it's rolled back.  See DB.BeginFunc.
+func (tx *Tx) BeginFunc(ctx context.Context, fn func(tx Conn) error) error {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	return tx.Tx.Rollback(ctx)
+	newTx, err := tx.Tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	nested := txPool.Get().(*Tx)
+	nested.Tx, nested.defaultTimeout, nested.parent = newTx, tx.defaultTimeout, tx
+
+	return nested.run(ctx, fn)
+}
+
+// run calls fn with the transaction, committing it if fn returns nil and rolling it back
+// otherwise, then recycles the transaction's wrapper.
+func (tx *Tx) run(ctx context.Context, fn func(tx Conn) error) error {
+	defer tx.recycle()
+
+	// Does nothing once the transaction commits
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// recycle clears the wrapper and returns it to the pool.
+func (tx *Tx) recycle() {
+	*tx = Tx{}
+	txPool.Put(tx)
 }
@@ -0,0 +1,121 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeTx counts the savepoints started, released, and rolled back.
+type fakeTx struct {
+	pgx.Tx
+
+	begun      int
+	committed  int
+	rolledBack int
+	closed     bool
+}
+
+func (tx *fakeTx) Begin(context.Context) (pgx.Tx, error) {
+	tx.begun++
+	return &fakeTx{}, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.committed++
+	tx.closed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+	tx.rolledBack++
+	tx.closed = true
+	return nil
+}
+
+func TestTxBeginFunc(t *testing.T) {
+	tx := &Tx{Tx: &fakeTx{}}
+	ctx := context.Background()
+
+	var nested *fakeTx
+	err := tx.BeginFunc(ctx, func(conn Conn) error {
+		nested = conn.(*Tx).Tx.(*fakeTx)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unable to run the nested transaction: %s", err)
+	}
+
+	if nested.committed != 1 || nested.rolledBack != 0 {
+		t.Errorf("Expected the savepoint to be released; was %d commits, %d rollbacks",
+			nested.committed, nested.rolledBack)
+	}
+
+	failed := errors.New("failed")
+	err = tx.BeginFunc(ctx, func(conn Conn) error {
+		nested = conn.(*Tx).Tx.(*fakeTx)
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("Expected fn's error; was %v", err)
+	}
+
+	if nested.committed != 0 || nested.rolledBack != 1 {
+		t.Errorf("Expected the savepoint to be rolled back; was %d commits, %d rollbacks",
+			nested.committed, nested.rolledBack)
+	}
+}
+
+func TestTxBeginFuncNotifications(t *testing.T) {
+	tx := &Tx{Tx: &fakeTx{}}
+
+	err := tx.BeginFunc(context.Background(), func(conn Conn) error {
+		return conn.(*Tx).NotifyOnCommit("events", "created")
+	})
+	if err != nil {
+		t.Fatalf("Unable to run the nested transaction: %s", err)
+	}
+
+	if len(tx.notifications) != 1 {
+		t.Errorf("Expected the notification to be held for the enclosing transaction; was %d",
+			len(tx.notifications))
+	}
+}
+
+func BenchmarkTxBegin(b *testing.B) {
+	tx := &Tx{Tx: &fakeTx{}}
+	ctx := context.Background()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		nested, err := tx.Begin(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if err := nested.Commit(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTxBeginFunc(b *testing.B) {
+	tx := &Tx{Tx: &fakeTx{}}
+	ctx := context.Background()
+	fn := func(Conn) error { return nil }
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := tx.BeginFunc(ctx, fn); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
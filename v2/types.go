@@ -0,0 +1,352 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrUnknownType is returned when connecting if a registered data type doesn't exist in the
+// database.
+var ErrUnknownType = errors.New("unknown data type")
+
+var dataTypes []dataType
+var typeHooks []func(m *pgtype.Map)
+var dtMutex sync.RWMutex
+
+// extensionTypes are registered on every connection, ahead of the registered data types, if
+// they're installed in the database.  Without them, columns of these types fail to scan with
+// unknown OID errors.
+var extensionTypes = []dataType{
+	{name: "citext", codec: &pgtype.TextCodec{}, optional: true},
+	{name: "ltree", codec: ltreeCodec{}, values: []interface{}{Ltree("")}, optional: true},
+	{name: "lquery", codec: ltreeCodec{}, values: []interface{}{Lquery("")}, optional: true},
+}
+
+// dataType is a custom PostgreSQL data type registered on every connection.
+type dataType struct {
+	// name of the PostgreSQL type, optionally qualified with its schema
+	name string
+
+	// codec encodes and decodes the type; if nil, the codec is determined from the type's
+	// definition in the database
+	codec pgtype.Codec
+
+	// wrap, if set, adapts the codec before it's registered, e.g. to map a composite type to a Go
+	// struct
+	wrap func(codec pgtype.Codec) (pgtype.Codec, error)
+
+	// values are Go values encoded as this type by default
+	values []interface{}
+
+	// optional types, such as those from extensions, are skipped if they don't exist
+	optional bool
+}
+
+// Register a custom PostgreSQL data type, by name, to be associated with every connection, along
+// with the codec used to encode and decode it.  If codec is nil, the codec is chosen from the
+// type's definition in the database; this supports enum, composite, domain, and range types.  The
+// type's array type is registered as well.
+//
+// The types' OIDs are looked up the first time the pool makes a connection, then reused for every
+// connection after that.  Types are registered in the order Register is called, so register types
+// before any composite types that use them.  Best to call this before calling Connect.
+func Register(name string, codec pgtype.Codec) {
+	register(dataType{name: name, codec: codec})
+}
+
+// RegisterType adds a function that registers types directly in each connection's type map, for
+// codecs that come with their own registration, such as a UUID library's or a generated enum's:
+//
+//	hermes.RegisterType(pgxuuid.Register)
+//
+// The functions are called on every new connection in the pool, in the order RegisterType is
+// called, after the types added with Register, so they may override them.  Best to call this
+// before calling Connect.
+func RegisterType(fn func(m *pgtype.Map)) {
+	dtMutex.Lock()
+	defer dtMutex.Unlock()
+
+	typeHooks = append(typeHooks, fn)
+}
+
+// register adds the data type to the list of types registered on every connection.
+func register(dt dataType) {
+	dtMutex.Lock()
+	defer dtMutex.Unlock()
+
+	dataTypes = append(dataTypes, dt)
+}
+
+// registered returns a copy of the registered data types.
+func registered() []dataType {
+	dtMutex.RLock()
+	defer dtMutex.RUnlock()
+
+	types := make([]dataType, len(dataTypes))
+	copy(types, dataTypes)
+
+	return types
+}
+
+// registeredHooks returns a copy of the functions added with RegisterType.
+func registeredHooks() []func(m *pgtype.Map) {
+	dtMutex.RLock()
+	defer dtMutex.RUnlock()
+
+	hooks := make([]func(m *pgtype.Map), len(typeHooks))
+	copy(hooks, typeHooks)
+
+	return hooks
+}
+
+// typeInfo describes a registered data type, as defined in the database.
+type typeInfo struct {
+	oid      uint32
+	arrayOID uint32
+
+	// kind is the pg_type.typtype: b(ase), c(omposite), d(omain), e(num), r(ange), m(ultirange)
+	kind string
+
+	// elemOID is a domain's base type or a range's subtype
+	elemOID uint32
+
+	// fields of a composite type
+	fields []typeField
+}
+
+// typeField is an attribute of a composite type.
+type typeField struct {
+	name string
+	oid  uint32
+}
+
+// typeRegistry registers the custom data types on each of a pool's connections, caching the
+// types' definitions after the first connection.
+type typeRegistry struct {
+	mutex         sync.Mutex
+	types         map[string]*typeInfo
+	domains       []domainInfo
+	domainsLoaded bool
+}
+
+func newTypeRegistry() *typeRegistry {
+	return &typeRegistry{types: make(map[string]*typeInfo)}
+}
+
+// afterConnect returns an AfterConnect function that registers the custom data types before
+// calling next, if any.
+func (r *typeRegistry) afterConnect(next func(context.Context, *pgx.Conn) error) func(context.Context, *pgx.Conn) error {
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		if err := r.register(ctx, conn); err != nil {
+			return err
+		}
+
+		if next != nil {
+			return next(ctx, conn)
+		}
+
+		return nil
+	}
+}
+
+// register the extension and custom data types in the connection's type map, along with support
+// for Null, Range, and, on PostgreSQL 14 or later, Multirange, then calls the functions added with
+// RegisterType.
+func (r *typeRegistry) register(ctx context.Context, conn *pgx.Conn) error {
+	m := conn.TypeMap()
+	registerNull(m)
+	registerRanges(m)
+	if serverVersion(conn.PgConn()) >= multirangeVersion {
+		registerMultiranges(m)
+	}
+
+	types := append(append([]dataType{}, extensionTypes...), registered()...)
+
+	for _, dt := range types {
+		info, err := r.lookup(ctx, conn, dt.name)
+		if err != nil {
+			return err
+		}
+
+		if info == nil {
+			if dt.optional {
+				continue
+			}
+			return fmt.Errorf("%w: %s", ErrUnknownType, dt.name)
+		}
+
+		codec := dt.codec
+		if codec == nil {
+			if codec, err = info.codec(ctx, conn, dt.name); err != nil {
+				return fmt.Errorf("unable to register %s: %w", dt.name, err)
+			}
+		}
+
+		if dt.wrap != nil {
+			if codec, err = dt.wrap(codec); err != nil {
+				return fmt.Errorf("unable to register %s: %w", dt.name, err)
+			}
+		}
+
+		registerType(m, dt, codec, info.oid, info.arrayOID)
+	}
+
+	for _, hook := range registeredHooks() {
+		hook(m)
+	}
+
+	if useDomains() {
+		return r.registerDomains(ctx, conn)
+	}
+
+	return nil
+}
+
+// registerType adds the data type and its array type, if any, to the type map, and maps its Go
+// values to it.
+func registerType(m *pgtype.Map, dt dataType, codec pgtype.Codec, oid, arrayOID uint32) {
+	t := &pgtype.Type{Name: dt.name, OID: oid, Codec: codec}
+	m.RegisterType(t)
+
+	if arrayOID != 0 {
+		m.RegisterType(&pgtype.Type{Name: arrayName(dt.name), OID: arrayOID,
+			Codec: &pgtype.ArrayCodec{ElementType: t}})
+	}
+
+	for _, value := range dt.values {
+		m.RegisterDefaultPgType(value, dt.name)
+
+		if arrayOID != 0 {
+			slice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(value)), 0, 0).Interface()
+			m.RegisterDefaultPgType(slice, arrayName(dt.name))
+		}
+	}
+}
+
+// lookup returns the definition of the named type, loading it from the database the first time.
+// Returns nil if the type doesn't exist.
+func (r *typeRegistry) lookup(ctx context.Context, conn *pgx.Conn, name string) (*typeInfo, error) {
+	r.mutex.Lock()
+	info, ok := r.types[name]
+	r.mutex.Unlock()
+
+	if ok {
+		return info, nil
+	}
+
+	info, err := loadTypeInfo(ctx, conn, name)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	r.types[name] = info
+	r.mutex.Unlock()
+
+	return info, nil
+}
+
+// loadTypeInfo queries the database for the definition of the named type.  Returns nil if the
+// type doesn't exist.
+func loadTypeInfo(ctx context.Context, conn *pgx.Conn, name string) (*typeInfo, error) {
+	var info typeInfo
+	var relID uint32
+
+	row := conn.QueryRow(ctx, `
+		SELECT t.oid, t.typarray, t.typtype::text, coalesce(r.rngsubtype, t.typbasetype), t.typrelid
+		FROM pg_type t
+		LEFT JOIN pg_range r ON r.rngtypid = t.oid
+		WHERE t.oid = to_regtype($1)`, name)
+	if err := row.Scan(&info.oid, &info.arrayOID, &info.kind, &info.elemOID, &relID); err != nil {
+		if NoRows(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if info.kind == "c" {
+		rows, err := conn.Query(ctx, `
+			SELECT attname::text, atttypid
+			FROM pg_attribute
+			WHERE attrelid = $1 AND attnum > 0 AND NOT attisdropped
+			ORDER BY attnum`, relID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var field typeField
+			if err := rows.Scan(&field.name, &field.oid); err != nil {
+				return nil, err
+			}
+			info.fields = append(info.fields, field)
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &info, nil
+}
+
+// codec builds the codec for a type from its definition, using the types already registered on
+// the connection.
+func (info *typeInfo) codec(ctx context.Context, conn *pgx.Conn, name string) (pgtype.Codec, error) {
+	m := conn.TypeMap()
+
+	switch info.kind {
+	case "e":
+		return &pgtype.EnumCodec{}, nil
+
+	case "d":
+		base, ok := m.TypeForOID(info.elemOID)
+		if !ok {
+			return nil, fmt.Errorf("%w: domain base type %d", ErrUnknownType, info.elemOID)
+		}
+		return base.Codec, nil
+
+	case "r":
+		elem, ok := m.TypeForOID(info.elemOID)
+		if !ok {
+			return nil, fmt.Errorf("%w: range subtype %d", ErrUnknownType, info.elemOID)
+		}
+		return &pgtype.RangeCodec{ElementType: elem}, nil
+
+	case "c":
+		fields := make([]pgtype.CompositeCodecField, len(info.fields))
+		for i, field := range info.fields {
+			t, ok := m.TypeForOID(field.oid)
+			if !ok {
+				return nil, fmt.Errorf("%w: type %d of field %s", ErrUnknownType, field.oid, field.name)
+			}
+			fields[i] = pgtype.CompositeCodecField{Name: field.name, Type: t}
+		}
+		return &pgtype.CompositeCodec{Fields: fields}, nil
+	}
+
+	// Let pgx work out anything else
+	t, err := conn.LoadType(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Codec, nil
+}
+
+// arrayName returns the name of the array type for the named type, e.g. "_order_status" or
+// "app._order_status".
+func arrayName(name string) string {
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		return name[:idx+1] + "_" + name[idx+1:]
+	}
+	return "_" + name
+}
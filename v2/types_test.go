@@ -0,0 +1,150 @@
+package hermes
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// resetTypes clears the registered data types for the test, restoring them when the test ends.
+func resetTypes(t *testing.T) {
+	dtMutex.Lock()
+	saved, savedHooks := dataTypes, typeHooks
+	dataTypes, typeHooks = nil, nil
+	dtMutex.Unlock()
+
+	t.Cleanup(func() {
+		dtMutex.Lock()
+		dataTypes, typeHooks = saved, savedHooks
+		dtMutex.Unlock()
+	})
+}
+
+func TestArrayName(t *testing.T) {
+	expected := map[string]string{
+		"order_status":     "_order_status",
+		"app.order_status": "app._order_status",
+	}
+
+	for name, array := range expected {
+		if check := arrayName(name); check != array {
+			t.Errorf("Expected array type of %s to be %s; was %s", name, array, check)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	resetTypes(t)
+
+	Register("order_status", &pgtype.EnumCodec{})
+	Register("address", nil)
+
+	types := registered()
+	if len(types) != 2 {
+		t.Fatalf("Expected 2 registered types; was %d", len(types))
+	}
+
+	if types[0].name != "order_status" || types[1].name != "address" {
+		t.Errorf("Expected types in registration order; was %s, %s", types[0].name, types[1].name)
+	}
+
+	if types[1].codec != nil {
+		t.Error("Expected nil codec to be loaded from the database")
+	}
+}
+
+func TestRegisterType(t *testing.T) {
+	resetTypes(t)
+
+	var order []string
+	RegisterType(func(m *pgtype.Map) {
+		order = append(order, "first")
+		m.RegisterType(&pgtype.Type{Name: "widget", OID: 90001, Codec: &pgtype.TextCodec{}})
+	})
+	RegisterType(func(m *pgtype.Map) {
+		order = append(order, "second")
+	})
+
+	m := pgtype.NewMap()
+	for _, hook := range registeredHooks() {
+		hook(m)
+	}
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("Expected the functions in registration order; was %v", order)
+	}
+
+	if typ, ok := m.TypeForName("widget"); !ok || typ.OID != 90001 {
+		t.Error("Expected the function to register the type in the map")
+	}
+}
+
+func TestRegisterEnum(t *testing.T) {
+	type OrderStatus string
+
+	resetTypes(t)
+
+	RegisterEnum("order_status", OrderStatus(""))
+
+	types := registered()
+	if len(types) != 1 {
+		t.Fatalf("Expected 1 registered type; was %d", len(types))
+	}
+
+	if _, ok := types[0].codec.(*pgtype.EnumCodec); !ok {
+		t.Errorf("Expected an enum codec; was %T", types[0].codec)
+	}
+
+	if len(types[0].values) != 1 || types[0].values[0] != OrderStatus("") {
+		t.Errorf("Expected OrderStatus to be mapped to the enum; was %v", types[0].values)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a non-string enum to panic")
+		}
+	}()
+
+	RegisterEnum("order_status", 12)
+}
+
+func TestCitext(t *testing.T) {
+	citext := extensionTypes[0]
+	if citext.name != "citext" || !citext.optional {
+		t.Fatalf("Expected optional citext extension type; was %#v", citext)
+	}
+
+	m := pgtype.NewMap()
+	registerType(m, citext, citext.codec, 100001, 100002)
+
+	var s string
+	if err := m.Scan(100001, pgtype.BinaryFormatCode, []byte("Hello"), &s); err != nil {
+		t.Fatalf("Unable to scan citext: %s", err)
+	}
+
+	if s != "Hello" {
+		t.Errorf("Expected Hello; was %s", s)
+	}
+
+	var values []string
+	if err := m.Scan(100002, pgtype.TextFormatCode, []byte("{Hello,World}"), &values); err != nil {
+		t.Fatalf("Unable to scan citext array: %s", err)
+	}
+
+	if len(values) != 2 || values[1] != "World" {
+		t.Errorf("Expected [Hello World]; was %v", values)
+	}
+
+	buf, err := m.Encode(100002, pgtype.BinaryFormatCode, []string{"a", "b"}, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode citext array: %s", err)
+	}
+
+	if err := m.Scan(100002, pgtype.BinaryFormatCode, buf, &values); err != nil {
+		t.Fatalf("Unable to scan citext array: %s", err)
+	}
+
+	if len(values) != 2 || values[0] != "a" {
+		t.Errorf("Expected [a b]; was %v", values)
+	}
+}
@@ -0,0 +1,85 @@
+package hermes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbowman/hermes-pgx/v2"
+)
+
+func TestBeginTxUnavailable(t *testing.T) {
+	db := hermes.NewUnreachableDB(t)
+
+	if _, err := db.BeginTx(context.Background(), hermes.TxOptions{Isolation: pgx.Serializable}); err == nil {
+		t.Error("Expected an error without a database")
+	}
+
+	if db.OpenTransactions() != 0 {
+		t.Errorf("Expected no open transactions; was %d", db.OpenTransactions())
+	}
+}
+
+func TestHealthyUnavailable(t *testing.T) {
+	ctx := context.Background()
+
+	db := hermes.NewUnreachableDB(t)
+	db.SetHealthCheck(hermes.HealthCheck{Timeout: 500 * time.Millisecond, LagQuery: hermes.ReplicaLagQuery})
+
+	report := db.HealthReport(ctx)
+	if report.Status != hermes.HealthDown {
+		t.Errorf("Expected down; was %s", report.Status)
+	}
+
+	if report.MaxConns == 0 {
+		t.Error("Expected the pool's stats in the report")
+	}
+
+	if err := db.Healthy(ctx); !errors.Is(err, hermes.ErrDatabaseDown) {
+		t.Errorf("Expected ErrDatabaseDown; was %v", err)
+	}
+}
+
+func TestDBListenUnavailable(t *testing.T) {
+	db := hermes.NewUnreachableDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notifications, err := db.Listen(ctx, "users_changed")
+	if err == nil || errors.Is(err, hermes.ErrNotCancelable) || notifications != nil {
+		t.Errorf("Expected an error listening without a database; was %v", err)
+	}
+}
+
+func TestDBListenNotCancelable(t *testing.T) {
+	db := hermes.NewUnreachableDB(t)
+
+	// The listener would never be closed
+	if _, err := db.Listen(context.Background(), "users_changed"); !errors.Is(err, hermes.ErrNotCancelable) {
+		t.Errorf("Expected ErrNotCancelable; was %v", err)
+	}
+
+	if _, err := db.Listen(nil, "users_changed"); !errors.Is(err, hermes.ErrNotCancelable) {
+		t.Errorf("Expected ErrNotCancelable for a nil context; was %v", err)
+	}
+}
+
+func TestLockManagerUnavailable(t *testing.T) {
+	db := hermes.NewUnreachableDB(t)
+	m := hermes.NewLockManager(db)
+
+	if _, err := m.Acquire(context.Background(), 1); err == nil {
+		t.Error("Expected an error without a database")
+	}
+
+	if _, err := m.TryAcquire(context.Background(), 1); err == nil {
+		t.Error("Expected an error without a database")
+	}
+
+	if len(m.Leases()) != 0 || db.HeldLocks() != 0 {
+		t.Errorf("Expected no leases; was %d leases and %d locks", len(m.Leases()), db.HeldLocks())
+	}
+}
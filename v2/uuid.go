@@ -0,0 +1,102 @@
+package hermes
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// UseGoogleUUID registers a codec on every connection that encodes and scans
+// github.com/google/uuid UUID and NullUUID values as PostgreSQL uuids, including arrays of them,
+// and returns uuid.UUID values for uuid columns scanned into interface{}.  Best to call this
+// before calling Connect.
+func UseGoogleUUID() {
+	register(dataType{
+		name:   "uuid",
+		codec:  googleUUIDCodec{},
+		values: []interface{}{uuid.UUID{}, uuid.NullUUID{}},
+	})
+}
+
+// googleUUIDCodec adapts pgx's uuid codec to github.com/google/uuid types.
+type googleUUIDCodec struct {
+	pgtype.UUIDCodec
+}
+
+func (c googleUUIDCodec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value interface{}) pgtype.EncodePlan {
+	switch value.(type) {
+	case uuid.UUID, uuid.NullUUID:
+		next := c.UUIDCodec.PlanEncode(m, oid, format, pgtype.UUID{})
+		if next == nil {
+			return nil
+		}
+		return &googleUUIDEncodePlan{next: next}
+	}
+
+	return c.UUIDCodec.PlanEncode(m, oid, format, value)
+}
+
+func (c googleUUIDCodec) PlanScan(m *pgtype.Map, oid uint32, format int16, target interface{}) pgtype.ScanPlan {
+	switch target.(type) {
+	case *uuid.UUID, *uuid.NullUUID:
+		next := c.UUIDCodec.PlanScan(m, oid, format, &pgtype.UUID{})
+		if next == nil {
+			return nil
+		}
+		return &googleUUIDScanPlan{next: next}
+	}
+
+	return c.UUIDCodec.PlanScan(m, oid, format, target)
+}
+
+func (c googleUUIDCodec) DecodeValue(m *pgtype.Map, oid uint32, format int16, src []byte) (interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	var id uuid.UUID
+	if err := m.PlanScan(oid, format, &id).Scan(src, &id); err != nil {
+		return nil, err
+	}
+
+	return id, nil
+}
+
+type googleUUIDEncodePlan struct {
+	next pgtype.EncodePlan
+}
+
+func (plan *googleUUIDEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	switch v := value.(type) {
+	case uuid.UUID:
+		return plan.next.Encode(pgtype.UUID{Bytes: v, Valid: true}, buf)
+	case uuid.NullUUID:
+		return plan.next.Encode(pgtype.UUID{Bytes: v.UUID, Valid: v.Valid}, buf)
+	}
+
+	return nil, fmt.Errorf("cannot encode %T as uuid", value)
+}
+
+type googleUUIDScanPlan struct {
+	next pgtype.ScanPlan
+}
+
+func (plan *googleUUIDScanPlan) Scan(src []byte, target interface{}) error {
+	var id pgtype.UUID
+	if err := plan.next.Scan(src, &id); err != nil {
+		return err
+	}
+
+	switch t := target.(type) {
+	case *uuid.UUID:
+		if !id.Valid {
+			return fmt.Errorf("cannot scan NULL into %T", target)
+		}
+		*t = id.Bytes
+	case *uuid.NullUUID:
+		*t = uuid.NullUUID{UUID: id.Bytes, Valid: id.Valid}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package hermes
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestGoogleUUID(t *testing.T) {
+	resetTypes(t)
+	UseGoogleUUID()
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], googleUUIDCodec{}, pgtype.UUIDOID, pgtype.UUIDArrayOID)
+
+	id := uuid.New()
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(pgtype.UUIDOID, format, id, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode UUID in format %d: %s", format, err)
+		}
+
+		var check uuid.UUID
+		if err := m.Scan(pgtype.UUIDOID, format, buf, &check); err != nil {
+			t.Fatalf("Unable to scan UUID in format %d: %s", format, err)
+		}
+
+		if check != id {
+			t.Errorf("Expected %s; was %s", id, check)
+		}
+
+		dt, ok := m.TypeForOID(pgtype.UUIDOID)
+		if !ok {
+			t.Fatal("Expected uuid type to be registered")
+		}
+
+		decoded, _ := dt.Codec.DecodeValue(m, pgtype.UUIDOID, format, buf)
+		if decoded != id {
+			t.Errorf("Expected decoded value to be uuid.UUID %s; was %#v", id, decoded)
+		}
+	}
+}
+
+func TestGoogleNullUUID(t *testing.T) {
+	resetTypes(t)
+	UseGoogleUUID()
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], googleUUIDCodec{}, pgtype.UUIDOID, pgtype.UUIDArrayOID)
+
+	buf, err := m.Encode(pgtype.UUIDOID, pgtype.BinaryFormatCode, uuid.NullUUID{}, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode null UUID: %s", err)
+	}
+
+	if buf != nil {
+		t.Error("Expected invalid NullUUID to encode as NULL")
+	}
+
+	check := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	if err := m.Scan(pgtype.UUIDOID, pgtype.BinaryFormatCode, nil, &check); err != nil {
+		t.Fatalf("Unable to scan NULL into NullUUID: %s", err)
+	}
+
+	if check.Valid {
+		t.Error("Expected NULL to scan as invalid NullUUID")
+	}
+
+	var id uuid.UUID
+	if err := m.Scan(pgtype.UUIDOID, pgtype.BinaryFormatCode, nil, &id); err == nil {
+		t.Error("Expected error scanning NULL into uuid.UUID")
+	}
+}
+
+func TestGoogleUUIDArray(t *testing.T) {
+	resetTypes(t)
+	UseGoogleUUID()
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], googleUUIDCodec{}, pgtype.UUIDOID, pgtype.UUIDArrayOID)
+
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(pgtype.UUIDArrayOID, format, ids, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode UUID array in format %d: %s", format, err)
+		}
+
+		var check []uuid.UUID
+		if err := m.Scan(pgtype.UUIDArrayOID, format, buf, &check); err != nil {
+			t.Fatalf("Unable to scan UUID array in format %d: %s", format, err)
+		}
+
+		if len(check) != 2 || check[0] != ids[0] || check[1] != ids[1] {
+			t.Errorf("Expected %v; was %v", ids, check)
+		}
+	}
+
+	if dt, ok := m.TypeForValue([]uuid.UUID{}); !ok || dt.OID != pgtype.UUIDArrayOID {
+		t.Error("Expected []uuid.UUID to map to the uuid array type")
+	}
+}
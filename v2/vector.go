@@ -0,0 +1,200 @@
+package hermes
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrInvalidVector is returned when a pgvector value can't be decoded.
+var ErrInvalidVector = errors.New("invalid vector")
+
+// UseVector registers a codec on every connection for the pgvector extension's vector type,
+// encoding and scanning []float32 values, and arrays of vectors as [][]float32.  Vectors may be
+// copied into tables with CopyFrom, too.  After calling UseVector, []float32 arguments are sent
+// as vectors, rather than real arrays, when PostgreSQL doesn't say which type it expects.  If
+// the extension isn't installed in the database, the type is skipped.  Best to call this before
+// calling Connect.
+func UseVector() {
+	register(dataType{
+		name:     "vector",
+		codec:    vectorCodec{},
+		values:   []interface{}{[]float32{}},
+		optional: true,
+	})
+}
+
+// vectorCodec encodes and decodes pgvector vectors as []float32.  In the binary format, a vector
+// is its dimensions as an int16, an unused int16, then each element as a float32; in the text
+// format, it's the elements in square brackets, e.g. "[1,2.5,3]".
+type vectorCodec struct{}
+
+func (vectorCodec) FormatSupported(format int16) bool {
+	return format == pgtype.BinaryFormatCode || format == pgtype.TextFormatCode
+}
+
+func (vectorCodec) PreferredFormat() int16 {
+	return pgtype.BinaryFormatCode
+}
+
+func (vectorCodec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value interface{}) pgtype.EncodePlan {
+	if _, ok := value.([]float32); !ok {
+		return nil
+	}
+
+	if format == pgtype.TextFormatCode {
+		return vectorTextEncodePlan{}
+	}
+
+	return vectorBinaryEncodePlan{}
+}
+
+func (vectorCodec) PlanScan(m *pgtype.Map, oid uint32, format int16, target interface{}) pgtype.ScanPlan {
+	if _, ok := target.(*[]float32); !ok {
+		return nil
+	}
+
+	return vectorScanPlan{format: format}
+}
+
+func (vectorCodec) DecodeDatabaseSQLValue(m *pgtype.Map, oid uint32, format int16, src []byte) (driver.Value, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	if format == pgtype.TextFormatCode {
+		return string(src), nil
+	}
+
+	v, err := decodeVector(format, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(appendVectorText(nil, v)), nil
+}
+
+func (vectorCodec) DecodeValue(m *pgtype.Map, oid uint32, format int16, src []byte) (interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	return decodeVector(format, src)
+}
+
+type vectorBinaryEncodePlan struct{}
+
+func (vectorBinaryEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	v := value.([]float32)
+	if v == nil {
+		return nil, nil
+	}
+
+	if len(v) > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: %d dimensions", ErrInvalidVector, len(v))
+	}
+
+	buf = append(buf, byte(len(v)>>8), byte(len(v)), 0, 0)
+
+	for _, f := range v {
+		bits := math.Float32bits(f)
+		buf = append(buf, byte(bits>>24), byte(bits>>16), byte(bits>>8), byte(bits))
+	}
+
+	return buf, nil
+}
+
+type vectorTextEncodePlan struct{}
+
+func (vectorTextEncodePlan) Encode(value interface{}, buf []byte) ([]byte, error) {
+	v := value.([]float32)
+	if v == nil {
+		return nil, nil
+	}
+
+	return appendVectorText(buf, v), nil
+}
+
+type vectorScanPlan struct {
+	format int16
+}
+
+func (plan vectorScanPlan) Scan(src []byte, target interface{}) error {
+	dst := target.(*[]float32)
+
+	if src == nil {
+		*dst = nil
+		return nil
+	}
+
+	v, err := decodeVector(plan.format, src)
+	if err != nil {
+		return err
+	}
+
+	*dst = v
+	return nil
+}
+
+// appendVectorText appends the vector in the text format to buf.
+func appendVectorText(buf []byte, v []float32) []byte {
+	buf = append(buf, '[')
+	for i, f := range v {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendFloat(buf, float64(f), 'f', -1, 32)
+	}
+
+	return append(buf, ']')
+}
+
+// decodeVector decodes a vector in the binary or text format.
+func decodeVector(format int16, src []byte) ([]float32, error) {
+	if format == pgtype.TextFormatCode {
+		text := string(src)
+		if len(text) < 2 || text[0] != '[' || text[len(text)-1] != ']' {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVector, text)
+		}
+
+		text = text[1 : len(text)-1]
+		if text == "" {
+			return []float32{}, nil
+		}
+
+		elements := strings.Split(text, ",")
+		v := make([]float32, len(elements))
+
+		for i, element := range elements {
+			f, err := strconv.ParseFloat(strings.TrimSpace(element), 32)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidVector, err)
+			}
+			v[i] = float32(f)
+		}
+
+		return v, nil
+	}
+
+	if len(src) < 4 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidVector, len(src))
+	}
+
+	dims := int(binary.BigEndian.Uint16(src))
+	if len(src) != 4+4*dims {
+		return nil, fmt.Errorf("%w: %d bytes for %d dimensions", ErrInvalidVector, len(src), dims)
+	}
+
+	v := make([]float32, dims)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.BigEndian.Uint32(src[4+4*i:]))
+	}
+
+	return v, nil
+}
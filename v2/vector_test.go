@@ -0,0 +1,110 @@
+package hermes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const vectorOID = 100001
+
+func newVectorMap(t *testing.T) *pgtype.Map {
+	resetTypes(t)
+	UseVector()
+
+	m := pgtype.NewMap()
+	registerType(m, registered()[0], vectorCodec{}, vectorOID, vectorOID+1)
+
+	return m
+}
+
+func TestVector(t *testing.T) {
+	m := newVectorMap(t)
+
+	v := []float32{1, -2.5, 0.125}
+
+	for _, format := range []int16{pgtype.BinaryFormatCode, pgtype.TextFormatCode} {
+		buf, err := m.Encode(vectorOID, format, v, nil)
+		if err != nil {
+			t.Fatalf("Unable to encode vector in format %d: %s", format, err)
+		}
+
+		var check []float32
+		if err := m.Scan(vectorOID, format, buf, &check); err != nil {
+			t.Fatalf("Unable to scan vector in format %d: %s", format, err)
+		}
+
+		if !reflect.DeepEqual(check, v) {
+			t.Errorf("Expected %v; was %v", v, check)
+		}
+	}
+
+	buf, _ := m.Encode(vectorOID, pgtype.TextFormatCode, v, nil)
+	if string(buf) != "[1,-2.5,0.125]" {
+		t.Errorf("Expected [1,-2.5,0.125]; was %s", buf)
+	}
+
+	buf, _ = m.Encode(vectorOID, pgtype.BinaryFormatCode, []float32{1}, nil)
+	if !reflect.DeepEqual(buf, []byte{0, 1, 0, 0, 0x3f, 0x80, 0, 0}) {
+		t.Errorf("Expected pgvector binary format; was %v", buf)
+	}
+
+	var check []float32
+	if err := m.Scan(vectorOID, pgtype.TextFormatCode, []byte("[1, 2]"), &check); err != nil || len(check) != 2 {
+		t.Errorf("Expected [1 2]; was %v (%v)", check, err)
+	}
+
+	if err := m.Scan(vectorOID, pgtype.TextFormatCode, nil, &check); err != nil || check != nil {
+		t.Errorf("Expected NULL to scan as nil; was %v (%v)", check, err)
+	}
+
+	for _, src := range []string{"1,2", "[1,x]"} {
+		if err := m.Scan(vectorOID, pgtype.TextFormatCode, []byte(src), &check); !errors.Is(err, ErrInvalidVector) {
+			t.Errorf("Expected ErrInvalidVector for %q; was %v", src, err)
+		}
+	}
+
+	if err := m.Scan(vectorOID, pgtype.BinaryFormatCode, []byte{0, 2, 0, 0, 1}, &check); !errors.Is(err, ErrInvalidVector) {
+		t.Errorf("Expected ErrInvalidVector for short binary vector; was %v", err)
+	}
+}
+
+func TestVectorArray(t *testing.T) {
+	m := newVectorMap(t)
+
+	if dt, ok := m.TypeForValue([]float32{}); !ok || dt.OID != vectorOID {
+		t.Error("Expected []float32 to map to the vector type")
+	}
+
+	vectors := [][]float32{{1, 2}, {3, 4}}
+
+	dt, ok := m.TypeForValue(vectors)
+	if !ok || dt.OID != vectorOID+1 {
+		t.Fatal("Expected [][]float32 to map to the vector array type")
+	}
+
+	buf, err := m.Encode(dt.OID, pgtype.BinaryFormatCode, vectors, nil)
+	if err != nil {
+		t.Fatalf("Unable to encode vector array: %s", err)
+	}
+
+	var check [][]float32
+	if err := m.Scan(dt.OID, pgtype.BinaryFormatCode, buf, &check); err != nil {
+		t.Fatalf("Unable to scan vector array: %s", err)
+	}
+
+	if !reflect.DeepEqual(check, vectors) {
+		t.Errorf("Expected %v; was %v", vectors, check)
+	}
+
+	decoded, err := dt.Codec.DecodeValue(m, dt.OID, pgtype.TextFormatCode, []byte(`{"[1,2]","[3]"}`))
+	if err != nil {
+		t.Fatalf("Unable to decode vector array: %s", err)
+	}
+
+	if values, ok := decoded.([]interface{}); !ok || len(values) != 2 || !reflect.DeepEqual(values[1], []float32{3}) {
+		t.Errorf("Expected [[1 2] [3]]; was %#v", decoded)
+	}
+}
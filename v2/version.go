@@ -0,0 +1,51 @@
+package hermes
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL server versions, in the server_version_num format, that introduced features Hermes
+// relies on.
+const (
+	// multirangeVersion introduced multirange types
+	multirangeVersion = 140000
+)
+
+// serverVersion returns the version of the PostgreSQL server the connection is connected to, in
+// the server_version_num format, e.g. 140005 for 14.5.  Returns 0 if the version is unknown.
+func serverVersion(conn *pgconn.PgConn) int {
+	return parseServerVersion(conn.ParameterStatus("server_version"))
+}
+
+// parseServerVersion converts a server_version string, such as "14.5", "9.6.24", "15beta1", or
+// "13.8 (Debian 13.8-1.pgdg110+1)", to the server_version_num format.  Returns 0 if the version
+// can't be parsed.
+func parseServerVersion(version string) int {
+	end := strings.IndexFunc(version, func(r rune) bool {
+		return r != '.' && (r < '0' || r > '9')
+	})
+	if end >= 0 {
+		version = version[:end]
+	}
+
+	parts := strings.Split(version, ".")
+
+	var nums [3]int
+	for i := 0; i < len(parts) && i < len(nums); i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0
+		}
+		nums[i] = n
+	}
+
+	// Starting with 10, versions are major.minor
+	if nums[0] >= 10 {
+		return nums[0]*10000 + nums[1]
+	}
+
+	return nums[0]*10000 + nums[1]*100 + nums[2]
+}
@@ -0,0 +1,52 @@
+package hermes
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Conn abstracts the *pgxpool.Pool struct and the pgx.Tx interface into a common interface, so
+// the same function can run a single query outside of a transaction, or be included in a
+// transaction with other function calls.
+//
+// It's also useful for testing, as you can pass a transaction into any database-related function,
+// don't commit, and simply Close() at the end of the test to clean up the database.
+//
+// Go doesn't allow generic methods, so the typed query helpers, Get, Select, and Exec, are
+// functions that take a Conn.
+type Conn interface {
+	// Begin starts a transaction.  If Conn already represents a transaction, pgx will create a
+	// savepoint instead.
+	Begin(ctx context.Context) (Conn, error)
+
+	// Commit the transaction.  Does nothing if Conn is a *DB.  If the transaction is a pseudo
+	// nested transaction, i.e. a savepoint, releases the savepoint.  Otherwise commits the
+	// transaction.
+	Commit(ctx context.Context) error
+
+	// Rollback the transaction.  Does nothing if Conn is a *DB.
+	Rollback(ctx context.Context) error
+
+	// Close rolls back the transaction if it hasn't been committed or rolled back, and does
+	// nothing otherwise, so a defer conn.Close(ctx) is safe even if conn.Commit(ctx) is called
+	// first.  Does nothing if Conn is a *DB.
+	Close(ctx context.Context) error
+
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+
+	// Lock creates a session-wide advisory lock on a *DB, and a transactional advisory lock on a
+	// transaction.  Will block until the lock is available.  Returns an AdvisoryLock, which must
+	// be released when you're done with the lock.
+	Lock(ctx context.Context, key LockKey) (AdvisoryLock, error)
+
+	// TryLock tries to create a session-wide or transactional advisory lock, based on the
+	// connection type.  If the lock is in use, returns ErrLocked.
+	TryLock(ctx context.Context, key LockKey) (AdvisoryLock, error)
+}
@@ -0,0 +1,202 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrLocked returned if you try to acquire an advisory lock and it's already in use.
+var ErrLocked = errors.New("advisory lock already acquired")
+
+// LockKey identifies an advisory lock.  PostgreSQL keys advisory locks either by a single 64-bit
+// integer, see Key, or by a pair of 32-bit integers, see Pair.  The two key spaces don't overlap.
+type LockKey interface {
+	// lockArgs returns the arguments to the advisory lock functions.
+	lockArgs() []any
+}
+
+// Key is an advisory lock key made of a single 64-bit integer.
+type Key struct {
+	ID int64
+}
+
+func (k Key) lockArgs() []any {
+	return []any{k.ID}
+}
+
+// NamedKey returns a Key derived from the name, so services can agree on a lock without
+// coordinating numeric IDs, e.g. NamedKey("billing.invoices").
+func NamedKey(name string) Key {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+
+	return Key{ID: int64(h.Sum64())}
+}
+
+// Pair is an advisory lock key made of two 32-bit integers, typically a class of lock, such as a
+// table's OID, and the ID of a row in it.
+type Pair struct {
+	Class int32
+	ID    int32
+}
+
+func (k Pair) lockArgs() []any {
+	return []any{k.Class, k.ID}
+}
+
+// lockSQL returns the query calling the advisory lock function with the key's arguments.
+func lockSQL(fn string, args []any) string {
+	params := make([]string, len(args))
+	for i := range args {
+		params[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	return "SELECT " + fn + "(" + strings.Join(params, ", ") + ")"
+}
+
+// AdvisoryLock is an advisory lock acquired by Conn.Lock or Conn.TryLock.
+type AdvisoryLock interface {
+	// Release the lock.  Safe to call more than once.
+	Release(ctx context.Context) error
+}
+
+// SessionAdvisoryLock is a session-wide advisory lock.  It holds its connection out of the pool
+// until it's released.
+type SessionAdvisoryLock struct {
+	mutex sync.Mutex
+
+	Key  LockKey
+	conn *pgxpool.Conn
+}
+
+// Release the session-wide advisory lock and return its connection to the pool.  If the unlock
+// fails, the connection is closed instead, which also releases the lock, and the error returned.
+func (lock *SessionAdvisoryLock) Release(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+
+	// The lock was already released
+	if lock.conn == nil {
+		return nil
+	}
+
+	args := lock.Key.lockArgs()
+
+	_, err := lock.conn.Exec(ctx, lockSQL("pg_advisory_unlock", args), args...)
+	if err != nil {
+		_ = lock.conn.Conn().Close(context.Background())
+	}
+
+	lock.conn.Release()
+	lock.conn = nil
+
+	return err
+}
+
+// Lock creates a session-wide advisory lock in the database.  Call Release to release the lock.
+func (db *DB) Lock(ctx context.Context, key LockKey) (AdvisoryLock, error) {
+	return db.lock(ctx, key, "pg_advisory_lock")
+}
+
+// TryLock tries to create a session-wide advisory lock in the database.  If successful, returns
+// the advisory lock.  If not, returns ErrLocked.  If you acquire the lock, be sure to release it!
+func (db *DB) TryLock(ctx context.Context, key LockKey) (AdvisoryLock, error) {
+	return db.lock(ctx, key, "pg_try_advisory_lock")
+}
+
+// lock acquires a connection and calls the advisory lock function on it.
+func (db *DB) lock(ctx context.Context, key LockKey, fn string) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := callLock(ctx, conn, key, fn); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return &SessionAdvisoryLock{
+		Key:  key,
+		conn: conn,
+	}, nil
+}
+
+// TxAdvisoryLock is a transactional advisory lock, released when the transaction ends.
+type TxAdvisoryLock struct {
+	Key LockKey
+}
+
+// Release does nothing on a transactional advisory lock.
+func (lock *TxAdvisoryLock) Release(context.Context) error {
+	return nil
+}
+
+// Lock creates a transactional advisory lock in the database.  The lock is released at the end of
+// the transaction, on either commit or rollback.
+func (tx *Tx) Lock(ctx context.Context, key LockKey) (AdvisoryLock, error) {
+	return tx.lock(ctx, key, "pg_advisory_xact_lock")
+}
+
+// TryLock tries to create a transactional advisory lock in the database.  If the lock is in use,
+// returns ErrLocked.
+func (tx *Tx) TryLock(ctx context.Context, key LockKey) (AdvisoryLock, error) {
+	return tx.lock(ctx, key, "pg_try_advisory_xact_lock")
+}
+
+// lock calls the advisory lock function in the transaction.
+func (tx *Tx) lock(ctx context.Context, key LockKey, fn string) (AdvisoryLock, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := callLock(ctx, tx.Tx, key, fn); err != nil {
+		return nil, err
+	}
+
+	return &TxAdvisoryLock{Key: key}, nil
+}
+
+// locker is a connection or transaction that can call the advisory lock functions.
+type locker interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// callLock calls the advisory lock function with the key.  The pg_try_ functions return whether
+// the lock was acquired; ErrLocked is returned if it wasn't.
+func callLock(ctx context.Context, conn locker, key LockKey, fn string) error {
+	args := key.lockArgs()
+
+	if !strings.HasPrefix(fn, "pg_try_") {
+		_, err := conn.Exec(ctx, lockSQL(fn, args), args...)
+		return err
+	}
+
+	var available bool
+	if err := conn.QueryRow(ctx, lockSQL(fn, args), args...).Scan(&available); err != nil {
+		return err
+	}
+
+	if !available {
+		return ErrLocked
+	}
+
+	return nil
+}
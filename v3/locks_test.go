@@ -0,0 +1,81 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// lockConn records the advisory lock calls and returns whether the lock was available.
+type lockConn struct {
+	sql       string
+	args      []any
+	available bool
+}
+
+func (c *lockConn) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	c.sql, c.args = sql, args
+	return pgconn.NewCommandTag("SELECT 1"), nil
+}
+
+func (c *lockConn) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	c.sql, c.args = sql, args
+	return lockRow{available: c.available}
+}
+
+type lockRow struct {
+	available bool
+}
+
+func (r lockRow) Scan(dest ...any) error {
+	*dest[0].(*bool) = r.available
+	return nil
+}
+
+func TestLockKeys(t *testing.T) {
+	conn := &lockConn{}
+
+	if err := callLock(context.Background(), conn, Key{ID: 22}, "pg_advisory_lock"); err != nil {
+		t.Fatalf("Unable to lock: %s", err)
+	}
+
+	if conn.sql != "SELECT pg_advisory_lock($1)" || !reflect.DeepEqual(conn.args, []any{int64(22)}) {
+		t.Errorf("Expected pg_advisory_lock(22); was %s %v", conn.sql, conn.args)
+	}
+
+	if err := callLock(context.Background(), conn, Pair{Class: 1, ID: 22}, "pg_advisory_xact_lock"); err != nil {
+		t.Fatalf("Unable to lock: %s", err)
+	}
+
+	if conn.sql != "SELECT pg_advisory_xact_lock($1, $2)" || !reflect.DeepEqual(conn.args, []any{int32(1), int32(22)}) {
+		t.Errorf("Expected pg_advisory_xact_lock(1, 22); was %s %v", conn.sql, conn.args)
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	conn := &lockConn{}
+
+	if err := callLock(context.Background(), conn, Key{ID: 22}, "pg_try_advisory_lock"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked; was %v", err)
+	}
+
+	conn.available = true
+
+	if err := callLock(context.Background(), conn, Key{ID: 22}, "pg_try_advisory_lock"); err != nil {
+		t.Errorf("Expected the lock; was %s", err)
+	}
+}
+
+func TestNamedKey(t *testing.T) {
+	if NamedKey("billing.invoices") != NamedKey("billing.invoices") {
+		t.Error("Expected the same name to produce the same key")
+	}
+
+	if NamedKey("billing.invoices") == NamedKey("billing.payments") {
+		t.Error("Expected different names to produce different keys")
+	}
+}
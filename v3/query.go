@@ -0,0 +1,130 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrRowsAffected is returned by ExecOne when a statement doesn't affect exactly one row.
+var ErrRowsAffected = errors.New("unexpected number of rows affected")
+
+// Get runs the query and scans the first row into a T.  If T is a struct, columns are matched to
+// its fields by the fields' `db` tags, or their lowercased names, and fields of embedded structs
+// are matched as if they were fields of the outer struct; ErrUnmappedColumn is returned if a
+// column doesn't match any field.  Any other T, including time.Time and types implementing
+// sql.Scanner, is scanned from the query's single column.
+//
+// Returns pgx.ErrNoRows if the query doesn't return any rows.  See NoRows.
+func Get[T any](ctx context.Context, conn Conn, sql string, args ...any) (T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var value T
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return value, err
+	}
+	defer rows.Close()
+
+	s, err := newScanner[T](rows)
+	if err != nil {
+		return value, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return value, err
+		}
+		return value, pgx.ErrNoRows
+	}
+
+	if err := s.scan(rows, &value); err != nil {
+		return value, err
+	}
+
+	rows.Close()
+	return value, rows.Err()
+}
+
+// Select runs the query and scans every row into a T.  See Get for how rows are scanned.
+func Select[T any](ctx context.Context, conn Conn, sql string, args ...any) ([]T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s, err := newScanner[T](rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []T
+	for rows.Next() {
+		var value T
+		if err := s.scan(rows, &value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Result describes the outcome of a statement run by Exec.
+type Result struct {
+	// Command is the SQL command the statement ran, e.g. "INSERT" or "UPDATE".
+	Command string
+
+	// RowsAffected is the number of rows the statement inserted, updated, deleted, or selected.
+	RowsAffected int64
+}
+
+// Exec runs the statement and returns its Result.
+func Exec(ctx context.Context, conn Conn, sql string, args ...any) (Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tag, err := conn.Exec(ctx, sql, args...)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{Command: command(tag.String()), RowsAffected: tag.RowsAffected()}, nil
+}
+
+// command strips the row counts from a command tag, e.g. "INSERT 0 5" becomes "INSERT".
+func command(tag string) string {
+	return strings.TrimRight(tag, " 0123456789")
+}
+
+// ExecOne runs the statement, returning ErrRowsAffected if it doesn't affect exactly one row, such
+// as an update by primary key that finds no row to update.  In a transaction, roll back on the
+// error to undo the statement.
+func ExecOne(ctx context.Context, conn Conn, sql string, args ...any) (Result, error) {
+	result, err := Exec(ctx, conn, sql, args...)
+	if err != nil {
+		return result, err
+	}
+
+	if result.RowsAffected != 1 {
+		return result, fmt.Errorf("%w: %d", ErrRowsAffected, result.RowsAffected)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,187 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	_ Conn = (*DB)(nil)
+	_ Conn = (*Tx)(nil)
+)
+
+// fakeRows returns canned rows, assigning each value to its scan target.
+type fakeRows struct {
+	columns []string
+	values  [][]any
+	row     int
+	closed  bool
+}
+
+func (r *fakeRows) Close()                        { r.closed = true }
+func (r *fakeRows) Err() error                    { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag { return pgconn.CommandTag{} }
+func (r *fakeRows) RawValues() [][]byte           { return nil }
+func (r *fakeRows) Conn() *pgx.Conn               { return nil }
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription {
+	fields := make([]pgconn.FieldDescription, len(r.columns))
+	for i, column := range r.columns {
+		fields[i].Name = column
+	}
+	return fields
+}
+
+func (r *fakeRows) Next() bool {
+	r.row++
+	return r.row <= len(r.values)
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return r.values[r.row-1], nil
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	values := r.values[r.row-1]
+	if len(dest) != len(values) {
+		return fmt.Errorf("expected %d targets; was %d", len(values), len(dest))
+	}
+
+	for i, value := range values {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+
+	return nil
+}
+
+// queryConn is a Conn that returns canned rows and command tags.
+type queryConn struct {
+	Conn
+
+	rows *fakeRows
+	tag  pgconn.CommandTag
+}
+
+func (c *queryConn) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return c.rows, nil
+}
+
+func (c *queryConn) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return c.tag, nil
+}
+
+type audited struct {
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type account struct {
+	audited
+
+	ID      int64
+	Name    string `db:"display_name"`
+	Ignored string `db:"-"`
+}
+
+func TestGetStruct(t *testing.T) {
+	now := time.Now()
+	conn := &queryConn{rows: &fakeRows{
+		columns: []string{"id", "display_name", "created_at"},
+		values:  [][]any{{int64(12), "Alice", now}, {int64(13), "Bob", now}},
+	}}
+
+	acct, err := Get[account](context.Background(), conn, "select id, display_name, created_at from accounts")
+	if err != nil {
+		t.Fatalf("Unable to get account: %s", err)
+	}
+
+	if acct.ID != 12 || acct.Name != "Alice" || !acct.CreatedAt.Equal(now) {
+		t.Errorf("Expected 12, Alice, %s; was %#v", now, acct)
+	}
+
+	if !conn.rows.closed {
+		t.Error("Expected the rows to be closed")
+	}
+}
+
+func TestGetValue(t *testing.T) {
+	now := time.Now()
+	conn := &queryConn{rows: &fakeRows{columns: []string{"now"}, values: [][]any{{now}}}}
+
+	value, err := Get[time.Time](context.Background(), conn, "select now()")
+	if err != nil {
+		t.Fatalf("Unable to get time: %s", err)
+	}
+
+	if !value.Equal(now) {
+		t.Errorf("Expected %s; was %s", now, value)
+	}
+
+	conn.rows = &fakeRows{columns: []string{"count"}}
+
+	if _, err := Get[int64](context.Background(), conn, "select count(*) from accounts where false group by id"); !NoRows(err) {
+		t.Errorf("Expected no rows; was %v", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	conn := &queryConn{rows: &fakeRows{
+		columns: []string{"display_name"},
+		values:  [][]any{{"Alice"}, {"Bob"}},
+	}}
+
+	accounts, err := Select[account](context.Background(), conn, "select display_name from accounts")
+	if err != nil {
+		t.Fatalf("Unable to select accounts: %s", err)
+	}
+
+	if len(accounts) != 2 || accounts[0].Name != "Alice" || accounts[1].Name != "Bob" {
+		t.Errorf("Expected Alice and Bob; was %#v", accounts)
+	}
+
+	conn.rows = &fakeRows{columns: []string{"display_name"}, values: [][]any{{"Alice"}, {"Bob"}}}
+
+	names, err := Select[string](context.Background(), conn, "select display_name from accounts")
+	if err != nil {
+		t.Fatalf("Unable to select names: %s", err)
+	}
+
+	if len(names) != 2 || names[0] != "Alice" || names[1] != "Bob" {
+		t.Errorf("Expected Alice and Bob; was %v", names)
+	}
+
+	conn.rows = &fakeRows{columns: []string{"email"}, values: [][]any{{"alice@example.com"}}}
+
+	if _, err := Select[account](context.Background(), conn, "select email from accounts"); !errors.Is(err, ErrUnmappedColumn) {
+		t.Errorf("Expected ErrUnmappedColumn; was %v", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	conn := &queryConn{tag: pgconn.NewCommandTag("INSERT 0 3")}
+
+	result, err := Exec(context.Background(), conn, "insert into accounts select * from staged")
+	if err != nil {
+		t.Fatalf("Unable to insert: %s", err)
+	}
+
+	if result != (Result{Command: "INSERT", RowsAffected: 3}) {
+		t.Errorf("Expected INSERT of 3 rows; was %#v", result)
+	}
+
+	if _, err := ExecOne(context.Background(), conn, "insert into accounts select * from staged"); !errors.Is(err, ErrRowsAffected) {
+		t.Errorf("Expected ErrRowsAffected; was %v", err)
+	}
+
+	conn.tag = pgconn.NewCommandTag("CREATE TABLE")
+
+	if result, _ := Exec(context.Background(), conn, "create table staged ()"); result.Command != "CREATE TABLE" {
+		t.Errorf("Expected CREATE TABLE; was %s", result.Command)
+	}
+}
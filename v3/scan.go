@@ -0,0 +1,182 @@
+package hermes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnmappedColumn is returned when scanning a row into a struct if one of the columns doesn't
+// match any of the struct's fields.
+var ErrUnmappedColumn = errors.New("column doesn't match a struct field")
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	scannerType    = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	rowScannerType = reflect.TypeOf((*pgx.RowScanner)(nil)).Elem()
+)
+
+// scanPlans caches the scanPlan for each struct type and set of columns.
+var scanPlans sync.Map
+
+// scanKey identifies a scanPlan:  the struct type and the query's column names, separated by NUL
+// characters, which can't appear in a column name.
+type scanKey struct {
+	structType reflect.Type
+	columns    string
+}
+
+// scanPlan maps a query's columns to the fields of a struct.
+type scanPlan struct {
+	// fields holds the index of the struct field for each column, suitable for
+	// reflect.Value.FieldByIndex
+	fields [][]int
+}
+
+// scanner scans rows into values of type T:  field by field for structs, or whole for anything
+// else, such as an int, a time.Time, or a type implementing sql.Scanner.
+type scanner[T any] struct {
+	plan    *scanPlan
+	targets []any
+}
+
+// newScanner works out how to scan the rows into a T.
+func newScanner[T any](rows pgx.Rows) (*scanner[T], error) {
+	var zero T
+
+	t := reflect.TypeOf(zero)
+	if !isStruct(t) {
+		return &scanner[T]{}, nil
+	}
+
+	plan, err := planScan(t, fieldNames(rows))
+	if err != nil {
+		return nil, err
+	}
+
+	return &scanner[T]{plan: plan}, nil
+}
+
+// scan the current row into dest.
+func (s *scanner[T]) scan(rows pgx.Rows, dest *T) error {
+	if s.plan == nil {
+		return rows.Scan(dest)
+	}
+
+	s.targets = s.plan.targets(reflect.ValueOf(dest).Elem(), s.targets)
+	return rows.Scan(s.targets...)
+}
+
+// isStruct returns true if values of type t are scanned field by field, rather than as a single
+// value.
+func isStruct(t reflect.Type) bool {
+	if t == nil || t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+
+	ptr := reflect.PtrTo(t)
+	return !ptr.Implements(scannerType) && !ptr.Implements(rowScannerType)
+}
+
+// fieldNames returns the names of the columns in the rows.
+func fieldNames(rows pgx.Rows) []string {
+	fields := rows.FieldDescriptions()
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+
+	return names
+}
+
+// planScan returns the cached scan plan for the struct type and columns, creating it if
+// necessary.
+func planScan(structType reflect.Type, columns []string) (*scanPlan, error) {
+	key := scanKey{structType: structType, columns: strings.Join(columns, "\x00")}
+
+	if plan, ok := scanPlans.Load(key); ok {
+		return plan.(*scanPlan), nil
+	}
+
+	fields := make(map[string][]int)
+	structFields(structType, nil, fields)
+
+	plan := &scanPlan{fields: make([][]int, len(columns))}
+	for i, column := range columns {
+		index, ok := fields[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s in %s", ErrUnmappedColumn, column, structType)
+		}
+		plan.fields[i] = index
+	}
+
+	scanPlans.Store(key, plan)
+	return plan, nil
+}
+
+// structFields adds the struct's fields to the map by column name, along with the fields of any
+// embedded structs.  The outer struct's fields take precedence.
+func structFields(structType reflect.Type, parent []int, fields map[string][]int) {
+	var embedded []int
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("db") == "" {
+			embedded = append(embedded, i)
+			continue
+		}
+
+		name := columnName(field)
+		if name == "" {
+			continue
+		}
+
+		if _, ok := fields[name]; !ok {
+			fields[name] = append(append([]int{}, parent...), i)
+		}
+	}
+
+	for _, i := range embedded {
+		structFields(structType.Field(i).Type, append(append([]int{}, parent...), i), fields)
+	}
+}
+
+// columnName returns the column name for the struct field:  its `db` tag, or its lowercased name.
+// Returns an empty string for unexported fields, and fields tagged `db:"-"`.
+func columnName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+
+	name := field.Tag.Get("db")
+	if name == "-" {
+		return ""
+	} else if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+
+	return name
+}
+
+// targets returns pointers to the struct's fields in column order, reusing the buf slice if it's
+// big enough.
+func (plan *scanPlan) targets(v reflect.Value, buf []any) []any {
+	if cap(buf) < len(plan.fields) {
+		buf = make([]any, len(plan.fields))
+	}
+	buf = buf[:len(plan.fields)]
+
+	for i, index := range plan.fields {
+		buf[i] = v.FieldByIndex(index).Addr().Interface()
+	}
+
+	return buf
+}
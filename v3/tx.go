@@ -0,0 +1,44 @@
+package hermes
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Tx wraps the pgx.Tx interface and provides the missing hermes function wrappers.
+type Tx struct {
+	pgx.Tx
+}
+
+// Begin starts a pseudo nested transaction.
+func (tx *Tx) Begin(ctx context.Context) (Conn, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	newTx, err := tx.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{Tx: newTx}, nil
+}
+
+// Close rolls back the transaction if this is a real transaction or rolls back to the savepoint
+// if this is a pseudo nested transaction.  Does nothing if the transaction was already committed
+// or rolled back, so a defer tx.Close(ctx) is safe even if tx.Commit(ctx) is called first.
+//
+// Any other failure of a real transaction will result in the connection being closed.
+func (tx *Tx) Close(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := tx.Tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return err
+	}
+
+	return nil
+}